@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/aws"
+)
+
+var (
+	apiRPS         float64
+	apiMaxAttempts int
+	apiRetryMode   string
+	endpointURL    string
+	roleARN        string
+	externalID     string
+	sessionName    string
+	sessionTags    bool
+	ssoAutoLogin   bool
+
+	// commandName is the running command's path below the root, such as "export terraform"
+	commandName string
+)
+
+// addAPIFlags registers the AWS API endpoint, retry and rate limiting flags on a command
+func addAPIFlags(cmd *cobra.Command) {
+	defaults := aws.DefaultOptions()
+	cmd.Flags().Float64Var(&apiRPS, "api-rps", 0, "Maximum AWS API requests per second across all services (0 for no limit)")
+	cmd.Flags().IntVar(&apiMaxAttempts, "api-max-attempts", defaults.MaxAttempts, "Maximum attempts per AWS API call, including retries")
+	cmd.Flags().StringVar(&apiRetryMode, "api-retry-mode", defaults.RetryMode, "AWS API retry mode: standard, adaptive")
+	cmd.Flags().StringVar(&endpointURL, "endpoint-url", "", "Send AWS API calls to this endpoint, e.g. LocalStack (defaults to AWS_ENDPOINT_URL)")
+	cmd.Flags().StringVar(&roleARN, "role-arn", "", "Assume this IAM role with the profile's credentials, e.g. to scan a delegated account")
+	cmd.Flags().StringVar(&externalID, "external-id", "", "External ID to pass when assuming --role-arn")
+	cmd.Flags().StringVar(&sessionName, "role-session-name", "", "Role session name shown in CloudTrail (defaults to pikaatools-<command>)")
+	cmd.Flags().BoolVar(&sessionTags, "session-tags", true, "Tag the assumed role session with the tool and command (the role must allow sts:TagSession)")
+	cmd.Flags().BoolVar(&ssoAutoLogin, "sso-login", false, "Sign in to IAM Identity Center when the profile's SSO session has expired, instead of failing")
+}
+
+// newAWSClient creates an AWS client from the region, profile and API flags
+func newAWSClient(ctx context.Context) (*aws.Client, error) {
+	options := aws.Options{
+		MaxAttempts:       apiMaxAttempts,
+		RetryMode:         apiRetryMode,
+		RequestsPerSecond: apiRPS,
+		EndpointURL:       endpointURL,
+		RoleARN:           roleARN,
+		ExternalID:        externalID,
+		SessionName:       sessionName,
+	}
+
+	// Identify the scan in CloudTrail records of the assumed role's API calls
+	if options.SessionName == "" && commandName != "" {
+		options.SessionName = aws.DefaultSessionName + "-" + strings.ReplaceAll(commandName, " ", "-")
+	}
+	if sessionTags {
+		options.SessionTags = map[string]string{"Tool": aws.DefaultSessionName, "Command": commandName}
+	}
+
+	client, err := aws.NewClientWithOptions(ctx, region, profile, options)
+
+	// Sign in and try again once when the SSO session has expired
+	var loginErr *aws.SSOLoginError
+	if ssoAutoLogin && errors.As(err, &loginErr) {
+		logger.Debug("SSO session expired", "profile", loginErr.Profile, "error", loginErr.Err)
+		sso, ssoErr := aws.LoadSSOProfile(ctx, profile)
+		if ssoErr != nil || sso == nil {
+			return nil, err
+		}
+		if err := ssoLogin(ctx, sso); err != nil {
+			return nil, err
+		}
+		return aws.NewClientWithOptions(ctx, region, profile, options)
+	}
+	return client, err
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/benchmark"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/policy"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/sgaudit"
+)
+
+var (
+	auditStandard  string
+	auditStateFile string
+	auditOutput    string
+	auditFailOn    string
+	auditPeriod    string
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check the network against a security standard",
+	Long: `Check the scanned network against the built-in network controls of a security
+standard and report each control as passed, failed or not evaluated, with the
+resources that fail it:
+
+  cis   CIS AWS Foundations Benchmark v3.0.0, networking controls
+  fsbp  AWS Foundational Security Best Practices, EC2 networking controls
+
+With flow logs from --log-group or --s3, the report also labels every security
+group rule used or unused over --period, as the sg-audit command does.
+
+Exits with code 0 when no control fails, 1 when a violation at or above the
+--fail-on severity is found and 2 when the audit could not be performed.
+
+The network is scanned live unless --from-state names a saved working state.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		violated, err := runAudit(cmd.Context())
+		if err != nil {
+			return &ExitCodeError{Code: ExitError, Err: err}
+		}
+		if violated {
+			return &ExitCodeError{Code: ExitDifferences}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	auditCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(auditCmd)
+	auditCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to audit (audits all VPCs if not provided)")
+	auditCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	auditCmd.Flags().StringVar(&auditStandard, "standard", "cis", "Standard to audit against: "+strings.Join(benchmark.Standards(), ", "))
+	auditCmd.Flags().StringVarP(&auditStateFile, "from-state", "f", "", "Audit a working state file instead of scanning")
+	auditCmd.Flags().StringVarP(&auditOutput, "output", "o", "text", "Output format: text, json")
+	auditCmd.Flags().StringVar(&auditFailOn, "fail-on", "low", "Lowest violation severity that fails the run: low, medium, high")
+	auditCmd.Flags().StringVar(&auditPeriod, "period", "30d", "With flow logs, label rules used or unused by the traffic of this long before now, in days (30d) or hours (36h)")
+	addFlowLogFlags(auditCmd, "--period")
+}
+
+// runAudit audits the network and reports whether the run should fail
+func runAudit(ctx context.Context) (bool, error) {
+	standard, err := benchmark.Lookup(auditStandard)
+	if err != nil {
+		return false, err
+	}
+	threshold, err := policy.ParseSeverity(auditFailOn)
+	if err != nil {
+		return false, err
+	}
+	if auditOutput != "text" && auditOutput != "json" {
+		return false, fmt.Errorf("unsupported output format: %s", auditOutput)
+	}
+
+	network, err := loadOrScanNetwork(ctx, auditStateFile)
+	if err != nil {
+		return false, err
+	}
+	if auditStateFile != "" && vpcID != "" {
+		network = network.ForVPCs([]string{vpcID})
+	}
+
+	report := benchmark.Audit(standard, network)
+
+	if flowsLogGroup != "" || flowsS3URI != "" {
+		usage, err := auditRuleUsage(ctx, network)
+		if err != nil {
+			return false, err
+		}
+		report.RuleUsage = &usage
+	}
+
+	if auditOutput == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal audit report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(report.Text())
+	}
+
+	return report.Violated(threshold), nil
+}
+
+// auditRuleUsage labels the network's security group rules used or unused from the
+// flow logs named by the flow log flags
+func auditRuleUsage(ctx context.Context, network *scanner.Network) (sgaudit.Report, error) {
+	if err := validateFlowLogFlags(); err != nil {
+		return sgaudit.Report{}, err
+	}
+	period, err := sgaudit.ParsePeriod(auditPeriod)
+	if err != nil {
+		return sgaudit.Report{}, err
+	}
+	start, end, err := flowsWindow(period)
+	if err != nil {
+		return sgaudit.Report{}, err
+	}
+
+	traffic, err := readFlowReport(ctx, network, start, end, 0)
+	if err != nil {
+		return sgaudit.Report{}, err
+	}
+	return sgaudit.Analyze(network, traffic), nil
+}
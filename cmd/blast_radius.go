@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/reach"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+var (
+	blastRadiusTarget    string
+	blastRadiusPort      int32
+	blastRadiusProtocol  string
+	blastRadiusSubnets   []string
+	blastRadiusStateFile string
+	blastRadiusOutput    string
+)
+
+var blastRadiusCmd = &cobra.Command{
+	Use:   "blast-radius",
+	Short: "List the sources that can reach a port on an instance, network interface, security group or VPC endpoint",
+	Long: `Enumerate every source - CIDR blocks, security groups, prefix lists, peered VPCs and
+VPCs attached to the same transit gateway - that the target's security group rules
+allow to reach a port, and check each one against the subnet's network ACL and the
+routes back to the source. Each path is printed with the rule chain that allows or
+blocks it.
+
+A security group target is checked in every subnet of its VPC unless --subnet names
+some. A PrivateLink VPC endpoint target is given by its ID, or by a private IP
+address to check only the endpoint network interface holding it, and is resolved
+from the scanned network. Instance and network interface targets are resolved from
+the network interfaces in the scan, and looked up with ec2:DescribeNetworkInterfaces
+when the scan does not have them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBlastRadius(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(blastRadiusCmd)
+
+	blastRadiusCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	blastRadiusCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(blastRadiusCmd)
+	blastRadiusCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	blastRadiusCmd.Flags().StringVar(&blastRadiusTarget, "target", "", "Instance, network interface, security group or VPC endpoint ID, or a VPC endpoint IP address, to check")
+	blastRadiusCmd.Flags().Int32Var(&blastRadiusPort, "port", 0, "Destination port")
+	blastRadiusCmd.Flags().StringVar(&blastRadiusProtocol, "protocol", "tcp", "Protocol: tcp, udp")
+	blastRadiusCmd.Flags().StringSliceVar(&blastRadiusSubnets, "subnet", nil, "Subnets a security group target is checked in (defaults to every subnet of its VPC)")
+	blastRadiusCmd.Flags().StringVarP(&blastRadiusStateFile, "from-state", "f", "", "Check a working state file instead of scanning")
+	blastRadiusCmd.Flags().StringVarP(&blastRadiusOutput, "output", "o", "text", "Output format: text, json")
+	blastRadiusCmd.MarkFlagRequired("target")
+	blastRadiusCmd.MarkFlagRequired("port")
+}
+
+func runBlastRadius(ctx context.Context) error {
+	if blastRadiusPort < 1 || blastRadiusPort > 65535 {
+		return fmt.Errorf("--port must be between 1 and 65535")
+	}
+	if len(blastRadiusSubnets) > 0 && !strings.HasPrefix(blastRadiusTarget, "sg-") {
+		return fmt.Errorf("--subnet can only be used with a security group target")
+	}
+
+	network, err := loadOrScanNetwork(ctx, blastRadiusStateFile)
+	if err != nil {
+		return err
+	}
+
+	target, err := blastRadiusLookup(ctx, network)
+	if err != nil {
+		return err
+	}
+
+	report, err := reach.Analyze(network, target, blastRadiusProtocol, blastRadiusPort)
+	if err != nil {
+		return err
+	}
+
+	switch blastRadiusOutput {
+	case "text":
+		fmt.Print(report.Text())
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal blast radius report: %w", err)
+		}
+		fmt.Println(string(data))
+	default:
+		return fmt.Errorf("unsupported output format: %s", blastRadiusOutput)
+	}
+
+	return nil
+}
+
+// blastRadiusLookup finds the subnets and security groups of the target
+func blastRadiusLookup(ctx context.Context, network *scanner.Network) (reach.Target, error) {
+	if strings.HasPrefix(blastRadiusTarget, "sg-") {
+		return reach.SecurityGroupTarget(network, blastRadiusTarget, blastRadiusSubnets)
+	}
+	if strings.HasPrefix(blastRadiusTarget, "vpce-") || net.ParseIP(blastRadiusTarget) != nil {
+		return reach.VpcEndpointTarget(network, blastRadiusTarget)
+	}
+
+	if target, ok := reach.NetworkInterfaceTarget(network, blastRadiusTarget); ok {
+		return target, nil
+	}
+
+	// States scanned without network interfaces, or older than the interface, need a lookup
+	awsClient, err := newAWSClient(ctx)
+	if err != nil {
+		return reach.Target{}, fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+	return reach.LookupTarget(ctx, awsClient.EC2, blastRadiusTarget)
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+var (
+	cacheFile   string
+	cacheMaxAge time.Duration
+)
+
+// addCacheFlags registers the scan cache flags on a command
+func addCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&cacheFile, "cache", "", "Reuse per-resource API results between scans from this cache file (e.g. .pikaatools-cache.json)")
+	cmd.Flags().DurationVar(&cacheMaxAge, "cache-max-age", time.Hour, "With --cache, refetch cached results older than this. Changes inside cached results stay hidden until they expire; watch caps it at --interval unless --cloudtrail invalidates changed results")
+}
+
+// loadScanCache loads the cache named by --cache, or returns nil when caching is off
+func loadScanCache() (*scanner.ScanCache, error) {
+	if cacheFile == "" {
+		return nil, nil
+	}
+	return scanner.LoadScanCache(cacheFile, cacheMaxAge)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var noColor bool
+
+// addColorFlags registers the --no-color flag shared by every command
+func addColorFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also disabled by NO_COLOR or when stdout is not a terminal)")
+}
+
+// setupColor turns colored output off for --no-color. Colors are already off when
+// NO_COLOR is set, TERM is dumb or stdout is not a terminal.
+func setupColor() {
+	if noColor {
+		color.NoColor = true
+	}
+}
+
+// colorOutput reports whether output written to stdout should be colored
+func colorOutput() bool {
+	return !color.NoColor
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/completion"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// recordCompletions remembers the region and VPCs of a scan for shell completion.
+// Completion is a convenience, so failures are only logged.
+func recordCompletions(network *scanner.Network, scannedVpcID string) {
+	path, err := completion.DefaultPath()
+	if err != nil {
+		logger.Debug("failed to record completions", "error", err)
+		return
+	}
+	cache, err := completion.Load(path)
+	if err != nil {
+		logger.Debug("failed to record completions", "error", err)
+		return
+	}
+	cache.Record(network, scannedVpcID)
+	if err := cache.Save(path); err != nil {
+		logger.Debug("failed to record completions", "error", err)
+	}
+}
+
+// loadCompletions loads the completion cache, or an empty one if it cannot be read
+func loadCompletions() *completion.Cache {
+	empty := &completion.Cache{}
+	path, err := completion.DefaultPath()
+	if err != nil {
+		return empty
+	}
+	cache, err := completion.Load(path)
+	if err != nil {
+		return empty
+	}
+	return cache
+}
+
+// completeRegions suggests the regions of earlier scans
+func completeRegions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return loadCompletions().RegionNames(toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeVPCIDs suggests the VPCs of earlier scans, limited to the --region given
+func completeVPCIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	scannedRegion, _ := cmd.Flags().GetString("region")
+	return loadCompletions().VPCIDs(scannedRegion, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// registerCompletions adds dynamic completion to the --region and --vpc-id flags of
+// a command and its subcommands
+func registerCompletions(cmd *cobra.Command) {
+	if cmd.Flags().Lookup("region") != nil {
+		_ = cmd.RegisterFlagCompletionFunc("region", completeRegions)
+	}
+	if cmd.Flags().Lookup("vpc-id") != nil {
+		_ = cmd.RegisterFlagCompletionFunc("vpc-id", completeVPCIDs)
+	}
+	for _, child := range cmd.Commands() {
+		registerCompletions(child)
+	}
+}
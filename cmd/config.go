@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/config"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+var (
+	configPath string
+
+	// configFile is the loaded config file, nil when there is none
+	configFile *config.File
+)
+
+// addConfigFlags registers the config file flag shared by every command
+func addConfigFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file with persistent flag defaults (defaults to ~/.pikaatools.yaml if present)")
+}
+
+// applyConfig loads the config file and sets the command's flags that were not
+// given on the command line from it
+func applyConfig(cmd *cobra.Command) error {
+	path := configPath
+	if path == "" {
+		defaultPath, err := config.DefaultPath()
+		if err != nil {
+			return nil
+		}
+		if _, err := os.Stat(defaultPath); err != nil {
+			return nil
+		}
+		path = defaultPath
+	}
+
+	file, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	configFile = file
+
+	return file.Apply(config.CommandName(cmd.CommandPath()), cmd.Flags())
+}
+
+// configIgnoreRules returns the suppression rules from the config file
+func configIgnoreRules() []watch.IgnoreRule {
+	if configFile == nil {
+		return nil
+	}
+	return configFile.IgnoreRules
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/coverage"
+)
+
+var (
+	coverageSnapshotDir  string
+	coverageAccounts     []string
+	coverageAccountsFile string
+	coverageRegions      []string
+	coverageMaxAge       time.Duration
+	coverageOutput       string
+)
+
+var coverageCmd = &cobra.Command{
+	Use:   "coverage",
+	Short: "Report which accounts and regions have recent snapshots",
+	Long: `Report which account/region pairs have a recent working state snapshot and which
+are unscanned or stale beyond a threshold, so gaps in drift monitoring are visible.
+
+Accounts come from --accounts or an --accounts-file with one "id" or "id,name" per
+line. Regions come from --regions, or default to the regions enabled for the current
+credentials.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCoverage(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(coverageCmd)
+
+	coverageCmd.Flags().StringVarP(&coverageSnapshotDir, "snapshots", "s", ".", "Directory containing working state snapshots")
+	coverageCmd.Flags().StringSliceVar(&coverageAccounts, "accounts", nil, "Account IDs expected to be monitored")
+	coverageCmd.Flags().StringVar(&coverageAccountsFile, "accounts-file", "", "File listing account IDs expected to be monitored")
+	coverageCmd.Flags().StringSliceVar(&coverageRegions, "regions", nil, "Regions expected to be monitored (defaults to enabled regions)")
+	coverageCmd.Flags().DurationVar(&coverageMaxAge, "max-age", 24*time.Hour, "Snapshots older than this are reported as stale")
+	coverageCmd.Flags().StringVarP(&coverageOutput, "output", "o", "text", "Output format: text, json")
+	coverageCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region used to list enabled regions")
+	coverageCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile used to list enabled regions")
+	addAPIFlags(coverageCmd)
+}
+
+func runCoverage(ctx context.Context) error {
+	var accounts []coverage.Account
+	for _, id := range coverageAccounts {
+		accounts = append(accounts, coverage.Account{ID: id})
+	}
+	if coverageAccountsFile != "" {
+		fileAccounts, err := coverage.LoadAccounts(coverageAccountsFile)
+		if err != nil {
+			return err
+		}
+		accounts = append(accounts, fileAccounts...)
+	}
+	if len(accounts) == 0 {
+		return fmt.Errorf("no accounts given, use --accounts or --accounts-file")
+	}
+
+	regions := coverageRegions
+	if len(regions) == 0 {
+		enabled, err := enabledRegions(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list enabled regions, use --regions: %w", err)
+		}
+		regions = enabled
+	}
+
+	snapshots, err := coverage.LoadSnapshots(coverageSnapshotDir)
+	if err != nil {
+		return fmt.Errorf("failed to load snapshots from %s: %w", coverageSnapshotDir, err)
+	}
+
+	report := coverage.BuildReport(accounts, regions, snapshots, coverageMaxAge, time.Now())
+
+	switch coverageOutput {
+	case "text":
+		fmt.Print(report.Text())
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal coverage report: %w", err)
+		}
+		fmt.Println(string(data))
+	default:
+		return fmt.Errorf("unsupported output format: %s", coverageOutput)
+	}
+
+	return nil
+}
+
+// enabledRegions lists the regions enabled for the current credentials
+func enabledRegions(ctx context.Context) ([]string, error) {
+	awsClient, err := newAWSClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := awsClient.EC2.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []string
+	for _, r := range result.Regions {
+		if r.RegionName != nil {
+			regions = append(regions, *r.RegionName)
+		}
+	}
+	sort.Strings(regions)
+
+	return regions, nil
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/schedule"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+var (
+	daemonSchedule   string
+	daemonS3URI      string
+	daemonCompare    bool
+	daemonRunOnStart bool
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Scan on a schedule and upload each snapshot to S3",
+	Long: `Run as a long-lived drift sentinel, for example as a Fargate task or on an EC2
+instance. The network is scanned on a cron schedule and each scan is written to S3
+under a date-partitioned key:
+
+  s3://bucket/prefix/<account>/<region>/YYYY/MM/DD/YYYYMMDDTHHMMSSZ.json
+
+Scans limited with --vpc-id have the VPC ID after the region.
+
+The schedule is a five-field cron expression (minute hour day-of-month month
+day-of-week) in the local time zone, a descriptor such as @hourly or @daily, or
+"@every <duration>".
+
+With --compare each scan is compared with the previous snapshot, including the
+latest one in the bucket when the daemon starts, and differences are printed and
+sent to the configured notifiers.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDaemon(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().StringVar(&daemonSchedule, "schedule", "@hourly", "Cron schedule of the scans (e.g. \"*/30 * * * *\", @daily, \"@every 2h\")")
+	daemonCmd.Flags().StringVar(&daemonS3URI, "s3-uri", "", "S3 bucket and prefix snapshots are written to, as s3://bucket/prefix")
+	daemonCmd.Flags().BoolVar(&daemonCompare, "compare", false, "Compare each scan with the previous snapshot and report differences")
+	daemonCmd.Flags().BoolVar(&daemonRunOnStart, "run-on-start", false, "Scan once at startup before waiting for the schedule")
+	daemonCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	daemonCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(daemonCmd)
+	daemonCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to scan (scans all VPCs if not provided)")
+	daemonCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	daemonCmd.Flags().StringVar(&notifySNSArn, "notify-sns-arn", "", "With --compare, publish drift events to this SNS topic ARN")
+	daemonCmd.Flags().StringVar(&notifyEventBridgeBus, "notify-eventbridge-bus", "", "With --compare, publish drift events to this EventBridge bus name or ARN")
+	daemonCmd.Flags().BoolVar(&notifySlack, "notify-slack", false, "With --compare, post drift events to the Slack incoming webhook URL in "+slackWebhookEnv)
+	daemonCmd.Flags().StringVar(&historyFile, "history", "", "With --compare, append each set of detected differences to this JSONL drift log")
+	daemonCmd.Flags().StringVar(&ignoreFile, "ignore-file", "", "Suppression rules file (defaults to .pikaaignore.yaml if present)")
+	daemonCmd.Flags().StringVar(&diffOutput, "diff-output", "", "Structured diff output format: json, junit, sarif (default colored text)")
+	daemonCmd.Flags().StringVar(&reportFile, "report", "", "With --compare, write an HTML drift report of each scan to this file")
+	daemonCmd.Flags().StringVar(&reportS3URI, "report-s3", "", "With --compare, upload an HTML drift report of each scan with differences under this s3://bucket/prefix")
+	daemonCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address (e.g. :9090)")
+	daemonCmd.Flags().BoolVar(&scanAppMesh, "app-mesh", false, "Also discover App Mesh virtual gateways")
+	daemonCmd.Flags().BoolVar(&scanContainers, "containers", false, "Also discover EKS clusters and awsvpc ECS services")
+	daemonCmd.Flags().BoolVar(&scanDatabases, "databases", false, "Also discover RDS, ElastiCache and Redshift placement")
+	daemonCmd.Flags().BoolVar(&scanEdge, "edge", false, "Also discover Global Accelerator and CloudFront ingress into the scanned VPCs")
+	addPagingFlags(daemonCmd)
+	addIAMFlags(daemonCmd)
+	addNameFlags(daemonCmd)
+	addSigningFlags(daemonCmd)
+	daemonCmd.MarkFlagRequired("s3-uri")
+}
+
+func runDaemon(ctx context.Context) error {
+	if err := validateDiffOutput(diffOutput); err != nil {
+		return err
+	}
+
+	cron, err := schedule.Parse(daemonSchedule)
+	if err != nil {
+		return err
+	}
+
+	awsClient, err := newAWSClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+
+	store, err := watch.NewSnapshotStore(awsClient.S3, daemonS3URI)
+	if err != nil {
+		return err
+	}
+	store.SetVPCID(vpcID)
+
+	signer, err := newSigner(awsClient)
+	if err != nil {
+		return err
+	}
+	store.SetSigner(signer)
+
+	logger.Debug("starting daemon", "region", awsClient.Region(), "schedule", cron, "s3_uri", daemonS3URI, "compare", daemonCompare)
+
+	// The interval is unused, the daemon runs on its schedule
+	watcher := watch.NewWatcher(awsClient, 0, verbose, awsClient.Region(), vpcID)
+
+	watcher.SetLogger(logger)
+	watcher.SetProgress(scanProgress())
+	watcher.SetDiffOutput(diffOutput)
+	watcher.SetReportFile(reportFile)
+	watcher.SetScanAppMesh(scanAppMesh)
+	watcher.SetScanContainers(scanContainers)
+	watcher.SetScanDatabases(scanDatabases)
+	watcher.SetScanEdge(scanEdge)
+	watcher.SetScanIAM(withIAM, allIAMRoles)
+	watcher.SetIAMConcurrency(iamConcurrency)
+
+	ignoreRules, err := loadIgnoreRules(ignoreFile)
+	if err != nil {
+		return err
+	}
+	watcher.SetIgnoreRules(ignoreRules)
+
+	providers, err := nameProviders()
+	if err != nil {
+		return err
+	}
+	watcher.SetNameProviders(providers)
+
+	if err := setReportUploader(watcher, awsClient); err != nil {
+		return err
+	}
+	if err := addDriftNotifiers(watcher, awsClient); err != nil {
+		return err
+	}
+	serveWatchMetrics(watcher)
+
+	return watcher.Daemon(ctx, watch.DaemonOptions{
+		Schedule:   cron,
+		Store:      store,
+		Compare:    daemonCompare,
+		RunOnStart: daemonRunOnStart,
+	})
+}
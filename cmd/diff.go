@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/aws"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/config"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/export"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+// Exit codes returned by the diff, policy eval and audit commands
+const (
+	ExitNoDifferences = 0
+	ExitDifferences   = 1
+	ExitError         = 2
+)
+
+// ExitCodeError carries a specific process exit code out of a command
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	diffFrom string
+	diffTo   string
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare AWS network infrastructure against a baseline once",
+	Long: `Scan your AWS network infrastructure once and compare it against a baseline
+working state. Exits with code 0 when there are no differences, 1 when differences
+are found and 2 when the comparison could not be performed, so it can gate CI/CD
+deployments.
+
+With --from and --to, two saved working states are compared offline instead, for
+reviewing the changes between two known points in time. Nothing is scanned and
+AWS is only called to read snapshots from S3 or verify KMS signatures.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// A file set by the config file is a default for live diffs, not a conflict
+		if diffFrom != "" && config.GivenOnCommandLine(cmd.Flags(), "file") {
+			return &ExitCodeError{Code: ExitError, Err: fmt.Errorf("--file cannot be used with --from and --to")}
+		}
+		return runDiff(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&workingStateFile, "file", "f", "working_state.json", "Working state file, s3://bucket/key or https:// URL to compare against")
+	diffCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	diffCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(diffCmd)
+	diffCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to compare (compares all VPCs if not provided)")
+	diffCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	diffCmd.Flags().StringVar(&ignoreFile, "ignore-file", "", "Suppression rules file (defaults to .pikaaignore.yaml if present)")
+	diffCmd.Flags().StringVar(&diffOutput, "diff-output", "", "Structured diff output format: json, junit, sarif (default colored text)")
+	diffCmd.Flags().StringVar(&reportFile, "report", "", "Write an HTML drift report with before and after values per resource to this file (e.g. drift.html)")
+	diffCmd.Flags().BoolVar(&scanAppMesh, "app-mesh", false, "Also discover App Mesh virtual gateways")
+	diffCmd.Flags().BoolVar(&scanContainers, "containers", false, "Also discover EKS clusters and awsvpc ECS services")
+	diffCmd.Flags().BoolVar(&scanDatabases, "databases", false, "Also discover RDS, ElastiCache and Redshift placement")
+	diffCmd.Flags().BoolVar(&scanEdge, "edge", false, "Also discover Global Accelerator and CloudFront ingress into the scanned VPCs")
+	addIAMFlags(diffCmd)
+	addCacheFlags(diffCmd)
+	addNameFlags(diffCmd)
+	addVerifyFlags(diffCmd)
+	addRemediationFlag(diffCmd)
+	diffCmd.Flags().StringVar(&diffFrom, "from", "", "Compare two saved working states offline: the earlier state (file, s3:// or https:// URL, or sqlite:state.db#id)")
+	diffCmd.Flags().StringVar(&diffTo, "to", "", "With --from, the later working state to compare it with")
+
+	diffCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &ExitCodeError{Code: ExitError, Err: err}
+	})
+}
+
+func runDiff(ctx context.Context) error {
+	differences, err := diff(ctx)
+	if err != nil {
+		return &ExitCodeError{Code: ExitError, Err: err}
+	}
+
+	if len(differences) > 0 {
+		return &ExitCodeError{Code: ExitDifferences}
+	}
+	return nil
+}
+
+// diff performs a single comparison against the baseline working state
+func diff(ctx context.Context) ([]watch.Difference, error) {
+	if err := validateDiffOutput(diffOutput); err != nil {
+		return nil, err
+	}
+
+	// Checked here rather than with flag groups so mistakes exit with ExitError
+	if (diffFrom == "") != (diffTo == "") {
+		return nil, fmt.Errorf("--from and --to must be used together")
+	}
+	remediation, err := remediationFormat()
+	if err != nil {
+		return nil, err
+	}
+	if diffFrom != "" {
+		return diffSnapshots(ctx, remediation)
+	}
+
+	// Check if working state file exists
+	if _, err := os.Stat(workingStateFile); os.IsNotExist(err) && !watch.IsRemoteBaseline(workingStateFile) {
+		return nil, fmt.Errorf("working state file %s does not exist. Please run 'scan --save-state' first to create a baseline", workingStateFile)
+	}
+
+	ignoreRules, err := loadIgnoreRules(ignoreFile)
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err := nameProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	scanCache, err := loadScanCache()
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize AWS client
+	awsClient, err := newAWSClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+
+	verifier, err := newSigner(awsClient)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("comparing against baseline", "region", awsClient.Region(), "baseline", workingStateFile)
+
+	watcher := watch.NewWatcher(awsClient, 0, verbose, awsClient.Region(), vpcID)
+	watcher.SetLogger(logger)
+	watcher.SetProgress(scanProgress())
+	watcher.SetDiffOutput(diffOutput)
+	watcher.SetReportFile(reportFile)
+	watcher.SetIgnoreRules(ignoreRules)
+	watcher.SetRemediation(remediation)
+	watcher.SetScanAppMesh(scanAppMesh)
+	watcher.SetScanContainers(scanContainers)
+	watcher.SetScanDatabases(scanDatabases)
+	watcher.SetScanEdge(scanEdge)
+	watcher.SetScanIAM(withIAM, allIAMRoles)
+	watcher.SetIAMConcurrency(iamConcurrency)
+	watcher.SetCache(scanCache)
+	watcher.SetNameProviders(providers)
+	watcher.SetVerifier(verifier)
+
+	return watcher.Check(ctx, workingStateFile)
+}
+
+// diffSnapshots compares two saved working states without scanning
+func diffSnapshots(ctx context.Context, remediation watch.RemediationFormat) ([]watch.Difference, error) {
+	ignoreRules, err := loadIgnoreRules(ignoreFile)
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err := nameProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	comparator := watch.NewComparator(verbose)
+	comparator.SetIgnoreRules(ignoreRules)
+	comparator.SetRemediation(remediation)
+
+	// AWS is only needed for snapshots in S3 and KMS signatures
+	var awsClient *aws.Client
+	if kmsKeyID != "" || isS3URI(diffFrom) || isS3URI(diffTo) {
+		awsClient, err = newAWSClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize AWS client: %w", err)
+		}
+		comparator.SetS3(awsClient.S3)
+	}
+
+	verifier, err := newSigner(awsClient)
+	if err != nil {
+		return nil, err
+	}
+	if verifier != nil && (strings.HasPrefix(diffFrom, "sqlite:") || strings.HasPrefix(diffTo, "sqlite:")) {
+		return nil, fmt.Errorf("snapshots in SQLite databases are not signed and cannot be verified")
+	}
+	comparator.SetVerifier(verifier)
+
+	from, err := loadSnapshot(ctx, comparator, diffFrom)
+	if err != nil {
+		return nil, err
+	}
+	to, err := loadSnapshot(ctx, comparator, diffTo)
+	if err != nil {
+		return nil, err
+	}
+	if vpcID != "" {
+		from = from.ForVPCs([]string{vpcID})
+		to = to.ForVPCs([]string{vpcID})
+	}
+
+	logger.Debug("comparing snapshots", "from", diffFrom, "to", diffTo)
+
+	differences := comparator.Compare(from, to)
+	if err := watch.ResolveNames(ctx, providers, differences); err != nil {
+		logger.Debug("failed to resolve resource names", "error", err)
+	}
+
+	if reportFile != "" {
+		info := watch.HTMLReportInfo{Region: to.Region, VpcID: vpcID, Baseline: diffFrom, Current: diffTo}
+		if err := watch.WriteHTMLReport(reportFile, differences, info); err != nil {
+			return nil, err
+		}
+	}
+
+	if diffOutput != "" {
+		report, err := watch.FormatDifferences(differences, diffOutput)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Print(report)
+		return differences, nil
+	}
+
+	fmt.Printf("Comparing %s (%s) to %s (%s)\n\n", diffFrom, snapshotTime(from), diffTo, snapshotTime(to))
+	comparator.PrintDifferences(differences)
+	return differences, nil
+}
+
+// snapshotTime formats the scan time of a saved working state
+func snapshotTime(network *scanner.Network) string {
+	if network.ScanTime.IsZero() {
+		return "scan time unknown"
+	}
+	return network.ScanTime.Local().Format("2006-01-02 15:04:05")
+}
+
+// loadSnapshot loads a saved working state, or a snapshot in a SQLite database given
+// as sqlite:state.db#id, or sqlite:state.db for its latest snapshot
+func loadSnapshot(ctx context.Context, comparator *watch.Comparator, location string) (*scanner.Network, error) {
+	path, ok := strings.CutPrefix(location, "sqlite:")
+	if !ok {
+		return comparator.LoadBaseline(ctx, location)
+	}
+
+	var id int64
+	if i := strings.LastIndexByte(path, '#'); i >= 0 {
+		parsed, err := strconv.ParseInt(path[i+1:], 10, 64)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid snapshot ID in %s, expected sqlite:file.db#id", location)
+		}
+		path, id = path[:i], parsed
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+	}
+	return export.ReadSQLiteSnapshot(path, id)
+}
+
+// isS3URI reports whether a location is an s3:// URI
+func isS3URI(location string) bool {
+	return strings.HasPrefix(strings.ToLower(location), "s3://")
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/schedule"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+// smtpPasswordEnv is the environment variable holding the SMTP password, kept
+// out of flags so it does not show up in process listings
+const smtpPasswordEnv = "PIKAATOOLS_SMTP_PASSWORD"
+
+var (
+	digestHistoryFile string
+	digestPeriod      string
+	digestSchedule    string
+	digestTo          []string
+	digestFrom        string
+	digestSMTPHost    string
+	digestSMTPUser    string
+	digestSkipEmpty   bool
+	digestDryRun      bool
+)
+
+// digestPeriods are the time windows a digest can cover
+var digestPeriods = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Email a digest of the drift recorded by watch --history",
+	Long: `Summarize the drift log written by 'watch --history' or 'daemon --history' over the
+last day or week and email it, for teams that review drift asynchronously. Each
+changed resource is listed once, most severe first, with how many scans found it
+differing and its latest difference.
+
+Email is sent through Amazon SES unless --smtp-host names an SMTP server. The SMTP
+password is read from the PIKAATOOLS_SMTP_PASSWORD environment variable.
+
+Without --schedule one digest is sent and the command exits, to run from cron. With
+--schedule it keeps running and sends a digest of the preceding --period at every
+scheduled time, such as "0 8 * * *" for a daily digest at 8:00 or "0 8 * * 1" for a
+weekly one on Mondays.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDigest(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(digestCmd)
+
+	digestCmd.Flags().StringVar(&digestHistoryFile, "history", "drift.jsonl", "Drift log to summarize")
+	digestCmd.Flags().StringVar(&digestPeriod, "period", "daily", "Period each digest covers: daily, weekly")
+	digestCmd.Flags().StringVar(&digestSchedule, "schedule", "", "Keep running and send a digest on this cron schedule (e.g. \"0 8 * * *\")")
+	digestCmd.Flags().StringSliceVar(&digestTo, "to", nil, "Recipient email address (repeatable)")
+	digestCmd.Flags().StringVar(&digestFrom, "from", "", "Sender email address, verified in SES when sending through SES")
+	digestCmd.Flags().StringVar(&digestSMTPHost, "smtp-host", "", "Send through this SMTP server (host:port) instead of SES")
+	digestCmd.Flags().StringVar(&digestSMTPUser, "smtp-username", "", "SMTP username, with the password in "+smtpPasswordEnv)
+	digestCmd.Flags().BoolVar(&digestSkipEmpty, "skip-empty", false, "Do not send a digest when no drift was recorded")
+	digestCmd.Flags().BoolVar(&digestDryRun, "dry-run", false, "Print the digest instead of sending it")
+	digestCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region of SES (defaults to AWS_REGION or us-east-1)")
+	digestCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(digestCmd)
+}
+
+func runDigest(ctx context.Context) error {
+	period, ok := digestPeriods[digestPeriod]
+	if !ok {
+		return fmt.Errorf("unsupported period %q, expected daily or weekly", digestPeriod)
+	}
+	var cron *schedule.Schedule
+	if digestSchedule != "" {
+		var err error
+		if cron, err = schedule.Parse(digestSchedule); err != nil {
+			return err
+		}
+	}
+
+	var sender watch.EmailSender
+	if !digestDryRun {
+		if len(digestTo) == 0 || digestFrom == "" {
+			return fmt.Errorf("--to and --from are required to send a digest")
+		}
+		if digestSMTPHost != "" {
+			sender = watch.NewSMTPSender(digestSMTPHost, digestSMTPUser, os.Getenv(smtpPasswordEnv))
+		} else {
+			awsClient, err := newAWSClient(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to initialize AWS client: %w", err)
+			}
+			sender = watch.NewSESSender(awsClient.SES)
+		}
+	}
+
+	if cron == nil {
+		return sendDigest(ctx, sender, period, time.Now())
+	}
+	for {
+		next := cron.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q never runs", cron)
+		}
+		logger.Info("next digest scheduled", "at", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+			// A digest that fails is logged and the next one is still sent
+			if err := sendDigest(ctx, sender, period, time.Now()); err != nil {
+				logger.Error("failed to send digest", "error", err)
+			}
+		}
+	}
+}
+
+// sendDigest sends a digest of the drift recorded in the period before until,
+// or prints it without a sender
+func sendDigest(ctx context.Context, sender watch.EmailSender, period time.Duration, until time.Time) error {
+	since := until.Add(-period)
+	events, err := watch.ReadHistory(digestHistoryFile, watch.HistoryFilter{Since: since, Until: until})
+	if err != nil {
+		return err
+	}
+	digest := watch.NewDigest(events, since, until)
+	if len(digest.Resources) == 0 && digestSkipEmpty {
+		logger.Info("no drift recorded, skipping digest", "since", since.Format(time.RFC3339))
+		return nil
+	}
+
+	if sender == nil {
+		fmt.Printf("Subject: %s\n\n%s", digest.Subject(), digest.Text())
+		return nil
+	}
+	message := watch.EmailMessage{
+		From:    digestFrom,
+		To:      digestTo,
+		Subject: digest.Subject(),
+		Text:    digest.Text(),
+		HTML:    digest.HTML(),
+	}
+	if err := sender.Send(ctx, message); err != nil {
+		return err
+	}
+	logger.Info("sent digest", "to", digestTo, "resources", len(digest.Resources))
+	return nil
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+var (
+	doctorAll    bool
+	doctorOutput string
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that the current credentials can make every API call a scan needs",
+	Long: `Check every API action a scan calls with the current credentials, and list the
+permissions that are missing before a scan fails halfway through. EC2 actions are
+checked with dry-run requests; other services with calls that list at most one
+resource or look up one that does not exist, so nothing is changed.
+
+Actions of optional features are checked when the same flags as scan are given,
+such as --with-iam or --containers, or all of them with --all.
+
+Exits with code 0 when every required action is allowed, 1 when a required action
+is denied and 2 when the check could not be performed. Denied optional actions,
+which scans continue without, are reported but do not fail the check.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		denied, err := runDoctor(cmd.Context())
+		if err != nil {
+			return &ExitCodeError{Code: ExitError, Err: err}
+		}
+		if denied {
+			return &ExitCodeError{Code: ExitDifferences}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	doctorCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(doctorCmd)
+	doctorCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	addIAMFlags(doctorCmd)
+	doctorCmd.Flags().BoolVar(&scanAppMesh, "app-mesh", false, "Also check the App Mesh actions")
+	doctorCmd.Flags().BoolVar(&scanContainers, "containers", false, "Also check the EKS and ECS actions")
+	doctorCmd.Flags().BoolVar(&scanDatabases, "databases", false, "Also check the RDS, ElastiCache and Redshift actions")
+	doctorCmd.Flags().BoolVar(&scanEdge, "edge", false, "Also check the Elastic IP, load balancer, Global Accelerator and CloudFront actions")
+	doctorCmd.Flags().BoolVar(&doctorAll, "all", false, "Check the actions of every optional feature")
+	doctorCmd.Flags().StringVarP(&doctorOutput, "output", "o", "text", "Output format: text, json")
+}
+
+// runDoctor checks the scanner's permissions and reports whether a required one is denied
+func runDoctor(ctx context.Context) (bool, error) {
+	if doctorOutput != "text" && doctorOutput != "json" {
+		return false, fmt.Errorf("unsupported output format: %s", doctorOutput)
+	}
+
+	awsClient, err := newAWSClient(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+
+	networkScanner := scanner.NewNetworkScanner(awsClient)
+	networkScanner.SetLogger(logger)
+	networkScanner.SetScanIAM(withIAM || doctorAll)
+	networkScanner.SetScanContainers(scanContainers || doctorAll)
+	networkScanner.SetScanDatabases(scanDatabases || doctorAll)
+	networkScanner.SetScanEdge(scanEdge || doctorAll)
+	networkScanner.SetScanAppMesh(scanAppMesh || doctorAll)
+
+	checks := networkScanner.Preflight(ctx)
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	denied := false
+	for _, check := range checks {
+		if check.Status == scanner.PermissionDenied && !check.Optional {
+			denied = true
+		}
+	}
+
+	if doctorOutput == "json" {
+		data, err := json.MarshalIndent(checks, "", "  ")
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal permission checks: %w", err)
+		}
+		fmt.Println(string(data))
+		return denied, nil
+	}
+
+	fmt.Print(formatPermissionChecks(checks, awsClient.Region()))
+	return denied, nil
+}
+
+// formatPermissionChecks lists each action with its outcome, followed by the missing ones
+func formatPermissionChecks(checks []scanner.PermissionCheck, region string) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Permissions a scan needs in %s:\n\n", region))
+
+	var missing []string
+	for _, check := range checks {
+		mark := color.GreenString("✓")
+		switch check.Status {
+		case scanner.PermissionDenied:
+			mark = color.RedString("✗")
+			missing = append(missing, check.Action)
+		case scanner.PermissionUnknown:
+			mark = color.YellowString("?")
+		}
+
+		var notes []string
+		if check.Feature != "" {
+			notes = append(notes, check.Feature)
+		}
+		if check.Optional {
+			notes = append(notes, "optional")
+		}
+		line := fmt.Sprintf("  %s %s", mark, check.Action)
+		if len(notes) > 0 {
+			line += fmt.Sprintf(" (%s)", strings.Join(notes, ", "))
+		}
+		if check.Message != "" {
+			line += ": " + check.Message
+		}
+		result.WriteString(line + "\n")
+	}
+
+	if len(missing) == 0 {
+		result.WriteString(fmt.Sprintf("\nAll %d actions are allowed.\n", len(checks)))
+		return result.String()
+	}
+	result.WriteString(fmt.Sprintf("\n%d of %d actions are denied. Add them to the policy of the scanning identity:\n", len(missing), len(checks)))
+	for _, action := range missing {
+		result.WriteString("  " + action + "\n")
+	}
+	return result.String()
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/export"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+var (
+	exportStateFile  string
+	exportOutputFile string
+	exportCSVDir     string
+	exportTablesDir  string
+	exportSchema     string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export scanned infrastructure to other formats",
+}
+
+var exportTerraformCmd = &cobra.Command{
+	Use:   "terraform",
+	Short: "Generate Terraform configuration from a scan",
+	Long: `Generate Terraform resource blocks (aws_vpc, aws_subnet, aws_route_table,
+aws_security_group, and related resources) plus import blocks for the scanned
+network, so unmanaged infrastructure can be brought under Terraform.
+
+The network is scanned live unless --from-state names a saved working state.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExportTerraform(cmd.Context())
+	},
+}
+
+var exportCSVCmd = &cobra.Command{
+	Use:   "csv",
+	Short: "Export a scan as per-resource-type CSV files",
+	Long: `Flatten every scanned resource type into its own CSV file (vpcs.csv, subnets.csv,
+security_groups.csv, iam_roles.csv, ...) in an output directory for import into
+spreadsheets, asset inventories and CMDBs. Tags and lists are joined with semicolons.
+
+The network is scanned live unless --from-state names a saved working state.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExportCSV(cmd.Context())
+	},
+}
+
+var exportTablesCmd = &cobra.Command{
+	Use:   "tables",
+	Short: "Export a scan as CloudQuery or Steampipe AWS tables",
+	Long: `Write every scanned resource type as the table of the CloudQuery or Steampipe AWS
+plugin it corresponds to (aws_ec2_vpcs or aws_vpc, aws_ec2_security_groups or
+aws_vpc_security_group, ...), one JSON Lines file per table, with the plugin's
+column names. Loading the files into the database behind a CloudQuery or Steampipe
+dashboard lets existing queries and dashboards read pikaatools scans.
+
+The network is scanned live unless --from-state names a saved working state.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExportTables(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.AddCommand(exportTerraformCmd)
+	exportCmd.AddCommand(exportCSVCmd)
+	exportCmd.AddCommand(exportTablesCmd)
+
+	exportTerraformCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	exportTerraformCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(exportTerraformCmd)
+	exportTerraformCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to export (exports all VPCs if not provided)")
+	exportTerraformCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	exportTerraformCmd.Flags().StringVarP(&exportStateFile, "from-state", "f", "", "Generate from a working state file instead of scanning")
+	exportTerraformCmd.Flags().StringVar(&exportOutputFile, "out", "", "Write configuration to this file instead of stdout")
+
+	exportCSVCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	exportCSVCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(exportCSVCmd)
+	exportCSVCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to export (exports all VPCs if not provided)")
+	exportCSVCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	exportCSVCmd.Flags().StringVarP(&exportStateFile, "from-state", "f", "", "Export a working state file instead of scanning")
+	exportCSVCmd.Flags().StringVarP(&exportCSVDir, "dir", "d", "inventory", "Directory to write the CSV files to")
+	addIAMFlags(exportCSVCmd)
+
+	exportTablesCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	exportTablesCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(exportTablesCmd)
+	exportTablesCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to export (exports all VPCs if not provided)")
+	exportTablesCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	exportTablesCmd.Flags().StringVarP(&exportStateFile, "from-state", "f", "", "Export a working state file instead of scanning")
+	exportTablesCmd.Flags().StringVarP(&exportTablesDir, "dir", "d", "tables", "Directory to write the table files to")
+	exportTablesCmd.Flags().StringVar(&exportSchema, "schema", export.SchemaCloudQuery, "Table schema: cloudquery, steampipe")
+	addIAMFlags(exportTablesCmd)
+}
+
+func runExportTerraform(ctx context.Context) error {
+	network, err := loadOrScanNetwork(ctx, exportStateFile)
+	if err != nil {
+		return err
+	}
+
+	config := export.NewTerraformGenerator(network).Generate()
+
+	if exportOutputFile == "" {
+		fmt.Print(config)
+		return nil
+	}
+
+	if err := os.WriteFile(exportOutputFile, []byte(config), 0644); err != nil {
+		return fmt.Errorf("failed to write Terraform file %s: %w", exportOutputFile, err)
+	}
+
+	logger.Debug("Terraform configuration written", "file", exportOutputFile)
+	return nil
+}
+
+func runExportCSV(ctx context.Context) error {
+	network, err := loadOrScanNetwork(ctx, exportStateFile)
+	if err != nil {
+		return err
+	}
+
+	files, err := export.NewCSVExporter(network).WriteDir(exportCSVDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d CSV files to %s\n", len(files), exportCSVDir)
+	return nil
+}
+
+func runExportTables(ctx context.Context) error {
+	// Checked before scanning so a typo does not waste a scan
+	if exportSchema != export.SchemaCloudQuery && exportSchema != export.SchemaSteampipe {
+		return fmt.Errorf("unsupported table schema %q, expected %s or %s", exportSchema, export.SchemaCloudQuery, export.SchemaSteampipe)
+	}
+	network, err := loadOrScanNetwork(ctx, exportStateFile)
+	if err != nil {
+		return err
+	}
+
+	files, err := export.NewTablesExporter(network, exportSchema).WriteDir(exportTablesDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d %s tables to %s\n", len(files), exportSchema, exportTablesDir)
+	return nil
+}
+
+// loadOrScanNetwork loads a working state file, or scans the network when no file is given
+func loadOrScanNetwork(ctx context.Context, stateFile string) (*scanner.Network, error) {
+	if stateFile != "" {
+		return watch.NewComparator(verbose).LoadWorkingState(stateFile)
+	}
+
+	awsClient, err := newAWSClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+
+	networkScanner := scanner.NewNetworkScanner(awsClient)
+	networkScanner.SetVerbose(verbose)
+	networkScanner.SetLogger(logger)
+	networkScanner.SetProgress(scanProgress())
+	networkScanner.SetScanIAM(withIAM)
+	networkScanner.SetAllIAMRoles(allIAMRoles)
+	networkScanner.SetIAMConcurrency(iamConcurrency)
+
+	network, err := networkScanner.ScanNetwork(ctx, vpcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan network: %w", err)
+	}
+	recordCompletions(network, vpcID)
+
+	return network, nil
+}
@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/flows"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/graph"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/names"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+var (
+	flowsLogGroup   string
+	flowsS3URI      string
+	flowsSince      time.Duration
+	flowsStart      string
+	flowsEnd        string
+	flowsFormat     string
+	flowsStateFile  string
+	flowsOutput     string
+	flowsOutputFile string
+	flowsTop        int
+)
+
+var flowsCmd = &cobra.Command{
+	Use:   "flows",
+	Short: "Summarise VPC Flow Log traffic and overlay it on the topology",
+	Long: `Read VPC Flow Logs from a CloudWatch Logs log group or an S3 bucket for a time
+window and aggregate the observed traffic: top talkers per network interface, subnet
+and security group, traffic between subnets and the internet, and how many accepted
+flows each security group rule allowed, so unused rules stand out.
+
+With -o dot, mermaid or html the observed traffic is drawn over the topology as
+edges weighted by volume. The network is scanned live unless --from-state names a
+saved working state. Records must use the default flow log format unless --format
+gives the custom one.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runFlows(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(flowsCmd)
+
+	flowsCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	flowsCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(flowsCmd)
+	flowsCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to scan (scans all VPCs if not provided)")
+	flowsCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	flowsCmd.Flags().DurationVar(&flowsSince, "since", time.Hour, "Read the traffic of this long before now")
+	addFlowLogFlags(flowsCmd, "--since")
+	flowsCmd.Flags().StringVarP(&flowsStateFile, "from-state", "f", "", "Overlay on a working state file instead of scanning")
+	flowsCmd.Flags().StringVarP(&flowsOutput, "output", "o", "text", "Output format: text, json, dot, mermaid, html")
+	flowsCmd.Flags().StringVar(&flowsOutputFile, "out", "", "Write the output to this file instead of stdout")
+	flowsCmd.Flags().IntVar(&flowsTop, "top", 10, "Number of top talkers and traffic edges to show (0 for all)")
+	addDetailFlag(flowsCmd)
+	addLayoutFlag(flowsCmd)
+	addLegendFlag(flowsCmd)
+	addIconsFlag(flowsCmd)
+	addViewFlags(flowsCmd)
+	addNameFlags(flowsCmd)
+}
+
+// addFlowLogFlags registers the flow log source and time window flags on a command.
+// The command registers its own window length flag, named by lengthFlag.
+func addFlowLogFlags(cmd *cobra.Command, lengthFlag string) {
+	cmd.Flags().StringVar(&flowsLogGroup, "log-group", "", "CloudWatch Logs log group the flow logs are delivered to")
+	cmd.Flags().StringVar(&flowsS3URI, "s3", "", "S3 location the flow logs are delivered to, e.g. s3://bucket/AWSLogs/")
+	cmd.Flags().StringVar(&flowsStart, "start", "", "Start of the time window (RFC 3339), instead of "+lengthFlag)
+	cmd.Flags().StringVar(&flowsEnd, "end", "", "End of the time window (RFC 3339, defaults to now)")
+	cmd.Flags().StringVar(&flowsFormat, "format", flows.DefaultFormat, "Flow log record format, as field names in order")
+}
+
+// flowsWindow returns the time window ending at --end (or now) and lasting length,
+// or starting at --start when given
+func flowsWindow(length time.Duration) (time.Time, time.Time, error) {
+	end := time.Now()
+	if flowsEnd != "" {
+		parsed, err := time.Parse(time.RFC3339, flowsEnd)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --end time: %w", err)
+		}
+		end = parsed
+	}
+
+	start := end.Add(-length)
+	if flowsStart != "" {
+		parsed, err := time.Parse(time.RFC3339, flowsStart)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --start time: %w", err)
+		}
+		start = parsed
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the time window start %s is not before its end %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return start, end, nil
+}
+
+func runFlows(ctx context.Context) error {
+	if err := validateFlowLogFlags(); err != nil {
+		return err
+	}
+	detailLevel, err := graph.ParseDetailLevel(detail)
+	if err != nil {
+		return err
+	}
+	switch flowsOutput {
+	case "text", "json", "dot", "mermaid", "html":
+	default:
+		return fmt.Errorf("unsupported output format: %s", flowsOutput)
+	}
+	layout, err := parseGraphLayout(flowsOutput)
+	if err != nil {
+		return err
+	}
+	if err := validateLegend(flowsOutput); err != nil {
+		return err
+	}
+	icons, err := loadGraphIcons(flowsOutput)
+	if err != nil {
+		return err
+	}
+	view, err := parseViewFlags()
+	if err != nil {
+		return err
+	}
+
+	start, end, err := flowsWindow(flowsSince)
+	if err != nil {
+		return err
+	}
+
+	network, err := loadOrScanNetwork(ctx, flowsStateFile)
+	if err != nil {
+		return err
+	}
+
+	providers, err := nameProviders()
+	if err != nil {
+		return err
+	}
+	if err := names.Apply(ctx, network, providers); err != nil {
+		return fmt.Errorf("failed to resolve resource names: %w", err)
+	}
+
+	report, err := readFlowReport(ctx, network, start, end, flowsTop)
+	if err != nil {
+		return err
+	}
+
+	var result string
+	switch flowsOutput {
+	case "text":
+		result = report.Text()
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal flow report: %w", err)
+		}
+		result = string(data) + "\n"
+	default:
+		visualizer := graph.NewVisualizer(flowsOutput)
+		visualizer.SetDetailLevel(detailLevel)
+		visualizer.SetLayout(layout)
+		visualizer.SetLegend(graphLegend)
+		visualizer.SetIcons(icons)
+		view.apply(visualizer)
+		visualizer.SetToolVersion(toolVersion)
+		visualizer.SetColor(flowsOutputFile == "" && colorOutput())
+		visualizer.SetObservedFlows(observedFlows(report.Edges))
+		result, err = visualizer.Generate(network)
+		if err != nil {
+			return fmt.Errorf("failed to generate visualization: %w", err)
+		}
+	}
+
+	if flowsOutputFile == "" {
+		fmt.Print(result)
+		return nil
+	}
+
+	if err := os.WriteFile(flowsOutputFile, []byte(result), 0644); err != nil {
+		return fmt.Errorf("failed to write flow report to %s: %w", flowsOutputFile, err)
+	}
+	logger.Debug("flow report written", "file", flowsOutputFile)
+	return nil
+}
+
+// validateFlowLogFlags checks that exactly one flow log source is given
+func validateFlowLogFlags() error {
+	if (flowsLogGroup == "") == (flowsS3URI == "") {
+		return fmt.Errorf("exactly one of --log-group or --s3 is required")
+	}
+	return nil
+}
+
+// readFlowReport reads the flow logs named by the flow log flags for a time window
+// and aggregates them against the network
+func readFlowReport(ctx context.Context, network *scanner.Network, start, end time.Time, top int) (flows.Report, error) {
+	parser, err := flows.NewParser(flowsFormat)
+	if err != nil {
+		return flows.Report{}, err
+	}
+
+	awsClient, err := newAWSClient(ctx)
+	if err != nil {
+		return flows.Report{}, fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+
+	var source flows.Source = flows.NewCloudWatchSource(awsClient.CloudWatchLogs, flowsLogGroup)
+	if flowsS3URI != "" {
+		source, err = flows.NewS3Source(awsClient.S3, flowsS3URI)
+		if err != nil {
+			return flows.Report{}, err
+		}
+	}
+
+	// Records are kept until the interfaces they were captured on are looked up
+	var records []flows.Record
+	interfaceIDs := make(map[string]bool)
+	var invalid int
+	err = source.Read(ctx, start, end, func(line string) error {
+		record, ok, err := parser.Parse(line)
+		if err != nil {
+			invalid++
+			logger.Debug("skipped invalid flow log record", "error", err)
+			return nil
+		}
+		if ok && record.Within(start, end) {
+			records = append(records, record)
+			interfaceIDs[record.InterfaceID] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return flows.Report{}, err
+	}
+	if invalid > 0 {
+		logger.Warn("skipped flow log records that did not match the flow log format", "count", invalid)
+	}
+
+	// Interfaces recorded in the scan are only looked up when they are missing
+	interfaces := flows.InterfacesFromNetwork(network)
+	ids := make([]string, 0, len(interfaceIDs))
+	for id := range interfaceIDs {
+		if _, ok := interfaces[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	looked, err := flows.LookupInterfaces(ctx, awsClient.EC2, ids)
+	if err != nil {
+		// Without interfaces, traffic is still attributed to subnets by address
+		logger.Debug("failed to describe network interfaces, security group rule usage is unavailable for interfaces created since the scan", "error", err)
+	}
+	for id, eni := range looked {
+		interfaces[id] = eni
+	}
+
+	aggregator := flows.NewAggregator(network, interfaces)
+	for _, record := range records {
+		aggregator.Add(record)
+	}
+	return aggregator.Report(start, end, top), nil
+}
+
+// observedFlows converts traffic edges into graph edges weighted against the heaviest one
+func observedFlows(edges []flows.Edge) []graph.ObservedFlow {
+	var heaviest int64
+	for _, edge := range edges {
+		if edge.Bytes > heaviest {
+			heaviest = edge.Bytes
+		}
+	}
+
+	var observed []graph.ObservedFlow
+	for _, edge := range edges {
+		flow := graph.ObservedFlow{
+			From:  edge.From,
+			To:    edge.To,
+			Label: flows.FormatBytes(edge.Bytes),
+		}
+		if heaviest > 0 {
+			flow.Weight = float64(edge.Bytes) / float64(heaviest)
+		}
+		observed = append(observed, flow)
+	}
+	return observed
+}
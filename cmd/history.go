@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/sgaudit"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+var (
+	historyLogFile      string
+	historySince        string
+	historyUntil        string
+	historyResourceType string
+	historyResourceID   string
+	historyOutput       string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Query the drift log written by watch --history",
+	Long: `Show the differences recorded in a drift log written by 'watch --history', oldest
+first, to see exactly when each change appeared. Filter by time window with --since
+and --until, which take a timestamp (2024-01-15T10:00:00Z) or a period before now
+(36h, 7d), and by resource with --resource-type and --resource-id.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runHistory()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVar(&historyLogFile, "history", "drift.jsonl", "Drift log to query")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Only differences detected at or after this time or period before now")
+	historyCmd.Flags().StringVar(&historyUntil, "until", "", "Only differences detected at or before this time or period before now")
+	historyCmd.Flags().StringVar(&historyResourceType, "resource-type", "", "Only differences of this resource type (e.g. SecurityGroup)")
+	historyCmd.Flags().StringVar(&historyResourceID, "resource-id", "", "Only differences of this resource ID")
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", "text", "Output format: text, json")
+}
+
+func runHistory() error {
+	filter := watch.HistoryFilter{ResourceType: historyResourceType, ResourceID: historyResourceID}
+
+	var err error
+	if filter.Since, err = parseHistoryTime(historySince); err != nil {
+		return err
+	}
+	if filter.Until, err = parseHistoryTime(historyUntil); err != nil {
+		return err
+	}
+
+	events, err := watch.ReadHistory(historyLogFile, filter)
+	if err != nil {
+		return err
+	}
+
+	switch historyOutput {
+	case "text":
+		if len(events) == 0 {
+			fmt.Println("No drift recorded")
+			return nil
+		}
+		fmt.Print(watch.FormatHistory(events))
+	case "json":
+		if events == nil {
+			events = []watch.DriftEvent{}
+		}
+		data, err := json.MarshalIndent(events, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal drift history: %w", err)
+		}
+		fmt.Println(string(data))
+	default:
+		return fmt.Errorf("unsupported output format: %s", historyOutput)
+	}
+
+	return nil
+}
+
+// parseHistoryTime parses an RFC 3339 timestamp or a period before now such as
+// 36h or 7d. An empty value is the zero time.
+func parseHistoryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	period, err := sgaudit.ParsePeriod(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected an RFC 3339 timestamp or a period such as 36h or 7d", value)
+	}
+	return time.Now().Add(-period), nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/iampolicy"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+var (
+	withIAM        bool
+	allIAMRoles    bool
+	iamConcurrency int
+
+	iamStateFile string
+	iamOutput    string
+)
+
+var iamCmd = &cobra.Command{
+	Use:   "iam",
+	Short: "Analyze scanned IAM roles and policies",
+}
+
+var iamSummarizeCmd = &cobra.Command{
+	Use:   "summarize",
+	Short: "Report roles with risky permissions",
+	Long: `Parse the attached and inline policies of IAM roles and report the roles with
+admin-equivalent permissions (every IAM action on every resource), iam:PassRole on
+wildcard resources, or network-mutating actions such as ec2:CreateRoute and
+ec2:AuthorizeSecurityGroupIngress.
+
+Only Allow statements are considered: Deny statements, permission boundaries and
+conditions are not evaluated, so a finding is what a role may be able to do.
+
+Roles are scanned live unless --from-state names a working state saved with
+--with-iam.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runIAMSummarize(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(iamCmd)
+	iamCmd.AddCommand(iamSummarizeCmd)
+
+	iamSummarizeCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	iamSummarizeCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(iamSummarizeCmd)
+	iamSummarizeCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	iamSummarizeCmd.Flags().StringVarP(&iamStateFile, "from-state", "f", "", "Summarize a working state file instead of scanning")
+	iamSummarizeCmd.Flags().StringVarP(&iamOutput, "output", "o", "text", "Output format: text, json")
+	iamSummarizeCmd.Flags().BoolVar(&allIAMRoles, "all-iam-roles", false, "Summarize every IAM role in the account, not only those assumable by EC2, ECS, EKS or Lambda")
+}
+
+// addIAMFlags registers the IAM scanning flags on a command
+func addIAMFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&withIAM, "with-iam", false, "Also scan IAM roles assumable by EC2, ECS, EKS or Lambda and their policies")
+	cmd.Flags().BoolVar(&allIAMRoles, "all-iam-roles", false, "With --with-iam, scan every IAM role in the account")
+	cmd.Flags().IntVar(&iamConcurrency, "iam-concurrency", 0, "With --with-iam, how many roles to fetch policies for at once (default 8)")
+}
+
+func runIAMSummarize(ctx context.Context) error {
+	roles, err := loadOrScanIAMRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	report := iampolicy.Summarize(roles)
+
+	switch iamOutput {
+	case "text":
+		fmt.Print(report.Text())
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal IAM summary: %w", err)
+		}
+		fmt.Println(string(data))
+	default:
+		return fmt.Errorf("unsupported output format: %s", iamOutput)
+	}
+
+	return nil
+}
+
+// loadOrScanIAMRoles reads the IAM roles of a working state, or scans them
+func loadOrScanIAMRoles(ctx context.Context) ([]scanner.IAMRole, error) {
+	if iamStateFile != "" {
+		network, err := watch.NewComparator(verbose).LoadWorkingState(iamStateFile)
+		if err != nil {
+			return nil, err
+		}
+		return network.IAMRoles, nil
+	}
+
+	awsClient, err := newAWSClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+
+	networkScanner := scanner.NewNetworkScanner(awsClient)
+	networkScanner.SetVerbose(verbose)
+	networkScanner.SetLogger(logger)
+	networkScanner.SetProgress(scanProgress())
+	networkScanner.SetScanIAM(true)
+	networkScanner.SetAllIAMRoles(allIAMRoles)
+	networkScanner.SetIAMConcurrency(iamConcurrency)
+
+	// Rescanning only IAM roles against an empty scan skips the network resources
+	network, err := networkScanner.RescanNetwork(ctx, "", &scanner.Network{}, []string{"iam_role"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan IAM roles: %w", err)
+	}
+	return network.IAMRoles, nil
+}
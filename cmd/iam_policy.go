@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/iampolicy"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+var (
+	iamPolicyAll        bool
+	iamPolicyOutputFile string
+)
+
+var iamPolicyCmd = &cobra.Command{
+	Use:   "iam-policy",
+	Short: "Print the least-privilege IAM policy a scan needs",
+	Long: `Print an IAM policy document that allows exactly the API actions a scan with the
+same flags calls, so scanning identities can be granted scoped read access.
+Actions of optional features are included when their flags are given, such as
+--with-iam or --containers, or all of them with --all.
+
+No AWS credentials are needed. Check that an identity has the actions with doctor.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		networkScanner := scanner.NewNetworkScannerFromAPIs("", scanner.APIs{})
+		networkScanner.SetScanIAM(withIAM || iamPolicyAll)
+		networkScanner.SetScanContainers(scanContainers || iamPolicyAll)
+		networkScanner.SetScanDatabases(scanDatabases || iamPolicyAll)
+		networkScanner.SetScanEdge(scanEdge || iamPolicyAll)
+		networkScanner.SetScanAppMesh(scanAppMesh || iamPolicyAll)
+
+		document := iampolicy.Document{
+			Version: "2012-10-17",
+			Statement: []iampolicy.Statement{{
+				Sid:      "PikaatoolsScan",
+				Effect:   "Allow",
+				Action:   networkScanner.Actions(),
+				Resource: iampolicy.StringList{"*"},
+			}},
+		}
+		data, err := json.MarshalIndent(document, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal IAM policy: %w", err)
+		}
+		data = append(data, '\n')
+
+		if iamPolicyOutputFile == "" {
+			fmt.Print(string(data))
+			return nil
+		}
+		if err := os.WriteFile(iamPolicyOutputFile, data, 0644); err != nil {
+			return fmt.Errorf("failed to write IAM policy: %w", err)
+		}
+		fmt.Printf("IAM policy written to: %s\n", iamPolicyOutputFile)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(iamPolicyCmd)
+
+	addIAMFlags(iamPolicyCmd)
+	iamPolicyCmd.Flags().BoolVar(&scanAppMesh, "app-mesh", false, "Include the App Mesh actions")
+	iamPolicyCmd.Flags().BoolVar(&scanContainers, "containers", false, "Include the EKS and ECS actions")
+	iamPolicyCmd.Flags().BoolVar(&scanDatabases, "databases", false, "Include the RDS, ElastiCache and Redshift actions")
+	iamPolicyCmd.Flags().BoolVar(&scanEdge, "edge", false, "Include the Elastic IP, load balancer, Global Accelerator and CloudFront actions")
+	iamPolicyCmd.Flags().BoolVar(&iamPolicyAll, "all", false, "Include the actions of every optional feature")
+	iamPolicyCmd.Flags().StringVar(&iamPolicyOutputFile, "out", "", "Write the policy to this file instead of stdout")
+}
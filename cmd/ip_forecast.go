@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/capacity"
+)
+
+var (
+	ipForecastStateFile      string
+	ipForecastSnapshots      []string
+	ipForecastEnvironmentTag string
+	ipForecastWarning        float64
+	ipForecastCritical       float64
+	ipForecastHorizon        int
+	ipForecastOutput         string
+)
+
+var ipForecastCmd = &cobra.Command{
+	Use:   "ip-forecast",
+	Short: "Predict which subnets will run out of IP addresses",
+	Long: `Report the IPv4 address utilization of every subnet and, from the usage recorded
+in earlier working states given with --snapshots, the daily growth and the date each
+subnet is expected to run out of addresses.
+
+A subnet is a warning when its utilization reaches --warning or it is expected to run
+out within --horizon days, and critical when its utilization reaches --critical or it
+is full. Limits per environment, found from the subnet's or its VPC's Environment
+tag, are set under capacity-thresholds in the config file.
+
+The network is scanned live unless --from-state names a saved working state.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runIPForecast(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ipForecastCmd)
+
+	ipForecastCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	ipForecastCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(ipForecastCmd)
+	ipForecastCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to forecast (forecasts all VPCs if not provided)")
+	ipForecastCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	ipForecastCmd.Flags().StringVarP(&ipForecastStateFile, "from-state", "f", "", "Forecast from a working state file instead of scanning")
+	ipForecastCmd.Flags().StringSliceVar(&ipForecastSnapshots, "snapshots", nil, "Earlier working state files, or directories of them, to forecast growth from")
+	ipForecastCmd.Flags().StringVar(&ipForecastEnvironmentTag, "environment-tag", "Environment", "Tag naming the environment of a subnet or VPC")
+	ipForecastCmd.Flags().Float64Var(&ipForecastWarning, "warning", 80, "Utilization percentage that raises a warning")
+	ipForecastCmd.Flags().Float64Var(&ipForecastCritical, "critical", 95, "Utilization percentage that is critical")
+	ipForecastCmd.Flags().IntVar(&ipForecastHorizon, "horizon", 30, "Warn about subnets expected to run out within this many days")
+	ipForecastCmd.Flags().StringVarP(&ipForecastOutput, "output", "o", "text", "Output format: text, json")
+}
+
+func runIPForecast(ctx context.Context) error {
+	thresholds := capacity.Thresholds{
+		Default: capacity.Limits{Warning: ipForecastWarning, Critical: ipForecastCritical, HorizonDays: ipForecastHorizon},
+	}
+	if err := thresholds.Default.Validate(); err != nil {
+		return err
+	}
+	if configFile != nil {
+		thresholds.Environments = configFile.CapacityThresholds
+	}
+	if ipForecastOutput != "text" && ipForecastOutput != "json" {
+		return fmt.Errorf("unsupported output format: %s", ipForecastOutput)
+	}
+
+	history, err := capacity.LoadHistory(ipForecastSnapshots)
+	if err != nil {
+		return err
+	}
+	logger.Debug("loaded snapshots", "count", len(history))
+
+	network, err := loadOrScanNetwork(ctx, ipForecastStateFile)
+	if err != nil {
+		return err
+	}
+	if ipForecastStateFile != "" && vpcID != "" {
+		network = network.ForVPCs([]string{vpcID})
+	}
+
+	report := capacity.Forecast(network, history, thresholds, ipForecastEnvironmentTag)
+
+	if ipForecastOutput == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal IP forecast: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print(report.Text())
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/logging"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+var (
+	logFormat    string
+	quiet        bool
+	showProgress bool
+
+	// logger receives diagnostics; command output goes to stdout
+	logger = slog.Default()
+)
+
+// addLoggingFlags registers the logging flags shared by every command
+func addLoggingFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "Log format for diagnostics on stderr: text, json")
+	cmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only log errors")
+	cmd.PersistentFlags().BoolVar(&showProgress, "progress", false, "Show a progress bar of the resource types scanned on stderr")
+}
+
+// setupLogging creates the logger for the logging flags once the command's flags are parsed
+func setupLogging(cmd *cobra.Command, args []string) error {
+	if verbose && quiet {
+		return fmt.Errorf("--verbose and --quiet cannot be used together")
+	}
+
+	var err error
+	logger, err = logging.New(os.Stderr, logFormat, logging.Level(verbose, quiet))
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// scanProgress returns the progress bar for --progress, or nil when it is off
+func scanProgress() scanner.ProgressReporter {
+	if !showProgress || quiet {
+		return nil
+	}
+	return logging.NewProgressBar(os.Stderr)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/aws"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Sign in to IAM Identity Center (AWS SSO) for a profile",
+	Long: `Sign in to IAM Identity Center for a profile configured with sso_session or
+sso_start_url, using the device authorization flow. Open the printed link, check that
+the code matches and approve the sign-in; the token is cached in ~/.aws/sso/cache,
+where the AWS CLI and SDKs find it too.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runLogin(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+
+	loginCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile to sign in for (defaults to AWS_PROFILE or the default profile)")
+	loginCmd.Flags().StringVar(&endpointURL, "endpoint-url", "", "Send sign-in calls to this endpoint instead of IAM Identity Center")
+}
+
+func runLogin(ctx context.Context) error {
+	sso, err := aws.LoadSSOProfile(ctx, profile)
+	if err != nil {
+		return err
+	}
+	if sso == nil {
+		return fmt.Errorf("profile %q is not configured for IAM Identity Center (sso_session or sso_start_url)", profileName())
+	}
+	return ssoLogin(ctx, sso)
+}
+
+// ssoLogin runs the device authorization flow for a profile, printing the sign-in link
+func ssoLogin(ctx context.Context, sso *aws.SSOProfile) error {
+	err := aws.SSOLogin(ctx, sso, endpointURL, func(verificationURL, userCode string) {
+		// Prompts go to stderr so they do not mix with a command's output
+		fmt.Fprintf(os.Stderr, "Signing in to %s for profile %s.\n", sso.StartURL, sso.Profile)
+		fmt.Fprintf(os.Stderr, "Open %s and check that it shows the code %s\n", verificationURL, userCode)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Signed in for profile %s\n", sso.Profile)
+	return nil
+}
+
+// profileName returns the profile name for messages
+func profileName() string {
+	if profile != "" {
+		return profile
+	}
+	return "default"
+}
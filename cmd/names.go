@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/names"
+)
+
+var (
+	namesCSVFiles []string
+	namesURLs     []string
+)
+
+// addNameFlags registers the name-resolution provider flags on a command
+func addNameFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&namesCSVFiles, "names-csv", nil, "CSV file of id,name pairs used to show friendly resource names")
+	cmd.Flags().StringSliceVar(&namesURLs, "names-url", nil, "HTTP endpoint (CMDB, IPAM) resolving ?ids=... to a JSON map of friendly names")
+}
+
+// nameProviders builds the name-resolution providers from the command flags.
+// CSV files are consulted before HTTP endpoints, each in the order given.
+func nameProviders() ([]names.Provider, error) {
+	var providers []names.Provider
+
+	for _, filename := range namesCSVFiles {
+		provider, err := names.NewCSVProvider(filename)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	for _, endpoint := range namesURLs {
+		providers = append(providers, names.NewHTTPProvider(endpoint))
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/policy"
+)
+
+var (
+	policyRules     string
+	policyStateFile string
+	policyOutput    string
+	policyFailOn    string
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Check the network against policy rules",
+}
+
+var policyEvalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Evaluate policy rules and report violations",
+	Long: `Evaluate user-defined rules against the scanned network and report every resource
+that violates them, such as a public subnet in a prod VPC or a security group without
+a Description tag. Rules are YAML files selecting a resource type, the resources a
+rule applies to (when) and the conditions they must meet (assert).
+
+Exits with code 0 when every resource complies, 1 when a violation at or above the
+--fail-on severity is found and 2 when the rules could not be evaluated, so it can
+gate CI/CD deployments.
+
+The network is scanned live unless --from-state names a saved working state.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		violated, err := runPolicyEval(cmd.Context())
+		if err != nil {
+			return &ExitCodeError{Code: ExitError, Err: err}
+		}
+		if violated {
+			return &ExitCodeError{Code: ExitDifferences}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(policyCmd)
+	policyCmd.AddCommand(policyEvalCmd)
+
+	policyEvalCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	policyEvalCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(policyEvalCmd)
+	policyEvalCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to evaluate (evaluates all VPCs if not provided)")
+	policyEvalCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	policyEvalCmd.Flags().StringVar(&policyRules, "rules", "", "Rules file, or directory of .yaml rule files")
+	policyEvalCmd.Flags().StringVarP(&policyStateFile, "from-state", "f", "", "Evaluate a working state file instead of scanning")
+	policyEvalCmd.Flags().StringVarP(&policyOutput, "output", "o", "text", "Output format: text, json")
+	policyEvalCmd.Flags().StringVar(&policyFailOn, "fail-on", "low", "Lowest violation severity that fails the run: low, medium, high")
+	addIAMFlags(policyEvalCmd)
+}
+
+// runPolicyEval evaluates the rules and reports whether the run should fail
+func runPolicyEval(ctx context.Context) (bool, error) {
+	// Checked here rather than as a required flag so a missing flag exits with ExitError
+	if policyRules == "" {
+		return false, fmt.Errorf("--rules is required")
+	}
+	threshold, err := policy.ParseSeverity(policyFailOn)
+	if err != nil {
+		return false, err
+	}
+	if policyOutput != "text" && policyOutput != "json" {
+		return false, fmt.Errorf("unsupported output format: %s", policyOutput)
+	}
+
+	rules, err := policy.Load(policyRules)
+	if err != nil {
+		return false, err
+	}
+
+	network, err := loadOrScanNetwork(ctx, policyStateFile)
+	if err != nil {
+		return false, err
+	}
+	if policyStateFile != "" && vpcID != "" {
+		network = network.ForVPCs([]string{vpcID})
+	}
+
+	resources, err := policy.Resources(network)
+	if err != nil {
+		return false, err
+	}
+	report := policy.Evaluate(rules, resources)
+
+	if policyOutput == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal policy report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(report.Text())
+	}
+
+	return report.Violated(threshold), nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/preview"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+var (
+	previewPlan       string
+	previewTemplate   string
+	previewStackName  string
+	previewParameters map[string]string
+	previewStateFile  string
+)
+
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Preview how a Terraform plan or CloudFormation template changes the network",
+	Long: `Apply the changes of a Terraform plan or CloudFormation template to the scanned
+network and print the differences, showing what the network will look like after
+the change is deployed.
+
+A Terraform plan is read as JSON, converted with:
+
+  terraform plan -out plan.out && terraform show -json plan.out > plan.json
+
+A CloudFormation template is read as JSON or YAML. With --stack-name, resources the
+stack already has are updated and those the template no longer declares are deleted;
+otherwise every resource is created. Parameters take their defaults unless set with
+--parameter.
+
+Created resources are named by their Terraform address or logical ID, and values only
+known once deployed are shown as "(known after apply)". Resources of types pikaatools
+does not scan are listed as skipped.
+
+Exits with code 0 when the change leaves the network as it is, 1 when it changes it
+and 2 when the preview could not be built. The network is scanned live unless
+--from-state names a saved working state.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		changed, err := runPreview(cmd.Context())
+		if err != nil {
+			return &ExitCodeError{Code: ExitError, Err: err}
+		}
+		if changed {
+			return &ExitCodeError{Code: ExitDifferences}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(previewCmd)
+
+	previewCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	previewCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(previewCmd)
+	previewCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	previewCmd.Flags().StringVar(&previewPlan, "plan", "", "Terraform plan in JSON, from terraform show -json")
+	previewCmd.Flags().StringVar(&previewTemplate, "template", "", "CloudFormation template in JSON or YAML")
+	previewCmd.Flags().StringVar(&previewStackName, "stack-name", "", "With --template, the stack the template updates")
+	previewCmd.Flags().StringToStringVar(&previewParameters, "parameter", nil, "With --template, a parameter value as Name=value (repeatable)")
+	previewCmd.Flags().StringVarP(&previewStateFile, "from-state", "f", "", "Preview against a working state file instead of scanning")
+	previewCmd.Flags().StringVar(&ignoreFile, "ignore-file", "", "Suppression rules file (defaults to .pikaaignore.yaml if present)")
+	previewCmd.Flags().StringVar(&diffOutput, "diff-output", "", "Structured diff output format: json, junit, sarif (default colored text)")
+
+	previewCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &ExitCodeError{Code: ExitError, Err: err}
+	})
+}
+
+func runPreview(ctx context.Context) (bool, error) {
+	if err := validateDiffOutput(diffOutput); err != nil {
+		return false, err
+	}
+	// Checked here rather than with flag groups so mistakes exit with ExitError
+	if (previewPlan == "") == (previewTemplate == "") {
+		return false, fmt.Errorf("exactly one of --plan and --template is required")
+	}
+	if previewPlan != "" && (previewStackName != "" || len(previewParameters) > 0) {
+		return false, fmt.Errorf("--stack-name and --parameter can only be used with --template")
+	}
+
+	source := previewPlan
+	if source == "" {
+		source = previewTemplate
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	ignoreRules, err := loadIgnoreRules(ignoreFile)
+	if err != nil {
+		return false, err
+	}
+
+	network, err := loadOrScanNetwork(ctx, previewStateFile)
+	if err != nil {
+		return false, err
+	}
+
+	var result *preview.Preview
+	if previewPlan != "" {
+		result, err = preview.FromTerraformPlan(network, data)
+	} else {
+		result, err = preview.FromCloudFormation(network, data, previewStackName, previewParameters)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	comparator := watch.NewComparator(verbose)
+	comparator.SetIgnoreRules(ignoreRules)
+	differences := comparator.Compare(network, result.Network)
+
+	if diffOutput != "" {
+		report, err := watch.FormatDifferences(differences, diffOutput)
+		if err != nil {
+			return false, err
+		}
+		fmt.Print(report)
+		return len(differences) > 0, nil
+	}
+
+	fmt.Printf("Previewing %s\n\n", source)
+	comparator.PrintDifferences(differences)
+	if len(result.Skipped) > 0 {
+		fmt.Printf("\nSkipped %d resources of types pikaatools does not scan:\n", len(result.Skipped))
+		for _, address := range result.Skipped {
+			fmt.Printf("  %s\n", address)
+		}
+	}
+	return len(differences) > 0, nil
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/graph"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/names"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+var (
+	renderStateFile  string
+	renderOutput     string
+	renderOutputFile string
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render a saved working state without scanning",
+	Long: `Regenerate a visualization from a saved JSON working state, without AWS
+credentials or a re-scan. Output formats are text, table (aligned inventory tables
+with columns chosen by --columns), dot (Graphviz), mermaid and html (a standalone
+page with a summary, the Mermaid diagram and the text tree).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRender(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().StringVarP(&renderStateFile, "file", "f", "working_state.json", "Working state file to render")
+	renderCmd.Flags().StringVarP(&renderOutput, "output", "o", "text", "Output format: "+strings.Join(graph.Formats(), ", "))
+	renderCmd.Flags().StringVar(&renderOutputFile, "out", "", "Write the visualization to this file instead of stdout")
+	addDetailFlag(renderCmd)
+	addColumnsFlag(renderCmd)
+	addLayoutFlag(renderCmd)
+	addLegendFlag(renderCmd)
+	addIconsFlag(renderCmd)
+	addViewFlags(renderCmd)
+	renderCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	addNameFlags(renderCmd)
+}
+
+// addDetailFlag registers the --detail flag of commands that draw the network graph.
+// A bare --detail selects the full level.
+func addDetailFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&detail, "detail", string(graph.DetailNormal), "Detail level of the graph: minimal, normal, full (route tables, security group rules and connectivity limits)")
+	cmd.Flags().Lookup("detail").NoOptDefVal = string(graph.DetailFull)
+}
+
+// addColumnsFlag registers the --columns flag of commands with table output
+func addColumnsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&tableColumns, "columns", nil, "Table output columns, as table=column,... or column,... for every table (repeatable)")
+}
+
+// addLayoutFlag registers the --layout flag of commands with DOT output
+func addLayoutFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&graphLayout, "layout", string(graph.LayoutHierarchy), "DOT output layout: hierarchy, az-columns (subnets in a column per availability zone), hub-spoke (transit gateways as the hub of their VPCs)")
+}
+
+// parseGraphLayout parses --layout, which only applies to DOT output
+func parseGraphLayout(format string) (graph.Layout, error) {
+	layout, err := graph.ParseLayout(graphLayout)
+	if err != nil {
+		return "", err
+	}
+	if layout != graph.LayoutHierarchy && format != "dot" {
+		return "", fmt.Errorf("--layout can only be used with --output dot")
+	}
+	return layout, nil
+}
+
+// addLegendFlag registers the --legend flag of commands with DOT or HTML output
+func addLegendFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&graphLegend, "legend", false, "Add a legend and a footer with the account, region, scan time and pikaatools version to DOT and HTML output")
+}
+
+// validateLegend checks that --legend is only used with DOT or HTML output
+func validateLegend(format string) error {
+	if graphLegend && format != "dot" && format != "html" {
+		return fmt.Errorf("--legend can only be used with --output dot or html")
+	}
+	return nil
+}
+
+// addIconsFlag registers the --icons flag of commands with DOT output
+func addIconsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&graphIcons, "icons", "", "Directory of AWS Architecture Icons (or <kind>.png files) to draw DOT nodes with")
+}
+
+// loadGraphIcons loads the --icons directory, which only applies to DOT output
+func loadGraphIcons(format string) (graph.IconSet, error) {
+	if graphIcons == "" {
+		return nil, nil
+	}
+	if format != "dot" {
+		return nil, fmt.Errorf("--icons can only be used with --output dot")
+	}
+	icons, err := graph.LoadIcons(graphIcons)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load icons from %s: %w", graphIcons, err)
+	}
+	return icons, nil
+}
+
+// addViewFlags registers the flags that limit the graph to part of the network
+func addViewFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&graphFocus, "focus", "", "Only draw this VPC and what is within --depth hops of it through peering connections and transit gateways")
+	cmd.Flags().IntVar(&graphDepth, "depth", 1, "Hops from the --focus VPC to draw: 1 adds its peered VPCs and transit gateways, 2 also the VPCs sharing those transit gateways")
+	cmd.Flags().StringArrayVar(&graphHide, "hide", nil, "Resource kinds to leave out of the graph: subnets, igw, eigw, nat, peering, tgw, mesh, containers, databases, edge (comma separated, repeatable)")
+	cmd.Flags().StringVar(&graphColorBy, "color-by", "", "Color VPCs, subnets and gateways by the value of a tag, as tag:<key> (e.g. tag:Environment)")
+}
+
+// viewOptions are the parsed flags that choose what the graph shows
+type viewOptions struct {
+	hidden  []string
+	colorBy string
+}
+
+// parseViewFlags parses --hide and --color-by and checks --depth
+func parseViewFlags() (viewOptions, error) {
+	if graphDepth < 0 {
+		return viewOptions{}, fmt.Errorf("--depth must not be negative")
+	}
+	hidden, err := graph.ParseHidden(graphHide)
+	if err != nil {
+		return viewOptions{}, err
+	}
+	colorBy, err := graph.ParseColorBy(graphColorBy)
+	if err != nil {
+		return viewOptions{}, err
+	}
+	return viewOptions{hidden: hidden, colorBy: colorBy}, nil
+}
+
+// apply sets the view options and --focus on a visualizer
+func (o viewOptions) apply(visualizer *graph.Visualizer) {
+	visualizer.SetFocus(graphFocus, graphDepth)
+	visualizer.SetHidden(o.hidden)
+	visualizer.SetColorByTag(o.colorBy)
+}
+
+// parseTableColumns parses --columns, which only applies to table output
+func parseTableColumns(format string) (map[string][]string, error) {
+	if len(tableColumns) > 0 && format != "table" {
+		return nil, fmt.Errorf("--columns can only be used with --output table")
+	}
+	return graph.ParseColumns(tableColumns)
+}
+
+func runRender(ctx context.Context) error {
+	detailLevel, err := graph.ParseDetailLevel(detail)
+	if err != nil {
+		return err
+	}
+	columns, err := parseTableColumns(renderOutput)
+	if err != nil {
+		return err
+	}
+	layout, err := parseGraphLayout(renderOutput)
+	if err != nil {
+		return err
+	}
+	if err := validateLegend(renderOutput); err != nil {
+		return err
+	}
+	icons, err := loadGraphIcons(renderOutput)
+	if err != nil {
+		return err
+	}
+	view, err := parseViewFlags()
+	if err != nil {
+		return err
+	}
+
+	network, err := watch.NewComparator(verbose).LoadWorkingState(renderStateFile)
+	if err != nil {
+		return err
+	}
+
+	providers, err := nameProviders()
+	if err != nil {
+		return err
+	}
+	if err := names.Apply(ctx, network, providers); err != nil {
+		return fmt.Errorf("failed to resolve resource names: %w", err)
+	}
+
+	visualizer := graph.NewVisualizer(renderOutput)
+	visualizer.SetDetailLevel(detailLevel)
+	visualizer.SetColumns(columns)
+	visualizer.SetLayout(layout)
+	visualizer.SetLegend(graphLegend)
+	visualizer.SetIcons(icons)
+	view.apply(visualizer)
+	visualizer.SetToolVersion(toolVersion)
+	visualizer.SetColor(renderOutputFile == "" && colorOutput())
+	result, err := visualizer.Generate(network)
+	if err != nil {
+		return fmt.Errorf("failed to generate visualization: %w", err)
+	}
+
+	if renderOutputFile == "" {
+		fmt.Print(result)
+		return nil
+	}
+
+	if err := os.WriteFile(renderOutputFile, []byte(result), 0644); err != nil {
+		return fmt.Errorf("failed to write visualization to %s: %w", renderOutputFile, err)
+	}
+	logger.Debug("visualization written", "file", renderOutputFile)
+	return nil
+}
@@ -2,30 +2,81 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/Yiu-Kelvin/pikaatools/pkg/aws"
 	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/config"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/export"
 	"github.com/Yiu-Kelvin/pikaatools/pkg/graph"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/names"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/statefile"
 	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
 )
 
 var (
-	region       string
-	profile      string
-	vpcID        string
-	output       string
-	verbose      bool
-	exportJSON   string
-	saveState    bool
+	region         string
+	profile        string
+	vpcID          string
+	output         string
+	verbose        bool
+	exportJSON     string
+	exportSQLite   string
+	saveState      bool
+	canonical      bool
+	stripIPs       bool
+	detail         string
+	tableColumns   []string
+	graphLayout    string
+	graphLegend    bool
+	graphIcons     string
+	graphFocus     string
+	graphDepth     int
+	graphHide      []string
+	graphColorBy   string
+	toolVersion    = "dev"
+	scanAppMesh    bool
+	scanContainers bool
+	scanDatabases  bool
+	scanEdge       bool
+	tagSelectors   []string
+	scanTimeout    time.Duration
+	bestEffort     bool
 	
 	// Watch command flags
-	workingStateFile string
-	watchInterval    time.Duration
+	workingStateFile     string
+	workingStateDir      string
+	watchInterval        time.Duration
+	reloadBaseline       time.Duration
+	notifySNSArn         string
+	notifyEventBridgeBus string
+	historyFile          string
+	diffOutput           string
+	reportFile           string
+	ignoreFile           string
+	metricsAddr          string
+	watchCloudTrail      bool
+	fullScanInterval     time.Duration
+	watchResourceTypes   []string
+	notifyDesktop        bool
+	notifySlack          bool
+	notifyPagerDuty      bool
+	notifyOpsgenie       bool
+	opsgenieAPIURL       string
+	pageSeverity         string
+	reportS3URI          string
+	desktopSeverity      string
+	suggestRemediation   string
+	autoRemediatePolicy  string
+	autoRemediateDryRun  bool
+	remediationAuditLog  string
 )
 
 var rootCmd = &cobra.Command{
@@ -58,66 +109,208 @@ when changes are detected.`,
 }
 
 func init() {
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		commandName = config.CommandName(cmd.CommandPath())
+		
+		// Config file defaults apply before logging so they can set its flags
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
+		setupColor()
+		return setupLogging(cmd, args)
+	}
+	addConfigFlags(rootCmd)
+	addLoggingFlags(rootCmd)
+	addColorFlags(rootCmd)
 	rootCmd.AddCommand(scanCmd)
 	rootCmd.AddCommand(watchCmd)
 	
 	// Scan command flags
 	scanCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
 	scanCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(scanCmd)
 	scanCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to scan (scans all VPCs if not provided)")
-	scanCmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text, dot")
+	scanCmd.Flags().StringArrayVar(&tagSelectors, "tag", nil, "Only scan VPCs with this tag, as key=value (repeatable)")
+	scanCmd.Flags().BoolVar(&bestEffort, "best-effort", false, "Keep scanning when a resource type fails to scan, e.g. for a missing permission, and report the failures at the end")
+	scanCmd.Flags().DurationVar(&scanTimeout, "timeout", 0, "Stop the scan after this long and show what was scanned by then (e.g. 5m, 0 for no limit)")
+	scanCmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: "+strings.Join(graph.Formats(), ", "))
 	scanCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
-	scanCmd.Flags().StringVar(&exportJSON, "export-json", "", "Export working state to JSON file (e.g., working_state.json)")
+	scanCmd.Flags().StringVar(&exportJSON, "export-json", "", "Export working state to JSON file (e.g., working_state.json, or working_state.json.gz or .zst to compress it)")
+	scanCmd.Flags().StringVar(&exportSQLite, "export-sqlite", "", "Append the scan as a snapshot to a SQLite database with a table per resource type (e.g., state.db)")
 	scanCmd.Flags().BoolVar(&saveState, "save-state", false, "Save working state to working_state.json")
+	scanCmd.Flags().BoolVar(&canonical, "canonical", false, "Save the working state in a canonical form for checking into git: sorted keys, no scan time or other volatile fields")
+	scanCmd.Flags().BoolVar(&stripIPs, "strip-ephemeral-ips", false, "Leave subnets' available IP counts and NAT gateway IPs out of a canonical working state")
+	addDetailFlag(scanCmd)
+	addColumnsFlag(scanCmd)
+	addLayoutFlag(scanCmd)
+	addLegendFlag(scanCmd)
+	addIconsFlag(scanCmd)
+	addViewFlags(scanCmd)
+	scanCmd.Flags().BoolVar(&scanAppMesh, "app-mesh", false, "Also discover App Mesh virtual gateways")
+	scanCmd.Flags().BoolVar(&scanContainers, "containers", false, "Also discover EKS clusters and awsvpc ECS services")
+	scanCmd.Flags().BoolVar(&scanDatabases, "databases", false, "Also discover RDS, ElastiCache and Redshift placement")
+	scanCmd.Flags().BoolVar(&scanEdge, "edge", false, "Also discover Global Accelerator and CloudFront ingress into the scanned VPCs")
+	addIAMFlags(scanCmd)
+	addCacheFlags(scanCmd)
+	addNameFlags(scanCmd)
+	addSigningFlags(scanCmd)
 	
 	// Watch command flags
-	watchCmd.Flags().StringVarP(&workingStateFile, "file", "f", "working_state.json", "Working state file to compare against")
+	watchCmd.Flags().StringVarP(&workingStateFile, "file", "f", "working_state.json", "Working state file, s3://bucket/key or https:// URL to compare against")
+	watchCmd.Flags().StringVar(&workingStateDir, "file-dir", "", "Directory of working state files, comparing each VPC against the baseline that contains it")
 	watchCmd.Flags().DurationVarP(&watchInterval, "interval", "i", 30*time.Second, "Scan interval (e.g., 30s, 1m, 5m)")
+	watchCmd.Flags().DurationVar(&scanTimeout, "timeout", 0, "Give up on a scan that takes longer than this and wait for the next interval (e.g. 5m, 0 for no limit)")
+	watchCmd.Flags().DurationVar(&reloadBaseline, "reload-baseline", 0, "Download s3:// and https:// baselines again this often (local files are reloaded whenever they change)")
 	watchCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
 	watchCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(watchCmd)
 	watchCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to watch (watches all VPCs if not provided)")
 	watchCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	watchCmd.Flags().StringVar(&notifySNSArn, "notify-sns-arn", "", "Publish drift events to this SNS topic ARN")
+	watchCmd.Flags().StringVar(&ignoreFile, "ignore-file", "", "Suppression rules file (defaults to .pikaaignore.yaml if present)")
+	watchCmd.Flags().StringVar(&diffOutput, "diff-output", "", "Structured diff output format: json, junit, sarif (default colored text)")
+	watchCmd.Flags().StringVar(&reportFile, "report", "", "Write an HTML drift report of each scan to this file (e.g. drift.html)")
+	watchCmd.Flags().StringVar(&reportS3URI, "report-s3", "", "Upload an HTML drift report of each scan with differences under this s3://bucket/prefix and link to it from notifications")
+	watchCmd.Flags().StringVar(&notifyEventBridgeBus, "notify-eventbridge-bus", "", "Publish drift events to this EventBridge bus name or ARN")
+	watchCmd.Flags().BoolVar(&notifySlack, "notify-slack", false, "Post drift events to the Slack incoming webhook URL in "+slackWebhookEnv)
+	addPagingFlags(watchCmd)
+	addRemediationFlag(watchCmd)
+	watchCmd.Flags().StringVar(&autoRemediatePolicy, "auto-remediate", "", "Automatically revert the drift this policy file allows (e.g. remediation.yaml)")
+	watchCmd.Flags().BoolVar(&autoRemediateDryRun, "auto-remediate-dry-run", false, "With --auto-remediate, only log and audit what would be reverted")
+	watchCmd.Flags().StringVar(&remediationAuditLog, "remediation-audit-log", "remediation-audit.jsonl", "With --auto-remediate, append every revert to this JSONL audit log")
+	watchCmd.Flags().BoolVar(&notifyDesktop, "notify-desktop", false, "Show a desktop notification when differences are detected")
+	watchCmd.Flags().StringVar(&desktopSeverity, "notify-desktop-severity", "low", "With --notify-desktop, only notify of differences of this severity or above: low, medium, high")
+	watchCmd.Flags().StringVar(&historyFile, "history", "", "Append each set of detected differences to this JSONL drift log (e.g. drift.jsonl)")
+	watchCmd.Flags().BoolVar(&scanAppMesh, "app-mesh", false, "Also discover App Mesh virtual gateways")
+	watchCmd.Flags().BoolVar(&scanContainers, "containers", false, "Also discover EKS clusters and awsvpc ECS services")
+	watchCmd.Flags().BoolVar(&scanDatabases, "databases", false, "Also discover RDS, ElastiCache and Redshift placement")
+	watchCmd.Flags().BoolVar(&scanEdge, "edge", false, "Also discover Global Accelerator and CloudFront ingress into the scanned VPCs")
+	watchCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address (e.g. :9090)")
+	watchCmd.Flags().BoolVar(&watchCloudTrail, "cloudtrail", false, "Only rescan resource types changed according to CloudTrail events")
+	watchCmd.Flags().DurationVar(&fullScanInterval, "full-scan-interval", time.Hour, "With --cloudtrail, rescan everything at least this often")
+	watchCmd.Flags().StringSliceVar(&watchResourceTypes, "watch-types", nil, "Only scan and compare these resource types (e.g. securitygroups,routetables)")
+	addIAMFlags(watchCmd)
+	addCacheFlags(watchCmd)
+	addNameFlags(watchCmd)
+	addVerifyFlags(watchCmd)
+	watchCmd.MarkFlagsMutuallyExclusive("file", "file-dir")
+	watchCmd.MarkFlagsMutuallyExclusive("vpc-id", "file-dir")
+}
+
+// SetVersion sets the pikaatools version reported by --version and in diagram footers
+func SetVersion(version string) {
+	toolVersion = version
+	rootCmd.Version = version
 }
 
 func Execute(ctx context.Context) error {
+	// Every command has been added by now
+	registerCompletions(rootCmd)
+
+	// SIGINT and SIGTERM cancel the context of every command, aborting API calls in
+	// flight and stopping watch, daemon and serve. A second signal exits immediately.
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	return rootCmd.ExecuteContext(ctx)
 }
 
 func runScan(ctx context.Context) error {
-	if verbose {
-		fmt.Println("Initializing AWS client...")
+	tagFilters, err := scanner.ParseTagFilters(tagSelectors)
+	if err != nil {
+		return err
+	}
+	detailLevel, err := graph.ParseDetailLevel(detail)
+	if err != nil {
+		return err
 	}
+	columns, err := parseTableColumns(output)
+	if err != nil {
+		return err
+	}
+	layout, err := parseGraphLayout(output)
+	if err != nil {
+		return err
+	}
+	if err := validateLegend(output); err != nil {
+		return err
+	}
+	icons, err := loadGraphIcons(output)
+	if err != nil {
+		return err
+	}
+	view, err := parseViewFlags()
+	if err != nil {
+		return err
+	}
+	
+	logger.Debug("initializing AWS client")
 	
 	// Initialize AWS client
-	awsClient, err := aws.NewClient(ctx, region, profile)
+	awsClient, err := newAWSClient(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to initialize AWS client: %w", err)
 	}
 	
-	if verbose {
-		fmt.Printf("Scanning AWS network infrastructure in region: %s\n", awsClient.Region())
+	// Load the signing key before scanning so a bad key fails fast
+	signer, err := newSigner(awsClient)
+	if err != nil {
+		return err
+	}
+	if signer != nil && exportJSON == "" && !saveState {
+		return fmt.Errorf("signing needs a saved working state, use --export-json or --save-state")
+	}
+	if canonical && exportJSON == "" && !saveState {
+		return fmt.Errorf("--canonical needs a saved working state, use --export-json or --save-state")
 	}
+	if stripIPs && !canonical {
+		return fmt.Errorf("--strip-ephemeral-ips can only be used with --canonical")
+	}
+	
+	logger.Debug("scanning AWS network infrastructure", "region", awsClient.Region())
 	
 	// Initialize scanner
 	networkScanner := scanner.NewNetworkScanner(awsClient)
 	networkScanner.SetVerbose(verbose)
+	networkScanner.SetLogger(logger)
+	networkScanner.SetProgress(scanProgress())
+	networkScanner.SetScanAppMesh(scanAppMesh)
+	networkScanner.SetScanContainers(scanContainers)
+	networkScanner.SetScanDatabases(scanDatabases)
+	networkScanner.SetScanEdge(scanEdge)
+	networkScanner.SetScanIAM(withIAM)
+	networkScanner.SetAllIAMRoles(allIAMRoles)
+	networkScanner.SetIAMConcurrency(iamConcurrency)
+	networkScanner.SetTagFilters(tagFilters)
+	networkScanner.SetTimeout(scanTimeout)
+	networkScanner.SetBestEffort(bestEffort)
+	
+	scanCache, err := loadScanCache()
+	if err != nil {
+		return err
+	}
+	networkScanner.SetCache(scanCache)
 	
 	// Scan network infrastructure
 	network, err := networkScanner.ScanNetwork(ctx, vpcID)
 	if err != nil {
 		return fmt.Errorf("failed to scan network: %w", err)
 	}
+	recordCompletions(network, vpcID)
+	defer reportScanErrors(network)
 	
-	if verbose {
-		fmt.Printf("Found %d VPCs, %d subnets, %d peering connections, %d transit gateways, %d security groups, %d network ACLs, %d IAM roles\n", 
-			len(network.VPCs), 
-			len(network.Subnets),
-			len(network.PeeringConnections),
-			len(network.TransitGateways),
-			len(network.SecurityGroups),
-			len(network.NetworkAcls),
-			len(network.IAMRoles))
-	}
+	logger.Debug("scan complete",
+		"vpcs", len(network.VPCs),
+		"subnets", len(network.Subnets),
+		"peering_connections", len(network.PeeringConnections),
+		"transit_gateways", len(network.TransitGateways),
+		"security_groups", len(network.SecurityGroups),
+		"network_acls", len(network.NetworkAcls),
+		"iam_roles", len(network.IAMRoles))
 	
 	// Set default filename if save-state flag is used
 	if saveState && exportJSON == "" {
@@ -126,32 +319,65 @@ func runScan(ctx context.Context) error {
 	
 	// Export to JSON if requested
 	if exportJSON != "" {
-		if verbose {
-			fmt.Printf("Exporting working state to %s...\n", exportJSON)
+		logger.Debug("exporting working state", "file", exportJSON)
+		if len(network.Incomplete) > 0 {
+			logger.Warn("saving an incomplete working state, comparisons against it will report the missing resources as added", "file", exportJSON, "incomplete", network.Incomplete)
 		}
-		
-		jsonData, err := json.MarshalIndent(network, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal network data to JSON: %w", err)
+		if len(network.ScanErrors) > 0 {
+			logger.Warn("saving a working state without the resource types that failed to scan", "file", exportJSON, "failed", len(network.ScanErrors))
 		}
 		
-		err = os.WriteFile(exportJSON, jsonData, 0644)
-		if err != nil {
-			return fmt.Errorf("failed to write JSON file %s: %w", exportJSON, err)
+		if err := writeWorkingState(exportJSON, network); err != nil {
+			return err
 		}
 		
-		if verbose {
-			fmt.Printf("Working state exported successfully to %s\n", exportJSON)
+		if signer != nil {
+			// The saved file is read back rather than kept in memory while it is written
+			jsonData, err := os.ReadFile(exportJSON)
+			if err != nil {
+				return fmt.Errorf("failed to read JSON file %s: %w", exportJSON, err)
+			}
+			if err := writeSignature(ctx, signer, exportJSON, jsonData); err != nil {
+				return err
+			}
 		}
 		
-		// If only JSON export was requested, don't generate visualization
-		if output == "text" && exportJSON != "" {
-			return nil
+		logger.Debug("working state exported", "file", exportJSON)
+	}
+	
+	if exportSQLite != "" {
+		snapshotID, err := export.NewSQLiteExporter(network).Write(exportSQLite)
+		if err != nil {
+			return err
 		}
+		logger.Debug("snapshot exported to SQLite", "file", exportSQLite, "snapshot", snapshotID)
+	}
+	
+	// If only an export was requested, don't generate visualization
+	if output == "text" && (exportJSON != "" || exportSQLite != "") {
+		return nil
+	}
+	
+	// Show friendly names in the visualization. This happens after the JSON export
+	// so saved working states keep the names recorded by AWS.
+	providers, err := nameProviders()
+	if err != nil {
+		return err
+	}
+	if err := names.Apply(ctx, network, providers); err != nil {
+		return fmt.Errorf("failed to resolve resource names: %w", err)
 	}
 	
 	// Generate visualization
 	visualizer := graph.NewVisualizer(output)
+	visualizer.SetDetailLevel(detailLevel)
+	visualizer.SetColumns(columns)
+	visualizer.SetLayout(layout)
+	visualizer.SetLegend(graphLegend)
+	visualizer.SetIcons(icons)
+	view.apply(visualizer)
+	visualizer.SetToolVersion(toolVersion)
+	visualizer.SetColor(colorOutput())
 	result, err := visualizer.Generate(network)
 	if err != nil {
 		return fmt.Errorf("failed to generate visualization: %w", err)
@@ -161,29 +387,329 @@ func runScan(ctx context.Context) error {
 	return nil
 }
 
+// writeWorkingState saves the network to filename, compressed by its extension. Canonical
+// states are encoded whole to sort their keys; others are streamed a resource at a time.
+func writeWorkingState(filename string, network *scanner.Network) error {
+	if canonical {
+		jsonData, err := export.CanonicalJSON(network, export.CanonicalOptions{StripEphemeralIPs: stripIPs})
+		if err != nil {
+			return fmt.Errorf("failed to marshal network data to JSON: %w", err)
+		}
+		if jsonData, err = statefile.Compress(filename, jsonData); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, jsonData, 0644); err != nil {
+			return fmt.Errorf("failed to write JSON file %s: %w", filename, err)
+		}
+		return nil
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create JSON file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	writer, err := statefile.NewWriter(filename, file)
+	if err != nil {
+		return err
+	}
+	if err := network.WriteJSON(writer); err != nil {
+		return fmt.Errorf("failed to write JSON file %s: %w", filename, err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to write JSON file %s: %w", filename, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write JSON file %s: %w", filename, err)
+	}
+	return nil
+}
+
+// reportScanErrors warns about each resource type a best-effort scan failed to scan
+func reportScanErrors(network *scanner.Network) {
+	for _, scanError := range network.ScanErrors {
+		logger.Warn("resource type could not be scanned", "resource_type", scanError.ResourceType, "error", scanError.Error)
+	}
+}
+
 func runWatch(ctx context.Context) error {
-	if verbose {
-		fmt.Println("Initializing AWS client...")
+	if err := validateDiffOutput(diffOutput); err != nil {
+		return err
 	}
 	
+	logger.Debug("initializing AWS client")
+	
 	// Initialize AWS client
-	awsClient, err := aws.NewClient(ctx, region, profile)
+	awsClient, err := newAWSClient(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to initialize AWS client: %w", err)
 	}
 	
-	if verbose {
-		fmt.Printf("Starting watch in region: %s with interval: %v\n", awsClient.Region(), watchInterval)
-		fmt.Printf("Watching for changes against baseline: %s\n", workingStateFile)
+	baseline := workingStateFile
+	if workingStateDir != "" {
+		baseline = workingStateDir
+	}
+	logger.Debug("starting watch", "region", awsClient.Region(), "interval", watchInterval, "baseline", baseline)
+	
+	if workingStateDir != "" && watch.IsRemoteBaseline(workingStateDir) {
+		return fmt.Errorf("--file-dir must be a local directory, got %s", workingStateDir)
 	}
 	
-	// Check if working state file exists
-	if _, err := os.Stat(workingStateFile); os.IsNotExist(err) {
+	// Check if working state file exists; remote baselines are checked when loaded
+	if _, err := os.Stat(baseline); os.IsNotExist(err) && !watch.IsRemoteBaseline(baseline) {
+		if workingStateDir != "" {
+			return fmt.Errorf("working state directory %s does not exist", workingStateDir)
+		}
 		return fmt.Errorf("working state file %s does not exist. Please run 'scan --save-state' first to create a baseline", workingStateFile)
 	}
 	
 	// Create and start watcher
 	watcher := watch.NewWatcher(awsClient, watchInterval, verbose, awsClient.Region(), vpcID)
 	
+	watcher.SetLogger(logger)
+	watcher.SetProgress(scanProgress())
+	watcher.SetDiffOutput(diffOutput)
+	watcher.SetReportFile(reportFile)
+	watcher.SetBaselineReload(reloadBaseline)
+	watcher.SetScanTimeout(scanTimeout)
+	watcher.SetScanAppMesh(scanAppMesh)
+	watcher.SetScanContainers(scanContainers)
+	watcher.SetScanDatabases(scanDatabases)
+	watcher.SetScanEdge(scanEdge)
+	watcher.SetScanIAM(withIAM, allIAMRoles)
+	watcher.SetIAMConcurrency(iamConcurrency)
+	
+	if len(watchResourceTypes) > 0 {
+		scope, err := watch.ParseScope(watchResourceTypes)
+		if err != nil {
+			return err
+		}
+		watcher.SetScope(scope)
+	}
+	
+	// Without CloudTrail events to say which cached results changed, they are only
+	// reused within an interval so drift inside them is seen on the next scan
+	if cacheFile != "" && !watchCloudTrail && cacheMaxAge > watchInterval {
+		logger.Warn("--cache without --cloudtrail refetches cached results every interval", "cache_max_age", watchInterval)
+		cacheMaxAge = watchInterval
+	}
+	scanCache, err := loadScanCache()
+	if err != nil {
+		return err
+	}
+	watcher.SetCache(scanCache)
+	
+	if watchCloudTrail {
+		// IAM events are only looked up when IAM roles are scanned
+		tracker := watch.NewChangeTracker(awsClient.CloudTrail, nil)
+		if withIAM {
+			tracker = watch.NewChangeTracker(awsClient.CloudTrail, awsClient.GlobalCloudTrail)
+		}
+		watcher.SetChangeTracker(tracker, fullScanInterval)
+	}
+	
+	ignoreRules, err := loadIgnoreRules(ignoreFile)
+	if err != nil {
+		return err
+	}
+	watcher.SetIgnoreRules(ignoreRules)
+	
+	remediation, err := remediationFormat()
+	if err != nil {
+		return err
+	}
+	watcher.SetRemediation(remediation)
+	
+	if autoRemediatePolicy != "" {
+		policy, err := watch.LoadRemediationPolicy(autoRemediatePolicy)
+		if err != nil {
+			return err
+		}
+		remediator := watch.NewAutoRemediator(awsClient.EC2, policy, awsClient.Region())
+		remediator.SetDryRun(autoRemediateDryRun)
+		remediator.SetAuditLog(remediationAuditLog)
+		remediator.SetLogger(logger)
+		watcher.SetAutoRemediator(remediator)
+		logger.Warn("auto-remediation enabled", "policy", autoRemediatePolicy, "rules", len(policy.Rules), "dry_run", autoRemediateDryRun)
+	}
+	
+	verifier, err := newSigner(awsClient)
+	if err != nil {
+		return err
+	}
+	watcher.SetVerifier(verifier)
+	
+	providers, err := nameProviders()
+	if err != nil {
+		return err
+	}
+	watcher.SetNameProviders(providers)
+	
+	if err := setReportUploader(watcher, awsClient); err != nil {
+		return err
+	}
+	if err := addDriftNotifiers(watcher, awsClient); err != nil {
+		return err
+	}
+	if notifyDesktop {
+		severity, err := watch.ParseSeverity(desktopSeverity)
+		if err != nil {
+			return err
+		}
+		watcher.AddNotifier(watch.NewDesktopNotifier(severity))
+	}
+	serveWatchMetrics(watcher)
+	
+	if workingStateDir != "" {
+		return watcher.WatchDir(ctx, workingStateDir)
+	}
 	return watcher.Watch(ctx, workingStateFile)
-}
\ No newline at end of file
+}
+
+// pagerDutyRoutingKeyEnv and opsgenieAPIKeyEnv are the environment variables holding
+// the paging integration keys, kept out of flags so they do not show up in process listings
+const (
+	pagerDutyRoutingKeyEnv = "PIKAATOOLS_PAGERDUTY_ROUTING_KEY"
+	opsgenieAPIKeyEnv      = "PIKAATOOLS_OPSGENIE_API_KEY"
+)
+
+// addPagingFlags adds the flags that page the on-call through PagerDuty or Opsgenie
+func addPagingFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&notifyPagerDuty, "notify-pagerduty", false, "Trigger PagerDuty incidents with the Events API v2 integration key in "+pagerDutyRoutingKeyEnv)
+	cmd.Flags().BoolVar(&notifyOpsgenie, "notify-opsgenie", false, "Create Opsgenie alerts with the API integration key in "+opsgenieAPIKeyEnv)
+	cmd.Flags().StringVar(&opsgenieAPIURL, "opsgenie-api-url", watch.OpsgenieAPIURL, "Opsgenie API, https://api.eu.opsgenie.com for EU accounts")
+	cmd.Flags().StringVar(&pageSeverity, "page-severity", string(watch.SeverityHigh), "Only page for differences of this severity or above: low, medium, high")
+}
+
+// addRemediationFlag adds the --suggest-remediation flag. A bare
+// --suggest-remediation suggests AWS CLI commands.
+func addRemediationFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&suggestRemediation, "suggest-remediation", "", "Print the commands that would revert security group rule, route and network ACL entry changes: cli, terraform")
+	cmd.Flags().Lookup("suggest-remediation").NoOptDefVal = string(watch.RemediationCLI)
+}
+
+// remediationFormat parses --suggest-remediation, empty when not set
+func remediationFormat() (watch.RemediationFormat, error) {
+	if suggestRemediation == "" {
+		return "", nil
+	}
+	return watch.ParseRemediationFormat(suggestRemediation)
+}
+
+// slackWebhookEnv is the environment variable holding the Slack incoming webhook URL,
+// a secret kept out of flags so it does not show up in process listings
+const slackWebhookEnv = "PIKAATOOLS_SLACK_WEBHOOK_URL"
+
+// addDriftNotifiers registers the drift notifiers selected by the notification flags
+func addDriftNotifiers(watcher *watch.Watcher, awsClient *aws.Client) error {
+	if notifySNSArn != "" {
+		watcher.AddNotifier(watch.NewSNSNotifier(awsClient.SNS, notifySNSArn))
+	}
+	if notifyEventBridgeBus != "" {
+		watcher.AddNotifier(watch.NewEventBridgeNotifier(awsClient.EventBridge, notifyEventBridgeBus))
+	}
+	if notifySlack {
+		webhookURL := os.Getenv(slackWebhookEnv)
+		if webhookURL == "" {
+			return fmt.Errorf("--notify-slack needs the incoming webhook URL in %s", slackWebhookEnv)
+		}
+		watcher.AddNotifier(watch.NewSlackNotifier(webhookURL))
+	}
+	if historyFile != "" {
+		watcher.AddNotifier(watch.NewHistoryLog(historyFile))
+	}
+	if !notifyPagerDuty && !notifyOpsgenie {
+		return nil
+	}
+	severity, err := watch.ParseSeverity(pageSeverity)
+	if err != nil {
+		return err
+	}
+	if notifyPagerDuty {
+		routingKey := os.Getenv(pagerDutyRoutingKeyEnv)
+		if routingKey == "" {
+			return fmt.Errorf("--notify-pagerduty needs the routing key in %s", pagerDutyRoutingKeyEnv)
+		}
+		watcher.AddNotifier(watch.NewPagerDutyNotifier(routingKey, severity))
+	}
+	if notifyOpsgenie {
+		apiKey := os.Getenv(opsgenieAPIKeyEnv)
+		if apiKey == "" {
+			return fmt.Errorf("--notify-opsgenie needs the API key in %s", opsgenieAPIKeyEnv)
+		}
+		opsgenie := watch.NewOpsgenieNotifier(apiKey, severity)
+		opsgenie.SetAPIURL(opsgenieAPIURL)
+		watcher.AddNotifier(opsgenie)
+	}
+	return nil
+}
+
+// setReportUploader uploads HTML drift reports to S3 when --report-s3 is set
+func setReportUploader(watcher *watch.Watcher, awsClient *aws.Client) error {
+	if reportS3URI == "" {
+		return nil
+	}
+	uploader, err := watch.NewReportUploader(awsClient.S3, reportS3URI)
+	if err != nil {
+		return err
+	}
+	watcher.SetReportUploader(uploader)
+	return nil
+}
+
+// serveWatchMetrics serves Prometheus metrics of the watcher's scans when --metrics-addr is set
+func serveWatchMetrics(watcher *watch.Watcher) {
+	if metricsAddr == "" {
+		return
+	}
+	
+	metrics := watch.NewMetrics()
+	watcher.SetMetrics(metrics)
+	
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	go func() {
+		if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+			logger.Error("metrics server failed", "error", err)
+		}
+	}()
+	
+	logger.Debug("serving Prometheus metrics", "url", metricsAddr+"/metrics")
+}
+
+// validateDiffOutput checks that a structured diff output format is supported
+func validateDiffOutput(format string) error {
+	switch format {
+	case "", watch.DiffOutputJSON, watch.DiffOutputJUnit, watch.DiffOutputSARIF:
+		return nil
+	default:
+		return fmt.Errorf("unsupported diff output format: %s", format)
+	}
+}
+
+// loadIgnoreRules loads the suppression rules file and adds the rules from the
+// config file. Without an explicit file the default file is used when it exists.
+func loadIgnoreRules(filename string) (*watch.IgnoreRules, error) {
+	if filename == "" {
+		if _, err := os.Stat(watch.DefaultIgnoreFile); err == nil {
+			filename = watch.DefaultIgnoreFile
+		}
+	}
+
+	rules := &watch.IgnoreRules{}
+	if filename != "" {
+		loaded, err := watch.LoadIgnoreRules(filename)
+		if err != nil {
+			return nil, err
+		}
+		rules = loaded
+		logger.Debug("loaded ignore rules", "count", len(rules.Rules), "file", filename)
+	}
+
+	rules.Rules = append(rules.Rules, configIgnoreRules()...)
+	if len(rules.Rules) == 0 {
+		return nil, nil
+	}
+	return rules, nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/routepath"
+)
+
+var (
+	routePathSubnet    string
+	routePathCIDR      string
+	routePathStateFile string
+	routePathOutput    string
+)
+
+var routePathCmd = &cobra.Command{
+	Use:   "route-path",
+	Short: "Trace the route a packet takes from a subnet to a destination",
+	Long: `Walk the subnet's route table, then any VPC peering connection or transit gateway
+route table the packet is sent to, and print the hop-by-hop path to the destination
+with the routes considered at each hop and the longest prefix match chosen.
+
+Only routing is traced: security groups and network ACLs are not evaluated. The
+network is scanned live unless --from-state names a saved working state.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRoutePath(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(routePathCmd)
+
+	routePathCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	routePathCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(routePathCmd)
+	routePathCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	routePathCmd.Flags().StringVar(&routePathSubnet, "src-subnet", "", "Subnet ID the packet leaves from")
+	routePathCmd.Flags().StringVar(&routePathCIDR, "dst-cidr", "", "Destination CIDR or IP address")
+	routePathCmd.Flags().StringVarP(&routePathStateFile, "from-state", "f", "", "Trace through a working state file instead of scanning")
+	routePathCmd.Flags().StringVarP(&routePathOutput, "output", "o", "text", "Output format: text, json")
+	routePathCmd.MarkFlagRequired("src-subnet")
+	routePathCmd.MarkFlagRequired("dst-cidr")
+}
+
+func runRoutePath(ctx context.Context) error {
+	network, err := loadOrScanNetwork(ctx, routePathStateFile)
+	if err != nil {
+		return err
+	}
+
+	path, err := routepath.Trace(network, routePathSubnet, routePathCIDR)
+	if err != nil {
+		return err
+	}
+
+	switch routePathOutput {
+	case "text":
+		fmt.Print(path.Text())
+	case "json":
+		data, err := json.MarshalIndent(path, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal route path: %w", err)
+		}
+		fmt.Println(string(data))
+	default:
+		return fmt.Errorf("unsupported output format: %s", routePathOutput)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/server"
+)
+
+var (
+	serveListen   string
+	serveCacheTTL time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve scan, diff and reachability operations over an HTTP/JSON API",
+	Long: `Run an HTTP/JSON API server exposing scan, diff and reachability operations, so other tools and
+dashboards can consume pikaatools without shelling out.
+
+Endpoints:
+  GET  /healthz                 Health check
+  GET  /api/v1/network          Current network (cached, ?vpc_id=, ?refresh=true)
+  POST /api/v1/scans            Start a background scan job ({"vpc_id": "..."})
+  GET  /api/v1/scans            List scan jobs
+  GET  /api/v1/scans/{id}       Scan job status and result
+  POST /api/v1/diff             Diff the current network against a working state in the body
+  POST /api/v1/reach            Sources that can reach a port on a target ({"target": "...", "port": 443})`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runServe(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	serveCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(serveCmd)
+	serveCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "Address to listen on")
+	serveCmd.Flags().DurationVar(&serveCacheTTL, "cache-ttl", 5*time.Minute, "How long scan results are reused before rescanning")
+	addIAMFlags(serveCmd)
+}
+
+func runServe(ctx context.Context) error {
+	awsClient, err := newAWSClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+
+	scan := func(ctx context.Context, vpcID string) (*scanner.Network, error) {
+		networkScanner := scanner.NewNetworkScanner(awsClient)
+		networkScanner.SetVerbose(verbose)
+		networkScanner.SetLogger(logger)
+		networkScanner.SetScanIAM(withIAM)
+		networkScanner.SetAllIAMRoles(allIAMRoles)
+		networkScanner.SetIAMConcurrency(iamConcurrency)
+		return networkScanner.ScanNetwork(ctx, vpcID)
+	}
+
+	apiServer := server.NewServer(ctx, scan, serveCacheTTL)
+	apiServer.SetVerbose(verbose)
+
+	httpServer := &http.Server{
+		Addr:    serveListen,
+		Handler: apiServer.Handler(),
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- httpServer.ListenAndServe()
+	}()
+
+	logger.Info("serving pikaatools API", "region", awsClient.Region(), "address", serveListen)
+
+	select {
+	case err := <-errChan:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to serve API: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down API server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down API server: %w", err)
+	}
+	apiServer.Wait()
+
+	return nil
+}
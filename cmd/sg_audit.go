@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/sgaudit"
+)
+
+var (
+	sgAuditStateFile  string
+	sgAuditOutput     string
+	sgAuditPeriod     string
+	sgAuditUnusedFile string
+)
+
+var sgAuditCmd = &cobra.Command{
+	Use:   "sg-audit",
+	Short: "Label security group rules as used or unused from flow logs",
+	Long: `Match the accepted traffic in VPC Flow Logs over a period against every security
+group rule and label each rule used, unused or unverified (prefix list rules, which
+traffic cannot be matched against). Unused rules, especially ones open to the
+internet, are candidates for tightening; export them with --unused-csv.
+
+A rule with no traffic in the period may still be needed, for example for failover
+or quarterly jobs, so choose a period that covers the workload's usual cycle. The
+network is scanned live unless --from-state names a saved working state.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSGAudit(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sgAuditCmd)
+
+	sgAuditCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	sgAuditCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(sgAuditCmd)
+	sgAuditCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to audit (audits all VPCs if not provided)")
+	sgAuditCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	sgAuditCmd.Flags().StringVar(&sgAuditPeriod, "period", "30d", "Audit the traffic of this long before now, in days (30d) or hours (36h)")
+	addFlowLogFlags(sgAuditCmd, "--period")
+	sgAuditCmd.Flags().StringVarP(&sgAuditStateFile, "from-state", "f", "", "Audit a working state file instead of scanning")
+	sgAuditCmd.Flags().StringVarP(&sgAuditOutput, "output", "o", "text", "Output format: text, json")
+	sgAuditCmd.Flags().StringVar(&sgAuditUnusedFile, "unused-csv", "", "Write the unused rules to this CSV file")
+}
+
+func runSGAudit(ctx context.Context) error {
+	if err := validateFlowLogFlags(); err != nil {
+		return err
+	}
+
+	period, err := sgaudit.ParsePeriod(sgAuditPeriod)
+	if err != nil {
+		return err
+	}
+	start, end, err := flowsWindow(period)
+	if err != nil {
+		return err
+	}
+
+	network, err := loadOrScanNetwork(ctx, sgAuditStateFile)
+	if err != nil {
+		return err
+	}
+
+	traffic, err := readFlowReport(ctx, network, start, end, 0)
+	if err != nil {
+		return err
+	}
+
+	report := sgaudit.Analyze(network, traffic)
+
+	switch sgAuditOutput {
+	case "text":
+		fmt.Print(report.Text())
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal security group audit: %w", err)
+		}
+		fmt.Println(string(data))
+	default:
+		return fmt.Errorf("unsupported output format: %s", sgAuditOutput)
+	}
+
+	if sgAuditUnusedFile != "" {
+		file, err := os.Create(sgAuditUnusedFile)
+		if err != nil {
+			return fmt.Errorf("failed to create unused rule list %s: %w", sgAuditUnusedFile, err)
+		}
+		defer file.Close()
+
+		if err := report.WriteCSV(file); err != nil {
+			return err
+		}
+
+		logger.Debug("unused rules written", "file", sgAuditUnusedFile)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/aws"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/signing"
+)
+
+var (
+	hmacKeyFile string
+	kmsKeyID    string
+)
+
+// addSigningFlags registers the flags that sign saved working states
+func addSigningFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&hmacKeyFile, "sign-hmac-key-file", "", "Sign saved working states with the HMAC-SHA256 key in this file, writing a .sig file next to each")
+	cmd.Flags().StringVar(&kmsKeyID, "sign-kms-key-id", "", "Sign saved working states with this asymmetric KMS key ID, ARN or alias, writing a .sig file next to each")
+	cmd.MarkFlagsMutuallyExclusive("sign-hmac-key-file", "sign-kms-key-id")
+}
+
+// addVerifyFlags registers the flags that require baselines to be signed
+func addVerifyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&hmacKeyFile, "verify-hmac-key-file", "", "Require each baseline to have a .sig file signed with the HMAC-SHA256 key in this file")
+	cmd.Flags().StringVar(&kmsKeyID, "verify-kms-key-id", "", "Require each baseline to have a .sig file signed with this asymmetric KMS key ID, ARN or alias")
+	cmd.MarkFlagsMutuallyExclusive("verify-hmac-key-file", "verify-kms-key-id")
+}
+
+// newSigner returns the signer selected by the signing or verification flags, or
+// nil when snapshots are not signed
+func newSigner(awsClient *aws.Client) (signing.Signer, error) {
+	switch {
+	case hmacKeyFile != "":
+		key, err := signing.LoadHMACKey(hmacKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return signing.NewHMACSigner(key)
+	case kmsKeyID != "":
+		return signing.NewKMSSigner(awsClient.KMS, kmsKeyID), nil
+	default:
+		return nil, nil
+	}
+}
+
+// writeSignature signs a saved working state and writes the signature next to it
+func writeSignature(ctx context.Context, signer signing.Signer, filename string, data []byte) error {
+	signature, err := signer.Sign(ctx, data)
+	if err != nil {
+		return fmt.Errorf("failed to sign %s: %w", filename, err)
+	}
+
+	encoded, err := signature.Marshal()
+	if err != nil {
+		return err
+	}
+
+	signatureFile := signing.SignatureLocation(filename)
+	if err := os.WriteFile(signatureFile, encoded, 0644); err != nil {
+		return fmt.Errorf("failed to write signature file %s: %w", signatureFile, err)
+	}
+	logger.Debug("working state signed", "file", filename, "signature", signatureFile, "algorithm", signature.Algorithm)
+	return nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/tagaudit"
+)
+
+var (
+	tagAuditStateFile string
+	tagAuditOutput    string
+	tagAuditPlanFile  string
+)
+
+var tagAuditCmd = &cobra.Command{
+	Use:   "tag-audit",
+	Short: "Inventory tags and suggest normalizations",
+	Long: `Inventory the tag keys and values used across all scanned resources, highlight
+near-duplicate keys (for example env, Env and Environment) and values that differ only
+by case, and export a remediation plan as CSV of resource to suggested tag changes.
+
+The network is scanned live unless --from-state names a saved working state.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagAudit(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagAuditCmd)
+
+	tagAuditCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	tagAuditCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(tagAuditCmd)
+	tagAuditCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to audit (audits all VPCs if not provided)")
+	tagAuditCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	tagAuditCmd.Flags().StringVarP(&tagAuditStateFile, "from-state", "f", "", "Audit a working state file instead of scanning")
+	tagAuditCmd.Flags().StringVarP(&tagAuditOutput, "output", "o", "text", "Output format: text, json")
+	tagAuditCmd.Flags().StringVar(&tagAuditPlanFile, "plan-csv", "", "Write the remediation plan to this CSV file")
+	addIAMFlags(tagAuditCmd)
+}
+
+func runTagAudit(ctx context.Context) error {
+	network, err := loadOrScanNetwork(ctx, tagAuditStateFile)
+	if err != nil {
+		return err
+	}
+
+	report := tagaudit.Audit(tagaudit.Resources(network))
+
+	switch tagAuditOutput {
+	case "text":
+		fmt.Print(report.Text())
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal tag audit: %w", err)
+		}
+		fmt.Println(string(data))
+	default:
+		return fmt.Errorf("unsupported output format: %s", tagAuditOutput)
+	}
+
+	if tagAuditPlanFile != "" {
+		file, err := os.Create(tagAuditPlanFile)
+		if err != nil {
+			return fmt.Errorf("failed to create remediation plan %s: %w", tagAuditPlanFile, err)
+		}
+		defer file.Close()
+
+		if err := report.WriteCSV(file); err != nil {
+			return err
+		}
+
+		logger.Debug("remediation plan written", "file", tagAuditPlanFile)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/tagaudit"
+)
+
+var (
+	tagsRequired  []string
+	tagsPatterns  []string
+	tagsStateFile string
+	tagsOutput    string
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Check resource tags",
+}
+
+var tagsCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report resources missing required tags",
+	Long: `Report the scanned resources that are missing any of the --required tags or whose
+tag values do not match a --pattern, grouped by VPC and resource type. Patterns are
+Key=regex pairs matched against the whole value; a pattern on a key that is not
+required only applies to resources carrying the tag.
+
+Output as CSV has one row per issue for remediation tracking. Exits with code 0 when
+every resource complies, 1 when a resource does not and 2 when the check could not
+be performed.
+
+The network is scanned live unless --from-state names a saved working state.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		compliant, err := runTagsCheck(cmd.Context())
+		if err != nil {
+			return &ExitCodeError{Code: ExitError, Err: err}
+		}
+		if !compliant {
+			return &ExitCodeError{Code: ExitDifferences}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagsCmd)
+	tagsCmd.AddCommand(tagsCheckCmd)
+
+	tagsCheckCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	tagsCheckCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(tagsCheckCmd)
+	tagsCheckCmd.Flags().StringVarP(&vpcID, "vpc-id", "v", "", "Specific VPC ID to check (checks all VPCs if not provided)")
+	tagsCheckCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	tagsCheckCmd.Flags().StringSliceVar(&tagsRequired, "required", nil, "Comma-separated tag keys every resource must have")
+	tagsCheckCmd.Flags().StringArrayVar(&tagsPatterns, "pattern", nil, "Key=regex the tag value must match (can be repeated)")
+	tagsCheckCmd.Flags().StringVarP(&tagsStateFile, "from-state", "f", "", "Check a working state file instead of scanning")
+	tagsCheckCmd.Flags().StringVarP(&tagsOutput, "output", "o", "text", "Output format: text, json, csv")
+	addIAMFlags(tagsCheckCmd)
+}
+
+// runTagsCheck checks the tags of the network and reports whether every resource complies
+func runTagsCheck(ctx context.Context) (bool, error) {
+	if len(tagsRequired) == 0 && len(tagsPatterns) == 0 {
+		return false, fmt.Errorf("at least one of --required or --pattern is required")
+	}
+	patterns, err := tagaudit.ParsePatterns(tagsPatterns)
+	if err != nil {
+		return false, err
+	}
+	if tagsOutput != "text" && tagsOutput != "json" && tagsOutput != "csv" {
+		return false, fmt.Errorf("unsupported output format: %s", tagsOutput)
+	}
+
+	network, err := loadOrScanNetwork(ctx, tagsStateFile)
+	if err != nil {
+		return false, err
+	}
+	if tagsStateFile != "" && vpcID != "" {
+		network = network.ForVPCs([]string{vpcID})
+	}
+
+	report := tagaudit.Check(tagaudit.Resources(network), tagsRequired, patterns)
+
+	switch tagsOutput {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal tag compliance report: %w", err)
+		}
+		fmt.Println(string(data))
+	case "csv":
+		if err := report.WriteCSV(os.Stdout); err != nil {
+			return false, err
+		}
+	default:
+		fmt.Print(report.Text())
+	}
+
+	return report.Compliant(), nil
+}
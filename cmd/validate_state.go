@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/validate"
+)
+
+var validateStateFile string
+
+var validateStateCmd = &cobra.Command{
+	Use:   "validate-state",
+	Short: "Validate a working state file",
+	Long: `Validate a working state file against the state schema and check its referential
+integrity. Dangling references (for example a VPC listing a subnet that is not in the
+file, or a route targeting an unknown gateway) are reported so a corrupted baseline is
+caught before it is used for diffs.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runValidateState()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateStateCmd)
+
+	validateStateCmd.Flags().StringVarP(&validateStateFile, "file", "f", "working_state.json", "Working state file to validate")
+}
+
+func runValidateState() error {
+	issues, err := validate.ValidateFile(validateStateFile)
+	if err != nil {
+		return err
+	}
+
+	if len(issues) == 0 {
+		fmt.Printf("%s is valid\n", validateStateFile)
+		return nil
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+
+	if validate.HasErrors(issues) {
+		return fmt.Errorf("%s failed validation with %d issues", validateStateFile, len(issues))
+	}
+
+	fmt.Printf("%s is valid with %d warnings\n", validateStateFile, len(issues))
+	return nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/reach"
+)
+
+var (
+	verifyPathSource      string
+	verifyPathDestination string
+	verifyPathProtocol    string
+	verifyPathPort        int32
+	verifyPathKeep        bool
+	verifyPathWait        time.Duration
+	verifyPathStateFile   string
+	verifyPathOutput      string
+)
+
+var verifyPathCmd = &cobra.Command{
+	Use:   "verify-path",
+	Short: "Check the path between two resources with EC2 Reachability Analyzer",
+	Long: `Create a Reachability Analyzer network insights path between two resources, run an
+analysis of it and print whether AWS found the destination reachable, with the hops
+of the path or the explanations of why it is blocked.
+
+When the destination is an instance or network interface and a port is given, the
+same pair is run through the static analysis of the scanned network, as in
+blast-radius, and the two conclusions are compared.
+
+The path and analysis are deleted afterwards unless --keep is set. AWS bills every
+analysis run.
+
+Exits with code 0 when the static analysis agrees with Reachability Analyzer or was
+not compared, 1 when it disagrees and 2 when the path could not be verified.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		disagrees, err := runVerifyPath(cmd.Context())
+		if err != nil {
+			return &ExitCodeError{Code: ExitError, Err: err}
+		}
+		if disagrees {
+			return &ExitCodeError{Code: ExitDifferences}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyPathCmd)
+
+	verifyPathCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region (defaults to AWS_REGION or us-east-1)")
+	verifyPathCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile (defaults to default profile)")
+	addAPIFlags(verifyPathCmd)
+	verifyPathCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	verifyPathCmd.Flags().StringVar(&verifyPathSource, "source", "", "ID or ARN of the resource the path starts at")
+	verifyPathCmd.Flags().StringVar(&verifyPathDestination, "destination", "", "ID or ARN of the resource the path ends at")
+	verifyPathCmd.Flags().StringVar(&verifyPathProtocol, "protocol", "tcp", "Protocol: tcp, udp")
+	verifyPathCmd.Flags().Int32Var(&verifyPathPort, "port", 0, "Destination port (any port when not set)")
+	verifyPathCmd.Flags().BoolVar(&verifyPathKeep, "keep", false, "Keep the path and analysis in the account")
+	verifyPathCmd.Flags().DurationVar(&verifyPathWait, "wait", 5*time.Minute, "How long to wait for the analysis to finish")
+	verifyPathCmd.Flags().StringVarP(&verifyPathStateFile, "from-state", "f", "", "Compare against a working state file instead of scanning")
+	verifyPathCmd.Flags().StringVarP(&verifyPathOutput, "output", "o", "text", "Output format: text, json")
+	verifyPathCmd.MarkFlagRequired("source")
+	verifyPathCmd.MarkFlagRequired("destination")
+}
+
+func runVerifyPath(ctx context.Context) (bool, error) {
+	if verifyPathPort < 0 || verifyPathPort > 65535 {
+		return false, fmt.Errorf("--port must be between 1 and 65535 when set")
+	}
+	if verifyPathOutput != "text" && verifyPathOutput != "json" {
+		return false, fmt.Errorf("unsupported output format: %s", verifyPathOutput)
+	}
+
+	awsClient, err := newAWSClient(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to initialize AWS client: %w", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, verifyPathWait)
+	defer cancel()
+
+	verifier := reach.NewVerifier(awsClient.EC2)
+	verifier.SetKeep(verifyPathKeep)
+	verifier.SetLogger(logger)
+	verification, err := verifier.Verify(waitCtx, verifyPathSource, verifyPathDestination, verifyPathProtocol, verifyPathPort)
+	if err != nil {
+		return false, err
+	}
+
+	if reach.Comparable(verification) {
+		network, err := loadOrScanNetwork(ctx, verifyPathStateFile)
+		if err != nil {
+			return false, err
+		}
+		if err := reach.CompareStatic(ctx, awsClient.EC2, network, verification); err != nil {
+			return false, err
+		}
+	}
+
+	switch verifyPathOutput {
+	case "text":
+		fmt.Print(verification.Text())
+	case "json":
+		data, err := json.MarshalIndent(verification, "", "  ")
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal path verification: %w", err)
+		}
+		fmt.Println(string(data))
+	}
+
+	return verification.Static != "" && !verification.Agrees(), nil
+}
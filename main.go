@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -9,11 +10,23 @@ import (
 	"github.com/Yiu-Kelvin/pikaatools/cmd"
 )
 
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
 	ctx := context.Background()
-	
+	cmd.SetVersion(version)
+
 	if err := cmd.Execute(ctx); err != nil {
+		var exitErr *cmd.ExitCodeError
+		if errors.As(err, &exitErr) {
+			if exitErr.Err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", exitErr.Err)
+			}
+			os.Exit(exitErr.Code)
+		}
+
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		log.Fatal(err)
 	}
-}
\ No newline at end of file
+}
@@ -2,23 +2,106 @@ package aws
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"net/url"
 	"os"
+	"sort"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/appmesh"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/elasticache"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	stsTypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
 )
 
+// DefaultSessionName is the role session name used when assuming a role without one
+const DefaultSessionName = "pikaatools"
+
 // Client wraps AWS services needed for network scanning
 type Client struct {
-	EC2    *ec2.Client
-	IAM    *iam.Client
-	config aws.Config
+	EC2               *ec2.Client
+	IAM               *iam.Client
+	SNS               *sns.Client
+	EventBridge       *eventbridge.Client
+	STS               *sts.Client
+	AppMesh           *appmesh.Client
+	CloudWatchLogs    *cloudwatchlogs.Client
+	S3                *s3.Client
+	EKS               *eks.Client
+	ECS               *ecs.Client
+	RDS               *rds.Client
+	ElastiCache       *elasticache.Client
+	Redshift          *redshift.Client
+	ELBv2             *elasticloadbalancingv2.Client
+	GlobalAccelerator *globalaccelerator.Client
+	CloudFront        *cloudfront.Client
+	CloudTrail        *cloudtrail.Client
+	GlobalCloudTrail  *cloudtrail.Client
+	KMS               *kms.Client
+	SES               *sesv2.Client
+	config            aws.Config
+}
+
+// Options controls how AWS API calls are retried and rate limited
+type Options struct {
+	// MaxAttempts is the maximum number of attempts per API call, including the first
+	MaxAttempts int
+	// RetryMode is "standard" or "adaptive". Adaptive mode also slows down
+	// client-side after throttling errors such as RequestLimitExceeded.
+	RetryMode string
+	// RequestsPerSecond caps the API call rate across all services, 0 for no limit
+	RequestsPerSecond float64
+	// EndpointURL sends every service's requests to one endpoint, such as
+	// LocalStack or moto. AWS_ENDPOINT_URL and AWS_ENDPOINT_URL_<SERVICE> are
+	// honoured when it is empty.
+	EndpointURL string
+	// RoleARN is a role to assume with the profile's credentials, such as a
+	// read-only role in a delegated account
+	RoleARN string
+	// ExternalID is passed when assuming RoleARN, if the role's trust policy requires one
+	ExternalID string
+	// SessionName names the assumed role session in CloudTrail, DefaultSessionName when empty
+	SessionName string
+	// SessionTags are attached to the assumed role session. The role's trust
+	// policy must allow sts:TagSession when there are any.
+	SessionTags map[string]string
+}
+
+// DefaultOptions returns the options used by NewClient
+func DefaultOptions() Options {
+	return Options{
+		MaxAttempts: 5,
+		RetryMode:   string(aws.RetryModeAdaptive),
+	}
 }
 
 // NewClient creates a new AWS client with the specified region and profile
 func NewClient(ctx context.Context, region, profile string) (*Client, error) {
+	return NewClientWithOptions(ctx, region, profile, DefaultOptions())
+}
+
+// NewClientWithOptions creates a new AWS client with the specified region, profile
+// and retry and rate limiting options
+func NewClientWithOptions(ctx context.Context, region, profile string, options Options) (*Client, error) {
 	var opts []func(*config.LoadOptions) error
 	
 	// Set region
@@ -35,20 +118,111 @@ func NewClient(ctx context.Context, region, profile string) (*Client, error) {
 		opts = append(opts, config.WithSharedConfigProfile(profile))
 	}
 	
+	// Set retry behaviour
+	if options.RetryMode != "" {
+		retryMode, err := aws.ParseRetryMode(options.RetryMode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry mode: %w", err)
+		}
+		opts = append(opts, config.WithRetryMode(retryMode))
+	}
+	if options.MaxAttempts > 0 {
+		opts = append(opts, config.WithRetryMaxAttempts(options.MaxAttempts))
+	}
+	
 	// Load AWS config
 	cfg, err := config.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
 	
+	// Override service endpoints
+	if options.EndpointURL != "" {
+		endpoint, err := url.Parse(options.EndpointURL)
+		if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint URL %q, expected e.g. http://localhost:4566", options.EndpointURL)
+		}
+		cfg.BaseEndpoint = aws.String(options.EndpointURL)
+	}
+	
+	// Share one token bucket across every service client
+	if options.RequestsPerSecond > 0 {
+		bucket := newTokenBucket(options.RequestsPerSecond, int(math.Ceil(options.RequestsPerSecond)))
+		cfg.APIOptions = append(cfg.APIOptions, rateLimitMiddleware(bucket))
+	}
+	
+	// Fail early with a sign-in hint when an SSO session has expired
+	if err := checkSSOSession(ctx, cfg, profile); err != nil {
+		return nil, err
+	}
+	
+	// Assume a role, with the endpoint and rate limit above applying to STS too
+	if options.RoleARN != "" {
+		cfg.Credentials = aws.NewCredentialsCache(assumeRoleProvider(cfg, options))
+	}
+	
+	return NewClientFromConfig(cfg), nil
+}
+
+// assumeRoleProvider returns credentials for the role in options, obtained with the credentials in cfg
+func assumeRoleProvider(cfg aws.Config, options Options) *stscreds.AssumeRoleProvider {
+	return stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), options.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = options.SessionName
+		if o.RoleSessionName == "" {
+			o.RoleSessionName = DefaultSessionName
+		}
+		if options.ExternalID != "" {
+			o.ExternalID = aws.String(options.ExternalID)
+		}
+
+		keys := make([]string, 0, len(options.SessionTags))
+		for key := range options.SessionTags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			o.Tags = append(o.Tags, stsTypes.Tag{Key: aws.String(key), Value: aws.String(options.SessionTags[key])})
+		}
+	})
+}
+
+// NewClientFromConfig creates a new AWS client from an already loaded configuration
+func NewClientFromConfig(cfg aws.Config) *Client {
 	return &Client{
-		EC2:    ec2.NewFromConfig(cfg),
-		IAM:    iam.NewFromConfig(cfg),
+		EC2:            ec2.NewFromConfig(cfg),
+		IAM:            iam.NewFromConfig(cfg),
+		SNS:            sns.NewFromConfig(cfg),
+		EventBridge:    eventbridge.NewFromConfig(cfg),
+		STS:            sts.NewFromConfig(cfg),
+		AppMesh:        appmesh.NewFromConfig(cfg),
+		CloudWatchLogs: cloudwatchlogs.NewFromConfig(cfg),
+		S3: s3.NewFromConfig(cfg, func(o *s3.Options) {
+			// Custom endpoints such as LocalStack do not serve virtual-hosted buckets
+			o.UsePathStyle = cfg.BaseEndpoint != nil
+		}),
+		EKS:         eks.NewFromConfig(cfg),
+		ECS:         ecs.NewFromConfig(cfg),
+		RDS:         rds.NewFromConfig(cfg),
+		ElastiCache: elasticache.NewFromConfig(cfg),
+		Redshift:    redshift.NewFromConfig(cfg),
+		ELBv2:       elasticloadbalancingv2.NewFromConfig(cfg),
+		GlobalAccelerator: globalaccelerator.NewFromConfig(cfg, func(o *globalaccelerator.Options) {
+			// The Global Accelerator API is only served from us-west-2
+			o.Region = "us-west-2"
+		}),
+		CloudFront: cloudfront.NewFromConfig(cfg),
+		CloudTrail: cloudtrail.NewFromConfig(cfg),
+		GlobalCloudTrail: cloudtrail.NewFromConfig(cfg, func(o *cloudtrail.Options) {
+			// Global services such as IAM record their events in us-east-1
+			o.Region = "us-east-1"
+		}),
+		KMS:    kms.NewFromConfig(cfg),
+		SES:    sesv2.NewFromConfig(cfg),
 		config: cfg,
-	}, nil
+	}
 }
 
 // Region returns the current AWS region
 func (c *Client) Region() string {
 	return c.config.Region
-}
\ No newline at end of file
+}
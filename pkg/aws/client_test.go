@@ -0,0 +1,85 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestNewClientWithOptionsEndpoint(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	options := DefaultOptions()
+	options.EndpointURL = "http://localhost:4566"
+	client, err := NewClientWithOptions(context.Background(), "us-east-1", "", options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if client.config.BaseEndpoint == nil || *client.config.BaseEndpoint != options.EndpointURL {
+		t.Errorf("Expected base endpoint %s, got %v", options.EndpointURL, client.config.BaseEndpoint)
+	}
+
+	for _, endpoint := range []string{"localhost:4566", "not a url"} {
+		options.EndpointURL = endpoint
+		if _, err := NewClientWithOptions(context.Background(), "us-east-1", "", options); err == nil {
+			t.Errorf("Expected error for endpoint %q", endpoint)
+		}
+	}
+}
+
+func TestNewClientWithOptionsAssumeRole(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	var form url.Values
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse request: %v", err)
+		}
+		form = r.PostForm
+		fmt.Fprintf(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials>
+<AccessKeyId>ASIAASSUMED</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken>
+<Expiration>%s</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	defer sts.Close()
+
+	options := DefaultOptions()
+	options.EndpointURL = sts.URL
+	options.RoleARN = "arn:aws:iam::210987654321:role/NetworkAudit"
+	options.ExternalID = "audit-42"
+	options.SessionName = "pikaatools-scan"
+	options.SessionTags = map[string]string{"Tool": "pikaatools", "Command": "scan"}
+	client, err := NewClientWithOptions(context.Background(), "us-east-1", "", options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	credentials, err := client.config.Credentials.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to assume role: %s", err)
+	}
+	if credentials.AccessKeyID != "ASIAASSUMED" {
+		t.Errorf("Expected the assumed role's credentials, got %s", credentials.AccessKeyID)
+	}
+
+	expected := map[string]string{
+		"Action":              "AssumeRole",
+		"RoleArn":             options.RoleARN,
+		"ExternalId":          "audit-42",
+		"RoleSessionName":     "pikaatools-scan",
+		"Tags.member.1.Key":   "Command",
+		"Tags.member.1.Value": "scan",
+		"Tags.member.2.Key":   "Tool",
+		"Tags.member.2.Value": "pikaatools",
+	}
+	for key, value := range expected {
+		if form.Get(key) != value {
+			t.Errorf("Expected %s=%s in the AssumeRole request, got %q", key, value, form.Get(key))
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package aws
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketWait(t *testing.T) {
+	bucket := newTokenBucket(20, 2)
+	ctx := context.Background()
+
+	// The burst is available immediately
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := bucket.Wait(ctx); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("Expected burst to be served immediately, took %v", elapsed)
+	}
+
+	// Further calls wait for the bucket to refill
+	start = time.Now()
+	if err := bucket.Wait(ctx); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected call beyond the burst to be delayed, took %v", elapsed)
+	}
+}
+
+func TestTokenBucketWaitCancelled(t *testing.T) {
+	bucket := newTokenBucket(1, 1)
+	if err := bucket.Wait(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.Wait(ctx); err == nil {
+		t.Error("Expected an error when the context is done before a token is available")
+	}
+}
@@ -0,0 +1,225 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	oidcTypes "github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+const (
+	// ssoClientName is the name pikaatools registers with IAM Identity Center
+	ssoClientName = "pikaatools"
+	// ssoDeviceGrantType is the OAuth grant type of the device authorization flow
+	ssoDeviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	// ssoAccountAccessScope lets the token list and assume the user's account roles
+	ssoAccountAccessScope = "sso:account:access"
+)
+
+// SSOProfile holds the IAM Identity Center settings of a shared config profile
+type SSOProfile struct {
+	Profile string
+	// SessionName is the profile's sso-session, empty for legacy profiles that
+	// set sso_start_url and sso_region themselves
+	SessionName string
+	StartURL    string
+	Region      string
+}
+
+// cacheKey returns the key of the profile's token in the SSO token cache. The SDK
+// looks tokens up by session name, or by start URL for legacy profiles.
+func (p *SSOProfile) cacheKey() string {
+	if p.SessionName != "" {
+		return p.SessionName
+	}
+	return p.StartURL
+}
+
+// SSOLoginError reports that a profile signs in with IAM Identity Center and its
+// session has expired or was never started
+type SSOLoginError struct {
+	Profile string
+	Err     error
+}
+
+func (e *SSOLoginError) Error() string {
+	return fmt.Sprintf("the SSO session of profile %q has expired or was not started, run 'pikaatools login --profile %s': %v",
+		e.Profile, e.Profile, e.Err)
+}
+
+func (e *SSOLoginError) Unwrap() error {
+	return e.Err
+}
+
+// LoadSSOProfile returns the SSO settings of a shared config profile, or nil when
+// the profile does not sign in with IAM Identity Center. An empty profile is
+// AWS_PROFILE or the default profile.
+func LoadSSOProfile(ctx context.Context, profile string) (*SSOProfile, error) {
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	// Read the same config file as LoadDefaultConfig
+	shared, err := config.LoadSharedConfigProfile(ctx, profile, func(o *config.LoadSharedConfigOptions) {
+		if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+			o.ConfigFiles = []string{path}
+		}
+	})
+	if err != nil {
+		var notExist config.SharedConfigProfileNotExistError
+		if errors.As(err, &notExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load profile %s: %w", profile, err)
+	}
+
+	switch {
+	case shared.SSOSession != nil:
+		return &SSOProfile{Profile: profile, SessionName: shared.SSOSession.Name,
+			StartURL: shared.SSOSession.SSOStartURL, Region: shared.SSOSession.SSORegion}, nil
+	case shared.SSOStartURL != "":
+		return &SSOProfile{Profile: profile, StartURL: shared.SSOStartURL, Region: shared.SSORegion}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// checkSSOSession fetches credentials up front when the profile signs in with
+// IAM Identity Center, so an expired session fails with an SSOLoginError before
+// the scan starts instead of with a credentials error in its first API call
+func checkSSOSession(ctx context.Context, cfg aws.Config, profile string) error {
+	sso, err := LoadSSOProfile(ctx, profile)
+	if err != nil || sso == nil {
+		return nil
+	}
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return &SSOLoginError{Profile: sso.Profile, Err: err}
+	}
+	return nil
+}
+
+// SSOLogin signs in to IAM Identity Center with the device authorization flow and
+// caches the token where the SDK finds it. prompt shows the user the page to
+// approve the sign-in on and the code it should display. A non-empty
+// endpointURL sends the sign-in calls there, such as to a local emulator.
+func SSOLogin(ctx context.Context, sso *SSOProfile, endpointURL string, prompt func(verificationURL, userCode string)) error {
+	client := ssooidc.New(ssooidc.Options{Region: sso.Region}, func(o *ssooidc.Options) {
+		if endpointURL != "" {
+			o.BaseEndpoint = aws.String(endpointURL)
+		}
+	})
+
+	registration, err := client.RegisterClient(ctx, &ssooidc.RegisterClientInput{
+		ClientName: aws.String(ssoClientName),
+		ClientType: aws.String("public"),
+		Scopes:     []string{ssoAccountAccessScope},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register SSO client: %w", err)
+	}
+
+	authorization, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     registration.ClientId,
+		ClientSecret: registration.ClientSecret,
+		StartUrl:     aws.String(sso.StartURL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start SSO device authorization: %w", err)
+	}
+	prompt(aws.ToString(authorization.VerificationUriComplete), aws.ToString(authorization.UserCode))
+
+	interval := time.Duration(authorization.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(authorization.ExpiresIn) * time.Second)
+
+	for {
+		token, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+			ClientId:     registration.ClientId,
+			ClientSecret: registration.ClientSecret,
+			DeviceCode:   authorization.DeviceCode,
+			GrantType:    aws.String(ssoDeviceGrantType),
+		})
+		if err == nil {
+			return cacheSSOToken(sso, registration, token)
+		}
+
+		// Keep polling until the user approves the sign-in
+		var pending *oidcTypes.AuthorizationPendingException
+		var slowDown *oidcTypes.SlowDownException
+		switch {
+		case errors.As(err, &pending):
+		case errors.As(err, &slowDown):
+			interval += 5 * time.Second
+		default:
+			return fmt.Errorf("failed to create SSO token: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("SSO sign-in was not approved before the code expired")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ssoCachedToken is the SSO token cache file format shared with the AWS CLI and SDKs
+type ssoCachedToken struct {
+	StartURL              string `json:"startUrl"`
+	Region                string `json:"region"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+	ClientID              string `json:"clientId"`
+	ClientSecret          string `json:"clientSecret"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+}
+
+// cacheSSOToken writes a token to the SSO token cache
+func cacheSSOToken(sso *SSOProfile, registration *ssooidc.RegisterClientOutput, token *ssooidc.CreateTokenOutput) error {
+	path, err := ssocreds.StandardCachedTokenFilepath(sso.cacheKey())
+	if err != nil {
+		return fmt.Errorf("failed to find SSO token cache: %w", err)
+	}
+
+	cached := ssoCachedToken{
+		StartURL:     sso.StartURL,
+		Region:       sso.Region,
+		AccessToken:  aws.ToString(token.AccessToken),
+		ExpiresAt:    time.Now().Add(time.Duration(token.ExpiresIn) * time.Second).UTC().Format(time.RFC3339),
+		RefreshToken: aws.ToString(token.RefreshToken),
+		ClientID:     aws.ToString(registration.ClientId),
+		ClientSecret: aws.ToString(registration.ClientSecret),
+	}
+	if registration.ClientSecretExpiresAt > 0 {
+		cached.RegistrationExpiresAt = time.Unix(registration.ClientSecretExpiresAt, 0).UTC().Format(time.RFC3339)
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SSO token: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create SSO token cache: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write SSO token: %w", err)
+	}
+	return nil
+}
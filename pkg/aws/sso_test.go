@@ -0,0 +1,149 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+)
+
+const testSSOConfig = `[profile sso]
+sso_session = corp
+sso_account_id = 123456789012
+sso_role_name = NetworkAudit
+region = eu-west-1
+
+[profile legacy]
+sso_start_url = https://legacy.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 123456789012
+sso_role_name = NetworkAudit
+
+[profile keys]
+region = eu-west-1
+
+[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = eu-west-1
+`
+
+// setupSSOConfig points the shared config and the SSO token cache at a temporary home directory
+func setupSSOConfig(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(home, "credentials"))
+	configFile := filepath.Join(home, "config")
+	if err := os.WriteFile(configFile, []byte(testSSOConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	return home
+}
+
+func TestLoadSSOProfile(t *testing.T) {
+	setupSSOConfig(t)
+	ctx := context.Background()
+
+	sso, err := LoadSSOProfile(ctx, "sso")
+	if err != nil || sso == nil {
+		t.Fatalf("Expected an SSO profile, got %v, %v", sso, err)
+	}
+	if sso.SessionName != "corp" || sso.StartURL != "https://corp.awsapps.com/start" || sso.Region != "eu-west-1" || sso.cacheKey() != "corp" {
+		t.Errorf("Unexpected SSO profile %+v", sso)
+	}
+
+	sso, err = LoadSSOProfile(ctx, "legacy")
+	if err != nil || sso == nil || sso.cacheKey() != "https://legacy.awsapps.com/start" {
+		t.Errorf("Expected the legacy profile keyed by start URL, got %+v, %v", sso, err)
+	}
+
+	for _, profile := range []string{"keys", "missing"} {
+		if sso, err := LoadSSOProfile(ctx, profile); err != nil || sso != nil {
+			t.Errorf("Expected no SSO settings for profile %s, got %+v, %v", profile, sso, err)
+		}
+	}
+}
+
+func TestNewClientExpiredSSOSession(t *testing.T) {
+	setupSSOConfig(t)
+
+	_, err := NewClientWithOptions(context.Background(), "", "sso", DefaultOptions())
+	var loginErr *SSOLoginError
+	if !errors.As(err, &loginErr) || loginErr.Profile != "sso" {
+		t.Fatalf("Expected an SSO login error, got %v", err)
+	}
+}
+
+func TestSSOLogin(t *testing.T) {
+	home := setupSSOConfig(t)
+
+	tokenCalls := 0
+	oidc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/client/register":
+			w.Write([]byte(`{"clientId":"client-1","clientSecret":"secret-1","clientSecretExpiresAt":4102444800}`))
+		case "/device_authorization":
+			w.Write([]byte(`{"deviceCode":"device-1","userCode":"ABCD-EFGH","verificationUriComplete":"https://device.sso.eu-west-1.amazonaws.com/?user_code=ABCD-EFGH","interval":1,"expiresIn":600}`))
+		case "/token":
+			tokenCalls++
+			if tokenCalls == 1 {
+				w.Header().Set("X-Amzn-Errortype", "AuthorizationPendingException")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+			w.Write([]byte(`{"accessToken":"token-1","refreshToken":"refresh-1","expiresIn":28800,"tokenType":"Bearer"}`))
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer oidc.Close()
+
+	sso, err := LoadSSOProfile(context.Background(), "sso")
+	if err != nil {
+		t.Fatalf("LoadSSOProfile failed: %v", err)
+	}
+
+	var prompted string
+	err = SSOLogin(context.Background(), sso, oidc.URL, func(verificationURL, userCode string) {
+		prompted = userCode
+	})
+	if err != nil {
+		t.Fatalf("SSOLogin failed: %v", err)
+	}
+	if prompted != "ABCD-EFGH" || tokenCalls != 2 {
+		t.Errorf("Expected the user code to be shown and a pending poll, got %q after %d token calls", prompted, tokenCalls)
+	}
+
+	path, err := ssocreds.StandardCachedTokenFilepath("corp")
+	if err != nil {
+		t.Fatalf("Failed to find cache path: %v", err)
+	}
+	if !filepath.IsAbs(path) || filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(path)))) != home {
+		t.Fatalf("Expected the token cache under %s, got %s", home, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected a cached token: %v", err)
+	}
+	var cached ssoCachedToken
+	if err := json.Unmarshal(data, &cached); err != nil {
+		t.Fatalf("Failed to parse cached token: %v", err)
+	}
+	if cached.AccessToken != "token-1" || cached.RefreshToken != "refresh-1" || cached.ClientID != "client-1" ||
+		cached.StartURL != "https://corp.awsapps.com/start" || cached.ExpiresAt == "" {
+		t.Errorf("Unexpected cached token %+v", cached)
+	}
+}
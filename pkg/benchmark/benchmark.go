@@ -0,0 +1,418 @@
+// Package benchmark checks a scanned network against the network controls of
+// security standards: the CIS AWS Foundations Benchmark and AWS Foundational
+// Security Best Practices. Controls that depend on resources missing from the
+// scan are reported as not evaluated rather than passed.
+package benchmark
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/policy"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/sgaudit"
+)
+
+// Control statuses
+const (
+	StatusPass         = "pass"
+	StatusFail         = "fail"
+	StatusNotEvaluated = "not-evaluated"
+)
+
+// Finding is a resource that fails a control
+type Finding struct {
+	ResourceType string
+	ResourceID   string
+	Detail       string
+}
+
+// Control is one automated check of a standard
+type Control struct {
+	ID       string
+	Title    string
+	Severity policy.Severity
+	// check returns the resources failing the control
+	check func(network *scanner.Network) []Finding
+	// unavailable explains why the control cannot be evaluated against a network,
+	// or returns "" when it can
+	unavailable func(network *scanner.Network) string
+}
+
+// Standard is a set of controls
+type Standard struct {
+	Name     string
+	Title    string
+	Controls []Control
+}
+
+// adminPorts are the remote server administration ports of CIS 5.1 to 5.3
+var adminPorts = []int32{22, 3389}
+
+// highRiskPorts are the ports AWS Foundational Security Best Practices EC2.19
+// does not allow open to the internet
+var highRiskPorts = []int32{20, 21, 22, 23, 25, 110, 135, 143, 445, 1433, 1434, 3000, 3306, 3389, 4333, 5000, 5432, 5500, 5601, 8080, 8088, 8888, 9200, 9300}
+
+// flowLogsUnavailable explains why flow log controls cannot be evaluated
+func flowLogsUnavailable(network *scanner.Network) string {
+	if network.FlowLogs == nil {
+		return "flow logs were not scanned"
+	}
+	return ""
+}
+
+// standards are the built-in standards by name
+var standards = map[string]Standard{
+	"cis": {
+		Name:  "cis",
+		Title: "CIS AWS Foundations Benchmark v3.0.0 (networking)",
+		Controls: []Control{
+			{ID: "3.7", Title: "Ensure VPC flow logging is enabled in all VPCs", Severity: policy.SeverityMedium, check: vpcFlowLogsDisabled, unavailable: flowLogsUnavailable},
+			{ID: "5.1", Title: "Ensure no Network ACLs allow ingress from 0.0.0.0/0 to remote server administration ports", Severity: policy.SeverityMedium, check: naclAdminPortsOpen(adminPorts)},
+			{ID: "5.2", Title: "Ensure no security groups allow ingress from 0.0.0.0/0 to remote server administration ports", Severity: policy.SeverityHigh, check: securityGroupPortsOpen([]string{"0.0.0.0/0"}, adminPorts)},
+			{ID: "5.3", Title: "Ensure no security groups allow ingress from ::/0 to remote server administration ports", Severity: policy.SeverityHigh, check: securityGroupPortsOpen([]string{"::/0"}, adminPorts)},
+			{ID: "5.4", Title: "Ensure the default security group of every VPC restricts all traffic", Severity: policy.SeverityMedium, check: defaultSecurityGroupOpen},
+			{ID: "5.5", Title: "Ensure routing tables for VPC peering are \"least access\"", Severity: policy.SeverityLow, check: broadPeeringRoutes},
+		},
+	},
+	"fsbp": {
+		Name:  "fsbp",
+		Title: "AWS Foundational Security Best Practices (EC2 networking)",
+		Controls: []Control{
+			{ID: "EC2.2", Title: "VPC default security groups should not allow inbound or outbound traffic", Severity: policy.SeverityHigh, check: defaultSecurityGroupOpen},
+			{ID: "EC2.6", Title: "VPC flow logging should be enabled in all VPCs", Severity: policy.SeverityMedium, check: vpcFlowLogsDisabled, unavailable: flowLogsUnavailable},
+			{ID: "EC2.13", Title: "Security groups should not allow ingress from 0.0.0.0/0 or ::/0 to port 22", Severity: policy.SeverityHigh, check: securityGroupPortsOpen([]string{"0.0.0.0/0", "::/0"}, []int32{22})},
+			{ID: "EC2.14", Title: "Security groups should not allow ingress from 0.0.0.0/0 or ::/0 to port 3389", Severity: policy.SeverityHigh, check: securityGroupPortsOpen([]string{"0.0.0.0/0", "::/0"}, []int32{3389})},
+			{ID: "EC2.15", Title: "EC2 subnets should not automatically assign public IP addresses", Severity: policy.SeverityMedium, check: subnetsAssigningPublicIPs},
+			{ID: "EC2.18", Title: "Security groups should only allow unrestricted incoming traffic for authorized ports (80, 443)", Severity: policy.SeverityHigh, check: unauthorizedOpenPorts},
+			{ID: "EC2.19", Title: "Security groups should not allow unrestricted access to ports with high risk", Severity: policy.SeverityHigh, check: securityGroupPortsOpen([]string{"0.0.0.0/0", "::/0"}, highRiskPorts)},
+			{ID: "EC2.21", Title: "Network ACLs should not allow ingress from 0.0.0.0/0 to port 22 or port 3389", Severity: policy.SeverityMedium, check: naclAdminPortsOpen(adminPorts)},
+		},
+	},
+}
+
+// Standards returns the names of the built-in standards
+func Standards() []string {
+	var names []string
+	for name := range standards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns a built-in standard by name
+func Lookup(name string) (Standard, error) {
+	standard, ok := standards[strings.ToLower(name)]
+	if !ok {
+		return Standard{}, fmt.Errorf("unknown standard %q (want one of %s)", name, strings.Join(Standards(), ", "))
+	}
+	return standard, nil
+}
+
+// ControlResult is the outcome of one control
+type ControlResult struct {
+	ID         string          `json:"id"`
+	Title      string          `json:"title"`
+	Severity   policy.Severity `json:"severity"`
+	Status     string          `json:"status"`
+	Reason     string          `json:"reason,omitempty"`
+	Violations int             `json:"violations"`
+}
+
+// Report is the result of auditing a network against a standard
+type Report struct {
+	Standard   string             `json:"standard"`
+	Title      string             `json:"title"`
+	Controls   []ControlResult    `json:"controls"`
+	Violations []policy.Violation `json:"violations"`
+	// RuleUsage labels each security group rule used or unused from flow logs, when audited with them
+	RuleUsage *sgaudit.Report `json:"rule_usage,omitempty"`
+}
+
+// Audit runs every control of a standard against the network
+func Audit(standard Standard, network *scanner.Network) Report {
+	report := Report{Standard: standard.Name, Title: standard.Title, Violations: []policy.Violation{}}
+
+	for _, control := range standard.Controls {
+		result := ControlResult{ID: control.ID, Title: control.Title, Severity: control.Severity}
+
+		if control.unavailable != nil {
+			if reason := control.unavailable(network); reason != "" {
+				result.Status = StatusNotEvaluated
+				result.Reason = reason
+				report.Controls = append(report.Controls, result)
+				continue
+			}
+		}
+
+		findings := control.check(network)
+		for _, finding := range findings {
+			report.Violations = append(report.Violations, policy.Violation{
+				RuleID:       control.ID,
+				Description:  control.Title,
+				Severity:     control.Severity,
+				ResourceType: finding.ResourceType,
+				ResourceID:   finding.ResourceID,
+				Failures:     []string{finding.Detail},
+			})
+		}
+
+		result.Violations = len(findings)
+		result.Status = StatusPass
+		if len(findings) > 0 {
+			result.Status = StatusFail
+		}
+		report.Controls = append(report.Controls, result)
+	}
+
+	return report
+}
+
+// Violated reports whether any violation is at least as serious as a severity
+func (r Report) Violated(threshold policy.Severity) bool {
+	for _, violation := range r.Violations {
+		if violation.Severity.AtLeast(threshold) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text renders the report for the terminal
+func (r Report) Text() string {
+	var result strings.Builder
+
+	result.WriteString(fmt.Sprintf("%s\n\n", r.Title))
+
+	statusLabels := map[string]string{StatusPass: "PASS", StatusFail: "FAIL", StatusNotEvaluated: "N/A"}
+	counts := make(map[string]int)
+	for _, control := range r.Controls {
+		counts[control.Status]++
+		line := fmt.Sprintf("%-4s %-7s %s", statusLabels[control.Status], control.ID, control.Title)
+		switch control.Status {
+		case StatusFail:
+			line += fmt.Sprintf(" (%d)", control.Violations)
+		case StatusNotEvaluated:
+			line += fmt.Sprintf(" - %s", control.Reason)
+		}
+		result.WriteString(line + "\n")
+	}
+	result.WriteString(fmt.Sprintf("\nControls: %d passed, %d failed, %d not evaluated\n",
+		counts[StatusPass], counts[StatusFail], counts[StatusNotEvaluated]))
+
+	if len(r.Violations) > 0 {
+		result.WriteString("\nViolations:\n")
+		for _, violation := range r.Violations {
+			result.WriteString(fmt.Sprintf("  [%s] %s: %s %s - %s\n", strings.ToUpper(string(violation.Severity)),
+				violation.RuleID, violation.ResourceType, violation.ResourceID, strings.Join(violation.Failures, "; ")))
+		}
+	}
+
+	if r.RuleUsage != nil {
+		result.WriteString("\n" + r.RuleUsage.Text())
+	}
+
+	return result.String()
+}
+
+// securityGroupPortsOpen returns a check for ingress rules allowing any of the
+// ports from any of the sources
+func securityGroupPortsOpen(sources []string, ports []int32) func(*scanner.Network) []Finding {
+	return func(network *scanner.Network) []Finding {
+		var findings []Finding
+		for _, sg := range network.SecurityGroups {
+			for _, rule := range sg.IngressRules {
+				source := openSource(rule, sources)
+				if source == "" {
+					continue
+				}
+				var open []string
+				for _, port := range ports {
+					if ruleAllowsPort(rule, port) {
+						open = append(open, fmt.Sprint(port))
+					}
+				}
+				if len(open) > 0 {
+					findings = append(findings, Finding{
+						ResourceType: "SecurityGroup",
+						ResourceID:   sg.ID,
+						Detail:       fmt.Sprintf("ingress %s from %s allows port %s", ruleSummary(rule), source, strings.Join(open, ", ")),
+					})
+				}
+			}
+		}
+		return findings
+	}
+}
+
+// unauthorizedOpenPorts finds ingress rules open to the internet on ports other
+// than 80 and 443
+func unauthorizedOpenPorts(network *scanner.Network) []Finding {
+	var findings []Finding
+	for _, sg := range network.SecurityGroups {
+		for _, rule := range sg.IngressRules {
+			source := openSource(rule, []string{"0.0.0.0/0", "::/0"})
+			if source == "" {
+				continue
+			}
+			protocol := scanner.ProtocolNumber(rule.IpProtocol)
+			if protocol != -1 && protocol != 6 && protocol != 17 {
+				continue
+			}
+			authorized := protocol != -1 && rule.FromPort == rule.ToPort && (rule.FromPort == 80 || rule.FromPort == 443)
+			if !authorized {
+				findings = append(findings, Finding{
+					ResourceType: "SecurityGroup",
+					ResourceID:   sg.ID,
+					Detail:       fmt.Sprintf("ingress %s is open to %s", ruleSummary(rule), source),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// defaultSecurityGroupOpen finds default security groups with any rules
+func defaultSecurityGroupOpen(network *scanner.Network) []Finding {
+	var findings []Finding
+	for _, sg := range network.SecurityGroups {
+		if sg.Name != "default" {
+			continue
+		}
+		if len(sg.IngressRules) > 0 || len(sg.EgressRules) > 0 {
+			findings = append(findings, Finding{
+				ResourceType: "SecurityGroup",
+				ResourceID:   sg.ID,
+				Detail:       fmt.Sprintf("default security group of %s has %d ingress and %d egress rules", sg.VpcID, len(sg.IngressRules), len(sg.EgressRules)),
+			})
+		}
+	}
+	return findings
+}
+
+// naclAdminPortsOpen returns a check for network ACL entries allowing ingress to
+// any of the ports from anywhere
+func naclAdminPortsOpen(ports []int32) func(*scanner.Network) []Finding {
+	return func(network *scanner.Network) []Finding {
+		var findings []Finding
+		for _, nacl := range network.NetworkAcls {
+			for _, entry := range nacl.Entries {
+				if entry.Egress || entry.RuleAction != "allow" {
+					continue
+				}
+				source := ""
+				switch {
+				case entry.CidrBlock == "0.0.0.0/0":
+					source = entry.CidrBlock
+				case entry.Ipv6CidrBlock == "::/0":
+					source = entry.Ipv6CidrBlock
+				default:
+					continue
+				}
+
+				var open []string
+				for _, port := range ports {
+					if entryAllowsPort(entry, port) {
+						open = append(open, fmt.Sprint(port))
+					}
+				}
+				if len(open) > 0 {
+					findings = append(findings, Finding{
+						ResourceType: "NetworkAcl",
+						ResourceID:   nacl.ID,
+						Detail:       fmt.Sprintf("ingress entry %d from %s allows port %s", entry.RuleNumber, source, strings.Join(open, ", ")),
+					})
+				}
+			}
+		}
+		return findings
+	}
+}
+
+// broadPeeringRoutes finds routes sending a default route, or more than the peer
+// VPC's CIDR block, over a peering connection
+func broadPeeringRoutes(network *scanner.Network) []Finding {
+	vpcCidrs := make(map[string]string)
+	for _, vpc := range network.VPCs {
+		vpcCidrs[vpc.ID] = vpc.CidrBlock
+	}
+	peers := make(map[string]scanner.PeeringConnection)
+	for _, pc := range network.PeeringConnections {
+		peers[pc.ID] = pc
+	}
+
+	var findings []Finding
+	for _, rt := range network.RouteTables {
+		for _, route := range rt.Routes {
+			if route.VpcPeeringID == "" {
+				continue
+			}
+			destination := route.Destination()
+			if destination == "0.0.0.0/0" || destination == "::/0" {
+				findings = append(findings, Finding{
+					ResourceType: "RouteTable",
+					ResourceID:   rt.ID,
+					Detail:       fmt.Sprintf("route %s sends all traffic to %s", destination, route.VpcPeeringID),
+				})
+				continue
+			}
+
+			pc := peers[route.VpcPeeringID]
+			peerVpcID := pc.AccepterVpcID
+			if peerVpcID == rt.VpcID {
+				peerVpcID = pc.RequesterVpcID
+			}
+			if peerCidr := vpcCidrs[peerVpcID]; peerCidr != "" && broaderThan(destination, peerCidr) {
+				findings = append(findings, Finding{
+					ResourceType: "RouteTable",
+					ResourceID:   rt.ID,
+					Detail:       fmt.Sprintf("route %s to %s is broader than the peer VPC's %s", destination, route.VpcPeeringID, peerCidr),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// vpcFlowLogsDisabled finds VPCs without an active VPC flow log capturing
+// rejected traffic
+func vpcFlowLogsDisabled(network *scanner.Network) []Finding {
+	var findings []Finding
+	for _, vpc := range network.VPCs {
+		flowLogs := network.FlowLogsFor(vpc.ID)
+
+		detail := "no flow log captures the VPC's traffic"
+		enabled := false
+		for _, flowLog := range flowLogs {
+			switch {
+			case flowLog.Status != "ACTIVE":
+				detail = fmt.Sprintf("flow log %s is %s", flowLog.ID, strings.ToLower(flowLog.Status))
+			case flowLog.TrafficType == "ACCEPT":
+				detail = fmt.Sprintf("flow log %s captures accepted traffic only", flowLog.ID)
+			default:
+				enabled = true
+			}
+		}
+		if !enabled {
+			findings = append(findings, Finding{ResourceType: "VPC", ResourceID: vpc.ID, Detail: detail})
+		}
+	}
+	return findings
+}
+
+// subnetsAssigningPublicIPs finds subnets that give new instances a public IP
+func subnetsAssigningPublicIPs(network *scanner.Network) []Finding {
+	var findings []Finding
+	for _, subnet := range network.Subnets {
+		if subnet.MapPublicIP {
+			findings = append(findings, Finding{
+				ResourceType: "Subnet",
+				ResourceID:   subnet.ID,
+				Detail:       "assigns a public IPv4 address to new network interfaces",
+			})
+		}
+	}
+	return findings
+}
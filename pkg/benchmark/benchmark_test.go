@@ -0,0 +1,182 @@
+package benchmark
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/policy"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/sgaudit"
+)
+
+func testNetwork() *scanner.Network {
+	return &scanner.Network{
+		VPCs: []scanner.VPC{
+			{ID: "vpc-a", CidrBlock: "10.0.0.0/16"},
+			{ID: "vpc-b", CidrBlock: "10.1.0.0/16"},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-public", VpcID: "vpc-a", MapPublicIP: true},
+			{ID: "subnet-private", VpcID: "vpc-a"},
+		},
+		SecurityGroups: []scanner.SecurityGroup{
+			{ID: "sg-default", Name: "default", VpcID: "vpc-a", EgressRules: []scanner.SecurityGroupRule{{IpProtocol: "-1", CidrBlocks: []string{"0.0.0.0/0"}}}},
+			{ID: "sg-default-b", Name: "default", VpcID: "vpc-b"},
+			{ID: "sg-ssh", VpcID: "vpc-a", IngressRules: []scanner.SecurityGroupRule{
+				{IpProtocol: "tcp", FromPort: 0, ToPort: 1024, CidrBlocks: []string{"0.0.0.0/0"}},
+			}},
+			{ID: "sg-rdp6", VpcID: "vpc-a", IngressRules: []scanner.SecurityGroupRule{
+				{IpProtocol: "tcp", FromPort: 3389, ToPort: 3389, Ipv6CidrBlocks: []string{"::/0"}},
+			}},
+			{ID: "sg-web", VpcID: "vpc-a", IngressRules: []scanner.SecurityGroupRule{
+				{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}, Ipv6CidrBlocks: []string{"::/0"}},
+				{IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"10.0.0.0/8"}},
+				{IpProtocol: "icmp", FromPort: -1, ToPort: -1, CidrBlocks: []string{"0.0.0.0/0"}},
+			}},
+		},
+		NetworkAcls: []scanner.NetworkAcl{
+			{ID: "acl-open", Entries: []scanner.NetworkAclEntry{
+				{RuleNumber: 100, Protocol: "-1", RuleAction: "allow", CidrBlock: "0.0.0.0/0"},
+				{RuleNumber: 100, Protocol: "-1", RuleAction: "allow", CidrBlock: "0.0.0.0/0", Egress: true},
+				{RuleNumber: 32767, Protocol: "-1", RuleAction: "deny", CidrBlock: "0.0.0.0/0"},
+			}},
+			{ID: "acl-web", Entries: []scanner.NetworkAclEntry{
+				{RuleNumber: 100, Protocol: "6", RuleAction: "allow", CidrBlock: "0.0.0.0/0", PortRange: &scanner.NetworkAclPortRange{From: 443, To: 443}},
+			}},
+		},
+		PeeringConnections: []scanner.PeeringConnection{
+			{ID: "pcx-1", RequesterVpcID: "vpc-a", AccepterVpcID: "vpc-b"},
+		},
+		RouteTables: []scanner.RouteTable{
+			{ID: "rtb-broad", VpcID: "vpc-a", Routes: []scanner.Route{{DestinationCidr: "10.0.0.0/8", VpcPeeringID: "pcx-1"}}},
+			{ID: "rtb-exact", VpcID: "vpc-b", Routes: []scanner.Route{{DestinationCidr: "10.0.0.0/16", VpcPeeringID: "pcx-1"}}},
+		},
+	}
+}
+
+// violationsByControl groups the violated resource IDs of a report by control
+func violationsByControl(report Report) map[string]string {
+	violations := make(map[string][]string)
+	for _, violation := range report.Violations {
+		violations[violation.RuleID] = append(violations[violation.RuleID], violation.ResourceID)
+	}
+	joined := make(map[string]string)
+	for id, resources := range violations {
+		joined[id] = strings.Join(resources, ",")
+	}
+	return joined
+}
+
+func TestAuditCIS(t *testing.T) {
+	standard, err := Lookup("CIS")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report := Audit(standard, testNetwork())
+
+	want := map[string]string{
+		"5.1": "acl-open",
+		"5.2": "sg-ssh",
+		"5.3": "sg-rdp6",
+		"5.4": "sg-default",
+		"5.5": "rtb-broad",
+	}
+	got := violationsByControl(report)
+	for id, resources := range want {
+		if got[id] != resources {
+			t.Errorf("Control %s: expected violations %q, got %q", id, resources, got[id])
+		}
+	}
+
+	for _, control := range report.Controls {
+		if control.ID == "3.7" && control.Status != StatusNotEvaluated {
+			t.Errorf("Expected the flow log control not to be evaluated, got %s", control.Status)
+		}
+	}
+	if !report.Violated(policy.SeverityHigh) {
+		t.Error("Expected high severity violations")
+	}
+	if !strings.Contains(report.Text(), "FAIL 5.2") {
+		t.Errorf("Expected the failed control in the text report:\n%s", report.Text())
+	}
+}
+
+func TestAuditFSBP(t *testing.T) {
+	standard, err := Lookup("fsbp")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got := violationsByControl(Audit(standard, testNetwork()))
+
+	want := map[string]string{
+		"EC2.2":  "sg-default",
+		"EC2.13": "sg-ssh",
+		"EC2.14": "sg-rdp6",
+		"EC2.15": "subnet-public",
+		"EC2.18": "sg-ssh,sg-rdp6",
+		"EC2.19": "sg-ssh,sg-rdp6",
+		"EC2.21": "acl-open",
+	}
+	for id, resources := range want {
+		if got[id] != resources {
+			t.Errorf("Control %s: expected violations %q, got %q", id, resources, got[id])
+		}
+	}
+}
+
+func TestAuditCompliant(t *testing.T) {
+	standard, _ := Lookup("cis")
+	report := Audit(standard, &scanner.Network{})
+	if len(report.Violations) != 0 || report.Violated(policy.SeverityLow) {
+		t.Errorf("Expected no violations for an empty network, got %v", report.Violations)
+	}
+	for _, control := range report.Controls {
+		if control.Status == StatusFail {
+			t.Errorf("Expected control %s not to fail", control.ID)
+		}
+	}
+}
+
+func TestAuditFlowLogs(t *testing.T) {
+	network := &scanner.Network{
+		VPCs: []scanner.VPC{{ID: "vpc-all"}, {ID: "vpc-accept"}, {ID: "vpc-subnet-only"}, {ID: "vpc-none"}},
+		FlowLogs: []scanner.FlowLog{
+			{ID: "fl-1", ResourceID: "vpc-all", ResourceType: "VPC", VpcID: "vpc-all", TrafficType: "ALL", Status: "ACTIVE"},
+			{ID: "fl-2", ResourceID: "vpc-accept", ResourceType: "VPC", VpcID: "vpc-accept", TrafficType: "ACCEPT", Status: "ACTIVE"},
+			{ID: "fl-3", ResourceID: "subnet-1", ResourceType: "Subnet", VpcID: "vpc-subnet-only", TrafficType: "ALL", Status: "ACTIVE"},
+		},
+	}
+
+	standard, _ := Lookup("cis")
+	report := Audit(standard, network)
+	if got := violationsByControl(report)["3.7"]; got != "vpc-accept,vpc-subnet-only,vpc-none" {
+		t.Errorf("Expected the VPCs without a VPC flow log capturing rejected traffic, got %q", got)
+	}
+	if !strings.Contains(report.Text(), "fl-2 captures accepted traffic only") {
+		t.Errorf("Expected the reason in the text report:\n%s", report.Text())
+	}
+}
+
+func TestAuditRuleUsage(t *testing.T) {
+	standard, _ := Lookup("cis")
+	report := Audit(standard, testNetwork())
+	if strings.Contains(report.Text(), "Security Group Rule Usage") {
+		t.Errorf("Expected no rule usage without flow logs:\n%s", report.Text())
+	}
+
+	report.RuleUsage = &sgaudit.Report{Unused: 1, Rules: []sgaudit.Finding{
+		{GroupID: "sg-web", Direction: "ingress", Rule: "tcp 22-22 0.0.0.0/0", Status: sgaudit.StatusUnused, Notes: []string{sgaudit.NoteOpenToInternet}},
+	}}
+	text := report.Text()
+	for _, expected := range []string{"Security Group Rule Usage", "sg-web", "tcp 22-22 0.0.0.0/0", "Unused: 1"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in the text report:\n%s", expected, text)
+		}
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	if _, err := Lookup("pci"); err == nil {
+		t.Error("Expected an error for an unknown standard")
+	}
+}
@@ -0,0 +1,54 @@
+package benchmark
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// openSource returns the first of the sources a rule allows traffic from
+func openSource(rule scanner.SecurityGroupRule, sources []string) string {
+	for _, source := range sources {
+		for _, cidr := range append(append([]string{}, rule.CidrBlocks...), rule.Ipv6CidrBlocks...) {
+			if cidr == source {
+				return source
+			}
+		}
+	}
+	return ""
+}
+
+// ruleAllowsPort reports whether a security group rule allows TCP or UDP traffic to a port
+func ruleAllowsPort(rule scanner.SecurityGroupRule, port int32) bool {
+	return rule.AllowsPort("tcp", port) || rule.AllowsPort("udp", port)
+}
+
+// entryAllowsPort reports whether a network ACL entry allows TCP or UDP traffic to a port
+func entryAllowsPort(entry scanner.NetworkAclEntry, port int32) bool {
+	return entry.AllowsPorts("tcp", port, port) || entry.AllowsPorts("udp", port, port)
+}
+
+// ruleSummary describes a security group rule's protocol and ports
+func ruleSummary(rule scanner.SecurityGroupRule) string {
+	if scanner.ProtocolNumber(rule.IpProtocol) == -1 {
+		return "all traffic"
+	}
+	return fmt.Sprintf("%s %d-%d", rule.IpProtocol, rule.FromPort, rule.ToPort)
+}
+
+// broaderThan reports whether a CIDR block strictly contains another
+func broaderThan(cidr, other string) bool {
+	_, outer, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	_, inner, err := net.ParseCIDR(other)
+	if err != nil {
+		return false
+	}
+
+	outerBits, outerSize := outer.Mask.Size()
+	innerBits, innerSize := inner.Mask.Size()
+	return outerSize == innerSize && outerBits < innerBits && outer.Contains(inner.IP)
+}
@@ -0,0 +1,303 @@
+package capacity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Status is how close a subnet is to running out of IP addresses
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusWarning  Status = "warning"
+	StatusCritical Status = "critical"
+	// StatusUnknown means the available address count was not scanned
+	StatusUnknown Status = "unknown"
+)
+
+// statusOrder sorts the most urgent status first
+var statusOrder = map[Status]int{StatusCritical: 0, StatusWarning: 1, StatusOK: 2, StatusUnknown: 3}
+
+// Limits are the utilization percentages and forecast horizon that raise a subnet's status
+type Limits struct {
+	Warning     float64 `yaml:"warning" json:"warning"`
+	Critical    float64 `yaml:"critical" json:"critical"`
+	HorizonDays int     `yaml:"horizon-days" json:"horizon_days"`
+}
+
+// Validate checks that the limits are percentages with the warning below the critical limit
+func (l Limits) Validate() error {
+	if l.Warning < 0 || l.Warning > 100 || l.Critical < 0 || l.Critical > 100 {
+		return fmt.Errorf("utilization limits must be between 0 and 100")
+	}
+	if l.Warning != 0 && l.Critical != 0 && l.Warning > l.Critical {
+		return fmt.Errorf("warning limit %.0f%% is above the critical limit %.0f%%", l.Warning, l.Critical)
+	}
+	if l.HorizonDays < 0 {
+		return fmt.Errorf("horizon must not be negative")
+	}
+	return nil
+}
+
+// Thresholds are the default limits and the limits of specific environments.
+// Limits an environment leaves unset fall back to the defaults.
+type Thresholds struct {
+	Default      Limits
+	Environments map[string]Limits
+}
+
+// For returns the limits of an environment, matched case-insensitively
+func (t Thresholds) For(environment string) Limits {
+	limits := t.Default
+	for name, env := range t.Environments {
+		if !strings.EqualFold(name, environment) {
+			continue
+		}
+		if env.Warning != 0 {
+			limits.Warning = env.Warning
+		}
+		if env.Critical != 0 {
+			limits.Critical = env.Critical
+		}
+		if env.HorizonDays != 0 {
+			limits.HorizonDays = env.HorizonDays
+		}
+	}
+	return limits
+}
+
+// SubnetForecast is the IP address usage of a subnet and when it is expected to run out
+type SubnetForecast struct {
+	SubnetID         string     `json:"subnet_id"`
+	Name             string     `json:"name,omitempty"`
+	VpcID            string     `json:"vpc_id"`
+	CidrBlock        string     `json:"cidr_block"`
+	Environment      string     `json:"environment,omitempty"`
+	TotalIPs         int        `json:"total_ips"`
+	AvailableIPs     *int       `json:"available_ips,omitempty"`
+	Utilization      float64    `json:"utilization"` // Percent of usable addresses in use
+	Samples          int        `json:"samples"`
+	GrowthPerDay     *float64   `json:"growth_per_day,omitempty"`     // Addresses used per day, nil without history
+	DaysToExhaustion *float64   `json:"days_to_exhaustion,omitempty"` // Nil when usage is not growing
+	ExhaustionDate   *time.Time `json:"exhaustion_date,omitempty"`
+	Status           Status     `json:"status"`
+	Reasons          []string   `json:"reasons,omitempty"`
+	Limits           Limits     `json:"limits"`
+}
+
+// Report is the IP exhaustion forecast of every subnet
+type Report struct {
+	ScanTime  time.Time        `json:"scan_time"`
+	Snapshots int              `json:"snapshots"`
+	Subnets   []SubnetForecast `json:"subnets"`
+}
+
+// sample is the number of addresses in use in a subnet at a point in time
+type sample struct {
+	at   time.Time
+	used float64
+}
+
+// Environment returns the environment of a subnet from its tag, or from its VPC's tag
+func Environment(network *scanner.Network, subnet scanner.Subnet, tag string) string {
+	if env := subnet.Tags[tag]; env != "" {
+		return env
+	}
+	for _, vpc := range network.VPCs {
+		if vpc.ID == subnet.VpcID {
+			return vpc.Tags[tag]
+		}
+	}
+	return ""
+}
+
+// Forecast predicts when each subnet of the current network will run out of IP
+// addresses from the trend of its usage across the historical snapshots
+func Forecast(current *scanner.Network, history []*scanner.Network, thresholds Thresholds, environmentTag string) Report {
+	report := Report{ScanTime: current.ScanTime, Snapshots: len(history)}
+
+	// Usage per subnet, once per scan time
+	samples := make(map[string]map[time.Time]float64)
+	addSamples := func(network *scanner.Network) {
+		for _, subnet := range network.Subnets {
+			if subnet.AvailableIPs == nil || network.ScanTime.IsZero() {
+				continue
+			}
+			if samples[subnet.ID] == nil {
+				samples[subnet.ID] = make(map[time.Time]float64)
+			}
+			samples[subnet.ID][network.ScanTime] = float64(subnet.UsableIPv4AddressCount() - subnet.FreeIPv4AddressCount())
+		}
+	}
+	for _, network := range history {
+		addSamples(network)
+	}
+	addSamples(current)
+
+	for _, subnet := range current.Subnets {
+		environment := Environment(current, subnet, environmentTag)
+		forecast := SubnetForecast{
+			SubnetID:    subnet.ID,
+			Name:        subnet.Name,
+			VpcID:       subnet.VpcID,
+			CidrBlock:   subnet.CidrBlock,
+			Environment: environment,
+			TotalIPs:    subnet.UsableIPv4AddressCount(),
+			Samples:     len(samples[subnet.ID]),
+			Limits:      thresholds.For(environment),
+		}
+
+		utilization, ok := subnet.Utilization()
+		if !ok {
+			forecast.Status = StatusUnknown
+			report.Subnets = append(report.Subnets, forecast)
+			continue
+		}
+
+		// Addresses held by CIDR reservations cannot be assigned, so they are not available
+		available := subnet.FreeIPv4AddressCount()
+		forecast.AvailableIPs = &available
+		forecast.Utilization = utilization
+
+		var points []sample
+		for at, used := range samples[subnet.ID] {
+			points = append(points, sample{at: at, used: used})
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i].at.Before(points[j].at) })
+		if growth, ok := growthPerDay(points); ok {
+			forecast.GrowthPerDay = &growth
+			if growth > 0 {
+				days := float64(available) / growth
+				exhaustion := current.ScanTime.Add(time.Duration(days * float64(24*time.Hour)))
+				forecast.DaysToExhaustion = &days
+				forecast.ExhaustionDate = &exhaustion
+			}
+		}
+
+		forecast.Status, forecast.Reasons = classify(forecast, available)
+		report.Subnets = append(report.Subnets, forecast)
+	}
+
+	sort.SliceStable(report.Subnets, func(i, j int) bool {
+		a, b := report.Subnets[i], report.Subnets[j]
+		if statusOrder[a.Status] != statusOrder[b.Status] {
+			return statusOrder[a.Status] < statusOrder[b.Status]
+		}
+		if (a.DaysToExhaustion == nil) != (b.DaysToExhaustion == nil) {
+			return a.DaysToExhaustion != nil
+		}
+		if a.DaysToExhaustion != nil && *a.DaysToExhaustion != *b.DaysToExhaustion {
+			return *a.DaysToExhaustion < *b.DaysToExhaustion
+		}
+		if a.Utilization != b.Utilization {
+			return a.Utilization > b.Utilization
+		}
+		return a.SubnetID < b.SubnetID
+	})
+
+	return report
+}
+
+// growthPerDay fits a line through the usage samples and returns its slope in
+// addresses per day. At least two samples at different times are needed.
+func growthPerDay(points []sample) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+
+	origin := points[0].at
+	var sumX, sumY float64
+	for _, p := range points {
+		sumX += p.at.Sub(origin).Hours() / 24
+		sumY += p.used
+	}
+	n := float64(len(points))
+	meanX, meanY := sumX/n, sumY/n
+
+	var covariance, variance float64
+	for _, p := range points {
+		dx := p.at.Sub(origin).Hours()/24 - meanX
+		covariance += dx * (p.used - meanY)
+		variance += dx * dx
+	}
+	if variance == 0 {
+		return 0, false
+	}
+	return covariance / variance, true
+}
+
+// classify decides the status of a subnet from its utilization and forecast
+func classify(forecast SubnetForecast, available int) (Status, []string) {
+	limits := forecast.Limits
+	status := StatusOK
+	var reasons []string
+	raise := func(to Status, reason string) {
+		if statusOrder[to] < statusOrder[status] {
+			status = to
+		}
+		reasons = append(reasons, reason)
+	}
+
+	switch {
+	case available == 0:
+		raise(StatusCritical, "no addresses left")
+	case limits.Critical > 0 && forecast.Utilization >= limits.Critical:
+		raise(StatusCritical, fmt.Sprintf("utilization at or above %.0f%%", limits.Critical))
+	case limits.Warning > 0 && forecast.Utilization >= limits.Warning:
+		raise(StatusWarning, fmt.Sprintf("utilization at or above %.0f%%", limits.Warning))
+	}
+
+	if available > 0 && forecast.DaysToExhaustion != nil && limits.HorizonDays > 0 &&
+		*forecast.DaysToExhaustion <= float64(limits.HorizonDays) {
+		raise(StatusWarning, fmt.Sprintf("expected to run out within %d days", limits.HorizonDays))
+	}
+
+	return status, reasons
+}
+
+// Counts returns the number of subnets in each status
+func (r Report) Counts() map[Status]int {
+	counts := make(map[Status]int)
+	for _, subnet := range r.Subnets {
+		counts[subnet.Status]++
+	}
+	return counts
+}
+
+// Text renders the forecast for the terminal
+func (r Report) Text() string {
+	var result strings.Builder
+
+	counts := r.Counts()
+	result.WriteString("Subnet IP Exhaustion Forecast\n\n")
+	result.WriteString(fmt.Sprintf("Subnets: %d (%d critical, %d warning)\n", len(r.Subnets), counts[StatusCritical], counts[StatusWarning]))
+	result.WriteString(fmt.Sprintf("Historical snapshots: %d\n\n", r.Snapshots))
+
+	result.WriteString(fmt.Sprintf("%-26s %-23s %-12s %-13s %-6s %-10s %-12s %s\n",
+		"SUBNET", "VPC", "ENVIRONMENT", "USED", "UTIL", "PER DAY", "EXHAUSTS", "STATUS"))
+	for _, subnet := range r.Subnets {
+		used, utilization, growth, exhausts := "-", "-", "-", "-"
+		if subnet.AvailableIPs != nil {
+			used = fmt.Sprintf("%d/%d", subnet.TotalIPs-*subnet.AvailableIPs, subnet.TotalIPs)
+			utilization = fmt.Sprintf("%.0f%%", subnet.Utilization)
+		}
+		if subnet.GrowthPerDay != nil {
+			growth = fmt.Sprintf("%+.1f", *subnet.GrowthPerDay)
+		}
+		if subnet.ExhaustionDate != nil {
+			exhausts = subnet.ExhaustionDate.Format("2006-01-02")
+		}
+		result.WriteString(fmt.Sprintf("%-26s %-23s %-12s %-13s %-6s %-10s %-12s %s\n",
+			subnet.SubnetID, subnet.VpcID, subnet.Environment, used, utilization, growth, exhausts, subnet.Status))
+		for _, reason := range subnet.Reasons {
+			result.WriteString(fmt.Sprintf("  %s\n", reason))
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,172 @@
+package capacity
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// snapshot builds a network at a scan time with the available addresses of each subnet
+func snapshot(at time.Time, available map[string]int) *scanner.Network {
+	network := &scanner.Network{
+		ScanTime: at,
+		VPCs: []scanner.VPC{
+			{ID: "vpc-prod", Tags: map[string]string{"Environment": "prod"}},
+			{ID: "vpc-dev", Tags: map[string]string{"Environment": "dev"}},
+		},
+	}
+	subnets := []scanner.Subnet{
+		{ID: "subnet-growing", VpcID: "vpc-prod", CidrBlock: "10.0.1.0/24"},
+		{ID: "subnet-full", VpcID: "vpc-prod", CidrBlock: "10.0.2.0/28"},
+		{ID: "subnet-busy", VpcID: "vpc-dev", CidrBlock: "10.1.1.0/24", Tags: map[string]string{"Environment": "sandbox"}},
+		{ID: "subnet-idle", VpcID: "vpc-dev", CidrBlock: "10.1.2.0/24"},
+		{ID: "subnet-old", VpcID: "vpc-dev", CidrBlock: "10.1.3.0/24"},
+	}
+	for _, subnet := range subnets {
+		if count, ok := available[subnet.ID]; ok {
+			subnet.AvailableIPs = intPtr(count)
+		}
+		network.Subnets = append(network.Subnets, subnet)
+	}
+	return network
+}
+
+func TestForecast(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	history := []*scanner.Network{
+		snapshot(now.Add(-20*24*time.Hour), map[string]int{"subnet-growing": 151, "subnet-idle": 200}),
+		snapshot(now.Add(-10*24*time.Hour), map[string]int{"subnet-growing": 131, "subnet-idle": 200}),
+	}
+	current := snapshot(now, map[string]int{"subnet-growing": 111, "subnet-full": 0, "subnet-busy": 40, "subnet-idle": 200})
+
+	thresholds := Thresholds{
+		Default:      Limits{Warning: 80, Critical: 95, HorizonDays: 30},
+		Environments: map[string]Limits{"PROD": {HorizonDays: 90}, "sandbox": {Warning: 90}},
+	}
+	report := Forecast(current, history, thresholds, "Environment")
+
+	byID := make(map[string]SubnetForecast)
+	var order []string
+	for _, subnet := range report.Subnets {
+		byID[subnet.SubnetID] = subnet
+		order = append(order, subnet.SubnetID)
+	}
+	if got := strings.Join(order, ","); got != "subnet-full,subnet-growing,subnet-busy,subnet-idle,subnet-old" {
+		t.Errorf("Unexpected order: %s", got)
+	}
+
+	// 2 addresses a day with 111 left runs out in 55.5 days, within the prod horizon of 90
+	growing := byID["subnet-growing"]
+	if growing.Samples != 3 || growing.GrowthPerDay == nil || *growing.GrowthPerDay != 2 {
+		t.Fatalf("Expected growth of 2 addresses a day from 3 samples, got %+v", growing)
+	}
+	if growing.DaysToExhaustion == nil || *growing.DaysToExhaustion != 55.5 || growing.Status != StatusWarning {
+		t.Errorf("Expected a warning for exhaustion in 55.5 days, got %+v", growing)
+	}
+	if growing.ExhaustionDate == nil || growing.ExhaustionDate.Format("2006-01-02") != "2024-04-25" {
+		t.Errorf("Unexpected exhaustion date: %v", growing.ExhaustionDate)
+	}
+
+	if full := byID["subnet-full"]; full.Status != StatusCritical || full.Utilization != 100 || full.Environment != "prod" {
+		t.Errorf("Expected the full subnet to be critical, got %+v", full)
+	}
+
+	// 84% used is below the sandbox warning limit taken from the subnet's own tag
+	if busy := byID["subnet-busy"]; busy.Environment != "sandbox" || busy.Limits.Warning != 90 || busy.Status != StatusOK || busy.GrowthPerDay != nil {
+		t.Errorf("Expected the sandbox subnet to be ok without history, got %+v", busy)
+	}
+
+	if idle := byID["subnet-idle"]; idle.GrowthPerDay == nil || *idle.GrowthPerDay != 0 || idle.DaysToExhaustion != nil || idle.Status != StatusOK {
+		t.Errorf("Expected the idle subnet not to be forecast to run out, got %+v", idle)
+	}
+	if old := byID["subnet-old"]; old.Status != StatusUnknown {
+		t.Errorf("Expected a subnet without an available count to be unknown, got %s", old.Status)
+	}
+
+	text := report.Text()
+	if !strings.Contains(text, "Subnets: 5 (1 critical, 1 warning)") || !strings.Contains(text, "expected to run out within 90 days") {
+		t.Errorf("Unexpected text report:\n%s", text)
+	}
+}
+
+func TestForecastCidrReservations(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	reserved := func(network *scanner.Network) *scanner.Network {
+		for i := range network.Subnets {
+			if network.Subnets[i].ID == "subnet-growing" {
+				network.Subnets[i].CidrReservations = []scanner.SubnetCidrReservation{{Cidr: "10.0.1.192/26", ReservationType: "prefix"}}
+			}
+		}
+		return network
+	}
+	history := []*scanner.Network{
+		reserved(snapshot(now.Add(-20*24*time.Hour), map[string]int{"subnet-growing": 151})),
+		reserved(snapshot(now.Add(-10*24*time.Hour), map[string]int{"subnet-growing": 131})),
+	}
+	current := reserved(snapshot(now, map[string]int{"subnet-growing": 111}))
+
+	report := Forecast(current, history, Thresholds{Default: Limits{Warning: 80, Critical: 95, HorizonDays: 30}}, "Environment")
+
+	// 64 of the 111 unused addresses are reserved, so 47 are left at 2 a day
+	for _, subnet := range report.Subnets {
+		if subnet.SubnetID != "subnet-growing" {
+			continue
+		}
+		if subnet.AvailableIPs == nil || *subnet.AvailableIPs != 47 {
+			t.Errorf("Expected 47 available addresses, got %v", subnet.AvailableIPs)
+		}
+		if subnet.DaysToExhaustion == nil || *subnet.DaysToExhaustion != 23.5 || subnet.Status != StatusWarning {
+			t.Errorf("Expected a warning for exhaustion in 23.5 days, got %+v", subnet)
+		}
+		return
+	}
+	t.Fatal("Expected a forecast for subnet-growing")
+}
+
+func TestLimitsValidate(t *testing.T) {
+	if err := (Limits{Warning: 90, Critical: 80}).Validate(); err == nil {
+		t.Error("Expected an error for a warning limit above the critical limit")
+	}
+	if err := (Limits{Warning: 80, Critical: 120}).Validate(); err == nil {
+		t.Error("Expected an error for a limit above 100%")
+	}
+	if err := (Limits{Warning: 80}).Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, value interface{}) string {
+		data, _ := json.Marshal(value)
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("a.json", snapshot(time.Now().Add(-time.Hour), nil))
+	write("b.json", snapshot(time.Now(), nil))
+	unscanned := write("rules.json", map[string]string{"not": "a working state"})
+
+	history, err := LoadHistory([]string{dir})
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Errorf("Expected 2 snapshots from the directory, got %d", len(history))
+	}
+
+	if _, err := LoadHistory([]string{unscanned}); err == nil {
+		t.Error("Expected an error for a named file that is not a working state")
+	}
+}
@@ -0,0 +1,75 @@
+package capacity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/statefile"
+)
+
+// LoadHistory reads the working states saved at earlier scans. Each path is a working
+// state file or a directory tree of them; JSON files in a directory that are not
+// working states are skipped.
+func LoadHistory(paths []string) ([]*scanner.Network, error) {
+	var history []*scanner.Network
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+		}
+
+		if !info.IsDir() {
+			network, err := loadSnapshot(path)
+			if err != nil {
+				return nil, err
+			}
+			if network.ScanTime.IsZero() {
+				return nil, fmt.Errorf("snapshot %s has no scan time", path)
+			}
+			history = append(history, network)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || filepath.Ext(file) != ".json" {
+				return nil
+			}
+			network, err := loadSnapshot(file)
+			if err != nil || network.ScanTime.IsZero() {
+				// Not a working state, skip it
+				return nil
+			}
+			history = append(history, network)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshots in %s: %w", path, err)
+		}
+	}
+
+	return history, nil
+}
+
+// loadSnapshot reads one working state file
+func loadSnapshot(filename string) (*scanner.Network, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", filename, err)
+	}
+	if data, err = statefile.Decompress(data); err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot %s: %w", filename, err)
+	}
+
+	var network scanner.Network
+	if err := json.Unmarshal(data, &network); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", filename, err)
+	}
+	return &network, nil
+}
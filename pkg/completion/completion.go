@@ -0,0 +1,144 @@
+package completion
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Cache remembers the regions and VPCs seen by earlier scans so shell completion
+// can suggest them without calling AWS
+type Cache struct {
+	Regions map[string]Region `json:"regions"`
+}
+
+// Region holds the VPCs last seen in a region
+type Region struct {
+	ScannedAt time.Time `json:"scanned_at"`
+	VPCs      []VPC     `json:"vpcs"`
+}
+
+// VPC is a completion candidate
+type VPC struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// DefaultPath returns the cache file in the user's cache directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find cache directory: %w", err)
+	}
+	return filepath.Join(dir, "pikaatools", "completion.json"), nil
+}
+
+// Load reads the cache at path, or returns an empty cache if there is none
+func Load(path string) (*Cache, error) {
+	cache := &Cache{Regions: make(map[string]Region)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read completion cache: %w", err)
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("failed to parse completion cache %s: %w", path, err)
+	}
+	if cache.Regions == nil {
+		cache.Regions = make(map[string]Region)
+	}
+	return cache, nil
+}
+
+// Record replaces the VPCs of a scan's region. A scan limited to one VPC only
+// adds or updates that VPC.
+func (c *Cache) Record(network *scanner.Network, vpcID string) {
+	if network.Region == "" {
+		return
+	}
+
+	vpcs := make(map[string]VPC)
+	if vpcID != "" {
+		for _, vpc := range c.Regions[network.Region].VPCs {
+			vpcs[vpc.ID] = vpc
+		}
+	}
+	for _, vpc := range network.VPCs {
+		vpcs[vpc.ID] = VPC{ID: vpc.ID, Name: vpc.Name}
+	}
+
+	region := Region{ScannedAt: network.ScanTime}
+	for _, vpc := range vpcs {
+		region.VPCs = append(region.VPCs, vpc)
+	}
+	sort.Slice(region.VPCs, func(i, j int) bool { return region.VPCs[i].ID < region.VPCs[j].ID })
+	c.Regions[network.Region] = region
+}
+
+// Save writes the cache to path, creating its directory
+func (c *Cache) Save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal completion cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create completion cache directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write completion cache: %w", err)
+	}
+	return nil
+}
+
+// RegionNames returns the scanned regions starting with prefix, most recently scanned first
+func (c *Cache) RegionNames(prefix string) []string {
+	var names []string
+	for name := range c.Regions {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := c.Regions[names[i]], c.Regions[names[j]]
+		if !a.ScannedAt.Equal(b.ScannedAt) {
+			return a.ScannedAt.After(b.ScannedAt)
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// VPCIDs returns "id\tname" completions for the VPCs whose ID starts with prefix,
+// from one region or from every region when region is empty
+func (c *Cache) VPCIDs(region, prefix string) []string {
+	var completions []string
+	for _, name := range c.RegionNames("") {
+		if region != "" && name != region {
+			continue
+		}
+		for _, vpc := range c.Regions[name].VPCs {
+			if !strings.HasPrefix(vpc.ID, prefix) {
+				continue
+			}
+			description := vpc.Name
+			if region == "" {
+				description = strings.TrimSpace(vpc.Name + " " + name)
+			}
+			if description == "" {
+				completions = append(completions, vpc.ID)
+			} else {
+				completions = append(completions, vpc.ID+"\t"+description)
+			}
+		}
+	}
+	return completions
+}
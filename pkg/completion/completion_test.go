@@ -0,0 +1,50 @@
+package completion
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pikaatools", "completion.json")
+	cache, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	now := time.Now()
+	cache.Record(&scanner.Network{Region: "us-east-1", ScanTime: now.Add(-time.Hour), VPCs: []scanner.VPC{
+		{ID: "vpc-b", Name: "prod"}, {ID: "vpc-a"},
+	}}, "")
+	cache.Record(&scanner.Network{Region: "eu-west-1", ScanTime: now, VPCs: []scanner.VPC{{ID: "vpc-c", Name: "dev"}}}, "")
+	// A scan of one VPC keeps the others
+	cache.Record(&scanner.Network{Region: "us-east-1", ScanTime: now.Add(-time.Hour), VPCs: []scanner.VPC{{ID: "vpc-b", Name: "production"}}}, "vpc-b")
+
+	if err := cache.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	cache, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if regions := cache.RegionNames(""); !reflect.DeepEqual(regions, []string{"eu-west-1", "us-east-1"}) {
+		t.Errorf("Expected the most recently scanned region first, got %v", regions)
+	}
+	if regions := cache.RegionNames("us"); !reflect.DeepEqual(regions, []string{"us-east-1"}) {
+		t.Errorf("Expected regions matching the prefix, got %v", regions)
+	}
+
+	expected := []string{"vpc-a", "vpc-b\tproduction"}
+	if vpcs := cache.VPCIDs("us-east-1", "vpc-"); !reflect.DeepEqual(vpcs, expected) {
+		t.Errorf("Expected %v, got %v", expected, vpcs)
+	}
+	expected = []string{"vpc-c\tdev eu-west-1", "vpc-a\tus-east-1", "vpc-b\tproduction us-east-1"}
+	if vpcs := cache.VPCIDs("", ""); !reflect.DeepEqual(vpcs, expected) {
+		t.Errorf("Expected %v, got %v", expected, vpcs)
+	}
+}
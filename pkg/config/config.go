@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/capacity"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+// DefaultFile is the config file loaded from the home directory when present
+const DefaultFile = ".pikaatools.yaml"
+
+// fromConfigAnnotation marks the flags Apply set, which pflag reports as changed
+// just like flags given on the command line
+const fromConfigAnnotation = "pikaatools-config-file"
+
+// File holds persistent defaults for command-line flags. Top-level keys are flag
+// names and apply to every command that has the flag; keys under a command's
+// name in commands only apply to that command and take precedence. Flags given
+// on the command line always win.
+type File struct {
+	Path        string                            `yaml:"-"`
+	Flags       map[string]interface{}            `yaml:",inline"`
+	Commands    map[string]map[string]interface{} `yaml:"commands"`
+	IgnoreRules []watch.IgnoreRule                `yaml:"ignore-rules"`
+	// CapacityThresholds are the ip-forecast limits of each environment
+	CapacityThresholds map[string]capacity.Limits `yaml:"capacity-thresholds"`
+}
+
+// DefaultPath returns the path of the config file in the home directory
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, DefaultFile), nil
+}
+
+// Load reads a config file
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	file := &File{Path: path}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	rules := watch.IgnoreRules{Rules: file.IgnoreRules}
+	if err := rules.Validate(path); err != nil {
+		return nil, err
+	}
+	for environment, limits := range file.CapacityThresholds {
+		if err := limits.Validate(); err != nil {
+			return nil, fmt.Errorf("config file %s: capacity thresholds of %s: %w", path, environment, err)
+		}
+	}
+	return file, nil
+}
+
+// Apply sets the flags of a command that were not given on the command line from
+// the config file. The command is named by its path below the root command,
+// such as "watch" or "export terraform".
+func (f *File) Apply(command string, flags *pflag.FlagSet) error {
+	commandValues := f.Commands[command]
+
+	// A command section may only name flags of that command
+	for _, name := range sortedKeys(commandValues) {
+		if flags.Lookup(name) == nil {
+			return fmt.Errorf("config file %s: command %q has no flag --%s", f.Path, command, name)
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "config" {
+			return
+		}
+		value, ok := commandValues[flag.Name]
+		if !ok {
+			value, ok = f.Flags[flag.Name]
+		}
+		if !ok {
+			return
+		}
+		if err = setFlag(flags, flag.Name, value); err == nil {
+			err = flags.SetAnnotation(flag.Name, fromConfigAnnotation, []string{f.Path})
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+// GivenOnCommandLine reports whether a flag was given on the command line, as
+// opposed to left at its default or set from the config file
+func GivenOnCommandLine(flags *pflag.FlagSet, name string) bool {
+	flag := flags.Lookup(name)
+	return flag != nil && flag.Changed && flag.Annotations[fromConfigAnnotation] == nil
+}
+
+// setFlag sets a flag from a YAML value. Each item of a list is set in turn, so
+// repeatable and comma-separated list flags get every item.
+func setFlag(flags *pflag.FlagSet, name string, value interface{}) error {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := setFlag(flags, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		return fmt.Errorf("invalid value for --%s: expected a value or a list", name)
+	case nil:
+		return nil
+	}
+
+	if err := flags.Set(name, fmt.Sprint(value)); err != nil {
+		return fmt.Errorf("invalid value for --%s: %w", name, err)
+	}
+	return nil
+}
+
+// CommandName returns the name of a command's section: its path without the root command
+func CommandName(commandPath string) string {
+	_, name, _ := strings.Cut(commandPath, " ")
+	return name
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func testFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("watch", pflag.ContinueOnError)
+	flags.String("region", "", "")
+	flags.String("profile", "", "")
+	flags.Duration("interval", 30*time.Second, "")
+	flags.Bool("verbose", false, "")
+	flags.StringSlice("regions", nil, "")
+	flags.String("notify-sns-arn", "", "")
+	return flags
+}
+
+func TestApply(t *testing.T) {
+	path := writeConfig(t, `
+region: eu-west-1
+profile: prod
+regions: [us-east-1, eu-west-1]
+output: mermaid
+commands:
+  watch:
+    interval: 1m
+    verbose: true
+    notify-sns-arn: arn:aws:sns:eu-west-1:123456789012:drift
+ignore-rules:
+  - resource_type: NATGateway
+    field: PublicIP
+`)
+	file, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(file.IgnoreRules) != 1 || file.IgnoreRules[0].Field != "PublicIP" {
+		t.Errorf("Expected the ignore rule, got %+v", file.IgnoreRules)
+	}
+
+	flags := testFlags()
+	if err := flags.Parse([]string{"--profile", "dev"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if err := file.Apply("watch", flags); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	region, _ := flags.GetString("region")
+	profile, _ := flags.GetString("profile")
+	interval, _ := flags.GetDuration("interval")
+	verbose, _ := flags.GetBool("verbose")
+	regions, _ := flags.GetStringSlice("regions")
+	if region != "eu-west-1" || profile != "dev" || interval != time.Minute || !verbose {
+		t.Errorf("Unexpected flags: region=%s profile=%s interval=%v verbose=%v", region, profile, interval, verbose)
+	}
+	if strings.Join(regions, ",") != "us-east-1,eu-west-1" {
+		t.Errorf("Expected regions from the list, got %v", regions)
+	}
+	if !GivenOnCommandLine(flags, "profile") || GivenOnCommandLine(flags, "region") || GivenOnCommandLine(flags, "output") {
+		t.Error("Expected only --profile to be reported as given on the command line")
+	}
+
+	// Command sections only apply to their command
+	flags = testFlags()
+	if err := file.Apply("scan", flags); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if interval, _ := flags.GetDuration("interval"); interval != 30*time.Second {
+		t.Errorf("Expected the default interval outside watch, got %v", interval)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	file, err := Load(writeConfig(t, "commands:\n  watch:\n    intervall: 1m\n"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := file.Apply("watch", testFlags()); err == nil || !strings.Contains(err.Error(), "--intervall") {
+		t.Errorf("Expected an unknown flag error, got %v", err)
+	}
+
+	file, err = Load(writeConfig(t, "interval: soon\n"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := file.Apply("watch", testFlags()); err == nil {
+		t.Error("Expected an invalid value error")
+	}
+
+	if _, err := Load(writeConfig(t, "ignore-rules:\n  - reason: no selectors\n")); err == nil {
+		t.Error("Expected an error for an ignore rule without selectors")
+	}
+
+	if _, err := Load(writeConfig(t, "capacity-thresholds:\n  prod:\n    warning: 90\n    critical: 80\n")); err == nil {
+		t.Error("Expected an error for a warning limit above the critical limit")
+	}
+}
+
+func TestCommandName(t *testing.T) {
+	if name := CommandName("pikaatools export terraform"); name != "export terraform" {
+		t.Errorf("Expected export terraform, got %q", name)
+	}
+}
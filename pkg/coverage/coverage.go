@@ -0,0 +1,237 @@
+package coverage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/statefile"
+)
+
+// Status describes how well an account/region pair is covered by snapshots
+type Status string
+
+const (
+	// StatusCurrent means the newest snapshot is within the maximum age
+	StatusCurrent Status = "current"
+	// StatusStale means the newest snapshot is older than the maximum age
+	StatusStale Status = "stale"
+	// StatusUnscanned means no snapshot exists for the pair
+	StatusUnscanned Status = "unscanned"
+)
+
+// Snapshot identifies a saved working state
+type Snapshot struct {
+	File      string    `json:"file"`
+	AccountID string    `json:"account_id"`
+	Region    string    `json:"region"`
+	ScanTime  time.Time `json:"scan_time"`
+}
+
+// Entry reports the coverage of one account/region pair
+type Entry struct {
+	AccountID    string        `json:"account_id"`
+	AccountName  string        `json:"account_name,omitempty"`
+	Region       string        `json:"region"`
+	Status       Status        `json:"status"`
+	LastScanTime *time.Time    `json:"last_scan_time,omitempty"`
+	Age          time.Duration `json:"-"`
+	AgeSeconds   int64         `json:"age_seconds,omitempty"`
+	Snapshot     string        `json:"snapshot,omitempty"`
+}
+
+// Report is the coverage of every expected account/region pair
+type Report struct {
+	GeneratedAt   time.Time     `json:"generated_at"`
+	MaxAge        time.Duration `json:"-"`
+	MaxAgeSeconds int64         `json:"max_age_seconds"`
+	Entries       []Entry       `json:"entries"`
+	Unattributed  []string      `json:"unattributed_snapshots,omitempty"`
+}
+
+// Account is an account expected to be monitored
+type Account struct {
+	ID   string
+	Name string
+}
+
+// LoadAccounts reads an account list with one account per line as "id" or "id,name".
+// Blank lines and lines starting with # are ignored.
+func LoadAccounts(filename string) ([]Account, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open accounts file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	var accounts []Account
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ",", 2)
+		account := Account{ID: strings.TrimSpace(parts[0])}
+		if len(parts) == 2 {
+			account.Name = strings.TrimSpace(parts[1])
+		}
+		accounts = append(accounts, account)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read accounts file %s: %w", filename, err)
+	}
+
+	return accounts, nil
+}
+
+// LoadSnapshots reads the identity of every JSON working state in a directory tree,
+// including compressed ones
+func LoadSnapshots(dir string) ([]Snapshot, error) {
+	var snapshots []Snapshot
+
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !statefile.IsStateFile(path) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot %s: %w", path, err)
+		}
+		if data, err = statefile.Decompress(data); err != nil {
+			return fmt.Errorf("failed to decompress snapshot %s: %w", path, err)
+		}
+
+		var snapshot Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			// Not a working state, skip it
+			return nil
+		}
+		if snapshot.Region == "" || snapshot.ScanTime.IsZero() {
+			return nil
+		}
+
+		snapshot.File = path
+		snapshots = append(snapshots, snapshot)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// BuildReport determines the coverage of every account/region pair
+func BuildReport(accounts []Account, regions []string, snapshots []Snapshot, maxAge time.Duration, now time.Time) Report {
+	report := Report{
+		GeneratedAt:   now,
+		MaxAge:        maxAge,
+		MaxAgeSeconds: int64(maxAge / time.Second),
+	}
+
+	// Keep the newest snapshot per account/region pair
+	latest := make(map[string]Snapshot)
+	for _, snapshot := range snapshots {
+		if snapshot.AccountID == "" {
+			report.Unattributed = append(report.Unattributed, snapshot.File)
+			continue
+		}
+
+		key := snapshot.AccountID + "/" + snapshot.Region
+		if existing, ok := latest[key]; !ok || snapshot.ScanTime.After(existing.ScanTime) {
+			latest[key] = snapshot
+		}
+	}
+
+	for _, account := range accounts {
+		for _, region := range regions {
+			entry := Entry{
+				AccountID:   account.ID,
+				AccountName: account.Name,
+				Region:      region,
+				Status:      StatusUnscanned,
+			}
+
+			if snapshot, ok := latest[account.ID+"/"+region]; ok {
+				scanTime := snapshot.ScanTime
+				entry.LastScanTime = &scanTime
+				entry.Age = now.Sub(scanTime)
+				entry.AgeSeconds = int64(entry.Age / time.Second)
+				entry.Snapshot = snapshot.File
+				entry.Status = StatusCurrent
+				if entry.Age > maxAge {
+					entry.Status = StatusStale
+				}
+			}
+
+			report.Entries = append(report.Entries, entry)
+		}
+	}
+
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		if report.Entries[i].AccountID != report.Entries[j].AccountID {
+			return report.Entries[i].AccountID < report.Entries[j].AccountID
+		}
+		return report.Entries[i].Region < report.Entries[j].Region
+	})
+	sort.Strings(report.Unattributed)
+
+	return report
+}
+
+// Counts returns the number of entries with each status
+func (r Report) Counts() map[Status]int {
+	counts := make(map[Status]int)
+	for _, entry := range r.Entries {
+		counts[entry.Status]++
+	}
+	return counts
+}
+
+// HasGaps reports whether any pair is stale or unscanned
+func (r Report) HasGaps() bool {
+	counts := r.Counts()
+	return counts[StatusStale] > 0 || counts[StatusUnscanned] > 0
+}
+
+// Text renders the report as a table
+func (r Report) Text() string {
+	var result strings.Builder
+
+	result.WriteString(fmt.Sprintf("Snapshot Coverage (max age %v)\n\n", r.MaxAge))
+	result.WriteString(fmt.Sprintf("%-14s %-20s %-16s %-10s %s\n", "ACCOUNT", "NAME", "REGION", "STATUS", "LAST SCAN"))
+
+	for _, entry := range r.Entries {
+		lastScan := "-"
+		if entry.LastScanTime != nil {
+			lastScan = fmt.Sprintf("%s (%s ago)", entry.LastScanTime.Format("2006-01-02 15:04:05"), entry.Age.Round(time.Minute))
+		}
+		result.WriteString(fmt.Sprintf("%-14s %-20s %-16s %-10s %s\n", entry.AccountID, entry.AccountName, entry.Region, entry.Status, lastScan))
+	}
+
+	counts := r.Counts()
+	result.WriteString(fmt.Sprintf("\nSummary:\n"))
+	result.WriteString(fmt.Sprintf("  Current: %d\n", counts[StatusCurrent]))
+	result.WriteString(fmt.Sprintf("  Stale: %d\n", counts[StatusStale]))
+	result.WriteString(fmt.Sprintf("  Unscanned: %d\n", counts[StatusUnscanned]))
+
+	if len(r.Unattributed) > 0 {
+		result.WriteString(fmt.Sprintf("\n%d snapshots have no account ID and were not counted:\n", len(r.Unattributed)))
+		for _, file := range r.Unattributed {
+			result.WriteString(fmt.Sprintf("  %s\n", file))
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,103 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/statefile"
+)
+
+func TestBuildReport(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	accounts := []Account{{ID: "111111111111", Name: "prod"}, {ID: "222222222222"}}
+	regions := []string{"us-east-1", "eu-west-1"}
+	snapshots := []Snapshot{
+		{File: "prod-use1-old.json", AccountID: "111111111111", Region: "us-east-1", ScanTime: now.Add(-72 * time.Hour)},
+		{File: "prod-use1.json", AccountID: "111111111111", Region: "us-east-1", ScanTime: now.Add(-time.Hour)},
+		{File: "prod-euw1.json", AccountID: "111111111111", Region: "eu-west-1", ScanTime: now.Add(-48 * time.Hour)},
+		{File: "legacy.json", Region: "us-east-1", ScanTime: now},
+	}
+
+	report := BuildReport(accounts, regions, snapshots, 24*time.Hour, now)
+
+	if len(report.Entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(report.Entries))
+	}
+
+	expected := map[string]Status{
+		"111111111111/us-east-1": StatusCurrent,
+		"111111111111/eu-west-1": StatusStale,
+		"222222222222/us-east-1": StatusUnscanned,
+		"222222222222/eu-west-1": StatusUnscanned,
+	}
+
+	for _, entry := range report.Entries {
+		key := entry.AccountID + "/" + entry.Region
+		if entry.Status != expected[key] {
+			t.Errorf("Expected %s to be %s, got %s", key, expected[key], entry.Status)
+		}
+		if key == "111111111111/us-east-1" && entry.Snapshot != "prod-use1.json" {
+			t.Errorf("Expected newest snapshot to be used, got %s", entry.Snapshot)
+		}
+	}
+
+	if len(report.Unattributed) != 1 || report.Unattributed[0] != "legacy.json" {
+		t.Errorf("Expected legacy.json to be unattributed, got %v", report.Unattributed)
+	}
+
+	if !report.HasGaps() {
+		t.Error("Expected report to have gaps")
+	}
+
+	if !strings.Contains(report.Text(), "unscanned") {
+		t.Error("Expected text report to list unscanned pairs")
+	}
+}
+
+func TestLoadSnapshotsAndAccounts(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"prod.json":     `{"region": "us-east-1", "account_id": "111111111111", "scan_time": "2024-01-15T10:00:00Z", "vpcs": []}`,
+		"dev.json.gz":   `{"region": "eu-west-1", "account_id": "222222222222", "scan_time": "2024-01-15T10:00:00Z", "vpcs": []}`,
+		"test.json.zst": `{"region": "eu-west-1", "account_id": "333333333333", "scan_time": "2024-01-15T10:00:00Z", "vpcs": []}`,
+		"notes.json":    `{"title": "not a snapshot"}`,
+		"readme.txt":    `ignored`,
+		"accounts.txt":  "# org accounts\n111111111111,prod\n\n222222222222\n",
+	}
+	for name, content := range files {
+		data, err := statefile.Compress(name, []byte(content))
+		if err != nil {
+			t.Fatalf("Failed to compress %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	snapshots, err := LoadSnapshots(dir)
+	if err != nil {
+		t.Fatalf("Failed to load snapshots: %v", err)
+	}
+
+	accountIDs := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		accountIDs[snapshot.AccountID] = true
+	}
+	if len(snapshots) != 3 || !accountIDs["111111111111"] || !accountIDs["222222222222"] || !accountIDs["333333333333"] {
+		t.Errorf("Expected only the working states, compressed or not, to be loaded, got %+v", snapshots)
+	}
+
+	accounts, err := LoadAccounts(filepath.Join(dir, "accounts.txt"))
+	if err != nil {
+		t.Fatalf("Failed to load accounts: %v", err)
+	}
+
+	if len(accounts) != 2 || accounts[0].Name != "prod" || accounts[1].ID != "222222222222" {
+		t.Errorf("Unexpected accounts: %+v", accounts)
+	}
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// CanonicalOptions controls what a canonical working state leaves out
+type CanonicalOptions struct {
+	// StripEphemeralIPs removes addresses and address counts that change while the
+	// configuration does not: subnets' available IP counts and NAT gateway IPs
+	StripEphemeralIPs bool
+}
+
+// volatileFields change on every scan and are always left out of a canonical state
+var volatileFields = map[string][]string{
+	"":             {"scan_time"},
+	"ecs_services": {"running_count"},
+}
+
+// ephemeralIPFields are left out of a canonical state with StripEphemeralIPs
+var ephemeralIPFields = map[string][]string{
+	"subnets":      {"available_ips"},
+	"nat_gateways": {"public_ip", "private_ip"},
+}
+
+// CanonicalJSON returns a working state as JSON that only changes when the network
+// does: collections sorted by name and then ID, object keys sorted, volatile fields
+// removed and two-space indentation with a trailing newline
+func CanonicalJSON(network *scanner.Network, options CanonicalOptions) ([]byte, error) {
+	network.Sort()
+
+	data, err := json.Marshal(network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal working state: %w", err)
+	}
+
+	// Decoding into maps sorts the keys when they are encoded again
+	var state map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&state); err != nil {
+		return nil, fmt.Errorf("failed to decode working state: %w", err)
+	}
+
+	stripFields(state, volatileFields)
+	if options.StripEphemeralIPs {
+		stripFields(state, ephemeralIPFields)
+	}
+
+	var result bytes.Buffer
+	encoder := json.NewEncoder(&result)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(state); err != nil {
+		return nil, fmt.Errorf("failed to marshal canonical working state: %w", err)
+	}
+	return result.Bytes(), nil
+}
+
+// stripFields removes fields from the top level of a state (under "") or from every
+// element of one of its collections
+func stripFields(state map[string]interface{}, fields map[string][]string) {
+	for collection, names := range fields {
+		if collection == "" {
+			for _, name := range names {
+				delete(state, name)
+			}
+			continue
+		}
+
+		elements, _ := state[collection].([]interface{})
+		for _, element := range elements {
+			object, ok := element.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, name := range names {
+				delete(object, name)
+			}
+		}
+	}
+}
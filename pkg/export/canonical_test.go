@@ -0,0 +1,63 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	first := testNetwork()
+	available := 250
+	first.Subnets[0].AvailableIPs = &available
+	first.ECSServices = []scanner.ECSService{{Name: "api", Arn: "arn:ecs:api", RunningCount: 3}}
+
+	// A later scan of the same network returned in a different order
+	second := testNetwork()
+	second.ScanTime = time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC)
+	second.Subnets[0].AvailableIPs = &available
+	second.ECSServices = []scanner.ECSService{{Name: "api", Arn: "arn:ecs:api", RunningCount: 5}}
+	second.RouteTables[0], second.RouteTables[1] = second.RouteTables[1], second.RouteTables[0]
+	rules := second.SecurityGroups[0].IngressRules
+	rules[0], rules[1] = rules[1], rules[0]
+
+	firstJSON, err := CanonicalJSON(first, CanonicalOptions{})
+	if err != nil {
+		t.Fatalf("CanonicalJSON failed: %v", err)
+	}
+	secondJSON, err := CanonicalJSON(second, CanonicalOptions{})
+	if err != nil {
+		t.Fatalf("CanonicalJSON failed: %v", err)
+	}
+
+	if !bytes.Equal(firstJSON, secondJSON) {
+		t.Errorf("Expected identical canonical states, got:\n%s\nand:\n%s", firstJSON, secondJSON)
+	}
+	for _, field := range []string{`"scan_time"`, `"running_count"`} {
+		if strings.Contains(string(firstJSON), field) {
+			t.Errorf("Expected %s to be stripped", field)
+		}
+	}
+	if !strings.Contains(string(firstJSON), `"available_ips": 250`) || !strings.Contains(string(firstJSON), `"public_ip": "203.0.113.10"`) {
+		t.Errorf("Expected IPs to be kept without StripEphemeralIPs")
+	}
+	if !strings.HasSuffix(string(firstJSON), "}\n") {
+		t.Errorf("Expected a trailing newline")
+	}
+	if strings.Index(string(firstJSON), `"internet_gateways"`) > strings.Index(string(firstJSON), `"vpcs"`) {
+		t.Errorf("Expected object keys to be sorted")
+	}
+
+	stripped, err := CanonicalJSON(testNetwork(), CanonicalOptions{StripEphemeralIPs: true})
+	if err != nil {
+		t.Fatalf("CanonicalJSON failed: %v", err)
+	}
+	for _, field := range []string{`"public_ip"`, `"private_ip"`, `"available_ips"`} {
+		if strings.Contains(string(stripped), field) {
+			t.Errorf("Expected %s to be stripped with StripEphemeralIPs", field)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// csvTable is the header and rows of one CSV file
+type csvTable struct {
+	header []string
+	rows   [][]string
+}
+
+// CSVExporter writes a scanned network as one CSV file per resource type
+type CSVExporter struct {
+	network *scanner.Network
+}
+
+// NewCSVExporter creates a CSV exporter for a network
+func NewCSVExporter(network *scanner.Network) *CSVExporter {
+	return &CSVExporter{network: network}
+}
+
+// WriteDir writes the CSV files into a directory, creating it if needed.
+// It returns the paths of the files written.
+func (e *CSVExporter) WriteDir(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory %s: %w", dir, err)
+	}
+
+	tables := e.tables()
+
+	filenames := make([]string, 0, len(tables))
+	for filename := range tables {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	var written []string
+	for _, filename := range filenames {
+		path := filepath.Join(dir, filename)
+		if err := writeCSVFile(path, tables[filename]); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+
+	return written, nil
+}
+
+func writeCSVFile(path string, table csvTable) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(table.header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := writer.WriteAll(table.rows); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// formatTags flattens tags as sorted key=value pairs separated by semicolons
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
+
+func formatList(values []string) string {
+	return strings.Join(values, ";")
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func securityGroupRuleRow(groupID, direction string, rule scanner.SecurityGroupRule) []string {
+	return []string{groupID, direction, rule.IpProtocol, strconv.Itoa(int(rule.FromPort)), strconv.Itoa(int(rule.ToPort)),
+		formatList(rule.CidrBlocks), formatList(rule.Ipv6CidrBlocks), formatList(rule.PrefixListIds), formatList(rule.ReferencedGroupIDs()), rule.Description}
+}
+
+// tables returns the CSV tables keyed by file name
+func (e *CSVExporter) tables() map[string]csvTable {
+	n := e.network
+
+	vpcs := csvTable{header: []string{"id", "name", "cidr_block", "state", "is_default", "dhcp_options_id", "region", "account_id", "tags", "ipv6_cidr_blocks"}}
+	for _, vpc := range n.VPCs {
+		vpcs.rows = append(vpcs.rows, []string{vpc.ID, vpc.Name, vpc.CidrBlock, vpc.State, strconv.FormatBool(vpc.IsDefault), vpc.DhcpOptionsID, n.Region, n.AccountID, formatTags(vpc.Tags), formatList(vpc.Ipv6CidrBlocks)})
+	}
+
+	subnets := csvTable{header: []string{"id", "name", "vpc_id", "cidr_block", "availability_zone", "state", "type", "map_public_ip", "route_table_id", "network_acl_id", "usable_ips", "tags", "ipv6_cidr_blocks", "type_route"}}
+	for _, subnet := range n.Subnets {
+		typeRoute := ""
+		if subnet.TypeRoute != nil {
+			typeRoute = subnet.TypeRoute.Destination() + " -> " + subnet.TypeRoute.Target()
+		}
+		subnets.rows = append(subnets.rows, []string{subnet.ID, subnet.Name, subnet.VpcID, subnet.CidrBlock, subnet.AvailabilityZone, subnet.State, subnet.Type,
+			strconv.FormatBool(subnet.MapPublicIP), subnet.RouteTableID, subnet.NetworkAclID, strconv.Itoa(subnet.UsableIPv4AddressCount()), formatTags(subnet.Tags), formatList(subnet.Ipv6CidrBlocks), typeRoute})
+	}
+
+	peerings := csvTable{header: []string{"id", "name", "requester_vpc_id", "accepter_vpc_id", "requester_region", "accepter_region", "status", "tags"}}
+	for _, pc := range n.PeeringConnections {
+		peerings.rows = append(peerings.rows, []string{pc.ID, pc.Name, pc.RequesterVpcID, pc.AccepterVpcID, pc.RequesterRegion, pc.AccepterRegion, pc.Status, formatTags(pc.Tags)})
+	}
+
+	tgws := csvTable{header: []string{"id", "name", "state", "attachments", "tags"}}
+	attachments := csvTable{header: []string{"id", "transit_gateway_id", "resource_id", "resource_type", "state", "peer_transit_gateway_id", "peer_region", "tags"}}
+	for _, tgw := range n.TransitGateways {
+		tgws.rows = append(tgws.rows, []string{tgw.ID, tgw.Name, tgw.State, strconv.Itoa(len(tgw.Attachments)), formatTags(tgw.Tags)})
+		for _, att := range tgw.Attachments {
+			attachments.rows = append(attachments.rows, []string{att.ID, att.TransitGatewayID, att.ResourceID, att.ResourceType, att.State, att.PeerTransitGatewayID, att.PeerRegion, formatTags(att.Tags)})
+		}
+	}
+
+	igws := csvTable{header: []string{"id", "name", "vpc_id", "state", "tags"}}
+	for _, igw := range n.InternetGateways {
+		igws.rows = append(igws.rows, []string{igw.ID, igw.Name, igw.VpcID, igw.State, formatTags(igw.Tags)})
+	}
+
+	nats := csvTable{header: []string{"id", "name", "vpc_id", "subnet_id", "state", "connectivity_type", "public_ip", "private_ip", "tags"}}
+	for _, nat := range n.NATGateways {
+		nats.rows = append(nats.rows, []string{nat.ID, nat.Name, nat.VpcID, nat.SubnetID, nat.State, nat.ConnectivityType, nat.PublicIP, nat.PrivateIP, formatTags(nat.Tags)})
+	}
+
+	routeTables := csvTable{header: []string{"id", "name", "vpc_id", "is_main", "associations", "tags"}}
+	routes := csvTable{header: []string{"route_table_id", "destination_cidr", "gateway_id", "instance_id", "network_interface_id", "vpc_peering_id", "transit_gateway_id", "state", "origin", "destination_ipv6_cidr", "egress_only_gateway_id", "nat_gateway_id", "carrier_gateway_id", "local_gateway_id"}}
+	for _, rt := range n.RouteTables {
+		routeTables.rows = append(routeTables.rows, []string{rt.ID, rt.Name, rt.VpcID, strconv.FormatBool(rt.IsMain), formatList(rt.Associations), formatTags(rt.Tags)})
+		for _, route := range rt.Routes {
+			routes.rows = append(routes.rows, []string{rt.ID, route.DestinationCidr, route.GatewayID, route.InstanceID, route.NetworkInterfaceID, route.VpcPeeringID, route.TransitGatewayID, route.State, route.Origin, route.DestinationIpv6Cidr, route.EgressOnlyGatewayID,
+				route.NatGatewayID, route.CarrierGatewayID, route.LocalGatewayID})
+		}
+	}
+
+	sgs := csvTable{header: []string{"id", "name", "description", "vpc_id", "ingress_rules", "egress_rules", "tags"}}
+	sgRules := csvTable{header: []string{"security_group_id", "direction", "ip_protocol", "from_port", "to_port", "cidr_blocks", "ipv6_cidr_blocks", "prefix_list_ids", "referenced_group_ids", "description"}}
+	for _, sg := range n.SecurityGroups {
+		sgs.rows = append(sgs.rows, []string{sg.ID, sg.Name, sg.Description, sg.VpcID, strconv.Itoa(len(sg.IngressRules)), strconv.Itoa(len(sg.EgressRules)), formatTags(sg.Tags)})
+		for _, rule := range sg.IngressRules {
+			sgRules.rows = append(sgRules.rows, securityGroupRuleRow(sg.ID, "ingress", rule))
+		}
+		for _, rule := range sg.EgressRules {
+			sgRules.rows = append(sgRules.rows, securityGroupRuleRow(sg.ID, "egress", rule))
+		}
+	}
+
+	nacls := csvTable{header: []string{"id", "name", "vpc_id", "is_default", "entries", "associations", "tags"}}
+	for _, nacl := range n.NetworkAcls {
+		nacls.rows = append(nacls.rows, []string{nacl.ID, nacl.Name, nacl.VpcID, strconv.FormatBool(nacl.IsDefault), strconv.Itoa(len(nacl.Entries)), formatList(nacl.Associations), formatTags(nacl.Tags)})
+	}
+
+	dhcpOptions := csvTable{header: []string{"id", "name", "domain_name", "domain_name_servers", "ntp_servers", "netbios_name_servers", "netbios_node_type", "tags"}}
+	for _, options := range n.DhcpOptions {
+		dhcpOptions.rows = append(dhcpOptions.rows, []string{options.ID, options.Name, options.DomainName, formatList(options.DomainNameServers),
+			formatList(options.NtpServers), formatList(options.NetbiosNameServers), options.NetbiosNodeType, formatTags(options.Tags)})
+	}
+
+	endpointServices := csvTable{header: []string{"id", "name", "service_name", "service_type", "state", "acceptance_required", "private_dns_name",
+		"availability_zones", "network_load_balancer_arns", "gateway_load_balancer_arns", "allowed_principals", "tags"}}
+	for _, service := range n.EndpointServices {
+		endpointServices.rows = append(endpointServices.rows, []string{service.ID, service.Name, service.ServiceName, service.ServiceType, service.State,
+			strconv.FormatBool(service.AcceptanceRequired), service.PrivateDNSName, formatList(service.AvailabilityZones),
+			formatList(service.NetworkLoadBalancerArns), formatList(service.GatewayLoadBalancerArns), formatList(service.AllowedPrincipals), formatTags(service.Tags)})
+	}
+
+	eksClusters := csvTable{header: []string{"name", "arn", "version", "status", "vpc_id", "subnet_ids", "security_group_ids", "cluster_security_group_id",
+		"endpoint_public_access", "endpoint_private_access", "public_access_cidrs", "network_interfaces", "tags"}}
+	for _, cluster := range n.EKSClusters {
+		eksClusters.rows = append(eksClusters.rows, []string{cluster.Name, cluster.Arn, cluster.Version, cluster.Status, cluster.VpcID,
+			formatList(cluster.SubnetIDs), formatList(cluster.SecurityGroupIDs), cluster.ClusterSecurityGroupID, strconv.FormatBool(cluster.EndpointPublicAccess),
+			strconv.FormatBool(cluster.EndpointPrivateAccess), formatList(cluster.PublicAccessCidrs), formatList(cluster.NetworkInterfaces), formatTags(cluster.Tags)})
+	}
+
+	ecsServices := csvTable{header: []string{"arn", "name", "cluster_name", "launch_type", "status", "desired_count", "vpc_id", "subnet_ids",
+		"security_group_ids", "assign_public_ip", "tags"}}
+	for _, service := range n.ECSServices {
+		ecsServices.rows = append(ecsServices.rows, []string{service.Arn, service.Name, service.ClusterName, service.LaunchType, service.Status,
+			strconv.Itoa(int(service.DesiredCount)), service.VpcID, formatList(service.SubnetIDs), formatList(service.SecurityGroupIDs),
+			strconv.FormatBool(service.AssignPublicIP), formatTags(service.Tags)})
+	}
+
+	dbSubnetGroups := csvTable{header: []string{"service", "name", "description", "vpc_id", "subnet_ids"}}
+	for _, group := range n.DatabaseSubnetGroups {
+		dbSubnetGroups.rows = append(dbSubnetGroups.rows, []string{group.Service, group.Name, group.Description, group.VpcID, formatList(group.SubnetIDs)})
+	}
+
+	databases := csvTable{header: []string{"service", "id", "engine", "status", "endpoint", "port", "vpc_id", "subnet_group", "subnet_ids",
+		"security_group_ids", "publicly_accessible", "tags"}}
+	for _, database := range n.Databases {
+		databases.rows = append(databases.rows, []string{database.Service, database.ID, database.Engine, database.Status, database.Endpoint,
+			strconv.Itoa(int(database.Port)), database.VpcID, database.SubnetGroup, formatList(database.SubnetIDs), formatList(database.SecurityGroupIDs),
+			strconv.FormatBool(database.PubliclyAccessible), formatTags(database.Tags)})
+	}
+
+	edgeIngresses := csvTable{header: []string{"id", "name", "service", "dns_name", "aliases", "status", "enabled", "target_ids", "target_vpc_ids"}}
+	for _, ingress := range n.EdgeIngresses {
+		var targetIDs, targetVpcIDs []string
+		for _, target := range ingress.Targets {
+			targetIDs = append(targetIDs, target.ID)
+			targetVpcIDs = append(targetVpcIDs, target.VpcID)
+		}
+		edgeIngresses.rows = append(edgeIngresses.rows, []string{ingress.ID, ingress.Name, ingress.Service, ingress.DNSName, formatList(ingress.Aliases),
+			ingress.Status, strconv.FormatBool(ingress.Enabled), formatList(targetIDs), formatList(targetVpcIDs)})
+	}
+
+	roles := csvTable{header: []string{"id", "name", "path", "arn", "description", "create_date", "max_session_duration", "attached_policies", "inline_policies", "tags", "instance_profiles"}}
+	for _, role := range n.IAMRoles {
+		var attached, inline, profiles []string
+		for _, policy := range role.AttachedPolicies {
+			attached = append(attached, policy.Arn)
+		}
+		for _, policy := range role.InlinePolicies {
+			inline = append(inline, policy.PolicyName)
+		}
+		for _, profile := range role.InstanceProfiles {
+			profiles = append(profiles, profile.Arn)
+		}
+		roles.rows = append(roles.rows, []string{role.ID, role.Name, role.Path, role.Arn, role.Description, formatTime(role.CreateDate),
+			strconv.Itoa(int(role.MaxSessionDuration)), formatList(attached), formatList(inline), formatTags(role.Tags), formatList(profiles)})
+	}
+
+	return map[string]csvTable{
+		"vpcs.csv":                        vpcs,
+		"subnets.csv":                     subnets,
+		"peering_connections.csv":         peerings,
+		"transit_gateways.csv":            tgws,
+		"transit_gateway_attachments.csv": attachments,
+		"internet_gateways.csv":           igws,
+		"nat_gateways.csv":                nats,
+		"route_tables.csv":                routeTables,
+		"routes.csv":                      routes,
+		"security_groups.csv":             sgs,
+		"security_group_rules.csv":        sgRules,
+		"network_acls.csv":                nacls,
+		"dhcp_options.csv":                dhcpOptions,
+		"endpoint_services.csv":           endpointServices,
+		"eks_clusters.csv":                eksClusters,
+		"ecs_services.csv":                ecsServices,
+		"database_subnet_groups.csv":      dbSubnetGroups,
+		"databases.csv":                   databases,
+		"edge_ingresses.csv":              edgeIngresses,
+		"iam_roles.csv":                   roles,
+	}
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCSVExporterWriteDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "inventory")
+
+	files, err := NewCSVExporter(testNetwork()).WriteDir(dir)
+	if err != nil {
+		t.Fatalf("WriteDir failed: %v", err)
+	}
+
+	if len(files) != 20 {
+		t.Errorf("Expected 20 CSV files, got %d", len(files))
+	}
+
+	file, err := os.Open(filepath.Join(dir, "vpcs.csv"))
+	if err != nil {
+		t.Fatalf("Failed to open vpcs.csv: %v", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse vpcs.csv: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Expected a header and 1 row, got %d records", len(records))
+	}
+	if records[1][0] != "vpc-123" || records[1][6] != "us-east-1" {
+		t.Errorf("Unexpected VPC row: %v", records[1])
+	}
+	if records[1][8] != "Name=main;aws:cloudformation:stack-name=net" {
+		t.Errorf("Unexpected tags column: %s", records[1][8])
+	}
+
+	rules, err := os.ReadFile(filepath.Join(dir, "security_group_rules.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read security_group_rules.csv: %v", err)
+	}
+	expected := "security_group_id,direction,ip_protocol,from_port,to_port,cidr_blocks,ipv6_cidr_blocks,prefix_list_ids,referenced_group_ids,description\n" +
+		"sg-web,ingress,tcp,443,443,0.0.0.0/0,,,,\n" +
+		"sg-web,ingress,tcp,22,22,,,,sg-bastion,\n"
+	if string(rules) != expected {
+		t.Errorf("Unexpected security group rules:\n%s", rules)
+	}
+}
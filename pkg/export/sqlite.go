@@ -0,0 +1,194 @@
+package export
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	_ "modernc.org/sqlite"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// snapshotsSchema creates the table every exported scan is recorded in. The full
+// working state is kept as JSON so snapshots can be loaded back for diffs.
+const snapshotsSchema = `CREATE TABLE IF NOT EXISTS snapshots (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	scan_time TEXT NOT NULL,
+	region TEXT NOT NULL,
+	account_id TEXT NOT NULL,
+	schema_version INTEGER NOT NULL,
+	state TEXT NOT NULL
+)`
+
+// SQLiteExporter appends scanned networks to a SQLite database, one snapshot per
+// scan, with a table per resource type holding the same columns as the CSV export
+type SQLiteExporter struct {
+	network *scanner.Network
+}
+
+// NewSQLiteExporter creates a SQLite exporter for a network
+func NewSQLiteExporter(network *scanner.Network) *SQLiteExporter {
+	return &SQLiteExporter{network: network}
+}
+
+// Write appends the network to the database in filename as a new snapshot, creating
+// the database and its tables if needed. It returns the ID of the snapshot.
+func (e *SQLiteExporter) Write(filename string) (int64, error) {
+	state, err := json.Marshal(e.network)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal network data to JSON: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open SQLite database %s: %w", filename, err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to write SQLite database %s: %w", filename, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(snapshotsSchema); err != nil {
+		return 0, fmt.Errorf("failed to create snapshots table: %w", err)
+	}
+	result, err := tx.Exec(`INSERT INTO snapshots (scan_time, region, account_id, schema_version, state) VALUES (?, ?, ?, ?, ?)`,
+		formatTime(e.network.ScanTime), e.network.Region, e.network.AccountID, scanner.NetworkSchemaVersion, string(state))
+	if err != nil {
+		return 0, fmt.Errorf("failed to record snapshot: %w", err)
+	}
+	snapshotID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to record snapshot: %w", err)
+	}
+
+	tables := NewCSVExporter(e.network).tables()
+	filenames := make([]string, 0, len(tables))
+	for filename := range tables {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	for _, csvFile := range filenames {
+		name := strings.TrimSuffix(csvFile, ".csv")
+		if err := insertSQLiteTable(tx, name, snapshotID, tables[csvFile]); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to write SQLite database %s: %w", filename, err)
+	}
+	return snapshotID, nil
+}
+
+// insertSQLiteTable creates a resource type's table if needed, adding columns newer
+// versions export to tables created by older ones, and inserts the snapshot's rows
+func insertSQLiteTable(tx *sql.Tx, name string, snapshotID int64, table csvTable) error {
+	columns := []string{"snapshot_id INTEGER NOT NULL REFERENCES snapshots(id)"}
+	for _, column := range table.header {
+		columns = append(columns, quoteIdentifier(column)+" TEXT")
+	}
+	if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quoteIdentifier(name), strings.Join(columns, ", "))); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", name, err)
+	}
+	if _, err := tx.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (snapshot_id)", quoteIdentifier(name+"_snapshot_id"), quoteIdentifier(name))); err != nil {
+		return fmt.Errorf("failed to index table %s: %w", name, err)
+	}
+
+	existing, err := tableColumns(tx, name)
+	if err != nil {
+		return err
+	}
+	for _, column := range table.header {
+		if existing[column] {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", quoteIdentifier(name), quoteIdentifier(column))); err != nil {
+			return fmt.Errorf("failed to add column %s to table %s: %w", column, name, err)
+		}
+	}
+
+	quoted := []string{"snapshot_id"}
+	for _, column := range table.header {
+		quoted = append(quoted, quoteIdentifier(column))
+	}
+	statement, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (?%s)", quoteIdentifier(name), strings.Join(quoted, ", "), strings.Repeat(", ?", len(table.header))))
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert into %s: %w", name, err)
+	}
+	defer statement.Close()
+
+	for _, row := range table.rows {
+		values := []any{snapshotID}
+		for _, value := range row {
+			values = append(values, value)
+		}
+		if _, err := statement.Exec(values...); err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// tableColumns returns the names of a table's columns
+func tableColumns(tx *sql.Tx, name string) (map[string]bool, error) {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of table %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("failed to read columns of table %s: %w", name, err)
+		}
+		columns[column] = true
+	}
+	return columns, rows.Err()
+}
+
+// ReadSQLiteSnapshot loads the working state of a snapshot from a SQLite database
+// written by SQLiteExporter, or its latest snapshot when id is 0
+func ReadSQLiteSnapshot(filename string, id int64) (*scanner.Network, error) {
+	db, err := sql.Open("sqlite", "file:"+filename+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", filename, err)
+	}
+	defer db.Close()
+
+	var state string
+	if id == 0 {
+		err = db.QueryRow(`SELECT id, state FROM snapshots ORDER BY id DESC LIMIT 1`).Scan(&id, &state)
+	} else {
+		err = db.QueryRow(`SELECT state FROM snapshots WHERE id = ?`, id).Scan(&state)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if id == 0 {
+			return nil, fmt.Errorf("SQLite database %s has no snapshots", filename)
+		}
+		return nil, fmt.Errorf("SQLite database %s has no snapshot %d", filename, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot from SQLite database %s: %w", filename, err)
+	}
+
+	var network scanner.Network
+	if err := json.Unmarshal([]byte(state), &network); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %d in SQLite database %s: %w", id, filename, err)
+	}
+	network.Sort()
+	return &network, nil
+}
+
+// quoteIdentifier quotes a table or column name for SQLite
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func TestSQLiteExporter(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.db")
+	first := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		VPCs:     []scanner.VPC{{ID: "vpc-1", CidrBlock: "10.0.0.0/16"}},
+		Subnets:  []scanner.Subnet{{ID: "subnet-1", VpcID: "vpc-1", CidrBlock: "10.0.1.0/24"}},
+	}
+	second := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		VPCs:     []scanner.VPC{{ID: "vpc-1", CidrBlock: "10.0.0.0/16"}, {ID: "vpc-2", CidrBlock: "10.1.0.0/16"}},
+	}
+
+	firstID, err := NewSQLiteExporter(first).Write(filename)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	secondID, err := NewSQLiteExporter(second).Write(filename)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if firstID != 1 || secondID != 2 {
+		t.Errorf("Expected snapshots 1 and 2, got %d and %d", firstID, secondID)
+	}
+
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	var added string
+	err = db.QueryRow(`SELECT v.id FROM vpcs v WHERE v.snapshot_id = 2 AND v.id NOT IN (SELECT id FROM vpcs WHERE snapshot_id = 1)`).Scan(&added)
+	if err != nil || added != "vpc-2" {
+		t.Errorf("Expected vpc-2 added in the second snapshot, got %q, %v", added, err)
+	}
+	var subnets int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM subnets s JOIN snapshots ON snapshots.id = s.snapshot_id WHERE snapshots.scan_time = '2026-01-01T00:00:00Z'`).Scan(&subnets); err != nil || subnets != 1 {
+		t.Errorf("Expected the first snapshot's subnet, got %d, %v", subnets, err)
+	}
+
+	latest, err := ReadSQLiteSnapshot(filename, 0)
+	if err != nil || len(latest.VPCs) != 2 {
+		t.Fatalf("Expected the latest snapshot, got %+v, %v", latest, err)
+	}
+	earlier, err := ReadSQLiteSnapshot(filename, firstID)
+	if err != nil || len(earlier.Subnets) != 1 || !earlier.ScanTime.Equal(first.ScanTime) {
+		t.Fatalf("Expected the first snapshot, got %+v, %v", earlier, err)
+	}
+	if _, err := ReadSQLiteSnapshot(filename, 3); err == nil {
+		t.Error("Expected an error for a missing snapshot")
+	}
+}
+
+func TestSQLiteExporterAddsNewColumns(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.db")
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	// A table written before the ipv6_cidr_blocks column was exported
+	if _, err := db.Exec(snapshotsSchema + `; CREATE TABLE vpcs (snapshot_id INTEGER NOT NULL, "id" TEXT)`); err != nil {
+		t.Fatalf("Failed to create old table: %v", err)
+	}
+
+	network := &scanner.Network{VPCs: []scanner.VPC{{ID: "vpc-1", Ipv6CidrBlocks: []string{"2600:1f18::/56"}}}}
+	if _, err := NewSQLiteExporter(network).Write(filename); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var ipv6 string
+	if err := db.QueryRow(`SELECT ipv6_cidr_blocks FROM vpcs WHERE id = 'vpc-1'`).Scan(&ipv6); err != nil || ipv6 != "2600:1f18::/56" {
+		t.Errorf("Expected the new column added, got %q, %v", ipv6, err)
+	}
+}
@@ -0,0 +1,319 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Table schemas the tables exporter can write
+const (
+	SchemaCloudQuery = "cloudquery"
+	SchemaSteampipe  = "steampipe"
+)
+
+// tableRow is one row of a table, keyed by column. Nested values use the AWS SDK's
+// field names, as both CloudQuery and Steampipe keep them in their JSON columns.
+type tableRow map[string]any
+
+// tableNames are the CloudQuery and Steampipe names of the tables of each resource type
+var tableNames = map[string]map[string]string{
+	"vpcs":                        {SchemaCloudQuery: "aws_ec2_vpcs", SchemaSteampipe: "aws_vpc"},
+	"subnets":                     {SchemaCloudQuery: "aws_ec2_subnets", SchemaSteampipe: "aws_vpc_subnet"},
+	"security_groups":             {SchemaCloudQuery: "aws_ec2_security_groups", SchemaSteampipe: "aws_vpc_security_group"},
+	"route_tables":                {SchemaCloudQuery: "aws_ec2_route_tables", SchemaSteampipe: "aws_vpc_route_table"},
+	"network_acls":                {SchemaCloudQuery: "aws_ec2_network_acls", SchemaSteampipe: "aws_vpc_network_acl"},
+	"internet_gateways":           {SchemaCloudQuery: "aws_ec2_internet_gateways", SchemaSteampipe: "aws_vpc_internet_gateway"},
+	"nat_gateways":                {SchemaCloudQuery: "aws_ec2_nat_gateways", SchemaSteampipe: "aws_vpc_nat_gateway"},
+	"peering_connections":         {SchemaCloudQuery: "aws_ec2_vpc_peering_connections", SchemaSteampipe: "aws_vpc_peering_connection"},
+	"transit_gateways":            {SchemaCloudQuery: "aws_ec2_transit_gateways", SchemaSteampipe: "aws_ec2_transit_gateway"},
+	"transit_gateway_attachments": {SchemaCloudQuery: "aws_ec2_transit_gateway_attachments", SchemaSteampipe: "aws_ec2_transit_gateway_vpc_attachment"},
+	"iam_roles":                   {SchemaCloudQuery: "aws_iam_roles", SchemaSteampipe: "aws_iam_role"},
+}
+
+// TablesExporter writes a scanned network as the tables of the CloudQuery or
+// Steampipe AWS plugins, so dashboards and queries built on them can read scans
+type TablesExporter struct {
+	network *scanner.Network
+	schema  string
+}
+
+// NewTablesExporter creates a tables exporter for a network and table schema,
+// SchemaCloudQuery or SchemaSteampipe
+func NewTablesExporter(network *scanner.Network, schema string) *TablesExporter {
+	return &TablesExporter{network: network, schema: schema}
+}
+
+// WriteDir writes each table as a JSON Lines file named after it, such as
+// aws_ec2_vpcs.jsonl, into a directory, creating it if needed. It returns the
+// paths of the files written.
+func (e *TablesExporter) WriteDir(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory %s: %w", dir, err)
+	}
+
+	tables := e.tables()
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var written []string
+	for _, name := range names {
+		path := filepath.Join(dir, name+".jsonl")
+		if err := writeJSONLines(path, tables[name]); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+func writeJSONLines(path string, rows []tableRow) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// arn returns the ARN of an EC2 resource in the scanned account and region
+func (e *TablesExporter) arn(resourceType, id string) string {
+	return fmt.Sprintf("arn:aws:ec2:%s:%s:%s/%s", e.network.Region, e.network.AccountID, resourceType, id)
+}
+
+// row starts a row with the account and region columns every table has
+func (e *TablesExporter) row(columns tableRow) tableRow {
+	columns["account_id"] = e.network.AccountID
+	columns["region"] = e.network.Region
+	return columns
+}
+
+// tables returns the rows of each table, keyed by the schema's table name
+func (e *TablesExporter) tables() map[string][]tableRow {
+	n := e.network
+	rows := make(map[string][]tableRow)
+	add := func(kind string, row tableRow) {
+		name := tableNames[kind][e.schema]
+		rows[name] = append(rows[name], row)
+	}
+
+	for _, vpc := range n.VPCs {
+		var ipv6 []map[string]any
+		for _, block := range vpc.Ipv6CidrBlocks {
+			ipv6 = append(ipv6, map[string]any{"Ipv6CidrBlock": block})
+		}
+		add("vpcs", e.row(tableRow{
+			"arn": e.arn("vpc", vpc.ID), "vpc_id": vpc.ID, "cidr_block": vpc.CidrBlock, "state": vpc.State, "is_default": vpc.IsDefault,
+			"dhcp_options_id": vpc.DhcpOptionsID, "owner_id": n.AccountID, "ipv6_cidr_block_association_set": ipv6, "tags": vpc.Tags,
+		}))
+	}
+
+	for _, subnet := range n.Subnets {
+		var ipv6 []map[string]any
+		for _, block := range subnet.Ipv6CidrBlocks {
+			ipv6 = append(ipv6, map[string]any{"Ipv6CidrBlock": block})
+		}
+		row := e.row(tableRow{
+			"arn": e.arn("subnet", subnet.ID), "subnet_id": subnet.ID, "vpc_id": subnet.VpcID, "cidr_block": subnet.CidrBlock,
+			"availability_zone": subnet.AvailabilityZone, "state": subnet.State, "map_public_ip_on_launch": subnet.MapPublicIP,
+			"assign_ipv6_address_on_creation": subnet.AssignIpv6Address, "owner_id": n.AccountID, "ipv6_cidr_block_association_set": ipv6, "tags": subnet.Tags,
+		})
+		if subnet.AvailableIPs != nil {
+			row["available_ip_address_count"] = *subnet.AvailableIPs
+		}
+		add("subnets", row)
+	}
+
+	for _, sg := range n.SecurityGroups {
+		add("security_groups", e.row(tableRow{
+			"arn": e.arn("security-group", sg.ID), "group_id": sg.ID, "group_name": sg.Name, "description": sg.Description, "vpc_id": sg.VpcID,
+			"owner_id": n.AccountID, "ip_permissions": ipPermissions(sg.IngressRules), "ip_permissions_egress": ipPermissions(sg.EgressRules), "tags": sg.Tags,
+		}))
+	}
+
+	for _, rt := range n.RouteTables {
+		var routes, associations []map[string]any
+		for _, route := range rt.Routes {
+			routes = append(routes, sdkFields(map[string]any{
+				"DestinationCidrBlock": route.DestinationCidr, "DestinationIpv6CidrBlock": route.DestinationIpv6Cidr, "GatewayId": route.GatewayID,
+				"NatGatewayId": route.NatGatewayID, "EgressOnlyInternetGatewayId": route.EgressOnlyGatewayID, "CarrierGatewayId": route.CarrierGatewayID,
+				"LocalGatewayId": route.LocalGatewayID, "InstanceId": route.InstanceID, "NetworkInterfaceId": route.NetworkInterfaceID,
+				"VpcPeeringConnectionId": route.VpcPeeringID, "TransitGatewayId": route.TransitGatewayID, "State": route.State, "Origin": route.Origin,
+			}))
+		}
+		if rt.IsMain {
+			associations = append(associations, map[string]any{"Main": true, "RouteTableId": rt.ID})
+		}
+		for _, subnetID := range rt.Associations {
+			associations = append(associations, map[string]any{"Main": false, "RouteTableId": rt.ID, "SubnetId": subnetID})
+		}
+		add("route_tables", e.row(tableRow{
+			"arn": e.arn("route-table", rt.ID), "route_table_id": rt.ID, "vpc_id": rt.VpcID, "owner_id": n.AccountID,
+			"routes": routes, "associations": associations, "tags": rt.Tags,
+		}))
+	}
+
+	for _, acl := range n.NetworkAcls {
+		var entries, associations []map[string]any
+		for _, entry := range acl.Entries {
+			fields := sdkFields(map[string]any{
+				"RuleNumber": entry.RuleNumber, "Protocol": entry.Protocol, "RuleAction": entry.RuleAction, "Egress": entry.Egress,
+				"CidrBlock": entry.CidrBlock, "Ipv6CidrBlock": entry.Ipv6CidrBlock,
+			})
+			if entry.PortRange != nil {
+				fields["PortRange"] = map[string]any{"From": entry.PortRange.From, "To": entry.PortRange.To}
+			}
+			if entry.IcmpType != nil {
+				fields["IcmpTypeCode"] = map[string]any{"Type": entry.IcmpType.Type, "Code": entry.IcmpType.Code}
+			}
+			entries = append(entries, fields)
+		}
+		for _, subnetID := range acl.Associations {
+			associations = append(associations, map[string]any{"NetworkAclId": acl.ID, "SubnetId": subnetID})
+		}
+		add("network_acls", e.row(tableRow{
+			"arn": e.arn("network-acl", acl.ID), "network_acl_id": acl.ID, "vpc_id": acl.VpcID, "is_default": acl.IsDefault, "owner_id": n.AccountID,
+			"entries": entries, "associations": associations, "tags": acl.Tags,
+		}))
+	}
+
+	for _, igw := range n.InternetGateways {
+		var attachments []map[string]any
+		if igw.VpcID != "" {
+			attachments = append(attachments, map[string]any{"VpcId": igw.VpcID, "State": igw.State})
+		}
+		add("internet_gateways", e.row(tableRow{
+			"arn": e.arn("internet-gateway", igw.ID), "internet_gateway_id": igw.ID, "owner_id": n.AccountID, "attachments": attachments, "tags": igw.Tags,
+		}))
+	}
+
+	for _, nat := range n.NATGateways {
+		address := sdkFields(map[string]any{"PublicIp": nat.PublicIP, "PrivateIp": nat.PrivateIP})
+		add("nat_gateways", e.row(tableRow{
+			"arn": e.arn("natgateway", nat.ID), "nat_gateway_id": nat.ID, "vpc_id": nat.VpcID, "subnet_id": nat.SubnetID, "state": nat.State,
+			"connectivity_type": nat.ConnectivityType, "nat_gateway_addresses": []map[string]any{address}, "tags": nat.Tags,
+		}))
+	}
+
+	for _, pc := range n.PeeringConnections {
+		row := e.row(tableRow{"arn": e.arn("vpc-peering-connection", pc.ID), "tags": pc.Tags})
+		// CloudQuery keeps the SDK's nested structures, Steampipe flattens them
+		if e.schema == SchemaCloudQuery {
+			row["vpc_peering_connection_id"] = pc.ID
+			row["requester_vpc_info"] = sdkFields(map[string]any{"VpcId": pc.RequesterVpcID, "Region": pc.RequesterRegion})
+			row["accepter_vpc_info"] = sdkFields(map[string]any{"VpcId": pc.AccepterVpcID, "Region": pc.AccepterRegion})
+			row["status"] = map[string]any{"Code": pc.Status}
+		} else {
+			row["id"] = pc.ID
+			row["requester_vpc_id"] = pc.RequesterVpcID
+			row["requester_region"] = pc.RequesterRegion
+			row["accepter_vpc_id"] = pc.AccepterVpcID
+			row["accepter_region"] = pc.AccepterRegion
+			row["status_code"] = pc.Status
+		}
+		add("peering_connections", row)
+	}
+
+	for _, tgw := range n.TransitGateways {
+		arn := e.arn("transit-gateway", tgw.ID)
+		add("transit_gateways", e.row(tableRow{
+			"arn": arn, "transit_gateway_arn": arn, "transit_gateway_id": tgw.ID, "state": tgw.State, "owner_id": n.AccountID, "tags": tgw.Tags,
+		}))
+		for _, att := range tgw.Attachments {
+			add("transit_gateway_attachments", e.row(tableRow{
+				"arn": e.arn("transit-gateway-attachment", att.ID), "transit_gateway_attachment_id": att.ID, "transit_gateway_id": att.TransitGatewayID,
+				"resource_id": att.ResourceID, "resource_type": att.ResourceType, "state": att.State, "tags": att.Tags,
+			}))
+		}
+	}
+
+	for _, role := range n.IAMRoles {
+		row := e.row(tableRow{
+			"arn": role.Arn, "role_id": role.ID, "path": role.Path, "description": role.Description, "create_date": role.CreateDate,
+			"assume_role_policy_document": policyDocument(role.AssumeRolePolicyDocument), "max_session_duration": role.MaxSessionDuration, "tags": role.Tags,
+		})
+		// IAM is global, so neither plugin has a region for roles
+		delete(row, "region")
+		if e.schema == SchemaCloudQuery {
+			row["role_name"] = role.Name
+		} else {
+			var arns []string
+			for _, policy := range role.AttachedPolicies {
+				arns = append(arns, policy.Arn)
+			}
+			row["name"] = role.Name
+			row["attached_policy_arns"] = arns
+		}
+		add("iam_roles", row)
+	}
+
+	return rows
+}
+
+// ipPermissions converts security group rules to the SDK's IpPermissions
+func ipPermissions(rules []scanner.SecurityGroupRule) []map[string]any {
+	permissions := []map[string]any{}
+	for _, rule := range rules {
+		var ipRanges, ipv6Ranges, prefixLists, groups []map[string]any
+		for _, cidr := range rule.CidrBlocks {
+			ipRanges = append(ipRanges, sdkFields(map[string]any{"CidrIp": cidr, "Description": rule.Description}))
+		}
+		for _, cidr := range rule.Ipv6CidrBlocks {
+			ipv6Ranges = append(ipv6Ranges, sdkFields(map[string]any{"CidrIpv6": cidr, "Description": rule.Description}))
+		}
+		for _, id := range rule.PrefixListIds {
+			prefixLists = append(prefixLists, sdkFields(map[string]any{"PrefixListId": id, "Description": rule.Description}))
+		}
+		for _, group := range rule.ReferencedGroups {
+			groups = append(groups, sdkFields(map[string]any{"GroupId": group.GroupID, "UserId": group.OwnerID, "VpcId": group.VpcID,
+				"VpcPeeringConnectionId": group.PeeringID, "Description": rule.Description}))
+		}
+		permissions = append(permissions, map[string]any{
+			"IpProtocol": rule.IpProtocol, "FromPort": rule.FromPort, "ToPort": rule.ToPort,
+			"IpRanges": ipRanges, "Ipv6Ranges": ipv6Ranges, "PrefixListIds": prefixLists, "UserIdGroupPairs": groups,
+		})
+	}
+	return permissions
+}
+
+// sdkFields leaves out empty strings, which the SDK returns as missing fields
+func sdkFields(fields map[string]any) map[string]any {
+	for key, value := range fields {
+		if value == "" {
+			delete(fields, key)
+		}
+	}
+	return fields
+}
+
+// policyDocument returns a policy document as JSON, as both plugins store it, or
+// the raw text when it is not valid JSON
+func policyDocument(document string) any {
+	if document == "" {
+		return nil
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(document), &decoded); err != nil {
+		return document
+	}
+	return decoded
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// readJSONLines reads the rows of a JSON Lines table file
+func readJSONLines(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var rows []map[string]any
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		var row map[string]any
+		if err := json.Unmarshal(lines.Bytes(), &row); err != nil {
+			t.Fatalf("Invalid row in %s: %v", path, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestTablesExporter(t *testing.T) {
+	network := &scanner.Network{
+		Region:    "us-east-1",
+		AccountID: "123456789012",
+		VPCs:      []scanner.VPC{{ID: "vpc-1", CidrBlock: "10.0.0.0/16", Tags: map[string]string{"Name": "prod"}}},
+		SecurityGroups: []scanner.SecurityGroup{{ID: "sg-1", Name: "web", VpcID: "vpc-1", IngressRules: []scanner.SecurityGroupRule{
+			{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+		}}},
+		PeeringConnections: []scanner.PeeringConnection{{ID: "pcx-1", RequesterVpcID: "vpc-1", AccepterVpcID: "vpc-2", Status: "active"}},
+		IAMRoles:           []scanner.IAMRole{{Name: "app", Arn: "arn:aws:iam::123456789012:role/app", AssumeRolePolicyDocument: `{"Version":"2012-10-17"}`}},
+	}
+
+	dir := t.TempDir()
+	if _, err := NewTablesExporter(network, SchemaCloudQuery).WriteDir(dir); err != nil {
+		t.Fatalf("WriteDir failed: %v", err)
+	}
+	vpcs := readJSONLines(t, filepath.Join(dir, "aws_ec2_vpcs.jsonl"))
+	if len(vpcs) != 1 || vpcs[0]["vpc_id"] != "vpc-1" || vpcs[0]["arn"] != "arn:aws:ec2:us-east-1:123456789012:vpc/vpc-1" || vpcs[0]["account_id"] != "123456789012" {
+		t.Errorf("Expected the VPC row, got %v", vpcs)
+	}
+	groups := readJSONLines(t, filepath.Join(dir, "aws_ec2_security_groups.jsonl"))
+	permissions, _ := groups[0]["ip_permissions"].([]any)
+	if len(permissions) != 1 || permissions[0].(map[string]any)["IpRanges"].([]any)[0].(map[string]any)["CidrIp"] != "0.0.0.0/0" {
+		t.Errorf("Expected the rule as SDK IpPermissions, got %v", groups[0]["ip_permissions"])
+	}
+	peerings := readJSONLines(t, filepath.Join(dir, "aws_ec2_vpc_peering_connections.jsonl"))
+	if peerings[0]["vpc_peering_connection_id"] != "pcx-1" || peerings[0]["status"].(map[string]any)["Code"] != "active" {
+		t.Errorf("Expected the CloudQuery peering columns, got %v", peerings[0])
+	}
+	roles := readJSONLines(t, filepath.Join(dir, "aws_iam_roles.jsonl"))
+	if roles[0]["role_name"] != "app" || roles[0]["assume_role_policy_document"].(map[string]any)["Version"] != "2012-10-17" {
+		t.Errorf("Expected the CloudQuery role columns, got %v", roles[0])
+	}
+
+	dir = t.TempDir()
+	if _, err := NewTablesExporter(network, SchemaSteampipe).WriteDir(dir); err != nil {
+		t.Fatalf("WriteDir failed: %v", err)
+	}
+	if vpcs := readJSONLines(t, filepath.Join(dir, "aws_vpc.jsonl")); len(vpcs) != 1 || vpcs[0]["vpc_id"] != "vpc-1" {
+		t.Errorf("Expected the Steampipe VPC table, got %v", vpcs)
+	}
+	peerings = readJSONLines(t, filepath.Join(dir, "aws_vpc_peering_connection.jsonl"))
+	if peerings[0]["id"] != "pcx-1" || peerings[0]["status_code"] != "active" || peerings[0]["accepter_vpc_id"] != "vpc-2" {
+		t.Errorf("Expected the Steampipe peering columns, got %v", peerings[0])
+	}
+	if roles := readJSONLines(t, filepath.Join(dir, "aws_iam_role.jsonl")); roles[0]["name"] != "app" {
+		t.Errorf("Expected the Steampipe role columns, got %v", roles[0])
+	}
+}
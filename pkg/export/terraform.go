@@ -0,0 +1,579 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// defaultNaclRuleNumber is the implicit deny-all rule present in every network ACL
+const defaultNaclRuleNumber = 32767
+
+var invalidIdentifierChars = regexp.MustCompile(`[^a-z0-9_]`)
+
+// TerraformGenerator generates Terraform configuration from a scanned network
+type TerraformGenerator struct {
+	network *scanner.Network
+	result  strings.Builder
+
+	// Terraform addresses of generated resources by AWS ID
+	addresses map[string]string
+	// IDs of the generated default security groups
+	defaultSecurityGroups map[string]bool
+	// import blocks collected while generating resources
+	imports []terraformImport
+}
+
+type terraformImport struct {
+	to string
+	id string
+}
+
+// NewTerraformGenerator creates a Terraform generator for a network
+func NewTerraformGenerator(network *scanner.Network) *TerraformGenerator {
+	return &TerraformGenerator{
+		network:               network,
+		addresses:             make(map[string]string),
+		defaultSecurityGroups: make(map[string]bool),
+	}
+}
+
+// Generate returns Terraform resource blocks and import blocks for the network
+func (g *TerraformGenerator) Generate() string {
+	g.result.Reset()
+	g.imports = nil
+
+	g.registerAddresses()
+
+	g.result.WriteString("# Generated by pikaatools from a scan of ")
+	g.result.WriteString(g.network.Region)
+	g.result.WriteString(fmt.Sprintf(" at %s\n", g.network.ScanTime.Format("2006-01-02 15:04:05")))
+	g.result.WriteString("# Review before applying: attributes pikaatools does not scan are left at their defaults.\n")
+
+	g.writeVPCs()
+	g.writeSubnets()
+	g.writeInternetGateways()
+	g.writeNATGateways()
+	g.writeRouteTables()
+	g.writeSecurityGroups()
+	g.writeNetworkAcls()
+	g.writePeeringConnections()
+	g.writeImports()
+
+	return g.result.String()
+}
+
+// resourceName converts an AWS ID into a Terraform identifier
+func resourceName(id string) string {
+	name := invalidIdentifierChars.ReplaceAllString(strings.ToLower(id), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "r_" + name
+	}
+	return name
+}
+
+// hclString quotes a string as an HCL string literal
+func hclString(s string) string {
+	quoted := strconv.Quote(s)
+	quoted = strings.ReplaceAll(quoted, "${", "$${")
+	return strings.ReplaceAll(quoted, "%{", "%%{")
+}
+
+// hclStringList formats a list of strings as an HCL list
+func hclStringList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = hclString(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// registerAddresses records the Terraform address of every resource that will be generated
+func (g *TerraformGenerator) registerAddresses() {
+	n := g.network
+	for _, vpc := range n.VPCs {
+		g.addresses[vpc.ID] = "aws_vpc." + resourceName(vpc.ID)
+	}
+	for _, subnet := range n.Subnets {
+		g.addresses[subnet.ID] = "aws_subnet." + resourceName(subnet.ID)
+	}
+	for _, igw := range n.InternetGateways {
+		g.addresses[igw.ID] = "aws_internet_gateway." + resourceName(igw.ID)
+	}
+	for _, nat := range n.NATGateways {
+		g.addresses[nat.ID] = "aws_nat_gateway." + resourceName(nat.ID)
+	}
+	for _, sg := range n.SecurityGroups {
+		if sg.Name == "default" {
+			g.addresses[sg.ID] = "aws_default_security_group." + resourceName(sg.ID)
+			g.defaultSecurityGroups[sg.ID] = true
+		} else {
+			g.addresses[sg.ID] = "aws_security_group." + resourceName(sg.ID)
+		}
+	}
+	for _, pc := range n.PeeringConnections {
+		g.addresses[pc.ID] = "aws_vpc_peering_connection." + resourceName(pc.ID)
+	}
+}
+
+// reference returns an expression referring to a resource's ID, or the literal ID when it is not generated
+func (g *TerraformGenerator) reference(id string) string {
+	if address, ok := g.addresses[id]; ok {
+		return address + ".id"
+	}
+	return hclString(id)
+}
+
+func (g *TerraformGenerator) addImport(address, id string) {
+	g.imports = append(g.imports, terraformImport{to: address, id: id})
+}
+
+// writeTags writes a tags argument, omitting AWS-managed tags which cannot be set
+func (g *TerraformGenerator) writeTags(tags map[string]string, indent string) {
+	var keys []string
+	for key := range tags {
+		if !strings.HasPrefix(key, "aws:") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+
+	g.result.WriteString(indent + "tags = {\n")
+	for _, key := range keys {
+		g.result.WriteString(fmt.Sprintf("%s  %s = %s\n", indent, hclString(key), hclString(tags[key])))
+	}
+	g.result.WriteString(indent + "}\n")
+}
+
+func (g *TerraformGenerator) writeVPCs() {
+	vpcs := append([]scanner.VPC{}, g.network.VPCs...)
+	sort.Slice(vpcs, func(i, j int) bool { return vpcs[i].ID < vpcs[j].ID })
+
+	for _, vpc := range vpcs {
+		address := g.addresses[vpc.ID]
+		g.result.WriteString(fmt.Sprintf("\nresource \"aws_vpc\" %s {\n", hclString(resourceName(vpc.ID))))
+		g.result.WriteString(fmt.Sprintf("  cidr_block = %s\n", hclString(vpc.CidrBlock)))
+		if len(vpc.Ipv6CidrBlocks) > 0 {
+			g.result.WriteString("  assign_generated_ipv6_cidr_block = true\n")
+		}
+		g.writeTags(vpc.Tags, "  ")
+		g.result.WriteString("}\n")
+		g.addImport(address, vpc.ID)
+	}
+}
+
+func (g *TerraformGenerator) writeSubnets() {
+	subnets := append([]scanner.Subnet{}, g.network.Subnets...)
+	sort.Slice(subnets, func(i, j int) bool { return subnets[i].ID < subnets[j].ID })
+
+	for _, subnet := range subnets {
+		g.result.WriteString(fmt.Sprintf("\nresource \"aws_subnet\" %s {\n", hclString(resourceName(subnet.ID))))
+		g.result.WriteString(fmt.Sprintf("  vpc_id                  = %s\n", g.reference(subnet.VpcID)))
+		if subnet.CidrBlock != "" {
+			g.result.WriteString(fmt.Sprintf("  cidr_block              = %s\n", hclString(subnet.CidrBlock)))
+		} else {
+			g.result.WriteString("  ipv6_native             = true\n")
+		}
+		g.result.WriteString(fmt.Sprintf("  availability_zone       = %s\n", hclString(subnet.AvailabilityZone)))
+		g.result.WriteString(fmt.Sprintf("  map_public_ip_on_launch = %t\n", subnet.MapPublicIP))
+		if len(subnet.Ipv6CidrBlocks) > 0 {
+			g.result.WriteString(fmt.Sprintf("  ipv6_cidr_block         = %s\n", hclString(subnet.Ipv6CidrBlocks[0])))
+			g.result.WriteString(fmt.Sprintf("  assign_ipv6_address_on_creation = %t\n", subnet.AssignIpv6Address))
+		}
+		g.writeTags(subnet.Tags, "  ")
+		g.result.WriteString("}\n")
+		g.addImport(g.addresses[subnet.ID], subnet.ID)
+	}
+}
+
+func (g *TerraformGenerator) writeInternetGateways() {
+	// An internet gateway is listed once per attachment, generate it once
+	seen := make(map[string]bool)
+	igws := append([]scanner.InternetGateway{}, g.network.InternetGateways...)
+	sort.Slice(igws, func(i, j int) bool { return igws[i].ID < igws[j].ID })
+
+	for _, igw := range igws {
+		if seen[igw.ID] {
+			continue
+		}
+		seen[igw.ID] = true
+
+		g.result.WriteString(fmt.Sprintf("\nresource \"aws_internet_gateway\" %s {\n", hclString(resourceName(igw.ID))))
+		g.result.WriteString(fmt.Sprintf("  vpc_id = %s\n", g.reference(igw.VpcID)))
+		g.writeTags(igw.Tags, "  ")
+		g.result.WriteString("}\n")
+		g.addImport(g.addresses[igw.ID], igw.ID)
+	}
+}
+
+func (g *TerraformGenerator) writeNATGateways() {
+	nats := append([]scanner.NATGateway{}, g.network.NATGateways...)
+	sort.Slice(nats, func(i, j int) bool { return nats[i].ID < nats[j].ID })
+
+	for _, nat := range nats {
+		g.result.WriteString(fmt.Sprintf("\nresource \"aws_nat_gateway\" %s {\n", hclString(resourceName(nat.ID))))
+		g.result.WriteString(fmt.Sprintf("  subnet_id         = %s\n", g.reference(nat.SubnetID)))
+		if nat.ConnectivityType != "" {
+			g.result.WriteString(fmt.Sprintf("  connectivity_type = %s\n", hclString(nat.ConnectivityType)))
+		}
+		if nat.ConnectivityType != "private" && nat.PublicIP != "" {
+			g.result.WriteString(fmt.Sprintf("  # allocation_id = <EIP allocation of %s>\n", nat.PublicIP))
+		}
+		g.writeTags(nat.Tags, "  ")
+		g.result.WriteString("}\n")
+		g.addImport(g.addresses[nat.ID], nat.ID)
+	}
+}
+
+// routeTargetArgument returns the aws_route_table route argument for a route's target
+func (g *TerraformGenerator) routeTargetArgument(route scanner.Route) (string, string) {
+	switch {
+	case route.GatewayID != "":
+		return "gateway_id", g.reference(route.GatewayID)
+	case route.NatGatewayID != "":
+		return "nat_gateway_id", g.reference(route.NatGatewayID)
+	case route.EgressOnlyGatewayID != "":
+		return "egress_only_gateway_id", g.reference(route.EgressOnlyGatewayID)
+	case route.CarrierGatewayID != "":
+		return "carrier_gateway_id", g.reference(route.CarrierGatewayID)
+	case route.LocalGatewayID != "":
+		return "local_gateway_id", g.reference(route.LocalGatewayID)
+	case route.TransitGatewayID != "":
+		return "transit_gateway_id", g.reference(route.TransitGatewayID)
+	case route.VpcPeeringID != "":
+		return "vpc_peering_connection_id", g.reference(route.VpcPeeringID)
+	case route.NetworkInterfaceID != "":
+		return "network_interface_id", g.reference(route.NetworkInterfaceID)
+	}
+	return "", ""
+}
+
+func (g *TerraformGenerator) writeRoutes(routes []scanner.Route) {
+	for _, route := range routes {
+		// Local routes are implicit and cannot be managed
+		if route.GatewayID == "local" || route.Origin == "CreateRouteTable" || route.Destination() == "" {
+			continue
+		}
+
+		argument, target := g.routeTargetArgument(route)
+		if argument == "" {
+			continue
+		}
+
+		g.result.WriteString("\n  route {\n")
+		if route.DestinationCidr != "" {
+			g.result.WriteString(fmt.Sprintf("    cidr_block = %s\n", hclString(route.DestinationCidr)))
+		} else {
+			g.result.WriteString(fmt.Sprintf("    ipv6_cidr_block = %s\n", hclString(route.DestinationIpv6Cidr)))
+		}
+		g.result.WriteString(fmt.Sprintf("    %s = %s\n", argument, target))
+		g.result.WriteString("  }\n")
+	}
+}
+
+func (g *TerraformGenerator) writeRouteTables() {
+	routeTables := append([]scanner.RouteTable{}, g.network.RouteTables...)
+	sort.Slice(routeTables, func(i, j int) bool { return routeTables[i].ID < routeTables[j].ID })
+
+	for _, rt := range routeTables {
+		name := resourceName(rt.ID)
+
+		if rt.IsMain {
+			// The main route table is created with the VPC and adopted rather than created
+			g.result.WriteString(fmt.Sprintf("\nresource \"aws_default_route_table\" %s {\n", hclString(name)))
+			if address, ok := g.addresses[rt.VpcID]; ok {
+				g.result.WriteString(fmt.Sprintf("  default_route_table_id = %s.default_route_table_id\n", address))
+			} else {
+				g.result.WriteString(fmt.Sprintf("  default_route_table_id = %s\n", hclString(rt.ID)))
+			}
+			g.addImport("aws_default_route_table."+name, rt.VpcID)
+		} else {
+			g.result.WriteString(fmt.Sprintf("\nresource \"aws_route_table\" %s {\n", hclString(name)))
+			g.result.WriteString(fmt.Sprintf("  vpc_id = %s\n", g.reference(rt.VpcID)))
+			g.addImport("aws_route_table."+name, rt.ID)
+		}
+
+		g.writeRoutes(rt.Routes)
+		g.writeTags(rt.Tags, "  ")
+		g.result.WriteString("}\n")
+
+		tableRef := "aws_route_table." + name + ".id"
+		if rt.IsMain {
+			tableRef = "aws_default_route_table." + name + ".id"
+		}
+
+		associations := append([]string{}, rt.Associations...)
+		sort.Strings(associations)
+		for _, subnetID := range associations {
+			assocName := resourceName(rt.ID + "_" + subnetID)
+			g.result.WriteString(fmt.Sprintf("\nresource \"aws_route_table_association\" %s {\n", hclString(assocName)))
+			g.result.WriteString(fmt.Sprintf("  subnet_id      = %s\n", g.reference(subnetID)))
+			g.result.WriteString(fmt.Sprintf("  route_table_id = %s\n", tableRef))
+			g.result.WriteString("}\n")
+			g.addImport("aws_route_table_association."+assocName, subnetID+"/"+rt.ID)
+		}
+	}
+}
+
+// writeSecurityGroupRule writes an ingress or egress block of a security group. A
+// rule referencing its own group is written with self, since the group cannot
+// refer to its own ID.
+func (g *TerraformGenerator) writeSecurityGroupRule(groupID, block string, rule scanner.SecurityGroupRule) {
+	g.result.WriteString(fmt.Sprintf("\n  %s {\n", block))
+	g.result.WriteString(fmt.Sprintf("    protocol    = %s\n", hclString(rule.IpProtocol)))
+	g.result.WriteString(fmt.Sprintf("    from_port   = %d\n", rule.FromPort))
+	g.result.WriteString(fmt.Sprintf("    to_port     = %d\n", rule.ToPort))
+	if len(rule.CidrBlocks) > 0 {
+		g.result.WriteString(fmt.Sprintf("    cidr_blocks = %s\n", hclStringList(rule.CidrBlocks)))
+	}
+	if len(rule.Ipv6CidrBlocks) > 0 {
+		g.result.WriteString(fmt.Sprintf("    ipv6_cidr_blocks = %s\n", hclStringList(rule.Ipv6CidrBlocks)))
+	}
+	if len(rule.PrefixListIds) > 0 {
+		g.result.WriteString(fmt.Sprintf("    prefix_list_ids = %s\n", hclStringList(rule.PrefixListIds)))
+	}
+	var groups []string
+	for _, referencedID := range rule.ReferencedGroupIDs() {
+		if referencedID == groupID {
+			g.result.WriteString("    self        = true\n")
+		} else if g.defaultSecurityGroups[referencedID] {
+			// Default groups keep their rules inline, so two of them referring to
+			// each other would form a cycle
+			groups = append(groups, hclString(referencedID))
+		} else {
+			groups = append(groups, g.reference(referencedID))
+		}
+	}
+	if len(groups) > 0 {
+		g.result.WriteString(fmt.Sprintf("    security_groups = [%s]\n", strings.Join(groups, ", ")))
+	}
+	if rule.Description != "" {
+		g.result.WriteString(fmt.Sprintf("    description = %s\n", hclString(rule.Description)))
+	}
+	g.result.WriteString("  }\n")
+}
+
+// referencesOtherGroup reports whether a security group has a rule referencing
+// another security group that is generated
+func (g *TerraformGenerator) referencesOtherGroup(sg scanner.SecurityGroup) bool {
+	for _, rules := range [][]scanner.SecurityGroupRule{sg.IngressRules, sg.EgressRules} {
+		for _, rule := range rules {
+			for _, referencedID := range rule.ReferencedGroupIDs() {
+				if _, ok := g.addresses[referencedID]; ok && referencedID != sg.ID {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// writeSecurityGroupRuleResources writes a rule as aws_vpc_security_group_ingress_rule
+// or egress_rule resources, one per source, for groups whose rules reference other
+// generated groups. Inline rules would make groups referencing each other depend on
+// each other.
+func (g *TerraformGenerator) writeSecurityGroupRuleResources(groupID, direction string, index int, rule scanner.SecurityGroupRule) {
+	var sources [][2]string
+	for _, cidr := range rule.CidrBlocks {
+		sources = append(sources, [2]string{"cidr_ipv4", hclString(cidr)})
+	}
+	for _, cidr := range rule.Ipv6CidrBlocks {
+		sources = append(sources, [2]string{"cidr_ipv6", hclString(cidr)})
+	}
+	for _, prefixList := range rule.PrefixListIds {
+		sources = append(sources, [2]string{"prefix_list_id", hclString(prefixList)})
+	}
+	for _, referencedID := range rule.ReferencedGroupIDs() {
+		sources = append(sources, [2]string{"referenced_security_group_id", g.reference(referencedID)})
+	}
+
+	resourceType := "aws_vpc_security_group_" + direction + "_rule"
+	name := fmt.Sprintf("%s_%s_%d", groupID, direction, index+1)
+	if rule.ID != "" {
+		name = rule.ID
+	}
+	for i, source := range sources {
+		resourceID := resourceName(name)
+		if len(sources) > 1 {
+			resourceID = resourceName(fmt.Sprintf("%s_%d", name, i+1))
+		}
+
+		// Align the arguments the way terraform fmt does
+		width := len("security_group_id")
+		if len(source[0]) > width {
+			width = len(source[0])
+		}
+		argument := func(key, value string) {
+			g.result.WriteString(fmt.Sprintf("  %-*s = %s\n", width, key, value))
+		}
+
+		g.result.WriteString(fmt.Sprintf("\nresource %s %s {\n", hclString(resourceType), hclString(resourceID)))
+		argument("security_group_id", g.reference(groupID))
+		argument("ip_protocol", hclString(rule.IpProtocol))
+		if rule.IpProtocol != "-1" {
+			argument("from_port", strconv.Itoa(int(rule.FromPort)))
+			argument("to_port", strconv.Itoa(int(rule.ToPort)))
+		}
+		argument(source[0], source[1])
+		if rule.Description != "" {
+			argument("description", hclString(rule.Description))
+		}
+		g.writeTags(rule.Tags, "  ")
+		g.result.WriteString("}\n")
+
+		// Rules scanned with their IDs have a single source
+		if rule.ID != "" && len(sources) == 1 {
+			g.addImport(resourceType+"."+resourceID, rule.ID)
+		}
+	}
+}
+
+func (g *TerraformGenerator) writeSecurityGroups() {
+	sgs := append([]scanner.SecurityGroup{}, g.network.SecurityGroups...)
+	sort.Slice(sgs, func(i, j int) bool { return sgs[i].ID < sgs[j].ID })
+
+	for _, sg := range sgs {
+		name := resourceName(sg.ID)
+
+		// The default group's resource removes rules it does not list, so its
+		// rules are always inline
+		separateRules := sg.Name != "default" && g.referencesOtherGroup(sg)
+
+		if sg.Name == "default" {
+			g.result.WriteString(fmt.Sprintf("\nresource \"aws_default_security_group\" %s {\n", hclString(name)))
+			g.result.WriteString(fmt.Sprintf("  vpc_id = %s\n", g.reference(sg.VpcID)))
+		} else {
+			g.result.WriteString(fmt.Sprintf("\nresource \"aws_security_group\" %s {\n", hclString(name)))
+			g.result.WriteString(fmt.Sprintf("  name        = %s\n", hclString(sg.Name)))
+			g.result.WriteString(fmt.Sprintf("  description = %s\n", hclString(sg.Description)))
+			g.result.WriteString(fmt.Sprintf("  vpc_id      = %s\n", g.reference(sg.VpcID)))
+		}
+
+		if !separateRules {
+			for _, rule := range sg.IngressRules {
+				g.writeSecurityGroupRule(sg.ID, "ingress", rule)
+			}
+			for _, rule := range sg.EgressRules {
+				g.writeSecurityGroupRule(sg.ID, "egress", rule)
+			}
+		}
+
+		g.writeTags(sg.Tags, "  ")
+		g.result.WriteString("}\n")
+		g.addImport(g.addresses[sg.ID], sg.ID)
+
+		if separateRules {
+			for i, rule := range sg.IngressRules {
+				g.writeSecurityGroupRuleResources(sg.ID, "ingress", i, rule)
+			}
+			for i, rule := range sg.EgressRules {
+				g.writeSecurityGroupRuleResources(sg.ID, "egress", i, rule)
+			}
+		}
+	}
+}
+
+func (g *TerraformGenerator) writeNetworkAcls() {
+	nacls := append([]scanner.NetworkAcl{}, g.network.NetworkAcls...)
+	sort.Slice(nacls, func(i, j int) bool { return nacls[i].ID < nacls[j].ID })
+
+	for _, nacl := range nacls {
+		name := resourceName(nacl.ID)
+
+		subnetRefs := make([]string, 0, len(nacl.Associations))
+		associations := append([]string{}, nacl.Associations...)
+		sort.Strings(associations)
+		for _, subnetID := range associations {
+			subnetRefs = append(subnetRefs, g.reference(subnetID))
+		}
+
+		if nacl.IsDefault {
+			g.result.WriteString(fmt.Sprintf("\nresource \"aws_default_network_acl\" %s {\n", hclString(name)))
+			if address, ok := g.addresses[nacl.VpcID]; ok {
+				g.result.WriteString(fmt.Sprintf("  default_network_acl_id = %s.default_network_acl_id\n", address))
+			} else {
+				g.result.WriteString(fmt.Sprintf("  default_network_acl_id = %s\n", hclString(nacl.ID)))
+			}
+			g.addImport("aws_default_network_acl."+name, nacl.ID)
+		} else {
+			g.result.WriteString(fmt.Sprintf("\nresource \"aws_network_acl\" %s {\n", hclString(name)))
+			g.result.WriteString(fmt.Sprintf("  vpc_id = %s\n", g.reference(nacl.VpcID)))
+			g.addImport("aws_network_acl."+name, nacl.ID)
+		}
+
+		if len(subnetRefs) > 0 {
+			g.result.WriteString(fmt.Sprintf("  subnet_ids = [%s]\n", strings.Join(subnetRefs, ", ")))
+		}
+
+		entries := append([]scanner.NetworkAclEntry{}, nacl.Entries...)
+		sort.Slice(entries, func(i, j int) bool { return entries[i].RuleNumber < entries[j].RuleNumber })
+		for _, entry := range entries {
+			if entry.RuleNumber == defaultNaclRuleNumber {
+				continue
+			}
+
+			block := "ingress"
+			if entry.Egress {
+				block = "egress"
+			}
+
+			g.result.WriteString(fmt.Sprintf("\n  %s {\n", block))
+			g.result.WriteString(fmt.Sprintf("    rule_no    = %d\n", entry.RuleNumber))
+			g.result.WriteString(fmt.Sprintf("    protocol   = %s\n", hclString(entry.Protocol)))
+			g.result.WriteString(fmt.Sprintf("    action     = %s\n", hclString(entry.RuleAction)))
+			if entry.CidrBlock != "" {
+				g.result.WriteString(fmt.Sprintf("    cidr_block = %s\n", hclString(entry.CidrBlock)))
+			}
+			if entry.Ipv6CidrBlock != "" {
+				g.result.WriteString(fmt.Sprintf("    ipv6_cidr_block = %s\n", hclString(entry.Ipv6CidrBlock)))
+			}
+			fromPort, toPort := int32(0), int32(0)
+			if entry.PortRange != nil {
+				fromPort, toPort = entry.PortRange.From, entry.PortRange.To
+			}
+			g.result.WriteString(fmt.Sprintf("    from_port  = %d\n", fromPort))
+			g.result.WriteString(fmt.Sprintf("    to_port    = %d\n", toPort))
+			if entry.IcmpType != nil {
+				g.result.WriteString(fmt.Sprintf("    icmp_type  = %d\n", entry.IcmpType.Type))
+				g.result.WriteString(fmt.Sprintf("    icmp_code  = %d\n", entry.IcmpType.Code))
+			}
+			g.result.WriteString("  }\n")
+		}
+
+		g.writeTags(nacl.Tags, "  ")
+		g.result.WriteString("}\n")
+	}
+}
+
+func (g *TerraformGenerator) writePeeringConnections() {
+	peerings := append([]scanner.PeeringConnection{}, g.network.PeeringConnections...)
+	sort.Slice(peerings, func(i, j int) bool { return peerings[i].ID < peerings[j].ID })
+
+	for _, pc := range peerings {
+		g.result.WriteString(fmt.Sprintf("\nresource \"aws_vpc_peering_connection\" %s {\n", hclString(resourceName(pc.ID))))
+		g.result.WriteString(fmt.Sprintf("  vpc_id      = %s\n", g.reference(pc.RequesterVpcID)))
+		g.result.WriteString(fmt.Sprintf("  peer_vpc_id = %s\n", g.reference(pc.AccepterVpcID)))
+		g.writeTags(pc.Tags, "  ")
+		g.result.WriteString("}\n")
+		g.addImport(g.addresses[pc.ID], pc.ID)
+	}
+}
+
+// writeImports writes Terraform 1.5+ import blocks for every generated resource
+func (g *TerraformGenerator) writeImports() {
+	if len(g.imports) == 0 {
+		return
+	}
+
+	g.result.WriteString("\n# Import existing resources into state (Terraform 1.5+)\n")
+	for _, imp := range g.imports {
+		g.result.WriteString(fmt.Sprintf("\nimport {\n  to = %s\n  id = %s\n}\n", imp.to, hclString(imp.id)))
+	}
+}
@@ -0,0 +1,183 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func testNetwork() *scanner.Network {
+	return &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-123", CidrBlock: "10.0.0.0/16", Tags: map[string]string{"Name": "main", "aws:cloudformation:stack-name": "net"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-1", VpcID: "vpc-123", CidrBlock: "10.0.1.0/24", AvailabilityZone: "us-east-1a", MapPublicIP: true},
+		},
+		InternetGateways: []scanner.InternetGateway{
+			{ID: "igw-1", VpcID: "vpc-123"},
+		},
+		NATGateways: []scanner.NATGateway{
+			{ID: "nat-1", VpcID: "vpc-123", SubnetID: "subnet-1", ConnectivityType: "public", PublicIP: "203.0.113.10"},
+		},
+		RouteTables: []scanner.RouteTable{
+			{
+				ID:     "rtb-main",
+				VpcID:  "vpc-123",
+				IsMain: true,
+				Routes: []scanner.Route{
+					{DestinationCidr: "10.0.0.0/16", GatewayID: "local"},
+					{DestinationCidr: "0.0.0.0/0", NatGatewayID: "nat-1"},
+				},
+			},
+			{
+				ID:           "rtb-public",
+				VpcID:        "vpc-123",
+				Routes:       []scanner.Route{{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-1"}},
+				Associations: []string{"subnet-1"},
+			},
+		},
+		SecurityGroups: []scanner.SecurityGroup{
+			{
+				ID:          "sg-web",
+				Name:        "web",
+				Description: "Web ${tier}",
+				VpcID:       "vpc-123",
+				IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+					{IpProtocol: "tcp", FromPort: 22, ToPort: 22, ReferencedGroups: []scanner.SecurityGroupReference{{GroupID: "sg-bastion"}}},
+				},
+			},
+		},
+	}
+}
+
+func TestGenerateTerraform(t *testing.T) {
+	result := NewTerraformGenerator(testNetwork()).Generate()
+
+	expected := []string{
+		`resource "aws_vpc" "vpc_123" {`,
+		`  cidr_block = "10.0.0.0/16"`,
+		`    "Name" = "main"`,
+		`  vpc_id                  = aws_vpc.vpc_123.id`,
+		`  map_public_ip_on_launch = true`,
+		`resource "aws_internet_gateway" "igw_1" {`,
+		`  subnet_id         = aws_subnet.subnet_1.id`,
+		`  # allocation_id = <EIP allocation of 203.0.113.10>`,
+		`resource "aws_default_route_table" "rtb_main" {`,
+		`  default_route_table_id = aws_vpc.vpc_123.default_route_table_id`,
+		`    nat_gateway_id = aws_nat_gateway.nat_1.id`,
+		`    gateway_id = aws_internet_gateway.igw_1.id`,
+		`resource "aws_route_table_association" "rtb_public_subnet_1" {`,
+		`  route_table_id = aws_route_table.rtb_public.id`,
+		`  description = "Web $${tier}"`,
+		`    security_groups = ["sg-bastion"]`,
+		"import {\n  to = aws_vpc.vpc_123\n  id = \"vpc-123\"\n}",
+		"import {\n  to = aws_default_route_table.rtb_main\n  id = \"vpc-123\"\n}",
+		"import {\n  to = aws_route_table_association.rtb_public_subnet_1\n  id = \"subnet-1/rtb-public\"\n}",
+	}
+
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, result)
+		}
+	}
+
+	if strings.Contains(result, "aws:cloudformation") {
+		t.Error("Expected AWS-managed tags to be omitted")
+	}
+	if strings.Contains(result, `cidr_block = "10.0.0.0/16"`+"\n    gateway_id = \"local\"") {
+		t.Error("Expected local routes to be omitted")
+	}
+}
+
+func TestGenerateTerraformSecurityGroupReferences(t *testing.T) {
+	network := &scanner.Network{
+		Region: "us-east-1",
+		VPCs:   []scanner.VPC{{ID: "vpc-123", CidrBlock: "10.0.0.0/16"}},
+		SecurityGroups: []scanner.SecurityGroup{
+			{
+				ID:    "sg-default",
+				Name:  "default",
+				VpcID: "vpc-123",
+				IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "-1", ReferencedGroups: []scanner.SecurityGroupReference{{GroupID: "sg-default"}}},
+				},
+			},
+			{
+				ID:    "sg-app",
+				Name:  "app",
+				VpcID: "vpc-123",
+				IngressRules: []scanner.SecurityGroupRule{
+					{ID: "sgr-app-1", IpProtocol: "tcp", FromPort: 8080, ToPort: 8080, ReferencedGroups: []scanner.SecurityGroupReference{{GroupID: "sg-web"}}},
+				},
+				EgressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "-1", CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+			},
+			{
+				ID:    "sg-web",
+				Name:  "web",
+				VpcID: "vpc-123",
+				IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+				EgressRules: []scanner.SecurityGroupRule{
+					{ID: "sgr-web-1", IpProtocol: "tcp", FromPort: 8080, ToPort: 8080, ReferencedGroups: []scanner.SecurityGroupReference{{GroupID: "sg-app"}}},
+				},
+			},
+		},
+	}
+	result := NewTerraformGenerator(network).Generate()
+
+	// The default group's self-referencing rule stays inline
+	defaultGroup := "resource \"aws_default_security_group\" \"sg_default\" {\n  vpc_id = aws_vpc.vpc_123.id\n\n  ingress {\n    protocol    = \"-1\"\n    from_port   = 0\n    to_port     = 0\n    self        = true\n  }\n}"
+	if !strings.Contains(result, defaultGroup) {
+		t.Errorf("Expected the default group to allow itself with self, got:\n%s", result)
+	}
+
+	// Groups referencing each other have their rules written as separate resources
+	expected := []string{
+		"resource \"aws_security_group\" \"sg_app\" {\n  name        = \"app\"\n  description = \"\"\n  vpc_id      = aws_vpc.vpc_123.id\n}",
+		"resource \"aws_vpc_security_group_ingress_rule\" \"sgr_app_1\" {\n" +
+			"  security_group_id            = aws_security_group.sg_app.id\n" +
+			"  ip_protocol                  = \"tcp\"\n" +
+			"  from_port                    = 8080\n" +
+			"  to_port                      = 8080\n" +
+			"  referenced_security_group_id = aws_security_group.sg_web.id\n}",
+		"resource \"aws_vpc_security_group_egress_rule\" \"sg_app_egress_1\" {\n" +
+			"  security_group_id = aws_security_group.sg_app.id\n" +
+			"  ip_protocol       = \"-1\"\n" +
+			"  cidr_ipv4         = \"0.0.0.0/0\"\n}",
+		"resource \"aws_vpc_security_group_egress_rule\" \"sgr_web_1\" {",
+		"  referenced_security_group_id = aws_security_group.sg_app.id\n",
+		"resource \"aws_vpc_security_group_ingress_rule\" \"sg_web_ingress_1\" {",
+		"import {\n  to = aws_vpc_security_group_ingress_rule.sgr_app_1\n  id = \"sgr-app-1\"\n}",
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "security_groups = [aws_security_group.") {
+		t.Errorf("Expected no inline references between generated groups, got:\n%s", result)
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	tests := map[string]string{
+		"vpc-0abc123": "vpc_0abc123",
+		"123":         "r_123",
+		"Sub/Net":     "sub_net",
+	}
+
+	for input, want := range tests {
+		if got := resourceName(input); got != want {
+			t.Errorf("resourceName(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
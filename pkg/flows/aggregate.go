@@ -0,0 +1,435 @@
+package flows
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Endpoint names for traffic whose address is outside every scanned subnet
+const (
+	EndpointInternet = "internet"
+	EndpointExternal = "external"
+)
+
+// Rule directions
+const (
+	DirectionIngress = "ingress"
+	DirectionEgress  = "egress"
+)
+
+// ephemeralPortStart is the lowest port treated as a client's ephemeral port
+const ephemeralPortStart = 1024
+
+// Talker is the traffic observed on one network interface, subnet or security group
+type Talker struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Bytes    int64  `json:"bytes"`
+	Packets  int64  `json:"packets"`
+	Flows    int    `json:"flows"`
+	Rejected int    `json:"rejected"`
+}
+
+// RuleUsage is how many accepted flows a security group rule allowed
+type RuleUsage struct {
+	GroupID   string     `json:"group_id"`
+	GroupName string     `json:"group_name,omitempty"`
+	Direction string     `json:"direction"`
+	Rule      string     `json:"rule"`
+	Flows     int        `json:"flows"`
+	Bytes     int64      `json:"bytes"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
+}
+
+// Edge is the traffic observed between two subnets, or a subnet and an outside endpoint
+type Edge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Bytes   int64  `json:"bytes"`
+	Packets int64  `json:"packets"`
+	Flows   int    `json:"flows"`
+}
+
+// Report summarises the traffic observed in a time window
+type Report struct {
+	Start          time.Time   `json:"start"`
+	End            time.Time   `json:"end"`
+	Records        int         `json:"records"`
+	Rejected       int         `json:"rejected"`
+	Interfaces     []Talker    `json:"interfaces"`
+	Subnets        []Talker    `json:"subnets"`
+	SecurityGroups []Talker    `json:"security_groups"`
+	Rules          []RuleUsage `json:"rules"`
+	Edges          []Edge      `json:"edges"`
+}
+
+// subnetRange is a scanned subnet and its parsed CIDR block
+type subnetRange struct {
+	id      string
+	network *net.IPNet
+}
+
+// Aggregator accumulates flow log records against a scanned network
+type Aggregator struct {
+	network      *scanner.Network
+	interfaces   map[string]Interface
+	ipInterfaces map[string]Interface
+	groups       map[string]scanner.SecurityGroup
+	subnets      []subnetRange
+
+	records         int
+	rejected        int
+	byInterface     map[string]*Talker
+	bySubnet        map[string]*Talker
+	bySecurityGroup map[string]*Talker
+	ruleUsage       map[string]*RuleUsage
+	ruleOrder       []string
+	edges           map[string]*Edge
+}
+
+// NewAggregator creates an aggregator for a scanned network and the network
+// interfaces the flow logs were captured on
+func NewAggregator(network *scanner.Network, interfaces map[string]Interface) *Aggregator {
+	a := &Aggregator{
+		network:         network,
+		interfaces:      interfaces,
+		ipInterfaces:    make(map[string]Interface),
+		groups:          make(map[string]scanner.SecurityGroup),
+		byInterface:     make(map[string]*Talker),
+		bySubnet:        make(map[string]*Talker),
+		bySecurityGroup: make(map[string]*Talker),
+		ruleUsage:       make(map[string]*RuleUsage),
+		edges:           make(map[string]*Edge),
+	}
+
+	for _, i := range interfaces {
+		for _, ip := range i.PrivateIPs {
+			a.ipInterfaces[ip] = i
+		}
+	}
+
+	for _, subnet := range network.Subnets {
+		if _, cidr, err := net.ParseCIDR(subnet.CidrBlock); err == nil {
+			a.subnets = append(a.subnets, subnetRange{id: subnet.ID, network: cidr})
+		}
+	}
+
+	// Every rule is listed so rules no traffic used show up with zero flows
+	for _, sg := range network.SecurityGroups {
+		a.groups[sg.ID] = sg
+		for _, rule := range sg.IngressRules {
+			a.usage(sg, DirectionIngress, rule)
+		}
+		for _, rule := range sg.EgressRules {
+			a.usage(sg, DirectionEgress, rule)
+		}
+	}
+
+	return a
+}
+
+// Add accumulates one flow log record
+func (a *Aggregator) Add(record Record) {
+	a.records++
+	rejected := record.Action == "REJECT"
+	if rejected {
+		a.rejected++
+	}
+
+	count := func(talkers map[string]*Talker, id string) {
+		if id == "" {
+			return
+		}
+		t, exists := talkers[id]
+		if !exists {
+			t = &Talker{ID: id}
+			talkers[id] = t
+		}
+		t.Bytes += record.Bytes
+		t.Packets += record.Packets
+		t.Flows++
+		if rejected {
+			t.Rejected++
+		}
+	}
+
+	eni, known := a.interfaces[record.InterfaceID]
+	count(a.byInterface, record.InterfaceID)
+	if known {
+		count(a.bySubnet, eni.SubnetID)
+		for _, sg := range eni.SecurityGroups {
+			count(a.bySecurityGroup, sg)
+		}
+	} else {
+		count(a.bySubnet, a.subnetOf(record.SrcAddr))
+	}
+
+	if rejected {
+		return
+	}
+
+	if a.countsEdge(record, eni, known) {
+		key := a.endpoint(record.SrcAddr) + "|" + a.endpoint(record.DstAddr)
+		edge, exists := a.edges[key]
+		if !exists {
+			edge = &Edge{From: a.endpoint(record.SrcAddr), To: a.endpoint(record.DstAddr)}
+			a.edges[key] = edge
+		}
+		edge.Bytes += record.Bytes
+		edge.Packets += record.Packets
+		edge.Flows++
+	}
+
+	if known {
+		a.matchRules(record, eni)
+	}
+}
+
+// countsEdge reports whether a record is counted towards the subnet edges. A flow
+// between two known interfaces is captured on both, so only the sender's copy counts.
+func (a *Aggregator) countsEdge(record Record, eni Interface, known bool) bool {
+	if !known {
+		return true
+	}
+	if containsString(eni.PrivateIPs, record.SrcAddr) {
+		return true
+	}
+	_, sender := a.ipInterfaces[record.SrcAddr]
+	return !sender
+}
+
+// matchRules attributes an accepted flow to the first matching rule of each of the
+// interface's security groups. Responses to established connections are not matched,
+// since security groups are stateful and allow them without a rule.
+func (a *Aggregator) matchRules(record Record, eni Interface) {
+	if record.SrcPort > 0 && record.SrcPort < ephemeralPortStart && record.DstPort >= ephemeralPortStart {
+		return
+	}
+
+	var direction, peer string
+	switch {
+	case containsString(eni.PrivateIPs, record.DstAddr):
+		direction, peer = DirectionIngress, record.SrcAddr
+	case containsString(eni.PrivateIPs, record.SrcAddr):
+		direction, peer = DirectionEgress, record.DstAddr
+	default:
+		return
+	}
+
+	peerIP := net.ParseIP(peer)
+	for _, groupID := range eni.SecurityGroups {
+		sg, exists := a.groups[groupID]
+		if !exists {
+			continue
+		}
+		rules := sg.IngressRules
+		if direction == DirectionEgress {
+			rules = sg.EgressRules
+		}
+		for _, rule := range rules {
+			if a.ruleMatches(rule, record, peer, peerIP) {
+				usage := a.usage(sg, direction, rule)
+				usage.Flows++
+				usage.Bytes += record.Bytes
+				if usage.LastSeen == nil || record.End.After(*usage.LastSeen) {
+					lastSeen := record.End
+					usage.LastSeen = &lastSeen
+				}
+				break
+			}
+		}
+	}
+}
+
+// ruleMatches reports whether a rule allows a flow's protocol, destination port and peer
+func (a *Aggregator) ruleMatches(rule scanner.SecurityGroupRule, record Record, peer string, peerIP net.IP) bool {
+	if rule.IpProtocol != "-1" {
+		if protocolNumber(rule.IpProtocol) != record.Protocol {
+			return false
+		}
+		if (record.Protocol == 6 || record.Protocol == 17) && (record.DstPort < rule.FromPort || record.DstPort > rule.ToPort) {
+			return false
+		}
+	}
+
+	if peerIP != nil {
+		for _, block := range append(append([]string{}, rule.CidrBlocks...), rule.Ipv6CidrBlocks...) {
+			if _, cidr, err := net.ParseCIDR(block); err == nil && cidr.Contains(peerIP) {
+				return true
+			}
+		}
+	}
+
+	if peerENI, exists := a.ipInterfaces[peer]; exists {
+		for _, groupID := range rule.ReferencedGroupIDs() {
+			if containsString(peerENI.SecurityGroups, groupID) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// usage returns the usage counter of a rule, creating it on first use
+func (a *Aggregator) usage(sg scanner.SecurityGroup, direction string, rule scanner.SecurityGroupRule) *RuleUsage {
+	label := RuleLabel(rule)
+	key := sg.ID + "|" + direction + "|" + label
+	usage, exists := a.ruleUsage[key]
+	if !exists {
+		usage = &RuleUsage{GroupID: sg.ID, GroupName: sg.Name, Direction: direction, Rule: label}
+		a.ruleUsage[key] = usage
+		a.ruleOrder = append(a.ruleOrder, key)
+	}
+	return usage
+}
+
+// subnetOf returns the scanned subnet containing an address
+func (a *Aggregator) subnetOf(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return ""
+	}
+	for _, subnet := range a.subnets {
+		if subnet.network.Contains(ip) {
+			return subnet.id
+		}
+	}
+	return ""
+}
+
+// endpoint returns the subnet an address belongs to, or the outside endpoint it reaches
+func (a *Aggregator) endpoint(address string) string {
+	if eni, exists := a.ipInterfaces[address]; exists && eni.SubnetID != "" {
+		return eni.SubnetID
+	}
+	if subnet := a.subnetOf(address); subnet != "" {
+		return subnet
+	}
+	if ip := net.ParseIP(address); ip != nil && (ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()) {
+		return EndpointExternal
+	}
+	return EndpointInternet
+}
+
+// Report returns the accumulated traffic, keeping the top talkers of each kind and
+// the top edges. A top of zero or less keeps them all.
+func (a *Aggregator) Report(start, end time.Time, top int) Report {
+	report := Report{
+		Start:          start,
+		End:            end,
+		Records:        a.records,
+		Rejected:       a.rejected,
+		Interfaces:     topTalkers(a.byInterface, top, nil),
+		Subnets:        topTalkers(a.bySubnet, top, a.subnetName),
+		SecurityGroups: topTalkers(a.bySecurityGroup, top, a.groupName),
+	}
+
+	for _, key := range a.ruleOrder {
+		report.Rules = append(report.Rules, *a.ruleUsage[key])
+	}
+
+	for _, edge := range a.edges {
+		report.Edges = append(report.Edges, *edge)
+	}
+	sort.Slice(report.Edges, func(i, j int) bool {
+		if report.Edges[i].Bytes != report.Edges[j].Bytes {
+			return report.Edges[i].Bytes > report.Edges[j].Bytes
+		}
+		return report.Edges[i].From+report.Edges[i].To < report.Edges[j].From+report.Edges[j].To
+	})
+	if top > 0 && len(report.Edges) > top {
+		report.Edges = report.Edges[:top]
+	}
+
+	return report
+}
+
+// subnetName returns the name of a scanned subnet
+func (a *Aggregator) subnetName(id string) string {
+	for _, subnet := range a.network.Subnets {
+		if subnet.ID == id {
+			return subnet.Name
+		}
+	}
+	return ""
+}
+
+// groupName returns the name of a scanned security group
+func (a *Aggregator) groupName(id string) string {
+	return a.groups[id].Name
+}
+
+// topTalkers sorts talkers by bytes and keeps the first top of them
+func topTalkers(talkers map[string]*Talker, top int, name func(string) string) []Talker {
+	var sorted []Talker
+	for _, t := range talkers {
+		talker := *t
+		if name != nil {
+			talker.Name = name(talker.ID)
+		}
+		sorted = append(sorted, talker)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Bytes != sorted[j].Bytes {
+			return sorted[i].Bytes > sorted[j].Bytes
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	if top > 0 && len(sorted) > top {
+		sorted = sorted[:top]
+	}
+	return sorted
+}
+
+// RuleLabel formats a security group rule as its protocol, ports and sources,
+// e.g. "tcp 443-443 10.0.0.0/8"
+func RuleLabel(rule scanner.SecurityGroupRule) string {
+	ports := fmt.Sprintf("%d-%d", rule.FromPort, rule.ToPort)
+	if rule.IpProtocol == "-1" {
+		ports = "all"
+	}
+
+	var sources []string
+	sources = append(sources, rule.CidrBlocks...)
+	sources = append(sources, rule.Ipv6CidrBlocks...)
+	sources = append(sources, rule.PrefixListIds...)
+	sources = append(sources, rule.ReferencedGroupIDs()...)
+	sort.Strings(sources)
+
+	return fmt.Sprintf("%s %s %s", rule.IpProtocol, ports, strings.Join(sources, ","))
+}
+
+// protocolNumber returns the IANA protocol number of a security group rule protocol
+func protocolNumber(protocol string) int32 {
+	switch strings.ToLower(protocol) {
+	case "tcp":
+		return 6
+	case "udp":
+		return 17
+	case "icmp":
+		return 1
+	case "icmpv6":
+		return 58
+	}
+	if n, err := strconv.Atoi(protocol); err == nil {
+		return int32(n)
+	}
+	return -1
+}
+
+// containsString reports whether a slice contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,367 @@
+package flows
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func TestParse(t *testing.T) {
+	parser, err := NewParser(DefaultFormat)
+	if err != nil {
+		t.Fatalf("NewParser failed: %v", err)
+	}
+
+	record, ok, err := parser.Parse("2 123456789012 eni-1 10.0.1.10 10.0.2.20 49152 443 6 10 8400 1700000000 1700000060 ACCEPT OK")
+	if err != nil || !ok {
+		t.Fatalf("Parse failed: ok=%v err=%v", ok, err)
+	}
+	if record.InterfaceID != "eni-1" || record.SrcAddr != "10.0.1.10" || record.DstAddr != "10.0.2.20" {
+		t.Errorf("unexpected addresses: %+v", record)
+	}
+	if record.SrcPort != 49152 || record.DstPort != 443 || record.Protocol != 6 {
+		t.Errorf("unexpected ports or protocol: %+v", record)
+	}
+	if record.Packets != 10 || record.Bytes != 8400 || record.Action != "ACCEPT" {
+		t.Errorf("unexpected counters: %+v", record)
+	}
+	if !record.Start.Equal(time.Unix(1700000000, 0)) || !record.End.Equal(time.Unix(1700000060, 0)) {
+		t.Errorf("unexpected times: %v - %v", record.Start, record.End)
+	}
+
+	skipped := []string{
+		"version account-id interface-id srcaddr dstaddr srcport dstport protocol packets bytes start end action log-status",
+		"2 123456789012 eni-1 - - - - - - - 1700000000 1700000060 - NODATA",
+		"2 123456789012 eni-1 - - - - - - - 1700000000 1700000060 - SKIPDATA",
+		"",
+	}
+	for _, line := range skipped {
+		if _, ok, err := parser.Parse(line); ok || err != nil {
+			t.Errorf("Parse(%q) = ok %v, err %v, want skipped", line, ok, err)
+		}
+	}
+
+	if _, _, err := parser.Parse("2 123456789012 eni-1 10.0.1.10"); err == nil {
+		t.Error("expected an error for a truncated record")
+	}
+	if _, _, err := parser.Parse("2 123456789012 eni-1 10.0.1.10 10.0.2.20 x 443 6 10 8400 1700000000 1700000060 ACCEPT OK"); err == nil {
+		t.Error("expected an error for a non-numeric port")
+	}
+}
+
+func TestNewParserCustomFormat(t *testing.T) {
+	parser, err := NewParser("${interface-id} ${srcaddr} ${dstaddr} ${dstport} ${protocol} ${bytes} ${start} ${end} ${action}")
+	if err != nil {
+		t.Fatalf("NewParser failed: %v", err)
+	}
+	record, ok, err := parser.Parse("eni-1 10.0.1.10 10.0.2.20 443 6 100 1700000000 1700000060 REJECT")
+	if err != nil || !ok {
+		t.Fatalf("Parse failed: ok=%v err=%v", ok, err)
+	}
+	if record.DstPort != 443 || record.Bytes != 100 || record.Action != "REJECT" {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	if _, err := NewParser("version interface-id srcaddr"); err == nil {
+		t.Error("expected an error for a format missing required fields")
+	}
+}
+
+// flowNetwork is a VPC with a web subnet and an app subnet. The app security group
+// allows HTTPS from the web security group and SSH from the office range.
+func flowNetwork() *scanner.Network {
+	return &scanner.Network{
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-web", Name: "web", CidrBlock: "10.0.1.0/24"},
+			{ID: "subnet-app", Name: "app", CidrBlock: "10.0.2.0/24"},
+		},
+		SecurityGroups: []scanner.SecurityGroup{
+			{
+				ID:   "sg-web",
+				Name: "web",
+				IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+				EgressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "-1", CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+			},
+			{
+				ID:   "sg-app",
+				Name: "app",
+				IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "tcp", FromPort: 8443, ToPort: 8443, ReferencedGroups: []scanner.SecurityGroupReference{{GroupID: "sg-web"}}},
+					{IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"192.168.0.0/16"}},
+				},
+			},
+		},
+	}
+}
+
+func flowInterfaces() map[string]Interface {
+	return map[string]Interface{
+		"eni-web": {ID: "eni-web", SubnetID: "subnet-web", PrivateIPs: []string{"10.0.1.10"}, SecurityGroups: []string{"sg-web"}},
+		"eni-app": {ID: "eni-app", SubnetID: "subnet-app", PrivateIPs: []string{"10.0.2.20"}, SecurityGroups: []string{"sg-app"}},
+	}
+}
+
+func flowRecord(eni, src, dst string, srcPort, dstPort int32, bytes int64, action string) Record {
+	return Record{
+		InterfaceID: eni,
+		SrcAddr:     src,
+		DstAddr:     dst,
+		SrcPort:     srcPort,
+		DstPort:     dstPort,
+		Protocol:    6,
+		Packets:     1,
+		Bytes:       bytes,
+		Action:      action,
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	aggregator := NewAggregator(flowNetwork(), flowInterfaces())
+
+	// Internet client to the web tier, and the response
+	aggregator.Add(flowRecord("eni-web", "203.0.113.5", "10.0.1.10", 50000, 443, 1000, "ACCEPT"))
+	aggregator.Add(flowRecord("eni-web", "10.0.1.10", "203.0.113.5", 443, 50000, 9000, "ACCEPT"))
+	// Web tier to the app tier, captured on both interfaces
+	aggregator.Add(flowRecord("eni-web", "10.0.1.10", "10.0.2.20", 40000, 8443, 500, "ACCEPT"))
+	aggregator.Add(flowRecord("eni-app", "10.0.1.10", "10.0.2.20", 40000, 8443, 500, "ACCEPT"))
+	// Rejected SSH attempt from the internet
+	aggregator.Add(flowRecord("eni-app", "198.51.100.7", "10.0.2.20", 40001, 22, 60, "REJECT"))
+
+	report := aggregator.Report(time.Unix(0, 0), time.Unix(60, 0), 0)
+
+	if report.Records != 5 || report.Rejected != 1 {
+		t.Errorf("Records = %d, Rejected = %d, want 5 and 1", report.Records, report.Rejected)
+	}
+
+	if len(report.Interfaces) != 2 || report.Interfaces[0].ID != "eni-web" || report.Interfaces[0].Bytes != 10500 {
+		t.Errorf("unexpected interface talkers: %+v", report.Interfaces)
+	}
+	if len(report.Subnets) != 2 || report.Subnets[0].Name != "web" {
+		t.Errorf("unexpected subnet talkers: %+v", report.Subnets)
+	}
+	if report.SecurityGroups[1].ID != "sg-app" || report.SecurityGroups[1].Rejected != 1 {
+		t.Errorf("unexpected security group talkers: %+v", report.SecurityGroups)
+	}
+
+	edges := make(map[string]Edge)
+	for _, edge := range report.Edges {
+		edges[edge.From+">"+edge.To] = edge
+	}
+	if edge := edges["subnet-web>subnet-app"]; edge.Bytes != 500 || edge.Flows != 1 {
+		t.Errorf("web to app edge = %+v, want 500 bytes counted once", edge)
+	}
+	if edge := edges["internet>subnet-web"]; edge.Bytes != 1000 {
+		t.Errorf("internet to web edge = %+v, want 1000 bytes", edge)
+	}
+	if edge := edges["subnet-web>internet"]; edge.Bytes != 9000 {
+		t.Errorf("web to internet edge = %+v, want 9000 bytes", edge)
+	}
+	if _, exists := edges["internet>subnet-app"]; exists {
+		t.Error("rejected traffic should not be drawn as an edge")
+	}
+
+	usage := make(map[string]RuleUsage)
+	for _, rule := range report.Rules {
+		usage[rule.GroupID+" "+rule.Direction+" "+rule.Rule] = rule
+	}
+	expected := map[string]int{
+		"sg-web ingress tcp 443-443 0.0.0.0/0":    1,
+		"sg-web egress -1 all 0.0.0.0/0":          1,
+		"sg-app ingress tcp 8443-8443 sg-web":     1,
+		"sg-app ingress tcp 22-22 192.168.0.0/16": 0,
+	}
+	for key, flows := range expected {
+		rule, exists := usage[key]
+		if !exists {
+			t.Errorf("missing rule usage %q in %+v", key, report.Rules)
+			continue
+		}
+		if rule.Flows != flows {
+			t.Errorf("rule %q used by %d flows, want %d", key, rule.Flows, flows)
+		}
+		if (rule.LastSeen != nil) != (flows > 0) {
+			t.Errorf("rule %q last seen %v, want it set only for used rules", key, rule.LastSeen)
+		}
+	}
+	if unused := report.UnusedRules(); len(unused) != 1 {
+		t.Errorf("UnusedRules = %+v, want only the SSH rule", unused)
+	}
+}
+
+func TestAggregatorWithoutInterfaces(t *testing.T) {
+	aggregator := NewAggregator(flowNetwork(), nil)
+	aggregator.Add(flowRecord("eni-unknown", "10.0.1.10", "172.16.0.1", 40000, 5432, 100, "ACCEPT"))
+
+	report := aggregator.Report(time.Time{}, time.Time{}, 0)
+	if len(report.Subnets) != 1 || report.Subnets[0].ID != "subnet-web" {
+		t.Errorf("traffic should be attributed to the subnet by address: %+v", report.Subnets)
+	}
+	if len(report.Edges) != 1 || report.Edges[0].From != "subnet-web" || report.Edges[0].To != EndpointExternal {
+		t.Errorf("unexpected edges: %+v", report.Edges)
+	}
+	if len(report.SecurityGroups) != 0 {
+		t.Errorf("security groups need interface details: %+v", report.SecurityGroups)
+	}
+}
+
+func TestReportTop(t *testing.T) {
+	aggregator := NewAggregator(flowNetwork(), flowInterfaces())
+	aggregator.Add(flowRecord("eni-web", "203.0.113.5", "10.0.1.10", 50000, 443, 1000, "ACCEPT"))
+	aggregator.Add(flowRecord("eni-app", "10.0.2.20", "203.0.113.6", 40000, 443, 2000, "ACCEPT"))
+
+	report := aggregator.Report(time.Time{}, time.Time{}, 1)
+	if len(report.Interfaces) != 1 || report.Interfaces[0].ID != "eni-app" {
+		t.Errorf("unexpected top interfaces: %+v", report.Interfaces)
+	}
+	if len(report.Edges) != 1 || report.Edges[0].Bytes != 2000 {
+		t.Errorf("unexpected top edges: %+v", report.Edges)
+	}
+
+	text := report.Text()
+	for _, want := range []string{"Top Network Interfaces", "eni-app", "2.0 KiB", "unused"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text report missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for bytes, want := range tests {
+		if got := FormatBytes(bytes); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
+
+type fakeCloudWatchLogs struct {
+	input *cloudwatchlogs.FilterLogEventsInput
+	pages [][]string
+}
+
+func (f *fakeCloudWatchLogs) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	f.input = params
+	page := 0
+	if params.NextToken != nil {
+		page = int((*params.NextToken)[0] - '0')
+	}
+	output := &cloudwatchlogs.FilterLogEventsOutput{}
+	for _, message := range f.pages[page] {
+		output.Events = append(output.Events, cwtypes.FilteredLogEvent{Message: &[]string{message}[0]})
+	}
+	if page+1 < len(f.pages) {
+		output.NextToken = &[]string{string(rune('0' + page + 1))}[0]
+	}
+	return output, nil
+}
+
+func TestCloudWatchSource(t *testing.T) {
+	api := &fakeCloudWatchLogs{pages: [][]string{{"a", "b"}, {"c"}}}
+	start, end := time.Unix(1000, 0), time.Unix(2000, 0)
+
+	var lines []string
+	err := NewCloudWatchSource(api, "flow-logs").Read(context.Background(), start, end, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if strings.Join(lines, ",") != "a,b,c" {
+		t.Errorf("lines = %v, want a,b,c across pages", lines)
+	}
+	if *api.input.LogGroupName != "flow-logs" || *api.input.StartTime != 1000000 || *api.input.EndTime != 2000000 {
+		t.Errorf("unexpected input: %+v", api.input)
+	}
+}
+
+type fakeS3 struct {
+	objects  []s3types.Object
+	contents map[string][]byte
+	read     []string
+}
+
+func (f *fakeS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	var objects []s3types.Object
+	for _, object := range f.objects {
+		if strings.HasPrefix(*object.Key, *params.Prefix) {
+			objects = append(objects, object)
+		}
+	}
+	return &s3.ListObjectsV2Output{Contents: objects}, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.read = append(f.read, *params.Key)
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(f.contents[*params.Key]))}, nil
+}
+
+func TestS3Source(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("header\nrecord-1\nrecord-2\n"))
+	gz.Close()
+
+	start, end := time.Unix(10000, 0), time.Unix(20000, 0)
+	at := func(seconds int64) *time.Time { t := time.Unix(seconds, 0); return &t }
+	api := &fakeS3{
+		objects: []s3types.Object{
+			{Key: &[]string{"logs/old.log.gz"}[0], LastModified: at(5000)},
+			{Key: &[]string{"logs/current.log.gz"}[0], LastModified: at(15000)},
+			{Key: &[]string{"logs/plain.log"}[0], LastModified: at(20500)},
+			{Key: &[]string{"logs/later.log.gz"}[0], LastModified: at(40000)},
+			{Key: &[]string{"other/current.log.gz"}[0], LastModified: at(15000)},
+		},
+		contents: map[string][]byte{
+			"logs/current.log.gz": compressed.Bytes(),
+			"logs/plain.log":      []byte("record-3\n"),
+		},
+	}
+
+	source, err := NewS3Source(api, "s3://bucket/logs/")
+	if err != nil {
+		t.Fatalf("NewS3Source failed: %v", err)
+	}
+
+	var lines []string
+	err = source.Read(context.Background(), start, end, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if strings.Join(lines, ",") != "header,record-1,record-2,record-3" {
+		t.Errorf("lines = %v", lines)
+	}
+	if strings.Join(api.read, ",") != "logs/current.log.gz,logs/plain.log" {
+		t.Errorf("read objects %v, want only those delivered in the window", api.read)
+	}
+
+	for _, uri := range []string{"bucket/logs", "https://bucket/logs", "s3:///logs"} {
+		if _, err := NewS3Source(api, uri); err == nil {
+			t.Errorf("expected an error for %q", uri)
+		}
+	}
+}
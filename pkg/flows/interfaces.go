@@ -0,0 +1,95 @@
+package flows
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// interfaceBatchSize is how many network interface IDs are described per call
+const interfaceBatchSize = 200
+
+// Interface is the network interface a flow log record was captured on
+type Interface struct {
+	ID             string
+	SubnetID       string
+	VpcID          string
+	PrivateIPs     []string
+	SecurityGroups []string
+}
+
+// EC2API is the subset of the EC2 API used to look up network interfaces
+type EC2API interface {
+	DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
+}
+
+// LookupInterfaces describes the network interfaces with the given IDs. Interfaces
+// deleted since the traffic was logged are left out.
+func LookupInterfaces(ctx context.Context, api EC2API, ids []string) (map[string]Interface, error) {
+	interfaces := make(map[string]Interface)
+
+	for start := 0; start < len(ids); start += interfaceBatchSize {
+		end := start + interfaceBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		// A filter, unlike NetworkInterfaceIds, does not fail on deleted interfaces
+		pages := ec2.NewDescribeNetworkInterfacesPaginator(api, &ec2.DescribeNetworkInterfacesInput{
+			Filters: []types.Filter{
+				{
+					Name:   &[]string{"network-interface-id"}[0],
+					Values: ids[start:end],
+				},
+			},
+		})
+		for pages.HasMorePages() {
+			page, err := pages.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, eni := range page.NetworkInterfaces {
+				if eni.NetworkInterfaceId == nil {
+					continue
+				}
+				i := Interface{ID: *eni.NetworkInterfaceId}
+				if eni.SubnetId != nil {
+					i.SubnetID = *eni.SubnetId
+				}
+				if eni.VpcId != nil {
+					i.VpcID = *eni.VpcId
+				}
+				for _, address := range eni.PrivateIpAddresses {
+					if address.PrivateIpAddress != nil {
+						i.PrivateIPs = append(i.PrivateIPs, *address.PrivateIpAddress)
+					}
+				}
+				for _, group := range eni.Groups {
+					if group.GroupId != nil {
+						i.SecurityGroups = append(i.SecurityGroups, *group.GroupId)
+					}
+				}
+				interfaces[i.ID] = i
+			}
+		}
+	}
+
+	return interfaces, nil
+}
+
+// InterfacesFromNetwork returns the network interfaces recorded in a scan, so
+// only interfaces created since the scan need to be looked up
+func InterfacesFromNetwork(network *scanner.Network) map[string]Interface {
+	interfaces := make(map[string]Interface)
+	for _, eni := range network.NetworkInterfaces {
+		i := Interface{ID: eni.ID, SubnetID: eni.SubnetID, VpcID: eni.VpcID, SecurityGroups: eni.SecurityGroupIDs}
+		if eni.PrivateIP != "" {
+			i.PrivateIPs = append(i.PrivateIPs, eni.PrivateIP)
+		}
+		i.PrivateIPs = append(i.PrivateIPs, eni.SecondaryPrivateIPs...)
+		interfaces[i.ID] = i
+	}
+	return interfaces
+}
@@ -0,0 +1,121 @@
+// Package flows reads VPC Flow Logs and aggregates the observed traffic against a scanned network.
+package flows
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFormat is the field order of the default (version 2) flow log format
+const DefaultFormat = "version account-id interface-id srcaddr dstaddr srcport dstport protocol packets bytes start end action log-status"
+
+// Record is one flow log record
+type Record struct {
+	InterfaceID string
+	SrcAddr     string
+	DstAddr     string
+	SrcPort     int32
+	DstPort     int32
+	Protocol    int32
+	Packets     int64
+	Bytes       int64
+	Start       time.Time
+	End         time.Time
+	Action      string
+}
+
+// requiredFields are the fields a flow log format must include to be aggregated
+var requiredFields = []string{"interface-id", "srcaddr", "dstaddr", "dstport", "protocol", "bytes", "start", "end", "action"}
+
+// Parser parses flow log records in a given format
+type Parser struct {
+	fields map[string]int
+}
+
+// NewParser creates a parser for a flow log format. The format lists the field
+// names in order, with or without the ${...} wrapping used when creating flow logs.
+func NewParser(format string) (*Parser, error) {
+	p := &Parser{fields: make(map[string]int)}
+	for i, field := range strings.Fields(format) {
+		field = strings.TrimSuffix(strings.TrimPrefix(field, "${"), "}")
+		p.fields[field] = i
+	}
+
+	for _, field := range requiredFields {
+		if _, ok := p.fields[field]; !ok {
+			return nil, fmt.Errorf("flow log format is missing the %s field", field)
+		}
+	}
+
+	return p, nil
+}
+
+// Parse parses one line. Header lines and records without data (NODATA, SKIPDATA)
+// are skipped with ok set to false.
+func (p *Parser) Parse(line string) (record Record, ok bool, err error) {
+	values := strings.Fields(line)
+	if len(values) < len(p.fields) {
+		if len(values) == 0 {
+			return Record{}, false, nil
+		}
+		return Record{}, false, fmt.Errorf("flow log record has %d fields, expected %d: %q", len(values), len(p.fields), line)
+	}
+
+	value := func(field string) string {
+		if i, exists := p.fields[field]; exists {
+			return values[i]
+		}
+		return "-"
+	}
+
+	// S3 log files start with a header naming the fields
+	if value("interface-id") == "interface-id" {
+		return Record{}, false, nil
+	}
+	if status := value("log-status"); status == "NODATA" || status == "SKIPDATA" {
+		return Record{}, false, nil
+	}
+	if value("srcaddr") == "-" || value("action") == "-" {
+		return Record{}, false, nil
+	}
+
+	record = Record{
+		InterfaceID: value("interface-id"),
+		SrcAddr:     value("srcaddr"),
+		DstAddr:     value("dstaddr"),
+		Action:      value("action"),
+	}
+
+	var parseErr error
+	parseInt := func(field string, bits int) int64 {
+		v := value(field)
+		if v == "-" {
+			return 0
+		}
+		n, err := strconv.ParseInt(v, 10, bits)
+		if err != nil && parseErr == nil {
+			parseErr = fmt.Errorf("invalid %s %q in flow log record", field, v)
+		}
+		return n
+	}
+
+	record.SrcPort = int32(parseInt("srcport", 32))
+	record.DstPort = int32(parseInt("dstport", 32))
+	record.Protocol = int32(parseInt("protocol", 32))
+	record.Packets = parseInt("packets", 64)
+	record.Bytes = parseInt("bytes", 64)
+	record.Start = time.Unix(parseInt("start", 64), 0)
+	record.End = time.Unix(parseInt("end", 64), 0)
+	if parseErr != nil {
+		return Record{}, false, parseErr
+	}
+
+	return record, true, nil
+}
+
+// Within reports whether the record overlaps the time window
+func (r Record) Within(start, end time.Time) bool {
+	return r.Start.Before(end) && !r.End.Before(start)
+}
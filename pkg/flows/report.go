@@ -0,0 +1,78 @@
+package flows
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatBytes formats a byte count with a binary unit, e.g. "1.5 MiB"
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// UnusedRules returns the rules no accepted flow was attributed to
+func (r Report) UnusedRules() []RuleUsage {
+	var unused []RuleUsage
+	for _, rule := range r.Rules {
+		if rule.Flows == 0 {
+			unused = append(unused, rule)
+		}
+	}
+	return unused
+}
+
+// Text renders the report as tables
+func (r Report) Text() string {
+	var result strings.Builder
+
+	result.WriteString(fmt.Sprintf("Flow Log Traffic %s - %s\n", r.Start.Format("2006-01-02 15:04:05"), r.End.Format("2006-01-02 15:04:05")))
+	result.WriteString(fmt.Sprintf("Records: %d (%d rejected)\n", r.Records, r.Rejected))
+
+	writeTalkers := func(title string, talkers []Talker) {
+		result.WriteString(fmt.Sprintf("\nTop %s:\n", title))
+		if len(talkers) == 0 {
+			result.WriteString("  (none)\n")
+			return
+		}
+		result.WriteString(fmt.Sprintf("  %-26s %-24s %12s %10s %8s %8s\n", "ID", "NAME", "BYTES", "PACKETS", "FLOWS", "REJECTED"))
+		for _, t := range talkers {
+			result.WriteString(fmt.Sprintf("  %-26s %-24s %12s %10d %8d %8d\n", t.ID, t.Name, FormatBytes(t.Bytes), t.Packets, t.Flows, t.Rejected))
+		}
+	}
+	writeTalkers("Network Interfaces", r.Interfaces)
+	writeTalkers("Subnets", r.Subnets)
+	writeTalkers("Security Groups", r.SecurityGroups)
+
+	result.WriteString("\nObserved Traffic:\n")
+	if len(r.Edges) == 0 {
+		result.WriteString("  (none)\n")
+	}
+	for _, edge := range r.Edges {
+		result.WriteString(fmt.Sprintf("  %s → %s: %s in %d flows\n", edge.From, edge.To, FormatBytes(edge.Bytes), edge.Flows))
+	}
+
+	result.WriteString("\nSecurity Group Rule Usage:\n")
+	if len(r.Rules) == 0 {
+		result.WriteString("  (none)\n")
+	}
+	for _, rule := range r.Rules {
+		usage := "unused"
+		if rule.Flows > 0 {
+			usage = fmt.Sprintf("%d flows, %s", rule.Flows, FormatBytes(rule.Bytes))
+		}
+		result.WriteString(fmt.Sprintf("  %s %-7s %-40s %s\n", rule.GroupID, rule.Direction, rule.Rule, usage))
+	}
+
+	result.WriteString(fmt.Sprintf("\nSummary: %d of %d security group rules unused in this window\n", len(r.UnusedRules()), len(r.Rules)))
+
+	return result.String()
+}
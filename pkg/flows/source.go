@@ -0,0 +1,159 @@
+package flows
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3DeliveryDelay is how long after a record's capture window its log file may still be delivered
+const s3DeliveryDelay = time.Hour
+
+// Source reads flow log lines for a time window
+type Source interface {
+	Read(ctx context.Context, start, end time.Time, handle func(line string) error) error
+}
+
+// CloudWatchLogsAPI is the subset of the CloudWatch Logs API used to read flow logs
+type CloudWatchLogsAPI interface {
+	FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error)
+}
+
+// S3API is the subset of the S3 API used to read flow logs
+type S3API interface {
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// CloudWatchSource reads flow logs delivered to a CloudWatch Logs log group
+type CloudWatchSource struct {
+	api      CloudWatchLogsAPI
+	logGroup string
+}
+
+// NewCloudWatchSource creates a source reading the given log group
+func NewCloudWatchSource(api CloudWatchLogsAPI, logGroup string) *CloudWatchSource {
+	return &CloudWatchSource{api: api, logGroup: logGroup}
+}
+
+// Read reads the log events ingested within the time window
+func (s *CloudWatchSource) Read(ctx context.Context, start, end time.Time, handle func(line string) error) error {
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: &s.logGroup,
+		StartTime:    &[]int64{start.UnixMilli()}[0],
+		EndTime:      &[]int64{end.UnixMilli()}[0],
+	}
+
+	pages := cloudwatchlogs.NewFilterLogEventsPaginator(s.api, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read log group %s: %w", s.logGroup, err)
+		}
+		for _, event := range page.Events {
+			if event.Message == nil {
+				continue
+			}
+			if err := handle(*event.Message); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// S3Source reads flow log files delivered to an S3 bucket
+type S3Source struct {
+	api    S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Source creates a source reading the files under an s3://bucket/prefix URI
+func NewS3Source(api S3API, uri string) (*S3Source, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "s3" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid S3 URI %q, expected s3://bucket/prefix", uri)
+	}
+	return &S3Source{
+		api:    api,
+		bucket: parsed.Host,
+		prefix: strings.TrimPrefix(parsed.Path, "/"),
+	}, nil
+}
+
+// Read reads the lines of every log file that may hold records of the time window.
+// Files delivered before the window starts, or long after it ends, are skipped.
+func (s *S3Source) Read(ctx context.Context, start, end time.Time, handle func(line string) error) error {
+	pages := s3.NewListObjectsV2Paginator(s.api, &s3.ListObjectsV2Input{
+		Bucket: &s.bucket,
+		Prefix: &s.prefix,
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list s3://%s/%s: %w", s.bucket, s.prefix, err)
+		}
+
+		for _, object := range page.Contents {
+			if object.Key == nil {
+				continue
+			}
+			if object.LastModified != nil && (object.LastModified.Before(start) || object.LastModified.After(end.Add(s3DeliveryDelay))) {
+				continue
+			}
+			if strings.HasSuffix(*object.Key, ".parquet") {
+				return fmt.Errorf("flow log file s3://%s/%s is Parquet, only text flow logs are supported", s.bucket, *object.Key)
+			}
+			if err := s.readObject(ctx, *object.Key, handle); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readObject reads the lines of one log file, decompressing gzip files
+func (s *S3Source) readObject(ctx context.Context, key string, handle func(line string) error) error {
+	result, err := s.api.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read s3://%s/%s: %w", s.bucket, key, err)
+	}
+	defer result.Body.Close()
+
+	var reader io.Reader = result.Body
+	if strings.HasSuffix(key, ".gz") {
+		gz, err := gzip.NewReader(result.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decompress s3://%s/%s: %w", s.bucket, key, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := handle(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read s3://%s/%s: %w", s.bucket, key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Architectural limits of inter-VPC connectivity. These are published AWS
+// quotas and pricing rules that cannot be raised, so they are worth surfacing
+// next to the edges they constrain.
+const (
+	peeringNonTransitiveNote = "non-transitive: traffic cannot route through this VPC to a third VPC, IGW, NAT or VPN"
+	peeringBandwidthNote     = "no aggregate bandwidth limit; per-flow limits of the instances apply"
+	crossAZChargeNote        = "cross-AZ traffic is billed per GB in each direction"
+
+	tgwVPCBandwidthNote     = "up to 100 Gbps per VPC attachment per AZ"
+	tgwVPNBandwidthNote     = "up to 1.25 Gbps per VPN tunnel; use ECMP across tunnels for more"
+	tgwConnectBandwidthNote = "up to 5 Gbps per Connect (GRE) peer"
+	tgwPeeringNote          = "inter-region TGW peering: static routes only, billed as inter-region transfer"
+	tgwDXGatewayNote        = "bandwidth bounded by the Direct Connect connection"
+	tgwProcessingChargeNote = "data processing billed per GB sent through the transit gateway"
+)
+
+// peeringAnnotations returns the architectural notes for a VPC peering connection
+func peeringAnnotations(peering scanner.PeeringConnection) []string {
+	notes := []string{
+		peeringNonTransitiveNote,
+		peeringBandwidthNote,
+		crossAZChargeNote,
+	}
+	return append(notes, regionPairAnnotations(peering.RequesterRegion, peering.AccepterRegion)...)
+}
+
+// transitGatewayAttachmentAnnotations returns the architectural notes for a TGW attachment
+// of a transit gateway in the given region
+func transitGatewayAttachmentAnnotations(attachment scanner.TransitGatewayAttachment, region string) []string {
+	var notes []string
+
+	switch attachment.ResourceType {
+	case "vpc":
+		notes = append(notes, tgwVPCBandwidthNote, crossAZChargeNote)
+	case "vpn":
+		notes = append(notes, tgwVPNBandwidthNote)
+	case "connect":
+		notes = append(notes, tgwConnectBandwidthNote)
+	case "peering":
+		notes = append(notes, tgwPeeringNote)
+		notes = append(notes, regionPairAnnotations(region, attachment.PeerRegion)...)
+	case "direct-connect-gateway":
+		notes = append(notes, tgwDXGatewayNote)
+	}
+
+	return append(notes, tgwProcessingChargeNote)
+}
+
+// writeAnnotations writes notes beneath a tree item
+func writeAnnotations(result *strings.Builder, notes []string, isLast bool) {
+	indent := "│   "
+	if isLast {
+		indent = "    "
+	}
+
+	for _, note := range notes {
+		result.WriteString(indent + "  ⓘ " + note + "\n")
+	}
+}
+
+// dotTooltip joins notes into a DOT tooltip attribute value
+func dotTooltip(notes []string) string {
+	return strings.Join(notes, "\\n")
+}
@@ -0,0 +1,48 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+// SetColor enables ANSI colors in the text graph: public subnets green, isolated
+// subnets red and resources in a stale state, such as a deleted NAT gateway or a
+// blackhole route, yellow
+func (v *Visualizer) SetColor(enabled bool) {
+	v.color = enabled
+}
+
+// paint colors text when colors are enabled. Only the text graph is colored, since
+// the HTML page embeds the same tree.
+func (v *Visualizer) paint(attribute color.Attribute, text string) string {
+	if !v.color || v.format != "text" {
+		return text
+	}
+	c := color.New(attribute)
+	c.EnableColor()
+	return c.Sprint(text)
+}
+
+// subnetTypeLabel colors a subnet type label by how exposed the subnet is
+func (v *Visualizer) subnetTypeLabel(subnetType, label string) string {
+	switch subnetType {
+	case "public":
+		return v.paint(color.FgGreen, label)
+	case "isolated":
+		return v.paint(color.FgRed, label)
+	}
+	return label
+}
+
+// stateLabel formats a resource state in brackets, in yellow unless it is one of
+// the resource's healthy states
+func (v *Visualizer) stateLabel(state string, healthy ...string) string {
+	label := fmt.Sprintf("[%s]", state)
+	for _, h := range healthy {
+		if state == h {
+			return label
+		}
+	}
+	return v.paint(color.FgYellow, label)
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// subnetNames returns the names of subnets, falling back to their IDs
+func subnetNames(subnetIDs []string, subnetMap map[string]scanner.Subnet) string {
+	names := make([]string, len(subnetIDs))
+	for i, id := range subnetIDs {
+		names[i] = id
+		if subnet, exists := subnetMap[id]; exists && subnet.Name != "" {
+			names[i] = subnet.Name
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// eksEndpointAccess describes who can reach an EKS cluster's API server endpoint
+func eksEndpointAccess(cluster scanner.EKSCluster) string {
+	switch {
+	case cluster.EndpointPublicAccess && cluster.EndpointPrivateAccess:
+		return "public+private"
+	case cluster.EndpointPublicAccess:
+		return "public"
+	default:
+		return "private"
+	}
+}
+
+// ecsServiceLabel names an ECS service with its cluster
+func ecsServiceLabel(service scanner.ECSService) string {
+	return fmt.Sprintf("%s (cluster %s)", service.Name, service.ClusterName)
+}
+
+// writeEKSCluster writes an EKS cluster and the subnets its control plane uses
+func (v *Visualizer) writeEKSCluster(result *strings.Builder, cluster scanner.EKSCluster, subnetMap map[string]scanner.Subnet, isLast bool) {
+	prefix := "├── "
+	if isLast {
+		prefix = "└── "
+	}
+
+	result.WriteString(fmt.Sprintf("%sEKS Cluster: %s v%s [%s] Endpoint:%s Subnets: %s\n", prefix, cluster.Name, cluster.Version,
+		cluster.Status, eksEndpointAccess(cluster), subnetNames(cluster.SubnetIDs, subnetMap)))
+}
+
+// writeECSService writes an ECS service and the subnets its tasks run in
+func (v *Visualizer) writeECSService(result *strings.Builder, service scanner.ECSService, subnetMap map[string]scanner.Subnet, isLast bool) {
+	prefix := "├── "
+	if isLast {
+		prefix = "└── "
+	}
+
+	launchType := ""
+	if service.LaunchType != "" {
+		launchType = fmt.Sprintf(" [%s]", service.LaunchType)
+	}
+
+	result.WriteString(fmt.Sprintf("%sECS Service: %s%s Tasks:%d/%d Subnets: %s\n", prefix, ecsServiceLabel(service), launchType,
+		service.RunningCount, service.DesiredCount, subnetNames(service.SubnetIDs, subnetMap)))
+}
+
+// writeDotContainers writes EKS clusters and ECS services with edges to the subnets they run in
+func (v *Visualizer) writeDotContainers(result *strings.Builder, network *scanner.Network) {
+	if len(network.EKSClusters) == 0 && len(network.ECSServices) == 0 {
+		return
+	}
+
+	result.WriteString("\n  // Containers\n")
+	for _, cluster := range network.EKSClusters {
+		label := fmt.Sprintf("%s\\nEKS Cluster v%s\\nEndpoint: %s", cluster.Name, cluster.Version, eksEndpointAccess(cluster))
+		result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", shape=component, fillcolor=lightskyblue%s];\n", cluster.Arn, label, v.dotIcon("eks")))
+		for _, subnetID := range cluster.SubnetIDs {
+			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [style=dotted, label=\"in\"];\n", cluster.Arn, subnetID))
+		}
+	}
+	for _, service := range network.ECSServices {
+		label := fmt.Sprintf("%s\\nECS Service (%s)\\nTasks: %d/%d", service.Name, service.ClusterName, service.RunningCount, service.DesiredCount)
+		result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", shape=component, fillcolor=lightskyblue%s];\n", service.Arn, label, v.dotIcon("ecs")))
+		for _, subnetID := range service.SubnetIDs {
+			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [style=dotted, label=\"in\"];\n", service.Arn, subnetID))
+		}
+	}
+}
+
+// writeMermaidContainers writes the EKS clusters and ECS services of a VPC inside its subgraph
+func (v *Visualizer) writeMermaidContainers(result *strings.Builder, network *scanner.Network, vpcID string) {
+	for _, cluster := range network.EKSClusters {
+		if cluster.VpcID == vpcID {
+			result.WriteString(fmt.Sprintf("    %s[[\"%s\"]]:::container\n", mermaidID(cluster.Arn),
+				mermaidLabel(cluster.Name, "EKS Cluster v"+cluster.Version, "Endpoint: "+eksEndpointAccess(cluster))))
+		}
+	}
+	for _, service := range network.ECSServices {
+		if service.VpcID == vpcID {
+			result.WriteString(fmt.Sprintf("    %s[[\"%s\"]]:::container\n", mermaidID(service.Arn),
+				mermaidLabel(service.Name, fmt.Sprintf("ECS Service (%s)", service.ClusterName), fmt.Sprintf("Tasks: %d/%d", service.RunningCount, service.DesiredCount))))
+		}
+	}
+}
+
+// writeMermaidContainerLinks links EKS clusters and ECS services to the subnets they run in
+func (v *Visualizer) writeMermaidContainerLinks(result *strings.Builder, network *scanner.Network) {
+	for _, cluster := range network.EKSClusters {
+		for _, subnetID := range cluster.SubnetIDs {
+			result.WriteString(fmt.Sprintf("  %s -.->|in| %s\n", mermaidID(cluster.Arn), mermaidID(subnetID)))
+		}
+	}
+	for _, service := range network.ECSServices {
+		for _, subnetID := range service.SubnetIDs {
+			result.WriteString(fmt.Sprintf("  %s -.->|in| %s\n", mermaidID(service.Arn), mermaidID(subnetID)))
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// databaseServiceNames are the display names of the database services
+var databaseServiceNames = map[string]string{
+	"rds":         "RDS",
+	"elasticache": "ElastiCache",
+	"redshift":    "Redshift",
+}
+
+// databaseNodeID returns the graph node ID of a database
+func databaseNodeID(database scanner.Database) string {
+	return "db:" + database.Key()
+}
+
+// databaseTitle names a database with its service and engine
+func databaseTitle(database scanner.Database) string {
+	service := databaseServiceNames[database.Service]
+	if service == "" {
+		service = database.Service
+	}
+	return fmt.Sprintf("%s %s (%s)", service, database.ID, database.Engine)
+}
+
+// ruleAllowsPort reports whether a security group rule allows TCP traffic to a port.
+// A zero port matches every rule.
+func ruleAllowsPort(rule scanner.SecurityGroupRule, port int32) bool {
+	if rule.IpProtocol == "-1" || port == 0 {
+		return true
+	}
+	if rule.IpProtocol != "tcp" && rule.IpProtocol != "6" {
+		return false
+	}
+	return rule.FromPort <= port && port <= rule.ToPort
+}
+
+// databaseSources lists the security groups, CIDR blocks and prefix lists that the
+// database's security groups let in on its port
+func databaseSources(database scanner.Database, network *scanner.Network) []string {
+	groups := make(map[string]scanner.SecurityGroup)
+	for _, sg := range network.SecurityGroups {
+		groups[sg.ID] = sg
+	}
+
+	seen := make(map[string]bool)
+	var sources []string
+	add := func(source string) {
+		if source != "" && !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+
+	for _, sgID := range database.SecurityGroupIDs {
+		for _, rule := range groups[sgID].IngressRules {
+			if !ruleAllowsPort(rule, database.Port) {
+				continue
+			}
+			for _, groupID := range rule.ReferencedGroupIDs() {
+				name := groupID
+				if referenced, exists := groups[groupID]; exists && referenced.Name != "" {
+					name = referenced.Name
+				}
+				add(name)
+			}
+			for _, block := range rule.CidrBlocks {
+				add(block)
+			}
+			for _, block := range rule.Ipv6CidrBlocks {
+				add(block)
+			}
+			for _, prefixList := range rule.PrefixListIds {
+				add(prefixList)
+			}
+		}
+	}
+
+	sort.Strings(sources)
+	return sources
+}
+
+// databaseClients returns the node IDs of the EKS clusters and ECS services whose
+// security groups the database's security groups let in on its port
+func databaseClients(database scanner.Database, network *scanner.Network) []string {
+	allowed := make(map[string]bool)
+	for _, sg := range network.SecurityGroups {
+		if !containsString(database.SecurityGroupIDs, sg.ID) {
+			continue
+		}
+		for _, rule := range sg.IngressRules {
+			if !ruleAllowsPort(rule, database.Port) {
+				continue
+			}
+			for _, groupID := range rule.ReferencedGroupIDs() {
+				allowed[groupID] = true
+			}
+		}
+	}
+
+	var clients []string
+	for _, cluster := range network.EKSClusters {
+		for _, sgID := range append([]string{cluster.ClusterSecurityGroupID}, cluster.SecurityGroupIDs...) {
+			if allowed[sgID] {
+				clients = append(clients, cluster.Arn)
+				break
+			}
+		}
+	}
+	for _, service := range network.ECSServices {
+		for _, sgID := range service.SecurityGroupIDs {
+			if allowed[sgID] {
+				clients = append(clients, service.Arn)
+				break
+			}
+		}
+	}
+	return clients
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDatabase writes a database, the subnets it is placed in and what can reach it
+func (v *Visualizer) writeDatabase(result *strings.Builder, database scanner.Database, network *scanner.Network,
+	subnetMap map[string]scanner.Subnet, isLast bool) {
+	prefix := "├── "
+	if isLast {
+		prefix = "└── "
+	}
+
+	endpoint := ""
+	if database.Endpoint != "" {
+		endpoint = fmt.Sprintf(" %s:%d", database.Endpoint, database.Port)
+	}
+
+	public := ""
+	if database.PubliclyAccessible {
+		public = " [Public]"
+	}
+
+	sources := ""
+	if reachable := databaseSources(database, network); len(reachable) > 0 {
+		sources = " From: " + strings.Join(reachable, ", ")
+	}
+
+	result.WriteString(fmt.Sprintf("%s%s%s [%s]%s Subnets: %s%s\n", prefix, databaseTitle(database), endpoint, database.Status, public,
+		subnetNames(database.SubnetIDs, subnetMap), sources))
+}
+
+// writeDotDatabases writes databases as cylinders in their subnets, with edges from
+// the EKS clusters and ECS services allowed to reach them
+func (v *Visualizer) writeDotDatabases(result *strings.Builder, network *scanner.Network) {
+	if len(network.Databases) == 0 {
+		return
+	}
+
+	result.WriteString("\n  // Databases\n")
+	for _, database := range network.Databases {
+		id := databaseNodeID(database)
+		label := fmt.Sprintf("%s\\n%s %s", database.ID, databaseServiceNames[database.Service], database.Engine)
+		if database.Port != 0 {
+			label += fmt.Sprintf("\\nport %d", database.Port)
+		}
+		if sources := databaseSources(database, network); len(sources) > 0 {
+			label += "\\nfrom: " + strings.Join(sources, ", ")
+		}
+		result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", shape=cylinder, fillcolor=wheat%s];\n", id, label, v.dotIcon(database.Service)))
+		for _, subnetID := range database.SubnetIDs {
+			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [style=dotted, label=\"in\"];\n", id, subnetID))
+		}
+		for _, client := range databaseClients(database, network) {
+			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"%d\", color=saddlebrown];\n", client, id, database.Port))
+		}
+	}
+}
+
+// writeMermaidDatabases writes the databases of a VPC inside its subgraph
+func (v *Visualizer) writeMermaidDatabases(result *strings.Builder, network *scanner.Network, vpcID string) {
+	for _, database := range network.Databases {
+		if database.VpcID != vpcID {
+			continue
+		}
+		label := []string{database.ID, databaseServiceNames[database.Service] + " " + database.Engine}
+		if database.Port != 0 {
+			label = append(label, fmt.Sprintf("port %d", database.Port))
+		}
+		result.WriteString(fmt.Sprintf("    %s[(\"%s\")]:::database\n", mermaidID(databaseNodeID(database)), mermaidLabel(label...)))
+	}
+}
+
+// writeMermaidDatabaseLinks links databases to their subnets and the workloads allowed to reach them
+func (v *Visualizer) writeMermaidDatabaseLinks(result *strings.Builder, network *scanner.Network) {
+	for _, database := range network.Databases {
+		id := mermaidID(databaseNodeID(database))
+		for _, subnetID := range database.SubnetIDs {
+			result.WriteString(fmt.Sprintf("  %s -.->|in| %s\n", id, mermaidID(subnetID)))
+		}
+		for _, client := range databaseClients(database, network) {
+			result.WriteString(fmt.Sprintf("  %s -->|%d| %s\n", mermaidID(client), database.Port, id))
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// DetailLevel controls how much of the network the text graph shows
+type DetailLevel string
+
+const (
+	// DetailMinimal shows only VPCs and their subnets
+	DetailMinimal DetailLevel = "minimal"
+	// DetailNormal shows VPCs with their subnets, gateways, workloads and connections
+	DetailNormal DetailLevel = "normal"
+	// DetailFull adds route tables under each subnet, security group rules under
+	// each VPC and connectivity limit annotations
+	DetailFull DetailLevel = "full"
+)
+
+// ParseDetailLevel parses a detail level name
+func ParseDetailLevel(level string) (DetailLevel, error) {
+	switch DetailLevel(level) {
+	case DetailMinimal, DetailNormal, DetailFull:
+		return DetailLevel(level), nil
+	}
+	return "", fmt.Errorf("unsupported detail level: %s (expected minimal, normal or full)", level)
+}
+
+// writeRouteTable writes a subnet's route table and its routes beneath the subnet
+func (v *Visualizer) writeRouteTable(result *strings.Builder, routeTable scanner.RouteTable, isLastSubnet bool) {
+	indent := "│   "
+	if isLastSubnet {
+		indent = "    "
+	}
+
+	name := routeTable.Name
+	if name == "" {
+		name = routeTable.ID
+	}
+	main := ""
+	if routeTable.IsMain {
+		main = " [Main]"
+	}
+	result.WriteString(fmt.Sprintf("%s└── Route Table: %s%s\n", indent, name, main))
+
+	for i, route := range routeTable.Routes {
+		prefix := "├── "
+		if i == len(routeTable.Routes)-1 {
+			prefix = "└── "
+		}
+		state := ""
+		if route.State != "" && route.State != "active" {
+			state = " " + v.stateLabel(route.State)
+		}
+		result.WriteString(fmt.Sprintf("%s    %s%s → %s%s\n", indent, prefix, route.Destination(), route.Target(), state))
+	}
+}
+
+// writeSecurityGroup writes a security group and its rules
+func writeSecurityGroup(result *strings.Builder, sg scanner.SecurityGroup, isLast bool) {
+	prefix := "├── "
+	indent := "│   "
+	if isLast {
+		prefix = "└── "
+		indent = "    "
+	}
+
+	name := sg.ID
+	if sg.Name != "" {
+		name = fmt.Sprintf("%s (%s)", sg.Name, sg.ID)
+	}
+	result.WriteString(fmt.Sprintf("%sSecurity Group: %s\n", prefix, name))
+
+	var rules []string
+	for _, rule := range sg.IngressRules {
+		rules = append(rules, "Inbound: "+securityGroupRuleText(rule, "from"))
+	}
+	for _, rule := range sg.EgressRules {
+		rules = append(rules, "Outbound: "+securityGroupRuleText(rule, "to"))
+	}
+	for i, rule := range rules {
+		rulePrefix := "├── "
+		if i == len(rules)-1 {
+			rulePrefix = "└── "
+		}
+		result.WriteString(indent + rulePrefix + rule + "\n")
+	}
+}
+
+// securityGroupRuleText describes a security group rule, e.g. "tcp 443 from 0.0.0.0/0"
+func securityGroupRuleText(rule scanner.SecurityGroupRule, direction string) string {
+	ports := ""
+	switch {
+	case scanner.ProtocolNumber(rule.IpProtocol) == -1:
+		ports = "all traffic"
+	case rule.FromPort == rule.ToPort:
+		ports = fmt.Sprintf("%s %d", rule.IpProtocol, rule.FromPort)
+	default:
+		ports = fmt.Sprintf("%s %d-%d", rule.IpProtocol, rule.FromPort, rule.ToPort)
+	}
+
+	var peers []string
+	peers = append(peers, rule.CidrBlocks...)
+	peers = append(peers, rule.Ipv6CidrBlocks...)
+	peers = append(peers, rule.PrefixListIds...)
+	peers = append(peers, rule.ReferencedGroupIDs()...)
+
+	text := fmt.Sprintf("%s %s %s", ports, direction, strings.Join(peers, ", "))
+	if rule.Description != "" {
+		text += fmt.Sprintf(" (%s)", rule.Description)
+	}
+	return text
+}
@@ -0,0 +1,158 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// edgeServiceNames are the display names of the edge services
+var edgeServiceNames = map[string]string{
+	"globalaccelerator": "Global Accelerator",
+	"cloudfront":        "CloudFront",
+}
+
+// edgeIconKinds are the icons of the edge services
+var edgeIconKinds = map[string]string{
+	"globalaccelerator": "global-accelerator",
+	"cloudfront":        "cloudfront",
+}
+
+// edgeTargetTypes are the short names of the edge target types
+var edgeTargetTypes = map[string]string{
+	"application": "ALB",
+	"network":     "NLB",
+	"gateway":     "GWLB",
+	"eip":         "EIP",
+}
+
+// edgeIngressName names an accelerator or distribution, falling back to its first alias and ID
+func edgeIngressName(ingress scanner.EdgeIngress) string {
+	if ingress.Name != "" {
+		return ingress.Name
+	}
+	if len(ingress.Aliases) > 0 {
+		return ingress.Aliases[0]
+	}
+	return ingress.ID
+}
+
+// edgeTargetLabel names a load balancer or Elastic IP with its type
+func edgeTargetLabel(target scanner.EdgeTarget) string {
+	name := target.Name
+	if name == "" {
+		name = target.Address
+	}
+	return fmt.Sprintf("%s %s", edgeTargetTypes[target.Type], name)
+}
+
+// edgeTargetIconKind returns the icon of an edge target
+func edgeTargetIconKind(target scanner.EdgeTarget) string {
+	if target.Type == "eip" {
+		return "elastic-ip"
+	}
+	return "load-balancer"
+}
+
+// edgeTargetsInVPC returns the targets of an accelerator or distribution that are in a VPC
+func edgeTargetsInVPC(ingress scanner.EdgeIngress, vpcID string) []scanner.EdgeTarget {
+	var targets []scanner.EdgeTarget
+	for _, target := range ingress.Targets {
+		if target.VpcID == vpcID {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// edgeIngressesInVPC returns the accelerators and distributions with targets in a VPC
+func edgeIngressesInVPC(network *scanner.Network, vpcID string) []scanner.EdgeIngress {
+	var ingresses []scanner.EdgeIngress
+	for _, ingress := range network.EdgeIngresses {
+		if len(edgeTargetsInVPC(ingress, vpcID)) > 0 {
+			ingresses = append(ingresses, ingress)
+		}
+	}
+	return ingresses
+}
+
+// writeEdgeIngress writes an accelerator or distribution and its targets in a VPC
+func (v *Visualizer) writeEdgeIngress(result *strings.Builder, ingress scanner.EdgeIngress, vpcID string, isLast bool) {
+	prefix := "├── "
+	if isLast {
+		prefix = "└── "
+	}
+
+	disabled := ""
+	if !ingress.Enabled {
+		disabled = " [Disabled]"
+	}
+
+	var targets []string
+	for _, target := range edgeTargetsInVPC(ingress, vpcID) {
+		targets = append(targets, edgeTargetLabel(target))
+	}
+
+	result.WriteString(fmt.Sprintf("%s%s: %s (%s)%s → %s\n", prefix, edgeServiceNames[ingress.Service], edgeIngressName(ingress),
+		ingress.DNSName, disabled, strings.Join(targets, ", ")))
+}
+
+// writeDotEdgeIngress writes accelerators and distributions with edges to the load
+// balancers and Elastic IPs they send traffic to, and those to their subnets
+func (v *Visualizer) writeDotEdgeIngress(result *strings.Builder, network *scanner.Network) {
+	if len(network.EdgeIngresses) == 0 {
+		return
+	}
+
+	result.WriteString("\n  // Edge Ingress\n")
+	written := make(map[string]bool)
+	for _, ingress := range network.EdgeIngresses {
+		label := fmt.Sprintf("%s\\n%s\\n%s", edgeIngressName(ingress), edgeServiceNames[ingress.Service], ingress.DNSName)
+		result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", shape=hexagon, fillcolor=mediumpurple1%s];\n", ingress.ID, label, v.dotIcon(edgeIconKinds[ingress.Service])))
+		for _, target := range ingress.Targets {
+			if !written[target.ID] {
+				written[target.ID] = true
+				result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\\n%s\", shape=invtrapezium, fillcolor=lavender%s];\n", target.ID,
+					edgeTargetLabel(target), target.Address, v.dotIcon(edgeTargetIconKind(target))))
+				for _, subnetID := range target.SubnetIDs {
+					result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [style=dotted, label=\"in\"];\n", target.ID, subnetID))
+				}
+			}
+			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"routes\", color=purple];\n", ingress.ID, target.ID))
+		}
+	}
+}
+
+// writeMermaidEdgeTargets writes the load balancers and Elastic IPs of a VPC that
+// accelerators or distributions send traffic to inside its subgraph
+func (v *Visualizer) writeMermaidEdgeTargets(result *strings.Builder, network *scanner.Network, vpcID string) {
+	written := make(map[string]bool)
+	for _, ingress := range network.EdgeIngresses {
+		for _, target := range edgeTargetsInVPC(ingress, vpcID) {
+			if !written[target.ID] {
+				written[target.ID] = true
+				result.WriteString(fmt.Sprintf("    %s[/\"%s\"\\]:::edgetarget\n", mermaidID(target.ID), mermaidLabel(edgeTargetLabel(target), target.Address)))
+			}
+		}
+	}
+}
+
+// writeMermaidEdgeIngress writes accelerators and distributions and links them to
+// their targets, and the targets to their subnets
+func (v *Visualizer) writeMermaidEdgeIngress(result *strings.Builder, network *scanner.Network) {
+	linked := make(map[string]bool)
+	for _, ingress := range network.EdgeIngresses {
+		id := mermaidID(ingress.ID)
+		result.WriteString(fmt.Sprintf("  %s{{\"%s\"}}:::edge\n", id, mermaidLabel(edgeIngressName(ingress), edgeServiceNames[ingress.Service])))
+		for _, target := range ingress.Targets {
+			result.WriteString(fmt.Sprintf("  %s -->|routes| %s\n", id, mermaidID(target.ID)))
+			if !linked[target.ID] {
+				linked[target.ID] = true
+				for _, subnetID := range target.SubnetIDs {
+					result.WriteString(fmt.Sprintf("  %s -.->|in| %s\n", mermaidID(target.ID), mermaidID(subnetID)))
+				}
+			}
+		}
+	}
+}
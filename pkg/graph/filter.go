@@ -0,0 +1,177 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// hideableKinds are the kinds of resources --hide can leave out of a graph, with
+// the collections of the network that hold them
+var hideableKinds = map[string]func(network *scanner.Network){
+	"subnets":    func(n *scanner.Network) { n.Subnets = nil },
+	"igw":        func(n *scanner.Network) { n.InternetGateways = nil },
+	"eigw":       func(n *scanner.Network) { n.EgressOnlyInternetGateways = nil },
+	"nat":        func(n *scanner.Network) { n.NATGateways = nil },
+	"peering":    func(n *scanner.Network) { n.PeeringConnections = nil },
+	"tgw":        func(n *scanner.Network) { n.TransitGateways = nil },
+	"mesh":       func(n *scanner.Network) { n.MeshVirtualGateways = nil },
+	"containers": func(n *scanner.Network) { n.EKSClusters, n.ECSServices = nil, nil },
+	"databases":  func(n *scanner.Network) { n.Databases = nil },
+	"edge":       func(n *scanner.Network) { n.EdgeIngresses = nil },
+}
+
+// ParseHidden parses the comma separated kinds of resources to hide from a graph
+func ParseHidden(selections []string) ([]string, error) {
+	var kinds []string
+	for _, selection := range selections {
+		for _, kind := range strings.Split(selection, ",") {
+			kind = strings.TrimSpace(strings.ToLower(kind))
+			if kind == "" {
+				continue
+			}
+			if _, ok := hideableKinds[kind]; !ok {
+				return nil, fmt.Errorf("unknown resource kind %q in --hide (expected one of %s)", kind, strings.Join(hideableKindNames(), ", "))
+			}
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds, nil
+}
+
+// hideableKindNames returns the sorted names of the kinds --hide accepts
+func hideableKindNames() []string {
+	names := make([]string, 0, len(hideableKinds))
+	for name := range hideableKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetFocus limits the graph to a VPC and what is within depth hops of it. A
+// peering connection is one hop between VPCs and a transit gateway is a node of
+// its own, so VPCs sharing a transit gateway are two hops apart.
+func (v *Visualizer) SetFocus(vpcID string, depth int) {
+	v.focus = vpcID
+	v.depth = depth
+}
+
+// SetHidden leaves kinds of resources, as accepted by ParseHidden, out of the graph
+func (v *Visualizer) SetHidden(kinds []string) {
+	v.hidden = kinds
+}
+
+// filter returns the part of the network the focus and hidden kinds leave in the
+// graph, or the network itself when neither is set
+func (v *Visualizer) filter(network *scanner.Network) (*scanner.Network, error) {
+	if v.focus == "" && len(v.hidden) == 0 {
+		return network, nil
+	}
+
+	filtered := network
+	if v.focus != "" {
+		vpcs, tgws, err := focusedResources(network, v.focus, v.depth)
+		if err != nil {
+			return nil, err
+		}
+		filtered = network.ForVPCs(vpcs)
+
+		// Only connections between drawn VPCs, and the transit gateways in reach
+		// with their attachments to drawn VPCs, are drawn
+		in := make(map[string]bool, len(vpcs))
+		for _, id := range vpcs {
+			in[id] = true
+		}
+		filtered.PeeringConnections = nil
+		for _, peering := range network.PeeringConnections {
+			if in[peering.RequesterVpcID] && in[peering.AccepterVpcID] {
+				filtered.PeeringConnections = append(filtered.PeeringConnections, peering)
+			}
+		}
+		filtered.TransitGateways = nil
+		for _, tgw := range network.TransitGateways {
+			if !tgws[tgw.ID] {
+				continue
+			}
+			var attachments []scanner.TransitGatewayAttachment
+			for _, attachment := range tgw.Attachments {
+				if attachment.ResourceType != "vpc" || in[attachment.ResourceID] {
+					attachments = append(attachments, attachment)
+				}
+			}
+			tgw.Attachments = attachments
+			filtered.TransitGateways = append(filtered.TransitGateways, tgw)
+		}
+	} else {
+		copied := *network
+		filtered = &copied
+	}
+
+	for _, kind := range v.hidden {
+		hideableKinds[kind](filtered)
+	}
+	return filtered, nil
+}
+
+// focusedResources walks the peering connections and transit gateway attachments
+// from a VPC, returning the VPCs and transit gateways within depth hops of it
+func focusedResources(network *scanner.Network, vpcID string, depth int) ([]string, map[string]bool, error) {
+	found := false
+	for _, vpc := range network.VPCs {
+		if vpc.ID == vpcID {
+			found = true
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("VPC %s is not in the scanned network", vpcID)
+	}
+
+	neighbours := make(map[string][]string)
+	link := func(a, b string) {
+		neighbours[a] = append(neighbours[a], b)
+		neighbours[b] = append(neighbours[b], a)
+	}
+	for _, peering := range network.PeeringConnections {
+		link(peering.RequesterVpcID, peering.AccepterVpcID)
+	}
+	for _, tgw := range network.TransitGateways {
+		for _, attachment := range tgw.Attachments {
+			if attachment.ResourceType == "vpc" {
+				link(tgw.ID, attachment.ResourceID)
+			}
+		}
+	}
+
+	hops := map[string]int{vpcID: 0}
+	queue := []string{vpcID}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if hops[node] == depth {
+			continue
+		}
+		for _, next := range neighbours[node] {
+			if _, seen := hops[next]; !seen {
+				hops[next] = hops[node] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	tgws := make(map[string]bool)
+	for _, tgw := range network.TransitGateways {
+		if _, ok := hops[tgw.ID]; ok {
+			tgws[tgw.ID] = true
+		}
+	}
+	var vpcs []string
+	for _, vpc := range network.VPCs {
+		if _, ok := hops[vpc.ID]; ok {
+			vpcs = append(vpcs, vpc.ID)
+		}
+	}
+	return vpcs, tgws, nil
+}
@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// externalNodeID is the node representing private addresses outside the scanned subnets
+const externalNodeID = "external"
+
+// observedEdgeStyle distinguishes observed traffic from the derived traffic paths
+const observedEdgeStyle = "color=steelblue, fontcolor=steelblue"
+
+// Pen widths observed traffic edges are scaled between
+const (
+	minObservedPenWidth = 1.0
+	maxObservedPenWidth = 8.0
+)
+
+// ObservedFlow is traffic observed between two nodes, such as two subnets or a
+// subnet and the internet. Weight is relative to the heaviest flow, from 0 to 1.
+type ObservedFlow struct {
+	From   string
+	To     string
+	Label  string
+	Weight float64
+}
+
+// SetObservedFlows overlays observed traffic on the graph as weighted edges.
+// Flows from or to "internet" and "external" are drawn against nodes of those names.
+func (v *Visualizer) SetObservedFlows(flows []ObservedFlow) {
+	v.observedFlows = flows
+}
+
+// observedPenWidth scales a flow weight to a DOT pen width
+func observedPenWidth(weight float64) float64 {
+	if weight < 0 {
+		weight = 0
+	}
+	if weight > 1 {
+		weight = 1
+	}
+	return minObservedPenWidth + weight*(maxObservedPenWidth-minObservedPenWidth)
+}
+
+// observedEndpoints reports whether the observed flows reach the internet or external nodes
+func (v *Visualizer) observedEndpoints() (internet, external bool) {
+	for _, flow := range v.observedFlows {
+		internet = internet || flow.From == internetNodeID || flow.To == internetNodeID
+		external = external || flow.From == externalNodeID || flow.To == externalNodeID
+	}
+	return internet, external
+}
+
+// writeObservedFlows writes the observed traffic as DOT edges weighted by volume
+func (v *Visualizer) writeObservedFlows(result *strings.Builder) {
+	if len(v.observedFlows) == 0 {
+		return
+	}
+
+	result.WriteString("\n  // Observed Traffic\n")
+	internet, external := v.observedEndpoints()
+	if internet {
+		result.WriteString(fmt.Sprintf("  \"%s\" [label=\"Internet\", shape=ellipse, fillcolor=white];\n", internetNodeID))
+	}
+	if external {
+		result.WriteString(fmt.Sprintf("  \"%s\" [label=\"External (private)\", shape=ellipse, fillcolor=white];\n", externalNodeID))
+	}
+	for _, flow := range v.observedFlows {
+		result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"%s\", penwidth=%.1f, %s];\n",
+			flow.From, flow.To, flow.Label, observedPenWidth(flow.Weight), observedEdgeStyle))
+	}
+}
+
+// writeMermaidObservedFlows writes the observed traffic as labeled Mermaid links
+func (v *Visualizer) writeMermaidObservedFlows(result *strings.Builder) {
+	if len(v.observedFlows) == 0 {
+		return
+	}
+
+	internet, external := v.observedEndpoints()
+	if internet {
+		result.WriteString(fmt.Sprintf("  %s((Internet))\n", mermaidID(internetNodeID)))
+	}
+	if external {
+		result.WriteString(fmt.Sprintf("  %s((\"External (private)\"))\n", mermaidID(externalNodeID)))
+	}
+	for _, flow := range v.observedFlows {
+		result.WriteString(fmt.Sprintf("  %s -.->|\"%s\"| %s\n", mermaidID(flow.From), mermaidLabel("observed "+flow.Label), mermaidID(flow.To)))
+	}
+}
@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// mermaidScriptURL is the Mermaid build the HTML page loads to draw the diagram
+const mermaidScriptURL = "https://cdn.jsdelivr.net/npm/mermaid@11/dist/mermaid.esm.min.mjs"
+
+// htmlStyle is the stylesheet embedded in the HTML page
+const htmlStyle = `body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+.meta { color: #666; }
+table { border-collapse: collapse; margin: 1em 0; }
+td, th { border: 1px solid #ddd; padding: 4px 12px; text-align: left; }
+pre.tree { background: #f6f8fa; padding: 1em; overflow-x: auto; }
+.legend td:first-child { width: 2em; }`
+
+// generateHTMLPage generates a standalone HTML page with a summary, the Mermaid
+// diagram and the text tree. The diagram is drawn by Mermaid in the browser; the
+// text tree is readable without it.
+func (v *Visualizer) generateHTMLPage(network *scanner.Network) string {
+	var result strings.Builder
+
+	title := fmt.Sprintf("AWS Network Infrastructure - Region: %s", network.Region)
+
+	result.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	result.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(title)))
+	result.WriteString(fmt.Sprintf("<style>\n%s\n</style>\n</head>\n<body>\n", htmlStyle))
+
+	result.WriteString(fmt.Sprintf("<h1>%s</h1>\n", html.EscapeString(title)))
+	meta := fmt.Sprintf("Scan Time: %s", network.ScanTime.Format("2006-01-02 15:04:05"))
+	if network.AccountID != "" {
+		meta = fmt.Sprintf("Account: %s · %s", network.AccountID, meta)
+	}
+	result.WriteString(fmt.Sprintf("<p class=\"meta\">%s</p>\n", html.EscapeString(meta)))
+
+	// Summary
+	result.WriteString("<table>\n")
+	counts := []struct {
+		name  string
+		count int
+	}{
+		{"VPCs", len(network.VPCs)},
+		{"Subnets", len(network.Subnets)},
+		{"Peering Connections", len(network.PeeringConnections)},
+		{"Transit Gateways", len(network.TransitGateways)},
+		{"Internet Gateways", len(network.InternetGateways)},
+		{"NAT Gateways", len(network.NATGateways)},
+		{"Security Groups", len(network.SecurityGroups)},
+		{"Network ACLs", len(network.NetworkAcls)},
+	}
+	for _, c := range counts {
+		result.WriteString(fmt.Sprintf("<tr><th>%s</th><td>%d</td></tr>\n", c.name, c.count))
+	}
+	if len(network.EgressOnlyInternetGateways) > 0 {
+		result.WriteString(fmt.Sprintf("<tr><th>Egress-only Internet Gateways</th><td>%d</td></tr>\n", len(network.EgressOnlyInternetGateways)))
+	}
+	if len(network.MeshVirtualGateways) > 0 {
+		result.WriteString(fmt.Sprintf("<tr><th>Mesh Virtual Gateways</th><td>%d</td></tr>\n", len(network.MeshVirtualGateways)))
+	}
+	if len(network.EKSClusters) > 0 {
+		result.WriteString(fmt.Sprintf("<tr><th>EKS Clusters</th><td>%d</td></tr>\n", len(network.EKSClusters)))
+	}
+	if len(network.ECSServices) > 0 {
+		result.WriteString(fmt.Sprintf("<tr><th>ECS Services</th><td>%d</td></tr>\n", len(network.ECSServices)))
+	}
+	if len(network.Databases) > 0 {
+		result.WriteString(fmt.Sprintf("<tr><th>Databases</th><td>%d</td></tr>\n", len(network.Databases)))
+	}
+	if len(network.EdgeIngresses) > 0 {
+		result.WriteString(fmt.Sprintf("<tr><th>Edge Entry Points</th><td>%d</td></tr>\n", len(network.EdgeIngresses)))
+	}
+	result.WriteString("</table>\n")
+
+	// Diagram
+	result.WriteString("<pre class=\"mermaid\">\n")
+	result.WriteString(html.EscapeString(v.generateMermaidGraph(network)))
+	result.WriteString("</pre>\n")
+	if v.legend {
+		v.writeHTMLLegend(&result, network)
+	}
+
+	// Text tree
+	result.WriteString("<details>\n<summary>Text view</summary>\n<pre class=\"tree\">\n")
+	result.WriteString(html.EscapeString(v.generateTextGraph(network)))
+	result.WriteString("</pre>\n</details>\n")
+
+	if v.legend {
+		result.WriteString(fmt.Sprintf("<footer class=\"meta\">%s</footer>\n", html.EscapeString(v.diagramMetadata(network))))
+	}
+
+	result.WriteString("<script type=\"module\">\n")
+	result.WriteString(fmt.Sprintf("import mermaid from %q;\n", mermaidScriptURL))
+	result.WriteString("mermaid.initialize({ startOnLoad: true, securityLevel: \"strict\" });\n")
+	result.WriteString("</script>\n</body>\n</html>\n")
+
+	return result.String()
+}
@@ -0,0 +1,137 @@
+package graph
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// iconKind names the image of one kind of resource. An icon directory can hold
+// files named after the kind, such as vpc.png, or the official AWS Architecture
+// Icons package, whose files are found by the keywords in their names.
+type iconKind struct {
+	name     string
+	keywords []string
+	// excludes are keywords of other icons that also contain this icon's keywords
+	excludes []string
+}
+
+// iconKinds are the kinds of resources drawn with an icon
+var iconKinds = []iconKind{
+	{name: "vpc", keywords: []string{"virtual-private-cloud"}},
+	{name: "public-subnet", keywords: []string{"public-subnet"}},
+	{name: "private-subnet", keywords: []string{"private-subnet"}},
+	{name: "internet-gateway", keywords: []string{"internet-gateway"}, excludes: []string{"egress"}},
+	{name: "egress-only-internet-gateway", keywords: []string{"egress-only-internet-gateway"}},
+	{name: "nat-gateway", keywords: []string{"nat-gateway"}},
+	{name: "transit-gateway", keywords: []string{"transit-gateway"}, excludes: []string{"attachment"}},
+	{name: "app-mesh", keywords: []string{"app-mesh"}},
+	{name: "eks", keywords: []string{"elastic-kubernetes-service"}},
+	{name: "ecs", keywords: []string{"elastic-container-service"}},
+	{name: "rds", keywords: []string{"amazon-rds"}},
+	{name: "elasticache", keywords: []string{"elasticache"}},
+	{name: "redshift", keywords: []string{"redshift"}},
+	{name: "cloudfront", keywords: []string{"cloudfront"}},
+	{name: "global-accelerator", keywords: []string{"global-accelerator"}},
+	{name: "load-balancer", keywords: []string{"elastic-load-balancing"}},
+	{name: "elastic-ip", keywords: []string{"elastic-ip-address"}},
+}
+
+// IconSet maps kinds of resources to the image files DOT draws them with
+type IconSet map[string]string
+
+// LoadIcons finds an icon for each kind of resource in a directory and its
+// subdirectories. A file named after the kind (vpc.png or vpc.svg) wins over the
+// AWS Architecture Icons files, of which PNGs are preferred, then the 48 pixel size.
+// Kinds without an icon are drawn as plain nodes.
+func LoadIcons(dir string) (IconSet, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve icon directory %s: %w", dir, err)
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !entry.IsDir() && (ext == ".png" || ext == ".svg") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read icon directory %s: %w", dir, err)
+	}
+	sort.Strings(files)
+
+	icons := make(IconSet)
+	for _, kind := range iconKinds {
+		best, bestScore := "", -1
+		for _, file := range files {
+			if score := iconScore(kind, file); score > bestScore {
+				best, bestScore = file, score
+			}
+		}
+		if best != "" {
+			icons[kind.name] = best
+		}
+	}
+	if len(icons) == 0 {
+		return nil, fmt.Errorf("no icons found in %s", dir)
+	}
+	return icons, nil
+}
+
+// iconScore rates how well an image file fits a kind of resource, or -1 when it
+// does not fit
+func iconScore(kind iconKind, path string) int {
+	ext := strings.ToLower(filepath.Ext(path))
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	name = strings.ReplaceAll(name, " ", "-")
+
+	score := 0
+	if ext == ".png" {
+		score++
+	}
+	if name == kind.name {
+		return score + 100
+	}
+
+	matched := false
+	for _, keyword := range kind.keywords {
+		if strings.Contains(name, keyword) {
+			matched = true
+		}
+	}
+	for _, exclude := range kind.excludes {
+		if strings.Contains(name, exclude) {
+			matched = false
+		}
+	}
+	if !matched {
+		return -1
+	}
+	if strings.HasSuffix(name, "_48") {
+		score += 2
+	}
+	return score
+}
+
+// SetIcons draws the DOT graph's nodes with images of their kind of resource
+func (v *Visualizer) SetIcons(icons IconSet) {
+	v.icons = icons
+}
+
+// dotIcon returns the DOT attributes drawing a node with the icon of a kind of
+// resource above its label, or nothing when there is no icon for it
+func (v *Visualizer) dotIcon(kind string) string {
+	path, ok := v.icons[kind]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(", image=\"%s\", imagepos=tc, labelloc=b, height=1.4", strings.ReplaceAll(path, "\\", "\\\\"))
+}
@@ -0,0 +1,190 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Layout is a preset arrangement of the DOT graph
+type Layout string
+
+const (
+	// LayoutHierarchy ranks the graph top to bottom from gateways to VPCs to subnets
+	LayoutHierarchy Layout = "hierarchy"
+	// LayoutAZColumns clusters each VPC and stacks its subnets in one column per
+	// availability zone
+	LayoutAZColumns Layout = "az-columns"
+	// LayoutHubSpoke ranks the graph left to right with transit gateways as a hub
+	// column, the VPCs attached to them as spokes and subnets beyond them
+	LayoutHubSpoke Layout = "hub-spoke"
+)
+
+// ParseLayout parses a layout preset name
+func ParseLayout(layout string) (Layout, error) {
+	switch Layout(layout) {
+	case LayoutHierarchy, LayoutAZColumns, LayoutHubSpoke:
+		return Layout(layout), nil
+	}
+	return "", fmt.Errorf("unsupported layout: %s (expected hierarchy, az-columns or hub-spoke)", layout)
+}
+
+// SetLayout sets the arrangement of the DOT graph
+func (v *Visualizer) SetLayout(layout Layout) {
+	v.layout = layout
+}
+
+// dotGraphAttributes returns the graph attributes of the layout
+func (v *Visualizer) dotGraphAttributes() string {
+	switch v.layout {
+	case LayoutAZColumns:
+		return "  rankdir=TB;\n  newrank=true;\n"
+	case LayoutHubSpoke:
+		return "  rankdir=LR;\n  ranksep=1.5;\n"
+	}
+	return "  rankdir=TB;\n"
+}
+
+// dotEdgeConstraint keeps edges across the hub and spoke columns, such as peering
+// between spokes and gateways attached to them, from pulling VPCs out of their rank
+func (v *Visualizer) dotEdgeConstraint() string {
+	if v.layout == LayoutHubSpoke {
+		return ", constraint=false"
+	}
+	return ""
+}
+
+// writeDotLayout writes the clusters, rank groups and invisible edges of the layout
+func (v *Visualizer) writeDotLayout(result *strings.Builder, network *scanner.Network) {
+	switch v.layout {
+	case LayoutAZColumns:
+		v.writeDotAZColumns(result, network)
+	case LayoutHubSpoke:
+		v.writeDotHubSpoke(result, network)
+	}
+}
+
+// writeDotAZColumns clusters each VPC with one column per availability zone,
+// holding the zone's subnets and the NAT gateways in them
+func (v *Visualizer) writeDotAZColumns(result *strings.Builder, network *scanner.Network) {
+	natGateways := make(map[string][]string)
+	for _, nat := range network.NATGateways {
+		natGateways[nat.SubnetID] = append(natGateways[nat.SubnetID], nat.ID)
+	}
+
+	result.WriteString("\n  // Layout: availability zone columns\n")
+	for _, vpc := range network.VPCs {
+		zones := make(map[string][]string)
+		for _, subnet := range network.Subnets {
+			if subnet.VpcID == vpc.ID {
+				zones[subnet.AvailabilityZone] = append(zones[subnet.AvailabilityZone], subnet.ID)
+			}
+		}
+
+		name := vpc.Name
+		if name == "" {
+			name = vpc.ID
+		}
+		result.WriteString(fmt.Sprintf("  subgraph \"cluster_%s\" {\n", vpc.ID))
+		result.WriteString(fmt.Sprintf("    label=\"%s\";\n    style=rounded;\n", name))
+		result.WriteString(fmt.Sprintf("    \"%s\";\n", vpc.ID))
+
+		azs := make([]string, 0, len(zones))
+		for az := range zones {
+			azs = append(azs, az)
+		}
+		sort.Strings(azs)
+
+		var tops []string
+		for _, az := range azs {
+			label := az
+			if label == "" {
+				label = "unknown AZ"
+			}
+			result.WriteString(fmt.Sprintf("    subgraph \"cluster_%s_%s\" {\n", vpc.ID, az))
+			result.WriteString(fmt.Sprintf("      label=\"%s\";\n      style=dashed;\n", label))
+
+			// NAT gateways already sit above their subnet, and each subnet sits
+			// below the previous one
+			column := zones[az]
+			for _, id := range column {
+				result.WriteString(fmt.Sprintf("      \"%s\";\n", id))
+				for _, natID := range natGateways[id] {
+					result.WriteString(fmt.Sprintf("      \"%s\";\n", natID))
+				}
+			}
+			for i := 1; i < len(column); i++ {
+				result.WriteString(fmt.Sprintf("      \"%s\" -> \"%s\" [style=invis];\n", column[i-1], column[i]))
+			}
+			result.WriteString("    }\n")
+			tops = append(tops, zones[az][0])
+		}
+		result.WriteString("  }\n")
+
+		if len(tops) > 1 {
+			result.WriteString(fmt.Sprintf("  { rank=same; %s }\n", dotNodeList(tops)))
+		}
+	}
+}
+
+// writeDotHubSpoke places transit gateways in one column and every VPC in the
+// next, with internet gateways beside their VPC's subnets
+func (v *Visualizer) writeDotHubSpoke(result *strings.Builder, network *scanner.Network) {
+	result.WriteString("\n  // Layout: hub and spoke\n")
+
+	var hubs []string
+	for _, tgw := range network.TransitGateways {
+		hubs = append(hubs, tgw.ID)
+	}
+	if len(hubs) > 0 {
+		result.WriteString(fmt.Sprintf("  { rank=same; %s }\n", dotNodeList(hubs)))
+	}
+
+	var spokes []string
+	for _, vpc := range network.VPCs {
+		spokes = append(spokes, vpc.ID)
+	}
+	if len(spokes) > 0 {
+		result.WriteString(fmt.Sprintf("  { rank=same; %s }\n", dotNodeList(spokes)))
+	}
+
+	// VPCs without a transit gateway attachment are tied to the hub column so
+	// they line up with the other spokes
+	attached := make(map[string]bool)
+	for _, tgw := range network.TransitGateways {
+		for _, attachment := range tgw.Attachments {
+			if attachment.ResourceType == "vpc" {
+				attached[attachment.ResourceID] = true
+			}
+		}
+	}
+	if len(hubs) > 0 {
+		for _, vpc := range network.VPCs {
+			if !attached[vpc.ID] {
+				result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [style=invis];\n", hubs[0], vpc.ID))
+			}
+		}
+	}
+
+	for _, igw := range network.InternetGateways {
+		if igw.VpcID != "" {
+			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [style=invis];\n", igw.VpcID, igw.ID))
+		}
+	}
+	for _, eigw := range network.EgressOnlyInternetGateways {
+		if eigw.VpcID != "" {
+			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [style=invis];\n", eigw.VpcID, eigw.ID))
+		}
+	}
+}
+
+// dotNodeList quotes node IDs for a DOT statement
+func dotNodeList(ids []string) string {
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = fmt.Sprintf("\"%s\";", id)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,148 @@
+package graph
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// legendEntry describes how one kind of resource is drawn
+type legendEntry struct {
+	label string
+	// dot is the entry's DOT node attributes, and color its fill in the HTML page
+	dot   string
+	color string
+	shown func(network *scanner.Network) bool
+	// tagColored entries are colored by tag value instead with --color-by
+	tagColored bool
+}
+
+// legendEdge describes how one kind of edge is drawn
+type legendEdge struct {
+	label string
+	style string
+}
+
+// legendEntries lists the kinds of resources a diagram can draw. Each appears in
+// the legend only when the network has one.
+var legendEntries = []legendEntry{
+	{"VPC", "fillcolor=lightcyan", "#e0ffff", func(n *scanner.Network) bool { return len(n.VPCs) > 0 }, true},
+	{"Public subnet", "fillcolor=lightgreen", "#90ee90", func(n *scanner.Network) bool { return hasSubnetType(n, "public") }, true},
+	{"Private subnet", "fillcolor=lightyellow", "#ffffe0", func(n *scanner.Network) bool { return hasSubnetType(n, "private") }, true},
+	{"Isolated subnet", "fillcolor=lightcoral", "#f08080", func(n *scanner.Network) bool { return hasSubnetType(n, "isolated") }, true},
+	{"Internet Gateway", "fillcolor=orange", "#ffa500", func(n *scanner.Network) bool { return len(n.InternetGateways) > 0 }, true},
+	{"Egress-only Internet Gateway", "fillcolor=sandybrown", "#f4a460", func(n *scanner.Network) bool { return len(n.EgressOnlyInternetGateways) > 0 }, true},
+	{"NAT Gateway", "fillcolor=gold", "#ffd700", func(n *scanner.Network) bool { return len(n.NATGateways) > 0 }, true},
+	{"Transit Gateway", "fillcolor=purple, fontcolor=white", "#800080", func(n *scanner.Network) bool { return len(n.TransitGateways) > 0 }, false},
+	{"Mesh Virtual Gateway", "shape=hexagon, fillcolor=plum", "#dda0dd", func(n *scanner.Network) bool { return len(n.MeshVirtualGateways) > 0 }, false},
+	{"EKS Cluster / ECS Service", "shape=component, fillcolor=lightskyblue", "#87cefa", func(n *scanner.Network) bool { return len(n.EKSClusters)+len(n.ECSServices) > 0 }, false},
+	{"Database", "shape=cylinder, fillcolor=wheat", "#f5deb3", func(n *scanner.Network) bool { return len(n.Databases) > 0 }, false},
+	{"Accelerator / Distribution", "shape=hexagon, fillcolor=mediumpurple1", "#ab82ff", func(n *scanner.Network) bool { return len(n.EdgeIngresses) > 0 }, false},
+	{"Load Balancer / Elastic IP", "shape=invtrapezium, fillcolor=lavender", "#e6e6fa", func(n *scanner.Network) bool { return len(n.EdgeIngresses) > 0 }, false},
+}
+
+// hasSubnetType reports whether the network has a subnet of the type
+func hasSubnetType(network *scanner.Network, subnetType string) bool {
+	for _, subnet := range network.Subnets {
+		if subnet.Type == subnetType {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLegend adds a legend and a metadata footer to DOT and HTML output
+func (v *Visualizer) SetLegend(legend bool) {
+	v.legend = legend
+}
+
+// SetToolVersion sets the pikaatools version named in the metadata footer
+func (v *Visualizer) SetToolVersion(version string) {
+	v.toolVersion = version
+}
+
+// diagramMetadata returns the account, region, scan time and tool version a
+// diagram was made from
+func (v *Visualizer) diagramMetadata(network *scanner.Network) string {
+	var parts []string
+	if network.AccountID != "" {
+		parts = append(parts, "Account: "+network.AccountID)
+	}
+	parts = append(parts, "Region: "+network.Region)
+	parts = append(parts, "Scan Time: "+network.ScanTime.Format("2006-01-02 15:04:05 MST"))
+	version := v.toolVersion
+	if version == "" {
+		version = "dev"
+	}
+	parts = append(parts, "pikaatools "+version)
+	return strings.Join(parts, " · ")
+}
+
+// writeDotLegend writes the legend cluster with a sample node of each kind of
+// resource in the network and the traffic edge styles drawn
+func (v *Visualizer) writeDotLegend(result *strings.Builder, network *scanner.Network) {
+	var nodes []string
+	result.WriteString("\n  // Legend\n")
+	result.WriteString("  subgraph \"cluster_legend\" {\n")
+	result.WriteString("    label=\"Legend\";\n    style=dashed;\n    fontsize=12;\n")
+	for i, entry := range legendEntries {
+		if !entry.shown(network) || (entry.tagColored && v.colorByTag != "") {
+			continue
+		}
+		id := fmt.Sprintf("legend_%d", i)
+		nodes = append(nodes, id)
+		result.WriteString(fmt.Sprintf("    \"%s\" [label=\"%s\", %s];\n", id, entry.label, entry.dot))
+	}
+	for i, entry := range v.tagLegend() {
+		id := fmt.Sprintf("legend_tag_%d", i)
+		nodes = append(nodes, id)
+		result.WriteString(fmt.Sprintf("    \"%s\" [label=\"%s\", fillcolor=%s];\n", id, entry.label, entry.color.dot))
+	}
+
+	var edges []legendEdge
+	if len(network.PeeringConnections) > 0 {
+		edges = append(edges, legendEdge{"peering", "color=blue"})
+	}
+	if len(trafficPaths(network)) > 0 {
+		edges = append(edges, legendEdge{"egress", egressEdgeStyle}, legendEdge{"ingress", ingressEdgeStyle})
+	}
+	if len(v.observedFlows) > 0 {
+		edges = append(edges, legendEdge{"observed traffic", observedEdgeStyle})
+	}
+	for i, edge := range edges {
+		from, to := fmt.Sprintf("legend_edge_%d_from", i), fmt.Sprintf("legend_edge_%d_to", i)
+		result.WriteString(fmt.Sprintf("    \"%s\" [label=\"\", shape=point];\n    \"%s\" [label=\"\", shape=point];\n", from, to))
+		result.WriteString(fmt.Sprintf("    \"%s\" -> \"%s\" [label=\"%s\", %s];\n", from, to, edge.label, edge.style))
+		nodes = append(nodes, from)
+	}
+
+	// Stack the samples in one column
+	for i := 1; i < len(nodes); i++ {
+		result.WriteString(fmt.Sprintf("    \"%s\" -> \"%s\" [style=invis];\n", nodes[i-1], nodes[i]))
+	}
+	result.WriteString("  }\n")
+}
+
+// writeDotFooter labels the graph with its metadata beneath the diagram. It is
+// written last so clusters do not inherit the label.
+func (v *Visualizer) writeDotFooter(result *strings.Builder, network *scanner.Network) {
+	result.WriteString("\n  // Metadata\n")
+	result.WriteString(fmt.Sprintf("  label=\"%s\";\n  labelloc=b;\n  labeljust=r;\n  fontsize=10;\n", v.diagramMetadata(network)))
+}
+
+// writeHTMLLegend writes a table of the colors used for the resources in the network
+func (v *Visualizer) writeHTMLLegend(result *strings.Builder, network *scanner.Network) {
+	result.WriteString("<table class=\"legend\">\n<tr><th colspan=\"2\">Legend</th></tr>\n")
+	for _, entry := range legendEntries {
+		if !entry.shown(network) || (entry.tagColored && v.colorByTag != "") {
+			continue
+		}
+		result.WriteString(fmt.Sprintf("<tr><td style=\"background: %s\"></td><td>%s</td></tr>\n", entry.color, html.EscapeString(entry.label)))
+	}
+	for _, entry := range v.tagLegend() {
+		result.WriteString(fmt.Sprintf("<tr><td style=\"background: %s\"></td><td>%s</td></tr>\n", entry.color.hex, html.EscapeString(entry.label)))
+	}
+	result.WriteString("</table>\n")
+}
@@ -0,0 +1,232 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// mermaidClassDefs style the Mermaid nodes like the DOT fill colors
+var mermaidClassDefs = []string{
+	"classDef public fill:#90ee90,stroke:#333",
+	"classDef private fill:#ffffe0,stroke:#333",
+	"classDef isolated fill:#f08080,stroke:#333",
+	"classDef igw fill:#ffa500,stroke:#333",
+	"classDef eigw fill:#f4a460,stroke:#333",
+	"classDef nat fill:#ffd700,stroke:#333",
+	"classDef tgw fill:#800080,stroke:#333,color:#fff",
+	"classDef mesh fill:#dda0dd,stroke:#333",
+	"classDef container fill:#87cefa,stroke:#333",
+	"classDef database fill:#f5deb3,stroke:#333",
+	"classDef edge fill:#ab82ff,stroke:#333",
+	"classDef edgetarget fill:#e6e6fa,stroke:#333",
+}
+
+// mermaidID converts a resource ID or ARN into a Mermaid node ID
+func mermaidID(id string) string {
+	var result strings.Builder
+	for _, r := range id {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			result.WriteRune(r)
+		} else {
+			result.WriteRune('_')
+		}
+	}
+	return result.String()
+}
+
+// mermaidLabel joins label lines for a quoted Mermaid label
+func mermaidLabel(lines ...string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = strings.ReplaceAll(line, "\"", "#quot;")
+	}
+	return strings.Join(escaped, "<br/>")
+}
+
+// generateMermaidGraph generates a Mermaid flowchart with one subgraph per VPC
+func (v *Visualizer) generateMermaidGraph(network *scanner.Network) string {
+	var result strings.Builder
+
+	result.WriteString("flowchart TB\n")
+
+	// Sort VPCs by name and then ID for consistent output
+	vpcs := make([]scanner.VPC, len(network.VPCs))
+	copy(vpcs, network.VPCs)
+	sort.Slice(vpcs, func(i, j int) bool {
+		if vpcs[i].Name != vpcs[j].Name {
+			return vpcs[i].Name < vpcs[j].Name
+		}
+		return vpcs[i].ID < vpcs[j].ID
+	})
+
+	// VPCs contain their subnets and NAT gateways
+	for _, vpc := range vpcs {
+		vpcName := vpc.Name
+		if vpcName == "" {
+			vpcName = vpc.ID
+		}
+		label := append([]string{vpcName}, cidrBlocks(vpc.CidrBlock, vpc.Ipv6CidrBlocks)...)
+		if vpc.IsDefault {
+			label = append(label, "[Default]")
+		}
+
+		result.WriteString(fmt.Sprintf("  subgraph %s[\"%s\"]\n", mermaidID(vpc.ID), mermaidLabel(label...)))
+		for _, subnet := range network.Subnets {
+			if subnet.VpcID != vpc.ID {
+				continue
+			}
+			subnetName := subnet.Name
+			if subnetName == "" {
+				subnetName = subnet.ID
+			}
+			result.WriteString(fmt.Sprintf("    %s[\"%s\"]", mermaidID(subnet.ID),
+				mermaidLabel(append(append([]string{subnetName}, cidrBlocks(subnet.CidrBlock, subnet.Ipv6CidrBlocks)...), strings.Title(subnet.Type))...)))
+			if class := v.mermaidClass(subnet.Tags, subnet.Type); class != "" {
+				result.WriteString(":::" + class)
+			}
+			result.WriteString("\n")
+		}
+		for _, nat := range network.NATGateways {
+			if nat.VpcID != vpc.ID {
+				continue
+			}
+			natName := nat.Name
+			if natName == "" {
+				natName = nat.ID
+			}
+			label := []string{natName, "NAT Gateway"}
+			if nat.PublicIP != "" {
+				label = append(label, nat.PublicIP)
+			}
+			result.WriteString(fmt.Sprintf("    %s[\"%s\"]:::%s\n", mermaidID(nat.ID), mermaidLabel(label...), v.mermaidClass(nat.Tags, "nat")))
+		}
+		v.writeMermaidContainers(&result, network, vpc.ID)
+		v.writeMermaidDatabases(&result, network, vpc.ID)
+		v.writeMermaidEdgeTargets(&result, network, vpc.ID)
+		result.WriteString("  end\n")
+	}
+
+	// NAT gateways sit in a subnet
+	for _, nat := range network.NATGateways {
+		if nat.SubnetID != "" {
+			result.WriteString(fmt.Sprintf("  %s -.->|in| %s\n", mermaidID(nat.ID), mermaidID(nat.SubnetID)))
+		}
+	}
+
+	// Containers run in subnets
+	v.writeMermaidContainerLinks(&result, network)
+
+	// Databases are placed in subnets and reached by workloads
+	v.writeMermaidDatabaseLinks(&result, network)
+
+	// Global Accelerator and CloudFront send traffic to load balancers and Elastic IPs
+	v.writeMermaidEdgeIngress(&result, network)
+
+	// Internet gateways attach to a VPC
+	for _, igw := range network.InternetGateways {
+		igwName := igw.Name
+		if igwName == "" {
+			igwName = igw.ID
+		}
+		result.WriteString(fmt.Sprintf("  %s([\"%s\"]):::%s\n", mermaidID(igw.ID), mermaidLabel(igwName, "Internet Gateway"), v.mermaidClass(igw.Tags, "igw")))
+		result.WriteString(fmt.Sprintf("  %s ---|attached| %s\n", mermaidID(igw.ID), mermaidID(igw.VpcID)))
+	}
+
+	// Egress-only internet gateways attach to a VPC
+	for _, eigw := range network.EgressOnlyInternetGateways {
+		eigwName := eigw.Name
+		if eigwName == "" {
+			eigwName = eigw.ID
+		}
+		result.WriteString(fmt.Sprintf("  %s([\"%s\"]):::%s\n", mermaidID(eigw.ID), mermaidLabel(eigwName, "Egress-only Internet Gateway"), v.mermaidClass(eigw.Tags, "eigw")))
+		result.WriteString(fmt.Sprintf("  %s ---|attached| %s\n", mermaidID(eigw.ID), mermaidID(eigw.VpcID)))
+	}
+
+	// Peering connections link two VPCs
+	for _, peering := range network.PeeringConnections {
+		peeringName := peering.Name
+		if peeringName == "" {
+			peeringName = peering.ID
+		}
+		label := fmt.Sprintf("%s [%s]", peeringName, peering.Status)
+		if v.detailed && peering.RequesterRegion != "" && peering.AccepterRegion != "" && peering.RequesterRegion != peering.AccepterRegion {
+			label += fmt.Sprintf(" %s ↔ %s", peering.RequesterRegion, peering.AccepterRegion)
+		}
+
+		link := "<-->"
+		if peering.Status != "active" {
+			link = "<-.->"
+		}
+		result.WriteString(fmt.Sprintf("  %s %s|\"%s\"| %s\n", mermaidID(peering.RequesterVpcID), link, mermaidLabel(label), mermaidID(peering.AccepterVpcID)))
+	}
+
+	// Transit gateways attach to VPCs, and in detail output to their peers
+	for _, tgw := range network.TransitGateways {
+		tgwName := tgw.Name
+		if tgwName == "" {
+			tgwName = tgw.ID
+		}
+		result.WriteString(fmt.Sprintf("  %s{{\"%s\"}}:::tgw\n", mermaidID(tgw.ID), mermaidLabel(tgwName, "Transit Gateway")))
+
+		for _, attachment := range tgw.Attachments {
+			if attachment.ResourceType == "vpc" {
+				link := "---"
+				if attachment.State != "available" {
+					link = "-.-"
+				}
+				result.WriteString(fmt.Sprintf("  %s %s|attached| %s\n", mermaidID(tgw.ID), link, mermaidID(attachment.ResourceID)))
+			}
+
+			if attachment.ResourceType == "peering" && v.detailed && attachment.PeerTransitGatewayID != "" {
+				label := "peering"
+				if attachment.PeerRegion != "" && attachment.PeerRegion != network.Region {
+					label += fmt.Sprintf(" %s ↔ %s", network.Region, attachment.PeerRegion)
+				}
+				result.WriteString(fmt.Sprintf("  %s -.-|\"%s\"| %s\n", mermaidID(tgw.ID), mermaidLabel(label), mermaidID(attachment.PeerTransitGatewayID)))
+			}
+		}
+	}
+
+	// Service mesh gateways are not tied to a VPC
+	for _, gateway := range network.MeshVirtualGateways {
+		label := []string{gateway.Name, fmt.Sprintf("Virtual Gateway (%s)", gateway.MeshName)}
+		if len(gateway.Listeners) > 0 {
+			label = append(label, meshListenerSummary(gateway.Listeners))
+		}
+		result.WriteString(fmt.Sprintf("  %s{{\"%s\"}}:::mesh\n", mermaidID(gateway.Arn), mermaidLabel(label...)))
+	}
+
+	// Traffic paths, with ingress drawn as thick links
+	edges := trafficPaths(network)
+	if len(edges) > 0 {
+		result.WriteString(fmt.Sprintf("  %s((Internet))\n", mermaidID(internetNodeID)))
+		for _, edge := range edges {
+			link := "-->"
+			if edge.style == ingressEdgeStyle {
+				link = "==>"
+			}
+			result.WriteString(fmt.Sprintf("  %s %s|%s| %s\n", mermaidID(edge.from), link, edge.label, mermaidID(edge.to)))
+		}
+	}
+
+	// Observed traffic, when flow logs are overlaid
+	v.writeMermaidObservedFlows(&result)
+
+	for _, classDef := range mermaidClassDefs {
+		result.WriteString("  " + classDef + "\n")
+	}
+	for _, classDef := range v.mermaidTagClassDefs() {
+		result.WriteString("  " + classDef + "\n")
+	}
+	// VPC subgraphs take their tag value's color as a style
+	for _, vpc := range vpcs {
+		if c, ok := v.tagColorOf(vpc.Tags); ok {
+			result.WriteString(fmt.Sprintf("  style %s fill:%s\n", mermaidID(vpc.ID), c.hex))
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Latency classes for traffic between two regions. Round-trip times are typical
+// values between region pairs and vary with the exact pair and path.
+const (
+	latencyLow    = "low (typically under 20 ms round trip)"
+	latencyMedium = "medium (typically 20-80 ms round trip)"
+	latencyHigh   = "high (typically over 80 ms round trip)"
+)
+
+// Inter-region data transfer pricing tiers, billed per GB out of the source region
+const (
+	transferStandardTier = "standard tier, about $0.02/GB"
+	transferPremiumTier  = "premium tier, about $0.08-0.15/GB"
+	transferChinaTier    = "priced separately for China regions"
+)
+
+// regionGeography returns the broad geography a region belongs to
+func regionGeography(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-"), strings.HasPrefix(region, "ca-"), strings.HasPrefix(region, "mx-"):
+		return "north-america"
+	case strings.HasPrefix(region, "sa-"):
+		return "south-america"
+	case strings.HasPrefix(region, "eu-"), strings.HasPrefix(region, "il-"):
+		return "europe"
+	case strings.HasPrefix(region, "me-"):
+		return "middle-east"
+	case strings.HasPrefix(region, "af-"):
+		return "africa"
+	case strings.HasPrefix(region, "cn-"):
+		return "china"
+	case strings.HasPrefix(region, "ap-"):
+		return "asia-pacific"
+	}
+	return ""
+}
+
+// regionArea returns the region name without its number, e.g. "us-east" for "us-east-1"
+func regionArea(region string) string {
+	if i := strings.LastIndex(region, "-"); i > 0 {
+		return region[:i]
+	}
+	return region
+}
+
+// regionLatencyClass returns the typical latency class between two regions
+func regionLatencyClass(a, b string) string {
+	switch {
+	case regionArea(a) == regionArea(b):
+		return latencyLow
+	case regionGeography(a) != "" && regionGeography(a) == regionGeography(b):
+		return latencyMedium
+	default:
+		return latencyHigh
+	}
+}
+
+// regionTransferTier returns the data transfer pricing tier for traffic leaving a region
+func regionTransferTier(region string) string {
+	switch regionGeography(region) {
+	case "north-america", "europe":
+		return transferStandardTier
+	case "china":
+		return transferChinaTier
+	default:
+		return transferPremiumTier
+	}
+}
+
+// regionPairAnnotations returns the region pair, latency class and transfer pricing
+// of a cross-region link. Links within one region, or with an unknown side, have none.
+func regionPairAnnotations(local, peer string) []string {
+	if local == "" || peer == "" || local == peer {
+		return nil
+	}
+
+	notes := []string{
+		fmt.Sprintf("region pair: %s ↔ %s", local, peer),
+		"latency: " + regionLatencyClass(local, peer),
+	}
+
+	localTier, peerTier := regionTransferTier(local), regionTransferTier(peer)
+	if localTier == peerTier {
+		notes = append(notes, "inter-region transfer: "+localTier+" in each direction")
+	} else {
+		notes = append(notes,
+			fmt.Sprintf("inter-region transfer out of %s: %s", local, localTier),
+			fmt.Sprintf("inter-region transfer out of %s: %s", peer, peerTier))
+	}
+
+	return notes
+}
@@ -0,0 +1,48 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegionLatencyClass(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected string
+	}{
+		{"us-east-1", "us-east-2", latencyLow},
+		{"us-east-1", "us-west-2", latencyMedium},
+		{"eu-west-1", "eu-central-1", latencyMedium},
+		{"us-east-1", "eu-west-1", latencyHigh},
+		{"ap-southeast-1", "sa-east-1", latencyHigh},
+	}
+
+	for _, test := range tests {
+		if got := regionLatencyClass(test.a, test.b); got != test.expected {
+			t.Errorf("regionLatencyClass(%s, %s) = %q, expected %q", test.a, test.b, got, test.expected)
+		}
+	}
+}
+
+func TestRegionPairAnnotations(t *testing.T) {
+	if notes := regionPairAnnotations("us-east-1", "us-east-1"); notes != nil {
+		t.Errorf("Expected no annotations within one region, got %v", notes)
+	}
+	if notes := regionPairAnnotations("us-east-1", ""); notes != nil {
+		t.Errorf("Expected no annotations with an unknown peer region, got %v", notes)
+	}
+
+	notes := strings.Join(regionPairAnnotations("us-east-1", "eu-west-1"), "\n")
+	for _, want := range []string{"region pair: us-east-1 ↔ eu-west-1", latencyHigh, transferStandardTier + " in each direction"} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("Expected annotations to contain %q, got:\n%s", want, notes)
+		}
+	}
+
+	notes = strings.Join(regionPairAnnotations("us-east-1", "ap-northeast-1"), "\n")
+	for _, want := range []string{"out of us-east-1: " + transferStandardTier, "out of ap-northeast-1: " + transferPremiumTier} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("Expected annotations to contain %q, got:\n%s", want, notes)
+		}
+	}
+}
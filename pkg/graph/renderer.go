@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Renderer draws a network in one output format. Generate calls it with the
+// network already sorted and limited by the visualizer's focus and hidden kinds.
+type Renderer interface {
+	Render(v *Visualizer, network *scanner.Network) (string, error)
+}
+
+// RendererFunc adapts a function to the Renderer interface
+type RendererFunc func(v *Visualizer, network *scanner.Network) (string, error)
+
+// Render calls the function
+func (f RendererFunc) Render(v *Visualizer, network *scanner.Network) (string, error) {
+	return f(v, network)
+}
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = make(map[string]Renderer)
+)
+
+func init() {
+	RegisterRenderer("text", RendererFunc(func(v *Visualizer, network *scanner.Network) (string, error) {
+		return v.generateTextGraph(network), nil
+	}))
+	RegisterRenderer("dot", RendererFunc(func(v *Visualizer, network *scanner.Network) (string, error) {
+		return v.generateDotGraph(network), nil
+	}))
+	RegisterRenderer("mermaid", RendererFunc(func(v *Visualizer, network *scanner.Network) (string, error) {
+		return v.generateMermaidGraph(network), nil
+	}))
+	RegisterRenderer("html", RendererFunc(func(v *Visualizer, network *scanner.Network) (string, error) {
+		return v.generateHTMLPage(network), nil
+	}))
+	RegisterRenderer("table", RendererFunc(func(v *Visualizer, network *scanner.Network) (string, error) {
+		return v.generateTable(network), nil
+	}))
+}
+
+// RegisterRenderer makes an output format available to NewVisualizer. It is meant
+// to be called from an init function, and panics when the format is already
+// registered or the renderer is nil.
+func RegisterRenderer(format string, renderer Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	if renderer == nil {
+		panic(fmt.Sprintf("graph: renderer for format %q is nil", format))
+	}
+	if _, ok := renderers[format]; ok {
+		panic(fmt.Sprintf("graph: renderer for format %q is already registered", format))
+	}
+	renderers[format] = renderer
+}
+
+// Formats returns the sorted names of the registered output formats
+func Formats() []string {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	formats := make([]string, 0, len(renderers))
+	for format := range renderers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// lookupRenderer returns the renderer registered for an output format
+func lookupRenderer(format string) (Renderer, bool) {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	renderer, ok := renderers[format]
+	return renderer, ok
+}
+
+// Detail returns the detail level renderers draw the graph at
+func (v *Visualizer) Detail() DetailLevel {
+	return v.detail
+}
+
+// Color reports whether renderers may color their output for the terminal
+func (v *Visualizer) Color() bool {
+	return v.color
+}
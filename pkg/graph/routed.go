@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// maxRoutedCIDRs is how many routed CIDRs an edge label lists before summarising the rest
+const maxRoutedCIDRs = 4
+
+// vpcRoutedCIDRs returns the destinations a VPC's route tables send to a peering
+// connection or transit gateway. Blackhole routes carry no traffic and are left out.
+func vpcRoutedCIDRs(network *scanner.Network, vpcID, target string) []string {
+	seen := make(map[string]bool)
+	var cidrs []string
+	for _, routeTable := range network.RouteTables {
+		if routeTable.VpcID != vpcID {
+			continue
+		}
+		for _, route := range routeTable.Routes {
+			if route.Target() != target || route.State == "blackhole" || seen[route.Destination()] {
+				continue
+			}
+			seen[route.Destination()] = true
+			cidrs = append(cidrs, route.Destination())
+		}
+	}
+	sort.Strings(cidrs)
+	return cidrs
+}
+
+// transitGatewayRoutedCIDRs returns the destinations a transit gateway's route
+// tables send to an attachment
+func transitGatewayRoutedCIDRs(tgw scanner.TransitGateway, attachmentID string) []string {
+	seen := make(map[string]bool)
+	var cidrs []string
+	for _, routeTable := range tgw.RouteTables {
+		for _, route := range routeTable.Routes {
+			if route.AttachmentID != attachmentID || route.State == "blackhole" || seen[route.DestinationCidr] {
+				continue
+			}
+			seen[route.DestinationCidr] = true
+			cidrs = append(cidrs, route.DestinationCidr)
+		}
+	}
+	sort.Strings(cidrs)
+	return cidrs
+}
+
+// routedLabel returns DOT label lines listing the CIDRs routed each way across an
+// edge, with → for traffic in the edge's direction and ← against it
+func routedLabel(forward, backward []string) string {
+	var label string
+	if len(forward) > 0 {
+		label += "\\n→ " + cidrSummary(forward)
+	}
+	if len(backward) > 0 {
+		label += "\\n← " + cidrSummary(backward)
+	}
+	return label
+}
+
+// cidrSummary joins CIDRs, naming only the first few of a long list
+func cidrSummary(cidrs []string) string {
+	if len(cidrs) <= maxRoutedCIDRs {
+		return strings.Join(cidrs, ", ")
+	}
+	return fmt.Sprintf("%s +%d more", strings.Join(cidrs[:maxRoutedCIDRs], ", "), len(cidrs)-maxRoutedCIDRs)
+}
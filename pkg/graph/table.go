@@ -0,0 +1,314 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// maxCellWidth is the widest a table cell is printed before it is truncated
+const maxCellWidth = 48
+
+// inventoryTable is a table of one resource type in the table output
+type inventoryTable struct {
+	name     string // Name used to select the table's columns, e.g. "subnets"
+	title    string
+	defaults []string // Columns shown when none are selected
+	rows     []map[string]string
+}
+
+// inventoryColumns lists the tables of the table output with their columns, for
+// validating --columns before the network is scanned
+var inventoryColumns = map[string][]string{
+	"vpcs":                 {"id", "name", "cidr", "ipv6", "state", "default", "dhcp-options", "subnets", "tags"},
+	"subnets":              {"id", "name", "vpc", "cidr", "ipv6", "az", "type", "route-table", "network-acl", "used", "available", "tags"},
+	"internet-gateways":    {"id", "name", "vpc", "state", "tags"},
+	"egress-only-gateways": {"id", "name", "vpc", "state", "tags"},
+	"nat-gateways":         {"id", "name", "vpc", "subnet", "state", "type", "public-ip", "private-ip", "tags"},
+	"peering-connections":  {"id", "name", "requester", "accepter", "requester-region", "accepter-region", "status", "tags"},
+	"transit-gateways":     {"id", "name", "state", "attachments", "route-tables", "tags"},
+	"network-interfaces":   {"id", "vpc", "subnet", "az", "attachment", "attached-to", "interface-type", "status", "private-ip", "ips", "ipv6", "public-ip", "security-groups", "description", "tags"},
+}
+
+// ParseColumns parses --columns selections of the form "table=column,column", or
+// "column,column" for every table that has those columns
+func ParseColumns(selections []string) (map[string][]string, error) {
+	columns := make(map[string][]string)
+	for _, selection := range selections {
+		table, list := "", selection
+		if i := strings.Index(selection, "="); i >= 0 {
+			table, list = strings.TrimSpace(selection[:i]), selection[i+1:]
+			if _, ok := inventoryColumns[table]; !ok {
+				return nil, fmt.Errorf("unknown table %q in --columns (expected one of %s)", table, strings.Join(inventoryTableNames(), ", "))
+			}
+		}
+
+		for _, column := range strings.Split(list, ",") {
+			column = strings.TrimSpace(strings.ToLower(column))
+			if column == "" {
+				continue
+			}
+			if !knownColumn(table, column) {
+				if table == "" {
+					return nil, fmt.Errorf("unknown column %q in --columns", column)
+				}
+				return nil, fmt.Errorf("unknown column %q for table %s (expected one of %s)", column, table, strings.Join(inventoryColumns[table], ", "))
+			}
+			columns[table] = append(columns[table], column)
+		}
+	}
+	return columns, nil
+}
+
+// inventoryTableNames returns the sorted names of the table output's tables
+func inventoryTableNames() []string {
+	names := make([]string, 0, len(inventoryColumns))
+	for name := range inventoryColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// knownColumn reports whether a table, or any table when none is named, has a column
+func knownColumn(table, column string) bool {
+	for name, columns := range inventoryColumns {
+		if table != "" && name != table {
+			continue
+		}
+		for _, known := range columns {
+			if known == column {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetColumns selects the columns of the table output by table name, with the
+// columns under "" applying to every table that has them
+func (v *Visualizer) SetColumns(columns map[string][]string) {
+	v.columns = columns
+}
+
+// generateTable generates aligned inventory tables of VPCs, subnets and gateways
+func (v *Visualizer) generateTable(network *scanner.Network) string {
+	var result strings.Builder
+
+	result.WriteString(fmt.Sprintf("AWS Network Infrastructure - Region: %s\n", network.Region))
+	result.WriteString(fmt.Sprintf("Scan Time: %s\n", network.ScanTime.Format("2006-01-02 15:04:05")))
+
+	for _, table := range inventoryTables(network) {
+		if len(table.rows) == 0 {
+			continue
+		}
+		columns := v.tableColumns(table)
+		if len(columns) == 0 {
+			continue
+		}
+		result.WriteString(fmt.Sprintf("\n%s (%d)\n", table.title, len(table.rows)))
+		writeTable(&result, columns, table.rows)
+	}
+
+	return result.String()
+}
+
+// tableColumns returns the selected columns of a table, or its default columns
+func (v *Visualizer) tableColumns(table inventoryTable) []string {
+	if selected, ok := v.columns[table.name]; ok {
+		return selected
+	}
+	shared, ok := v.columns[""]
+	if !ok {
+		return table.defaults
+	}
+
+	var columns []string
+	for _, column := range shared {
+		if knownColumn(table.name, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// writeTable writes rows as a table with each column as wide as its widest cell
+func writeTable(result *strings.Builder, columns []string, rows []map[string]string) {
+	cells := make([][]string, 0, len(rows)+1)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+	}
+	cells = append(cells, header)
+	for _, row := range rows {
+		line := make([]string, len(columns))
+		for i, column := range columns {
+			line[i] = truncateCell(row[column])
+		}
+		cells = append(cells, line)
+	}
+
+	widths := make([]int, len(columns))
+	for _, line := range cells {
+		for i, cell := range line {
+			if width := utf8.RuneCountInString(cell); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	for _, line := range cells {
+		var out strings.Builder
+		for i, cell := range line {
+			out.WriteString(cell)
+			if i < len(line)-1 {
+				out.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		result.WriteString(out.String() + "\n")
+	}
+}
+
+// truncateCell shortens a cell to maxCellWidth characters, and shows empty cells as "-"
+func truncateCell(cell string) string {
+	if cell == "" {
+		return "-"
+	}
+	if utf8.RuneCountInString(cell) <= maxCellWidth {
+		return cell
+	}
+	return string([]rune(cell)[:maxCellWidth-1]) + "…"
+}
+
+// tagsCell formats tags as sorted key=value pairs
+func tagsCell(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// inventoryTables builds the tables of the table output, with rows sorted by ID
+func inventoryTables(network *scanner.Network) []inventoryTable {
+	vpcs := inventoryTable{name: "vpcs", title: "VPCs", defaults: []string{"id", "name", "cidr", "state", "default", "subnets"}}
+	for _, vpc := range network.VPCs {
+		vpcs.rows = append(vpcs.rows, map[string]string{
+			"id":           vpc.ID,
+			"name":         vpc.Name,
+			"cidr":         vpc.CidrBlock,
+			"ipv6":         strings.Join(vpc.Ipv6CidrBlocks, ","),
+			"state":        vpc.State,
+			"default":      strconv.FormatBool(vpc.IsDefault),
+			"dhcp-options": vpc.DhcpOptionsID,
+			"subnets":      strconv.Itoa(len(vpc.Subnets)),
+			"tags":         tagsCell(vpc.Tags),
+		})
+	}
+
+	subnets := inventoryTable{name: "subnets", title: "Subnets", defaults: []string{"id", "name", "vpc", "cidr", "az", "type", "used"}}
+	for _, subnet := range network.Subnets {
+		used, available := "", ""
+		if utilization, ok := subnet.Utilization(); ok {
+			used = fmt.Sprintf("%.0f%%", utilization)
+			available = strconv.Itoa(subnet.FreeIPv4AddressCount())
+		}
+		subnets.rows = append(subnets.rows, map[string]string{
+			"id":          subnet.ID,
+			"name":        subnet.Name,
+			"vpc":         subnet.VpcID,
+			"cidr":        subnet.CidrBlock,
+			"ipv6":        strings.Join(subnet.Ipv6CidrBlocks, ","),
+			"az":          subnet.AvailabilityZone,
+			"type":        subnet.Type,
+			"route-table": subnet.RouteTableID,
+			"network-acl": subnet.NetworkAclID,
+			"used":        used,
+			"available":   available,
+			"tags":        tagsCell(subnet.Tags),
+		})
+	}
+
+	igws := inventoryTable{name: "internet-gateways", title: "Internet Gateways", defaults: []string{"id", "name", "vpc", "state"}}
+	for _, igw := range network.InternetGateways {
+		igws.rows = append(igws.rows, map[string]string{"id": igw.ID, "name": igw.Name, "vpc": igw.VpcID, "state": igw.State, "tags": tagsCell(igw.Tags)})
+	}
+
+	eigws := inventoryTable{name: "egress-only-gateways", title: "Egress-only Internet Gateways", defaults: []string{"id", "name", "vpc", "state"}}
+	for _, eigw := range network.EgressOnlyInternetGateways {
+		eigws.rows = append(eigws.rows, map[string]string{"id": eigw.ID, "name": eigw.Name, "vpc": eigw.VpcID, "state": eigw.State, "tags": tagsCell(eigw.Tags)})
+	}
+
+	nats := inventoryTable{name: "nat-gateways", title: "NAT Gateways", defaults: []string{"id", "name", "vpc", "subnet", "state", "public-ip"}}
+	for _, nat := range network.NATGateways {
+		nats.rows = append(nats.rows, map[string]string{
+			"id":         nat.ID,
+			"name":       nat.Name,
+			"vpc":        nat.VpcID,
+			"subnet":     nat.SubnetID,
+			"state":      nat.State,
+			"type":       nat.ConnectivityType,
+			"public-ip":  nat.PublicIP,
+			"private-ip": nat.PrivateIP,
+			"tags":       tagsCell(nat.Tags),
+		})
+	}
+
+	peerings := inventoryTable{name: "peering-connections", title: "Peering Connections", defaults: []string{"id", "name", "requester", "accepter", "status"}}
+	for _, peering := range network.PeeringConnections {
+		peerings.rows = append(peerings.rows, map[string]string{
+			"id":               peering.ID,
+			"name":             peering.Name,
+			"requester":        peering.RequesterVpcID,
+			"accepter":         peering.AccepterVpcID,
+			"requester-region": peering.RequesterRegion,
+			"accepter-region":  peering.AccepterRegion,
+			"status":           peering.Status,
+			"tags":             tagsCell(peering.Tags),
+		})
+	}
+
+	tgws := inventoryTable{name: "transit-gateways", title: "Transit Gateways", defaults: []string{"id", "name", "state", "attachments"}}
+	for _, tgw := range network.TransitGateways {
+		tgws.rows = append(tgws.rows, map[string]string{
+			"id":           tgw.ID,
+			"name":         tgw.Name,
+			"state":        tgw.State,
+			"attachments":  strconv.Itoa(len(tgw.Attachments)),
+			"route-tables": strconv.Itoa(len(tgw.RouteTables)),
+			"tags":         tagsCell(tgw.Tags),
+		})
+	}
+
+	enis := inventoryTable{name: "network-interfaces", title: "Network Interfaces", defaults: []string{"id", "subnet", "attachment", "attached-to", "private-ip", "ips"}}
+	for _, eni := range network.NetworkInterfaces {
+		enis.rows = append(enis.rows, map[string]string{
+			"id":              eni.ID,
+			"vpc":             eni.VpcID,
+			"subnet":          eni.SubnetID,
+			"az":              eni.AvailabilityZone,
+			"attachment":      eni.AttachmentType,
+			"attached-to":     eni.AttachedTo,
+			"interface-type":  eni.InterfaceType,
+			"status":          eni.Status,
+			"private-ip":      eni.PrivateIP,
+			"ips":             strconv.Itoa(eni.IPv4AddressCount()),
+			"ipv6":            strings.Join(eni.Ipv6Addresses, ", "),
+			"public-ip":       eni.PublicIP,
+			"security-groups": strings.Join(eni.SecurityGroupIDs, ", "),
+			"description":     eni.Description,
+			"tags":            tagsCell(eni.Tags),
+		})
+	}
+
+	tables := []inventoryTable{vpcs, subnets, igws, eigws, nats, peerings, tgws, enis}
+	for i := range tables {
+		sort.SliceStable(tables[i].rows, func(a, b int) bool { return tables[i].rows[a]["id"] < tables[i].rows[b]["id"] })
+	}
+	return tables
+}
@@ -0,0 +1,106 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func tableNetwork() *scanner.Network {
+	available := 51
+	return &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-2", CidrBlock: "10.1.0.0/16", State: "available"},
+			{ID: "vpc-1", Name: "production", CidrBlock: "10.0.0.0/16", State: "available", IsDefault: true, Subnets: []string{"subnet-a"},
+				Tags: map[string]string{"Team": "network", "Env": "prod"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-a", VpcID: "vpc-1", CidrBlock: "10.0.1.0/24", AvailabilityZone: "us-east-1a", Type: "public", AvailableIPs: &available},
+		},
+		NATGateways: []scanner.NATGateway{
+			{ID: "nat-1", VpcID: "vpc-1", SubnetID: "subnet-a", State: "available", PublicIP: "203.0.113.10"},
+		},
+	}
+}
+
+func TestTableOutput(t *testing.T) {
+	text, err := NewVisualizer("table").Generate(tableNetwork())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, expected := range []string{
+		"\nVPCs (2)\nID     NAME        CIDR         STATE      DEFAULT  SUBNETS\nvpc-1  production  10.0.0.0/16  available  true     1\nvpc-2  -           10.1.0.0/16  available  false    0\n",
+		"\nSubnets (1)\nID        NAME  VPC    CIDR         AZ          TYPE    USED\nsubnet-a  -     vpc-1  10.0.1.0/24  us-east-1a  public  80%\n",
+		"\nNAT Gateways (1)\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in the table output, got:\n%s", expected, text)
+		}
+	}
+	if strings.Contains(text, "Internet Gateways") {
+		t.Errorf("Expected empty tables to be left out, got:\n%s", text)
+	}
+}
+
+func TestTableColumns(t *testing.T) {
+	columns, err := ParseColumns([]string{"id,tags", "subnets=id,available"})
+	if err != nil {
+		t.Fatalf("ParseColumns failed: %v", err)
+	}
+
+	v := NewVisualizer("table")
+	v.SetColumns(columns)
+	text, err := v.Generate(tableNetwork())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, expected := range []string{
+		"ID     TAGS\nvpc-1  Env=prod,Team=network\nvpc-2  -\n",
+		"ID        AVAILABLE\nsubnet-a  51\n",
+		"ID     TAGS\nnat-1  -\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in the table output, got:\n%s", expected, text)
+		}
+	}
+
+	if _, err := ParseColumns([]string{"routers=id"}); err == nil {
+		t.Error("Expected an error for an unknown table")
+	}
+	if _, err := ParseColumns([]string{"vpcs=az"}); err == nil {
+		t.Error("Expected an error for a column the table does not have")
+	}
+	if _, err := ParseColumns([]string{"bandwidth"}); err == nil {
+		t.Error("Expected an error for a column no table has")
+	}
+}
+
+func TestTableCidrReservations(t *testing.T) {
+	network := tableNetwork()
+	network.Subnets[0].CidrReservations = []scanner.SubnetCidrReservation{{Cidr: "10.0.1.224/28", ReservationType: "explicit"}}
+
+	v := NewVisualizer("table")
+	v.SetColumns(map[string][]string{"subnets": {"id", "used", "available"}})
+	text, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// The 16 reserved addresses count as used and are not available
+	if expected := "ID        USED  AVAILABLE\nsubnet-a  86%   35\n"; !strings.Contains(text, expected) {
+		t.Errorf("Expected %q in the table output, got:\n%s", expected, text)
+	}
+}
+
+func TestTruncateCell(t *testing.T) {
+	long := strings.Repeat("x", maxCellWidth+10)
+	if got := truncateCell(long); len([]rune(got)) != maxCellWidth || !strings.HasSuffix(got, "…") {
+		t.Errorf("Expected the cell to be truncated to %d characters, got %q", maxCellWidth, got)
+	}
+}
@@ -0,0 +1,176 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// tagColor is how resources with one value of the --color-by tag are drawn in
+// each format
+type tagColor struct {
+	dot  string
+	hex  string
+	ansi color.Attribute
+}
+
+// tagPalette colors the values of the --color-by tag in sorted order, wrapping
+// around when there are more values than colors
+var tagPalette = []tagColor{
+	{"lightskyblue", "#87cefa", color.FgCyan},
+	{"palegreen", "#98fb98", color.FgGreen},
+	{"lightpink", "#ffb6c1", color.FgMagenta},
+	{"khaki", "#f0e68c", color.FgYellow},
+	{"plum", "#dda0dd", color.FgBlue},
+	{"lightsalmon", "#ffa07a", color.FgRed},
+	{"aquamarine", "#7fffd4", color.FgHiCyan},
+	{"thistle", "#d8bfd8", color.FgHiMagenta},
+}
+
+// untaggedColor draws resources without the --color-by tag
+var untaggedColor = tagColor{"gray90", "#e5e5e5", color.FgHiBlack}
+
+// ParseColorBy parses a --color-by selection, returning the tag key to color by
+func ParseColorBy(selection string) (string, error) {
+	if selection == "" {
+		return "", nil
+	}
+	key, ok := strings.CutPrefix(selection, "tag:")
+	if !ok || key == "" {
+		return "", fmt.Errorf("unsupported --color-by %q (expected tag:<key>)", selection)
+	}
+	return key, nil
+}
+
+// SetColorByTag colors VPCs, subnets and gateways by the value of a tag instead of
+// by their type. An empty key turns it off.
+func (v *Visualizer) SetColorByTag(key string) {
+	v.colorByTag = key
+}
+
+// prepareTagColors orders the values of the --color-by tag in the network, which
+// take the palette's colors in turn
+func (v *Visualizer) prepareTagColors(network *scanner.Network) {
+	v.tagValues = nil
+	if v.colorByTag == "" {
+		return
+	}
+
+	seen := make(map[string]bool)
+	add := func(tags map[string]string) {
+		if value, ok := tags[v.colorByTag]; ok && !seen[value] {
+			seen[value] = true
+			v.tagValues = append(v.tagValues, value)
+		}
+	}
+	for _, vpc := range network.VPCs {
+		add(vpc.Tags)
+	}
+	for _, subnet := range network.Subnets {
+		add(subnet.Tags)
+	}
+	for _, igw := range network.InternetGateways {
+		add(igw.Tags)
+	}
+	for _, eigw := range network.EgressOnlyInternetGateways {
+		add(eigw.Tags)
+	}
+	for _, nat := range network.NATGateways {
+		add(nat.Tags)
+	}
+	sort.Strings(v.tagValues)
+}
+
+// tagValueIndex returns the palette position of a resource's --color-by tag value,
+// or -1 when the resource does not have the tag
+func (v *Visualizer) tagValueIndex(tags map[string]string) int {
+	value, ok := tags[v.colorByTag]
+	if !ok {
+		return -1
+	}
+	for i, known := range v.tagValues {
+		if known == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// tagColorOf returns the color of a resource's --color-by tag value, or false when
+// resources are not colored by tag
+func (v *Visualizer) tagColorOf(tags map[string]string) (tagColor, bool) {
+	if v.colorByTag == "" {
+		return tagColor{}, false
+	}
+	if i := v.tagValueIndex(tags); i >= 0 {
+		return tagPalette[i%len(tagPalette)], true
+	}
+	return untaggedColor, true
+}
+
+// dotFill returns a node's DOT fill color: its tag value's color, or the color of
+// its kind of resource
+func (v *Visualizer) dotFill(tags map[string]string, fallback string) string {
+	if c, ok := v.tagColorOf(tags); ok {
+		return c.dot
+	}
+	return fallback
+}
+
+// mermaidClass returns a node's Mermaid class: its tag value's class, or the
+// class of its kind of resource
+func (v *Visualizer) mermaidClass(tags map[string]string, fallback string) string {
+	if v.colorByTag == "" {
+		return fallback
+	}
+	if i := v.tagValueIndex(tags); i >= 0 {
+		return fmt.Sprintf("tag%d", i)
+	}
+	return "untagged"
+}
+
+// mermaidTagClassDefs returns the Mermaid classes of the tag values
+func (v *Visualizer) mermaidTagClassDefs() []string {
+	if v.colorByTag == "" {
+		return nil
+	}
+	defs := []string{fmt.Sprintf("classDef untagged fill:%s,stroke:#333", untaggedColor.hex)}
+	for i := range v.tagValues {
+		defs = append(defs, fmt.Sprintf("classDef tag%d fill:%s,stroke:#333", i, tagPalette[i%len(tagPalette)].hex))
+	}
+	return defs
+}
+
+// textTagLabel returns the tag value a text graph line is colored by, painted in
+// its color, or nothing when the resource does not have the tag
+func (v *Visualizer) textTagLabel(tags map[string]string) string {
+	i := -1
+	if v.colorByTag != "" {
+		i = v.tagValueIndex(tags)
+	}
+	if i < 0 {
+		return ""
+	}
+	return " " + v.paint(tagPalette[i%len(tagPalette)].ansi, fmt.Sprintf("{%s=%s}", v.colorByTag, v.tagValues[i]))
+}
+
+// tagLegendEntry is a --color-by tag value and its color in the legend
+type tagLegendEntry struct {
+	label string
+	color tagColor
+}
+
+// tagLegend lists the --color-by tag values and the color of resources without the tag
+func (v *Visualizer) tagLegend() []tagLegendEntry {
+	if v.colorByTag == "" {
+		return nil
+	}
+	var entries []tagLegendEntry
+	for i, value := range v.tagValues {
+		entries = append(entries, tagLegendEntry{fmt.Sprintf("%s=%s", v.colorByTag, value), tagPalette[i%len(tagPalette)]})
+	}
+	return append(entries, tagLegendEntry{fmt.Sprintf("no %s tag", v.colorByTag), untaggedColor})
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// internetNodeID is the DOT node representing the public internet
+const internetNodeID = "internet"
+
+// Edge styles for traffic flow, distinct from the structural "contains/attached" edges
+const (
+	egressEdgeStyle  = "color=firebrick, fontcolor=firebrick, penwidth=2"
+	ingressEdgeStyle = "color=darkgreen, fontcolor=darkgreen, penwidth=2"
+)
+
+// trafficEdge is a directed hop on a traffic path
+type trafficEdge struct {
+	from  string
+	to    string
+	label string
+	style string
+}
+
+// defaultRouteTarget returns the target of a route table's IPv4 default route
+func defaultRouteTarget(routeTable *scanner.RouteTable) string {
+	for _, route := range routeTable.Routes {
+		if route.DestinationCidr != "0.0.0.0/0" {
+			continue
+		}
+		if target := route.Target(); target != "" {
+			return target
+		}
+	}
+	return ""
+}
+
+// defaultIPv6RouteTarget returns the target of a route table's IPv6 default route
+func defaultIPv6RouteTarget(routeTable *scanner.RouteTable) string {
+	for _, route := range routeTable.Routes {
+		if route.DestinationIpv6Cidr != "::/0" {
+			continue
+		}
+		if target := route.Target(); target != "" {
+			return target
+		}
+	}
+	return ""
+}
+
+// trafficPaths derives egress (subnet → NAT → IGW → internet, and for IPv6
+// subnet → egress-only IGW → internet) and ingress (internet → IGW → public
+// subnet) hops from the subnets' default routes
+func trafficPaths(network *scanner.Network) []trafficEdge {
+	routeTables := make(map[string]*scanner.RouteTable)
+	for i := range network.RouteTables {
+		routeTables[network.RouteTables[i].ID] = &network.RouteTables[i]
+	}
+
+	subnetTargets := make(map[string]string)
+	ipv6Targets := make(map[string]string)
+	for _, subnet := range network.Subnets {
+		if rt, exists := routeTables[subnet.RouteTableID]; exists {
+			subnetTargets[subnet.ID] = defaultRouteTarget(rt)
+			ipv6Targets[subnet.ID] = defaultIPv6RouteTarget(rt)
+		}
+	}
+
+	natSubnets := make(map[string]string)
+	for _, nat := range network.NATGateways {
+		natSubnets[nat.ID] = nat.SubnetID
+	}
+
+	var edges []trafficEdge
+	seen := make(map[trafficEdge]bool)
+	add := func(edge trafficEdge) {
+		if !seen[edge] {
+			seen[edge] = true
+			edges = append(edges, edge)
+		}
+	}
+
+	for _, subnet := range network.Subnets {
+		target := subnetTargets[subnet.ID]
+
+		switch {
+		case strings.HasPrefix(target, "igw-"):
+			add(trafficEdge{from: internetNodeID, to: target, label: "ingress", style: ingressEdgeStyle})
+			add(trafficEdge{from: target, to: subnet.ID, label: "ingress", style: ingressEdgeStyle})
+			add(trafficEdge{from: target, to: internetNodeID, label: "egress", style: egressEdgeStyle})
+
+		case strings.HasPrefix(target, "nat-"):
+			add(trafficEdge{from: subnet.ID, to: target, label: "egress", style: egressEdgeStyle})
+
+			// The NAT gateway reaches the internet through its own subnet's route
+			natTarget := subnetTargets[natSubnets[target]]
+			if strings.HasPrefix(natTarget, "igw-") {
+				add(trafficEdge{from: target, to: natTarget, label: "egress", style: egressEdgeStyle})
+				add(trafficEdge{from: natTarget, to: internetNodeID, label: "egress", style: egressEdgeStyle})
+			}
+		}
+
+		// Egress-only internet gateways let IPv6 traffic out but never in
+		if eigw := ipv6Targets[subnet.ID]; strings.HasPrefix(eigw, "eigw-") {
+			add(trafficEdge{from: subnet.ID, to: eigw, label: "egress", style: egressEdgeStyle})
+			add(trafficEdge{from: eigw, to: internetNodeID, label: "egress", style: egressEdgeStyle})
+		}
+	}
+
+	return edges
+}
+
+// writeTrafficPaths writes the internet node and the traffic flow edges
+func (v *Visualizer) writeTrafficPaths(result *strings.Builder, network *scanner.Network) {
+	edges := trafficPaths(network)
+	if len(edges) == 0 {
+		return
+	}
+
+	result.WriteString("\n  // Traffic Paths\n")
+	result.WriteString(fmt.Sprintf("  \"%s\" [label=\"Internet\", shape=ellipse, fillcolor=white];\n", internetNodeID))
+	for _, edge := range edges {
+		result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"%s\", %s];\n", edge.from, edge.to, edge.label, edge.style))
+	}
+}
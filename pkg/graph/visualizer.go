@@ -10,26 +10,64 @@ import (
 
 // Visualizer generates graph representations of AWS network infrastructure
 type Visualizer struct {
-	format string
+	format        string
+	detail        DetailLevel
+	layout        Layout
+	legend        bool
+	focus         string
+	depth         int
+	hidden        []string
+	colorByTag    string
+	tagValues     []string
+	toolVersion   string
+	detailed      bool
+	columns       map[string][]string
+	color         bool
+	observedFlows []ObservedFlow
+	icons         IconSet
 }
 
 // NewVisualizer creates a new graph visualizer
 func NewVisualizer(format string) *Visualizer {
 	return &Visualizer{
 		format: format,
+		detail: DetailNormal,
+		layout: LayoutHierarchy,
 	}
 }
 
-// Generate generates a graph representation of the network
+// SetDetailed enables or disables detail output such as connectivity limit annotations
+func (v *Visualizer) SetDetailed(detailed bool) {
+	if detailed {
+		v.SetDetailLevel(DetailFull)
+	} else {
+		v.SetDetailLevel(DetailNormal)
+	}
+}
+
+// SetDetailLevel sets how much of the network the text graph shows. DOT, Mermaid and
+// HTML output add connectivity limit annotations at the full level.
+func (v *Visualizer) SetDetailLevel(level DetailLevel) {
+	v.detail = level
+	v.detailed = level == DetailFull
+}
+
+// Generate generates a graph representation of the network. The network's
+// collections are sorted in place first, so every format lists them in the same order,
+// and then filtered to the focus and hidden kinds of resources.
 func (v *Visualizer) Generate(network *scanner.Network) (string, error) {
-	switch v.format {
-	case "text":
-		return v.generateTextGraph(network), nil
-	case "dot":
-		return v.generateDotGraph(network), nil
-	default:
+	network.Sort()
+	network, err := v.filter(network)
+	if err != nil {
+		return "", err
+	}
+	v.prepareTagColors(network)
+	
+	renderer, ok := lookupRenderer(v.format)
+	if !ok {
 		return "", fmt.Errorf("unsupported output format: %s", v.format)
 	}
+	return renderer.Render(v, network)
 }
 
 // generateTextGraph generates a text-based tree representation
@@ -39,10 +77,13 @@ func (v *Visualizer) generateTextGraph(network *scanner.Network) string {
 	result.WriteString(fmt.Sprintf("AWS Network Infrastructure - Region: %s\n", network.Region))
 	result.WriteString(fmt.Sprintf("Scan Time: %s\n\n", network.ScanTime.Format("2006-01-02 15:04:05")))
 	
-	// Sort VPCs by ID for consistent output
+	// Sort VPCs by name and then ID for consistent output
 	vpcs := make([]scanner.VPC, len(network.VPCs))
 	copy(vpcs, network.VPCs)
 	sort.Slice(vpcs, func(i, j int) bool {
+		if vpcs[i].Name != vpcs[j].Name {
+			return vpcs[i].Name < vpcs[j].Name
+		}
 		return vpcs[i].ID < vpcs[j].ID
 	})
 	
@@ -67,24 +108,54 @@ func (v *Visualizer) generateTextGraph(network *scanner.Network) string {
 		igwMap[igw.VpcID] = append(igwMap[igw.VpcID], igw)
 	}
 	
+	// Create egress-only IGW map for quick lookup
+	eigwMap := make(map[string][]scanner.EgressOnlyInternetGateway)
+	for _, eigw := range network.EgressOnlyInternetGateways {
+		eigwMap[eigw.VpcID] = append(eigwMap[eigw.VpcID], eigw)
+	}
+	
 	// Create NAT map for quick lookup
 	natMap := make(map[string][]scanner.NATGateway)
 	for _, nat := range network.NATGateways {
 		natMap[nat.VpcID] = append(natMap[nat.VpcID], nat)
 	}
 	
+	// Create container maps for quick lookup
+	eksMap := make(map[string][]scanner.EKSCluster)
+	for _, cluster := range network.EKSClusters {
+		eksMap[cluster.VpcID] = append(eksMap[cluster.VpcID], cluster)
+	}
+	ecsMap := make(map[string][]scanner.ECSService)
+	for _, service := range network.ECSServices {
+		ecsMap[service.VpcID] = append(ecsMap[service.VpcID], service)
+	}
+	
+	// Create database map for quick lookup
+	dbMap := make(map[string][]scanner.Database)
+	for _, database := range network.Databases {
+		dbMap[database.VpcID] = append(dbMap[database.VpcID], database)
+	}
+	
 	// Display VPCs and their resources
 	for i, vpc := range vpcs {
 		isLast := i == len(vpcs)-1
-		v.writeVPC(&result, vpc, subnetMap, peeringMap, igwMap, natMap, isLast)
+		v.writeVPC(&result, network, vpc, subnetMap, peeringMap, igwMap, eigwMap, natMap, eksMap, ecsMap, dbMap, isLast)
 	}
 	
 	// Display Transit Gateways
-	if len(network.TransitGateways) > 0 {
+	if len(network.TransitGateways) > 0 && v.detail != DetailMinimal {
 		result.WriteString("\n")
 		for i, tgw := range network.TransitGateways {
 			isLast := i == len(network.TransitGateways)-1
-			v.writeTransitGateway(&result, tgw, network.VPCs, isLast)
+			v.writeTransitGateway(&result, tgw, network.VPCs, network.Region, isLast)
+		}
+	}
+	
+	// Display service mesh gateways
+	if len(network.MeshVirtualGateways) > 0 && v.detail != DetailMinimal {
+		result.WriteString("\nService Mesh Gateways:\n")
+		for i, gateway := range network.MeshVirtualGateways {
+			v.writeMeshVirtualGateway(&result, gateway, i == len(network.MeshVirtualGateways)-1)
 		}
 	}
 	
@@ -95,15 +166,34 @@ func (v *Visualizer) generateTextGraph(network *scanner.Network) string {
 	result.WriteString(fmt.Sprintf("  Peering Connections: %d\n", len(network.PeeringConnections)))
 	result.WriteString(fmt.Sprintf("  Transit Gateways: %d\n", len(network.TransitGateways)))
 	result.WriteString(fmt.Sprintf("  Internet Gateways: %d\n", len(network.InternetGateways)))
+	if len(network.EgressOnlyInternetGateways) > 0 {
+		result.WriteString(fmt.Sprintf("  Egress-only Internet Gateways: %d\n", len(network.EgressOnlyInternetGateways)))
+	}
 	result.WriteString(fmt.Sprintf("  NAT Gateways: %d\n", len(network.NATGateways)))
+	if len(network.MeshVirtualGateways) > 0 {
+		result.WriteString(fmt.Sprintf("  Mesh Virtual Gateways: %d\n", len(network.MeshVirtualGateways)))
+	}
+	if len(network.EKSClusters) > 0 {
+		result.WriteString(fmt.Sprintf("  EKS Clusters: %d\n", len(network.EKSClusters)))
+	}
+	if len(network.ECSServices) > 0 {
+		result.WriteString(fmt.Sprintf("  ECS Services: %d\n", len(network.ECSServices)))
+	}
+	if len(network.Databases) > 0 {
+		result.WriteString(fmt.Sprintf("  Databases: %d\n", len(network.Databases)))
+	}
+	if len(network.EdgeIngresses) > 0 {
+		result.WriteString(fmt.Sprintf("  Edge Entry Points: %d\n", len(network.EdgeIngresses)))
+	}
 	
 	return result.String()
 }
 
 // writeVPC writes a VPC and its associated resources
-func (v *Visualizer) writeVPC(result *strings.Builder, vpc scanner.VPC, subnetMap map[string]scanner.Subnet, 
+func (v *Visualizer) writeVPC(result *strings.Builder, network *scanner.Network, vpc scanner.VPC, subnetMap map[string]scanner.Subnet, 
 	peeringMap map[string][]scanner.PeeringConnection, igwMap map[string][]scanner.InternetGateway,
-	natMap map[string][]scanner.NATGateway, isLastVPC bool) {
+	eigwMap map[string][]scanner.EgressOnlyInternetGateway, natMap map[string][]scanner.NATGateway, eksMap map[string][]scanner.EKSCluster,
+	ecsMap map[string][]scanner.ECSService, dbMap map[string][]scanner.Database, isLastVPC bool) {
 	
 	vpcName := vpc.Name
 	if vpcName == "" {
@@ -115,7 +205,23 @@ func (v *Visualizer) writeVPC(result *strings.Builder, vpc scanner.VPC, subnetMa
 		defaultStr = " [Default]"
 	}
 	
-	result.WriteString(fmt.Sprintf("VPC: %s (%s)%s\n", vpcName, vpc.CidrBlock, defaultStr))
+	result.WriteString(fmt.Sprintf("VPC: %s (%s)%s%s\n", vpcName, strings.Join(cidrBlocks(vpc.CidrBlock, vpc.Ipv6CidrBlocks), ", "), defaultStr, v.textTagLabel(vpc.Tags)))
+	
+	// Minimal output lists only the subnets
+	if v.detail == DetailMinimal {
+		igwMap, eigwMap, natMap, peeringMap = nil, nil, nil, nil
+		eksMap, ecsMap, dbMap = nil, nil, nil
+	}
+	
+	// Full output lists the VPC's security groups and their rules
+	var securityGroups []scanner.SecurityGroup
+	if v.detail == DetailFull {
+		for _, sg := range network.SecurityGroups {
+			if sg.VpcID == vpc.ID {
+				securityGroups = append(securityGroups, sg)
+			}
+		}
+	}
 	
 	// Count total items to display
 	itemCount := 0
@@ -123,12 +229,19 @@ func (v *Visualizer) writeVPC(result *strings.Builder, vpc scanner.VPC, subnetMa
 	if igws, exists := igwMap[vpc.ID]; exists {
 		itemCount += len(igws)
 	}
+	itemCount += len(eigwMap[vpc.ID])
 	if nats, exists := natMap[vpc.ID]; exists {
 		itemCount += len(nats)
 	}
 	if peerings, exists := peeringMap[vpc.ID]; exists {
 		itemCount += len(peerings)
 	}
+	itemCount += len(eksMap[vpc.ID]) + len(ecsMap[vpc.ID]) + len(dbMap[vpc.ID])
+	var edgeIngresses []scanner.EdgeIngress
+	if v.detail != DetailMinimal {
+		edgeIngresses = edgeIngressesInVPC(network, vpc.ID)
+	}
+	itemCount += len(edgeIngresses) + len(securityGroups)
 	
 	currentItem := 0
 	
@@ -137,7 +250,7 @@ func (v *Visualizer) writeVPC(result *strings.Builder, vpc scanner.VPC, subnetMa
 		if subnet, exists := subnetMap[subnetID]; exists {
 			currentItem++
 			isLast := currentItem == itemCount
-			v.writeSubnet(result, subnet, isLast)
+			v.writeSubnet(result, network, subnet, isLast)
 		}
 	}
 	
@@ -150,6 +263,12 @@ func (v *Visualizer) writeVPC(result *strings.Builder, vpc scanner.VPC, subnetMa
 		}
 	}
 	
+	// Display egress-only Internet Gateways
+	for _, eigw := range eigwMap[vpc.ID] {
+		currentItem++
+		v.writeEgressOnlyInternetGateway(result, eigw, currentItem == itemCount)
+	}
+	
 	// Display NAT Gateways
 	if nats, exists := natMap[vpc.ID]; exists {
 		for _, nat := range nats {
@@ -159,6 +278,28 @@ func (v *Visualizer) writeVPC(result *strings.Builder, vpc scanner.VPC, subnetMa
 		}
 	}
 	
+	// Display EKS clusters and ECS services
+	for _, cluster := range eksMap[vpc.ID] {
+		currentItem++
+		v.writeEKSCluster(result, cluster, subnetMap, currentItem == itemCount)
+	}
+	for _, service := range ecsMap[vpc.ID] {
+		currentItem++
+		v.writeECSService(result, service, subnetMap, currentItem == itemCount)
+	}
+	
+	// Display databases
+	for _, database := range dbMap[vpc.ID] {
+		currentItem++
+		v.writeDatabase(result, database, network, subnetMap, currentItem == itemCount)
+	}
+	
+	// Display Global Accelerator and CloudFront ingress
+	for _, ingress := range edgeIngresses {
+		currentItem++
+		v.writeEdgeIngress(result, ingress, vpc.ID, currentItem == itemCount)
+	}
+	
 	// Display Peering Connections
 	if peerings, exists := peeringMap[vpc.ID]; exists {
 		for _, peering := range peerings {
@@ -168,13 +309,19 @@ func (v *Visualizer) writeVPC(result *strings.Builder, vpc scanner.VPC, subnetMa
 		}
 	}
 	
+	// Display security groups
+	for _, sg := range securityGroups {
+		currentItem++
+		writeSecurityGroup(result, sg, currentItem == itemCount)
+	}
+	
 	if !isLastVPC {
 		result.WriteString("\n")
 	}
 }
 
 // writeSubnet writes a subnet with proper tree formatting
-func (v *Visualizer) writeSubnet(result *strings.Builder, subnet scanner.Subnet, isLast bool) {
+func (v *Visualizer) writeSubnet(result *strings.Builder, network *scanner.Network, subnet scanner.Subnet, isLast bool) {
 	prefix := "├── "
 	if isLast {
 		prefix = "└── "
@@ -187,7 +334,19 @@ func (v *Visualizer) writeSubnet(result *strings.Builder, subnet scanner.Subnet,
 	
 	typeStr := ""
 	if subnet.Type != "" {
-		typeStr = fmt.Sprintf(" [%s]", strings.Title(subnet.Type))
+		typeStr = fmt.Sprintf("[%s]", strings.Title(subnet.Type))
+		// Detail output explains the type with the route that decided it
+		if v.detailed && subnet.TypeRoute != nil {
+			typeStr = fmt.Sprintf("[%s via %s → %s]", strings.Title(subnet.Type), subnet.TypeRoute.Destination(), subnet.TypeRoute.Target())
+		}
+		typeStr = " " + v.subnetTypeLabel(subnet.Type, typeStr)
+	}
+	typeStr += v.textTagLabel(subnet.Tags)
+	
+	if v.detail == DetailMinimal {
+		result.WriteString(fmt.Sprintf("%sSubnet: %s (%s)%s\n", prefix, subnetName,
+			strings.Join(cidrBlocks(subnet.CidrBlock, subnet.Ipv6CidrBlocks), ", "), typeStr))
+		return
 	}
 	
 	azStr := ""
@@ -195,7 +354,37 @@ func (v *Visualizer) writeSubnet(result *strings.Builder, subnet scanner.Subnet,
 		azStr = fmt.Sprintf(" AZ:%s", subnet.AvailabilityZone)
 	}
 	
-	result.WriteString(fmt.Sprintf("%sSubnet: %s (%s)%s%s\n", prefix, subnetName, subnet.CidrBlock, typeStr, azStr))
+	usedStr := ""
+	if utilization, ok := subnet.Utilization(); ok {
+		usable := subnet.UsableIPv4AddressCount()
+		usedStr = fmt.Sprintf(" Used:%d/%d IPs (%.0f%%)", usable-subnet.FreeIPv4AddressCount(), usable, utilization)
+	}
+	
+	reservedStr := ""
+	if reserved := subnet.ReservedIPv4AddressCount(); reserved > 0 {
+		reservedStr = fmt.Sprintf(" Reserved:%d IPs", reserved)
+	}
+	
+	result.WriteString(fmt.Sprintf("%sSubnet: %s (%s)%s%s%s%s\n", prefix, subnetName,
+		strings.Join(cidrBlocks(subnet.CidrBlock, subnet.Ipv6CidrBlocks), ", "), typeStr, azStr, usedStr, reservedStr))
+	
+	// Full output nests the subnet's route table and its routes
+	if v.detail == DetailFull {
+		for _, routeTable := range network.RouteTables {
+			if routeTable.ID == subnet.RouteTableID {
+				v.writeRouteTable(result, routeTable, isLast)
+			}
+		}
+	}
+}
+
+// cidrBlocks returns a VPC or subnet's IPv4 CIDR block followed by its IPv6 CIDR
+// blocks. IPv6-only subnets have no IPv4 CIDR block.
+func cidrBlocks(cidr string, ipv6 []string) []string {
+	if cidr == "" {
+		return ipv6
+	}
+	return append([]string{cidr}, ipv6...)
 }
 
 // writeInternetGateway writes an internet gateway
@@ -210,7 +399,22 @@ func (v *Visualizer) writeInternetGateway(result *strings.Builder, igw scanner.I
 		igwName = igw.ID
 	}
 	
-	result.WriteString(fmt.Sprintf("%sInternet Gateway: %s [%s]\n", prefix, igwName, igw.State))
+	result.WriteString(fmt.Sprintf("%sInternet Gateway: %s %s\n", prefix, igwName, v.stateLabel(igw.State, "attached", "available")))
+}
+
+// writeEgressOnlyInternetGateway writes an egress-only internet gateway
+func (v *Visualizer) writeEgressOnlyInternetGateway(result *strings.Builder, eigw scanner.EgressOnlyInternetGateway, isLast bool) {
+	prefix := "├── "
+	if isLast {
+		prefix = "└── "
+	}
+	
+	eigwName := eigw.Name
+	if eigwName == "" {
+		eigwName = eigw.ID
+	}
+	
+	result.WriteString(fmt.Sprintf("%sEgress-only Internet Gateway: %s %s\n", prefix, eigwName, v.stateLabel(eigw.State, "attached")))
 }
 
 // writeNATGateway writes a NAT gateway
@@ -233,7 +437,7 @@ func (v *Visualizer) writeNATGateway(result *strings.Builder, nat scanner.NATGat
 		ipInfo += fmt.Sprintf(" Private:%s", nat.PrivateIP)
 	}
 	
-	result.WriteString(fmt.Sprintf("%sNAT Gateway: %s [%s]%s\n", prefix, natName, nat.State, ipInfo))
+	result.WriteString(fmt.Sprintf("%sNAT Gateway: %s %s%s\n", prefix, natName, v.stateLabel(nat.State, "available"), ipInfo))
 }
 
 // writePeeringConnection writes a peering connection
@@ -256,17 +460,21 @@ func (v *Visualizer) writePeeringConnection(result *strings.Builder, peering sca
 		direction = "←"
 	}
 	
-	result.WriteString(fmt.Sprintf("%sPeering: %s %s %s [%s]\n", prefix, peeringName, direction, targetVPC, peering.Status))
+	result.WriteString(fmt.Sprintf("%sPeering: %s %s %s %s\n", prefix, peeringName, direction, targetVPC, v.stateLabel(peering.Status, "active")))
+	
+	if v.detailed {
+		writeAnnotations(result, peeringAnnotations(peering), isLast)
+	}
 }
 
 // writeTransitGateway writes a transit gateway and its attachments
-func (v *Visualizer) writeTransitGateway(result *strings.Builder, tgw scanner.TransitGateway, vpcs []scanner.VPC, isLast bool) {
+func (v *Visualizer) writeTransitGateway(result *strings.Builder, tgw scanner.TransitGateway, vpcs []scanner.VPC, region string, isLast bool) {
 	tgwName := tgw.Name
 	if tgwName == "" {
 		tgwName = tgw.ID
 	}
 	
-	result.WriteString(fmt.Sprintf("Transit Gateway: %s [%s]\n", tgwName, tgw.State))
+	result.WriteString(fmt.Sprintf("Transit Gateway: %s %s\n", tgwName, v.stateLabel(tgw.State, "available")))
 	
 	// Create VPC map for name lookup
 	vpcMap := make(map[string]string)
@@ -293,8 +501,15 @@ func (v *Visualizer) writeTransitGateway(result *strings.Builder, tgw scanner.Tr
 			}
 		}
 		
-		result.WriteString(fmt.Sprintf("%sAttachment: %s (%s) [%s]\n", 
-			prefix, resourceName, attachment.ResourceType, attachment.State))
+		result.WriteString(fmt.Sprintf("%sAttachment: %s (%s) %s\n", 
+			prefix, resourceName, attachment.ResourceType, v.stateLabel(attachment.State, "available")))
+		
+		if v.detailed {
+			writeAnnotations(result, transitGatewayAttachmentAnnotations(attachment, region), isLastAttachment)
+		}
+	}
+	if tgw.AttachmentsError != "" {
+		result.WriteString(fmt.Sprintf("└── Attachments not scanned: %s\n", tgw.AttachmentsError))
 	}
 	
 	if !isLast {
@@ -302,12 +517,40 @@ func (v *Visualizer) writeTransitGateway(result *strings.Builder, tgw scanner.Tr
 	}
 }
 
+// meshListenerSummary describes the listeners of a virtual gateway, e.g. "http:8080, grpc:9090 (tls)"
+func meshListenerSummary(listeners []scanner.MeshListener) string {
+	parts := make([]string, 0, len(listeners))
+	for _, listener := range listeners {
+		part := fmt.Sprintf("%s:%d", listener.Protocol, listener.Port)
+		if listener.TLS {
+			part += " (tls)"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeMeshVirtualGateway writes an App Mesh virtual gateway
+func (v *Visualizer) writeMeshVirtualGateway(result *strings.Builder, gateway scanner.MeshVirtualGateway, isLast bool) {
+	prefix := "├── "
+	if isLast {
+		prefix = "└── "
+	}
+	
+	listeners := ""
+	if len(gateway.Listeners) > 0 {
+		listeners = " Listeners: " + meshListenerSummary(gateway.Listeners)
+	}
+	
+	result.WriteString(fmt.Sprintf("%sVirtual Gateway: %s (mesh %s) %s%s\n", prefix, gateway.Name, gateway.MeshName, v.stateLabel(gateway.Status, "ACTIVE"), listeners))
+}
+
 // generateDotGraph generates a Graphviz DOT representation
 func (v *Visualizer) generateDotGraph(network *scanner.Network) string {
 	var result strings.Builder
 	
 	result.WriteString("digraph AWSNetwork {\n")
-	result.WriteString("  rankdir=TB;\n")
+	result.WriteString(v.dotGraphAttributes())
 	result.WriteString("  node [shape=box, style=rounded];\n")
 	result.WriteString("  edge [fontsize=10];\n\n")
 	
@@ -322,12 +565,12 @@ func (v *Visualizer) generateDotGraph(network *scanner.Network) string {
 			vpcName = vpc.ID
 		}
 		
-		label := fmt.Sprintf("%s\\n%s", vpcName, vpc.CidrBlock)
+		label := fmt.Sprintf("%s\\n%s", vpcName, strings.Join(cidrBlocks(vpc.CidrBlock, vpc.Ipv6CidrBlocks), "\\n"))
 		if vpc.IsDefault {
 			label += "\\n[Default]"
 		}
 		
-		result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", fillcolor=lightcyan];\n", vpc.ID, label))
+		result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", fillcolor=%s%s];\n", vpc.ID, label, v.dotFill(vpc.Tags, "lightcyan"), v.dotIcon("vpc")))
 	}
 	
 	// Add subnets
@@ -338,7 +581,7 @@ func (v *Visualizer) generateDotGraph(network *scanner.Network) string {
 			subnetName = subnet.ID
 		}
 		
-		label := fmt.Sprintf("%s\\n%s\\n[%s]", subnetName, subnet.CidrBlock, strings.Title(subnet.Type))
+		label := fmt.Sprintf("%s\\n%s\\n[%s]", subnetName, strings.Join(cidrBlocks(subnet.CidrBlock, subnet.Ipv6CidrBlocks), "\\n"), strings.Title(subnet.Type))
 		
 		color := "lightgreen"
 		switch subnet.Type {
@@ -350,7 +593,11 @@ func (v *Visualizer) generateDotGraph(network *scanner.Network) string {
 			color = "lightcoral"
 		}
 		
-		result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", fillcolor=%s];\n", subnet.ID, label, color))
+		icon := "private-subnet"
+		if subnet.Type == "public" {
+			icon = "public-subnet"
+		}
+		result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", fillcolor=%s%s];\n", subnet.ID, label, v.dotFill(subnet.Tags, color), v.dotIcon(icon)))
 		result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [style=dotted, label=\"contains\"];\n", subnet.VpcID, subnet.ID))
 	}
 	
@@ -363,8 +610,22 @@ func (v *Visualizer) generateDotGraph(network *scanner.Network) string {
 				igwName = igw.ID
 			}
 			
-			result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\\nInternet Gateway\", fillcolor=orange];\n", igw.ID, igwName))
-			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"attached\"];\n", igw.ID, igw.VpcID))
+			result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\\nInternet Gateway\", fillcolor=%s%s];\n", igw.ID, igwName, v.dotFill(igw.Tags, "orange"), v.dotIcon("internet-gateway")))
+			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"attached\"%s];\n", igw.ID, igw.VpcID, v.dotEdgeConstraint()))
+		}
+	}
+	
+	// Add egress-only Internet Gateways
+	if len(network.EgressOnlyInternetGateways) > 0 {
+		result.WriteString("\n  // Egress-only Internet Gateways\n")
+		for _, eigw := range network.EgressOnlyInternetGateways {
+			eigwName := eigw.Name
+			if eigwName == "" {
+				eigwName = eigw.ID
+			}
+			
+			result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\\nEgress-only Internet Gateway\", fillcolor=%s%s];\n", eigw.ID, eigwName, v.dotFill(eigw.Tags, "sandybrown"), v.dotIcon("egress-only-internet-gateway")))
+			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"attached\"%s];\n", eigw.ID, eigw.VpcID, v.dotEdgeConstraint()))
 		}
 	}
 	
@@ -382,7 +643,7 @@ func (v *Visualizer) generateDotGraph(network *scanner.Network) string {
 				label += fmt.Sprintf("\\n%s", nat.PublicIP)
 			}
 			
-			result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", fillcolor=gold];\n", nat.ID, label))
+			result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", fillcolor=%s%s];\n", nat.ID, label, v.dotFill(nat.Tags, "gold"), v.dotIcon("nat-gateway")))
 			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [style=dotted, label=\"in\"];\n", nat.ID, nat.SubnetID))
 		}
 	}
@@ -403,8 +664,19 @@ func (v *Visualizer) generateDotGraph(network *scanner.Network) string {
 				color = "gray"
 			}
 			
-			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"%s\\n[%s]\", style=%s, color=%s];\n", 
-				peering.RequesterVpcID, peering.AccepterVpcID, peeringName, peering.Status, style, color))
+			tooltip := ""
+			regionPair := ""
+			if v.detailed {
+				tooltip = fmt.Sprintf(", tooltip=\"%s\"", dotTooltip(peeringAnnotations(peering)))
+				if peering.RequesterRegion != "" && peering.AccepterRegion != "" && peering.RequesterRegion != peering.AccepterRegion {
+					regionPair = fmt.Sprintf("\\n%s ↔ %s", peering.RequesterRegion, peering.AccepterRegion)
+				}
+			}
+			
+			routed := routedLabel(vpcRoutedCIDRs(network, peering.RequesterVpcID, peering.ID), vpcRoutedCIDRs(network, peering.AccepterVpcID, peering.ID))
+			
+			result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"%s\\n[%s]%s%s\", style=%s, color=%s%s%s];\n", 
+				peering.RequesterVpcID, peering.AccepterVpcID, peeringName, peering.Status, regionPair, routed, style, color, tooltip, v.dotEdgeConstraint()))
 		}
 	}
 	
@@ -417,7 +689,7 @@ func (v *Visualizer) generateDotGraph(network *scanner.Network) string {
 				tgwName = tgw.ID
 			}
 			
-			result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\\nTransit Gateway\", fillcolor=purple, fontcolor=white];\n", tgw.ID, tgwName))
+			result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\\nTransit Gateway\", fillcolor=purple, fontcolor=white%s];\n", tgw.ID, tgwName, v.dotIcon("transit-gateway")))
 			
 			// Add attachments
 			for _, attachment := range tgw.Attachments {
@@ -426,13 +698,59 @@ func (v *Visualizer) generateDotGraph(network *scanner.Network) string {
 					if attachment.State != "available" {
 						style = "dashed"
 					}
-					result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"attached\", style=%s, color=purple];\n", 
-						tgw.ID, attachment.ResourceID, style))
+					tooltip := ""
+					if v.detailed {
+						tooltip = fmt.Sprintf(", tooltip=\"%s\"", dotTooltip(transitGatewayAttachmentAnnotations(attachment, network.Region)))
+					}
+					routed := routedLabel(transitGatewayRoutedCIDRs(tgw, attachment.ID), vpcRoutedCIDRs(network, attachment.ResourceID, tgw.ID))
+					result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"attached%s\", style=%s, color=purple%s];\n", 
+						tgw.ID, attachment.ResourceID, routed, style, tooltip))
+				}
+				
+				// Cross-region peering is drawn in detail output where its region pair is annotated
+				if attachment.ResourceType == "peering" && v.detailed && attachment.PeerTransitGatewayID != "" {
+					label := "peering"
+					if attachment.PeerRegion != "" && attachment.PeerRegion != network.Region {
+						label += fmt.Sprintf("\\n%s ↔ %s", network.Region, attachment.PeerRegion)
+					}
+					result.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"%s\", style=dashed, color=purple, tooltip=\"%s\"];\n",
+						tgw.ID, attachment.PeerTransitGatewayID, label, dotTooltip(transitGatewayAttachmentAnnotations(attachment, network.Region))))
 				}
 			}
 		}
 	}
 	
+	// Add service mesh gateways
+	if len(network.MeshVirtualGateways) > 0 {
+		result.WriteString("\n  // Service Mesh Gateways\n")
+		for _, gateway := range network.MeshVirtualGateways {
+			label := fmt.Sprintf("%s\\nVirtual Gateway (%s)", gateway.Name, gateway.MeshName)
+			if len(gateway.Listeners) > 0 {
+				label += "\\n" + meshListenerSummary(gateway.Listeners)
+			}
+			result.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", shape=hexagon, fillcolor=plum%s];\n", gateway.Arn, label, v.dotIcon("app-mesh")))
+		}
+	}
+	
+	// Add container networking
+	v.writeDotContainers(&result, network)
+	v.writeDotDatabases(&result, network)
+	
+	// Add Global Accelerator and CloudFront ingress
+	v.writeDotEdgeIngress(&result, network)
+	
+	// Arrange the graph
+	v.writeDotLayout(&result, network)
+	
+	// Add traffic paths
+	v.writeTrafficPaths(&result, network)
+	v.writeObservedFlows(&result)
+	
+	if v.legend {
+		v.writeDotLegend(&result, network)
+		v.writeDotFooter(&result, network)
+	}
+	
 	result.WriteString("}\n")
 	return result.String()
 }
\ No newline at end of file
@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -146,4 +148,1147 @@ func TestGenerateDotGraph(t *testing.T) {
 	if !strings.HasSuffix(strings.TrimSpace(result), "}") {
 		t.Error("Expected DOT graph to end with '}'")
 	}
-}
\ No newline at end of file
+}
+func TestDetailedAnnotations(t *testing.T) {
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", CidrBlock: "10.0.0.0/16"},
+		},
+		PeeringConnections: []scanner.PeeringConnection{
+			{ID: "pcx-12345", RequesterVpcID: "vpc-12345", AccepterVpcID: "vpc-67890", Status: "active"},
+		},
+		TransitGateways: []scanner.TransitGateway{
+			{
+				ID:    "tgw-12345",
+				State: "available",
+				Attachments: []scanner.TransitGatewayAttachment{
+					{ID: "tgw-attach-1", ResourceID: "vpc-12345", ResourceType: "vpc", State: "available"},
+					{ID: "tgw-attach-2", ResourceID: "vpn-12345", ResourceType: "vpn", State: "available"},
+				},
+			},
+		},
+	}
+
+	v := NewVisualizer("text")
+	result, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if strings.Contains(result, peeringNonTransitiveNote) {
+		t.Error("Expected annotations to be omitted without detail output")
+	}
+
+	v.SetDetailed(true)
+	result, err = v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, note := range []string{peeringNonTransitiveNote, tgwVPCBandwidthNote, tgwVPNBandwidthNote} {
+		if !strings.Contains(result, note) {
+			t.Errorf("Expected detail output to contain %q", note)
+		}
+	}
+
+	v = NewVisualizer("dot")
+	v.SetDetailed(true)
+	result, err = v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if !strings.Contains(result, "tooltip=") {
+		t.Error("Expected DOT edges to carry annotation tooltips in detail output")
+	}
+}
+
+func TestDotTrafficPaths(t *testing.T) {
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", CidrBlock: "10.0.0.0/16"},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-public", VpcID: "vpc-12345", Type: "public", RouteTableID: "rtb-public"},
+			{ID: "subnet-private", VpcID: "vpc-12345", Type: "private", RouteTableID: "rtb-private"},
+		},
+		InternetGateways: []scanner.InternetGateway{
+			{ID: "igw-12345", VpcID: "vpc-12345"},
+		},
+		NATGateways: []scanner.NATGateway{
+			{ID: "nat-12345", VpcID: "vpc-12345", SubnetID: "subnet-public"},
+		},
+		RouteTables: []scanner.RouteTable{
+			{ID: "rtb-public", VpcID: "vpc-12345", Routes: []scanner.Route{{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-12345"}}},
+			{ID: "rtb-private", VpcID: "vpc-12345", Routes: []scanner.Route{{DestinationCidr: "0.0.0.0/0", NatGatewayID: "nat-12345"}}},
+		},
+	}
+
+	v := NewVisualizer("dot")
+	result, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{
+		`"internet" -> "igw-12345" [label="ingress"`,
+		`"igw-12345" -> "subnet-public" [label="ingress"`,
+		`"subnet-private" -> "nat-12345" [label="egress"`,
+		`"nat-12345" -> "igw-12345" [label="egress"`,
+		`"igw-12345" -> "internet" [label="egress"`,
+	}
+
+	for _, edge := range expected {
+		if strings.Count(result, edge) != 1 {
+			t.Errorf("Expected DOT graph to contain edge %s exactly once", edge)
+		}
+	}
+
+	if strings.Contains(result, `"subnet-private" [label="ingress"`) || strings.Contains(result, `-> "subnet-private" [label="ingress"`) {
+		t.Error("Expected no ingress path to the private subnet")
+	}
+}
+
+func TestEgressOnlyInternetGateways(t *testing.T) {
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", Ipv6CidrBlocks: []string{"2600:1f18::/56"}, Subnets: []string{"subnet-ipv6"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-ipv6", VpcID: "vpc-12345", Ipv6CidrBlocks: []string{"2600:1f18::/64"}, Type: "private", RouteTableID: "rtb-ipv6"},
+		},
+		EgressOnlyInternetGateways: []scanner.EgressOnlyInternetGateway{
+			{ID: "eigw-12345", Name: "ipv6-out", VpcID: "vpc-12345", State: "attached"},
+		},
+		RouteTables: []scanner.RouteTable{
+			{ID: "rtb-ipv6", VpcID: "vpc-12345", Routes: []scanner.Route{{DestinationIpv6Cidr: "::/0", EgressOnlyGatewayID: "eigw-12345"}}},
+		},
+	}
+
+	text, err := NewVisualizer("text").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, want := range []string{"└── Egress-only Internet Gateway: ipv6-out [attached]", "Egress-only Internet Gateways: 1"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected text output to contain %q, got:\n%s", want, text)
+		}
+	}
+
+	dot, err := NewVisualizer("dot").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, want := range []string{
+		`"eigw-12345" [label="ipv6-out\nEgress-only Internet Gateway"`,
+		`"eigw-12345" -> "vpc-12345" [label="attached"]`,
+		`"subnet-ipv6" -> "eigw-12345" [label="egress"`,
+		`"eigw-12345" -> "internet" [label="egress"`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected DOT graph to contain %s", want)
+		}
+	}
+	if strings.Contains(dot, `-> "eigw-12345" [label="ingress"`) {
+		t.Error("Expected no ingress path through the egress-only internet gateway")
+	}
+
+	mermaid, err := NewVisualizer("mermaid").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(mermaid, `eigw_12345(["ipv6-out<br/>Egress-only Internet Gateway"]):::eigw`) {
+		t.Errorf("Expected Mermaid output to contain the egress-only internet gateway, got:\n%s", mermaid)
+	}
+}
+
+func TestMeshVirtualGateways(t *testing.T) {
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		MeshVirtualGateways: []scanner.MeshVirtualGateway{
+			{
+				Arn:       "arn:aws:appmesh:us-east-1:111111111111:mesh/payments/virtualGateway/ingress",
+				Name:      "ingress",
+				MeshName:  "payments",
+				Status:    "active",
+				Listeners: []scanner.MeshListener{{Port: 8443, Protocol: "http2", TLS: true}},
+			},
+		},
+	}
+
+	text, err := NewVisualizer("text").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(text, "└── Virtual Gateway: ingress (mesh payments) [active] Listeners: http2:8443 (tls)") {
+		t.Errorf("Expected text graph to list the virtual gateway, got:\n%s", text)
+	}
+
+	dot, err := NewVisualizer("dot").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(dot, `[label="ingress\nVirtual Gateway (payments)\nhttp2:8443 (tls)", shape=hexagon`) {
+		t.Errorf("Expected DOT graph to contain the virtual gateway node, got:\n%s", dot)
+	}
+}
+
+func TestContainers(t *testing.T) {
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", CidrBlock: "10.0.0.0/16", Subnets: []string{"subnet-a"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-a", Name: "app-a", VpcID: "vpc-12345", CidrBlock: "10.0.1.0/24", Type: "private"},
+		},
+		EKSClusters: []scanner.EKSCluster{
+			{Name: "prod", Arn: "arn:aws:eks:us-east-1:111111111111:cluster/prod", Version: "1.30", Status: "ACTIVE",
+				VpcID: "vpc-12345", SubnetIDs: []string{"subnet-a"}, EndpointPrivateAccess: true},
+		},
+		ECSServices: []scanner.ECSService{
+			{Name: "api", Arn: "arn:aws:ecs:us-east-1:111111111111:service/web/api", ClusterName: "web", LaunchType: "FARGATE",
+				DesiredCount: 2, RunningCount: 2, VpcID: "vpc-12345", SubnetIDs: []string{"subnet-a"}},
+		},
+	}
+
+	text, err := NewVisualizer("text").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, line := range []string{
+		"├── EKS Cluster: prod v1.30 [ACTIVE] Endpoint:private Subnets: app-a",
+		"└── ECS Service: api (cluster web) [FARGATE] Tasks:2/2 Subnets: app-a",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("Expected text graph to contain %q, got:\n%s", line, text)
+		}
+	}
+
+	dot, err := NewVisualizer("dot").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(dot, `"arn:aws:ecs:us-east-1:111111111111:service/web/api" -> "subnet-a" [style=dotted, label="in"];`) {
+		t.Errorf("Expected DOT graph to place the ECS service in its subnet, got:\n%s", dot)
+	}
+
+	mermaid, err := NewVisualizer("mermaid").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(mermaid, "    arn_aws_eks_us_east_1_111111111111_cluster_prod[[\"prod<br/>EKS Cluster v1.30<br/>Endpoint: private\"]]:::container") {
+		t.Errorf("Expected Mermaid graph to nest the EKS cluster in its VPC, got:\n%s", mermaid)
+	}
+}
+
+func TestDatabases(t *testing.T) {
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", CidrBlock: "10.0.0.0/16", Subnets: []string{"subnet-a", "subnet-b"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-a", Name: "data-a", VpcID: "vpc-12345", CidrBlock: "10.0.1.0/24", Type: "private"},
+			{ID: "subnet-b", Name: "data-b", VpcID: "vpc-12345", CidrBlock: "10.0.2.0/24", Type: "private"},
+		},
+		SecurityGroups: []scanner.SecurityGroup{
+			{ID: "sg-db", Name: "db", VpcID: "vpc-12345", IngressRules: []scanner.SecurityGroupRule{
+				{IpProtocol: "tcp", FromPort: 5432, ToPort: 5432, ReferencedGroups: []scanner.SecurityGroupReference{{GroupID: "sg-api"}}},
+				{IpProtocol: "tcp", FromPort: 5432, ToPort: 5432, CidrBlocks: []string{"10.1.0.0/16"}},
+				{IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"0.0.0.0/0"}},
+			}},
+			{ID: "sg-api", Name: "api", VpcID: "vpc-12345"},
+		},
+		ECSServices: []scanner.ECSService{
+			{Name: "api", Arn: "arn:aws:ecs:us-east-1:111111111111:service/web/api", ClusterName: "web",
+				VpcID: "vpc-12345", SubnetIDs: []string{"subnet-a"}, SecurityGroupIDs: []string{"sg-api"}},
+		},
+		Databases: []scanner.Database{
+			{ID: "orders", Service: "rds", Engine: "aurora-postgresql", Status: "available", Endpoint: "orders.cluster.local", Port: 5432,
+				VpcID: "vpc-12345", SubnetIDs: []string{"subnet-a", "subnet-b"}, SecurityGroupIDs: []string{"sg-db"}},
+		},
+	}
+
+	text, err := NewVisualizer("text").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	line := "└── RDS orders (aurora-postgresql) orders.cluster.local:5432 [available] Subnets: data-a, data-b From: 10.1.0.0/16, api"
+	if !strings.Contains(text, line) {
+		t.Errorf("Expected text graph to contain %q, got:\n%s", line, text)
+	}
+
+	dot, err := NewVisualizer("dot").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, edge := range []string{
+		`"db:rds:orders" -> "subnet-b" [style=dotted, label="in"];`,
+		`"arn:aws:ecs:us-east-1:111111111111:service/web/api" -> "db:rds:orders" [label="5432", color=saddlebrown];`,
+	} {
+		if !strings.Contains(dot, edge) {
+			t.Errorf("Expected DOT graph to contain %q, got:\n%s", edge, dot)
+		}
+	}
+
+	mermaid, err := NewVisualizer("mermaid").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(mermaid, "    db_rds_orders[(\"orders<br/>RDS aurora-postgresql<br/>port 5432\")]:::database") {
+		t.Errorf("Expected Mermaid graph to nest the database in its VPC, got:\n%s", mermaid)
+	}
+}
+
+func TestEdgeIngress(t *testing.T) {
+	albArn := "arn:aws:elasticloadbalancing:us-east-1:111111111111:loadbalancer/app/web/abc"
+	alb := scanner.EdgeTarget{ID: albArn, Name: "web", Type: "application", Address: "web-123.us-east-1.elb.amazonaws.com",
+		VpcID: "vpc-12345", SubnetIDs: []string{"subnet-a"}}
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", CidrBlock: "10.0.0.0/16", Subnets: []string{"subnet-a"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-a", Name: "public-a", VpcID: "vpc-12345", CidrBlock: "10.0.1.0/24", Type: "public"},
+		},
+		EdgeIngresses: []scanner.EdgeIngress{
+			{ID: "E1", Service: "cloudfront", DNSName: "d111.cloudfront.net", Aliases: []string{"www.example.com"}, Enabled: true,
+				Targets: []scanner.EdgeTarget{alb}},
+			{ID: "arn:aws:globalaccelerator::111111111111:accelerator/web", Name: "web", Service: "globalaccelerator",
+				DNSName: "a123.awsglobalaccelerator.com", Targets: []scanner.EdgeTarget{alb}},
+		},
+	}
+
+	text, err := NewVisualizer("text").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, line := range []string{
+		"├── CloudFront: www.example.com (d111.cloudfront.net) → ALB web",
+		"└── Global Accelerator: web (a123.awsglobalaccelerator.com) [Disabled] → ALB web",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("Expected text graph to contain %q, got:\n%s", line, text)
+		}
+	}
+
+	dot, err := NewVisualizer("dot").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if strings.Count(dot, "shape=invtrapezium") != 1 {
+		t.Errorf("Expected one node for the shared load balancer, got:\n%s", dot)
+	}
+	if !strings.Contains(dot, `"E1" -> "`+albArn+`" [label="routes", color=purple];`) {
+		t.Errorf("Expected DOT graph to route the distribution to the load balancer, got:\n%s", dot)
+	}
+
+	mermaid, err := NewVisualizer("mermaid").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(mermaid, "  E1{{\"www.example.com<br/>CloudFront\"}}:::edge") {
+		t.Errorf("Expected Mermaid graph to contain the distribution, got:\n%s", mermaid)
+	}
+}
+
+func TestCrossRegionAnnotations(t *testing.T) {
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", CidrBlock: "10.0.0.0/16"},
+		},
+		PeeringConnections: []scanner.PeeringConnection{
+			{ID: "pcx-12345", RequesterVpcID: "vpc-12345", AccepterVpcID: "vpc-67890", Status: "active",
+				RequesterRegion: "us-east-1", AccepterRegion: "eu-west-1"},
+		},
+		TransitGateways: []scanner.TransitGateway{
+			{
+				ID:    "tgw-12345",
+				State: "available",
+				Attachments: []scanner.TransitGatewayAttachment{
+					{ID: "tgw-attach-1", ResourceID: "tgw-67890", ResourceType: "peering", State: "available",
+						PeerTransitGatewayID: "tgw-67890", PeerRegion: "us-west-2"},
+				},
+			},
+		},
+	}
+
+	v := NewVisualizer("text")
+	v.SetDetailed(true)
+	result, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, want := range []string{"region pair: us-east-1 ↔ eu-west-1", "region pair: us-east-1 ↔ us-west-2", latencyHigh, latencyMedium} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected detail output to contain %q", want)
+		}
+	}
+
+	v = NewVisualizer("dot")
+	v.SetDetailed(true)
+	result, err = v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if !strings.Contains(result, `"tgw-12345" -> "tgw-67890"`) {
+		t.Error("Expected DOT output to draw the transit gateway peering in detail output")
+	}
+}
+
+// renderNetwork returns a VPC with a public subnet behind an internet gateway, peered to another VPC
+func renderNetwork() *scanner.Network {
+	return &scanner.Network{
+		Region:    "us-east-1",
+		AccountID: "123456789012",
+		ScanTime:  time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", Name: `Prod "main"`, CidrBlock: "10.0.0.0/16"},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-12345", VpcID: "vpc-12345", CidrBlock: "10.0.1.0/24", Type: "public", RouteTableID: "rtb-12345"},
+		},
+		InternetGateways: []scanner.InternetGateway{
+			{ID: "igw-12345", VpcID: "vpc-12345"},
+		},
+		RouteTables: []scanner.RouteTable{
+			{ID: "rtb-12345", VpcID: "vpc-12345", Routes: []scanner.Route{{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-12345"}}},
+		},
+		PeeringConnections: []scanner.PeeringConnection{
+			{ID: "pcx-12345", RequesterVpcID: "vpc-12345", AccepterVpcID: "vpc-67890", Status: "active"},
+		},
+	}
+}
+
+func TestGenerateMermaidGraph(t *testing.T) {
+	v := NewVisualizer("mermaid")
+	result, err := v.Generate(renderNetwork())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{
+		"flowchart TB",
+		`subgraph vpc_12345["Prod #quot;main#quot;<br/>10.0.0.0/16"]`,
+		`subnet_12345["subnet-12345<br/>10.0.1.0/24<br/>Public"]:::public`,
+		"igw_12345 ---|attached| vpc_12345",
+		`vpc_12345 <-->|"pcx-12345 [active]"| vpc_67890`,
+		"internet ==>|ingress| igw_12345",
+		"classDef public",
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected Mermaid output to contain %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestGenerateHTMLPage(t *testing.T) {
+	v := NewVisualizer("html")
+	result, err := v.Generate(renderNetwork())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{
+		"<!DOCTYPE html>",
+		"Account: 123456789012",
+		"<tr><th>VPCs</th><td>1</td></tr>",
+		`<pre class="mermaid">`,
+		"subnet-12345&lt;br/&gt;10.0.1.0/24",
+		mermaidScriptURL,
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected HTML output to contain %q", want)
+		}
+	}
+
+	if strings.Contains(result, `Prod "main"`) {
+		t.Error("Expected resource names to be HTML escaped")
+	}
+}
+
+func TestObservedFlows(t *testing.T) {
+	flows := []ObservedFlow{
+		{From: "internet", To: "subnet-12345", Label: "4.0 MiB", Weight: 1},
+		{From: "subnet-12345", To: "external", Label: "1.0 MiB", Weight: 0.25},
+	}
+
+	v := NewVisualizer("dot")
+	v.SetObservedFlows(flows)
+	result, err := v.Generate(renderNetwork())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{
+		"// Observed Traffic",
+		`"external" [label="External (private)"`,
+		`"internet" -> "subnet-12345" [label="4.0 MiB", penwidth=8.0`,
+		`"subnet-12345" -> "external" [label="1.0 MiB", penwidth=2.8`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected DOT output to contain %q, got:\n%s", want, result)
+		}
+	}
+
+	v = NewVisualizer("mermaid")
+	v.SetObservedFlows(flows)
+	result, err = v.Generate(renderNetwork())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(result, `internet -.->|"observed 4.0 MiB"| subnet_12345`) {
+		t.Errorf("Expected Mermaid output to contain the observed flow, got:\n%s", result)
+	}
+
+	// Without observed flows the graph is unchanged
+	result, _ = NewVisualizer("dot").Generate(renderNetwork())
+	if strings.Contains(result, "Observed Traffic") {
+		t.Error("Did not expect observed traffic without flows")
+	}
+}
+
+func TestSubnetTypeRoute(t *testing.T) {
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", CidrBlock: "10.0.0.0/16", Ipv6CidrBlocks: []string{"2600:1f18:1:100::/56"}, Subnets: []string{"subnet-a"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-a", Name: "public-a", VpcID: "vpc-12345", CidrBlock: "10.0.1.0/24", Type: "public",
+				TypeRoute: &scanner.Route{DestinationCidr: "203.0.113.0/24", GatewayID: "igw-12345"}},
+		},
+	}
+
+	v := NewVisualizer("text")
+	text, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(text, "VPC: vpc-12345 (10.0.0.0/16, 2600:1f18:1:100::/56)") || !strings.Contains(text, "public-a (10.0.1.0/24) [Public]") {
+		t.Errorf("Expected the VPC's IPv6 block and the plain subnet type, got:\n%s", text)
+	}
+	if strings.Contains(text, "via 203.0.113.0/24") {
+		t.Errorf("Expected the deciding route only in detail output, got:\n%s", text)
+	}
+
+	v.SetDetailed(true)
+	text, err = v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(text, "[Public via 203.0.113.0/24 → igw-12345]") {
+		t.Errorf("Expected detail output to explain the subnet type, got:\n%s", text)
+	}
+}
+
+func TestSubnetUtilization(t *testing.T) {
+	available := 51
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", CidrBlock: "10.0.0.0/16", Subnets: []string{"subnet-a", "subnet-b"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-a", VpcID: "vpc-12345", CidrBlock: "10.0.1.0/24", AvailableIPs: &available},
+			{ID: "subnet-b", VpcID: "vpc-12345", CidrBlock: "10.0.2.0/24"},
+		},
+	}
+
+	text, err := NewVisualizer("text").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(text, "subnet-a (10.0.1.0/24) Used:200/251 IPs (80%)") {
+		t.Errorf("Expected the subnet's utilization, got:\n%s", text)
+	}
+	if strings.Count(text, "Used:") != 1 {
+		t.Errorf("Expected no utilization for a subnet without an available count, got:\n%s", text)
+	}
+}
+
+func TestDetailLevels(t *testing.T) {
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", CidrBlock: "10.0.0.0/16", Subnets: []string{"subnet-a", "subnet-b"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-a", VpcID: "vpc-12345", CidrBlock: "10.0.1.0/24", AvailabilityZone: "us-east-1a", RouteTableID: "rtb-public"},
+			{ID: "subnet-b", VpcID: "vpc-12345", CidrBlock: "10.0.2.0/24", AvailabilityZone: "us-east-1b", RouteTableID: "rtb-main"},
+		},
+		RouteTables: []scanner.RouteTable{
+			{ID: "rtb-public", VpcID: "vpc-12345", Routes: []scanner.Route{
+				{DestinationCidr: "10.0.0.0/16", GatewayID: "local", State: "active"},
+				{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-12345", State: "active"},
+			}},
+			{ID: "rtb-main", Name: "main", VpcID: "vpc-12345", IsMain: true, Routes: []scanner.Route{
+				{DestinationCidr: "10.0.0.0/16", GatewayID: "local", State: "active"},
+				{DestinationCidr: "192.168.0.0/16", VpcPeeringID: "pcx-12345", State: "blackhole"},
+			}},
+		},
+		SecurityGroups: []scanner.SecurityGroup{
+			{ID: "sg-12345", Name: "web", VpcID: "vpc-12345",
+				IngressRules: []scanner.SecurityGroupRule{{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}, Description: "HTTPS"}},
+				EgressRules:  []scanner.SecurityGroupRule{{IpProtocol: "-1", CidrBlocks: []string{"0.0.0.0/0"}}},
+			},
+			{ID: "sg-other", VpcID: "vpc-other"},
+		},
+		InternetGateways: []scanner.InternetGateway{{ID: "igw-12345", VpcID: "vpc-12345", State: "attached"}},
+	}
+
+	generate := func(level DetailLevel) string {
+		v := NewVisualizer("text")
+		v.SetDetailLevel(level)
+		text, err := v.Generate(network)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		return text
+	}
+
+	minimal := generate(DetailMinimal)
+	if !strings.Contains(minimal, "└── Subnet: subnet-b (10.0.2.0/24)\n") || strings.Contains(minimal, "AZ:") || strings.Contains(minimal, "Internet Gateway:") {
+		t.Errorf("Expected only VPCs and subnets at the minimal level, got:\n%s", minimal)
+	}
+
+	normal := generate(DetailNormal)
+	if !strings.Contains(normal, "Internet Gateway: igw-12345") || strings.Contains(normal, "Route Table") || strings.Contains(normal, "Security Group") {
+		t.Errorf("Expected no route tables or security groups at the normal level, got:\n%s", normal)
+	}
+
+	full := generate(DetailFull)
+	for _, expected := range []string{
+		"├── Subnet: subnet-a (10.0.1.0/24) AZ:us-east-1a\n│   └── Route Table: rtb-public\n│       ├── 0.0.0.0/0 → igw-12345\n│       └── 10.0.0.0/16 → local\n",
+		"│   └── Route Table: main [Main]\n│       ├── 10.0.0.0/16 → local\n│       └── 192.168.0.0/16 → pcx-12345 [blackhole]\n",
+		"└── Security Group: web (sg-12345)\n    ├── Inbound: tcp 443 from 0.0.0.0/0 (HTTPS)\n    └── Outbound: all traffic to 0.0.0.0/0\n",
+	} {
+		if !strings.Contains(full, expected) {
+			t.Errorf("Expected %q at the full level, got:\n%s", expected, full)
+		}
+	}
+	if strings.Contains(full, "sg-other") {
+		t.Errorf("Expected only the VPC's own security groups, got:\n%s", full)
+	}
+
+	if _, err := ParseDetailLevel("verbose"); err == nil {
+		t.Error("Expected an error for an unknown detail level")
+	}
+}
+
+func TestTextColors(t *testing.T) {
+	network := &scanner.Network{
+		Region:   "us-east-1",
+		ScanTime: time.Now(),
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", CidrBlock: "10.0.0.0/16", Subnets: []string{"subnet-public", "subnet-private", "subnet-isolated"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-public", VpcID: "vpc-12345", CidrBlock: "10.0.1.0/24", Type: "public"},
+			{ID: "subnet-private", VpcID: "vpc-12345", CidrBlock: "10.0.2.0/24", Type: "private"},
+			{ID: "subnet-isolated", VpcID: "vpc-12345", CidrBlock: "10.0.3.0/24", Type: "isolated"},
+		},
+		NATGateways: []scanner.NATGateway{
+			{ID: "nat-live", VpcID: "vpc-12345", State: "available"},
+			{ID: "nat-gone", VpcID: "vpc-12345", State: "deleted"},
+		},
+	}
+
+	v := NewVisualizer("text")
+	plain, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if strings.Contains(plain, "\x1b[") {
+		t.Errorf("Expected no colors by default, got:\n%q", plain)
+	}
+
+	v.SetColor(true)
+	colored, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, expected := range []string{
+		"subnet-public (10.0.1.0/24) \x1b[32m[Public]\x1b[0m",
+		"subnet-private (10.0.2.0/24) [Private]",
+		"subnet-isolated (10.0.3.0/24) \x1b[31m[Isolated]\x1b[0m",
+		"NAT Gateway: nat-live [available]",
+		"NAT Gateway: nat-gone \x1b[33m[deleted]\x1b[0m",
+	} {
+		if !strings.Contains(colored, expected) {
+			t.Errorf("Expected %q in the colored text graph, got:\n%q", expected, colored)
+		}
+	}
+
+	html := NewVisualizer("html")
+	html.SetColor(true)
+	page, err := html.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if strings.Contains(page, "\x1b[") {
+		t.Error("Expected the HTML page's text tree not to be colored")
+	}
+}
+
+func TestDotLayouts(t *testing.T) {
+	network := &scanner.Network{
+		Region: "us-east-1",
+		VPCs: []scanner.VPC{
+			{ID: "vpc-hub", Name: "shared", CidrBlock: "10.0.0.0/16"},
+			{ID: "vpc-spoke", CidrBlock: "10.1.0.0/16"},
+			{ID: "vpc-lone", CidrBlock: "10.2.0.0/16"},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-a1", VpcID: "vpc-hub", CidrBlock: "10.0.1.0/24", AvailabilityZone: "us-east-1a", Type: "public"},
+			{ID: "subnet-a2", VpcID: "vpc-hub", CidrBlock: "10.0.2.0/24", AvailabilityZone: "us-east-1a", Type: "private"},
+			{ID: "subnet-b1", VpcID: "vpc-hub", CidrBlock: "10.0.3.0/24", AvailabilityZone: "us-east-1b", Type: "private"},
+		},
+		InternetGateways: []scanner.InternetGateway{{ID: "igw-1", VpcID: "vpc-hub"}},
+		NATGateways:      []scanner.NATGateway{{ID: "nat-1", SubnetID: "subnet-a1"}},
+		PeeringConnections: []scanner.PeeringConnection{
+			{ID: "pcx-1", RequesterVpcID: "vpc-hub", AccepterVpcID: "vpc-lone", Status: "active"},
+		},
+		TransitGateways: []scanner.TransitGateway{{ID: "tgw-1", Attachments: []scanner.TransitGatewayAttachment{
+			{ResourceType: "vpc", ResourceID: "vpc-hub", State: "available"},
+			{ResourceType: "vpc", ResourceID: "vpc-spoke", State: "available"},
+		}}},
+	}
+
+	v := NewVisualizer("dot")
+	result, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(result, "rankdir=TB;") || strings.Contains(result, "subgraph") || strings.Contains(result, "constraint=false") {
+		t.Errorf("Expected the hierarchy layout by default, got:\n%s", result)
+	}
+
+	v.SetLayout(LayoutAZColumns)
+	result, err = v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, want := range []string{
+		"subgraph \"cluster_vpc-hub\" {",
+		"subgraph \"cluster_vpc-hub_us-east-1a\" {",
+		"      \"nat-1\";",
+		"\"subnet-a1\" -> \"subnet-a2\" [style=invis];",
+		"{ rank=same; \"subnet-a1\"; \"subnet-b1\"; }",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected az-columns layout to contain %q, got:\n%s", want, result)
+		}
+	}
+
+	v.SetLayout(LayoutHubSpoke)
+	result, err = v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, want := range []string{
+		"rankdir=LR;",
+		"{ rank=same; \"tgw-1\"; }",
+		"{ rank=same; \"vpc-lone\"; \"vpc-spoke\"; \"vpc-hub\"; }",
+		"\"tgw-1\" -> \"vpc-lone\" [style=invis];",
+		"\"vpc-hub\" -> \"igw-1\" [style=invis];",
+		"color=blue, constraint=false];",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected hub-spoke layout to contain %q, got:\n%s", want, result)
+		}
+	}
+
+	if _, err := ParseLayout("radial"); err == nil {
+		t.Error("Expected an unsupported layout to fail")
+	}
+}
+
+func TestDotRoutedCIDRs(t *testing.T) {
+	network := &scanner.Network{
+		Region: "us-east-1",
+		VPCs: []scanner.VPC{
+			{ID: "vpc-a", CidrBlock: "10.0.0.0/16"},
+			{ID: "vpc-b", CidrBlock: "10.1.0.0/16"},
+		},
+		RouteTables: []scanner.RouteTable{
+			{ID: "rtb-a1", VpcID: "vpc-a", Routes: []scanner.Route{
+				{DestinationCidr: "10.1.0.0/16", VpcPeeringID: "pcx-1", State: "active"},
+				{DestinationCidr: "10.9.0.0/16", VpcPeeringID: "pcx-1", State: "blackhole"},
+				{DestinationCidr: "172.16.0.0/12", TransitGatewayID: "tgw-1", State: "active"},
+			}},
+			{ID: "rtb-a2", VpcID: "vpc-a", Routes: []scanner.Route{
+				{DestinationCidr: "10.1.0.0/16", VpcPeeringID: "pcx-1", State: "active"},
+			}},
+			{ID: "rtb-b", VpcID: "vpc-b", Routes: []scanner.Route{
+				{DestinationCidr: "10.0.1.0/24", VpcPeeringID: "pcx-1", State: "active"},
+				{DestinationCidr: "10.0.2.0/24", VpcPeeringID: "pcx-1", State: "active"},
+			}},
+		},
+		PeeringConnections: []scanner.PeeringConnection{
+			{ID: "pcx-1", RequesterVpcID: "vpc-a", AccepterVpcID: "vpc-b", Status: "active"},
+		},
+		TransitGateways: []scanner.TransitGateway{{
+			ID: "tgw-1",
+			Attachments: []scanner.TransitGatewayAttachment{
+				{ID: "tgw-attach-a", ResourceType: "vpc", ResourceID: "vpc-a", State: "available"},
+				{ID: "tgw-attach-b", ResourceType: "vpc", ResourceID: "vpc-b", State: "available"},
+			},
+			RouteTables: []scanner.TransitGatewayRouteTable{{ID: "tgw-rtb-1", Routes: []scanner.TransitGatewayRoute{
+				{DestinationCidr: "10.0.0.0/16", AttachmentID: "tgw-attach-a", State: "active"},
+				{DestinationCidr: "10.0.0.0/8", AttachmentID: "tgw-attach-a", State: "blackhole"},
+			}}},
+		}},
+	}
+
+	result, err := NewVisualizer("dot").Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, want := range []string{
+		`"vpc-a" -> "vpc-b" [label="pcx-1\n[active]\n→ 10.1.0.0/16\n← 10.0.1.0/24, 10.0.2.0/24"`,
+		`"tgw-1" -> "vpc-a" [label="attached\n→ 10.0.0.0/16\n← 172.16.0.0/12"`,
+		`"tgw-1" -> "vpc-b" [label="attached"`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected DOT graph to contain %s, got:\n%s", want, result)
+		}
+	}
+
+	if got := cidrSummary([]string{"a", "b", "c", "d", "e", "f"}); got != "a, b, c, d +2 more" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+}
+
+func TestLegend(t *testing.T) {
+	network := &scanner.Network{
+		Region:    "us-east-1",
+		AccountID: "123456789012",
+		ScanTime:  time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC),
+		VPCs:      []scanner.VPC{{ID: "vpc-1", CidrBlock: "10.0.0.0/16"}},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-1", VpcID: "vpc-1", CidrBlock: "10.0.1.0/24", Type: "private"},
+		},
+		NATGateways: []scanner.NATGateway{{ID: "nat-1", SubnetID: "subnet-1"}},
+	}
+
+	v := NewVisualizer("dot")
+	result, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if strings.Contains(result, "Legend") {
+		t.Error("Expected no legend by default")
+	}
+
+	v.SetLegend(true)
+	v.SetToolVersion("v1.2.3")
+	result, err = v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, want := range []string{
+		"subgraph \"cluster_legend\" {",
+		"[label=\"Private subnet\", fillcolor=lightyellow];",
+		"[label=\"NAT Gateway\", fillcolor=gold];",
+		"label=\"Account: 123456789012 · Region: us-east-1 · Scan Time: 2024-05-01 09:30:00 UTC · pikaatools v1.2.3\";",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected DOT graph to contain %q, got:\n%s", want, result)
+		}
+	}
+	// Only resources in the network are in the legend
+	if strings.Contains(result, "Public subnet") || strings.Contains(result, "Transit Gateway") {
+		t.Errorf("Expected only the resources in the network in the legend, got:\n%s", result)
+	}
+
+	v = NewVisualizer("html")
+	v.SetLegend(true)
+	result, err = v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(result, "<td style=\"background: #ffd700\"></td><td>NAT Gateway</td>") || !strings.Contains(result, "pikaatools dev</footer>") {
+		t.Errorf("Expected the HTML legend and footer, got:\n%s", result)
+	}
+}
+
+func TestFocusAndHide(t *testing.T) {
+	network := func() *scanner.Network {
+		return &scanner.Network{
+			Region: "us-east-1",
+			VPCs: []scanner.VPC{
+				{ID: "vpc-app", CidrBlock: "10.0.0.0/16"},
+				{ID: "vpc-peer", CidrBlock: "10.1.0.0/16"},
+				{ID: "vpc-spoke", CidrBlock: "10.2.0.0/16"},
+				{ID: "vpc-far", CidrBlock: "10.3.0.0/16"},
+			},
+			Subnets: []scanner.Subnet{
+				{ID: "subnet-app", VpcID: "vpc-app", CidrBlock: "10.0.1.0/24", Type: "private"},
+				{ID: "subnet-far", VpcID: "vpc-far", CidrBlock: "10.3.1.0/24", Type: "private"},
+			},
+			InternetGateways: []scanner.InternetGateway{{ID: "igw-app", VpcID: "vpc-app"}},
+			PeeringConnections: []scanner.PeeringConnection{
+				{ID: "pcx-1", RequesterVpcID: "vpc-app", AccepterVpcID: "vpc-peer", Status: "active"},
+				{ID: "pcx-2", RequesterVpcID: "vpc-spoke", AccepterVpcID: "vpc-far", Status: "active"},
+			},
+			TransitGateways: []scanner.TransitGateway{
+				{ID: "tgw-1", Attachments: []scanner.TransitGatewayAttachment{
+					{ResourceType: "vpc", ResourceID: "vpc-app", State: "available"},
+					{ResourceType: "vpc", ResourceID: "vpc-spoke", State: "available"},
+				}},
+				{ID: "tgw-unrelated"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		depth    int
+		hidden   []string
+		contains []string
+		excludes []string
+	}{
+		{"direct peers", 1, nil,
+			[]string{`"vpc-peer"`, `"tgw-1" [`, `"igw-app"`},
+			[]string{`"vpc-spoke"`, `"vpc-far"`, `"tgw-unrelated"`}},
+		{"shared transit gateway", 2, nil,
+			[]string{`"tgw-1" -> "vpc-spoke"`},
+			[]string{`"subnet-far"`, `"tgw-unrelated"`}},
+		{"only the VPC", 0, []string{"igw,subnets"},
+			[]string{`"vpc-app" [`},
+			[]string{`"vpc-peer"`, `"tgw-1"`, `"igw-app"`, `"subnet-app"`}},
+	}
+	for _, test := range tests {
+		v := NewVisualizer("dot")
+		v.SetFocus("vpc-app", test.depth)
+		hidden, err := ParseHidden(test.hidden)
+		if err != nil {
+			t.Fatalf("%s: ParseHidden failed: %v", test.name, err)
+		}
+		v.SetHidden(hidden)
+		result, err := v.Generate(network())
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.name, err)
+		}
+		for _, want := range test.contains {
+			if !strings.Contains(result, want) {
+				t.Errorf("%s: expected %s, got:\n%s", test.name, want, result)
+			}
+		}
+		for _, unwanted := range test.excludes {
+			if strings.Contains(result, unwanted) {
+				t.Errorf("%s: expected no %s, got:\n%s", test.name, unwanted, result)
+			}
+		}
+	}
+
+	v := NewVisualizer("text")
+	v.SetFocus("vpc-missing", 1)
+	if _, err := v.Generate(network()); err == nil {
+		t.Error("Expected an error for a VPC that is not in the network")
+	}
+	if _, err := ParseHidden([]string{"subnets,routers"}); err == nil {
+		t.Error("Expected an error for an unknown resource kind")
+	}
+}
+
+func TestColorByTag(t *testing.T) {
+	network := func() *scanner.Network {
+		return &scanner.Network{
+			Region: "us-east-1",
+			VPCs: []scanner.VPC{
+				{ID: "vpc-prod", CidrBlock: "10.0.0.0/16", Tags: map[string]string{"Environment": "prod"}, Subnets: []string{"subnet-prod"}},
+				{ID: "vpc-dev", CidrBlock: "10.1.0.0/16", Tags: map[string]string{"Environment": "dev"}, Subnets: []string{"subnet-untagged"}},
+			},
+			Subnets: []scanner.Subnet{
+				{ID: "subnet-prod", VpcID: "vpc-prod", CidrBlock: "10.0.1.0/24", Type: "public", Tags: map[string]string{"Environment": "prod"}},
+				{ID: "subnet-untagged", VpcID: "vpc-dev", CidrBlock: "10.1.1.0/24", Type: "private"},
+			},
+		}
+	}
+
+	// Values take the palette's colors in sorted order: dev, then prod
+	v := NewVisualizer("dot")
+	v.SetColorByTag("Environment")
+	v.SetLegend(true)
+	result, err := v.Generate(network())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, want := range []string{
+		`"vpc-dev" [label="vpc-dev\n10.1.0.0/16", fillcolor=lightskyblue];`,
+		`"vpc-prod" [label="vpc-prod\n10.0.0.0/16", fillcolor=palegreen];`,
+		`"subnet-untagged" [label="subnet-untagged\n10.1.1.0/24\n[Private]", fillcolor=gray90];`,
+		`[label="Environment=prod", fillcolor=palegreen];`,
+		`[label="no Environment tag", fillcolor=gray90];`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected DOT graph to contain %s, got:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "Public subnet") {
+		t.Error("Expected the subnet type colors to be left out of the legend")
+	}
+
+	v = NewVisualizer("mermaid")
+	v.SetColorByTag("Environment")
+	result, err = v.Generate(network())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, want := range []string{":::tag1\n", ":::untagged\n", "classDef tag0 fill:#87cefa", "style vpc_prod fill:#98fb98"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected Mermaid graph to contain %q, got:\n%s", want, result)
+		}
+	}
+
+	v = NewVisualizer("text")
+	v.SetColorByTag("Environment")
+	result, err = v.Generate(network())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(result, "VPC: vpc-prod (10.0.0.0/16) {Environment=prod}") || !strings.Contains(result, "[Public] {Environment=prod}") {
+		t.Errorf("Expected the tag values in the text graph, got:\n%s", result)
+	}
+
+	if _, err := ParseColorBy("Environment"); err == nil {
+		t.Error("Expected an error without the tag: prefix")
+	}
+}
+
+func TestIcons(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"Architecture-Group-Icons/Virtual-private-cloud-VPC_32.png",
+		"Resource-Icons/Res_Amazon-VPC_Internet-Gateway_48.png",
+		"Resource-Icons/Res_Amazon-VPC_Internet-Gateway_48.svg",
+		"Resource-Icons/Res_Amazon-VPC_Egress-Only-Internet-Gateway_48.png",
+		"Resource-Icons/Res_Amazon-VPC_NAT-Gateway_32.png",
+		"Resource-Icons/Res_Amazon-VPC_NAT-Gateway_48.png",
+		"nat-gateway.svg",
+		"README.txt",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	icons, err := LoadIcons(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for kind, want := range map[string]string{
+		"vpc":                          "Architecture-Group-Icons/Virtual-private-cloud-VPC_32.png",
+		"internet-gateway":             "Resource-Icons/Res_Amazon-VPC_Internet-Gateway_48.png",
+		"egress-only-internet-gateway": "Resource-Icons/Res_Amazon-VPC_Egress-Only-Internet-Gateway_48.png",
+		"nat-gateway":                  "nat-gateway.svg",
+	} {
+		if icons[kind] != filepath.Join(dir, want) {
+			t.Errorf("Expected the %s icon to be %s, got %s", kind, want, icons[kind])
+		}
+	}
+	if _, ok := icons["transit-gateway"]; ok {
+		t.Error("Expected no transit gateway icon")
+	}
+
+	network := &scanner.Network{
+		Region:           "us-east-1",
+		VPCs:             []scanner.VPC{{ID: "vpc-1", CidrBlock: "10.0.0.0/16"}},
+		InternetGateways: []scanner.InternetGateway{{ID: "igw-1", VpcID: "vpc-1"}},
+	}
+	v := NewVisualizer("dot")
+	v.SetIcons(icons)
+	result, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	want := "fillcolor=orange, image=\"" + icons["internet-gateway"] + "\", imagepos=tc, labelloc=b, height=1.4];"
+	if !strings.Contains(result, want) {
+		t.Errorf("Expected DOT graph to contain %q, got:\n%s", want, result)
+	}
+
+	if _, err := LoadIcons(t.TempDir()); err == nil {
+		t.Error("Expected an error for a directory without icons")
+	}
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	RegisterRenderer("test-wiki", RendererFunc(func(v *Visualizer, network *scanner.Network) (string, error) {
+		var lines []string
+		for _, vpc := range network.VPCs {
+			lines = append(lines, "* "+vpc.ID)
+		}
+		for _, subnet := range network.Subnets {
+			lines = append(lines, "** "+subnet.ID)
+		}
+		return strings.Join(lines, "\n") + "\n", nil
+	}))
+	// Leave the registry as other tests and repeated runs expect it
+	t.Cleanup(func() {
+		renderersMu.Lock()
+		defer renderersMu.Unlock()
+		delete(renderers, "test-wiki")
+	})
+
+	found := false
+	for _, format := range Formats() {
+		if format == "test-wiki" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the registered format in %v", Formats())
+	}
+
+	network := &scanner.Network{
+		VPCs:    []scanner.VPC{{ID: "vpc-2"}, {ID: "vpc-1"}},
+		Subnets: []scanner.Subnet{{ID: "subnet-1", VpcID: "vpc-1"}},
+	}
+	v := NewVisualizer("test-wiki")
+	v.SetHidden([]string{"subnets"})
+	result, err := v.Generate(network)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// The renderer gets the sorted and filtered network
+	if result != "* vpc-1\n* vpc-2\n" {
+		t.Errorf("Unexpected output from the registered renderer:\n%s", result)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a format twice to panic")
+		}
+	}()
+	RegisterRenderer("text", RendererFunc(func(v *Visualizer, network *scanner.Network) (string, error) {
+		return "", nil
+	}))
+}
@@ -0,0 +1,173 @@
+// Package iampolicy parses IAM policy documents and summarizes the permissions
+// scanned roles are granted.
+package iampolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Document is a parsed IAM policy document
+type Document struct {
+	Version   string      `json:"Version,omitempty"`
+	Statement []Statement `json:"Statement"`
+}
+
+// Statement is one statement of a policy document
+type Statement struct {
+	Sid         string          `json:"Sid,omitempty"`
+	Effect      string          `json:"Effect"`
+	Action      StringList      `json:"Action,omitempty"`
+	NotAction   StringList      `json:"NotAction,omitempty"`
+	Resource    StringList      `json:"Resource,omitempty"`
+	NotResource StringList      `json:"NotResource,omitempty"`
+	Condition   json.RawMessage `json:"Condition,omitempty"`
+}
+
+// StringList is a policy element written as either a single string or a list of strings
+type StringList []string
+
+// UnmarshalJSON accepts a single string as a list of one
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// UnmarshalJSON accepts a single statement object as a list of one
+func (d *Document) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		Version   string          `json:"Version"`
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	d.Version = decoded.Version
+	d.Statement = nil
+	statements := bytes.TrimSpace(decoded.Statement)
+	switch {
+	case len(statements) == 0 || bytes.Equal(statements, []byte("null")):
+		return nil
+	case statements[0] == '{':
+		var statement Statement
+		if err := json.Unmarshal(statements, &statement); err != nil {
+			return err
+		}
+		d.Statement = []Statement{statement}
+		return nil
+	default:
+		return json.Unmarshal(statements, &d.Statement)
+	}
+}
+
+// Parse parses a policy document as stored in a working state
+func Parse(document string) (*Document, error) {
+	var parsed Document
+	if err := json.Unmarshal([]byte(document), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse policy document: %w", err)
+	}
+	return &parsed, nil
+}
+
+// Allows reports whether the statement allows an action such as "iam:PassRole",
+// on some resource. Conditions are not evaluated.
+func (s Statement) Allows(action string) bool {
+	if !strings.EqualFold(s.Effect, "Allow") {
+		return false
+	}
+	if len(s.NotAction) > 0 {
+		return !matchesAny(s.NotAction, action)
+	}
+	return matchesAny(s.Action, action)
+}
+
+// AllResources reports whether the statement applies to every resource
+func (s Statement) AllResources() bool {
+	if len(s.NotResource) > 0 {
+		return false
+	}
+	for _, resource := range s.Resource {
+		if resource == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WildcardResources returns the statement's resources containing wildcards, or
+// its NotResource entries prefixed with "not ", which match everything else
+func (s Statement) WildcardResources() []string {
+	var resources []string
+	for _, resource := range s.Resource {
+		if strings.ContainsAny(resource, "*?") {
+			resources = append(resources, resource)
+		}
+	}
+	for _, resource := range s.NotResource {
+		resources = append(resources, "not "+resource)
+	}
+	return resources
+}
+
+// Conditional reports whether the statement only applies under conditions
+func (s Statement) Conditional() bool {
+	condition := bytes.TrimSpace(s.Condition)
+	return len(condition) > 0 && !bytes.Equal(condition, []byte("null")) && !bytes.Equal(condition, []byte("{}"))
+}
+
+// matchesAny reports whether any action pattern matches the action
+func matchesAny(patterns []string, action string) bool {
+	for _, pattern := range patterns {
+		if MatchAction(pattern, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAction reports whether an action pattern, which may contain * and ?
+// wildcards, matches an action. Actions are case-insensitive.
+func MatchAction(pattern, action string) bool {
+	return matchWildcard(strings.ToLower(pattern), strings.ToLower(action))
+}
+
+// matchWildcard matches a value against a pattern where * matches any run of
+// characters and ? matches one character
+func matchWildcard(pattern, value string) bool {
+	// Backtrack to the last * on a mismatch
+	p, v := 0, 0
+	star, mark := -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, v
+			p++
+		case star >= 0:
+			p = star + 1
+			mark++
+			v = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
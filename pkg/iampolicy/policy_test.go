@@ -0,0 +1,54 @@
+package iampolicy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	document, err := Parse(`{"Version":"2012-10-17","Statement":{"Effect":"Allow","Action":"ec2:Describe*","Resource":["*"],"Condition":{"StringEquals":{"aws:RequestedRegion":"us-east-1"}}}}`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(document.Statement) != 1 {
+		t.Fatalf("Expected a single statement object to parse as one statement, got %d", len(document.Statement))
+	}
+
+	statement := document.Statement[0]
+	if !reflect.DeepEqual(statement.Action, StringList{"ec2:Describe*"}) || !reflect.DeepEqual(statement.Resource, StringList{"*"}) {
+		t.Errorf("Expected string and list elements to parse as lists, got %v and %v", statement.Action, statement.Resource)
+	}
+	if !statement.Conditional() {
+		t.Error("Expected the statement to be conditional")
+	}
+
+	if _, err := Parse(`{"Statement":[{"Action":1}]}`); err == nil {
+		t.Error("Expected an error for a malformed action")
+	}
+}
+
+func TestStatementAllows(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement Statement
+		action    string
+		expected  bool
+	}{
+		{"exact", Statement{Effect: "Allow", Action: StringList{"iam:PassRole"}}, "iam:PassRole", true},
+		{"case-insensitive", Statement{Effect: "Allow", Action: StringList{"IAM:passrole"}}, "iam:PassRole", true},
+		{"wildcard", Statement{Effect: "Allow", Action: StringList{"ec2:*Route*"}}, "ec2:ReplaceRouteTableAssociation", true},
+		{"single character", Statement{Effect: "Allow", Action: StringList{"ec2:?eleteRoute"}}, "ec2:DeleteRoute", true},
+		{"other action", Statement{Effect: "Allow", Action: StringList{"ec2:Describe*"}}, "ec2:CreateRoute", false},
+		{"deny", Statement{Effect: "Deny", Action: StringList{"*"}}, "ec2:CreateRoute", false},
+		{"not action", Statement{Effect: "Allow", NotAction: StringList{"iam:*"}}, "ec2:CreateRoute", true},
+		{"excluded by not action", Statement{Effect: "Allow", NotAction: StringList{"iam:*"}}, "iam:PassRole", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.statement.Allows(tt.action); got != tt.expected {
+				t.Errorf("Allows(%q) = %v, expected %v", tt.action, got, tt.expected)
+			}
+		})
+	}
+}
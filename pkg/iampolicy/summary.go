@@ -0,0 +1,249 @@
+package iampolicy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Finding kinds
+const (
+	// KindAdmin marks statements allowing every IAM action on every resource
+	KindAdmin = "admin"
+	// KindPassRole marks statements allowing iam:PassRole on wildcard resources
+	KindPassRole = "pass-role-wildcard"
+	// KindNetworkMutation marks statements allowing route or security group changes
+	KindNetworkMutation = "network-mutation"
+)
+
+// networkMutatingActions are the ec2:*Route* and ec2:*SecurityGroup* actions that
+// change how traffic is routed or filtered
+var networkMutatingActions = []string{
+	"ec2:AssociateRouteTable",
+	"ec2:AuthorizeSecurityGroupEgress",
+	"ec2:AuthorizeSecurityGroupIngress",
+	"ec2:CreateRoute",
+	"ec2:CreateRouteTable",
+	"ec2:CreateSecurityGroup",
+	"ec2:CreateTransitGatewayRoute",
+	"ec2:CreateTransitGatewayRouteTable",
+	"ec2:DeleteRoute",
+	"ec2:DeleteRouteTable",
+	"ec2:DeleteSecurityGroup",
+	"ec2:DeleteTransitGatewayRoute",
+	"ec2:DisassociateRouteTable",
+	"ec2:ModifySecurityGroupRules",
+	"ec2:ReplaceRoute",
+	"ec2:ReplaceRouteTableAssociation",
+	"ec2:ReplaceTransitGatewayRoute",
+	"ec2:RevokeSecurityGroupEgress",
+	"ec2:RevokeSecurityGroupIngress",
+	"ec2:UpdateSecurityGroupRuleDescriptionsEgress",
+	"ec2:UpdateSecurityGroupRuleDescriptionsIngress",
+}
+
+// Finding is a risky permission granted by one policy statement
+type Finding struct {
+	Kind   string `json:"kind"`
+	Policy string `json:"policy"`
+	Sid    string `json:"sid,omitempty"`
+	// Actions are the risky actions the statement allows
+	Actions []string `json:"actions"`
+	// Resources are the statement's resources the actions apply to
+	Resources   []string `json:"resources"`
+	Conditional bool     `json:"conditional,omitempty"`
+}
+
+// RoleSummary is the risky permissions of one role
+type RoleSummary struct {
+	Name     string    `json:"name"`
+	Arn      string    `json:"arn"`
+	Admin    bool      `json:"admin"`
+	Findings []Finding `json:"findings"`
+	// InstanceProfiles are the instance profiles EC2 instances act with the role through
+	InstanceProfiles []string `json:"instance_profiles,omitempty"`
+}
+
+// Report summarizes the risky permissions of scanned IAM roles
+type Report struct {
+	Roles           int           `json:"roles"`
+	Admin           int           `json:"admin"`
+	PassRole        int           `json:"pass_role_wildcard"`
+	NetworkMutating int           `json:"network_mutating"`
+	Flagged         []RoleSummary `json:"flagged_roles"`
+	// Errors lists the policy documents that could not be parsed
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Summarize reports the roles with admin-equivalent permissions, iam:PassRole on
+// wildcard resources or network-mutating actions. Only Allow statements are
+// considered: Deny statements, permission boundaries and conditions are not
+// evaluated, so findings are what a role may do, not what it can.
+func Summarize(roles []scanner.IAMRole) Report {
+	report := Report{Roles: len(roles)}
+
+	for _, role := range roles {
+		summary := RoleSummary{Name: role.Name, Arn: role.Arn}
+
+		policies := make(map[string]string)
+		var names []string
+		add := func(name, document string) {
+			if document == "" {
+				return
+			}
+			if _, exists := policies[name]; !exists {
+				names = append(names, name)
+			}
+			policies[name] = document
+		}
+		for _, policy := range role.AttachedPolicies {
+			add(policy.PolicyName, policy.PolicyDocument)
+		}
+		for _, policy := range role.InlinePolicies {
+			add(policy.PolicyName, policy.PolicyDocument)
+		}
+
+		for _, name := range names {
+			document, err := Parse(policies[name])
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %s: %v", role.Name, name, err))
+				continue
+			}
+			summary.Findings = append(summary.Findings, statementFindings(name, document)...)
+		}
+
+		if len(summary.Findings) == 0 {
+			continue
+		}
+
+		kinds := make(map[string]bool)
+		for _, finding := range summary.Findings {
+			kinds[finding.Kind] = true
+		}
+		summary.Admin = kinds[KindAdmin]
+		if kinds[KindAdmin] {
+			report.Admin++
+		}
+		if kinds[KindPassRole] {
+			report.PassRole++
+		}
+		if kinds[KindNetworkMutation] {
+			report.NetworkMutating++
+		}
+		for _, profile := range role.InstanceProfiles {
+			summary.InstanceProfiles = append(summary.InstanceProfiles, profile.Name)
+		}
+		report.Flagged = append(report.Flagged, summary)
+	}
+
+	// Admin roles first, then by name
+	sort.SliceStable(report.Flagged, func(i, j int) bool {
+		if report.Flagged[i].Admin != report.Flagged[j].Admin {
+			return report.Flagged[i].Admin
+		}
+		return report.Flagged[i].Name < report.Flagged[j].Name
+	})
+
+	return report
+}
+
+// statementFindings returns the risky permissions of a policy's statements. An
+// admin-equivalent statement is reported only as admin, since it allows the rest.
+func statementFindings(policy string, document *Document) []Finding {
+	var findings []Finding
+	for _, statement := range document.Statement {
+		finding := Finding{
+			Policy:      policy,
+			Sid:         statement.Sid,
+			Resources:   statement.Resource,
+			Conditional: statement.Conditional(),
+		}
+		if len(statement.NotResource) > 0 {
+			finding.Resources = statement.WildcardResources()
+		}
+
+		// Allowing every IAM action, such as with "*", "iam:*" or a NotAction
+		// without IAM actions, lets the role grant itself anything else
+		if statement.AllResources() && statement.Allows("iam:*") {
+			finding.Kind = KindAdmin
+			finding.Actions = actionsOf(statement)
+			findings = append(findings, finding)
+			continue
+		}
+
+		if statement.Allows("iam:PassRole") {
+			if resources := statement.WildcardResources(); len(resources) > 0 {
+				passRole := finding
+				passRole.Kind = KindPassRole
+				passRole.Actions = []string{"iam:PassRole"}
+				passRole.Resources = resources
+				findings = append(findings, passRole)
+			}
+		}
+
+		var mutating []string
+		for _, action := range networkMutatingActions {
+			if statement.Allows(action) {
+				mutating = append(mutating, action)
+			}
+		}
+		if len(mutating) > 0 {
+			network := finding
+			network.Kind = KindNetworkMutation
+			network.Actions = mutating
+			findings = append(findings, network)
+		}
+	}
+	return findings
+}
+
+// actionsOf describes the actions a statement allows
+func actionsOf(statement Statement) []string {
+	if len(statement.NotAction) > 0 {
+		actions := make([]string, len(statement.NotAction))
+		for i, action := range statement.NotAction {
+			actions[i] = "not " + action
+		}
+		return actions
+	}
+	return statement.Action
+}
+
+// Text formats the report for the terminal
+func (r Report) Text() string {
+	var result strings.Builder
+
+	result.WriteString(fmt.Sprintf("IAM Permission Summary (%d roles, %d flagged)\n", r.Roles, len(r.Flagged)))
+
+	for _, role := range r.Flagged {
+		result.WriteString(fmt.Sprintf("\n%s (%s)\n", role.Name, role.Arn))
+		for _, finding := range role.Findings {
+			statement := finding.Policy
+			if finding.Sid != "" {
+				statement += " " + finding.Sid
+			}
+			line := fmt.Sprintf("  %-19s %s allows %s on %s", finding.Kind, statement,
+				strings.Join(finding.Actions, ", "), strings.Join(finding.Resources, ", "))
+			if finding.Conditional {
+				line += " (conditional)"
+			}
+			result.WriteString(line + "\n")
+		}
+		if len(role.InstanceProfiles) > 0 {
+			result.WriteString(fmt.Sprintf("  EC2 instance profiles: %s\n", strings.Join(role.InstanceProfiles, ", ")))
+		}
+	}
+
+	for _, err := range r.Errors {
+		result.WriteString(fmt.Sprintf("\nWarning: %s\n", err))
+	}
+
+	result.WriteString("\nSummary:\n")
+	result.WriteString(fmt.Sprintf("  Admin-equivalent: %d\n", r.Admin))
+	result.WriteString(fmt.Sprintf("  iam:PassRole on wildcards: %d\n", r.PassRole))
+	result.WriteString(fmt.Sprintf("  Network-mutating: %d\n", r.NetworkMutating))
+
+	return result.String()
+}
@@ -0,0 +1,59 @@
+package iampolicy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func TestSummarize(t *testing.T) {
+	roles := []scanner.IAMRole{
+		{Name: "reader", AttachedPolicies: []scanner.IAMPolicy{
+			{PolicyName: "ReadOnly", PolicyDocument: `{"Statement":[{"Effect":"Allow","Action":["ec2:Describe*","iam:Get*"],"Resource":"*"}]}`},
+		}},
+		{Name: "network", InstanceProfiles: []scanner.IAMInstanceProfile{{Name: "network-profile"}}, InlinePolicies: []scanner.IAMInlinePolicy{
+			{PolicyName: "routes", PolicyDocument: `{"Statement":[{"Sid":"Routes","Effect":"Allow","Action":"ec2:*Route","Resource":"*"},
+				{"Effect":"Allow","Action":"iam:PassRole","Resource":"arn:aws:iam::123456789012:role/app-*"}]}`},
+		}},
+		{Name: "admin", AttachedPolicies: []scanner.IAMPolicy{
+			{PolicyName: "AdministratorAccess", PolicyDocument: `{"Statement":[{"Effect":"Allow","Action":"*","Resource":"*"}]}`},
+			{PolicyName: "Broken", PolicyDocument: `{`},
+		}},
+	}
+
+	report := Summarize(roles)
+	if report.Roles != 3 || report.Admin != 1 || report.PassRole != 1 || report.NetworkMutating != 1 {
+		t.Errorf("Expected 3 roles with 1 admin, 1 PassRole and 1 network-mutating, got %+v", report)
+	}
+	if len(report.Flagged) != 2 || report.Flagged[0].Name != "admin" || report.Flagged[1].Name != "network" {
+		t.Fatalf("Expected admin then network to be flagged, got %+v", report.Flagged)
+	}
+
+	// An admin statement is not also reported as PassRole or network findings
+	if findings := report.Flagged[0].Findings; len(findings) != 1 || findings[0].Kind != KindAdmin {
+		t.Errorf("Expected a single admin finding, got %+v", findings)
+	}
+
+	network := report.Flagged[1].Findings
+	if len(network) != 2 || network[0].Kind != KindNetworkMutation || network[1].Kind != KindPassRole {
+		t.Fatalf("Expected network-mutation and PassRole findings, got %+v", network)
+	}
+	expected := []string{"ec2:CreateRoute", "ec2:CreateTransitGatewayRoute", "ec2:DeleteRoute", "ec2:DeleteTransitGatewayRoute",
+		"ec2:ReplaceRoute", "ec2:ReplaceTransitGatewayRoute"}
+	if !reflect.DeepEqual(network[0].Actions, expected) {
+		t.Errorf("Expected actions %v, got %v", expected, network[0].Actions)
+	}
+	if !reflect.DeepEqual(network[1].Resources, []string{"arn:aws:iam::123456789012:role/app-*"}) {
+		t.Errorf("Expected the wildcard role resource, got %v", network[1].Resources)
+	}
+
+	if len(report.Errors) != 1 || !strings.HasPrefix(report.Errors[0], "admin: Broken:") {
+		t.Errorf("Expected the unparseable policy to be reported, got %v", report.Errors)
+	}
+	if text := report.Text(); !strings.Contains(text, "network-mutation    routes Routes allows ec2:CreateRoute") ||
+		!strings.Contains(text, "EC2 instance profiles: network-profile") {
+		t.Errorf("Expected the finding in the text report, got:\n%s", text)
+	}
+}
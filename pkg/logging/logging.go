@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+const (
+	// FormatText logs key=value lines
+	FormatText = "text"
+	// FormatJSON logs one JSON object per line
+	FormatJSON = "json"
+)
+
+// New creates a logger that writes records at level and above to w in format
+func New(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "", FormatText:
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format %q, expected %s or %s", format, FormatText, FormatJSON)
+	}
+}
+
+// Level returns the level for the verbose and quiet flags. Verbose logging
+// includes per-step details, quiet logging only errors.
+func Level(verbose, quiet bool) slog.Level {
+	switch {
+	case quiet:
+		return slog.LevelError
+	case verbose:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, Level(false, false))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	logger.Debug("hidden")
+	logger.Warn("failed to scan", "resource_type", "subnet")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "failed to scan" || record["resource_type"] != "subnet" || record["level"] != "WARN" {
+		t.Errorf("Unexpected record %v", record)
+	}
+
+	if _, err := New(&buf, "xml", slog.LevelInfo); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func TestLevel(t *testing.T) {
+	if Level(true, false) != slog.LevelDebug || Level(false, true) != slog.LevelError || Level(false, false) != slog.LevelInfo {
+		t.Error("Unexpected levels for the verbose and quiet flags")
+	}
+	if Level(true, true) != slog.LevelError {
+		t.Error("Expected quiet to win over verbose")
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	var buf bytes.Buffer
+	bar := NewProgressBar(&buf)
+
+	bar.Scanned("vpc", 1, 2)
+	if strings.Contains(buf.String(), "\n") || !strings.Contains(buf.String(), "1/2 vpc") {
+		t.Errorf("Expected an unfinished bar, got %q", buf.String())
+	}
+
+	bar.Scanned("subnet", 2, 2)
+	if !strings.HasSuffix(buf.String(), "\n") || !strings.Contains(buf.String(), "["+strings.Repeat("=", progressBarWidth)+"] 2/2 subnet") {
+		t.Errorf("Expected a full bar ending the line, got %q", buf.String())
+	}
+}
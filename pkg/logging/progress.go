@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// progressBarWidth is the number of cells in the progress bar
+const progressBarWidth = 30
+
+// ProgressBar draws a single-line progress bar of the resource types scanned so far
+type ProgressBar struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewProgressBar creates a progress bar that draws on w, usually a terminal's stderr
+func NewProgressBar(w io.Writer) *ProgressBar {
+	return &ProgressBar{w: w}
+}
+
+// Scanned redraws the bar after a resource type finished scanning, ending the
+// line once all of them are done
+func (p *ProgressBar) Scanned(resourceType string, done, total int) {
+	if total <= 0 {
+		return
+	}
+	if done > total {
+		done = total
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	filled := done * progressBarWidth / total
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	// Pad the label so a shorter name overwrites a longer one
+	fmt.Fprintf(p.w, "\r[%s] %d/%d %-24s", bar, done, total, resourceType)
+	if done == total {
+		fmt.Fprintln(p.w)
+	}
+}
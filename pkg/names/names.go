@@ -0,0 +1,276 @@
+package names
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Provider resolves AWS resource IDs to human-friendly names from an external source
+type Provider interface {
+	// Resolve returns names for the IDs it knows. Unknown IDs are left out of the result.
+	Resolve(ctx context.Context, ids []string) (map[string]string, error)
+}
+
+// CSVProvider resolves names from a CSV file with one "id,name" pair per line
+type CSVProvider struct {
+	names map[string]string
+}
+
+// NewCSVProvider loads a CSV name mapping. Blank lines and lines starting with # are ignored.
+func NewCSVProvider(filename string) (*CSVProvider, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open names file %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	provider := &CSVProvider{names: make(map[string]string)}
+
+	lines := bufio.NewScanner(file)
+	lineNumber := 0
+	for lines.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ",", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("names file %s line %d: expected id,name", filename, lineNumber)
+		}
+		provider.names[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read names file %s: %w", filename, err)
+	}
+
+	return provider, nil
+}
+
+// Resolve returns the names in the CSV mapping
+func (p *CSVProvider) Resolve(ctx context.Context, ids []string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, id := range ids {
+		if name, ok := p.names[id]; ok {
+			result[id] = name
+		}
+	}
+	return result, nil
+}
+
+// httpBatchSize limits the number of IDs sent in one request to keep URLs short
+const httpBatchSize = 100
+
+// HTTPProvider resolves names from an HTTP API such as a CMDB or IPAM tool.
+// IDs are sent as a comma-separated "ids" query parameter and the API responds
+// with a JSON object mapping IDs to names.
+type HTTPProvider struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewHTTPProvider creates a provider for a name lookup endpoint
+func NewHTTPProvider(endpoint string) *HTTPProvider {
+	return &HTTPProvider{
+		endpoint: endpoint,
+		client:   http.DefaultClient,
+	}
+}
+
+// Resolve queries the endpoint for the names of the IDs in batches
+func (p *HTTPProvider) Resolve(ctx context.Context, ids []string) (map[string]string, error) {
+	result := make(map[string]string)
+
+	for start := 0; start < len(ids); start += httpBatchSize {
+		end := start + httpBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		names, err := p.resolveBatch(ctx, ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for id, name := range names {
+			result[id] = name
+		}
+	}
+
+	return result, nil
+}
+
+func (p *HTTPProvider) resolveBatch(ctx context.Context, ids []string) (map[string]string, error) {
+	endpoint, err := url.Parse(p.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid names endpoint %s: %w", p.endpoint, err)
+	}
+	query := endpoint.Query()
+	query.Set("ids", strings.Join(ids, ","))
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create names request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query names endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("names endpoint returned %s", resp.Status)
+	}
+
+	var result map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode names response: %w", err)
+	}
+
+	return result, nil
+}
+
+// Resolve asks each provider in turn for names. Earlier providers take precedence.
+func Resolve(ctx context.Context, providers []Provider, ids []string) (map[string]string, error) {
+	resolved := make(map[string]string)
+
+	for _, provider := range providers {
+		var pending []string
+		for _, id := range ids {
+			if _, ok := resolved[id]; !ok {
+				pending = append(pending, id)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		names, err := provider.Resolve(ctx, pending)
+		if err != nil {
+			return nil, err
+		}
+		for id, name := range names {
+			if _, ok := resolved[id]; !ok && name != "" {
+				resolved[id] = name
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+// Apply replaces the names of network resources with names from the providers
+func Apply(ctx context.Context, network *scanner.Network, providers []Provider) error {
+	if len(providers) == 0 {
+		return nil
+	}
+
+	resolved, err := Resolve(ctx, providers, resourceIDs(network))
+	if err != nil {
+		return err
+	}
+
+	rename := func(id string, name *string) {
+		if resolvedName, ok := resolved[id]; ok {
+			*name = resolvedName
+		}
+	}
+
+	for i := range network.VPCs {
+		rename(network.VPCs[i].ID, &network.VPCs[i].Name)
+	}
+	for i := range network.Subnets {
+		rename(network.Subnets[i].ID, &network.Subnets[i].Name)
+	}
+	for i := range network.PeeringConnections {
+		rename(network.PeeringConnections[i].ID, &network.PeeringConnections[i].Name)
+	}
+	for i := range network.TransitGateways {
+		rename(network.TransitGateways[i].ID, &network.TransitGateways[i].Name)
+	}
+	for i := range network.InternetGateways {
+		rename(network.InternetGateways[i].ID, &network.InternetGateways[i].Name)
+	}
+	for i := range network.NATGateways {
+		rename(network.NATGateways[i].ID, &network.NATGateways[i].Name)
+	}
+	for i := range network.RouteTables {
+		rename(network.RouteTables[i].ID, &network.RouteTables[i].Name)
+	}
+	for i := range network.SecurityGroups {
+		rename(network.SecurityGroups[i].ID, &network.SecurityGroups[i].Name)
+	}
+	for i := range network.NetworkAcls {
+		rename(network.NetworkAcls[i].ID, &network.NetworkAcls[i].Name)
+	}
+	for i := range network.DhcpOptions {
+		rename(network.DhcpOptions[i].ID, &network.DhcpOptions[i].Name)
+	}
+	for i := range network.EndpointServices {
+		rename(network.EndpointServices[i].ID, &network.EndpointServices[i].Name)
+	}
+	for i := range network.VpcEndpoints {
+		rename(network.VpcEndpoints[i].ID, &network.VpcEndpoints[i].Name)
+	}
+	for i := range network.EdgeIngresses {
+		rename(network.EdgeIngresses[i].ID, &network.EdgeIngresses[i].Name)
+	}
+
+	return nil
+}
+
+// resourceIDs lists the IDs of all named network resources
+func resourceIDs(network *scanner.Network) []string {
+	var ids []string
+	for _, vpc := range network.VPCs {
+		ids = append(ids, vpc.ID)
+	}
+	for _, subnet := range network.Subnets {
+		ids = append(ids, subnet.ID)
+	}
+	for _, pc := range network.PeeringConnections {
+		ids = append(ids, pc.ID)
+	}
+	for _, tgw := range network.TransitGateways {
+		ids = append(ids, tgw.ID)
+	}
+	for _, igw := range network.InternetGateways {
+		ids = append(ids, igw.ID)
+	}
+	for _, nat := range network.NATGateways {
+		ids = append(ids, nat.ID)
+	}
+	for _, rt := range network.RouteTables {
+		ids = append(ids, rt.ID)
+	}
+	for _, sg := range network.SecurityGroups {
+		ids = append(ids, sg.ID)
+	}
+	for _, nacl := range network.NetworkAcls {
+		ids = append(ids, nacl.ID)
+	}
+	for _, options := range network.DhcpOptions {
+		ids = append(ids, options.ID)
+	}
+	for _, service := range network.EndpointServices {
+		ids = append(ids, service.ID)
+	}
+	for _, endpoint := range network.VpcEndpoints {
+		ids = append(ids, endpoint.ID)
+	}
+	for _, ingress := range network.EdgeIngresses {
+		ids = append(ids, ingress.ID)
+	}
+	return ids
+}
@@ -0,0 +1,101 @@
+package names
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func TestCSVProvider(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "names.csv")
+	content := "# id,name\nvpc-123, payments-prod\n\nsubnet-1,payments-app-a\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write names file: %v", err)
+	}
+
+	provider, err := NewCSVProvider(filename)
+	if err != nil {
+		t.Fatalf("Failed to load names file: %v", err)
+	}
+
+	names, err := provider.Resolve(context.Background(), []string{"vpc-123", "sg-unknown"})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if len(names) != 1 || names["vpc-123"] != "payments-prod" {
+		t.Errorf("Unexpected names: %v", names)
+	}
+}
+
+func TestCSVProviderInvalidLine(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "names.csv")
+	if err := os.WriteFile(filename, []byte("vpc-123\n"), 0644); err != nil {
+		t.Fatalf("Failed to write names file: %v", err)
+	}
+
+	if _, err := NewCSVProvider(filename); err == nil {
+		t.Error("Expected an error for a line without a name")
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names := make(map[string]string)
+		for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+			if id == "sg-web" {
+				names[id] = "web-frontend"
+			}
+		}
+		json.NewEncoder(w).Encode(names)
+	}))
+	defer server.Close()
+
+	names, err := NewHTTPProvider(server.URL+"/lookup?source=aws").Resolve(context.Background(), []string{"sg-web", "sg-db"})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if len(names) != 1 || names["sg-web"] != "web-frontend" {
+		t.Errorf("Unexpected names: %v", names)
+	}
+}
+
+type staticProvider map[string]string
+
+func (p staticProvider) Resolve(ctx context.Context, ids []string) (map[string]string, error) {
+	return p, nil
+}
+
+func TestApply(t *testing.T) {
+	network := &scanner.Network{
+		VPCs:    []scanner.VPC{{ID: "vpc-123", Name: "aws-name"}},
+		Subnets: []scanner.Subnet{{ID: "subnet-1"}, {ID: "subnet-2", Name: "kept"}},
+	}
+
+	providers := []Provider{
+		staticProvider{"vpc-123": "payments-prod"},
+		staticProvider{"vpc-123": "ignored", "subnet-1": "payments-app-a"},
+	}
+
+	if err := Apply(context.Background(), network, providers); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if network.VPCs[0].Name != "payments-prod" {
+		t.Errorf("Expected the first provider to take precedence, got %s", network.VPCs[0].Name)
+	}
+	if network.Subnets[0].Name != "payments-app-a" {
+		t.Errorf("Expected subnet-1 to be renamed, got %s", network.Subnets[0].Name)
+	}
+	if network.Subnets[1].Name != "kept" {
+		t.Errorf("Expected unresolved names to be kept, got %s", network.Subnets[1].Name)
+	}
+}
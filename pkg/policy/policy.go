@@ -0,0 +1,386 @@
+// Package policy evaluates user-defined rules against a scanned network, such as
+// "no subnet in a prod VPC may be public", so network conventions can gate CI.
+// Rules are written in a small YAML language over the working state's fields.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity ranks how serious a violation is
+type Severity string
+
+const (
+	SeverityHigh   Severity = "high"
+	SeverityMedium Severity = "medium"
+	SeverityLow    Severity = "low"
+)
+
+// severityRanks orders severities from least to most serious
+var severityRanks = map[Severity]int{
+	SeverityLow:    1,
+	SeverityMedium: 2,
+	SeverityHigh:   3,
+}
+
+// ParseSeverity checks a severity name
+func ParseSeverity(name string) (Severity, error) {
+	severity := Severity(strings.ToLower(name))
+	if severityRanks[severity] == 0 {
+		return "", fmt.Errorf("unknown severity %q (want high, medium or low)", name)
+	}
+	return severity, nil
+}
+
+// AtLeast reports whether a severity is as serious as another
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRanks[s] >= severityRanks[other]
+}
+
+// RuleSet is a set of policy rules
+type RuleSet struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule requires every resource of a type selected by When to satisfy Assert.
+// All conditions of a list must hold.
+type Rule struct {
+	ID          string      `yaml:"id"`
+	Description string      `yaml:"description"`
+	Severity    Severity    `yaml:"severity"`
+	Resource    string      `yaml:"resource"`
+	When        []Condition `yaml:"when"`
+	Assert      []Condition `yaml:"assert"`
+}
+
+// Condition tests the values of a field path with exactly one operator. A path
+// yielding several values, such as the CIDR blocks of every ingress rule, holds
+// for equals, in and matches when any value does, and for not_equals and not_in
+// when no value does.
+type Condition struct {
+	Field     string   `yaml:"field"`
+	Equals    *string  `yaml:"equals"`
+	NotEquals *string  `yaml:"not_equals"`
+	In        []string `yaml:"in"`
+	NotIn     []string `yaml:"not_in"`
+	Matches   string   `yaml:"matches"`
+	Exists    *bool    `yaml:"exists"`
+
+	pattern *regexp.Regexp
+}
+
+// Load reads rules from a YAML file, or from every .yaml and .yml file in a directory
+func Load(path string) (*RuleSet, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules %s: %w", path, err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules directory %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no .yaml rule files found in %s", path)
+		}
+	}
+
+	rules := &RuleSet{}
+	seen := make(map[string]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules file %s: %w", file, err)
+		}
+
+		var fileRules RuleSet
+		if err := yaml.Unmarshal(data, &fileRules); err != nil {
+			return nil, fmt.Errorf("failed to parse rules file %s: %w", file, err)
+		}
+		if err := fileRules.Validate(file); err != nil {
+			return nil, err
+		}
+
+		for _, rule := range fileRules.Rules {
+			if other, ok := seen[rule.ID]; ok {
+				return nil, fmt.Errorf("rule %s in %s is already defined in %s", rule.ID, file, other)
+			}
+			seen[rule.ID] = file
+		}
+		rules.Rules = append(rules.Rules, fileRules.Rules...)
+	}
+
+	return rules, nil
+}
+
+// Validate checks every rule and compiles its patterns. The source names where the
+// rules came from.
+func (r *RuleSet) Validate(source string) error {
+	resourceTypes := make(map[string]bool)
+	for _, resourceType := range ResourceTypes {
+		resourceTypes[resourceType] = true
+	}
+
+	ids := make(map[string]bool)
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if rule.ID == "" {
+			return fmt.Errorf("rule %d in %s has no id", i+1, source)
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("rule %s in %s is defined twice", rule.ID, source)
+		}
+		ids[rule.ID] = true
+
+		if !resourceTypes[rule.Resource] {
+			return fmt.Errorf("rule %s in %s has unknown resource %q (want one of %s)", rule.ID, source, rule.Resource, strings.Join(ResourceTypes, ", "))
+		}
+		if rule.Severity == "" {
+			rule.Severity = SeverityMedium
+		}
+		severity, err := ParseSeverity(string(rule.Severity))
+		if err != nil {
+			return fmt.Errorf("rule %s in %s: %w", rule.ID, source, err)
+		}
+		rule.Severity = severity
+
+		if len(rule.Assert) == 0 {
+			return fmt.Errorf("rule %s in %s has no assert conditions", rule.ID, source)
+		}
+		for _, conditions := range [][]Condition{rule.When, rule.Assert} {
+			for j := range conditions {
+				if err := conditions[j].compile(); err != nil {
+					return fmt.Errorf("rule %s in %s: %w", rule.ID, source, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// compile checks that a condition has a field and one operator, and compiles its pattern
+func (c *Condition) compile() error {
+	if c.Field == "" {
+		return fmt.Errorf("condition has no field")
+	}
+
+	operators := 0
+	for _, set := range []bool{c.Equals != nil, c.NotEquals != nil, c.In != nil, c.NotIn != nil, c.Matches != "", c.Exists != nil} {
+		if set {
+			operators++
+		}
+	}
+	if operators != 1 {
+		return fmt.Errorf("condition on %s needs exactly one of equals, not_equals, in, not_in, matches or exists", c.Field)
+	}
+
+	if c.Matches != "" {
+		pattern, err := regexp.Compile(c.Matches)
+		if err != nil {
+			return fmt.Errorf("condition on %s has an invalid pattern: %w", c.Field, err)
+		}
+		c.pattern = pattern
+	}
+	return nil
+}
+
+// holds tests a condition against a resource
+func (c *Condition) holds(resource Resource) bool {
+	values := resource.Values(c.Field)
+
+	switch {
+	case c.Exists != nil:
+		set := false
+		for _, value := range values {
+			if value != "" {
+				set = true
+			}
+		}
+		return set == *c.Exists
+	case c.Equals != nil:
+		return containsAny(values, []string{*c.Equals})
+	case c.NotEquals != nil:
+		return !containsAny(values, []string{*c.NotEquals})
+	case c.In != nil:
+		return containsAny(values, c.In)
+	case c.NotIn != nil:
+		return !containsAny(values, c.NotIn)
+	case c.pattern != nil:
+		for _, value := range values {
+			if c.pattern.MatchString(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsAny reports whether any value is one of the wanted values
+func containsAny(values, wanted []string) bool {
+	for _, value := range values {
+		for _, want := range wanted {
+			if value == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String describes what a condition requires
+func (c *Condition) String() string {
+	switch {
+	case c.Exists != nil && *c.Exists:
+		return fmt.Sprintf("%s must be set", c.Field)
+	case c.Exists != nil:
+		return fmt.Sprintf("%s must not be set", c.Field)
+	case c.Equals != nil:
+		return fmt.Sprintf("%s must equal %q", c.Field, *c.Equals)
+	case c.NotEquals != nil:
+		return fmt.Sprintf("%s must not equal %q", c.Field, *c.NotEquals)
+	case c.In != nil:
+		return fmt.Sprintf("%s must be one of %s", c.Field, strings.Join(c.In, ", "))
+	case c.NotIn != nil:
+		return fmt.Sprintf("%s must not be any of %s", c.Field, strings.Join(c.NotIn, ", "))
+	default:
+		return fmt.Sprintf("%s must match %s", c.Field, c.Matches)
+	}
+}
+
+// Violation is a resource that fails a rule
+type Violation struct {
+	RuleID       string   `json:"rule_id"`
+	Description  string   `json:"description,omitempty"`
+	Severity     Severity `json:"severity"`
+	ResourceType string   `json:"resource_type"`
+	ResourceID   string   `json:"resource_id"`
+	// Failures are the assert conditions the resource fails, with the values found
+	Failures []string `json:"failures"`
+}
+
+// Report is the result of evaluating rules against a network
+type Report struct {
+	Rules      int         `json:"rules"`
+	Resources  int         `json:"resources"`
+	Violations []Violation `json:"violations"`
+}
+
+// Evaluate checks every resource against every rule
+func Evaluate(rules *RuleSet, resources []Resource) Report {
+	report := Report{Rules: len(rules.Rules), Resources: len(resources), Violations: []Violation{}}
+
+	for _, rule := range rules.Rules {
+		for _, resource := range resources {
+			if resource.Type != rule.Resource || !allHold(rule.When, resource) {
+				continue
+			}
+
+			var failures []string
+			for i := range rule.Assert {
+				condition := &rule.Assert[i]
+				if !condition.holds(resource) {
+					failures = append(failures, fmt.Sprintf("%s, found %s", condition, describeValues(resource.Values(condition.Field))))
+				}
+			}
+			if len(failures) > 0 {
+				report.Violations = append(report.Violations, Violation{
+					RuleID:       rule.ID,
+					Description:  rule.Description,
+					Severity:     rule.Severity,
+					ResourceType: resource.Type,
+					ResourceID:   resource.ID,
+					Failures:     failures,
+				})
+			}
+		}
+	}
+
+	// Most serious first, then by rule and resource
+	sort.SliceStable(report.Violations, func(i, j int) bool {
+		a, b := report.Violations[i], report.Violations[j]
+		if a.Severity != b.Severity {
+			return a.Severity.AtLeast(b.Severity)
+		}
+		if a.RuleID != b.RuleID {
+			return a.RuleID < b.RuleID
+		}
+		return a.ResourceID < b.ResourceID
+	})
+
+	return report
+}
+
+// allHold reports whether a resource satisfies every condition
+func allHold(conditions []Condition, resource Resource) bool {
+	for i := range conditions {
+		if !conditions[i].holds(resource) {
+			return false
+		}
+	}
+	return true
+}
+
+// describeValues lists the values found for a field in a failure message
+func describeValues(values []string) string {
+	if len(values) == 0 {
+		return "nothing"
+	}
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Violated reports whether any violation is at least as serious as a severity
+func (r Report) Violated(threshold Severity) bool {
+	for _, violation := range r.Violations {
+		if violation.Severity.AtLeast(threshold) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text renders the report for the terminal
+func (r Report) Text() string {
+	var result strings.Builder
+
+	result.WriteString("Policy Evaluation\n\n")
+	result.WriteString(fmt.Sprintf("Rules: %d\n", r.Rules))
+	result.WriteString(fmt.Sprintf("Resources: %d\n", r.Resources))
+	result.WriteString(fmt.Sprintf("Violations: %d\n", len(r.Violations)))
+
+	if len(r.Violations) == 0 {
+		result.WriteString("\n✓ All resources comply with the rules\n")
+		return result.String()
+	}
+
+	for _, violation := range r.Violations {
+		result.WriteString(fmt.Sprintf("\n[%s] %s: %s %s\n", strings.ToUpper(string(violation.Severity)), violation.RuleID, violation.ResourceType, violation.ResourceID))
+		if violation.Description != "" {
+			result.WriteString(fmt.Sprintf("  %s\n", violation.Description))
+		}
+		for _, failure := range violation.Failures {
+			result.WriteString(fmt.Sprintf("  - %s\n", failure))
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+const testRules = `rules:
+  - id: no-public-prod-subnets
+    description: No subnet in a prod VPC may be public
+    severity: high
+    resource: Subnet
+    when:
+      - field: vpc.tags.Environment
+        equals: prod
+    assert:
+      - field: type
+        not_equals: public
+      - field: map_public_ip
+        equals: false
+  - id: sg-description-tag
+    resource: SecurityGroup
+    assert:
+      - field: tags.Description
+        exists: true
+  - id: no-open-ssh
+    severity: low
+    resource: SecurityGroup
+    assert:
+      - field: ingress_rules.cidr_blocks
+        not_in: [0.0.0.0/0]
+`
+
+func testNetwork() *scanner.Network {
+	return &scanner.Network{
+		VPCs: []scanner.VPC{
+			{ID: "vpc-prod", Tags: map[string]string{"Environment": "prod"}},
+			{ID: "vpc-dev", Tags: map[string]string{"Environment": "dev"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-prod-public", VpcID: "vpc-prod", Type: "public", MapPublicIP: true},
+			{ID: "subnet-prod-private", VpcID: "vpc-prod", Type: "private"},
+			{ID: "subnet-dev-public", VpcID: "vpc-dev", Type: "public", MapPublicIP: true},
+		},
+		SecurityGroups: []scanner.SecurityGroup{
+			{ID: "sg-tagged", Tags: map[string]string{"Description": "web"}},
+			{
+				ID: "sg-open",
+				IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"10.0.0.0/8"}},
+					{IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+			},
+		},
+	}
+}
+
+func loadTestRules(t *testing.T, content string) *RuleSet {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return rules
+}
+
+func TestEvaluate(t *testing.T) {
+	rules := loadTestRules(t, testRules)
+	resources, err := Resources(testNetwork())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	report := Evaluate(rules, resources)
+
+	var got []string
+	for _, violation := range report.Violations {
+		got = append(got, violation.RuleID+" "+violation.ResourceID)
+	}
+	want := []string{
+		"no-public-prod-subnets subnet-prod-public",
+		"sg-description-tag sg-open",
+		"no-open-ssh sg-open",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected violations\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	if failures := report.Violations[0].Failures; len(failures) != 2 || !strings.Contains(failures[0], `found "public"`) {
+		t.Errorf("Expected both failed conditions with the values found, got %v", failures)
+	}
+	if report.Violations[1].Severity != SeverityMedium {
+		t.Errorf("Expected medium as the default severity, got %s", report.Violations[1].Severity)
+	}
+
+	if !report.Violated(SeverityHigh) {
+		t.Error("Expected a high severity violation")
+	}
+	if Evaluate(rules, nil).Violated(SeverityLow) {
+		t.Error("Expected no violations without resources")
+	}
+}
+
+func TestResourceValues(t *testing.T) {
+	resource := Resource{Fields: map[string]interface{}{
+		"tags":  map[string]interface{}{"kubernetes.io/role/elb": "1"},
+		"rules": []interface{}{map[string]interface{}{"port": float64(22)}, map[string]interface{}{"port": float64(443)}},
+	}}
+
+	if got := resource.Values("tags.kubernetes.io/role/elb"); len(got) != 1 || got[0] != "1" {
+		t.Errorf("Expected a tag key containing dots to be found, got %v", got)
+	}
+	if got := resource.Values("rules.port"); strings.Join(got, ",") != "22,443" {
+		t.Errorf("Expected list values to be flattened, got %v", got)
+	}
+	if got := resource.Values("tags.missing"); len(got) != 0 {
+		t.Errorf("Expected no values for a missing field, got %v", got)
+	}
+}
+
+func TestLoadInvalidRules(t *testing.T) {
+	tests := map[string]string{
+		"no id":            "rules:\n  - resource: Subnet\n    assert:\n      - field: type\n        equals: private\n",
+		"unknown resource": "rules:\n  - id: a\n    resource: Instance\n    assert:\n      - field: type\n        equals: private\n",
+		"no assert":        "rules:\n  - id: a\n    resource: Subnet\n",
+		"two operators":    "rules:\n  - id: a\n    resource: Subnet\n    assert:\n      - field: type\n        equals: private\n        exists: true\n",
+		"bad pattern":      "rules:\n  - id: a\n    resource: Subnet\n    assert:\n      - field: type\n        matches: \"(\"\n",
+		"bad severity":     "rules:\n  - id: a\n    severity: urgent\n    resource: Subnet\n    assert:\n      - field: type\n        equals: private\n",
+		"duplicate id":     "rules:\n  - id: a\n    resource: Subnet\n    assert:\n      - field: type\n        equals: private\n  - id: a\n    resource: VPC\n    assert:\n      - field: state\n        equals: available\n",
+	}
+	for name, content := range tests {
+		path := filepath.Join(t.TempDir(), "rules.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"subnets.yaml": "rules:\n  - id: private\n    resource: Subnet\n    assert:\n      - field: type\n        equals: private\n",
+		"vpcs.yml":     "rules:\n  - id: available\n    resource: VPC\n    assert:\n      - field: state\n        equals: available\n",
+		"README.md":    "not rules",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rules.Rules) != 2 {
+		t.Errorf("Expected the rules of both YAML files, got %d", len(rules.Rules))
+	}
+
+	duplicate := "rules:\n  - id: private\n    resource: Subnet\n    assert:\n      - field: type\n        equals: public\n"
+	if err := os.WriteFile(filepath.Join(dir, "more.yaml"), []byte(duplicate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an error for a rule ID defined in two files")
+	}
+}
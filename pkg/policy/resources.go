@@ -0,0 +1,190 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Resource is a scanned resource as rules see it: its working state JSON, with
+// the VPC it belongs to under "vpc"
+type Resource struct {
+	Type   string
+	ID     string
+	Fields map[string]interface{}
+}
+
+// ResourceTypes are the resource types rules can select
+var ResourceTypes = []string{
+	"VPC",
+	"Subnet",
+	"SecurityGroup",
+	"NetworkAcl",
+	"RouteTable",
+	"InternetGateway",
+	"EgressOnlyInternetGateway",
+	"NATGateway",
+	"PeeringConnection",
+	"TransitGateway",
+	"DhcpOptions",
+	"EndpointService",
+	"EKSCluster",
+	"ECSService",
+	"Database",
+	"IAMRole",
+}
+
+// Resources lists every resource in the network rules can be evaluated against
+func Resources(network *scanner.Network) ([]Resource, error) {
+	vpcs := make(map[string]map[string]interface{})
+	for _, vpc := range network.VPCs {
+		fields, err := toFields(vpc)
+		if err != nil {
+			return nil, err
+		}
+		vpcs[vpc.ID] = fields
+	}
+
+	var resources []Resource
+	var err error
+	add := func(resourceType, id string, value interface{}) {
+		if err != nil {
+			return
+		}
+		var fields map[string]interface{}
+		fields, err = toFields(value)
+		if err != nil {
+			return
+		}
+		if vpcID, ok := fields["vpc_id"].(string); ok && vpcs[vpcID] != nil {
+			fields["vpc"] = vpcs[vpcID]
+		}
+		resources = append(resources, Resource{Type: resourceType, ID: id, Fields: fields})
+	}
+
+	for _, vpc := range network.VPCs {
+		add("VPC", vpc.ID, vpc)
+	}
+	for _, subnet := range network.Subnets {
+		add("Subnet", subnet.ID, subnet)
+	}
+	for _, sg := range network.SecurityGroups {
+		add("SecurityGroup", sg.ID, sg)
+	}
+	for _, nacl := range network.NetworkAcls {
+		add("NetworkAcl", nacl.ID, nacl)
+	}
+	for _, rt := range network.RouteTables {
+		add("RouteTable", rt.ID, rt)
+	}
+	for _, igw := range network.InternetGateways {
+		add("InternetGateway", igw.ID, igw)
+	}
+	for _, eigw := range network.EgressOnlyInternetGateways {
+		add("EgressOnlyInternetGateway", eigw.ID, eigw)
+	}
+	for _, nat := range network.NATGateways {
+		add("NATGateway", nat.ID, nat)
+	}
+	for _, pc := range network.PeeringConnections {
+		add("PeeringConnection", pc.ID, pc)
+	}
+	for _, tgw := range network.TransitGateways {
+		add("TransitGateway", tgw.ID, tgw)
+	}
+	for _, options := range network.DhcpOptions {
+		add("DhcpOptions", options.ID, options)
+	}
+	for _, service := range network.EndpointServices {
+		add("EndpointService", service.ID, service)
+	}
+	for _, cluster := range network.EKSClusters {
+		add("EKSCluster", cluster.Name, cluster)
+	}
+	for _, service := range network.ECSServices {
+		add("ECSService", service.Arn, service)
+	}
+	for _, database := range network.Databases {
+		add("Database", database.Key(), database)
+	}
+	for _, role := range network.IAMRoles {
+		add("IAMRole", role.Name, role)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// toFields converts a resource to its working state JSON fields
+func toFields(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resource: %w", err)
+	}
+	return fields, nil
+}
+
+// Values returns the values of a dotted field path, such as "tags.Environment",
+// "vpc.is_default" or "ingress_rules.cidr_blocks". Lists along the path are
+// flattened, so a path can yield several values.
+func (r Resource) Values(field string) []string {
+	var values []string
+	for _, value := range lookup(r.Fields, strings.Split(field, ".")) {
+		values = append(values, formatValue(value))
+	}
+	return values
+}
+
+// lookup walks a field path. Map keys may themselves contain dots, as tag keys
+// often do, so the longest matching key is tried first.
+func lookup(value interface{}, path []string) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		var values []interface{}
+		for _, element := range v {
+			values = append(values, lookup(element, path)...)
+		}
+		return values
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return []interface{}{v}
+		}
+		for i := len(path); i > 0; i-- {
+			if next, ok := v[strings.Join(path[:i], ".")]; ok {
+				return lookup(next, path[i:])
+			}
+		}
+		return nil
+	default:
+		if len(path) > 0 {
+			return nil
+		}
+		return []interface{}{v}
+	}
+}
+
+// formatValue renders a JSON value for comparison with a rule's values
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
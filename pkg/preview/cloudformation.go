@@ -0,0 +1,650 @@
+package preview
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// CloudFormation tags AWS puts on the resources of a stack
+const (
+	stackNameTag = "aws:cloudformation:stack-name"
+	stackIDTag   = "aws:cloudformation:stack-id"
+	logicalIDTag = "aws:cloudformation:logical-id"
+)
+
+// cloudFormationOrder is the order resource types are applied in, so resources
+// exist before the resources attached to them
+var cloudFormationOrder = []string{
+	"AWS::EC2::VPC",
+	"AWS::EC2::VPCCidrBlock",
+	"AWS::EC2::Subnet",
+	"AWS::EC2::InternetGateway",
+	"AWS::EC2::VPCGatewayAttachment",
+	"AWS::EC2::NatGateway",
+	"AWS::EC2::RouteTable",
+	"AWS::EC2::Route",
+	"AWS::EC2::SubnetRouteTableAssociation",
+	"AWS::EC2::SecurityGroup",
+	"AWS::EC2::SecurityGroupIngress",
+	"AWS::EC2::SecurityGroupEgress",
+	"AWS::EC2::NetworkAcl",
+	"AWS::EC2::NetworkAclEntry",
+	"AWS::EC2::SubnetNetworkAclAssociation",
+}
+
+// cloudFormationResource is a resource declared in a template
+type cloudFormationResource struct {
+	logicalID  string
+	typeName   string
+	properties map[string]any
+}
+
+// cloudFormationApplier applies the resources of a CloudFormation template
+type cloudFormationApplier struct {
+	*applier
+	template   map[string]any
+	parameters map[string]string
+	resources  map[string]cloudFormationResource
+	// ids are the physical IDs of resources the stack already has, and the logical
+	// IDs of those it creates
+	ids       map[string]string
+	stackName string
+	stackID   string
+}
+
+// FromCloudFormation applies a CloudFormation template, in JSON or YAML, to a scanned
+// network. With the name of the stack deploying it, resources the stack already has,
+// found by the tags CloudFormation puts on them, are updated and those the template
+// no longer declares are deleted. Other resources are created with their logical ID
+// as ID. Parameters default to the template's defaults, conditions are assumed true,
+// and values computed by functions pikaatools does not evaluate are left Unknown.
+func FromCloudFormation(network *scanner.Network, data []byte, stackName string, parameters map[string]string) (*Preview, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse CloudFormation template: %w", err)
+	}
+	template, _ := templateValue(&document).(map[string]any)
+	declared, _ := template["Resources"].(map[string]any)
+	if len(declared) == 0 {
+		return nil, fmt.Errorf("failed to parse CloudFormation template: no Resources")
+	}
+
+	base, err := newApplier(network)
+	if err != nil {
+		return nil, err
+	}
+	c := &cloudFormationApplier{
+		applier:    base,
+		template:   template,
+		parameters: make(map[string]string),
+		resources:  make(map[string]cloudFormationResource),
+		ids:        make(map[string]string),
+		stackName:  stackName,
+		stackID:    Unknown,
+	}
+	c.loadParameters(parameters)
+	deployed := c.deployedResources()
+
+	rank := make(map[string]int, len(cloudFormationOrder))
+	for i, typeName := range cloudFormationOrder {
+		rank[typeName] = i + 1
+	}
+	var resources []cloudFormationResource
+	for logicalID, value := range declared {
+		definition, _ := value.(map[string]any)
+		resource := cloudFormationResource{logicalID: logicalID}
+		resource.typeName, _ = definition["Type"].(string)
+		resource.properties, _ = definition["Properties"].(map[string]any)
+		c.resources[logicalID] = resource
+		c.ids[logicalID] = logicalID
+		if id, ok := deployed[logicalID]; ok {
+			c.ids[logicalID] = id
+		}
+		if rank[resource.typeName] == 0 {
+			c.skip(logicalID)
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if rank[resources[i].typeName] != rank[resources[j].typeName] {
+			return rank[resources[i].typeName] < rank[resources[j].typeName]
+		}
+		return resources[i].logicalID < resources[j].logicalID
+	})
+
+	for logicalID, id := range deployed {
+		if _, ok := c.resources[logicalID]; !ok {
+			c.delete(id)
+		}
+	}
+	for _, resource := range resources {
+		_, exists := deployed[resource.logicalID]
+		c.apply(resource, !exists)
+	}
+	return c.finish(), nil
+}
+
+// templateValue converts a YAML node to Go values, turning the short form of
+// intrinsic functions such as !Ref and !GetAtt into their JSON form
+func templateValue(node *yaml.Node) any {
+	// Standard YAML tags start with !!, local ones like !Ref are intrinsic functions
+	intrinsic := strings.HasPrefix(node.Tag, "!") && !strings.HasPrefix(node.Tag, "!!")
+	var value any
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return templateValue(node.Content[0])
+	case yaml.AliasNode:
+		return templateValue(node.Alias)
+	case yaml.MappingNode:
+		mapping := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			mapping[node.Content[i].Value] = templateValue(node.Content[i+1])
+		}
+		value = mapping
+	case yaml.SequenceNode:
+		sequence := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			sequence = append(sequence, templateValue(item))
+		}
+		value = sequence
+	default:
+		if intrinsic {
+			value = node.Value
+		} else if err := node.Decode(&value); err != nil {
+			value = node.Value
+		}
+	}
+
+	switch {
+	case node.Tag == "!Ref":
+		return map[string]any{"Ref": value}
+	case node.Tag == "!GetAtt":
+		if s, ok := value.(string); ok {
+			logicalID, attribute, _ := strings.Cut(s, ".")
+			value = []any{logicalID, attribute}
+		}
+		return map[string]any{"Fn::GetAtt": value}
+	case intrinsic:
+		return map[string]any{"Fn::" + strings.TrimPrefix(node.Tag, "!"): value}
+	}
+	return value
+}
+
+// loadParameters sets the template's parameters to the given values or their defaults
+func (c *cloudFormationApplier) loadParameters(values map[string]string) {
+	declared, _ := c.template["Parameters"].(map[string]any)
+	for name, value := range declared {
+		definition, _ := value.(map[string]any)
+		if value, ok := values[name]; ok {
+			c.parameters[name] = value
+		} else if value, ok := definition["Default"]; ok {
+			c.parameters[name] = fmt.Sprint(value)
+		} else {
+			c.parameters[name] = Unknown
+		}
+	}
+	c.parameters["AWS::Region"] = c.network.Region
+	c.parameters["AWS::AccountId"] = c.network.AccountID
+	c.parameters["AWS::StackName"] = Unknown
+	if c.stackName != "" {
+		c.parameters["AWS::StackName"] = c.stackName
+	}
+}
+
+// deployedResources returns the physical IDs of the resources the stack already
+// has, by logical ID
+func (c *cloudFormationApplier) deployedResources() map[string]string {
+	deployed := make(map[string]string)
+	if c.stackName == "" {
+		return deployed
+	}
+	record := func(id string, tags map[string]string) {
+		if tags[stackNameTag] == c.stackName && tags[logicalIDTag] != "" {
+			deployed[tags[logicalIDTag]] = id
+			if tags[stackIDTag] != "" {
+				c.stackID = tags[stackIDTag]
+			}
+		}
+	}
+	for _, vpc := range c.network.VPCs {
+		record(vpc.ID, vpc.Tags)
+	}
+	for _, subnet := range c.network.Subnets {
+		record(subnet.ID, subnet.Tags)
+	}
+	for _, igw := range c.network.InternetGateways {
+		record(igw.ID, igw.Tags)
+	}
+	for _, nat := range c.network.NATGateways {
+		record(nat.ID, nat.Tags)
+	}
+	for _, table := range c.network.RouteTables {
+		record(table.ID, table.Tags)
+	}
+	for _, group := range c.network.SecurityGroups {
+		record(group.ID, group.Tags)
+	}
+	for _, acl := range c.network.NetworkAcls {
+		record(acl.ID, acl.Tags)
+	}
+	return deployed
+}
+
+// value evaluates a property to a string, or Unknown when it cannot be evaluated
+func (c *cloudFormationApplier) value(property any) string {
+	switch property := property.(type) {
+	case nil:
+		return ""
+	case string:
+		return property
+	case map[string]any:
+		return c.function(property)
+	case []any:
+		return Unknown
+	}
+	return fmt.Sprint(property)
+}
+
+// list evaluates a property holding a list, such as a comma-delimited list parameter
+func (c *cloudFormationApplier) list(property any) []string {
+	if items, ok := property.([]any); ok {
+		var values []string
+		for _, item := range items {
+			values = append(values, c.value(item))
+		}
+		return values
+	}
+	if property == nil {
+		return nil
+	}
+	value := c.value(property)
+	if value == Unknown {
+		return []string{Unknown}
+	}
+	return strings.Split(value, ",")
+}
+
+// function evaluates an intrinsic function
+func (c *cloudFormationApplier) function(call map[string]any) string {
+	if len(call) != 1 {
+		return Unknown
+	}
+	for name, args := range call {
+		switch name {
+		case "Ref":
+			return c.ref(fmt.Sprint(args))
+		case "Fn::GetAtt":
+			parts, _ := args.([]any)
+			if len(parts) != 2 {
+				return Unknown
+			}
+			return c.attribute(fmt.Sprint(parts[0]), fmt.Sprint(parts[1]))
+		case "Fn::Sub":
+			return c.sub(args)
+		case "Fn::Join":
+			parts, _ := args.([]any)
+			if len(parts) != 2 {
+				return Unknown
+			}
+			values := c.list(parts[1])
+			if contains(values, Unknown) {
+				return Unknown
+			}
+			return strings.Join(values, fmt.Sprint(parts[0]))
+		case "Fn::Select":
+			parts, _ := args.([]any)
+			if len(parts) != 2 {
+				return Unknown
+			}
+			var index int
+			if _, err := fmt.Sscan(c.value(parts[0]), &index); err != nil {
+				return Unknown
+			}
+			values := c.list(parts[1])
+			if index < 0 || index >= len(values) {
+				return Unknown
+			}
+			return values[index]
+		case "Fn::FindInMap":
+			parts, _ := args.([]any)
+			if len(parts) != 3 {
+				return Unknown
+			}
+			mappings, _ := c.template["Mappings"].(map[string]any)
+			mapping, _ := mappings[c.value(parts[0])].(map[string]any)
+			keys, _ := mapping[c.value(parts[1])].(map[string]any)
+			if value, ok := keys[c.value(parts[2])]; ok {
+				return c.value(value)
+			}
+		}
+	}
+	return Unknown
+}
+
+// ref evaluates a Ref to a parameter, pseudo parameter or resource
+func (c *cloudFormationApplier) ref(name string) string {
+	if name == "AWS::NoValue" {
+		return ""
+	}
+	if value, ok := c.parameters[name]; ok {
+		return value
+	}
+	if id, ok := c.ids[name]; ok {
+		return id
+	}
+	return Unknown
+}
+
+// attribute evaluates a GetAtt of a resource's ID or CIDR block, or of the default
+// network ACL or security group of a VPC
+func (c *cloudFormationApplier) attribute(logicalID, attribute string) string {
+	resource, ok := c.resources[logicalID]
+	if !ok {
+		return Unknown
+	}
+	id := c.ids[logicalID]
+
+	switch {
+	case attribute == "CidrBlock":
+		return c.value(resource.properties["CidrBlock"])
+	case attribute == "DefaultNetworkAcl":
+		for _, acl := range c.network.NetworkAcls {
+			if acl.VpcID == id && acl.IsDefault {
+				return acl.ID
+			}
+		}
+		return id + "/default-network-acl"
+	case attribute == "DefaultSecurityGroup":
+		for _, group := range c.network.SecurityGroups {
+			if group.VpcID == id && group.Name == "default" {
+				return group.ID
+			}
+		}
+		return id + "/default-security-group"
+	case attribute == "Id" || strings.HasSuffix(attribute, "Id"):
+		return id
+	}
+	return Unknown
+}
+
+// sub evaluates a Fn::Sub, in its string or string and variables form
+func (c *cloudFormationApplier) sub(args any) string {
+	text, _ := args.(string)
+	variables := map[string]any{}
+	if parts, ok := args.([]any); ok && len(parts) == 2 {
+		text, _ = parts[0].(string)
+		variables, _ = parts[1].(map[string]any)
+	}
+
+	var result strings.Builder
+	for {
+		start := strings.Index(text, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(text[start:], "}")
+		if end < 0 {
+			break
+		}
+		result.WriteString(text[:start])
+		name := text[start+2 : start+end]
+		var value string
+		switch {
+		case strings.HasPrefix(name, "!"):
+			value = "${" + name[1:] + "}"
+		case variables[name] != nil:
+			value = c.value(variables[name])
+		case strings.Contains(name, ".") && !strings.HasPrefix(name, "AWS::"):
+			logicalID, attribute, _ := strings.Cut(name, ".")
+			value = c.attribute(logicalID, attribute)
+		default:
+			value = c.ref(name)
+		}
+		if value == Unknown {
+			return Unknown
+		}
+		result.WriteString(value)
+		text = text[start+end+1:]
+	}
+	result.WriteString(text)
+	return result.String()
+}
+
+// tags evaluates a resource's Tags property, adding the tags CloudFormation puts on
+// the resources of a stack
+func (c *cloudFormationApplier) tags(resource cloudFormationResource) map[string]string {
+	tags := map[string]string{logicalIDTag: resource.logicalID}
+	if c.stackName != "" {
+		tags[stackNameTag] = c.stackName
+		tags[stackIDTag] = c.stackID
+	}
+	items, _ := resource.properties["Tags"].([]any)
+	for _, item := range items {
+		tag, _ := item.(map[string]any)
+		if key := c.value(tag["Key"]); key != "" {
+			tags[key] = c.value(tag["Value"])
+		}
+	}
+	return tags
+}
+
+// bool evaluates a boolean property
+func (c *cloudFormationApplier) bool(property any) bool {
+	return strings.EqualFold(c.value(property), "true")
+}
+
+// int evaluates a number property
+func (c *cloudFormationApplier) int(property any) int32 {
+	var n int32
+	fmt.Sscan(c.value(property), &n)
+	return n
+}
+
+// apply creates or updates a resource of the template
+func (c *cloudFormationApplier) apply(resource cloudFormationResource, created bool) {
+	p := resource.properties
+	id := c.ids[resource.logicalID]
+
+	switch resource.typeName {
+	case "AWS::EC2::VPC":
+		var vpc scanner.VPC
+		if old := find(c.network.VPCs, id, vpcID); old != nil {
+			vpc = *old
+		}
+		vpc.ID = id
+		vpc.CidrBlock = c.value(p["CidrBlock"])
+		vpc.Tags = c.tags(resource)
+		c.putVPC(vpc, created)
+
+	case "AWS::EC2::VPCCidrBlock":
+		vpc := find(c.network.VPCs, c.value(p["VpcId"]), vpcID)
+		if vpc == nil {
+			return
+		}
+		if cidr := c.value(p["Ipv6CidrBlock"]); cidr != "" && !contains(vpc.Ipv6CidrBlocks, cidr) {
+			vpc.Ipv6CidrBlocks = append(vpc.Ipv6CidrBlocks, cidr)
+		} else if c.bool(p["AmazonProvidedIpv6CidrBlock"]) && len(vpc.Ipv6CidrBlocks) == 0 {
+			vpc.Ipv6CidrBlocks = []string{Unknown}
+		}
+
+	case "AWS::EC2::Subnet":
+		var subnet scanner.Subnet
+		if old := find(c.network.Subnets, id, subnetID); old != nil {
+			subnet = *old
+		}
+		subnet.ID = id
+		subnet.VpcID = c.value(p["VpcId"])
+		subnet.CidrBlock = c.value(p["CidrBlock"])
+		subnet.Ipv6CidrBlocks = nonEmpty(c.value(p["Ipv6CidrBlock"]))
+		subnet.AvailabilityZone = c.value(p["AvailabilityZone"])
+		if subnet.AvailabilityZone == "" {
+			subnet.AvailabilityZone = Unknown
+		}
+		subnet.MapPublicIP = c.bool(p["MapPublicIpOnLaunch"])
+		subnet.AssignIpv6Address = c.bool(p["AssignIpv6AddressOnCreation"])
+		subnet.Tags = c.tags(resource)
+		c.putSubnet(subnet, created)
+
+	case "AWS::EC2::InternetGateway":
+		igw := scanner.InternetGateway{ID: id, Tags: c.tags(resource)}
+		// Attachments are separate resources, which are applied next
+		c.putInternetGateway(igw)
+
+	case "AWS::EC2::VPCGatewayAttachment":
+		if igw := find(c.network.InternetGateways, c.value(p["InternetGatewayId"]), igwID); igw != nil {
+			igw.VpcID = c.value(p["VpcId"])
+			igw.State = "available"
+		}
+
+	case "AWS::EC2::NatGateway":
+		var nat scanner.NATGateway
+		if old := find(c.network.NATGateways, id, natID); old != nil {
+			nat = *old
+		}
+		nat.ID = id
+		nat.SubnetID = c.value(p["SubnetId"])
+		nat.ConnectivityType = c.value(p["ConnectivityType"])
+		if created {
+			nat.PrivateIP = c.value(p["PrivateIpAddress"])
+			if nat.PrivateIP == "" {
+				nat.PrivateIP = Unknown
+			}
+			if nat.ConnectivityType != "private" {
+				nat.PublicIP = Unknown
+			}
+		}
+		nat.Tags = c.tags(resource)
+		c.putNATGateway(nat)
+
+	case "AWS::EC2::RouteTable":
+		// The stack's routes and associations are separate resources, which are applied next
+		c.putRouteTable(scanner.RouteTable{ID: id, VpcID: c.value(p["VpcId"]), Tags: c.tags(resource)}, created)
+		if table := find(c.network.RouteTables, id, routeTableID); table != nil {
+			table.Associations = nil
+		}
+
+	case "AWS::EC2::Route":
+		c.putRoute(c.value(p["RouteTableId"]), scanner.Route{
+			DestinationCidr:     c.value(p["DestinationCidrBlock"]),
+			DestinationIpv6Cidr: c.value(p["DestinationIpv6CidrBlock"]),
+			GatewayID:           c.value(p["GatewayId"]),
+			NatGatewayID:        c.value(p["NatGatewayId"]),
+			EgressOnlyGatewayID: c.value(p["EgressOnlyInternetGatewayId"]),
+			CarrierGatewayID:    c.value(p["CarrierGatewayId"]),
+			LocalGatewayID:      c.value(p["LocalGatewayId"]),
+			InstanceID:          c.value(p["InstanceId"]),
+			NetworkInterfaceID:  c.value(p["NetworkInterfaceId"]),
+			VpcPeeringID:        c.value(p["VpcPeeringConnectionId"]),
+			TransitGatewayID:    c.value(p["TransitGatewayId"]),
+		})
+
+	case "AWS::EC2::SubnetRouteTableAssociation":
+		c.associateRouteTable(c.value(p["SubnetId"]), c.value(p["RouteTableId"]))
+
+	case "AWS::EC2::SecurityGroup":
+		var group scanner.SecurityGroup
+		if old := find(c.network.SecurityGroups, id, securityGroupID); old != nil {
+			group = *old
+		}
+		group.ID = id
+		if name := c.value(p["GroupName"]); name != "" {
+			group.Name = name
+		} else if created {
+			group.Name = Unknown
+		}
+		group.Description = c.value(p["GroupDescription"])
+		group.VpcID = c.value(p["VpcId"])
+		group.Tags = c.tags(resource)
+		group.IngressRules = c.groupRules(p["SecurityGroupIngress"], "Source")
+		group.EgressRules = c.groupRules(p["SecurityGroupEgress"], "Destination")
+		// Groups declared without egress rules keep the rule AWS creates them with
+		if p["SecurityGroupEgress"] == nil {
+			group.EgressRules = []scanner.SecurityGroupRule{{IpProtocol: "-1", CidrBlocks: []string{"0.0.0.0/0"}}}
+		}
+		c.putSecurityGroup(group)
+
+	case "AWS::EC2::SecurityGroupIngress", "AWS::EC2::SecurityGroupEgress":
+		egress := resource.typeName == "AWS::EC2::SecurityGroupEgress"
+		prefix := "Source"
+		if egress {
+			prefix = "Destination"
+		}
+		groupID := c.value(p["GroupId"])
+		for _, rule := range splitRule(c.groupRule(p, prefix)) {
+			c.putSecurityGroupRule(groupID, rule, egress)
+		}
+
+	case "AWS::EC2::NetworkAcl":
+		var acl scanner.NetworkAcl
+		if old := find(c.network.NetworkAcls, id, networkAclID); old != nil {
+			acl = *old
+			// The stack's associations are separate resources, which are applied next
+			for _, subnet := range old.Associations {
+				c.disassociateNetworkAcl(subnet)
+			}
+		}
+		acl.ID = id
+		acl.VpcID = c.value(p["VpcId"])
+		acl.Tags = c.tags(resource)
+		acl.Entries = nil
+		acl.Associations = nil
+		c.putNetworkAcl(acl, nil)
+
+	case "AWS::EC2::NetworkAclEntry":
+		entry := scanner.NetworkAclEntry{
+			RuleNumber:    c.int(p["RuleNumber"]),
+			Protocol:      aclProtocol(c.value(p["Protocol"])),
+			RuleAction:    c.value(p["RuleAction"]),
+			CidrBlock:     c.value(p["CidrBlock"]),
+			Ipv6CidrBlock: c.value(p["Ipv6CidrBlock"]),
+			Egress:        c.bool(p["Egress"]),
+		}
+		if ports, ok := p["PortRange"].(map[string]any); ok {
+			entry.PortRange = &scanner.NetworkAclPortRange{From: c.int(ports["From"]), To: c.int(ports["To"])}
+		}
+		if icmp, ok := p["Icmp"].(map[string]any); ok {
+			entry.IcmpType = &scanner.NetworkAclIcmpType{Type: c.int(icmp["Type"]), Code: c.int(icmp["Code"])}
+		}
+		c.putNetworkAclEntry(c.value(p["NetworkAclId"]), entry)
+
+	case "AWS::EC2::SubnetNetworkAclAssociation":
+		c.associateNetworkAcl(c.value(p["SubnetId"]), c.value(p["NetworkAclId"]))
+	}
+}
+
+// groupRules evaluates the inline rules of a security group, one per source
+func (c *cloudFormationApplier) groupRules(property any, prefix string) []scanner.SecurityGroupRule {
+	items, _ := property.([]any)
+	var rules []scanner.SecurityGroupRule
+	for _, item := range items {
+		properties, _ := item.(map[string]any)
+		rules = append(rules, splitRule(c.groupRule(properties, prefix))...)
+	}
+	return rules
+}
+
+// groupRule evaluates a security group rule, whose prefix list and referenced group
+// are named with Source for ingress and Destination for egress rules
+func (c *cloudFormationApplier) groupRule(p map[string]any, prefix string) scanner.SecurityGroupRule {
+	rule := scanner.SecurityGroupRule{
+		IpProtocol:     groupProtocol(c.value(p["IpProtocol"])),
+		FromPort:       c.int(p["FromPort"]),
+		ToPort:         c.int(p["ToPort"]),
+		CidrBlocks:     nonEmpty(c.value(p["CidrIp"])),
+		Ipv6CidrBlocks: nonEmpty(c.value(p["CidrIpv6"])),
+		PrefixListIds:  nonEmpty(c.value(p[prefix+"PrefixListId"])),
+		Description:    c.value(p["Description"]),
+	}
+	if group := c.value(p[prefix+"SecurityGroupId"]); group != "" {
+		rule.ReferencedGroups = []scanner.SecurityGroupReference{{GroupID: group}}
+	}
+	return rule
+}
@@ -0,0 +1,141 @@
+package preview
+
+import (
+	"reflect"
+	"testing"
+)
+
+// cloudFormationTemplate creates a public subnet in an existing VPC, routed through
+// its internet gateway, with a security group opening a parameterised port
+const cloudFormationTemplate = `
+AWSTemplateFormatVersion: "2010-09-09"
+Parameters:
+  VpcId:
+    Type: AWS::EC2::VPC::Id
+  Port:
+    Type: Number
+    Default: 8080
+Resources:
+  PublicSubnet:
+    Type: AWS::EC2::Subnet
+    Properties:
+      VpcId: !Ref VpcId
+      CidrBlock: 10.0.2.0/24
+      AvailabilityZone: !Select [0, !GetAZs ""]
+      MapPublicIpOnLaunch: true
+      Tags:
+        - Key: Name
+          Value: !Sub "${AWS::Region}-public"
+  PublicRouteTable:
+    Type: AWS::EC2::RouteTable
+    Properties:
+      VpcId: !Ref VpcId
+  DefaultRoute:
+    Type: AWS::EC2::Route
+    Properties:
+      RouteTableId: !Ref PublicRouteTable
+      DestinationCidrBlock: 0.0.0.0/0
+      GatewayId: igw-1
+  PublicAssociation:
+    Type: AWS::EC2::SubnetRouteTableAssociation
+    Properties:
+      SubnetId: !Ref PublicSubnet
+      RouteTableId: !Ref PublicRouteTable
+  AppGroup:
+    Type: AWS::EC2::SecurityGroup
+    Properties:
+      GroupDescription: app
+      VpcId: !Ref VpcId
+      SecurityGroupIngress:
+        - IpProtocol: tcp
+          FromPort: !Ref Port
+          ToPort: !Ref Port
+          SourceSecurityGroupId: sg-web
+  WebGroupLink:
+    Type: AWS::EC2::SecurityGroupIngress
+    Properties:
+      GroupId: sg-web
+      IpProtocol: tcp
+      FromPort: 22
+      ToPort: 22
+      SourceSecurityGroupId: !GetAtt AppGroup.GroupId
+  Bastion:
+    Type: AWS::EC2::Instance
+`
+
+func TestFromCloudFormation(t *testing.T) {
+	preview, err := FromCloudFormation(previewNetwork(), []byte(cloudFormationTemplate), "", map[string]string{"VpcId": "vpc-1"})
+	if err != nil {
+		t.Fatalf("FromCloudFormation failed: %v", err)
+	}
+	result := preview.Network
+
+	subnet := find(result.Subnets, "PublicSubnet", subnetID)
+	if subnet == nil {
+		t.Fatalf("created subnet missing from %+v", result.Subnets)
+	}
+	if subnet.VpcID != "vpc-1" || subnet.Type != "public" || subnet.RouteTableID != "PublicRouteTable" {
+		t.Errorf("subnet in %s is %s through %s, want public in vpc-1 through PublicRouteTable", subnet.VpcID, subnet.Type, subnet.RouteTableID)
+	}
+	if subnet.Name != "us-east-1-public" || subnet.AvailabilityZone != Unknown {
+		t.Errorf("subnet named %q in %q, want us-east-1-public in an unknown zone", subnet.Name, subnet.AvailabilityZone)
+	}
+	if subnet.Tags[logicalIDTag] != "PublicSubnet" {
+		t.Errorf("subnet tags = %v, want the logical ID tag", subnet.Tags)
+	}
+
+	group := find(result.SecurityGroups, "AppGroup", securityGroupID)
+	if group == nil || len(group.IngressRules) != 1 || group.IngressRules[0].FromPort != 8080 {
+		t.Fatalf("created security group = %+v, want an ingress rule on port 8080", group)
+	}
+	if got := group.IngressRules[0].ReferencedGroupIDs(); !reflect.DeepEqual(got, []string{"sg-web"}) {
+		t.Errorf("referenced groups = %v, want sg-web", got)
+	}
+	if len(group.EgressRules) != 1 || group.EgressRules[0].IpProtocol != "-1" {
+		t.Errorf("egress rules = %+v, want the default allow all rule", group.EgressRules)
+	}
+
+	web := find(result.SecurityGroups, "sg-web", securityGroupID)
+	var sources []string
+	for _, rule := range web.IngressRules {
+		sources = append(sources, ruleKey(rule))
+	}
+	if want := []string{"tcp 22-22 AppGroup", "tcp 443-443 0.0.0.0/0"}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("sg-web ingress rules = %v, want %v", sources, want)
+	}
+
+	if !reflect.DeepEqual(preview.Skipped, []string{"Bastion"}) {
+		t.Errorf("Skipped = %v, want Bastion", preview.Skipped)
+	}
+}
+
+func TestFromCloudFormationDeployedStack(t *testing.T) {
+	network := previewNetwork()
+	stackTags := func(logicalID string) map[string]string {
+		return map[string]string{stackNameTag: "net", stackIDTag: "arn:stack/net", logicalIDTag: logicalID}
+	}
+	network.VPCs[0].Tags = stackTags("VPC")
+	network.Subnets[0].Tags = stackTags("PrivateSubnet")
+
+	template := `{"Resources": {"VPC": {"Type": "AWS::EC2::VPC", "Properties": {"CidrBlock": "10.0.0.0/16"}}}}`
+	preview, err := FromCloudFormation(network, []byte(template), "net", nil)
+	if err != nil {
+		t.Fatalf("FromCloudFormation failed: %v", err)
+	}
+
+	if len(preview.Network.Subnets) != 0 {
+		t.Errorf("subnets = %+v, want the subnet the template no longer declares deleted", preview.Network.Subnets)
+	}
+	if len(preview.Network.VPCs) != 1 || !reflect.DeepEqual(preview.Network.VPCs[0].Tags, stackTags("VPC")) {
+		t.Errorf("VPCs = %+v, want vpc-1 updated in place", preview.Network.VPCs)
+	}
+	if len(preview.Network.RouteTables) != 1 {
+		t.Errorf("route tables = %+v, want no defaults created for the existing VPC", preview.Network.RouteTables)
+	}
+}
+
+func TestFromCloudFormationInvalid(t *testing.T) {
+	if _, err := FromCloudFormation(previewNetwork(), []byte("Description: empty"), "", nil); err == nil {
+		t.Error("FromCloudFormation accepted a template without resources")
+	}
+}
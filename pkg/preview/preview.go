@@ -0,0 +1,560 @@
+// Package preview builds the network a Terraform plan or CloudFormation template
+// would leave behind, by applying its changes to a copy of a scanned network, so it
+// can be diffed against the network as it is before the change is deployed.
+package preview
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Unknown stands for a value that is only known once the change is applied, such as
+// a CIDR block computed by a function or an attribute of an unscanned resource
+const Unknown = "(known after apply)"
+
+// Preview is the network a plan or template would leave behind
+type Preview struct {
+	Network *scanner.Network
+	// Skipped lists the resources left out of the preview, as their types do not
+	// change what pikaatools scans
+	Skipped []string
+}
+
+// applier applies resource changes to a copy of a network
+type applier struct {
+	network *scanner.Network
+	// original is the network before any change, whose routes and rules recreated
+	// unchanged keep what was scanned
+	original *scanner.Network
+	skipped  []string
+}
+
+// newApplier copies a network for changes to be applied to
+func newApplier(network *scanner.Network) (*applier, error) {
+	data, err := json.Marshal(network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy network: %w", err)
+	}
+	var copied scanner.Network
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, fmt.Errorf("failed to copy network: %w", err)
+	}
+	return &applier{network: &copied, original: network}, nil
+}
+
+// skip records a resource of a type the preview does not model
+func (a *applier) skip(address string) {
+	a.skipped = append(a.skipped, address)
+}
+
+// finish derives subnet types and VPC associations for the changed network
+func (a *applier) finish() *Preview {
+	// Scans only record internet gateways attached to a VPC
+	attached := a.network.InternetGateways[:0]
+	for _, igw := range a.network.InternetGateways {
+		if igw.VpcID != "" {
+			attached = append(attached, igw)
+		}
+	}
+	a.network.InternetGateways = attached
+
+	a.network.Relink()
+	sort.Strings(a.skipped)
+	return &Preview{Network: a.network, Skipped: a.skipped}
+}
+
+// find returns the item with an ID, or nil
+func find[T any](items []T, id string, idOf func(*T) string) *T {
+	for i := range items {
+		if idOf(&items[i]) == id {
+			return &items[i]
+		}
+	}
+	return nil
+}
+
+// put replaces the item with the same ID, or appends it
+func put[T any](items *[]T, item T, idOf func(*T) string) {
+	if existing := find(*items, idOf(&item), idOf); existing != nil {
+		*existing = item
+		return
+	}
+	*items = append(*items, item)
+}
+
+// remove deletes the item with an ID
+func remove[T any](items *[]T, id string, idOf func(*T) string) {
+	kept := (*items)[:0]
+	for i := range *items {
+		if idOf(&(*items)[i]) != id {
+			kept = append(kept, (*items)[i])
+		}
+	}
+	*items = kept
+}
+
+func vpcID(vpc *scanner.VPC) string                       { return vpc.ID }
+func subnetID(subnet *scanner.Subnet) string              { return subnet.ID }
+func igwID(igw *scanner.InternetGateway) string           { return igw.ID }
+func natID(nat *scanner.NATGateway) string                { return nat.ID }
+func routeTableID(table *scanner.RouteTable) string       { return table.ID }
+func securityGroupID(group *scanner.SecurityGroup) string { return group.ID }
+func networkAclID(acl *scanner.NetworkAcl) string         { return acl.ID }
+
+// putVPC adds or updates a VPC. New VPCs get the main route table, default network
+// ACL and default security group AWS creates with them.
+func (a *applier) putVPC(vpc scanner.VPC, created bool) {
+	vpc.Name = vpc.Tags["Name"]
+	if vpc.State == "" {
+		vpc.State = "available"
+	}
+	put(&a.network.VPCs, vpc, vpcID)
+	if !created {
+		return
+	}
+
+	table := scanner.RouteTable{ID: vpc.ID + "/main-route-table", VpcID: vpc.ID, IsMain: true, Tags: map[string]string{}}
+	table.Routes = localRoutes(vpc)
+	put(&a.network.RouteTables, table, routeTableID)
+
+	acl := scanner.NetworkAcl{ID: vpc.ID + "/default-network-acl", VpcID: vpc.ID, IsDefault: true, Tags: map[string]string{}}
+	for _, egress := range []bool{false, true} {
+		acl.Entries = append(acl.Entries,
+			scanner.NetworkAclEntry{RuleNumber: 100, Protocol: "-1", RuleAction: "allow", CidrBlock: "0.0.0.0/0", Egress: egress},
+			scanner.NetworkAclEntry{RuleNumber: 32767, Protocol: "-1", RuleAction: "deny", CidrBlock: "0.0.0.0/0", Egress: egress})
+	}
+	put(&a.network.NetworkAcls, acl, networkAclID)
+
+	group := scanner.SecurityGroup{
+		ID:          vpc.ID + "/default-security-group",
+		Name:        "default",
+		Description: "default VPC security group",
+		VpcID:       vpc.ID,
+		Tags:        map[string]string{},
+	}
+	group.IngressRules = []scanner.SecurityGroupRule{{IpProtocol: "-1", ReferencedGroups: []scanner.SecurityGroupReference{{GroupID: group.ID}}}}
+	group.EgressRules = []scanner.SecurityGroupRule{{IpProtocol: "-1", CidrBlocks: []string{"0.0.0.0/0"}}}
+	put(&a.network.SecurityGroups, group, securityGroupID)
+}
+
+// localRoutes returns the routes every route table of a VPC has to the VPC itself
+func localRoutes(vpc scanner.VPC) []scanner.Route {
+	routes := []scanner.Route{{DestinationCidr: vpc.CidrBlock, GatewayID: "local", State: "active", Origin: "CreateRouteTable"}}
+	for _, cidr := range vpc.Ipv6CidrBlocks {
+		routes = append(routes, scanner.Route{DestinationIpv6Cidr: cidr, GatewayID: "local", State: "active", Origin: "CreateRouteTable"})
+	}
+	return routes
+}
+
+// putSubnet adds or updates a subnet. New subnets are associated with the default
+// network ACL of their VPC.
+func (a *applier) putSubnet(subnet scanner.Subnet, created bool) {
+	subnet.Name = subnet.Tags["Name"]
+	if subnet.State == "" {
+		subnet.State = "available"
+	}
+	put(&a.network.Subnets, subnet, subnetID)
+	if !created {
+		return
+	}
+	for _, acl := range a.network.NetworkAcls {
+		if acl.VpcID == subnet.VpcID && acl.IsDefault {
+			a.associateNetworkAcl(subnet.ID, acl.ID)
+			break
+		}
+	}
+}
+
+// putInternetGateway adds or updates an internet gateway
+func (a *applier) putInternetGateway(igw scanner.InternetGateway) {
+	igw.Name = igw.Tags["Name"]
+	if igw.State == "" && igw.VpcID != "" {
+		igw.State = "available"
+	}
+	put(&a.network.InternetGateways, igw, igwID)
+}
+
+// putNATGateway adds or updates a NAT gateway, in the VPC of its subnet
+func (a *applier) putNATGateway(nat scanner.NATGateway) {
+	nat.Name = nat.Tags["Name"]
+	if nat.State == "" {
+		nat.State = "available"
+	}
+	if nat.ConnectivityType == "" {
+		nat.ConnectivityType = "public"
+	}
+	if subnet := find(a.network.Subnets, nat.SubnetID, subnetID); subnet != nil {
+		nat.VpcID = subnet.VpcID
+	}
+	put(&a.network.NATGateways, nat, natID)
+}
+
+// putRouteTable adds or updates a route table. New route tables get the local
+// routes of their VPC, and routes unchanged from the scan keep what was scanned.
+func (a *applier) putRouteTable(table scanner.RouteTable, created bool) {
+	table.Name = table.Tags["Name"]
+	var existing []scanner.Route
+	if old := find(a.network.RouteTables, table.ID, routeTableID); old != nil {
+		existing = old.Routes
+		table.IsMain = old.IsMain
+		table.Associations = old.Associations
+	} else if vpc := find(a.network.VPCs, table.VpcID, vpcID); created && vpc != nil {
+		existing = localRoutes(*vpc)
+	}
+
+	routes := make([]scanner.Route, 0, len(existing)+len(table.Routes))
+	for _, route := range existing {
+		if route.GatewayID == "local" {
+			routes = append(routes, route)
+		}
+	}
+	for _, route := range table.Routes {
+		routes = append(routes, keepRoute(existing, route))
+	}
+	table.Routes = routes
+	put(&a.network.RouteTables, table, routeTableID)
+}
+
+// keepRoute returns the scanned route with the same destination and target as a
+// route, or the route as newly created
+func keepRoute(existing []scanner.Route, route scanner.Route) scanner.Route {
+	for _, old := range existing {
+		if routeKey(old) == routeKey(route) {
+			return old
+		}
+	}
+	route.State = "active"
+	route.Origin = "CreateRoute"
+	return route
+}
+
+// routeKey identifies a route by its destination and target
+func routeKey(route scanner.Route) string {
+	return strings.Join([]string{route.DestinationCidr, route.DestinationIpv6Cidr, route.GatewayID, route.NatGatewayID,
+		route.EgressOnlyGatewayID, route.CarrierGatewayID, route.LocalGatewayID, route.InstanceID,
+		route.NetworkInterfaceID, route.VpcPeeringID, route.TransitGatewayID}, "|")
+}
+
+// putRoute adds a route to a route table, replacing any route to the same destination
+func (a *applier) putRoute(tableID string, route scanner.Route) {
+	table := find(a.network.RouteTables, tableID, routeTableID)
+	if table == nil {
+		return
+	}
+	a.removeRoute(tableID, route)
+	var scanned []scanner.Route
+	if old := find(a.original.RouteTables, tableID, routeTableID); old != nil {
+		scanned = old.Routes
+	}
+	table.Routes = append(table.Routes, keepRoute(scanned, route))
+}
+
+// removeRoute removes the route to a route's destination from a route table
+func (a *applier) removeRoute(tableID string, route scanner.Route) {
+	table := find(a.network.RouteTables, tableID, routeTableID)
+	if table == nil {
+		return
+	}
+	kept := table.Routes[:0]
+	for _, old := range table.Routes {
+		if old.DestinationCidr != route.DestinationCidr || old.DestinationIpv6Cidr != route.DestinationIpv6Cidr {
+			kept = append(kept, old)
+		}
+	}
+	table.Routes = kept
+}
+
+// associateRouteTable associates a subnet with a route table, replacing its
+// previous association
+func (a *applier) associateRouteTable(subnet, tableID string) {
+	a.disassociateRouteTable(subnet)
+	if table := find(a.network.RouteTables, tableID, routeTableID); table != nil {
+		table.Associations = append(table.Associations, subnet)
+	}
+}
+
+// disassociateRouteTable returns a subnet to the main route table of its VPC
+func (a *applier) disassociateRouteTable(subnet string) {
+	for i := range a.network.RouteTables {
+		table := &a.network.RouteTables[i]
+		table.Associations = without(table.Associations, subnet)
+	}
+}
+
+// putSecurityGroup adds or updates a security group. Rules unchanged from the scan
+// keep their IDs and tags.
+func (a *applier) putSecurityGroup(group scanner.SecurityGroup) {
+	if old := find(a.network.SecurityGroups, group.ID, securityGroupID); old != nil {
+		group.IngressRules = keepRules(old.IngressRules, group.IngressRules)
+		group.EgressRules = keepRules(old.EgressRules, group.EgressRules)
+	}
+	put(&a.network.SecurityGroups, group, securityGroupID)
+}
+
+// keepRules returns rules with those matching a scanned rule replaced by it
+func keepRules(existing, rules []scanner.SecurityGroupRule) []scanner.SecurityGroupRule {
+	kept := make([]scanner.SecurityGroupRule, 0, len(rules))
+	for _, rule := range rules {
+		kept = append(kept, keepRule(existing, rule))
+	}
+	return kept
+}
+
+// keepRule returns the scanned rule with the same protocol, ports and source as a
+// rule, with the rule's description, or the rule itself
+func keepRule(existing []scanner.SecurityGroupRule, rule scanner.SecurityGroupRule) scanner.SecurityGroupRule {
+	for _, old := range existing {
+		if ruleKey(old) == ruleKey(rule) {
+			old.Description = rule.Description
+			return old
+		}
+	}
+	return rule
+}
+
+// ruleKey identifies a security group rule by its protocol, ports and sources
+func ruleKey(rule scanner.SecurityGroupRule) string {
+	sources := append(append(append(append([]string{}, rule.CidrBlocks...), rule.Ipv6CidrBlocks...), rule.PrefixListIds...), rule.ReferencedGroupIDs()...)
+	sort.Strings(sources)
+	return fmt.Sprintf("%s %d-%d %s", rule.IpProtocol, rule.FromPort, rule.ToPort, strings.Join(sources, ","))
+}
+
+// putSecurityGroupRule adds a rule to a security group, unless it already has it
+func (a *applier) putSecurityGroupRule(groupID string, rule scanner.SecurityGroupRule, egress bool) {
+	group := find(a.network.SecurityGroups, groupID, securityGroupID)
+	if group == nil {
+		return
+	}
+	rules := &group.IngressRules
+	if egress {
+		rules = &group.EgressRules
+	}
+	for _, old := range *rules {
+		if ruleKey(old) == ruleKey(rule) {
+			return
+		}
+	}
+	var scanned []scanner.SecurityGroupRule
+	if old := find(a.original.SecurityGroups, groupID, securityGroupID); old != nil {
+		scanned = old.IngressRules
+		if egress {
+			scanned = old.EgressRules
+		}
+	}
+	*rules = append(*rules, keepRule(scanned, rule))
+}
+
+// removeSecurityGroupRule removes the rules matching a rule, or with its ID, from a
+// security group
+func (a *applier) removeSecurityGroupRule(groupID string, rule scanner.SecurityGroupRule, egress bool) {
+	group := find(a.network.SecurityGroups, groupID, securityGroupID)
+	if group == nil {
+		return
+	}
+	rules := &group.IngressRules
+	if egress {
+		rules = &group.EgressRules
+	}
+	kept := (*rules)[:0]
+	for _, old := range *rules {
+		if (rule.ID == "" || old.ID != rule.ID) && ruleKey(old) != ruleKey(rule) {
+			kept = append(kept, old)
+		}
+	}
+	*rules = kept
+}
+
+// putNetworkAcl adds or updates a network ACL, with the deny-all rules every
+// network ACL ends with, and associates it with its subnets
+func (a *applier) putNetworkAcl(acl scanner.NetworkAcl, subnets []string) {
+	acl.Name = acl.Tags["Name"]
+	for _, egress := range []bool{false, true} {
+		if !hasEntry(acl.Entries, 32767, egress) {
+			acl.Entries = append(acl.Entries, scanner.NetworkAclEntry{RuleNumber: 32767, Protocol: "-1", RuleAction: "deny", CidrBlock: "0.0.0.0/0", Egress: egress})
+		}
+	}
+	if old := find(a.network.NetworkAcls, acl.ID, networkAclID); old != nil {
+		acl.Associations = old.Associations
+	}
+	put(&a.network.NetworkAcls, acl, networkAclID)
+
+	if subnets == nil {
+		return
+	}
+	for _, subnet := range acl.Associations {
+		if !contains(subnets, subnet) {
+			a.disassociateNetworkAcl(subnet)
+		}
+	}
+	for _, subnet := range subnets {
+		a.associateNetworkAcl(subnet, acl.ID)
+	}
+}
+
+// hasEntry reports whether entries include a rule number in a direction
+func hasEntry(entries []scanner.NetworkAclEntry, ruleNumber int32, egress bool) bool {
+	for _, entry := range entries {
+		if entry.RuleNumber == ruleNumber && entry.Egress == egress {
+			return true
+		}
+	}
+	return false
+}
+
+// putNetworkAclEntry adds an entry to a network ACL, replacing the entry with the
+// same rule number and direction
+func (a *applier) putNetworkAclEntry(aclID string, entry scanner.NetworkAclEntry) {
+	acl := find(a.network.NetworkAcls, aclID, networkAclID)
+	if acl == nil {
+		return
+	}
+	a.removeNetworkAclEntry(aclID, entry)
+	acl.Entries = append(acl.Entries, entry)
+}
+
+// removeNetworkAclEntry removes the entry with a rule number and direction
+func (a *applier) removeNetworkAclEntry(aclID string, entry scanner.NetworkAclEntry) {
+	acl := find(a.network.NetworkAcls, aclID, networkAclID)
+	if acl == nil {
+		return
+	}
+	kept := acl.Entries[:0]
+	for _, old := range acl.Entries {
+		if old.RuleNumber != entry.RuleNumber || old.Egress != entry.Egress {
+			kept = append(kept, old)
+		}
+	}
+	acl.Entries = kept
+}
+
+// associateNetworkAcl associates a subnet with a network ACL, replacing its previous
+// association
+func (a *applier) associateNetworkAcl(subnet, aclID string) {
+	for i := range a.network.NetworkAcls {
+		acl := &a.network.NetworkAcls[i]
+		acl.Associations = without(acl.Associations, subnet)
+		if acl.ID == aclID {
+			acl.Associations = append(acl.Associations, subnet)
+		}
+	}
+}
+
+// disassociateNetworkAcl returns a subnet to the default network ACL of its VPC
+func (a *applier) disassociateNetworkAcl(subnet string) {
+	s := find(a.network.Subnets, subnet, subnetID)
+	if s == nil {
+		return
+	}
+	for _, acl := range a.network.NetworkAcls {
+		if acl.VpcID == s.VpcID && acl.IsDefault {
+			a.associateNetworkAcl(subnet, acl.ID)
+			return
+		}
+	}
+}
+
+// delete removes a resource by ID, with the associations subnets had with it.
+// Subnets of a deleted network ACL return to the default one.
+func (a *applier) delete(id string) {
+	if acl := find(a.network.NetworkAcls, id, networkAclID); acl != nil && !acl.IsDefault {
+		for _, subnet := range acl.Associations {
+			a.disassociateNetworkAcl(subnet)
+		}
+	}
+	remove(&a.network.VPCs, id, vpcID)
+	remove(&a.network.Subnets, id, subnetID)
+	remove(&a.network.InternetGateways, id, igwID)
+	remove(&a.network.NATGateways, id, natID)
+	remove(&a.network.RouteTables, id, routeTableID)
+	remove(&a.network.SecurityGroups, id, securityGroupID)
+	remove(&a.network.NetworkAcls, id, networkAclID)
+	for i := range a.network.RouteTables {
+		a.network.RouteTables[i].Associations = without(a.network.RouteTables[i].Associations, id)
+	}
+	for i := range a.network.NetworkAcls {
+		a.network.NetworkAcls[i].Associations = without(a.network.NetworkAcls[i].Associations, id)
+	}
+}
+
+// without returns values with every occurrence of a value removed
+func without(values []string, value string) []string {
+	var kept []string
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// contains reports whether values include a value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// protocolNumbers maps the protocol names network ACLs accept to the numbers AWS
+// reports for them
+var protocolNumbers = map[string]string{"all": "-1", "tcp": "6", "udp": "17", "icmp": "1", "icmpv6": "58"}
+
+// protocolNames maps the protocol numbers security groups accept to the names AWS
+// reports for them
+var protocolNames = map[string]string{"all": "-1", "6": "tcp", "17": "udp", "1": "icmp", "58": "icmpv6"}
+
+// aclProtocol normalizes a network ACL entry's protocol to the number AWS reports
+func aclProtocol(protocol string) string {
+	if number, ok := protocolNumbers[strings.ToLower(protocol)]; ok {
+		return number
+	}
+	return protocol
+}
+
+// groupProtocol normalizes a security group rule's protocol to the name AWS reports
+func groupProtocol(protocol string) string {
+	protocol = strings.ToLower(protocol)
+	if name, ok := protocolNames[protocol]; ok {
+		return name
+	}
+	return protocol
+}
+
+// splitRule splits a rule with several sources into one rule per source, the way
+// scans record them
+func splitRule(rule scanner.SecurityGroupRule) []scanner.SecurityGroupRule {
+	if rule.IpProtocol == "-1" {
+		rule.FromPort, rule.ToPort = 0, 0
+	}
+	base := rule
+	base.CidrBlocks, base.Ipv6CidrBlocks, base.PrefixListIds, base.ReferencedGroups = nil, nil, nil, nil
+
+	var rules []scanner.SecurityGroupRule
+	for _, cidr := range rule.CidrBlocks {
+		r := base
+		r.CidrBlocks = []string{cidr}
+		rules = append(rules, r)
+	}
+	for _, cidr := range rule.Ipv6CidrBlocks {
+		r := base
+		r.Ipv6CidrBlocks = []string{cidr}
+		rules = append(rules, r)
+	}
+	for _, prefixList := range rule.PrefixListIds {
+		r := base
+		r.PrefixListIds = []string{prefixList}
+		rules = append(rules, r)
+	}
+	for _, group := range rule.ReferencedGroups {
+		r := base
+		r.ReferencedGroups = []scanner.SecurityGroupReference{group}
+		rules = append(rules, r)
+	}
+	return rules
+}
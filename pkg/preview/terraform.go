@@ -0,0 +1,689 @@
+package preview
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// terraformPlan is the part of `terraform show -json` output for a saved plan the
+// preview reads
+type terraformPlan struct {
+	FormatVersion   string                    `json:"format_version"`
+	ResourceChanges []terraformResourceChange `json:"resource_changes"`
+	Configuration   struct {
+		RootModule terraformModule `json:"root_module"`
+	} `json:"configuration"`
+}
+
+// terraformResourceChange is a resource the plan creates, updates, replaces, deletes
+// or leaves alone
+type terraformResourceChange struct {
+	Address       string `json:"address"`
+	ModuleAddress string `json:"module_address"`
+	Mode          string `json:"mode"`
+	Type          string `json:"type"`
+	Name          string `json:"name"`
+	Index         any    `json:"index"`
+	Change        struct {
+		Actions      []string       `json:"actions"`
+		Before       map[string]any `json:"before"`
+		After        map[string]any `json:"after"`
+		AfterUnknown map[string]any `json:"after_unknown"`
+	} `json:"change"`
+}
+
+// terraformModule is a module of the plan's configuration, whose expressions hold
+// the references that resolve attributes only known after apply
+type terraformModule struct {
+	Resources []struct {
+		Address     string         `json:"address"`
+		Expressions map[string]any `json:"expressions"`
+	} `json:"resources"`
+	ModuleCalls map[string]struct {
+		Module terraformModule `json:"module"`
+	} `json:"module_calls"`
+}
+
+// terraformOrder is the order resource types are applied in, so resources exist
+// before the resources attached to them
+var terraformOrder = []string{
+	"aws_vpc",
+	"aws_subnet",
+	"aws_internet_gateway",
+	"aws_internet_gateway_attachment",
+	"aws_nat_gateway",
+	"aws_route_table",
+	"aws_route",
+	"aws_route_table_association",
+	"aws_security_group",
+	"aws_security_group_rule",
+	"aws_vpc_security_group_ingress_rule",
+	"aws_vpc_security_group_egress_rule",
+	"aws_network_acl",
+	"aws_network_acl_rule",
+	"aws_network_acl_association",
+}
+
+// terraformInPlace are the resource types updates modify in place. The others are
+// part of another resource, and their updates remove them before adding them back.
+var terraformInPlace = map[string]bool{
+	"aws_vpc":              true,
+	"aws_subnet":           true,
+	"aws_internet_gateway": true,
+	"aws_nat_gateway":      true,
+	"aws_route_table":      true,
+	"aws_security_group":   true,
+	"aws_network_acl":      true,
+}
+
+var moduleIndex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// terraformApplier applies the resource changes of a Terraform plan
+type terraformApplier struct {
+	*applier
+	byAddress   map[string]*terraformResourceChange
+	byConfig    map[string][]*terraformResourceChange
+	expressions map[string]map[string]any
+}
+
+// FromTerraformPlan applies a Terraform plan, as printed by `terraform show -json`,
+// to a scanned network. Resources the plan creates get their Terraform address as
+// ID, and attributes only known after apply are resolved through the references in
+// the plan's configuration or left Unknown.
+func FromTerraformPlan(network *scanner.Network, data []byte) (*Preview, error) {
+	var plan terraformPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse Terraform plan: %w", err)
+	}
+	if plan.FormatVersion == "" {
+		return nil, fmt.Errorf("failed to parse Terraform plan: no format_version, convert the plan with terraform show -json")
+	}
+
+	base, err := newApplier(network)
+	if err != nil {
+		return nil, err
+	}
+	t := &terraformApplier{
+		applier:     base,
+		byAddress:   make(map[string]*terraformResourceChange),
+		byConfig:    make(map[string][]*terraformResourceChange),
+		expressions: make(map[string]map[string]any),
+	}
+	for i := range plan.ResourceChanges {
+		change := &plan.ResourceChanges[i]
+		t.byAddress[change.Address] = change
+		t.byConfig[configAddress(change)] = append(t.byConfig[configAddress(change)], change)
+	}
+	t.collectExpressions(plan.Configuration.RootModule, "")
+
+	rank := make(map[string]int, len(terraformOrder))
+	for i, resourceType := range terraformOrder {
+		rank[resourceType] = i + 1
+	}
+	var changes []*terraformResourceChange
+	for i := range plan.ResourceChanges {
+		change := &plan.ResourceChanges[i]
+		if change.Mode != "managed" || changeAction(change) == "no-op" {
+			continue
+		}
+		if rank[change.Type] == 0 {
+			t.skip(change.Address)
+			continue
+		}
+		changes = append(changes, change)
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return rank[changes[i].Type] < rank[changes[j].Type] })
+
+	// Everything deleted goes first, so replacements do not collide with what they replace
+	for _, change := range changes {
+		action := changeAction(change)
+		if action == "delete" || action == "replace" || (action == "update" && !terraformInPlace[change.Type]) {
+			t.remove(change)
+		}
+	}
+	for _, change := range changes {
+		switch changeAction(change) {
+		case "create", "replace":
+			t.apply(change, true)
+		case "update":
+			t.apply(change, false)
+		}
+	}
+	return t.finish(), nil
+}
+
+// changeAction summarizes a change's actions as create, update, replace, delete or no-op
+func changeAction(change *terraformResourceChange) string {
+	actions := change.Change.Actions
+	switch {
+	case len(actions) == 2:
+		return "replace"
+	case len(actions) == 1 && (actions[0] == "create" || actions[0] == "update" || actions[0] == "delete"):
+		return actions[0]
+	}
+	return "no-op"
+}
+
+// configAddress returns the address of a change's resource in the configuration,
+// without the instance keys of counted resources and modules
+func configAddress(change *terraformResourceChange) string {
+	address := change.Type + "." + change.Name
+	if change.Mode == "data" {
+		address = "data." + address
+	}
+	return modulePrefix(change) + address
+}
+
+// modulePrefix returns the configuration address of a change's module with a
+// trailing dot, or nothing for the root module
+func modulePrefix(change *terraformResourceChange) string {
+	if change.ModuleAddress == "" {
+		return ""
+	}
+	return moduleIndex.ReplaceAllString(change.ModuleAddress, "") + "."
+}
+
+// collectExpressions indexes the expressions of every resource in a module and the
+// modules it calls by configuration address
+func (t *terraformApplier) collectExpressions(module terraformModule, prefix string) {
+	for _, resource := range module.Resources {
+		t.expressions[prefix+resource.Address] = resource.Expressions
+	}
+	for name, call := range module.ModuleCalls {
+		t.collectExpressions(call.Module, prefix+"module."+name+".")
+	}
+}
+
+// id returns the ID a change's resource has after apply: its scanned ID when it is
+// kept, or its address when it is created
+func (t *terraformApplier) id(change *terraformResourceChange) string {
+	switch action := changeAction(change); {
+	case change.Mode == "data":
+		id, _ := change.Change.After["id"].(string)
+		return id
+	case action == "create" || action == "replace":
+		return change.Address
+	}
+	id, _ := change.Change.Before["id"].(string)
+	return id
+}
+
+// lookup finds the resource a reference in a change's module refers to
+func (t *terraformApplier) lookup(address string, from *terraformResourceChange) (string, bool) {
+	if change, ok := t.byAddress[address]; ok {
+		return t.id(change), true
+	}
+	candidates := t.byConfig[address]
+	if len(candidates) == 1 {
+		return t.id(candidates[0]), true
+	}
+	// Counted resources referring to each other usually pair up by index
+	for _, candidate := range candidates {
+		if fmt.Sprint(candidate.Index) == fmt.Sprint(from.Index) {
+			return t.id(candidate), true
+		}
+	}
+	return "", false
+}
+
+// resolve returns the IDs of the resources an expression refers to
+func (t *terraformApplier) resolve(from *terraformResourceChange, expression any) []string {
+	expr, _ := expression.(map[string]any)
+	references, _ := expr["references"].([]any)
+
+	var ids []string
+	for _, reference := range references {
+		candidate, _ := reference.(string)
+		for candidate != "" {
+			if id, ok := t.lookup(modulePrefix(from)+candidate, from); ok {
+				if !contains(ids, id) {
+					ids = append(ids, id)
+				}
+				break
+			}
+			candidate = parentReference(candidate)
+		}
+	}
+	return ids
+}
+
+// parentReference strips the last attribute or instance key from a reference
+func parentReference(reference string) string {
+	if strings.HasSuffix(reference, "]") {
+		return reference[:strings.LastIndex(reference, "[")]
+	}
+	if i := strings.LastIndex(reference, "."); i > 0 {
+		return reference[:i]
+	}
+	return ""
+}
+
+// values returns the attributes a change leaves its resource with, or had before
+// for deletions
+func (t *terraformApplier) values(change *terraformResourceChange, before bool) terraformValues {
+	if before {
+		return terraformValues{t: t, change: change, values: change.Change.Before}
+	}
+	return terraformValues{
+		t:       t,
+		change:  change,
+		values:  change.Change.After,
+		unknown: change.Change.AfterUnknown,
+		exprs:   t.expressions[configAddress(change)],
+	}
+}
+
+// terraformValues reads the attributes of a resource, or of one of its nested
+// blocks, from a plan
+type terraformValues struct {
+	t       *terraformApplier
+	change  *terraformResourceChange
+	values  map[string]any
+	unknown map[string]any
+	exprs   map[string]any
+}
+
+// str returns a string attribute, or Unknown when it is only known after apply
+func (v terraformValues) str(key string) string {
+	if s, ok := v.values[key].(string); ok {
+		return s
+	}
+	if isUnknown(v.unknown[key]) {
+		return Unknown
+	}
+	return ""
+}
+
+// ref returns an attribute holding a resource ID, resolving IDs only known after
+// apply through the configuration
+func (v terraformValues) ref(key string) string {
+	if s, ok := v.values[key].(string); ok && s != "" {
+		return s
+	}
+	if isUnknown(v.unknown[key]) {
+		if ids := v.t.resolve(v.change, v.exprs[key]); len(ids) > 0 {
+			return ids[0]
+		}
+		return Unknown
+	}
+	return ""
+}
+
+// strs returns a list of strings attribute, or nil when it is not set
+func (v terraformValues) strs(key string) []string {
+	list, ok := v.values[key].([]any)
+	if !ok && !isUnknown(v.unknown[key]) {
+		return nil
+	}
+	result := []string{}
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	if isUnknown(v.unknown[key]) {
+		result = append(result, Unknown)
+	}
+	return result
+}
+
+// refs returns a list of resource IDs attribute, or nil when it is not set
+func (v terraformValues) refs(key string) []string {
+	list, ok := v.values[key].([]any)
+	if !ok && !isUnknown(v.unknown[key]) {
+		return nil
+	}
+	result := []string{}
+	for _, item := range list {
+		if s, ok := item.(string); ok && !contains(result, s) {
+			result = append(result, s)
+		}
+	}
+	if isUnknown(v.unknown[key]) {
+		resolved := v.t.resolve(v.change, v.exprs[key])
+		if len(resolved) == 0 {
+			resolved = []string{Unknown}
+		}
+		for _, id := range resolved {
+			if !contains(result, id) {
+				result = append(result, id)
+			}
+		}
+	}
+	return result
+}
+
+// int returns a number attribute
+func (v terraformValues) int(key string) int32 {
+	switch value := v.values[key].(type) {
+	case float64:
+		return int32(value)
+	case string:
+		n, _ := strconv.Atoi(value)
+		return int32(n)
+	}
+	return 0
+}
+
+// bool returns a boolean attribute
+func (v terraformValues) bool(key string) bool {
+	value, _ := v.values[key].(bool)
+	return value
+}
+
+// tags returns the tags a resource is given, including the provider's default tags
+func (v terraformValues) tags() map[string]string {
+	tags := make(map[string]string)
+	for _, key := range []string{"tags", "tags_all"} {
+		values, _ := v.values[key].(map[string]any)
+		for name, value := range values {
+			if s, ok := value.(string); ok {
+				tags[name] = s
+			}
+		}
+	}
+	return tags
+}
+
+// blocks returns the nested blocks of an attribute
+func (v terraformValues) blocks(key string) []terraformValues {
+	list, _ := v.values[key].([]any)
+	unknown, _ := v.unknown[key].([]any)
+	exprs, _ := v.exprs[key].([]any)
+
+	var blocks []terraformValues
+	for i, item := range list {
+		block := terraformValues{t: v.t, change: v.change}
+		block.values, _ = item.(map[string]any)
+		if i < len(unknown) {
+			block.unknown, _ = unknown[i].(map[string]any)
+		}
+		if i < len(exprs) {
+			block.exprs, _ = exprs[i].(map[string]any)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// isUnknown reports whether an after_unknown value marks an attribute, or any
+// element of it, as only known after apply
+func isUnknown(value any) bool {
+	switch value := value.(type) {
+	case bool:
+		return value
+	case []any:
+		for _, item := range value {
+			if isUnknown(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// apply creates or updates the resource of a change
+func (t *terraformApplier) apply(change *terraformResourceChange, created bool) {
+	v := t.values(change, false)
+	id := t.id(change)
+
+	switch change.Type {
+	case "aws_vpc":
+		var vpc scanner.VPC
+		if old := find(t.network.VPCs, id, vpcID); old != nil {
+			vpc = *old
+		}
+		vpc.ID = id
+		vpc.CidrBlock = v.str("cidr_block")
+		vpc.Ipv6CidrBlocks = nonEmpty(v.str("ipv6_cidr_block"))
+		vpc.Tags = v.tags()
+		t.putVPC(vpc, created)
+
+	case "aws_subnet":
+		var subnet scanner.Subnet
+		if old := find(t.network.Subnets, id, subnetID); old != nil {
+			subnet = *old
+		}
+		subnet.ID = id
+		subnet.VpcID = v.ref("vpc_id")
+		subnet.CidrBlock = v.str("cidr_block")
+		subnet.Ipv6CidrBlocks = nonEmpty(v.str("ipv6_cidr_block"))
+		subnet.AvailabilityZone = v.str("availability_zone")
+		subnet.MapPublicIP = v.bool("map_public_ip_on_launch")
+		subnet.AssignIpv6Address = v.bool("assign_ipv6_address_on_creation")
+		subnet.Tags = v.tags()
+		t.putSubnet(subnet, created)
+
+	case "aws_internet_gateway":
+		igw := scanner.InternetGateway{ID: id, VpcID: v.ref("vpc_id"), Tags: v.tags()}
+		t.putInternetGateway(igw)
+
+	case "aws_internet_gateway_attachment":
+		if igw := find(t.network.InternetGateways, v.ref("internet_gateway_id"), igwID); igw != nil {
+			igw.VpcID = v.ref("vpc_id")
+			igw.State = "available"
+		}
+
+	case "aws_nat_gateway":
+		var nat scanner.NATGateway
+		if old := find(t.network.NATGateways, id, natID); old != nil {
+			nat = *old
+		}
+		nat.ID = id
+		nat.SubnetID = v.ref("subnet_id")
+		nat.ConnectivityType = v.str("connectivity_type")
+		nat.PublicIP = v.str("public_ip")
+		nat.PrivateIP = v.str("private_ip")
+		nat.Tags = v.tags()
+		t.putNATGateway(nat)
+
+	case "aws_route_table":
+		table := scanner.RouteTable{ID: id, VpcID: v.ref("vpc_id"), Tags: v.tags()}
+		for _, block := range v.blocks("route") {
+			if route := terraformRoute(block, "cidr_block", "ipv6_cidr_block"); route.GatewayID != "local" {
+				table.Routes = append(table.Routes, route)
+			}
+		}
+		t.putRouteTable(table, created)
+
+	case "aws_route":
+		t.putRoute(v.ref("route_table_id"), terraformRoute(v, "destination_cidr_block", "destination_ipv6_cidr_block"))
+
+	case "aws_route_table_association":
+		if subnet := v.ref("subnet_id"); subnet != "" {
+			t.associateRouteTable(subnet, v.ref("route_table_id"))
+		}
+
+	case "aws_security_group":
+		var group scanner.SecurityGroup
+		if old := find(t.network.SecurityGroups, id, securityGroupID); old != nil {
+			group = *old
+		}
+		group.ID = id
+		group.Name = v.str("name")
+		group.Description = v.str("description")
+		group.VpcID = v.ref("vpc_id")
+		group.Tags = v.tags()
+		group.IngressRules, group.EgressRules = nil, nil
+		for _, block := range v.blocks("ingress") {
+			group.IngressRules = append(group.IngressRules, splitRule(terraformGroupRule(block, id, "security_groups"))...)
+		}
+		for _, block := range v.blocks("egress") {
+			group.EgressRules = append(group.EgressRules, splitRule(terraformGroupRule(block, id, "security_groups"))...)
+		}
+		t.putSecurityGroup(group)
+
+	case "aws_security_group_rule":
+		groupID := v.ref("security_group_id")
+		for _, rule := range splitRule(terraformGroupRule(v, groupID, "source_security_group_id")) {
+			t.putSecurityGroupRule(groupID, rule, v.str("type") == "egress")
+		}
+
+	case "aws_vpc_security_group_ingress_rule", "aws_vpc_security_group_egress_rule":
+		rule := terraformVPCGroupRule(v)
+		if !created {
+			rule.ID = id
+		}
+		t.putSecurityGroupRule(v.ref("security_group_id"), rule, change.Type == "aws_vpc_security_group_egress_rule")
+
+	case "aws_network_acl":
+		var acl scanner.NetworkAcl
+		if old := find(t.network.NetworkAcls, id, networkAclID); old != nil {
+			acl = *old
+		}
+		acl.ID = id
+		acl.VpcID = v.ref("vpc_id")
+		acl.Tags = v.tags()
+		acl.Entries = nil
+		for _, block := range v.blocks("ingress") {
+			acl.Entries = append(acl.Entries, terraformAclEntry(block, "rule_no", "action", false))
+		}
+		for _, block := range v.blocks("egress") {
+			acl.Entries = append(acl.Entries, terraformAclEntry(block, "rule_no", "action", true))
+		}
+		t.putNetworkAcl(acl, v.refs("subnet_ids"))
+
+	case "aws_network_acl_rule":
+		t.putNetworkAclEntry(v.ref("network_acl_id"), terraformAclEntry(v, "rule_number", "rule_action", v.bool("egress")))
+
+	case "aws_network_acl_association":
+		t.associateNetworkAcl(v.ref("subnet_id"), v.ref("network_acl_id"))
+	}
+}
+
+// remove deletes the resource of a change as it was before the change
+func (t *terraformApplier) remove(change *terraformResourceChange) {
+	v := t.values(change, true)
+
+	switch change.Type {
+	case "aws_internet_gateway_attachment":
+		if igw := find(t.network.InternetGateways, v.str("internet_gateway_id"), igwID); igw != nil {
+			igw.VpcID = ""
+		}
+
+	case "aws_route":
+		t.removeRoute(v.str("route_table_id"), terraformRoute(v, "destination_cidr_block", "destination_ipv6_cidr_block"))
+
+	case "aws_route_table_association":
+		if table := find(t.network.RouteTables, v.str("route_table_id"), routeTableID); table != nil {
+			table.Associations = without(table.Associations, v.str("subnet_id"))
+		}
+
+	case "aws_security_group_rule":
+		groupID := v.str("security_group_id")
+		for _, rule := range splitRule(terraformGroupRule(v, groupID, "source_security_group_id")) {
+			t.removeSecurityGroupRule(groupID, rule, v.str("type") == "egress")
+		}
+
+	case "aws_vpc_security_group_ingress_rule", "aws_vpc_security_group_egress_rule":
+		rule := terraformVPCGroupRule(v)
+		rule.ID = v.str("id")
+		t.removeSecurityGroupRule(v.str("security_group_id"), rule, change.Type == "aws_vpc_security_group_egress_rule")
+
+	case "aws_network_acl_rule":
+		t.removeNetworkAclEntry(v.str("network_acl_id"), terraformAclEntry(v, "rule_number", "rule_action", v.bool("egress")))
+
+	case "aws_network_acl_association":
+		t.disassociateNetworkAcl(v.str("subnet_id"))
+
+	default:
+		t.delete(v.str("id"))
+	}
+}
+
+// terraformRoute converts a route of an aws_route_table or an aws_route
+func terraformRoute(v terraformValues, cidrKey, ipv6Key string) scanner.Route {
+	return scanner.Route{
+		DestinationCidr:     v.str(cidrKey),
+		DestinationIpv6Cidr: v.str(ipv6Key),
+		GatewayID:           v.ref("gateway_id"),
+		NatGatewayID:        v.ref("nat_gateway_id"),
+		EgressOnlyGatewayID: v.ref("egress_only_gateway_id"),
+		CarrierGatewayID:    v.ref("carrier_gateway_id"),
+		LocalGatewayID:      v.ref("local_gateway_id"),
+		NetworkInterfaceID:  v.ref("network_interface_id"),
+		VpcPeeringID:        v.ref("vpc_peering_connection_id"),
+		TransitGatewayID:    v.ref("transit_gateway_id"),
+	}
+}
+
+// terraformGroupRule converts an inline rule of an aws_security_group or an
+// aws_security_group_rule, whose referenced groups are in groupsKey
+func terraformGroupRule(v terraformValues, groupID, groupsKey string) scanner.SecurityGroupRule {
+	rule := scanner.SecurityGroupRule{
+		IpProtocol:     groupProtocol(v.str("protocol")),
+		FromPort:       v.int("from_port"),
+		ToPort:         v.int("to_port"),
+		CidrBlocks:     v.strs("cidr_blocks"),
+		Ipv6CidrBlocks: v.strs("ipv6_cidr_blocks"),
+		PrefixListIds:  v.refs("prefix_list_ids"),
+		Description:    v.str("description"),
+	}
+	groups := v.refs(groupsKey)
+	if groupsKey == "source_security_group_id" {
+		groups = nonEmpty(v.ref(groupsKey))
+	}
+	if v.bool("self") {
+		groups = append(groups, groupID)
+	}
+	for _, group := range groups {
+		rule.ReferencedGroups = append(rule.ReferencedGroups, scanner.SecurityGroupReference{GroupID: group})
+	}
+	return rule
+}
+
+// terraformVPCGroupRule converts an aws_vpc_security_group_ingress_rule or
+// aws_vpc_security_group_egress_rule, which have a single source
+func terraformVPCGroupRule(v terraformValues) scanner.SecurityGroupRule {
+	rule := scanner.SecurityGroupRule{
+		IpProtocol:     groupProtocol(v.str("ip_protocol")),
+		FromPort:       v.int("from_port"),
+		ToPort:         v.int("to_port"),
+		CidrBlocks:     nonEmpty(v.str("cidr_ipv4")),
+		Ipv6CidrBlocks: nonEmpty(v.str("cidr_ipv6")),
+		PrefixListIds:  nonEmpty(v.ref("prefix_list_id")),
+		Description:    v.str("description"),
+		Tags:           v.tags(),
+	}
+	if rule.IpProtocol == "-1" {
+		rule.FromPort, rule.ToPort = 0, 0
+	}
+	if group := v.ref("referenced_security_group_id"); group != "" {
+		rule.ReferencedGroups = []scanner.SecurityGroupReference{{GroupID: group, Description: rule.Description}}
+	}
+	return rule
+}
+
+// terraformAclEntry converts an inline entry of an aws_network_acl or an
+// aws_network_acl_rule, whose rule number and action are in the given attributes
+func terraformAclEntry(v terraformValues, ruleKey, actionKey string, egress bool) scanner.NetworkAclEntry {
+	entry := scanner.NetworkAclEntry{
+		RuleNumber:    v.int(ruleKey),
+		Protocol:      aclProtocol(v.str("protocol")),
+		RuleAction:    v.str(actionKey),
+		CidrBlock:     v.str("cidr_block"),
+		Ipv6CidrBlock: v.str("ipv6_cidr_block"),
+		Egress:        egress,
+	}
+	switch entry.Protocol {
+	case "6", "17":
+		entry.PortRange = &scanner.NetworkAclPortRange{From: v.int("from_port"), To: v.int("to_port")}
+	case "1", "58":
+		entry.IcmpType = &scanner.NetworkAclIcmpType{Type: v.int("icmp_type"), Code: v.int("icmp_code")}
+	}
+	return entry
+}
+
+// nonEmpty returns a value as a list, or nil when it is empty
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
@@ -0,0 +1,148 @@
+package preview
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// previewNetwork has a VPC with an internet gateway, a private subnet using the main
+// route table, a web security group and a custom network ACL
+func previewNetwork() *scanner.Network {
+	local := scanner.Route{DestinationCidr: "10.0.0.0/16", GatewayID: "local", State: "active", Origin: "CreateRouteTable"}
+	return &scanner.Network{
+		Region: "us-east-1",
+		VPCs:   []scanner.VPC{{ID: "vpc-1", CidrBlock: "10.0.0.0/16", State: "available", Tags: map[string]string{}}},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-private", VpcID: "vpc-1", CidrBlock: "10.0.1.0/24", State: "available", Tags: map[string]string{}},
+		},
+		InternetGateways: []scanner.InternetGateway{{ID: "igw-1", VpcID: "vpc-1", State: "available", Tags: map[string]string{}}},
+		RouteTables: []scanner.RouteTable{
+			{ID: "rtb-main", VpcID: "vpc-1", IsMain: true, Routes: []scanner.Route{local}, Tags: map[string]string{}},
+		},
+		SecurityGroups: []scanner.SecurityGroup{
+			{ID: "sg-web", Name: "web", VpcID: "vpc-1", Tags: map[string]string{}, IngressRules: []scanner.SecurityGroupRule{
+				{ID: "sgr-1", IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+			}},
+		},
+		NetworkAcls: []scanner.NetworkAcl{
+			{ID: "acl-default", VpcID: "vpc-1", IsDefault: true, Tags: map[string]string{}},
+			{ID: "acl-custom", VpcID: "vpc-1", Tags: map[string]string{}, Associations: []string{"subnet-private"}},
+		},
+	}
+}
+
+// terraformPlanJSON creates a public subnet routed through the internet gateway,
+// opens port 80 on the web security group, deletes the custom network ACL and
+// creates an instance
+const terraformPlanJSON = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "aws_vpc.main", "mode": "managed", "type": "aws_vpc", "name": "main",
+     "change": {"actions": ["no-op"], "before": {"id": "vpc-1", "cidr_block": "10.0.0.0/16"}, "after": {"id": "vpc-1", "cidr_block": "10.0.0.0/16"}}},
+    {"address": "aws_subnet.public", "mode": "managed", "type": "aws_subnet", "name": "public",
+     "change": {"actions": ["create"], "before": null,
+       "after": {"vpc_id": "vpc-1", "cidr_block": "10.0.2.0/24", "availability_zone": "us-east-1a", "map_public_ip_on_launch": true, "tags": {"Name": "public"}},
+       "after_unknown": {"id": true}}},
+    {"address": "aws_route_table.public", "mode": "managed", "type": "aws_route_table", "name": "public",
+     "change": {"actions": ["create"], "before": null,
+       "after": {"vpc_id": "vpc-1", "route": [{"cidr_block": "0.0.0.0/0", "gateway_id": "igw-1"}], "tags": null},
+       "after_unknown": {"id": true}}},
+    {"address": "aws_route_table_association.public", "mode": "managed", "type": "aws_route_table_association", "name": "public",
+     "change": {"actions": ["create"], "before": null, "after": {}, "after_unknown": {"id": true, "subnet_id": true, "route_table_id": true}}},
+    {"address": "aws_security_group.web", "mode": "managed", "type": "aws_security_group", "name": "web",
+     "change": {"actions": ["update"],
+       "before": {"id": "sg-web", "name": "web", "vpc_id": "vpc-1"},
+       "after": {"id": "sg-web", "name": "web", "vpc_id": "vpc-1", "egress": [], "ingress": [
+         {"protocol": "tcp", "from_port": 443, "to_port": 443, "cidr_blocks": ["0.0.0.0/0"], "self": false},
+         {"protocol": "6", "from_port": 80, "to_port": 80, "cidr_blocks": ["0.0.0.0/0", "10.0.0.0/8"], "self": false}
+       ]}}},
+    {"address": "aws_network_acl.custom", "mode": "managed", "type": "aws_network_acl", "name": "custom",
+     "change": {"actions": ["delete"], "before": {"id": "acl-custom", "vpc_id": "vpc-1"}, "after": null}},
+    {"address": "aws_instance.web", "mode": "managed", "type": "aws_instance", "name": "web",
+     "change": {"actions": ["create"], "before": null, "after": {}}}
+  ],
+  "configuration": {"root_module": {"resources": [
+    {"address": "aws_route_table_association.public", "expressions": {
+      "subnet_id": {"references": ["aws_subnet.public.id", "aws_subnet.public"]},
+      "route_table_id": {"references": ["aws_route_table.public.id", "aws_route_table.public"]}
+    }}
+  ]}}
+}`
+
+func TestFromTerraformPlan(t *testing.T) {
+	network := previewNetwork()
+	preview, err := FromTerraformPlan(network, []byte(terraformPlanJSON))
+	if err != nil {
+		t.Fatalf("FromTerraformPlan failed: %v", err)
+	}
+	result := preview.Network
+
+	subnet := find(result.Subnets, "aws_subnet.public", subnetID)
+	if subnet == nil {
+		t.Fatalf("created subnet missing from %+v", result.Subnets)
+	}
+	if subnet.Type != "public" || subnet.RouteTableID != "aws_route_table.public" {
+		t.Errorf("subnet type %s through %s, want public through aws_route_table.public", subnet.Type, subnet.RouteTableID)
+	}
+	if subnet.NetworkAclID != "acl-default" || subnet.Name != "public" {
+		t.Errorf("subnet named %q with network ACL %s, want public with acl-default", subnet.Name, subnet.NetworkAclID)
+	}
+
+	table := find(result.RouteTables, "aws_route_table.public", routeTableID)
+	var targets []string
+	if table != nil {
+		for _, route := range table.Routes {
+			targets = append(targets, route.DestinationCidr+" "+route.GatewayID)
+		}
+	}
+	if want := []string{"0.0.0.0/0 igw-1", "10.0.0.0/16 local"}; !reflect.DeepEqual(targets, want) {
+		t.Errorf("created route table routes = %v, want %v", targets, want)
+	}
+
+	// The private subnet falls back to the default network ACL
+	if private := find(result.Subnets, "subnet-private", subnetID); private.NetworkAclID != "acl-default" || private.RouteTableID != "rtb-main" {
+		t.Errorf("private subnet uses %s and %s, want acl-default and rtb-main", private.NetworkAclID, private.RouteTableID)
+	}
+	if find(result.NetworkAcls, "acl-custom", networkAclID) != nil {
+		t.Error("deleted network ACL still in the preview")
+	}
+
+	group := find(result.SecurityGroups, "sg-web", securityGroupID)
+	var rules []string
+	for _, rule := range group.IngressRules {
+		rules = append(rules, rule.ID+" "+ruleKey(rule))
+	}
+	want := []string{" tcp 80-80 0.0.0.0/0", " tcp 80-80 10.0.0.0/8", "sgr-1 tcp 443-443 0.0.0.0/0"}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("ingress rules = %q, want %q", rules, want)
+	}
+
+	if !reflect.DeepEqual(preview.Skipped, []string{"aws_instance.web"}) {
+		t.Errorf("Skipped = %v, want aws_instance.web", preview.Skipped)
+	}
+	if len(network.Subnets) != 1 || len(network.NetworkAcls) != 2 {
+		t.Error("FromTerraformPlan modified the scanned network")
+	}
+}
+
+func TestFromTerraformPlanInvalid(t *testing.T) {
+	if _, err := FromTerraformPlan(previewNetwork(), []byte(`{"resource_changes": []}`)); err == nil {
+		t.Error("FromTerraformPlan accepted JSON without a format version")
+	}
+}
+
+func TestParentReference(t *testing.T) {
+	tests := map[string]string{
+		"aws_subnet.public.id":            "aws_subnet.public",
+		"aws_subnet.private[0]":           "aws_subnet.private",
+		"aws_subnet.private[count.index]": "aws_subnet.private",
+		"aws_subnet":                      "",
+	}
+	for reference, want := range tests {
+		if got := parentReference(reference); got != want {
+			t.Errorf("parentReference(%q) = %q, want %q", reference, got, want)
+		}
+	}
+}
@@ -0,0 +1,475 @@
+// Package reach works out which sources the security groups, network ACLs and
+// routing of a scanned network allow to reach a target on a port.
+package reach
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/routepath"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Reachability of a source
+const (
+	StatusReachable = "reachable"
+	StatusBlocked   = "blocked"
+	StatusUnknown   = "unknown"
+)
+
+// Kinds of source
+const (
+	SourceSameVPC        = "same-vpc"
+	SourcePeeredVPC      = "peered-vpc"
+	SourceTransitGateway = "transit-gateway"
+	SourceInternet       = "internet"
+	SourceExternal       = "external" // VPN, on-premises or carrier network
+	SourceSecurityGroup  = "security-group"
+	SourcePrefixList     = "prefix-list"
+)
+
+// Steps of a rule chain
+const (
+	StepSecurityGroup = "security-group"
+	StepNetworkAcl    = "network-acl"
+	StepRoute         = "route"
+)
+
+// ephemeralFrom and ephemeralTo are the client ports replies are sent to
+const (
+	ephemeralFrom = 1024
+	ephemeralTo   = 65535
+)
+
+// Step is one rule on the path from a source to the target
+type Step struct {
+	Kind     string `json:"kind"`
+	Resource string `json:"resource"`
+	Detail   string `json:"detail"`
+	Allows   bool   `json:"allows"`
+}
+
+// Path is a source the target's security groups admit and whether the network
+// ACLs and routing let its traffic through
+type Path struct {
+	Source    string `json:"source"` // CIDR, security group or prefix list
+	Kind      string `json:"kind"`
+	VpcID     string `json:"vpc_id,omitempty"` // VPC of the source, when known
+	SubnetID  string `json:"subnet_id"`        // Target subnet the traffic enters
+	Interface string `json:"interface,omitempty"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+	Chain     []Step `json:"chain"`
+}
+
+// Report lists every source allowed to reach a target on a port
+type Report struct {
+	Target   string `json:"target"`
+	Service  string `json:"service,omitempty"` // Service of a VPC endpoint target
+	Protocol string `json:"protocol"`
+	Port     int32  `json:"port"`
+	Paths    []Path `json:"paths"`
+}
+
+// analyzer evaluates the paths into one target endpoint
+type analyzer struct {
+	network  *scanner.Network
+	protocol string
+	port     int32
+	endpoint Endpoint
+	subnet   *scanner.Subnet
+	acl      *scanner.NetworkAcl
+	paths    []Path
+	seen     map[string]bool
+}
+
+// Analyze finds the sources the target's security groups allow on the port, and
+// checks each against the network ACLs of the target subnet and the route back to it
+func Analyze(network *scanner.Network, target Target, protocol string, port int32) (Report, error) {
+	protocol = strings.ToLower(protocol)
+	if protocol != "tcp" && protocol != "udp" {
+		return Report{}, fmt.Errorf("unsupported protocol %q, expected tcp or udp", protocol)
+	}
+	if port < 0 || port > 65535 {
+		return Report{}, fmt.Errorf("invalid port %d", port)
+	}
+
+	report := Report{Target: target.ID, Service: target.Service, Protocol: protocol, Port: port}
+	for _, endpoint := range target.Endpoints {
+		a := &analyzer{network: network, protocol: protocol, port: port, endpoint: endpoint, seen: make(map[string]bool)}
+		if err := a.analyze(); err != nil {
+			return Report{}, err
+		}
+		report.Paths = append(report.Paths, a.paths...)
+	}
+
+	statusOrder := map[string]int{StatusReachable: 0, StatusUnknown: 1, StatusBlocked: 2}
+	sort.SliceStable(report.Paths, func(i, j int) bool {
+		a, b := report.Paths[i], report.Paths[j]
+		if a.SubnetID != b.SubnetID {
+			return a.SubnetID < b.SubnetID
+		}
+		return statusOrder[a.Status] < statusOrder[b.Status]
+	})
+
+	return report, nil
+}
+
+// analyze evaluates every ingress rule of the endpoint's security groups
+func (a *analyzer) analyze() error {
+	for i := range a.network.Subnets {
+		if a.network.Subnets[i].ID == a.endpoint.SubnetID {
+			a.subnet = &a.network.Subnets[i]
+		}
+	}
+	if a.subnet == nil {
+		return fmt.Errorf("subnet %s is not in the scanned network", a.endpoint.SubnetID)
+	}
+	for i := range a.network.NetworkAcls {
+		if a.network.NetworkAcls[i].ID == a.subnet.NetworkAclID {
+			a.acl = &a.network.NetworkAcls[i]
+		}
+	}
+
+	for _, groupID := range a.endpoint.SecurityGroups {
+		group := a.securityGroup(groupID)
+		if group == nil {
+			return fmt.Errorf("security group %s is not in the scanned network", groupID)
+		}
+
+		for _, rule := range group.IngressRules {
+			if !rule.AllowsPort(a.protocol, a.port) {
+				continue
+			}
+			for _, cidr := range append(append([]string{}, rule.CidrBlocks...), rule.Ipv6CidrBlocks...) {
+				for _, source := range a.cidrSources(cidr) {
+					a.cidrPath(source, a.ruleStep(group.ID, rule, cidr))
+				}
+			}
+			for _, reference := range rule.ReferencedGroups {
+				a.groupPath(reference, a.ruleStep(group.ID, rule, reference.GroupID))
+			}
+			for _, prefixList := range rule.PrefixListIds {
+				a.add(Path{
+					Source: prefixList,
+					Kind:   SourcePrefixList,
+					Status: StatusUnknown,
+					Reason: "prefix list entries are not scanned",
+					Chain:  []Step{a.ruleStep(group.ID, rule, prefixList)},
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// securityGroup returns a scanned security group
+func (a *analyzer) securityGroup(id string) *scanner.SecurityGroup {
+	for i := range a.network.SecurityGroups {
+		if a.network.SecurityGroups[i].ID == id {
+			return &a.network.SecurityGroups[i]
+		}
+	}
+	return nil
+}
+
+// ruleStep describes the security group rule admitting a source
+func (a *analyzer) ruleStep(groupID string, rule scanner.SecurityGroupRule, source string) Step {
+	detail := fmt.Sprintf("inbound %s/%d from %s", a.protocol, a.port, source)
+	if rule.ID != "" {
+		detail += " (" + rule.ID + ")"
+	}
+	return Step{Kind: StepSecurityGroup, Resource: groupID, Detail: detail, Allows: true}
+}
+
+// cidrSources splits a rule's CIDR into the sources behind it: the CIDR itself,
+// and every narrower route destination and scanned VPC inside it, since each may
+// be reached a different way
+func (a *analyzer) cidrSources(cidr string) []string {
+	sources := []string{cidr}
+	seen := map[string]bool{cidr: true}
+	add := func(candidate string) {
+		if candidate != "" && !seen[candidate] && contains(cidr, candidate) {
+			seen[candidate] = true
+			sources = append(sources, candidate)
+		}
+	}
+
+	if table := a.routeTable(); table != nil {
+		for _, route := range table.Routes {
+			add(route.Destination())
+		}
+	}
+	for _, vpc := range a.network.VPCs {
+		add(vpc.CidrBlock)
+		for _, block := range vpc.Ipv6CidrBlocks {
+			add(block)
+		}
+	}
+	return sources
+}
+
+// routeTable returns the route table of the target subnet
+func (a *analyzer) routeTable() *scanner.RouteTable {
+	for i := range a.network.RouteTables {
+		if a.network.RouteTables[i].ID == a.subnet.RouteTableID {
+			return &a.network.RouteTables[i]
+		}
+	}
+	return nil
+}
+
+// cidrPath checks a CIDR source against the network ACLs and the route back to it
+func (a *analyzer) cidrPath(source string, ruleStep Step) {
+	path := Path{Source: source, Chain: []Step{ruleStep}}
+	a.checkRoute(&path, source)
+	a.checkNetworkAcl(&path, source)
+	a.add(path)
+}
+
+// groupPath checks a referenced security group source. Its members may be in any
+// subnet of the group's VPC, so that VPC's CIDR stands in for their addresses.
+func (a *analyzer) groupPath(reference scanner.SecurityGroupReference, ruleStep Step) {
+	path := Path{Source: reference.GroupID, Kind: SourceSecurityGroup, VpcID: reference.VpcID, Chain: []Step{ruleStep}}
+	if path.VpcID == "" {
+		if group := a.securityGroup(reference.GroupID); group != nil {
+			path.VpcID = group.VpcID
+		}
+	}
+
+	var vpc *scanner.VPC
+	for i := range a.network.VPCs {
+		if a.network.VPCs[i].ID == path.VpcID {
+			vpc = &a.network.VPCs[i]
+		}
+	}
+	if vpc == nil {
+		path.Status = StatusUnknown
+		path.Reason = fmt.Sprintf("the VPC of %s was not scanned", reference.GroupID)
+		a.add(path)
+		return
+	}
+
+	a.checkRoute(&path, vpc.CidrBlock)
+	path.Kind, path.VpcID = SourceSecurityGroup, vpc.ID
+	a.checkNetworkAcl(&path, vpc.CidrBlock)
+	a.add(path)
+}
+
+// checkRoute traces the route from the target subnet back to the source, which
+// replies must take, and sets the kind of source from where it leads
+func (a *analyzer) checkRoute(path *Path, source string) {
+	trace, err := routepath.Trace(a.network, a.subnet.ID, source)
+	if err != nil {
+		path.Status = StatusUnknown
+		path.Reason = err.Error()
+		return
+	}
+
+	target := ""
+	if len(trace.Hops) > 0 && trace.Hops[0].Selected != nil {
+		target = trace.Hops[0].Selected.Target
+	}
+	step := Step{Kind: StepRoute, Resource: a.subnet.RouteTableID, Detail: fmt.Sprintf("replies to %s %s %s", source, trace.Outcome, trace.Detail)}
+	path.VpcID = trace.VpcID
+	path.Status = StatusReachable
+
+	switch {
+	case trace.Outcome == routepath.OutcomeDropped:
+		path.Status = StatusBlocked
+		path.Reason = "no route back: " + trace.Detail
+	case trace.Outcome == routepath.OutcomeUnknown:
+		path.Status = StatusUnknown
+		path.Reason = trace.Detail
+	case target == "local":
+		path.Kind = SourceSameVPC
+	case strings.HasPrefix(target, "pcx-"):
+		path.Kind = SourcePeeredVPC
+	case strings.HasPrefix(target, "tgw-"):
+		path.Kind = SourceTransitGateway
+	case strings.HasPrefix(target, "igw-"):
+		path.Kind = SourceInternet
+		step.Detail += " (the target needs a public IP)"
+	case strings.HasPrefix(target, "eigw-"):
+		path.Kind = SourceInternet
+		path.Status = StatusBlocked
+		path.Reason = fmt.Sprintf("egress-only internet gateway %s does not allow inbound connections", target)
+	case strings.HasPrefix(target, "nat-"):
+		path.Kind = SourceInternet
+		path.Status = StatusBlocked
+		path.Reason = fmt.Sprintf("NAT gateway %s does not allow inbound connections", target)
+	case strings.HasPrefix(target, "vgw-"), strings.HasPrefix(target, "lgw-"), strings.HasPrefix(target, "cagw-"):
+		path.Kind = SourceExternal
+	default:
+		path.Status = StatusUnknown
+		path.Reason = trace.Detail
+	}
+	step.Allows = path.Status != StatusBlocked
+	path.Chain = append(path.Chain, step)
+}
+
+// checkNetworkAcl checks the target subnet's network ACL lets the request in and the reply out
+func (a *analyzer) checkNetworkAcl(path *Path, source string) {
+	if a.acl == nil {
+		if path.Status == StatusReachable {
+			path.Status = StatusUnknown
+			path.Reason = fmt.Sprintf("network ACL of %s was not scanned", a.subnet.ID)
+		}
+		return
+	}
+
+	for _, direction := range []struct {
+		egress   bool
+		from, to int32
+		name     string
+	}{
+		{false, a.port, a.port, fmt.Sprintf("inbound %s/%d from %s", a.protocol, a.port, source)},
+		{true, ephemeralFrom, ephemeralTo, fmt.Sprintf("outbound replies to %s", source)},
+	} {
+		entry, exceptions, found := a.firstEntry(direction.egress, source, direction.from, direction.to)
+		step := Step{Kind: StepNetworkAcl, Resource: a.acl.ID}
+		if !found {
+			step.Detail = direction.name + ": no matching rule"
+		} else {
+			step.Allows = entry.RuleAction == "allow"
+			step.Detail = fmt.Sprintf("%s: %s", direction.name, entrySummary(entry))
+		}
+		if len(exceptions) > 0 {
+			step.Detail += fmt.Sprintf(" (except %s)", strings.Join(exceptions, ", "))
+		}
+		path.Chain = append(path.Chain, step)
+
+		if !step.Allows && path.Status != StatusBlocked {
+			path.Status = StatusBlocked
+			path.Reason = fmt.Sprintf("network ACL %s denies %s", a.acl.ID, direction.name)
+		}
+	}
+}
+
+// firstEntry returns the lowest numbered network ACL entry matching traffic with the
+// whole source, and the narrower entries before it that decide part of the source
+func (a *analyzer) firstEntry(egress bool, source string, from, to int32) (scanner.NetworkAclEntry, []string, bool) {
+	entries := append([]scanner.NetworkAclEntry{}, a.acl.Entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].RuleNumber < entries[j].RuleNumber })
+
+	var exceptions []string
+	for _, entry := range entries {
+		if entry.Egress != egress || !entry.AllowsPorts(a.protocol, from, to) {
+			continue
+		}
+		cidr := entryCidr(entry)
+		switch {
+		case contains(cidr, source):
+			return entry, exceptions, true
+		case contains(source, cidr):
+			exceptions = append(exceptions, entrySummary(entry))
+		}
+	}
+	return scanner.NetworkAclEntry{}, exceptions, false
+}
+
+// entryCidr returns the IPv4 or IPv6 CIDR of a network ACL entry
+func entryCidr(entry scanner.NetworkAclEntry) string {
+	if entry.CidrBlock != "" {
+		return entry.CidrBlock
+	}
+	return entry.Ipv6CidrBlock
+}
+
+// entrySummary describes a network ACL entry
+func entrySummary(entry scanner.NetworkAclEntry) string {
+	rule := fmt.Sprintf("rule %d", entry.RuleNumber)
+	if entry.RuleNumber == 32767 {
+		rule = "rule *"
+	}
+	return fmt.Sprintf("%s %s %s", rule, entry.RuleAction, entryCidr(entry))
+}
+
+// add records a path into the endpoint once
+func (a *analyzer) add(path Path) {
+	path.SubnetID = a.endpoint.SubnetID
+	path.Interface = a.endpoint.Interface
+	key := path.Source + "|" + path.Chain[0].Resource
+	if a.seen[key] {
+		return
+	}
+	a.seen[key] = true
+	a.paths = append(a.paths, path)
+}
+
+// contains reports whether a CIDR contains another
+func contains(cidr, other string) bool {
+	_, outer, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+	_, inner, err := net.ParseCIDR(other)
+	if err != nil {
+		return false
+	}
+	outerBits, outerSize := outer.Mask.Size()
+	innerBits, innerSize := inner.Mask.Size()
+	return outerSize == innerSize && outerBits <= innerBits && outer.Contains(inner.IP)
+}
+
+// Reachable returns the paths whose traffic reaches the target
+func (r Report) Reachable() []Path {
+	var reachable []Path
+	for _, path := range r.Paths {
+		if path.Status == StatusReachable {
+			reachable = append(reachable, path)
+		}
+	}
+	return reachable
+}
+
+// Text renders every path with its rule chain
+func (r Report) Text() string {
+	var result strings.Builder
+
+	target := r.Target
+	if r.Service != "" {
+		target += fmt.Sprintf(" (%s)", r.Service)
+	}
+	result.WriteString(fmt.Sprintf("Who can reach %s on %s/%d\n", target, r.Protocol, r.Port))
+	result.WriteString(fmt.Sprintf("Reachable sources: %d of %d\n", len(r.Reachable()), len(r.Paths)))
+
+	subnet := ""
+	for _, path := range r.Paths {
+		if path.SubnetID != subnet {
+			subnet = path.SubnetID
+			into := subnet
+			if path.Interface != "" {
+				into = fmt.Sprintf("%s (%s)", path.Interface, subnet)
+			}
+			result.WriteString(fmt.Sprintf("\nInto %s:\n", into))
+		}
+
+		source := path.Source
+		if path.Kind != "" {
+			source += " [" + path.Kind
+			if path.VpcID != "" {
+				source += " " + path.VpcID
+			}
+			source += "]"
+		}
+		result.WriteString(fmt.Sprintf("  %-9s %s\n", strings.ToUpper(path.Status), source))
+		for _, step := range path.Chain {
+			marker := "✓"
+			if !step.Allows {
+				marker = "✗"
+			}
+			result.WriteString(fmt.Sprintf("      %s %s %s: %s\n", marker, step.Kind, step.Resource, step.Detail))
+		}
+		if path.Reason != "" && path.Status != StatusReachable {
+			result.WriteString(fmt.Sprintf("      %s\n", path.Reason))
+		}
+	}
+
+	if len(r.Paths) == 0 {
+		result.WriteString("\nNo security group rule allows this port\n")
+	}
+	return result.String()
+}
@@ -0,0 +1,223 @@
+package reach
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// reachNetwork has a web subnet in vpc-a that routes to the internet, to vpc-b
+// through peering and to vpc-c through a transit gateway, and a private subnet
+// that only reaches the internet through a NAT gateway
+func reachNetwork() *scanner.Network {
+	return &scanner.Network{
+		VPCs: []scanner.VPC{
+			{ID: "vpc-a", CidrBlock: "10.0.0.0/16"},
+			{ID: "vpc-b", CidrBlock: "10.1.0.0/16"},
+			{ID: "vpc-c", CidrBlock: "10.2.0.0/16"},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-web", VpcID: "vpc-a", CidrBlock: "10.0.1.0/24", RouteTableID: "rtb-web", NetworkAclID: "acl-a"},
+			{ID: "subnet-private", VpcID: "vpc-a", CidrBlock: "10.0.2.0/24", RouteTableID: "rtb-private", NetworkAclID: "acl-a"},
+			{ID: "subnet-c", VpcID: "vpc-c", CidrBlock: "10.2.1.0/24", RouteTableID: "rtb-c", NetworkAclID: "acl-c"},
+		},
+		RouteTables: []scanner.RouteTable{
+			{ID: "rtb-web", VpcID: "vpc-a", Routes: []scanner.Route{
+				{DestinationCidr: "10.0.0.0/16", GatewayID: "local", State: "active"},
+				{DestinationCidr: "10.1.0.0/16", VpcPeeringID: "pcx-ab", State: "active"},
+				{DestinationCidr: "10.2.0.0/16", TransitGatewayID: "tgw-1", State: "active"},
+				{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-a", State: "active"},
+			}},
+			{ID: "rtb-private", VpcID: "vpc-a", Routes: []scanner.Route{
+				{DestinationCidr: "10.0.0.0/16", GatewayID: "local", State: "active"},
+				{DestinationCidr: "0.0.0.0/0", NatGatewayID: "nat-a", State: "active"},
+			}},
+		},
+		NetworkAcls: []scanner.NetworkAcl{
+			{ID: "acl-a", VpcID: "vpc-a", Entries: []scanner.NetworkAclEntry{
+				{RuleNumber: 90, Protocol: "6", RuleAction: "deny", CidrBlock: "10.1.0.0/16", PortRange: &scanner.NetworkAclPortRange{From: 443, To: 443}},
+				{RuleNumber: 100, Protocol: "-1", RuleAction: "allow", CidrBlock: "0.0.0.0/0"},
+				{RuleNumber: 100, Protocol: "-1", RuleAction: "allow", CidrBlock: "0.0.0.0/0", Egress: true},
+				{RuleNumber: 32767, Protocol: "-1", RuleAction: "deny", CidrBlock: "0.0.0.0/0"},
+				{RuleNumber: 32767, Protocol: "-1", RuleAction: "deny", CidrBlock: "0.0.0.0/0", Egress: true},
+			}},
+		},
+		SecurityGroups: []scanner.SecurityGroup{
+			{ID: "sg-web", VpcID: "vpc-a", IngressRules: []scanner.SecurityGroupRule{
+				{ID: "sgr-https", IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+				{IpProtocol: "tcp", FromPort: 443, ToPort: 443, ReferencedGroups: []scanner.SecurityGroupReference{{GroupID: "sg-app"}}},
+				{IpProtocol: "tcp", FromPort: 443, ToPort: 443, PrefixListIds: []string{"pl-1"}},
+				{IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"10.0.0.0/16"}},
+			}},
+			{ID: "sg-app", VpcID: "vpc-a"},
+		},
+		PeeringConnections: []scanner.PeeringConnection{
+			{ID: "pcx-ab", RequesterVpcID: "vpc-a", AccepterVpcID: "vpc-b", Status: "active"},
+		},
+		TransitGateways: []scanner.TransitGateway{
+			{
+				ID: "tgw-1",
+				Attachments: []scanner.TransitGatewayAttachment{
+					{ID: "tgw-attach-a", ResourceID: "vpc-a", ResourceType: "vpc", RouteTableID: "tgw-rtb"},
+					{ID: "tgw-attach-c", ResourceID: "vpc-c", ResourceType: "vpc", RouteTableID: "tgw-rtb"},
+				},
+				RouteTables: []scanner.TransitGatewayRouteTable{
+					{ID: "tgw-rtb", Routes: []scanner.TransitGatewayRoute{
+						{DestinationCidr: "10.0.0.0/16", AttachmentID: "tgw-attach-a", ResourceID: "vpc-a", ResourceType: "vpc", State: "active"},
+						{DestinationCidr: "10.2.0.0/16", AttachmentID: "tgw-attach-c", ResourceID: "vpc-c", ResourceType: "vpc", State: "active"},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	network := reachNetwork()
+	target, err := SecurityGroupTarget(network, "sg-web", []string{"subnet-web", "subnet-private"})
+	if err != nil {
+		t.Fatalf("SecurityGroupTarget failed: %v", err)
+	}
+
+	report, err := Analyze(network, target, "TCP", 443)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	type result struct{ kind, vpc, status string }
+	got := make(map[string]result)
+	for _, path := range report.Paths {
+		got[path.SubnetID+" "+path.Source] = result{path.Kind, path.VpcID, path.Status}
+	}
+
+	want := map[string]result{
+		"subnet-web 0.0.0.0/0":       {SourceInternet, "", StatusReachable},
+		"subnet-web 10.0.0.0/16":     {SourceSameVPC, "vpc-a", StatusReachable},
+		"subnet-web 10.1.0.0/16":     {SourcePeeredVPC, "vpc-b", StatusBlocked},
+		"subnet-web 10.2.0.0/16":     {SourceTransitGateway, "vpc-c", StatusReachable},
+		"subnet-web sg-app":          {SourceSecurityGroup, "vpc-a", StatusReachable},
+		"subnet-web pl-1":            {SourcePrefixList, "", StatusUnknown},
+		"subnet-private 0.0.0.0/0":   {SourceInternet, "", StatusBlocked},
+		"subnet-private 10.0.0.0/16": {SourceSameVPC, "vpc-a", StatusReachable},
+	}
+	for key, expected := range want {
+		if got[key] != expected {
+			t.Errorf("%s: expected %+v, got %+v", key, expected, got[key])
+		}
+	}
+
+	for _, path := range report.Paths {
+		if path.SubnetID == "subnet-web" && path.Source == "10.1.0.0/16" {
+			if !strings.Contains(path.Reason, "network ACL acl-a denies inbound tcp/443") {
+				t.Errorf("Expected the denying ACL as the reason, got %q", path.Reason)
+			}
+			if len(path.Chain) != 4 || path.Chain[2].Detail != "inbound tcp/443 from 10.1.0.0/16: rule 90 deny 10.1.0.0/16" {
+				t.Errorf("Unexpected rule chain: %+v", path.Chain)
+			}
+		}
+		// The narrower deny for the peered VPC does not block the internet as a whole
+		if path.SubnetID == "subnet-web" && path.Source == "0.0.0.0/0" && !strings.HasSuffix(path.Chain[2].Detail, "rule 100 allow 0.0.0.0/0 (except rule 90 deny 10.1.0.0/16)") {
+			t.Errorf("Expected the narrower deny as an exception, got %q", path.Chain[2].Detail)
+		}
+		if path.SubnetID == "subnet-private" && path.Source == "0.0.0.0/0" && !strings.Contains(path.Reason, "NAT gateway nat-a") {
+			t.Errorf("Expected the NAT gateway as the reason, got %q", path.Reason)
+		}
+	}
+
+	text := report.Text()
+	for _, expected := range []string{"Who can reach sg-web on tcp/443", "REACHABLE 0.0.0.0/0 [internet]", "security-group sg-web: inbound tcp/443 from 0.0.0.0/0 (sgr-https)", "(the target needs a public IP)"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in the text report:\n%s", expected, text)
+		}
+	}
+}
+
+func TestAnalyzeNoMatchingRule(t *testing.T) {
+	network := reachNetwork()
+	target, _ := SecurityGroupTarget(network, "sg-web", nil)
+
+	report, err := Analyze(network, target, "udp", 53)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(report.Paths) != 0 || !strings.Contains(report.Text(), "No security group rule allows this port") {
+		t.Errorf("Expected no paths, got %+v", report.Paths)
+	}
+	if len(target.Endpoints) != 2 {
+		t.Errorf("Expected every subnet of the group's VPC, got %+v", target.Endpoints)
+	}
+}
+
+func TestVpcEndpointTarget(t *testing.T) {
+	network := reachNetwork()
+	network.VpcEndpoints = []scanner.VpcEndpoint{
+		{ID: "vpce-s3", VpcID: "vpc-a", ServiceName: "com.amazonaws.us-east-1.s3", SecurityGroupIDs: []string{"sg-web"},
+			NetworkInterfaces: []scanner.EndpointNetworkInterface{
+				{ID: "eni-vpce-web", SubnetID: "subnet-web", PrivateIP: "10.0.1.45"},
+				{ID: "eni-vpce-private", SubnetID: "subnet-private", PrivateIP: "10.0.2.45"},
+			}},
+	}
+
+	target, err := VpcEndpointTarget(network, "vpce-s3")
+	if err != nil {
+		t.Fatalf("VpcEndpointTarget failed: %v", err)
+	}
+	if target.Service != "com.amazonaws.us-east-1.s3" || len(target.Endpoints) != 2 || target.Endpoints[0].SecurityGroups[0] != "sg-web" {
+		t.Errorf("Unexpected target %+v", target)
+	}
+
+	// An address targets only the interface holding it
+	target, err = VpcEndpointTarget(network, "10.0.2.45")
+	if err != nil {
+		t.Fatalf("VpcEndpointTarget failed: %v", err)
+	}
+	if target.ID != "vpce-s3" || len(target.Endpoints) != 1 || target.Endpoints[0].Interface != "eni-vpce-private" {
+		t.Errorf("Unexpected target %+v", target)
+	}
+
+	report, err := Analyze(network, target, "tcp", 443)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if !strings.HasPrefix(report.Text(), "Who can reach vpce-s3 (com.amazonaws.us-east-1.s3) on tcp/443\n") {
+		t.Errorf("Expected the endpoint's service in the report, got:\n%s", report.Text())
+	}
+
+	if _, err := VpcEndpointTarget(network, "10.0.2.46"); err == nil {
+		t.Error("Expected an error for an address no endpoint holds")
+	}
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	network := reachNetwork()
+	if _, err := SecurityGroupTarget(network, "sg-missing", nil); err == nil {
+		t.Error("Expected an error for an unknown security group")
+	}
+	target, _ := SecurityGroupTarget(network, "sg-web", []string{"subnet-web"})
+	if _, err := Analyze(network, target, "icmp", 0); err == nil {
+		t.Error("Expected an error for an unsupported protocol")
+	}
+}
+
+func TestNetworkInterfaceTarget(t *testing.T) {
+	network := reachNetwork()
+	network.NetworkInterfaces = []scanner.NetworkInterface{
+		{ID: "eni-primary", SubnetID: "subnet-web", AttachmentType: scanner.AttachmentEC2, AttachedTo: "i-web", SecurityGroupIDs: []string{"sg-web"}},
+		{ID: "eni-secondary", SubnetID: "subnet-private", AttachmentType: scanner.AttachmentEC2, AttachedTo: "i-web"},
+		{ID: "eni-nat", SubnetID: "subnet-web", AttachmentType: scanner.AttachmentNATGateway, AttachedTo: "nat-1"},
+	}
+
+	if target, ok := NetworkInterfaceTarget(network, "i-web"); !ok || len(target.Endpoints) != 2 || target.Endpoints[0].SecurityGroups[0] != "sg-web" {
+		t.Errorf("Expected both interfaces of i-web, got %+v", target)
+	}
+	if target, ok := NetworkInterfaceTarget(network, "eni-secondary"); !ok || len(target.Endpoints) != 1 || target.Endpoints[0].SubnetID != "subnet-private" {
+		t.Errorf("Expected eni-secondary, got %+v", target)
+	}
+	// Only instances are matched by attachment, and unknown IDs are looked up instead
+	for _, id := range []string{"nat-1", "i-unknown"} {
+		if _, ok := NetworkInterfaceTarget(network, id); ok {
+			t.Errorf("Expected no target for %s", id)
+		}
+	}
+}
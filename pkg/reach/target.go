@@ -0,0 +1,164 @@
+package reach
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/flows"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Endpoint is where traffic to the target enters: a subnet and the security
+// groups guarding the target there
+type Endpoint struct {
+	Interface      string   `json:"interface,omitempty"`
+	SubnetID       string   `json:"subnet_id"`
+	SecurityGroups []string `json:"security_groups"`
+}
+
+// Target is an instance, network interface, security group or VPC endpoint traffic is sent to
+type Target struct {
+	ID string
+	// Service is the service a VPC endpoint target connects to
+	Service   string
+	Endpoints []Endpoint
+}
+
+// SecurityGroupTarget targets the members of a security group in the given subnets,
+// or in every subnet of the group's VPC when none are given
+func SecurityGroupTarget(network *scanner.Network, groupID string, subnetIDs []string) (Target, error) {
+	var group *scanner.SecurityGroup
+	for i := range network.SecurityGroups {
+		if network.SecurityGroups[i].ID == groupID {
+			group = &network.SecurityGroups[i]
+		}
+	}
+	if group == nil {
+		return Target{}, fmt.Errorf("security group %s is not in the scanned network", groupID)
+	}
+
+	if len(subnetIDs) == 0 {
+		for _, subnet := range network.Subnets {
+			if subnet.VpcID == group.VpcID {
+				subnetIDs = append(subnetIDs, subnet.ID)
+			}
+		}
+		sort.Strings(subnetIDs)
+	}
+
+	target := Target{ID: groupID}
+	for _, subnetID := range subnetIDs {
+		target.Endpoints = append(target.Endpoints, Endpoint{SubnetID: subnetID, SecurityGroups: []string{groupID}})
+	}
+	return target, nil
+}
+
+// VpcEndpointTarget targets a PrivateLink VPC endpoint by its ID, or the one
+// network interface of an endpoint holding a private IP address
+func VpcEndpointTarget(network *scanner.Network, id string) (Target, error) {
+	var endpoint *scanner.VpcEndpoint
+	var interfaces []scanner.EndpointNetworkInterface
+	if found, eni, ok := network.VpcEndpointFor(id); ok {
+		endpoint = found
+		interfaces = []scanner.EndpointNetworkInterface{*eni}
+	} else {
+		for i := range network.VpcEndpoints {
+			if network.VpcEndpoints[i].ID == id {
+				endpoint = &network.VpcEndpoints[i]
+				interfaces = endpoint.NetworkInterfaces
+			}
+		}
+	}
+	if endpoint == nil {
+		return Target{}, fmt.Errorf("%s is not a VPC endpoint or VPC endpoint address in the scanned network", id)
+	}
+
+	target := Target{ID: endpoint.ID, Service: endpoint.ServiceName}
+	for _, eni := range interfaces {
+		if eni.SubnetID == "" {
+			continue
+		}
+		target.Endpoints = append(target.Endpoints, Endpoint{Interface: eni.ID, SubnetID: eni.SubnetID, SecurityGroups: endpoint.SecurityGroupIDs})
+	}
+	if len(target.Endpoints) == 0 {
+		return Target{}, fmt.Errorf("no network interface found for %s", endpoint.ID)
+	}
+	return target, nil
+}
+
+// NetworkInterfaceTarget targets a network interface, or the network interfaces
+// attached to an instance, recorded in a scan. It reports false when the scan
+// has none for the ID.
+func NetworkInterfaceTarget(network *scanner.Network, id string) (Target, bool) {
+	target := Target{ID: id}
+	for _, eni := range network.NetworkInterfaces {
+		if eni.SubnetID == "" || (eni.ID != id && !(eni.AttachmentType == scanner.AttachmentEC2 && eni.AttachedTo == id)) {
+			continue
+		}
+		target.Endpoints = append(target.Endpoints, Endpoint{Interface: eni.ID, SubnetID: eni.SubnetID, SecurityGroups: eni.SecurityGroupIDs})
+	}
+	return target, len(target.Endpoints) > 0
+}
+
+// LookupTarget describes the network interfaces of an instance or a network
+// interface, for states scanned without network interfaces
+func LookupTarget(ctx context.Context, api flows.EC2API, id string) (Target, error) {
+	var interfaces []flows.Interface
+
+	switch {
+	case strings.HasPrefix(id, "eni-"):
+		found, err := flows.LookupInterfaces(ctx, api, []string{id})
+		if err != nil {
+			return Target{}, fmt.Errorf("failed to describe network interface %s: %w", id, err)
+		}
+		if eni, ok := found[id]; ok {
+			interfaces = append(interfaces, eni)
+		}
+	case strings.HasPrefix(id, "i-"):
+		pages := ec2.NewDescribeNetworkInterfacesPaginator(api, &ec2.DescribeNetworkInterfacesInput{
+			Filters: []types.Filter{
+				{
+					Name:   &[]string{"attachment.instance-id"}[0],
+					Values: []string{id},
+				},
+			},
+		})
+		var ids []string
+		for pages.HasMorePages() {
+			page, err := pages.NextPage(ctx)
+			if err != nil {
+				return Target{}, fmt.Errorf("failed to describe network interfaces of %s: %w", id, err)
+			}
+			for _, eni := range page.NetworkInterfaces {
+				if eni.NetworkInterfaceId != nil {
+					ids = append(ids, *eni.NetworkInterfaceId)
+				}
+			}
+		}
+		found, err := flows.LookupInterfaces(ctx, api, ids)
+		if err != nil {
+			return Target{}, fmt.Errorf("failed to describe network interfaces of %s: %w", id, err)
+		}
+		for _, eniID := range ids {
+			if eni, ok := found[eniID]; ok {
+				interfaces = append(interfaces, eni)
+			}
+		}
+	default:
+		return Target{}, fmt.Errorf("unsupported target %q, expected an instance, network interface or security group ID", id)
+	}
+
+	if len(interfaces) == 0 {
+		return Target{}, fmt.Errorf("no network interface found for %s", id)
+	}
+
+	target := Target{ID: id}
+	for _, eni := range interfaces {
+		target.Endpoints = append(target.Endpoints, Endpoint{Interface: eni.ID, SubnetID: eni.SubnetID, SecurityGroups: eni.SecurityGroups})
+	}
+	return target, nil
+}
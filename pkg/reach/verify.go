@@ -0,0 +1,359 @@
+package reach
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/flows"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// defaultPollInterval is how often a running analysis is checked by default
+const defaultPollInterval = 5 * time.Second
+
+// InsightsAPI is the subset of the EC2 API used to run Reachability Analyzer
+type InsightsAPI interface {
+	CreateNetworkInsightsPath(ctx context.Context, params *ec2.CreateNetworkInsightsPathInput, optFns ...func(*ec2.Options)) (*ec2.CreateNetworkInsightsPathOutput, error)
+	StartNetworkInsightsAnalysis(ctx context.Context, params *ec2.StartNetworkInsightsAnalysisInput, optFns ...func(*ec2.Options)) (*ec2.StartNetworkInsightsAnalysisOutput, error)
+	DescribeNetworkInsightsAnalyses(ctx context.Context, params *ec2.DescribeNetworkInsightsAnalysesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInsightsAnalysesOutput, error)
+	DeleteNetworkInsightsAnalysis(ctx context.Context, params *ec2.DeleteNetworkInsightsAnalysisInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInsightsAnalysisOutput, error)
+	DeleteNetworkInsightsPath(ctx context.Context, params *ec2.DeleteNetworkInsightsPathInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInsightsPathOutput, error)
+}
+
+// Verification is what Reachability Analyzer found for the path between two
+// resources, and what the static analysis of the scanned network found for it
+type Verification struct {
+	Source       string   `json:"source"`
+	Destination  string   `json:"destination"`
+	Protocol     string   `json:"protocol"`
+	Port         int32    `json:"port"`
+	PathID       string   `json:"path_id"`
+	AnalysisID   string   `json:"analysis_id"`
+	Reachable    bool     `json:"reachable"`
+	Hops         []string `json:"hops,omitempty"`         // Components of the forward path, when reachable
+	Explanations []string `json:"explanations,omitempty"` // Why the path is blocked, when it is not reachable
+	// Static is the status the static analysis gives the source, empty when it
+	// cannot evaluate the pair
+	Static       string `json:"static,omitempty"`
+	StaticReason string `json:"static_reason,omitempty"`
+}
+
+// Agrees reports whether the static analysis reached the same conclusion as
+// Reachability Analyzer. Pairs the static analysis could not decide never agree.
+func (v Verification) Agrees() bool {
+	return (v.Static == StatusReachable && v.Reachable) || (v.Static == StatusBlocked && !v.Reachable)
+}
+
+// Verifier runs Reachability Analyzer analyses
+type Verifier struct {
+	api          InsightsAPI
+	pollInterval time.Duration
+	keep         bool
+	logger       *slog.Logger
+}
+
+// NewVerifier creates a verifier running analyses with the EC2 API
+func NewVerifier(api InsightsAPI) *Verifier {
+	return &Verifier{api: api, pollInterval: defaultPollInterval, logger: slog.Default()}
+}
+
+// SetLogger sets the logger paths and analyses left behind are reported to
+func (v *Verifier) SetLogger(logger *slog.Logger) {
+	v.logger = logger
+}
+
+// SetPollInterval sets how often a running analysis is checked
+func (v *Verifier) SetPollInterval(interval time.Duration) {
+	v.pollInterval = interval
+}
+
+// SetKeep keeps the path and analysis in the account after verifying, so they can
+// be inspected in the console. By default both are deleted.
+func (v *Verifier) SetKeep(keep bool) {
+	v.keep = keep
+}
+
+// Verify creates a Network Insights path between two resources, analyzes it and
+// waits for the result. Analyses are billed per run by AWS.
+func (v *Verifier) Verify(ctx context.Context, source, destination, protocol string, port int32) (*Verification, error) {
+	protocol = strings.ToLower(protocol)
+	if protocol != "tcp" && protocol != "udp" {
+		return nil, fmt.Errorf("unsupported protocol %q, expected tcp or udp", protocol)
+	}
+
+	input := &ec2.CreateNetworkInsightsPathInput{
+		Source:      awssdk.String(source),
+		Destination: awssdk.String(destination),
+		Protocol:    types.Protocol(protocol),
+		TagSpecifications: []types.TagSpecification{{
+			ResourceType: types.ResourceTypeNetworkInsightsPath,
+			Tags:         []types.Tag{{Key: awssdk.String("CreatedBy"), Value: awssdk.String("pikaatools")}},
+		}},
+	}
+	if port > 0 {
+		input.DestinationPort = awssdk.Int32(port)
+	}
+	created, err := v.api.CreateNetworkInsightsPath(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create network insights path: %w", err)
+	}
+	if created.NetworkInsightsPath == nil {
+		return nil, fmt.Errorf("failed to create network insights path: no path returned")
+	}
+	pathID := awssdk.ToString(created.NetworkInsightsPath.NetworkInsightsPathId)
+
+	var analysisID string
+	if !v.keep {
+		defer v.cleanup(ctx, pathID, &analysisID)
+	}
+
+	started, err := v.api.StartNetworkInsightsAnalysis(ctx, &ec2.StartNetworkInsightsAnalysisInput{NetworkInsightsPathId: awssdk.String(pathID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start network insights analysis: %w", err)
+	}
+	if started.NetworkInsightsAnalysis == nil {
+		return nil, fmt.Errorf("failed to start network insights analysis: no analysis returned")
+	}
+	analysisID = awssdk.ToString(started.NetworkInsightsAnalysis.NetworkInsightsAnalysisId)
+
+	analysis, err := v.wait(ctx, analysisID)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &Verification{
+		Source:      source,
+		Destination: destination,
+		Protocol:    protocol,
+		Port:        port,
+		PathID:      pathID,
+		AnalysisID:  analysisID,
+		Reachable:   awssdk.ToBool(analysis.NetworkPathFound),
+	}
+	for _, hop := range analysis.ForwardPathComponents {
+		if hop.Component != nil {
+			verification.Hops = append(verification.Hops, componentName(hop.Component))
+		}
+	}
+	for _, explanation := range analysis.Explanations {
+		verification.Explanations = append(verification.Explanations, explain(explanation))
+	}
+	return verification, nil
+}
+
+// wait polls an analysis until it finishes
+func (v *Verifier) wait(ctx context.Context, analysisID string) (*types.NetworkInsightsAnalysis, error) {
+	for {
+		result, err := v.api.DescribeNetworkInsightsAnalyses(ctx, &ec2.DescribeNetworkInsightsAnalysesInput{
+			NetworkInsightsAnalysisIds: []string{analysisID},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe network insights analysis %s: %w", analysisID, err)
+		}
+		if len(result.NetworkInsightsAnalyses) == 0 {
+			return nil, fmt.Errorf("network insights analysis %s not found", analysisID)
+		}
+
+		analysis := result.NetworkInsightsAnalyses[0]
+		switch analysis.Status {
+		case types.AnalysisStatusSucceeded:
+			return &analysis, nil
+		case types.AnalysisStatusFailed:
+			return nil, fmt.Errorf("network insights analysis %s failed: %s", analysisID, awssdk.ToString(analysis.StatusMessage))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gave up waiting for network insights analysis %s: %w", analysisID, ctx.Err())
+		case <-time.After(v.pollInterval):
+		}
+	}
+}
+
+// cleanup deletes the analysis and path, even when verifying was cancelled. An
+// analysis still running cannot be deleted, so what is left behind is logged for
+// the user to delete.
+func (v *Verifier) cleanup(ctx context.Context, pathID string, analysisID *string) {
+	ctx = context.WithoutCancel(ctx)
+	// A path cannot be deleted while it has analyses
+	if *analysisID != "" {
+		if _, err := v.api.DeleteNetworkInsightsAnalysis(ctx, &ec2.DeleteNetworkInsightsAnalysisInput{NetworkInsightsAnalysisId: analysisID}); err != nil {
+			v.logger.Warn("failed to delete network insights analysis", "analysis_id", *analysisID, "path_id", pathID, "error", err)
+		}
+	}
+	if _, err := v.api.DeleteNetworkInsightsPath(ctx, &ec2.DeleteNetworkInsightsPathInput{NetworkInsightsPathId: awssdk.String(pathID)}); err != nil {
+		v.logger.Warn("failed to delete network insights path", "path_id", pathID, "analysis_id", *analysisID, "error", err)
+	}
+}
+
+// componentName returns the ID of an analysis component, or its name or ARN when it has none
+func componentName(component *types.AnalysisComponent) string {
+	switch {
+	case component.Id != nil:
+		return *component.Id
+	case component.Name != nil:
+		return *component.Name
+	}
+	return awssdk.ToString(component.Arn)
+}
+
+// explain formats an explanation as its code and the component it concerns
+func explain(explanation types.Explanation) string {
+	code := awssdk.ToString(explanation.ExplanationCode)
+	for _, component := range []*types.AnalysisComponent{
+		explanation.Component, explanation.SecurityGroup, explanation.Acl, explanation.RouteTable, explanation.Subnet,
+		explanation.NetworkInterface, explanation.InternetGateway, explanation.Vpc,
+	} {
+		if component != nil {
+			return code + ": " + componentName(component)
+		}
+	}
+	return code
+}
+
+// Comparable reports whether the static analysis can evaluate a verification: its
+// destination must be an instance or network interface, its source one of those or an
+// internet gateway, and its port set
+func Comparable(v *Verification) bool {
+	return isInterfaceTarget(v.Destination) && (isInterfaceTarget(v.Source) || strings.HasPrefix(v.Source, "igw-")) && v.Port > 0
+}
+
+// CompareStatic runs the static analysis of the scanned network for the verified
+// source and destination and records its status. The status is left empty for
+// verifications that are not Comparable.
+func CompareStatic(ctx context.Context, api flows.EC2API, network *scanner.Network, v *Verification) error {
+	if !Comparable(v) {
+		return nil
+	}
+
+	target, err := LookupTarget(ctx, api, v.Destination)
+	if err != nil {
+		return err
+	}
+	report, err := Analyze(network, target, v.Protocol, v.Port)
+	if err != nil {
+		return err
+	}
+
+	var ips, groups []string
+	internet := strings.HasPrefix(v.Source, "igw-")
+	if !internet {
+		source, err := LookupTarget(ctx, api, v.Source)
+		if err != nil {
+			return err
+		}
+		var ids []string
+		for _, endpoint := range source.Endpoints {
+			ids = append(ids, endpoint.Interface)
+		}
+		interfaces, err := flows.LookupInterfaces(ctx, api, ids)
+		if err != nil {
+			return fmt.Errorf("failed to describe network interfaces of %s: %w", v.Source, err)
+		}
+		for _, id := range ids {
+			ips = append(ips, interfaces[id].PrivateIPs...)
+			groups = append(groups, interfaces[id].SecurityGroups...)
+		}
+	}
+
+	path, ok := report.PathFrom(ips, groups, internet)
+	if !ok {
+		v.Static = StatusBlocked
+		v.StaticReason = "no security group rule of the destination admits the source"
+		return nil
+	}
+	v.Static = path.Status
+	v.StaticReason = path.Reason
+	if v.StaticReason == "" {
+		v.StaticReason = "admitted as " + path.Source
+	}
+	return nil
+}
+
+// isInterfaceTarget reports whether an ID is an instance or network interface
+func isInterfaceTarget(id string) bool {
+	return strings.HasPrefix(id, "i-") || strings.HasPrefix(id, "eni-")
+}
+
+// Text formats the verification for the terminal
+func (v Verification) Text() string {
+	var result strings.Builder
+
+	port := ""
+	if v.Port > 0 {
+		port = fmt.Sprintf("/%d", v.Port)
+	}
+	result.WriteString(fmt.Sprintf("Reachability Analyzer: %s → %s on %s%s\n", v.Source, v.Destination, v.Protocol, port))
+	if v.Reachable {
+		result.WriteString("  Result: REACHABLE\n")
+		if len(v.Hops) > 0 {
+			result.WriteString("  Path: " + strings.Join(v.Hops, " → ") + "\n")
+		}
+	} else {
+		result.WriteString("  Result: NOT REACHABLE\n")
+		for _, explanation := range v.Explanations {
+			result.WriteString("    ✗ " + explanation + "\n")
+		}
+	}
+
+	switch {
+	case v.Static == "":
+		result.WriteString("  Static analysis: not compared, it only evaluates instance and network interface destinations on a port\n")
+	case v.Agrees():
+		result.WriteString(fmt.Sprintf("  Static analysis: %s (%s), agrees\n", strings.ToUpper(v.Static), v.StaticReason))
+	default:
+		result.WriteString(fmt.Sprintf("  Static analysis: %s (%s), DISAGREES\n", strings.ToUpper(v.Static), v.StaticReason))
+	}
+	return result.String()
+}
+
+// PathFrom returns the path the report has for traffic from a source with the given
+// private IPs and security groups, or from the internet, preferring reachable paths
+func (r Report) PathFrom(ips, groups []string, internet bool) (Path, bool) {
+	statusOrder := map[string]int{StatusReachable: 0, StatusUnknown: 1, StatusBlocked: 2}
+	var best Path
+	found := false
+	for _, path := range r.Paths {
+		if !pathCovers(path, ips, groups, internet) {
+			continue
+		}
+		if !found || statusOrder[path.Status] < statusOrder[best.Status] {
+			best, found = path, true
+		}
+	}
+	return best, found
+}
+
+// pathCovers reports whether a path's source includes the given source
+func pathCovers(path Path, ips, groups []string, internet bool) bool {
+	// Internet paths stand for public addresses, a source inside the network matches
+	// them only through the narrower paths of its own addresses
+	if internet || path.Kind == SourceInternet {
+		return internet && path.Kind == SourceInternet
+	}
+	if path.Kind == SourceSecurityGroup {
+		for _, group := range groups {
+			if path.Source == group {
+				return true
+			}
+		}
+		return false
+	}
+	_, cidr, err := net.ParseCIDR(path.Source)
+	if err != nil {
+		return false
+	}
+	for _, ip := range ips {
+		if parsed := net.ParseIP(ip); parsed != nil && cidr.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package reach
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// fakeInsights runs an analysis that is still running on its first describe and
+// finds a path through a security group on its second
+type fakeInsights struct {
+	created   *ec2.CreateNetworkInsightsPathInput
+	describes int
+	deleted   []string
+	deleteErr error
+}
+
+func (f *fakeInsights) CreateNetworkInsightsPath(ctx context.Context, params *ec2.CreateNetworkInsightsPathInput, optFns ...func(*ec2.Options)) (*ec2.CreateNetworkInsightsPathOutput, error) {
+	f.created = params
+	return &ec2.CreateNetworkInsightsPathOutput{NetworkInsightsPath: &types.NetworkInsightsPath{NetworkInsightsPathId: awssdk.String("nip-1")}}, nil
+}
+
+func (f *fakeInsights) StartNetworkInsightsAnalysis(ctx context.Context, params *ec2.StartNetworkInsightsAnalysisInput, optFns ...func(*ec2.Options)) (*ec2.StartNetworkInsightsAnalysisOutput, error) {
+	return &ec2.StartNetworkInsightsAnalysisOutput{NetworkInsightsAnalysis: &types.NetworkInsightsAnalysis{NetworkInsightsAnalysisId: awssdk.String("nia-1")}}, nil
+}
+
+func (f *fakeInsights) DescribeNetworkInsightsAnalyses(ctx context.Context, params *ec2.DescribeNetworkInsightsAnalysesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInsightsAnalysesOutput, error) {
+	f.describes++
+	analysis := types.NetworkInsightsAnalysis{NetworkInsightsAnalysisId: awssdk.String("nia-1"), Status: types.AnalysisStatusRunning}
+	if f.describes > 1 {
+		analysis.Status = types.AnalysisStatusSucceeded
+		analysis.NetworkPathFound = awssdk.Bool(true)
+		analysis.ForwardPathComponents = []types.PathComponent{
+			{Component: &types.AnalysisComponent{Id: awssdk.String("eni-src")}},
+			{Component: &types.AnalysisComponent{Id: awssdk.String("sg-web")}},
+			{Component: &types.AnalysisComponent{Id: awssdk.String("eni-dst")}},
+		}
+	}
+	return &ec2.DescribeNetworkInsightsAnalysesOutput{NetworkInsightsAnalyses: []types.NetworkInsightsAnalysis{analysis}}, nil
+}
+
+func (f *fakeInsights) DeleteNetworkInsightsAnalysis(ctx context.Context, params *ec2.DeleteNetworkInsightsAnalysisInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInsightsAnalysisOutput, error) {
+	f.deleted = append(f.deleted, awssdk.ToString(params.NetworkInsightsAnalysisId))
+	return &ec2.DeleteNetworkInsightsAnalysisOutput{}, f.deleteErr
+}
+
+func (f *fakeInsights) DeleteNetworkInsightsPath(ctx context.Context, params *ec2.DeleteNetworkInsightsPathInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkInsightsPathOutput, error) {
+	f.deleted = append(f.deleted, awssdk.ToString(params.NetworkInsightsPathId))
+	return &ec2.DeleteNetworkInsightsPathOutput{}, f.deleteErr
+}
+
+func TestVerify(t *testing.T) {
+	api := &fakeInsights{}
+	verifier := NewVerifier(api)
+	verifier.SetPollInterval(0)
+
+	v, err := verifier.Verify(context.Background(), "i-src", "i-dst", "TCP", 443)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !v.Reachable || v.PathID != "nip-1" || v.AnalysisID != "nia-1" {
+		t.Errorf("Verification = %+v, want reachable through nip-1 and nia-1", v)
+	}
+	if want := []string{"eni-src", "sg-web", "eni-dst"}; !reflect.DeepEqual(v.Hops, want) {
+		t.Errorf("Hops = %v, want %v", v.Hops, want)
+	}
+	if api.created.Protocol != types.ProtocolTcp || awssdk.ToInt32(api.created.DestinationPort) != 443 {
+		t.Errorf("path created with protocol %s and port %d, want tcp and 443", api.created.Protocol, awssdk.ToInt32(api.created.DestinationPort))
+	}
+	// The analysis has to be deleted before its path
+	if want := []string{"nia-1", "nip-1"}; !reflect.DeepEqual(api.deleted, want) {
+		t.Errorf("deleted %v, want %v", api.deleted, want)
+	}
+
+	api = &fakeInsights{}
+	verifier = NewVerifier(api)
+	verifier.SetPollInterval(0)
+	verifier.SetKeep(true)
+	if _, err := verifier.Verify(context.Background(), "i-src", "i-dst", "tcp", 0); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(api.deleted) > 0 {
+		t.Errorf("deleted %v with keep set", api.deleted)
+	}
+	if api.created.DestinationPort != nil {
+		t.Errorf("path created with port %d, want any port", *api.created.DestinationPort)
+	}
+
+	if _, err := verifier.Verify(context.Background(), "i-src", "i-dst", "icmp", 0); err == nil {
+		t.Error("Verify accepted an unsupported protocol")
+	}
+
+	// What cannot be deleted is logged with its IDs
+	api = &fakeInsights{deleteErr: errors.New("IncorrectState: analysis is running")}
+	var logs bytes.Buffer
+	verifier = NewVerifier(api)
+	verifier.SetPollInterval(0)
+	verifier.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	if _, err := verifier.Verify(context.Background(), "i-src", "i-dst", "tcp", 443); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	for _, want := range []string{
+		"failed to delete network insights analysis\" analysis_id=nia-1 path_id=nip-1",
+		"failed to delete network insights path\" path_id=nip-1 analysis_id=nia-1",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("Expected the log to contain %q, got:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestPathFrom(t *testing.T) {
+	report := Report{Paths: []Path{
+		{Source: "10.0.0.0/16", Kind: SourceSameVPC, Status: StatusBlocked},
+		{Source: "10.0.1.0/24", Kind: SourceSameVPC, Status: StatusReachable},
+		{Source: "sg-app", Kind: SourceSecurityGroup, Status: StatusUnknown},
+		{Source: "0.0.0.0/0", Kind: SourceInternet, Status: StatusReachable},
+	}}
+
+	tests := []struct {
+		name     string
+		ips      []string
+		groups   []string
+		internet bool
+		want     string
+		found    bool
+	}{
+		{name: "reachable CIDR preferred", ips: []string{"10.0.1.5"}, want: "10.0.1.0/24", found: true},
+		{name: "only blocked CIDR", ips: []string{"10.0.2.5"}, want: "10.0.0.0/16", found: true},
+		{name: "security group", ips: []string{"192.168.0.1"}, groups: []string{"sg-app"}, want: "sg-app", found: true},
+		{name: "internet", internet: true, want: "0.0.0.0/0", found: true},
+		{name: "not covered", ips: []string{"192.168.0.1"}, groups: []string{"sg-other"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, found := report.PathFrom(tt.ips, tt.groups, tt.internet)
+			if found != tt.found || path.Source != tt.want {
+				t.Errorf("PathFrom() = %s, %v, want %s, %v", path.Source, found, tt.want, tt.found)
+			}
+		})
+	}
+}
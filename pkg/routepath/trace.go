@@ -0,0 +1,474 @@
+// Package routepath traces the path a packet takes through route tables,
+// peering connections and transit gateways of a scanned network.
+package routepath
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// maxHops bounds a trace so routing loops end
+const maxHops = 16
+
+// Hop kinds
+const (
+	HopRouteTable               = "route-table"
+	HopTransitGatewayRouteTable = "transit-gateway-route-table"
+)
+
+// Outcomes of a trace
+const (
+	OutcomeDelivered = "delivered"
+	OutcomeExited    = "exited"
+	OutcomeDropped   = "dropped"
+	OutcomeUnknown   = "unknown"
+)
+
+// Candidate is a route whose destination contains the traced destination
+type Candidate struct {
+	Destination string `json:"destination"`
+	Target      string `json:"target"`
+	State       string `json:"state"`
+}
+
+// Hop is one routing decision on the path
+type Hop struct {
+	Kind       string      `json:"kind"`
+	Location   string      `json:"location"` // Subnet, VPC or transit gateway the packet is in
+	Table      string      `json:"table"`
+	Candidates []Candidate `json:"candidates"`
+	Selected   *Candidate  `json:"selected,omitempty"`
+	Note       string      `json:"note,omitempty"`
+}
+
+// Path is the traced hop-by-hop path and where it ends
+type Path struct {
+	SourceSubnet string `json:"source_subnet"`
+	Destination  string `json:"destination"`
+	Hops         []Hop  `json:"hops"`
+	Outcome      string `json:"outcome"`
+	Detail       string `json:"detail"`
+	VpcID        string `json:"vpc_id,omitempty"` // VPC the packet is delivered in
+}
+
+// route is a route table entry reduced to what longest prefix matching needs
+type route struct {
+	destination string
+	target      string
+	state       string
+}
+
+// tracer walks a network's routing
+type tracer struct {
+	network     *scanner.Network
+	destination *net.IPNet
+	path        Path
+	visited     map[string]bool
+}
+
+// Trace traces the path from a subnet to a destination CIDR or address
+func Trace(network *scanner.Network, subnetID, destination string) (Path, error) {
+	dst, err := parseDestination(destination)
+	if err != nil {
+		return Path{}, err
+	}
+
+	var subnet *scanner.Subnet
+	for i := range network.Subnets {
+		if network.Subnets[i].ID == subnetID {
+			subnet = &network.Subnets[i]
+		}
+	}
+	if subnet == nil {
+		return Path{}, fmt.Errorf("subnet %s is not in the scanned network", subnetID)
+	}
+
+	t := &tracer{
+		network:     network,
+		destination: dst,
+		path:        Path{SourceSubnet: subnetID, Destination: dst.String()},
+		visited:     make(map[string]bool),
+	}
+
+	if subnet.RouteTableID == "" {
+		t.end(OutcomeUnknown, fmt.Sprintf("subnet %s has no route table", subnetID))
+		return t.path, nil
+	}
+	t.routeTable(subnet.ID, subnet.VpcID, subnet.RouteTableID, "")
+
+	return t.path, nil
+}
+
+// parseDestination parses a CIDR, or a single address as a host route
+func parseDestination(destination string) (*net.IPNet, error) {
+	if !strings.Contains(destination, "/") {
+		ip := net.ParseIP(destination)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid destination %q, expected a CIDR or IP address", destination)
+		}
+		bits := 32
+		if ip.To4() == nil {
+			bits = 128
+		}
+		destination = fmt.Sprintf("%s/%d", destination, bits)
+	}
+
+	_, dst, err := net.ParseCIDR(destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination %q, expected a CIDR or IP address", destination)
+	}
+	return dst, nil
+}
+
+// end records where the path ends
+func (t *tracer) end(outcome, detail string) {
+	t.path.Outcome = outcome
+	t.path.Detail = detail
+}
+
+// contains reports whether a route destination contains the whole traced destination
+func (t *tracer) contains(cidr string) (bool, int) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false, 0
+	}
+	routeBits, routeSize := network.Mask.Size()
+	dstBits, dstSize := t.destination.Mask.Size()
+	if routeSize != dstSize || routeBits > dstBits {
+		return false, 0
+	}
+	return network.Contains(t.destination.IP), routeBits
+}
+
+// containedIn reports whether any of the CIDRs contains the whole traced destination
+func (t *tracer) containedIn(cidrs []string) bool {
+	for _, cidr := range cidrs {
+		if ok, _ := t.contains(cidr); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// vpcCidrs returns a VPC's IPv4 and IPv6 CIDR blocks
+func vpcCidrs(vpc *scanner.VPC) []string {
+	return append([]string{vpc.CidrBlock}, vpc.Ipv6CidrBlocks...)
+}
+
+// longestPrefixMatch returns the routes containing the destination and the most
+// specific of them, or nil when none does
+func (t *tracer) longestPrefixMatch(routes []route) ([]Candidate, *Candidate) {
+	var candidates []Candidate
+	selected, best := -1, -1
+	for _, r := range routes {
+		ok, bits := t.contains(r.destination)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, Candidate{Destination: r.destination, Target: r.target, State: r.state})
+		if bits > best {
+			best = bits
+			selected = len(candidates) - 1
+		}
+	}
+	if selected < 0 {
+		return candidates, nil
+	}
+	chosen := candidates[selected]
+	return candidates, &chosen
+}
+
+// routeTable makes the routing decision of a VPC route table
+func (t *tracer) routeTable(location, vpcID, routeTableID, note string) {
+	if len(t.path.Hops) >= maxHops || t.visited[routeTableID] {
+		t.end(OutcomeDropped, fmt.Sprintf("routing loop through %s", routeTableID))
+		return
+	}
+	t.visited[routeTableID] = true
+
+	var table *scanner.RouteTable
+	for i := range t.network.RouteTables {
+		if t.network.RouteTables[i].ID == routeTableID {
+			table = &t.network.RouteTables[i]
+		}
+	}
+	if table == nil {
+		t.end(OutcomeUnknown, fmt.Sprintf("route table %s was not scanned", routeTableID))
+		return
+	}
+
+	var routes []route
+	for _, r := range table.Routes {
+		if r.Destination() == "" {
+			continue
+		}
+		routes = append(routes, route{destination: r.Destination(), target: r.Target(), state: r.State})
+	}
+
+	candidates, selected := t.longestPrefixMatch(routes)
+	t.path.Hops = append(t.path.Hops, Hop{
+		Kind:       HopRouteTable,
+		Location:   location,
+		Table:      routeTableID,
+		Candidates: candidates,
+		Selected:   selected,
+		Note:       note,
+	})
+
+	if selected == nil {
+		t.end(OutcomeDropped, fmt.Sprintf("no route in %s matches %s", routeTableID, t.path.Destination))
+		return
+	}
+	if selected.State == "blackhole" {
+		t.end(OutcomeDropped, fmt.Sprintf("route %s in %s is a blackhole", selected.Destination, routeTableID))
+		return
+	}
+
+	target := selected.Target
+	switch {
+	case target == "local":
+		t.end(OutcomeDelivered, t.deliveredIn(vpcID))
+	case strings.HasPrefix(target, "igw-"):
+		t.end(OutcomeExited, fmt.Sprintf("to the internet through internet gateway %s", target))
+	case strings.HasPrefix(target, "eigw-"):
+		t.end(OutcomeExited, fmt.Sprintf("to the internet through egress-only internet gateway %s, which only allows outbound IPv6", target))
+	case strings.HasPrefix(target, "cagw-"):
+		t.end(OutcomeExited, fmt.Sprintf("to the carrier network through carrier gateway %s", target))
+	case strings.HasPrefix(target, "lgw-"):
+		t.end(OutcomeExited, fmt.Sprintf("to the on-premises network through local gateway %s", target))
+	case strings.HasPrefix(target, "vgw-"):
+		t.end(OutcomeExited, fmt.Sprintf("to the VPN through virtual private gateway %s", target))
+	case strings.HasPrefix(target, "nat-"):
+		t.end(OutcomeExited, fmt.Sprintf("through NAT gateway %s", target))
+	case strings.HasPrefix(target, "pcx-"):
+		t.peering(vpcID, target)
+	case strings.HasPrefix(target, "tgw-"):
+		t.transitGateway(vpcID, target)
+	case strings.HasPrefix(target, "i-"), strings.HasPrefix(target, "eni-"):
+		t.end(OutcomeExited, fmt.Sprintf("to appliance %s, where forwarding is not traced", target))
+	default:
+		t.end(OutcomeUnknown, fmt.Sprintf("route target %q is not traced", target))
+	}
+}
+
+// deliveredIn records the VPC the packet is delivered in and describes delivery inside
+// it, naming the subnet holding the destination
+func (t *tracer) deliveredIn(vpcID string) string {
+	t.path.VpcID = vpcID
+	for _, subnet := range t.network.Subnets {
+		if subnet.VpcID != vpcID {
+			continue
+		}
+		if t.containedIn(append([]string{subnet.CidrBlock}, subnet.Ipv6CidrBlocks...)) {
+			return fmt.Sprintf("in %s, subnet %s", vpcID, subnet.ID) + t.vpcEndpointIn(vpcID)
+		}
+	}
+	return fmt.Sprintf("in %s", vpcID)
+}
+
+// vpcEndpointIn names the PrivateLink VPC endpoint in the VPC holding the
+// destination, when the destination is a single address
+func (t *tracer) vpcEndpointIn(vpcID string) string {
+	if ones, bits := t.destination.Mask.Size(); ones != bits {
+		return ""
+	}
+	endpoint, eni, ok := t.network.VpcEndpointFor(t.destination.IP.String())
+	if !ok || endpoint.VpcID != vpcID {
+		return ""
+	}
+	return fmt.Sprintf(", VPC endpoint %s (%s) on %s", endpoint.ID, endpoint.ServiceName, eni.ID)
+}
+
+// vpc returns a scanned VPC
+func (t *tracer) vpc(vpcID string) *scanner.VPC {
+	for i := range t.network.VPCs {
+		if t.network.VPCs[i].ID == vpcID {
+			return &t.network.VPCs[i]
+		}
+	}
+	return nil
+}
+
+// peering crosses a VPC peering connection. Peering is not transitive, so the
+// packet is only delivered when the destination is inside the peer VPC.
+func (t *tracer) peering(vpcID, peeringID string) {
+	var connection *scanner.PeeringConnection
+	for i := range t.network.PeeringConnections {
+		if t.network.PeeringConnections[i].ID == peeringID {
+			connection = &t.network.PeeringConnections[i]
+		}
+	}
+	if connection == nil {
+		t.end(OutcomeUnknown, fmt.Sprintf("peering connection %s was not scanned", peeringID))
+		return
+	}
+	if connection.Status != "active" {
+		t.end(OutcomeDropped, fmt.Sprintf("peering connection %s is %s", peeringID, connection.Status))
+		return
+	}
+
+	peerID := connection.AccepterVpcID
+	if peerID == vpcID {
+		peerID = connection.RequesterVpcID
+	}
+
+	peer := t.vpc(peerID)
+	if peer == nil {
+		t.end(OutcomeExited, fmt.Sprintf("through peering connection %s to %s, which was not scanned", peeringID, peerID))
+		return
+	}
+	if !t.containedIn(vpcCidrs(peer)) {
+		t.end(OutcomeDropped, fmt.Sprintf("%s is outside peer %s (%s) and peering does not route transitively", t.path.Destination, peerID, peer.CidrBlock))
+		return
+	}
+	t.end(OutcomeDelivered, t.deliveredIn(peerID)+fmt.Sprintf(" through peering connection %s", peeringID))
+}
+
+// transitGateway makes the routing decision of the transit gateway route table
+// associated with the VPC's attachment
+func (t *tracer) transitGateway(vpcID, tgwID string) {
+	var tgw *scanner.TransitGateway
+	for i := range t.network.TransitGateways {
+		if t.network.TransitGateways[i].ID == tgwID {
+			tgw = &t.network.TransitGateways[i]
+		}
+	}
+	if tgw == nil {
+		t.end(OutcomeUnknown, fmt.Sprintf("transit gateway %s was not scanned", tgwID))
+		return
+	}
+
+	var attachment *scanner.TransitGatewayAttachment
+	for i := range tgw.Attachments {
+		if tgw.Attachments[i].ResourceType == "vpc" && tgw.Attachments[i].ResourceID == vpcID {
+			attachment = &tgw.Attachments[i]
+		}
+	}
+	if attachment == nil {
+		t.end(OutcomeDropped, fmt.Sprintf("%s has no attachment to transit gateway %s", vpcID, tgwID))
+		return
+	}
+	if attachment.RouteTableID == "" {
+		t.end(OutcomeDropped, fmt.Sprintf("attachment %s is not associated with a transit gateway route table", attachment.ID))
+		return
+	}
+	if len(t.path.Hops) >= maxHops || t.visited[attachment.RouteTableID] {
+		t.end(OutcomeDropped, fmt.Sprintf("routing loop through %s", attachment.RouteTableID))
+		return
+	}
+	t.visited[attachment.RouteTableID] = true
+
+	var table *scanner.TransitGatewayRouteTable
+	for i := range tgw.RouteTables {
+		if tgw.RouteTables[i].ID == attachment.RouteTableID {
+			table = &tgw.RouteTables[i]
+		}
+	}
+	if table == nil {
+		t.end(OutcomeUnknown, fmt.Sprintf("transit gateway route table %s was not scanned", attachment.RouteTableID))
+		return
+	}
+
+	var routes []route
+	attachments := make(map[string]scanner.TransitGatewayRoute)
+	for _, r := range table.Routes {
+		if r.DestinationCidr == "" {
+			continue
+		}
+		target := r.AttachmentID
+		if target == "" {
+			target = "blackhole"
+		}
+		routes = append(routes, route{destination: r.DestinationCidr, target: target, state: r.State})
+		attachments[r.DestinationCidr] = r
+	}
+
+	candidates, selected := t.longestPrefixMatch(routes)
+	t.path.Hops = append(t.path.Hops, Hop{
+		Kind:       HopTransitGatewayRouteTable,
+		Location:   tgwID,
+		Table:      table.ID,
+		Candidates: candidates,
+		Selected:   selected,
+		Note:       fmt.Sprintf("associated with attachment %s", attachment.ID),
+	})
+
+	if selected == nil {
+		t.end(OutcomeDropped, fmt.Sprintf("no route in %s matches %s", table.ID, t.path.Destination))
+		return
+	}
+	if selected.State == "blackhole" {
+		t.end(OutcomeDropped, fmt.Sprintf("route %s in %s is a blackhole", selected.Destination, table.ID))
+		return
+	}
+
+	next := attachments[selected.Destination]
+	switch next.ResourceType {
+	case "vpc":
+		t.enterVPC(next.ResourceID, next.AttachmentID)
+	case "peering":
+		t.end(OutcomeExited, fmt.Sprintf("to the peer transit gateway through attachment %s", next.AttachmentID))
+	default:
+		t.end(OutcomeExited, fmt.Sprintf("through %s attachment %s to %s", next.ResourceType, next.AttachmentID, next.ResourceID))
+	}
+}
+
+// enterVPC continues the trace in a VPC reached through a transit gateway. The
+// packet is delivered when the destination is inside the VPC; otherwise it is
+// routed by the VPC's main route table, standing in for the attachment subnets' tables.
+func (t *tracer) enterVPC(vpcID, attachmentID string) {
+	vpc := t.vpc(vpcID)
+	if vpc == nil {
+		t.end(OutcomeExited, fmt.Sprintf("through attachment %s to %s, which was not scanned", attachmentID, vpcID))
+		return
+	}
+	if t.containedIn(vpcCidrs(vpc)) {
+		t.end(OutcomeDelivered, t.deliveredIn(vpcID)+fmt.Sprintf(" through attachment %s", attachmentID))
+		return
+	}
+
+	for _, table := range t.network.RouteTables {
+		if table.VpcID == vpcID && table.IsMain {
+			t.routeTable(vpcID, vpcID, table.ID, fmt.Sprintf("main route table, entered through attachment %s", attachmentID))
+			return
+		}
+	}
+	t.end(OutcomeUnknown, fmt.Sprintf("%s has no main route table", vpcID))
+}
+
+// Text renders the path hop by hop
+func (p Path) Text() string {
+	var result strings.Builder
+
+	result.WriteString(fmt.Sprintf("Route path from %s to %s\n\n", p.SourceSubnet, p.Destination))
+	for i, hop := range p.Hops {
+		kind := "route table"
+		if hop.Kind == HopTransitGatewayRouteTable {
+			kind = "transit gateway route table"
+		}
+		result.WriteString(fmt.Sprintf("%d. %s: %s %s\n", i+1, hop.Location, kind, hop.Table))
+		if hop.Note != "" {
+			result.WriteString(fmt.Sprintf("   (%s)\n", hop.Note))
+		}
+		for _, candidate := range hop.Candidates {
+			marker := " "
+			if hop.Selected != nil && candidate == *hop.Selected {
+				marker = "*"
+			}
+			state := ""
+			if candidate.State != "" && candidate.State != "active" {
+				state = " [" + candidate.State + "]"
+			}
+			result.WriteString(fmt.Sprintf("   %s %-20s → %s%s\n", marker, candidate.Destination, candidate.Target, state))
+		}
+		if hop.Selected != nil && len(hop.Candidates) > 1 {
+			result.WriteString(fmt.Sprintf("   longest prefix match: %s\n", hop.Selected.Destination))
+		}
+	}
+
+	result.WriteString(fmt.Sprintf("\nResult: %s %s\n", p.Outcome, p.Detail))
+	return result.String()
+}
@@ -0,0 +1,157 @@
+package routepath
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// traceNetwork has three VPCs. vpc-a reaches vpc-b through peering and vpc-c
+// through a transit gateway, and sends everything else to the internet. vpc-a is
+// dual-stack and sends IPv6 traffic out through an egress-only internet gateway.
+func traceNetwork() *scanner.Network {
+	return &scanner.Network{
+		VPCs: []scanner.VPC{
+			{ID: "vpc-a", CidrBlock: "10.0.0.0/16", Ipv6CidrBlocks: []string{"2600:1f18:1:100::/56"}},
+			{ID: "vpc-b", CidrBlock: "10.1.0.0/16"},
+			{ID: "vpc-c", CidrBlock: "10.2.0.0/16"},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-a", VpcID: "vpc-a", CidrBlock: "10.0.1.0/24", Ipv6CidrBlocks: []string{"2600:1f18:1:100::/64"}, RouteTableID: "rtb-a"},
+			{ID: "subnet-c", VpcID: "vpc-c", CidrBlock: "10.2.1.0/24", RouteTableID: "rtb-c"},
+			{ID: "subnet-orphan", VpcID: "vpc-a", CidrBlock: "10.0.9.0/24"},
+		},
+		VpcEndpoints: []scanner.VpcEndpoint{
+			{ID: "vpce-a", VpcID: "vpc-a", ServiceName: "com.amazonaws.us-east-1.s3", NetworkInterfaces: []scanner.EndpointNetworkInterface{
+				{ID: "eni-vpce-a", SubnetID: "subnet-a", PrivateIP: "10.0.1.45"},
+			}},
+		},
+		RouteTables: []scanner.RouteTable{
+			{ID: "rtb-a", VpcID: "vpc-a", Routes: []scanner.Route{
+				{DestinationCidr: "10.0.0.0/16", GatewayID: "local", State: "active"},
+				{DestinationCidr: "10.1.0.0/16", VpcPeeringID: "pcx-ab", State: "active"},
+				{DestinationCidr: "10.0.0.0/8", TransitGatewayID: "tgw-1", State: "active"},
+				{DestinationCidr: "10.3.0.0/16", TransitGatewayID: "tgw-1", State: "blackhole"},
+				{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-a", State: "active"},
+				{DestinationIpv6Cidr: "2600:1f18:1:100::/56", GatewayID: "local", State: "active"},
+				{DestinationIpv6Cidr: "::/0", EgressOnlyGatewayID: "eigw-a", State: "active"},
+			}},
+			{ID: "rtb-c", VpcID: "vpc-c", IsMain: true, Routes: []scanner.Route{
+				{DestinationCidr: "10.2.0.0/16", GatewayID: "local", State: "active"},
+				{DestinationCidr: "0.0.0.0/0", TransitGatewayID: "tgw-1", State: "active"},
+			}},
+		},
+		PeeringConnections: []scanner.PeeringConnection{
+			{ID: "pcx-ab", RequesterVpcID: "vpc-a", AccepterVpcID: "vpc-b", Status: "active"},
+		},
+		TransitGateways: []scanner.TransitGateway{
+			{
+				ID: "tgw-1",
+				Attachments: []scanner.TransitGatewayAttachment{
+					{ID: "tgw-attach-a", ResourceID: "vpc-a", ResourceType: "vpc", RouteTableID: "tgw-rtb-spoke"},
+					{ID: "tgw-attach-c", ResourceID: "vpc-c", ResourceType: "vpc", RouteTableID: "tgw-rtb-spoke"},
+				},
+				RouteTables: []scanner.TransitGatewayRouteTable{
+					{ID: "tgw-rtb-spoke", Routes: []scanner.TransitGatewayRoute{
+						{DestinationCidr: "10.0.0.0/16", AttachmentID: "tgw-attach-a", ResourceID: "vpc-a", ResourceType: "vpc", State: "active"},
+						{DestinationCidr: "10.2.0.0/16", AttachmentID: "tgw-attach-c", ResourceID: "vpc-c", ResourceType: "vpc", State: "active"},
+						{DestinationCidr: "10.4.0.0/16", AttachmentID: "tgw-attach-a", ResourceID: "vpc-a", ResourceType: "vpc", State: "active"},
+						{DestinationCidr: "10.5.0.0/16", State: "blackhole"},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		name        string
+		subnet      string
+		destination string
+		outcome     string
+		detail      string
+		hops        int
+	}{
+		{"local", "subnet-a", "10.0.1.5", OutcomeDelivered, "in vpc-a, subnet subnet-a", 1},
+		{"vpc endpoint", "subnet-a", "10.0.1.45", OutcomeDelivered, "in vpc-a, subnet subnet-a, VPC endpoint vpce-a (com.amazonaws.us-east-1.s3) on eni-vpce-a", 1},
+		{"peering", "subnet-a", "10.1.0.0/24", OutcomeDelivered, "in vpc-b through peering connection pcx-ab", 1},
+		{"transit gateway", "subnet-a", "10.2.1.0/24", OutcomeDelivered, "in vpc-c, subnet subnet-c through attachment tgw-attach-c", 2},
+		{"internet", "subnet-a", "8.8.8.8", OutcomeExited, "to the internet through internet gateway igw-a", 1},
+		{"ipv6 local", "subnet-a", "2600:1f18:1:100::5", OutcomeDelivered, "in vpc-a, subnet subnet-a", 1},
+		{"ipv6 internet", "subnet-a", "2001:db8::1", OutcomeExited, "to the internet through egress-only internet gateway eigw-a, which only allows outbound IPv6", 1},
+		{"vpc blackhole", "subnet-a", "10.3.0.0/16", OutcomeDropped, "route 10.3.0.0/16 in rtb-a is a blackhole", 1},
+		{"transit gateway blackhole", "subnet-a", "10.5.1.1", OutcomeDropped, "route 10.5.0.0/16 in tgw-rtb-spoke is a blackhole", 2},
+		{"no transit gateway route", "subnet-a", "10.6.0.0/16", OutcomeDropped, "no route in tgw-rtb-spoke matches 10.6.0.0/16", 2},
+		{"no route table", "subnet-orphan", "10.0.1.5", OutcomeUnknown, "subnet subnet-orphan has no route table", 0},
+		// 10.4.0.0/16 goes back to vpc-a, whose main route table is not scanned
+		{"back to source", "subnet-c", "10.4.0.1", OutcomeUnknown, "vpc-a has no main route table", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := Trace(traceNetwork(), tt.subnet, tt.destination)
+			if err != nil {
+				t.Fatalf("Trace failed: %v", err)
+			}
+			if path.Outcome != tt.outcome || path.Detail != tt.detail {
+				t.Errorf("got %s %q, want %s %q", path.Outcome, path.Detail, tt.outcome, tt.detail)
+			}
+			if len(path.Hops) != tt.hops {
+				t.Errorf("got %d hops, want %d: %+v", len(path.Hops), tt.hops, path.Hops)
+			}
+		})
+	}
+}
+
+func TestTraceLongestPrefixMatch(t *testing.T) {
+	path, err := Trace(traceNetwork(), "subnet-a", "10.2.1.0/24")
+	if err != nil {
+		t.Fatalf("Trace failed: %v", err)
+	}
+
+	first := path.Hops[0]
+	if len(first.Candidates) != 2 {
+		t.Errorf("expected 10.0.0.0/8 and 0.0.0.0/0 as candidates, got %+v", first.Candidates)
+	}
+	if first.Selected == nil || first.Selected.Destination != "10.0.0.0/8" || first.Selected.Target != "tgw-1" {
+		t.Errorf("expected the /8 to win, got %+v", first.Selected)
+	}
+
+	second := path.Hops[1]
+	if second.Kind != HopTransitGatewayRouteTable || second.Table != "tgw-rtb-spoke" || second.Selected.Target != "tgw-attach-c" {
+		t.Errorf("unexpected transit gateway hop: %+v", second)
+	}
+
+	text := path.Text()
+	for _, want := range []string{"Route path from subnet-a to 10.2.1.0/24", "* 10.0.0.0/8", "longest prefix match: 10.0.0.0/8", "transit gateway route table tgw-rtb-spoke", "Result: delivered"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text output missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestTraceLoop(t *testing.T) {
+	network := traceNetwork()
+	// Send vpc-c's traffic for 10.7.0.0/16 back to the transit gateway, which sends it to vpc-c
+	network.TransitGateways[0].RouteTables[0].Routes = append(network.TransitGateways[0].RouteTables[0].Routes,
+		scanner.TransitGatewayRoute{DestinationCidr: "10.7.0.0/16", AttachmentID: "tgw-attach-c", ResourceID: "vpc-c", ResourceType: "vpc", State: "active"})
+
+	path, err := Trace(network, "subnet-c", "10.7.0.1")
+	if err != nil {
+		t.Fatalf("Trace failed: %v", err)
+	}
+	if path.Outcome != OutcomeDropped || !strings.Contains(path.Detail, "routing loop") {
+		t.Errorf("expected a routing loop, got %s %q", path.Outcome, path.Detail)
+	}
+}
+
+func TestTraceErrors(t *testing.T) {
+	if _, err := Trace(traceNetwork(), "subnet-missing", "10.0.0.0/16"); err == nil {
+		t.Error("expected an error for an unknown subnet")
+	}
+	if _, err := Trace(traceNetwork(), "subnet-a", "not-an-address"); err == nil {
+		t.Error("expected an error for an invalid destination")
+	}
+}
@@ -0,0 +1,180 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/appmesh"
+	meshTypes "github.com/aws/aws-sdk-go-v2/service/appmesh/types"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// SetScanAppMesh enables or disables discovery of App Mesh virtual gateways
+func (s *NetworkScanner) SetScanAppMesh(scanAppMesh bool) {
+	s.scanAppMesh = scanAppMesh
+}
+
+// scanMeshVirtualGateways scans the virtual gateways of the meshes visible to the
+// account. When only some VPCs are scanned, only the gateways whose Envoy runs in
+// an ECS service of those VPCs are kept, since gateways are not placed in a VPC
+// themselves.
+func (s *NetworkScanner) scanMeshVirtualGateways(ctx context.Context, network *Network, allVPCs bool) ([]MeshVirtualGateway, error) {
+	inScope := make(map[string]bool)
+	if !allVPCs {
+		services, err := s.scannedECSServices(ctx, network)
+		if err != nil {
+			return nil, err
+		}
+		definitions, err := s.describeTaskDefinitions(ctx, services)
+		if err != nil {
+			return nil, err
+		}
+		for _, definition := range definitions {
+			if resource := meshResource(definition); strings.Contains(resource, "/virtualGateway/") {
+				inScope[resource] = true
+			}
+		}
+	}
+
+	var gateways []MeshVirtualGateway
+
+	meshes := appmesh.NewListMeshesPaginator(s.apis.AppMesh, &appmesh.ListMeshesInput{})
+	for meshes.HasMorePages() {
+		page, err := meshes.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mesh := range page.Meshes {
+			meshGateways, err := s.getMeshVirtualGateways(ctx, mesh)
+			if err != nil {
+				return nil, err
+			}
+			for _, gateway := range meshGateways {
+				if allVPCs || inScope[meshResourceName(gateway.Arn)] {
+					gateways = append(gateways, gateway)
+				}
+			}
+		}
+	}
+
+	return gateways, nil
+}
+
+// scannedECSServices returns the ECS services of the scanned VPCs, scanning them
+// when containers are not
+func (s *NetworkScanner) scannedECSServices(ctx context.Context, network *Network) ([]ECSService, error) {
+	if !s.scanContainers {
+		return s.scanECSServices(ctx, network.Subnets)
+	}
+	for _, scanError := range network.ScanErrors {
+		if scanError.ResourceType == "ecs_service" {
+			return nil, errors.New(scanError.Error)
+		}
+	}
+	return network.ECSServices, nil
+}
+
+// meshResource returns the App Mesh virtual node or gateway the Envoy container of
+// an ECS task definition runs as, such as mesh/payments/virtualGateway/ingress
+func meshResource(definition *ecsTypes.TaskDefinition) string {
+	if definition == nil {
+		return ""
+	}
+	for _, container := range definition.ContainerDefinitions {
+		for _, variable := range container.Environment {
+			if variable.Name == nil || variable.Value == nil {
+				continue
+			}
+			// Envoy takes the resource ARN, or its name in older images
+			if *variable.Name == "APPMESH_RESOURCE_ARN" || *variable.Name == "APPMESH_VIRTUAL_NODE_NAME" {
+				return meshResourceName(*variable.Value)
+			}
+		}
+	}
+	return ""
+}
+
+// meshResourceName returns the mesh/... name of an App Mesh resource from its ARN
+// or name
+func meshResourceName(resource string) string {
+	if i := strings.Index(resource, "mesh/"); i >= 0 {
+		return resource[i:]
+	}
+	return ""
+}
+
+// getMeshVirtualGateways lists and describes the virtual gateways of one mesh
+func (s *NetworkScanner) getMeshVirtualGateways(ctx context.Context, mesh meshTypes.MeshRef) ([]MeshVirtualGateway, error) {
+	var gateways []MeshVirtualGateway
+
+	refs := appmesh.NewListVirtualGatewaysPaginator(s.apis.AppMesh, &appmesh.ListVirtualGatewaysInput{
+		MeshName:  mesh.MeshName,
+		MeshOwner: mesh.MeshOwner,
+	})
+	for refs.HasMorePages() {
+		page, err := refs.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range page.VirtualGateways {
+			result, err := s.apis.AppMesh.DescribeVirtualGateway(ctx, &appmesh.DescribeVirtualGatewayInput{
+				MeshName:           ref.MeshName,
+				MeshOwner:          ref.MeshOwner,
+				VirtualGatewayName: ref.VirtualGatewayName,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			gateways = append(gateways, convertMeshVirtualGateway(result.VirtualGateway))
+		}
+	}
+
+	return gateways, nil
+}
+
+// convertMeshVirtualGateway converts a described virtual gateway
+func convertMeshVirtualGateway(data *meshTypes.VirtualGatewayData) MeshVirtualGateway {
+	gateway := MeshVirtualGateway{}
+	if data == nil {
+		return gateway
+	}
+
+	if data.VirtualGatewayName != nil {
+		gateway.Name = *data.VirtualGatewayName
+	}
+	if data.MeshName != nil {
+		gateway.MeshName = *data.MeshName
+	}
+	if data.Metadata != nil {
+		if data.Metadata.Arn != nil {
+			gateway.Arn = *data.Metadata.Arn
+		}
+		if data.Metadata.MeshOwner != nil {
+			gateway.MeshOwner = *data.Metadata.MeshOwner
+		}
+	}
+	if data.Status != nil {
+		gateway.Status = strings.ToLower(string(data.Status.Status))
+	}
+
+	if data.Spec != nil {
+		for _, listener := range data.Spec.Listeners {
+			meshListener := MeshListener{
+				TLS: listener.Tls != nil && listener.Tls.Mode != meshTypes.VirtualGatewayListenerTlsModeDisabled,
+			}
+			if listener.PortMapping != nil {
+				if listener.PortMapping.Port != nil {
+					meshListener.Port = *listener.PortMapping.Port
+				}
+				meshListener.Protocol = string(listener.PortMapping.Protocol)
+			}
+			gateway.Listeners = append(gateway.Listeners, meshListener)
+		}
+	}
+
+	return gateway
+}
@@ -0,0 +1,26 @@
+package scanner
+
+// SetBestEffort keeps scanning when a resource type fails to scan, such as when a
+// permission is missing, recording the failure in Network.ScanErrors instead of
+// aborting the scan. VPCs are always required.
+func (s *NetworkScanner) SetBestEffort(bestEffort bool) {
+	s.bestEffort = bestEffort
+}
+
+// scanFailed returns err to abort the scan, or in best-effort mode records it and
+// returns nil to carry on without the resource type
+func (s *NetworkScanner) scanFailed(network *Network, resourceType string, err error) error {
+	if !s.bestEffort {
+		return err
+	}
+	s.log().Debug("resource type failed to scan, continuing", "resource_type", resourceType, "error", err)
+	s.recordScanError(network, resourceType, err)
+	return nil
+}
+
+// recordScanError records a resource type that failed to scan, so comparisons
+// can leave it out rather than report its resources as removed. Optional resource
+// types are always recorded, others only in best-effort mode.
+func (s *NetworkScanner) recordScanError(network *Network, resourceType string, err error) {
+	network.ScanErrors = append(network.ScanErrors, ScanError{ResourceType: resourceType, Error: err.Error()})
+}
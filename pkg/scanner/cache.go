@@ -0,0 +1,177 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScanCache keeps the results of the per-resource API calls a scan fans out to,
+// such as IAM role policies, transit gateway route searches and subnet CIDR
+// reservations, so rescans of a large environment only repeat the calls whose
+// results may have changed. The list calls that find resources always run, so
+// added and removed resources are seen on every scan.
+//
+// Results keyed by a resource ID are reused until they are older than the
+// cache's maximum age, or until Invalidate is told their resource type changed.
+// Managed policy versions cannot change once created, so their documents are
+// reused for as long as the version is the default.
+//
+// A cache is safe for concurrent use by the roles of an IAM scan.
+type ScanCache struct {
+	mu      sync.Mutex
+	path    string
+	maxAge  time.Duration
+	entries map[string]cacheEntry
+	hits    int
+	misses  int
+}
+
+// cacheEntry is one cached API result
+type cacheEntry struct {
+	FetchedAt time.Time       `json:"fetched_at"`
+	UsedAt    time.Time       `json:"used_at"`
+	Immutable bool            `json:"immutable,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// cacheKeyPrefixes maps resource types to the prefixes of the keys their cached
+// results are stored under
+var cacheKeyPrefixes = map[string][]string{
+	"transit_gateway":  {"transit-gateway-routes:"},
+	"iam_role":         {"role-policies:"},
+	"subnet":           {"subnet-cidr-reservations:"},
+	"endpoint_service": {"endpoint-service-principals:"},
+}
+
+// NewScanCache creates an empty cache that saves to path and reuses results up to maxAge old
+func NewScanCache(path string, maxAge time.Duration) *ScanCache {
+	return &ScanCache{
+		path:    path,
+		maxAge:  maxAge,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// LoadScanCache loads the cache saved at path, or returns an empty cache if there is none
+func LoadScanCache(path string, maxAge time.Duration) (*ScanCache, error) {
+	cache := NewScanCache(path, maxAge)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scan cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse scan cache %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// Save writes the cache, dropping results that have not been used for twice the
+// maximum age, such as those of deleted resources
+func (c *ScanCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make(map[string]cacheEntry)
+	for key, entry := range c.entries {
+		if time.Since(entry.UsedAt) < 2*c.maxAge {
+			entries[key] = entry
+		}
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write scan cache: %w", err)
+	}
+
+	c.entries = entries
+	c.hits, c.misses = 0, 0
+	return nil
+}
+
+// Stats returns how many results were reused and fetched since the last save
+func (c *ScanCache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Invalidate drops the cached results of resource types that changed, such as
+// those CloudTrail recorded write events for, so the next scan fetches them
+// again. It returns how many results were dropped. A nil cache drops nothing.
+func (c *ScanCache) Invalidate(resourceTypes []string) int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dropped := 0
+	for _, resourceType := range resourceTypes {
+		for _, prefix := range cacheKeyPrefixes[resourceType] {
+			for key, entry := range c.entries {
+				if !entry.Immutable && strings.HasPrefix(key, prefix) {
+					delete(c.entries, key)
+					dropped++
+				}
+			}
+		}
+	}
+	return dropped
+}
+
+// fresh reports whether an entry can be reused
+func (c *ScanCache) fresh(entry cacheEntry) bool {
+	return entry.Immutable || time.Since(entry.FetchedAt) < c.maxAge
+}
+
+// cachedCall returns the cached result for key, or calls fetch and caches its
+// result. Errors are not cached. A nil cache always calls fetch.
+func cachedCall[T any](c *ScanCache, key string, immutable bool, fetch func() (T, error)) (T, error) {
+	if c == nil {
+		return fetch()
+	}
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && c.fresh(entry) {
+		var value T
+		if err := json.Unmarshal(entry.Data, &value); err == nil {
+			entry.UsedAt = time.Now()
+			c.entries[key] = entry
+			c.hits++
+			c.mu.Unlock()
+			return value, nil
+		}
+	}
+	c.mu.Unlock()
+
+	// The lock is not held while fetching, so other calls are not held up
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+
+	data, err := json.Marshal(value)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.misses++
+	if err == nil {
+		now := time.Now()
+		c.entries[key] = cacheEntry{FetchedAt: now, UsedAt: now, Immutable: immutable, Data: data}
+	}
+	return value, nil
+}
+
+// SetCache reuses per-resource API results from cache, nil to always fetch them
+func (s *NetworkScanner) SetCache(cache *ScanCache) {
+	s.cache = cache
+}
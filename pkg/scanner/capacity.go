@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"net/netip"
+)
+
+// awsReservedAddresses is the number of addresses AWS reserves in every IPv4 subnet
+// (network address, VPC router, DNS, future use and broadcast)
+const awsReservedAddresses = 5
+
+// UsableIPv4AddressCount returns the number of IPv4 addresses in the subnet
+// that can be assigned, excluding the addresses AWS reserves
+func (s Subnet) UsableIPv4AddressCount() int {
+	size := ipv4PrefixSize(s.CidrBlock)
+	if size <= awsReservedAddresses {
+		return 0
+	}
+	return size - awsReservedAddresses
+}
+
+// Utilization returns the percentage of the subnet's assignable IPv4 addresses in use
+// or held by CIDR reservations, or false when the available address count was not
+// scanned
+func (s Subnet) Utilization() (float64, bool) {
+	usable := s.UsableIPv4AddressCount()
+	if s.AvailableIPs == nil || usable == 0 {
+		return 0, false
+	}
+	return 100 * float64(usable-s.FreeIPv4AddressCount()) / float64(usable), true
+}
+
+// FreeIPv4AddressCount returns the number of IPv4 addresses EC2 can still assign
+// automatically. EC2 counts unassigned reserved addresses as available, so they
+// are taken off the available count; which reserved addresses are assigned is not
+// scanned, so all of them are taken to be unassigned, as far as the available count
+// allows.
+func (s Subnet) FreeIPv4AddressCount() int {
+	if s.AvailableIPs == nil {
+		return 0
+	}
+	free := *s.AvailableIPs - s.ReservedIPv4AddressCount()
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// ReservedIPv4AddressCount returns the number of IPv4 addresses held by subnet
+// CIDR reservations, which are not available for automatic assignment
+func (s Subnet) ReservedIPv4AddressCount() int {
+	count := 0
+	for _, reservation := range s.CidrReservations {
+		count += ipv4PrefixSize(reservation.Cidr)
+	}
+	return count
+}
+
+// ipv4PrefixSize returns the number of addresses in an IPv4 CIDR, or 0 if it is not one
+func ipv4PrefixSize(cidr string) int {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil || !prefix.Addr().Is4() {
+		return 0
+	}
+	return 1 << (32 - prefix.Bits())
+}
@@ -0,0 +1,261 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	eksTypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+)
+
+// ecsDescribeServicesBatch is the most services DescribeServices accepts per call
+const ecsDescribeServicesBatch = 10
+
+// SetScanContainers enables or disables discovery of EKS clusters and ECS services
+func (s *NetworkScanner) SetScanContainers(scanContainers bool) {
+	s.scanContainers = scanContainers
+}
+
+// scanEKSClusters scans the EKS clusters whose control plane is in one of the VPCs
+func (s *NetworkScanner) scanEKSClusters(ctx context.Context, vpcIDs []string) ([]EKSCluster, error) {
+	inScope := make(map[string]bool)
+	for _, id := range vpcIDs {
+		inScope[id] = true
+	}
+
+	var clusters []EKSCluster
+
+	names := eks.NewListClustersPaginator(s.apis.EKS, &eks.ListClustersInput{})
+	for names.HasMorePages() {
+		page, err := names.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range page.Clusters {
+			result, err := s.apis.EKS.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: &name})
+			if err != nil {
+				return nil, err
+			}
+
+			cluster := convertEKSCluster(result.Cluster)
+			if !inScope[cluster.VpcID] {
+				continue
+			}
+
+			interfaces, err := s.getEKSControlPlaneInterfaces(ctx, cluster.Name, cluster.VpcID)
+			if err != nil {
+				return nil, err
+			}
+			cluster.NetworkInterfaces = interfaces
+
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	return clusters, nil
+}
+
+// getEKSControlPlaneInterfaces lists the ENIs EKS places in the cluster subnets for the control plane
+func (s *NetworkScanner) getEKSControlPlaneInterfaces(ctx context.Context, clusterName, vpcID string) ([]string, error) {
+	// EKS describes its control plane interfaces as "Amazon EKS <cluster name>"
+	input := &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{
+			{
+				Name:   &[]string{"vpc-id"}[0],
+				Values: []string{vpcID},
+			},
+			{
+				Name:   &[]string{"description"}[0],
+				Values: []string{"Amazon EKS " + clusterName},
+			},
+		},
+	}
+
+	interfaces := []string{}
+	pages := ec2.NewDescribeNetworkInterfacesPaginator(s.apis.EC2, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, eni := range page.NetworkInterfaces {
+			if eni.NetworkInterfaceId != nil {
+				interfaces = append(interfaces, *eni.NetworkInterfaceId)
+			}
+		}
+	}
+
+	return interfaces, nil
+}
+
+// convertEKSCluster converts a described EKS cluster
+func convertEKSCluster(data *eksTypes.Cluster) EKSCluster {
+	cluster := EKSCluster{}
+	if data == nil {
+		return cluster
+	}
+
+	cluster.Status = string(data.Status)
+	cluster.Tags = data.Tags
+	if data.Name != nil {
+		cluster.Name = *data.Name
+	}
+	if data.Arn != nil {
+		cluster.Arn = *data.Arn
+	}
+	if data.Version != nil {
+		cluster.Version = *data.Version
+	}
+
+	if config := data.ResourcesVpcConfig; config != nil {
+		if config.VpcId != nil {
+			cluster.VpcID = *config.VpcId
+		}
+		if config.ClusterSecurityGroupId != nil {
+			cluster.ClusterSecurityGroupID = *config.ClusterSecurityGroupId
+		}
+		cluster.SubnetIDs = config.SubnetIds
+		cluster.SecurityGroupIDs = config.SecurityGroupIds
+		cluster.EndpointPublicAccess = config.EndpointPublicAccess
+		cluster.EndpointPrivateAccess = config.EndpointPrivateAccess
+		cluster.PublicAccessCidrs = config.PublicAccessCidrs
+	}
+
+	return cluster
+}
+
+// scanECSServices scans the ECS services whose awsvpc tasks run in the scanned subnets
+func (s *NetworkScanner) scanECSServices(ctx context.Context, subnets []Subnet) ([]ECSService, error) {
+	subnetVPCs := make(map[string]string)
+	for _, subnet := range subnets {
+		subnetVPCs[subnet.ID] = subnet.VpcID
+	}
+
+	var services []ECSService
+
+	clusters := ecs.NewListClustersPaginator(s.apis.ECS, &ecs.ListClustersInput{})
+	for clusters.HasMorePages() {
+		page, err := clusters.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, clusterArn := range page.ClusterArns {
+			clusterServices, err := s.getECSServices(ctx, clusterArn, subnetVPCs)
+			if err != nil {
+				return nil, err
+			}
+			services = append(services, clusterServices...)
+		}
+	}
+
+	return services, nil
+}
+
+// getECSServices lists and describes the awsvpc services of one ECS cluster
+func (s *NetworkScanner) getECSServices(ctx context.Context, clusterArn string, subnetVPCs map[string]string) ([]ECSService, error) {
+	var arns []string
+	pages := ecs.NewListServicesPaginator(s.apis.ECS, &ecs.ListServicesInput{Cluster: &clusterArn})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		arns = append(arns, page.ServiceArns...)
+	}
+
+	var services []ECSService
+	for start := 0; start < len(arns); start += ecsDescribeServicesBatch {
+		end := min(start+ecsDescribeServicesBatch, len(arns))
+		result, err := s.apis.ECS.DescribeServices(ctx, &ecs.DescribeServicesInput{
+			Cluster:  &clusterArn,
+			Services: arns[start:end],
+			Include:  []ecsTypes.ServiceField{ecsTypes.ServiceFieldTags},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, data := range result.Services {
+			// Only awsvpc tasks get their own network interfaces in the VPC
+			if data.NetworkConfiguration == nil || data.NetworkConfiguration.AwsvpcConfiguration == nil {
+				continue
+			}
+
+			service := convertECSService(data, clusterArn)
+			for _, subnetID := range service.SubnetIDs {
+				if vpcID, ok := subnetVPCs[subnetID]; ok {
+					service.VpcID = vpcID
+					break
+				}
+			}
+			if service.VpcID == "" {
+				continue
+			}
+
+			services = append(services, service)
+		}
+	}
+
+	return services, nil
+}
+
+// convertECSService converts a described ECS service with awsvpc networking
+func convertECSService(data ecsTypes.Service, clusterArn string) ECSService {
+	service := ECSService{
+		LaunchType:   string(data.LaunchType),
+		DesiredCount: data.DesiredCount,
+		RunningCount: data.RunningCount,
+		Tags:         make(map[string]string),
+	}
+
+	// Cluster ARNs end in cluster/<name>
+	service.ClusterName = clusterArn[strings.LastIndex(clusterArn, "/")+1:]
+
+	if data.ServiceArn != nil {
+		service.Arn = *data.ServiceArn
+	}
+	if data.ServiceName != nil {
+		service.Name = *data.ServiceName
+	}
+	if data.Status != nil {
+		service.Status = *data.Status
+	}
+	if data.TaskDefinition != nil {
+		service.taskDefinition = *data.TaskDefinition
+	}
+
+	awsvpc := data.NetworkConfiguration.AwsvpcConfiguration
+	service.SubnetIDs = awsvpc.Subnets
+	service.SecurityGroupIDs = awsvpc.SecurityGroups
+	service.AssignPublicIP = awsvpc.AssignPublicIp == ecsTypes.AssignPublicIpEnabled
+
+	for _, tag := range data.Tags {
+		if tag.Key != nil && tag.Value != nil {
+			service.Tags[*tag.Key] = *tag.Value
+		}
+	}
+
+	return service
+}
+
+// describeTaskDefinitions describes the task definitions ECS services run, by ARN
+func (s *NetworkScanner) describeTaskDefinitions(ctx context.Context, services []ECSService) (map[string]*ecsTypes.TaskDefinition, error) {
+	definitions := make(map[string]*ecsTypes.TaskDefinition)
+	for _, service := range services {
+		if service.taskDefinition == "" || definitions[service.taskDefinition] != nil {
+			continue
+		}
+		result, err := s.apis.ECS.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{TaskDefinition: &service.taskDefinition})
+		if err != nil {
+			return nil, err
+		}
+		definitions[service.taskDefinition] = result.TaskDefinition
+	}
+	return definitions, nil
+}
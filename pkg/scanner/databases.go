@@ -0,0 +1,325 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/elasticache"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	redshiftTypes "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+)
+
+// SetScanDatabases enables or disables discovery of RDS, ElastiCache and Redshift placement
+func (s *NetworkScanner) SetScanDatabases(scanDatabases bool) {
+	s.scanDatabases = scanDatabases
+}
+
+// scanDatabasePlacement scans the database subnet groups and databases in the VPCs.
+// Each service is optional, a failure is reported and the other services are kept.
+func (s *NetworkScanner) scanDatabasePlacement(ctx context.Context, network *Network, vpcIDs []string) {
+	inScope := make(map[string]bool)
+	for _, id := range vpcIDs {
+		inScope[id] = true
+	}
+
+	services := []struct {
+		name string
+		scan func(ctx context.Context) ([]DatabaseSubnetGroup, []Database, error)
+	}{
+		{"RDS", s.scanRDS},
+		{"ElastiCache", s.scanElastiCache},
+		{"Redshift", s.scanRedshift},
+	}
+	for _, service := range services {
+		groups, databases, err := service.scan(ctx)
+		if err != nil {
+			s.log().Warn("failed to scan databases", "service", service.name, "error", err)
+			s.recordScanError(network, "database", fmt.Errorf("failed to scan %s: %w", service.name, err))
+			continue
+		}
+
+		for _, group := range groups {
+			if inScope[group.VpcID] {
+				network.DatabaseSubnetGroups = append(network.DatabaseSubnetGroups, group)
+			}
+		}
+		for _, database := range databases {
+			if inScope[database.VpcID] {
+				network.Databases = append(network.Databases, database)
+			}
+		}
+	}
+}
+
+// placeDatabase fills in a database's VPC and subnets from its subnet group
+func placeDatabase(database *Database, groups map[string]DatabaseSubnetGroup) {
+	if group, ok := groups[database.SubnetGroup]; ok {
+		if database.VpcID == "" {
+			database.VpcID = group.VpcID
+		}
+		database.SubnetIDs = group.SubnetIDs
+	}
+}
+
+// scanRDS scans RDS subnet groups, DB clusters and the DB instances outside a cluster
+func (s *NetworkScanner) scanRDS(ctx context.Context) ([]DatabaseSubnetGroup, []Database, error) {
+	var groups []DatabaseSubnetGroup
+	groupsByName := make(map[string]DatabaseSubnetGroup)
+	groupPages := rds.NewDescribeDBSubnetGroupsPaginator(s.apis.RDS, &rds.DescribeDBSubnetGroupsInput{})
+	for groupPages.HasMorePages() {
+		page, err := groupPages.NextPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, data := range page.DBSubnetGroups {
+			group := DatabaseSubnetGroup{
+				Name:        awssdk.ToString(data.DBSubnetGroupName),
+				Service:     "rds",
+				Description: awssdk.ToString(data.DBSubnetGroupDescription),
+				VpcID:       awssdk.ToString(data.VpcId),
+				SubnetIDs:   []string{},
+			}
+			for _, subnet := range data.Subnets {
+				if subnet.SubnetIdentifier != nil {
+					group.SubnetIDs = append(group.SubnetIDs, *subnet.SubnetIdentifier)
+				}
+			}
+			groups = append(groups, group)
+			groupsByName[group.Name] = group
+		}
+	}
+
+	var databases []Database
+
+	// Aurora and Multi-AZ DB clusters are reached through the cluster endpoint
+	clusterPages := rds.NewDescribeDBClustersPaginator(s.apis.RDS, &rds.DescribeDBClustersInput{})
+	for clusterPages.HasMorePages() {
+		page, err := clusterPages.NextPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, data := range page.DBClusters {
+			database := Database{
+				ID:                 awssdk.ToString(data.DBClusterIdentifier),
+				Service:            "rds",
+				Engine:             awssdk.ToString(data.Engine),
+				Status:             awssdk.ToString(data.Status),
+				Endpoint:           awssdk.ToString(data.Endpoint),
+				Port:               awssdk.ToInt32(data.Port),
+				SubnetGroup:        awssdk.ToString(data.DBSubnetGroup),
+				SecurityGroupIDs:   rdsSecurityGroups(data.VpcSecurityGroups),
+				PubliclyAccessible: awssdk.ToBool(data.PubliclyAccessible),
+				Tags:               rdsTags(data.TagList),
+			}
+			placeDatabase(&database, groupsByName)
+			databases = append(databases, database)
+		}
+	}
+
+	instancePages := rds.NewDescribeDBInstancesPaginator(s.apis.RDS, &rds.DescribeDBInstancesInput{})
+	for instancePages.HasMorePages() {
+		page, err := instancePages.NextPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, data := range page.DBInstances {
+			// Cluster members are shown through their cluster
+			if data.DBClusterIdentifier != nil {
+				continue
+			}
+
+			database := Database{
+				ID:                 awssdk.ToString(data.DBInstanceIdentifier),
+				Service:            "rds",
+				Engine:             awssdk.ToString(data.Engine),
+				Status:             awssdk.ToString(data.DBInstanceStatus),
+				SecurityGroupIDs:   rdsSecurityGroups(data.VpcSecurityGroups),
+				PubliclyAccessible: awssdk.ToBool(data.PubliclyAccessible),
+				Tags:               rdsTags(data.TagList),
+			}
+			if data.Endpoint != nil {
+				database.Endpoint = awssdk.ToString(data.Endpoint.Address)
+				database.Port = awssdk.ToInt32(data.Endpoint.Port)
+			}
+			if data.DBSubnetGroup != nil {
+				database.SubnetGroup = awssdk.ToString(data.DBSubnetGroup.DBSubnetGroupName)
+				database.VpcID = awssdk.ToString(data.DBSubnetGroup.VpcId)
+			}
+			placeDatabase(&database, groupsByName)
+			databases = append(databases, database)
+		}
+	}
+
+	return groups, databases, nil
+}
+
+// rdsSecurityGroups returns the IDs of an RDS resource's VPC security groups
+func rdsSecurityGroups(memberships []rdsTypes.VpcSecurityGroupMembership) []string {
+	groups := []string{}
+	for _, membership := range memberships {
+		if membership.VpcSecurityGroupId != nil {
+			groups = append(groups, *membership.VpcSecurityGroupId)
+		}
+	}
+	return groups
+}
+
+// rdsTags converts RDS tags
+func rdsTags(tags []rdsTypes.Tag) map[string]string {
+	result := make(map[string]string)
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil {
+			result[*tag.Key] = *tag.Value
+		}
+	}
+	return result
+}
+
+// scanElastiCache scans ElastiCache subnet groups and cache clusters
+func (s *NetworkScanner) scanElastiCache(ctx context.Context) ([]DatabaseSubnetGroup, []Database, error) {
+	var groups []DatabaseSubnetGroup
+	groupsByName := make(map[string]DatabaseSubnetGroup)
+	groupPages := elasticache.NewDescribeCacheSubnetGroupsPaginator(s.apis.ElastiCache, &elasticache.DescribeCacheSubnetGroupsInput{})
+	for groupPages.HasMorePages() {
+		page, err := groupPages.NextPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, data := range page.CacheSubnetGroups {
+			group := DatabaseSubnetGroup{
+				Name:        awssdk.ToString(data.CacheSubnetGroupName),
+				Service:     "elasticache",
+				Description: awssdk.ToString(data.CacheSubnetGroupDescription),
+				VpcID:       awssdk.ToString(data.VpcId),
+				SubnetIDs:   []string{},
+			}
+			for _, subnet := range data.Subnets {
+				if subnet.SubnetIdentifier != nil {
+					group.SubnetIDs = append(group.SubnetIDs, *subnet.SubnetIdentifier)
+				}
+			}
+			groups = append(groups, group)
+			groupsByName[group.Name] = group
+		}
+	}
+
+	var databases []Database
+	clusterPages := elasticache.NewDescribeCacheClustersPaginator(s.apis.ElastiCache, &elasticache.DescribeCacheClustersInput{
+		ShowCacheNodeInfo: awssdk.Bool(true),
+	})
+	for clusterPages.HasMorePages() {
+		page, err := clusterPages.NextPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, data := range page.CacheClusters {
+			database := Database{
+				ID:               awssdk.ToString(data.CacheClusterId),
+				Service:          "elasticache",
+				Engine:           awssdk.ToString(data.Engine),
+				Status:           awssdk.ToString(data.CacheClusterStatus),
+				SubnetGroup:      awssdk.ToString(data.CacheSubnetGroupName),
+				SecurityGroupIDs: []string{},
+				Tags:             make(map[string]string),
+			}
+
+			// Memcached clusters have a configuration endpoint, Redis nodes their own
+			endpoint := data.ConfigurationEndpoint
+			if endpoint == nil && len(data.CacheNodes) > 0 {
+				endpoint = data.CacheNodes[0].Endpoint
+			}
+			if endpoint != nil {
+				database.Endpoint = awssdk.ToString(endpoint.Address)
+				database.Port = awssdk.ToInt32(endpoint.Port)
+			}
+
+			for _, membership := range data.SecurityGroups {
+				if membership.SecurityGroupId != nil {
+					database.SecurityGroupIDs = append(database.SecurityGroupIDs, *membership.SecurityGroupId)
+				}
+			}
+			placeDatabase(&database, groupsByName)
+			databases = append(databases, database)
+		}
+	}
+
+	return groups, databases, nil
+}
+
+// scanRedshift scans Redshift subnet groups and clusters
+func (s *NetworkScanner) scanRedshift(ctx context.Context) ([]DatabaseSubnetGroup, []Database, error) {
+	var groups []DatabaseSubnetGroup
+	groupsByName := make(map[string]DatabaseSubnetGroup)
+	groupPages := redshift.NewDescribeClusterSubnetGroupsPaginator(s.apis.Redshift, &redshift.DescribeClusterSubnetGroupsInput{})
+	for groupPages.HasMorePages() {
+		page, err := groupPages.NextPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, data := range page.ClusterSubnetGroups {
+			group := DatabaseSubnetGroup{
+				Name:        awssdk.ToString(data.ClusterSubnetGroupName),
+				Service:     "redshift",
+				Description: awssdk.ToString(data.Description),
+				VpcID:       awssdk.ToString(data.VpcId),
+				SubnetIDs:   []string{},
+			}
+			for _, subnet := range data.Subnets {
+				if subnet.SubnetIdentifier != nil {
+					group.SubnetIDs = append(group.SubnetIDs, *subnet.SubnetIdentifier)
+				}
+			}
+			groups = append(groups, group)
+			groupsByName[group.Name] = group
+		}
+	}
+
+	var databases []Database
+	clusterPages := redshift.NewDescribeClustersPaginator(s.apis.Redshift, &redshift.DescribeClustersInput{})
+	for clusterPages.HasMorePages() {
+		page, err := clusterPages.NextPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, data := range page.Clusters {
+			database := Database{
+				ID:                 awssdk.ToString(data.ClusterIdentifier),
+				Service:            "redshift",
+				Engine:             "redshift",
+				Status:             awssdk.ToString(data.ClusterStatus),
+				VpcID:              awssdk.ToString(data.VpcId),
+				SubnetGroup:        awssdk.ToString(data.ClusterSubnetGroupName),
+				SecurityGroupIDs:   []string{},
+				PubliclyAccessible: awssdk.ToBool(data.PubliclyAccessible),
+				Tags:               redshiftTags(data.Tags),
+			}
+			if data.Endpoint != nil {
+				database.Endpoint = awssdk.ToString(data.Endpoint.Address)
+				database.Port = awssdk.ToInt32(data.Endpoint.Port)
+			}
+			for _, membership := range data.VpcSecurityGroups {
+				if membership.VpcSecurityGroupId != nil {
+					database.SecurityGroupIDs = append(database.SecurityGroupIDs, *membership.VpcSecurityGroupId)
+				}
+			}
+			placeDatabase(&database, groupsByName)
+			databases = append(databases, database)
+		}
+	}
+
+	return groups, databases, nil
+}
+
+// redshiftTags converts Redshift tags
+func redshiftTags(tags []redshiftTypes.Tag) map[string]string {
+	result := make(map[string]string)
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil {
+			result[*tag.Key] = *tag.Value
+		}
+	}
+	return result
+}
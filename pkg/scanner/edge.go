@@ -0,0 +1,369 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	cfTypes "github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
+)
+
+// SetScanEdge enables or disables discovery of Global Accelerator and CloudFront ingress
+func (s *NetworkScanner) SetScanEdge(scanEdge bool) {
+	s.scanEdge = scanEdge
+}
+
+// edgeTargets indexes the load balancers and Elastic IPs an edge service can point at
+type edgeTargets struct {
+	byID  map[string]EdgeTarget // Load balancer ARN or allocation ID
+	byDNS map[string]EdgeTarget // Load balancer DNS name or public IP
+}
+
+// lookupDomain finds the target behind a CloudFront origin domain name. Origins can
+// name a load balancer, possibly with a "dualstack." prefix, or the public DNS name
+// of an Elastic IP such as ec2-203-0-113-10.compute-1.amazonaws.com.
+func (t edgeTargets) lookupDomain(domain string) (EdgeTarget, bool) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if target, ok := t.byDNS[strings.TrimPrefix(domain, "dualstack.")]; ok {
+		return target, true
+	}
+	if strings.HasPrefix(domain, "ec2-") {
+		label := strings.SplitN(strings.TrimPrefix(domain, "ec2-"), ".", 2)[0]
+		if ip := net.ParseIP(strings.ReplaceAll(label, "-", ".")); ip != nil {
+			target, ok := t.byDNS[ip.String()]
+			return target, ok
+		}
+	}
+	return EdgeTarget{}, false
+}
+
+// scanEdgeIngress scans the accelerators and distributions that send traffic to
+// load balancers and Elastic IPs in the VPCs. Each service is optional, a failure
+// is reported and the other service is kept.
+func (s *NetworkScanner) scanEdgeIngress(ctx context.Context, network *Network, vpcIDs []string) {
+	targets, err := s.scanEdgeTargets(ctx, network.Subnets, vpcIDs)
+	if err != nil {
+		s.log().Warn("failed to scan load balancers and Elastic IPs", "error", err)
+		s.recordScanError(network, "edge_ingress", err)
+		return
+	}
+
+	accelerators, err := s.scanAccelerators(ctx, targets)
+	if err != nil {
+		s.log().Warn("failed to scan Global Accelerator", "error", err)
+		s.recordScanError(network, "edge_ingress", err)
+	}
+	network.EdgeIngresses = append(network.EdgeIngresses, accelerators...)
+
+	distributions, err := s.scanDistributions(ctx, targets)
+	if err != nil {
+		s.log().Warn("failed to scan CloudFront", "error", err)
+		s.recordScanError(network, "edge_ingress", err)
+	}
+	network.EdgeIngresses = append(network.EdgeIngresses, distributions...)
+}
+
+// scanEdgeTargets indexes the load balancers and Elastic IPs in the VPCs
+func (s *NetworkScanner) scanEdgeTargets(ctx context.Context, subnets []Subnet, vpcIDs []string) (edgeTargets, error) {
+	targets := edgeTargets{byID: make(map[string]EdgeTarget), byDNS: make(map[string]EdgeTarget)}
+
+	inScope := make(map[string]bool)
+	for _, id := range vpcIDs {
+		inScope[id] = true
+	}
+	subnetVpcs := make(map[string]string)
+	for _, subnet := range subnets {
+		subnetVpcs[subnet.ID] = subnet.VpcID
+	}
+
+	pages := elasticloadbalancingv2.NewDescribeLoadBalancersPaginator(s.apis.ELBv2, &elasticloadbalancingv2.DescribeLoadBalancersInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return targets, fmt.Errorf("failed to describe load balancers: %w", err)
+		}
+		for _, lb := range page.LoadBalancers {
+			target := EdgeTarget{
+				ID:      awssdk.ToString(lb.LoadBalancerArn),
+				Name:    awssdk.ToString(lb.LoadBalancerName),
+				Type:    string(lb.Type),
+				Address: strings.ToLower(awssdk.ToString(lb.DNSName)),
+				VpcID:   awssdk.ToString(lb.VpcId),
+			}
+			if !inScope[target.VpcID] {
+				continue
+			}
+			for _, zone := range lb.AvailabilityZones {
+				if zone.SubnetId != nil {
+					target.SubnetIDs = append(target.SubnetIDs, *zone.SubnetId)
+				}
+			}
+			targets.byID[target.ID] = target
+			targets.byDNS[target.Address] = target
+		}
+	}
+
+	result, err := s.apis.EC2.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return targets, fmt.Errorf("failed to describe Elastic IPs: %w", err)
+	}
+
+	// Addresses only name their subnet when the API reports it, otherwise the
+	// subnet is looked up through the network interface they are associated with
+	interfaceSubnets, err := s.getAddressInterfaceSubnets(ctx, result.Addresses)
+	if err != nil {
+		return targets, fmt.Errorf("failed to describe Elastic IP network interfaces: %w", err)
+	}
+
+	for _, address := range result.Addresses {
+		if address.AllocationId == nil || address.PublicIp == nil {
+			continue
+		}
+		subnetID := awssdk.ToString(address.SubnetId)
+		if subnetID == "" {
+			subnetID = interfaceSubnets[awssdk.ToString(address.NetworkInterfaceId)]
+		}
+		if !inScope[subnetVpcs[subnetID]] {
+			continue
+		}
+
+		target := EdgeTarget{
+			ID:        *address.AllocationId,
+			Name:      convertTags(address.Tags)["Name"],
+			Type:      "eip",
+			Address:   *address.PublicIp,
+			VpcID:     subnetVpcs[subnetID],
+			SubnetIDs: []string{subnetID},
+		}
+		targets.byID[target.ID] = target
+		targets.byDNS[target.Address] = target
+	}
+
+	return targets, nil
+}
+
+// getAddressInterfaceSubnets maps the network interfaces of addresses without a subnet to their subnets
+func (s *NetworkScanner) getAddressInterfaceSubnets(ctx context.Context, addresses []types.Address) (map[string]string, error) {
+	subnets := make(map[string]string)
+
+	var interfaceIDs []string
+	for _, address := range addresses {
+		if address.SubnetId == nil && address.NetworkInterfaceId != nil {
+			interfaceIDs = append(interfaceIDs, *address.NetworkInterfaceId)
+		}
+	}
+	if len(interfaceIDs) == 0 {
+		return subnets, nil
+	}
+
+	input := &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{
+			{
+				Name:   &[]string{"network-interface-id"}[0],
+				Values: interfaceIDs,
+			},
+		},
+	}
+	pages := ec2.NewDescribeNetworkInterfacesPaginator(s.apis.EC2, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, eni := range page.NetworkInterfaces {
+			subnets[awssdk.ToString(eni.NetworkInterfaceId)] = awssdk.ToString(eni.SubnetId)
+		}
+	}
+
+	return subnets, nil
+}
+
+// scanAccelerators scans the accelerators with endpoints in this region that are scanned targets
+func (s *NetworkScanner) scanAccelerators(ctx context.Context, targets edgeTargets) ([]EdgeIngress, error) {
+	var ingresses []EdgeIngress
+
+	accelerators := globalaccelerator.NewListAcceleratorsPaginator(s.apis.GlobalAccelerator, &globalaccelerator.ListAcceleratorsInput{})
+	for accelerators.HasMorePages() {
+		page, err := accelerators.NextPage(ctx)
+		if err != nil {
+			return ingresses, err
+		}
+
+		for _, accelerator := range page.Accelerators {
+			ingress := EdgeIngress{
+				ID:      awssdk.ToString(accelerator.AcceleratorArn),
+				Name:    awssdk.ToString(accelerator.Name),
+				Service: "globalaccelerator",
+				DNSName: awssdk.ToString(accelerator.DnsName),
+				Status:  string(accelerator.Status),
+				Enabled: awssdk.ToBool(accelerator.Enabled),
+			}
+
+			endpointIDs, err := s.getAcceleratorEndpoints(ctx, ingress.ID)
+			if err != nil {
+				return ingresses, err
+			}
+			ingress.Targets = resolveEdgeTargets(endpointIDs, targets.byID)
+
+			if len(ingress.Targets) > 0 {
+				ingresses = append(ingresses, ingress)
+			}
+		}
+	}
+
+	return ingresses, nil
+}
+
+// getAcceleratorEndpoints lists the endpoint IDs of an accelerator's endpoint groups in this region
+func (s *NetworkScanner) getAcceleratorEndpoints(ctx context.Context, acceleratorArn string) ([]string, error) {
+	var endpointIDs []string
+
+	listeners := globalaccelerator.NewListListenersPaginator(s.apis.GlobalAccelerator, &globalaccelerator.ListListenersInput{
+		AcceleratorArn: &acceleratorArn,
+	})
+	for listeners.HasMorePages() {
+		page, err := listeners.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, listener := range page.Listeners {
+			groups := globalaccelerator.NewListEndpointGroupsPaginator(s.apis.GlobalAccelerator, &globalaccelerator.ListEndpointGroupsInput{
+				ListenerArn: listener.ListenerArn,
+			})
+			for groups.HasMorePages() {
+				groupPage, err := groups.NextPage(ctx)
+				if err != nil {
+					return nil, err
+				}
+				for _, group := range groupPage.EndpointGroups {
+					if awssdk.ToString(group.EndpointGroupRegion) != s.region {
+						continue
+					}
+					for _, endpoint := range group.EndpointDescriptions {
+						if endpoint.EndpointId != nil {
+							endpointIDs = append(endpointIDs, *endpoint.EndpointId)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return endpointIDs, nil
+}
+
+// scanDistributions scans the CloudFront distributions with an origin that is a scanned target
+func (s *NetworkScanner) scanDistributions(ctx context.Context, targets edgeTargets) ([]EdgeIngress, error) {
+	vpcOrigins, err := s.getVpcOrigins(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ingresses []EdgeIngress
+
+	pages := cloudfront.NewListDistributionsPaginator(s.apis.CloudFront, &cloudfront.ListDistributionsInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return ingresses, err
+		}
+		if page.DistributionList == nil {
+			continue
+		}
+
+		for _, distribution := range page.DistributionList.Items {
+			ingress := convertDistribution(distribution)
+
+			seen := make(map[string]bool)
+			for _, origin := range originsOf(distribution) {
+				var target EdgeTarget
+				var ok bool
+				if origin.VpcOriginConfig != nil {
+					target, ok = targets.byID[vpcOrigins[awssdk.ToString(origin.VpcOriginConfig.VpcOriginId)]]
+				} else {
+					target, ok = targets.lookupDomain(awssdk.ToString(origin.DomainName))
+				}
+				if ok && !seen[target.ID] {
+					seen[target.ID] = true
+					ingress.Targets = append(ingress.Targets, target)
+				}
+			}
+
+			if len(ingress.Targets) > 0 {
+				ingresses = append(ingresses, ingress)
+			}
+		}
+	}
+
+	return ingresses, nil
+}
+
+// getVpcOrigins maps CloudFront VPC origin IDs to the ARN of the resource they reach
+func (s *NetworkScanner) getVpcOrigins(ctx context.Context) (map[string]string, error) {
+	origins := make(map[string]string)
+
+	input := &cloudfront.ListVpcOriginsInput{}
+	for {
+		result, err := s.apis.CloudFront.ListVpcOrigins(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list VPC origins: %w", err)
+		}
+		if result.VpcOriginList == nil {
+			break
+		}
+		for _, origin := range result.VpcOriginList.Items {
+			origins[awssdk.ToString(origin.Id)] = awssdk.ToString(origin.OriginEndpointArn)
+		}
+		if !awssdk.ToBool(result.VpcOriginList.IsTruncated) || result.VpcOriginList.NextMarker == nil {
+			break
+		}
+		input.Marker = result.VpcOriginList.NextMarker
+	}
+
+	return origins, nil
+}
+
+// convertDistribution converts a CloudFront distribution summary without its targets
+func convertDistribution(distribution cfTypes.DistributionSummary) EdgeIngress {
+	ingress := EdgeIngress{
+		ID:      awssdk.ToString(distribution.Id),
+		Name:    awssdk.ToString(distribution.Comment),
+		Service: "cloudfront",
+		DNSName: awssdk.ToString(distribution.DomainName),
+		Status:  awssdk.ToString(distribution.Status),
+		Enabled: awssdk.ToBool(distribution.Enabled),
+	}
+	if distribution.Aliases != nil {
+		ingress.Aliases = distribution.Aliases.Items
+	}
+	return ingress
+}
+
+// originsOf returns the origins of a distribution
+func originsOf(distribution cfTypes.DistributionSummary) []cfTypes.Origin {
+	if distribution.Origins == nil {
+		return nil
+	}
+	return distribution.Origins.Items
+}
+
+// resolveEdgeTargets returns the scanned targets among endpoint IDs, once each
+func resolveEdgeTargets(ids []string, byID map[string]EdgeTarget) []EdgeTarget {
+	var resolved []EdgeTarget
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if target, ok := byID[id]; ok && !seen[id] {
+			seen[id] = true
+			resolved = append(resolved, target)
+		}
+	}
+	return resolved
+}
@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// scanEndpointServices scans the VPC endpoint services (PrivateLink providers) owned by the account
+func (s *NetworkScanner) scanEndpointServices(ctx context.Context) ([]EndpointService, error) {
+	var services []EndpointService
+
+	configurations := ec2.NewDescribeVpcEndpointServiceConfigurationsPaginator(s.apis.EC2, &ec2.DescribeVpcEndpointServiceConfigurationsInput{})
+	for configurations.HasMorePages() {
+		page, err := configurations.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, config := range page.ServiceConfigurations {
+			service := convertEndpointService(config)
+
+			principals, err := cachedCall(s.cache, "endpoint-service-principals:"+service.ID, false, func() ([]string, error) {
+				return s.getEndpointServicePrincipals(ctx, service.ID)
+			})
+			if err != nil {
+				return nil, err
+			}
+			service.AllowedPrincipals = principals
+
+			services = append(services, service)
+		}
+	}
+
+	return services, nil
+}
+
+// getEndpointServicePrincipals lists the principals allowed to connect to an endpoint service
+func (s *NetworkScanner) getEndpointServicePrincipals(ctx context.Context, serviceID string) ([]string, error) {
+	principals := []string{}
+
+	permissions := ec2.NewDescribeVpcEndpointServicePermissionsPaginator(s.apis.EC2, &ec2.DescribeVpcEndpointServicePermissionsInput{
+		ServiceId: &serviceID,
+	})
+	for permissions.HasMorePages() {
+		page, err := permissions.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, allowed := range page.AllowedPrincipals {
+			if allowed.Principal != nil {
+				principals = append(principals, *allowed.Principal)
+			}
+		}
+	}
+
+	return principals, nil
+}
+
+// convertEndpointService converts an endpoint service configuration
+func convertEndpointService(config types.ServiceConfiguration) EndpointService {
+	service := EndpointService{
+		State:                   string(config.ServiceState),
+		AvailabilityZones:       config.AvailabilityZones,
+		NetworkLoadBalancerArns: config.NetworkLoadBalancerArns,
+		GatewayLoadBalancerArns: config.GatewayLoadBalancerArns,
+		Tags:                    convertTags(config.Tags),
+	}
+
+	if config.ServiceId != nil {
+		service.ID = *config.ServiceId
+	}
+	if config.ServiceName != nil {
+		service.ServiceName = *config.ServiceName
+	}
+	if len(config.ServiceType) > 0 {
+		service.ServiceType = string(config.ServiceType[0].ServiceType)
+	}
+	if config.AcceptanceRequired != nil {
+		service.AcceptanceRequired = *config.AcceptanceRequired
+	}
+	if config.PrivateDnsName != nil {
+		service.PrivateDNSName = *config.PrivateDnsName
+	}
+
+	// Get name from tags
+	if name, ok := service.Tags["Name"]; ok {
+		service.Name = name
+	}
+
+	return service
+}
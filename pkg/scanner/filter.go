@@ -0,0 +1,47 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// ParseTagFilters parses key=value tag selectors. Values given for the same key
+// match any of them, while different keys must all match.
+func ParseTagFilters(selectors []string) (map[string][]string, error) {
+	filters := make(map[string][]string)
+	for _, selector := range selectors {
+		key, value, ok := strings.Cut(selector, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid tag selector %q, expected key=value", selector)
+		}
+		filters[key] = append(filters[key], strings.TrimSpace(value))
+	}
+	return filters, nil
+}
+
+// SetTagFilters limits scanning to VPCs matching the tag selectors
+func (s *NetworkScanner) SetTagFilters(tagFilters map[string][]string) {
+	s.tagFilters = tagFilters
+}
+
+// vpcTagFilters converts the tag selectors to DescribeVpcs filters
+func (s *NetworkScanner) vpcTagFilters() []types.Filter {
+	keys := make([]string, 0, len(s.tagFilters))
+	for key := range s.tagFilters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	filters := make([]types.Filter, 0, len(keys))
+	for _, key := range keys {
+		filters = append(filters, types.Filter{
+			Name:   &[]string{"tag:" + key}[0],
+			Values: s.tagFilters[key],
+		})
+	}
+	return filters
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// interfaceBatchSize is how many network interface IDs are described per call
+const interfaceBatchSize = 200
+
+// flowLogResourceType returns the type of resource a flow log captures from its ID,
+// or "" for resources other than VPCs, subnets and network interfaces
+func flowLogResourceType(resourceID string) string {
+	switch {
+	case strings.HasPrefix(resourceID, "vpc-"):
+		return "VPC"
+	case strings.HasPrefix(resourceID, "subnet-"):
+		return "Subnet"
+	case strings.HasPrefix(resourceID, "eni-"):
+		return "NetworkInterface"
+	}
+	return ""
+}
+
+// scanFlowLogs scans the flow logs of the VPCs, their subnets and their network interfaces
+func (s *NetworkScanner) scanFlowLogs(ctx context.Context, vpcIDs []string, subnets []Subnet) ([]FlowLog, error) {
+	flowLogs := []FlowLog{}
+	if len(vpcIDs) == 0 {
+		return flowLogs, nil
+	}
+
+	relevant := make(map[string]bool)
+	for _, id := range vpcIDs {
+		relevant[id] = true
+	}
+	subnetVPCs := make(map[string]string)
+	for _, subnet := range subnets {
+		subnetVPCs[subnet.ID] = subnet.VpcID
+	}
+
+	var all []FlowLog
+	var interfaceIDs []string
+	pages := ec2.NewDescribeFlowLogsPaginator(s.apis.EC2, &ec2.DescribeFlowLogsInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, flowLog := range page.FlowLogs {
+			if flowLog.FlowLogId == nil || flowLog.ResourceId == nil {
+				continue
+			}
+			f := convertFlowLog(flowLog)
+			switch f.ResourceType {
+			case "VPC":
+				f.VpcID = f.ResourceID
+			case "Subnet":
+				f.VpcID = subnetVPCs[f.ResourceID]
+			case "NetworkInterface":
+				interfaceIDs = append(interfaceIDs, f.ResourceID)
+			default:
+				continue
+			}
+			all = append(all, f)
+		}
+	}
+
+	interfaceVPCs, err := s.interfaceVPCs(ctx, interfaceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range all {
+		if f.ResourceType == "NetworkInterface" {
+			f.VpcID = interfaceVPCs[f.ResourceID]
+		}
+		if relevant[f.VpcID] {
+			flowLogs = append(flowLogs, f)
+		}
+	}
+	return flowLogs, nil
+}
+
+// convertFlowLog converts a flow log from the EC2 API
+func convertFlowLog(flowLog types.FlowLog) FlowLog {
+	f := FlowLog{
+		ID:              awssdk.ToString(flowLog.FlowLogId),
+		ResourceID:      awssdk.ToString(flowLog.ResourceId),
+		ResourceType:    flowLogResourceType(awssdk.ToString(flowLog.ResourceId)),
+		TrafficType:     string(flowLog.TrafficType),
+		DestinationType: string(flowLog.LogDestinationType),
+		Tags:            convertTags(flowLog.Tags),
+	}
+	if flowLog.LogDestination != nil {
+		f.Destination = *flowLog.LogDestination
+	} else if flowLog.LogGroupName != nil {
+		f.Destination = *flowLog.LogGroupName
+	}
+	if flowLog.FlowLogStatus != nil {
+		f.Status = *flowLog.FlowLogStatus
+	}
+	if flowLog.DeliverLogsStatus != nil {
+		f.DeliverStatus = *flowLog.DeliverLogsStatus
+	}
+	if flowLog.DeliverLogsErrorMessage != nil {
+		f.DeliverError = *flowLog.DeliverLogsErrorMessage
+	}
+	return f
+}
+
+// interfaceVPCs looks up the VPCs of network interfaces. Deleted interfaces are left out.
+func (s *NetworkScanner) interfaceVPCs(ctx context.Context, ids []string) (map[string]string, error) {
+	vpcs := make(map[string]string)
+
+	for start := 0; start < len(ids); start += interfaceBatchSize {
+		end := start + interfaceBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		// A filter, unlike NetworkInterfaceIds, does not fail on deleted interfaces
+		pages := ec2.NewDescribeNetworkInterfacesPaginator(s.apis.EC2, &ec2.DescribeNetworkInterfacesInput{
+			Filters: []types.Filter{
+				{
+					Name:   &[]string{"network-interface-id"}[0],
+					Values: ids[start:end],
+				},
+			},
+		})
+		for pages.HasMorePages() {
+			page, err := pages.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, eni := range page.NetworkInterfaces {
+				if eni.NetworkInterfaceId != nil && eni.VpcId != nil {
+					vpcs[*eni.NetworkInterfaceId] = *eni.VpcId
+				}
+			}
+		}
+	}
+
+	return vpcs, nil
+}
@@ -0,0 +1,102 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// workloadServicePrincipals are the service principals of compute that runs inside a VPC
+var workloadServicePrincipals = map[string]bool{
+	"ec2.amazonaws.com":       true,
+	"ecs.amazonaws.com":       true,
+	"ecs-tasks.amazonaws.com": true,
+	"eks.amazonaws.com":       true,
+	"pods.eks.amazonaws.com":  true,
+	"lambda.amazonaws.com":    true,
+}
+
+// SetScanIAM enables or disables scanning of IAM roles and their policies
+func (s *NetworkScanner) SetScanIAM(scanIAM bool) {
+	s.scanIAM = scanIAM
+}
+
+// SetAllIAMRoles includes every IAM role instead of only workload roles
+func (s *NetworkScanner) SetAllIAMRoles(allIAMRoles bool) {
+	s.allIAMRoles = allIAMRoles
+}
+
+// trustedServices returns the service principals allowed to assume a role by its trust policy
+func trustedServices(document string) []string {
+	var policy struct {
+		Statement []struct {
+			Effect    string          `json:"Effect"`
+			Principal json.RawMessage `json:"Principal"`
+		} `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		return nil
+	}
+
+	var services []string
+	for _, statement := range policy.Statement {
+		if statement.Effect != "Allow" {
+			continue
+		}
+
+		var principal struct {
+			Service json.RawMessage `json:"Service"`
+		}
+		if err := json.Unmarshal(statement.Principal, &principal); err != nil || principal.Service == nil {
+			continue
+		}
+
+		// Service is either a single principal or a list of them
+		var single string
+		if err := json.Unmarshal(principal.Service, &single); err == nil {
+			services = append(services, single)
+			continue
+		}
+		var list []string
+		if err := json.Unmarshal(principal.Service, &list); err == nil {
+			services = append(services, list...)
+		}
+	}
+
+	return services
+}
+
+// isWorkloadRole reports whether a role's trust policy lets EC2, ECS, EKS or Lambda assume it
+func isWorkloadRole(trustPolicy string) bool {
+	for _, service := range trustedServices(trustPolicy) {
+		if workloadServicePrincipals[service] {
+			return true
+		}
+	}
+	return false
+}
+
+// ecsTaskRoles returns the names of the task and task execution roles of the scanned
+// ECS services, which are workload roles whatever their trust policy says
+func (s *NetworkScanner) ecsTaskRoles(ctx context.Context, services []ECSService) map[string]bool {
+	roles := make(map[string]bool)
+	if len(services) == 0 {
+		return roles
+	}
+
+	definitions, err := s.describeTaskDefinitions(ctx, services)
+	if err != nil {
+		// Log error but continue, the roles are still found by their trust policies
+		s.log().Warn("failed to describe ECS task definitions", "error", err)
+		return roles
+	}
+	for _, definition := range definitions {
+		for _, arn := range []*string{definition.TaskRoleArn, definition.ExecutionRoleArn} {
+			if arn != nil && *arn != "" {
+				// Task definitions name roles by ARN, possibly with a path, or by name
+				roles[(*arn)[strings.LastIndex(*arn, "/")+1:]] = true
+			}
+		}
+	}
+	return roles
+}
@@ -0,0 +1,78 @@
+package scanner
+
+import (
+	"sync"
+)
+
+// defaultIAMConcurrency is how many roles have their policies fetched at once by default
+const defaultIAMConcurrency = 8
+
+// SetIAMConcurrency sets how many IAM roles have their policies fetched at once.
+// Zero uses the default; 1 fetches them one role at a time.
+func (s *NetworkScanner) SetIAMConcurrency(concurrency int) {
+	s.iamConcurrency = concurrency
+}
+
+// forEachRole calls fetch for each of count roles, running at most the IAM
+// concurrency at once
+func (s *NetworkScanner) forEachRole(count int, fetch func(i int)) {
+	workers := s.iamConcurrency
+	if workers <= 0 {
+		workers = defaultIAMConcurrency
+	}
+	if workers > count {
+		workers = count
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fetch(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// managedPolicies fetches each managed policy once per scan, since many roles
+// share the same AWS managed policies
+type managedPolicies struct {
+	mu       sync.Mutex
+	policies map[string]*managedPolicy
+}
+
+// managedPolicy is a managed policy fetched, or being fetched, by one of the roles attaching it
+type managedPolicy struct {
+	once   sync.Once
+	policy IAMPolicy
+	err    error
+}
+
+// newManagedPolicies creates an empty set of fetched managed policies
+func newManagedPolicies() *managedPolicies {
+	return &managedPolicies{policies: make(map[string]*managedPolicy)}
+}
+
+// get returns the policy with arn, calling fetch only for the first role asking for it
+func (m *managedPolicies) get(arn string, fetch func() (IAMPolicy, error)) (IAMPolicy, error) {
+	m.mu.Lock()
+	entry, ok := m.policies[arn]
+	if !ok {
+		entry = &managedPolicy{}
+		m.policies[arn] = entry
+	}
+	m.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.policy, entry.err = fetch()
+	})
+	return entry.policy, entry.err
+}
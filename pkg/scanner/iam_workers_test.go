@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner/scannertest"
+)
+
+// countingIAM counts managed policy lookups and how many roles are fetched at once
+type countingIAM struct {
+	*scannertest.FakeIAM
+
+	mu          sync.Mutex
+	getPolicy   int
+	inFlight    int
+	maxInFlight int
+}
+
+func (c *countingIAM) ListAttachedRolePolicies(ctx context.Context, params *iam.ListAttachedRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error) {
+	c.mu.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return c.FakeIAM.ListAttachedRolePolicies(ctx, params, optFns...)
+}
+
+func (c *countingIAM) GetPolicy(ctx context.Context, params *iam.GetPolicyInput, optFns ...func(*iam.Options)) (*iam.GetPolicyOutput, error) {
+	c.mu.Lock()
+	c.getPolicy++
+	c.mu.Unlock()
+	return c.FakeIAM.GetPolicy(ctx, params, optFns...)
+}
+
+func TestScanIAMRolesConcurrently(t *testing.T) {
+	policyArn := "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"
+	fakeIAM := &scannertest.FakeIAM{
+		AttachedPolicies: make(map[string][]iamTypes.AttachedPolicy),
+		Policies: []iamTypes.Policy{
+			{Arn: awssdk.String(policyArn), PolicyName: awssdk.String("AmazonSSMManagedInstanceCore"), DefaultVersionId: awssdk.String("v1")},
+		},
+		PolicyDocuments: map[string]string{policyArn: `{"Statement":[]}`},
+	}
+	for i := 0; i < 40; i++ {
+		name := fmt.Sprintf("role-%02d", i)
+		fakeIAM.Roles = append(fakeIAM.Roles, iamTypes.Role{RoleId: awssdk.String("id-" + name), RoleName: awssdk.String(name)})
+		fakeIAM.AttachedPolicies[name] = []iamTypes.AttachedPolicy{{PolicyArn: awssdk.String(policyArn)}}
+	}
+	counting := &countingIAM{FakeIAM: fakeIAM}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: newFakeEC2(), IAM: counting, STS: &scannertest.FakeSTS{}})
+	s.SetScanIAM(true)
+	s.SetAllIAMRoles(true)
+	s.SetIAMConcurrency(4)
+	s.SetCache(NewScanCache("", time.Hour))
+
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.IAMRoles) != 40 {
+		t.Fatalf("Expected every role, got %d", len(network.IAMRoles))
+	}
+	for i, role := range network.IAMRoles {
+		if role.Name != fmt.Sprintf("role-%02d", i) || len(role.AttachedPolicies) != 1 || role.AttachedPolicies[0].PolicyDocument != `{"Statement":[]}` {
+			t.Fatalf("Expected role-%02d with the shared policy, got %+v", i, role)
+		}
+	}
+	if counting.getPolicy != 1 {
+		t.Errorf("Expected the shared policy to be fetched once, got %d", counting.getPolicy)
+	}
+	if counting.maxInFlight < 2 || counting.maxInFlight > 4 {
+		t.Errorf("Expected at most 4 roles fetched at once, got %d", counting.maxInFlight)
+	}
+}
@@ -1,23 +1,52 @@
 package scanner
 
 import (
+	"encoding/json"
+	"net"
 	"time"
 )
 
 // Network represents the complete AWS network infrastructure
 type Network struct {
-	VPCs                []VPC                 `json:"vpcs"`
-	Subnets             []Subnet              `json:"subnets"`
-	PeeringConnections  []PeeringConnection   `json:"peering_connections"`
-	TransitGateways     []TransitGateway      `json:"transit_gateways"`
-	InternetGateways    []InternetGateway     `json:"internet_gateways"`
-	NATGateways         []NATGateway          `json:"nat_gateways"`
-	RouteTables         []RouteTable          `json:"route_tables"`
-	SecurityGroups      []SecurityGroup       `json:"security_groups"`
-	NetworkAcls         []NetworkAcl          `json:"network_acls"`
-	IAMRoles            []IAMRole             `json:"iam_roles"`
-	ScanTime            time.Time             `json:"scan_time"`
-	Region              string                `json:"region"`
+	VPCs                 []VPC                 `json:"vpcs"`
+	Subnets              []Subnet              `json:"subnets"`
+	PeeringConnections   []PeeringConnection   `json:"peering_connections"`
+	TransitGateways      []TransitGateway      `json:"transit_gateways"`
+	InternetGateways     []InternetGateway     `json:"internet_gateways"`
+	EgressOnlyInternetGateways []EgressOnlyInternetGateway `json:"egress_only_internet_gateways,omitempty"`
+	NATGateways          []NATGateway          `json:"nat_gateways"`
+	RouteTables          []RouteTable          `json:"route_tables"`
+	SecurityGroups       []SecurityGroup       `json:"security_groups"`
+	NetworkAcls          []NetworkAcl          `json:"network_acls"`
+	// FlowLogs is nil in working states saved before flow logs were scanned
+	FlowLogs             []FlowLog             `json:"flow_logs"`
+	IAMRoles             []IAMRole             `json:"iam_roles"`
+	MeshVirtualGateways  []MeshVirtualGateway  `json:"mesh_virtual_gateways,omitempty"`
+	DhcpOptions          []DhcpOptions         `json:"dhcp_options,omitempty"`
+	EndpointServices     []EndpointService     `json:"endpoint_services,omitempty"`
+	VpcEndpoints         []VpcEndpoint         `json:"vpc_endpoints,omitempty"`
+	NetworkInterfaces    []NetworkInterface    `json:"network_interfaces,omitempty"`
+	EKSClusters          []EKSCluster          `json:"eks_clusters,omitempty"`
+	ECSServices          []ECSService          `json:"ecs_services,omitempty"`
+	DatabaseSubnetGroups []DatabaseSubnetGroup `json:"database_subnet_groups,omitempty"`
+	Databases            []Database            `json:"databases,omitempty"`
+	EdgeIngresses        []EdgeIngress         `json:"edge_ingresses,omitempty"`
+	// SchemaVersion is the working state schema the network was scanned or loaded in
+	SchemaVersion        int                   `json:"schema_version"`
+	ScanTime             time.Time             `json:"scan_time"`
+	Region               string                `json:"region"`
+	AccountID            string                `json:"account_id,omitempty"`
+	// Incomplete lists the resource types a scan that timed out did not finish
+	Incomplete           []string              `json:"incomplete,omitempty"`
+	// ScanErrors lists the optional resource types a scan failed to scan, and in
+	// best-effort mode every other one
+	ScanErrors           []ScanError           `json:"scan_errors,omitempty"`
+}
+
+// ScanError records a resource type that failed to scan
+type ScanError struct {
+	ResourceType string `json:"resource_type"`
+	Error        string `json:"error"`
 }
 
 // VPC represents an AWS VPC
@@ -25,30 +54,60 @@ type VPC struct {
 	ID                string            `json:"id"`
 	Name              string            `json:"name"`
 	CidrBlock         string            `json:"cidr_block"`
+	Ipv6CidrBlocks    []string          `json:"ipv6_cidr_blocks,omitempty"` // Associated IPv6 CIDR blocks
 	State             string            `json:"state"`
 	IsDefault         bool              `json:"is_default"`
-	DhcpOptionsID     string            `json:"dhcp_options_id"`
+	DhcpOptionsID     string            `json:"dhcp_options_id,omitempty"`
 	Tags              map[string]string `json:"tags"`
 	Subnets           []string          `json:"subnets"`           // Subnet IDs
 	SecurityGroups    []string          `json:"security_groups"`    // Security Group IDs
 	InternetGateways  []string          `json:"internet_gateways"`  // Internet Gateway IDs
+	EgressOnlyInternetGateways []string `json:"egress_only_internet_gateways,omitempty"` // Egress-only Internet Gateway IDs
 	NATGateways       []string          `json:"nat_gateways"`       // NAT Gateway IDs
 	NetworkAcls       []string          `json:"network_acls"`       // Network ACL IDs
 }
 
+// DhcpOptions represents a DHCP option set, which sets the DNS and NTP servers
+// and domain name of the VPCs associated with it
+type DhcpOptions struct {
+	ID                 string            `json:"id"`
+	Name               string            `json:"name"`
+	DomainName         string            `json:"domain_name"`
+	DomainNameServers  []string          `json:"domain_name_servers"` // "AmazonProvidedDNS" for the Route 53 Resolver
+	NtpServers         []string          `json:"ntp_servers"`
+	NetbiosNameServers []string          `json:"netbios_name_servers,omitempty"`
+	NetbiosNodeType    string            `json:"netbios_node_type,omitempty"`
+	Tags               map[string]string `json:"tags"`
+}
+
 // Subnet represents an AWS subnet
 type Subnet struct {
 	ID                string            `json:"id"`
 	Name              string            `json:"name"`
 	VpcID             string            `json:"vpc_id"`
-	CidrBlock         string            `json:"cidr_block"`
+	CidrBlock         string            `json:"cidr_block,omitempty"`       // Empty for IPv6-only subnets
+	Ipv6CidrBlocks    []string          `json:"ipv6_cidr_blocks,omitempty"` // Associated IPv6 CIDR blocks
 	AvailabilityZone  string            `json:"availability_zone"`
 	State             string            `json:"state"`
 	MapPublicIP       bool              `json:"map_public_ip"`
+	AssignIpv6Address bool              `json:"assign_ipv6_address,omitempty"` // New interfaces get an IPv6 address
 	Tags              map[string]string `json:"tags"`
 	RouteTableID      string            `json:"route_table_id"`
 	NetworkAclID      string            `json:"network_acl_id"`
 	Type              string            `json:"type"` // "public", "private", "isolated"
+	TypeRoute         *Route            `json:"type_route,omitempty"` // The route that decided Type, nil for isolated subnets
+	CidrReservations  []SubnetCidrReservation `json:"cidr_reservations,omitempty"`
+	TotalIPs          int               `json:"total_ips,omitempty"`     // Assignable IPv4 addresses in the CIDR block
+	AvailableIPs      *int              `json:"available_ips,omitempty"` // Unused IPv4 addresses, nil in working states saved before it was scanned
+}
+
+// SubnetCidrReservation represents a CIDR range reserved within a subnet
+type SubnetCidrReservation struct {
+	ID              string            `json:"id"`
+	Cidr            string            `json:"cidr"`
+	ReservationType string            `json:"reservation_type"` // "prefix", "explicit"
+	Description     string            `json:"description"`
+	Tags            map[string]string `json:"tags"`
 }
 
 // PeeringConnection represents a VPC peering connection
@@ -57,6 +116,8 @@ type PeeringConnection struct {
 	Name             string            `json:"name"`
 	RequesterVpcID   string            `json:"requester_vpc_id"`
 	AccepterVpcID    string            `json:"accepter_vpc_id"`
+	RequesterRegion  string            `json:"requester_region,omitempty"`
+	AccepterRegion   string            `json:"accepter_region,omitempty"`
 	Status           string            `json:"status"`
 	Tags             map[string]string `json:"tags"`
 }
@@ -68,16 +129,52 @@ type TransitGateway struct {
 	State       string                     `json:"state"`
 	Tags        map[string]string          `json:"tags"`
 	Attachments []TransitGatewayAttachment `json:"attachments"`
+	RouteTables []TransitGatewayRouteTable `json:"route_tables,omitempty"`
+	// AttachmentsError is why the attachments could not be scanned, leaving them out
+	AttachmentsError string `json:"attachments_error,omitempty"`
 }
 
 // TransitGatewayAttachment represents a TGW attachment
 type TransitGatewayAttachment struct {
-	ID                 string            `json:"id"`
-	TransitGatewayID   string            `json:"transit_gateway_id"`
-	ResourceID         string            `json:"resource_id"`
-	ResourceType       string            `json:"resource_type"`
-	State              string            `json:"state"`
-	Tags               map[string]string `json:"tags"`
+	ID                   string            `json:"id"`
+	TransitGatewayID     string            `json:"transit_gateway_id"`
+	ResourceID           string            `json:"resource_id"`
+	ResourceType         string            `json:"resource_type"`
+	State                string            `json:"state"`
+	Tags                 map[string]string `json:"tags"`
+	PeerTransitGatewayID string            `json:"peer_transit_gateway_id,omitempty"` // Peering attachments only
+	PeerRegion           string            `json:"peer_region,omitempty"`             // Peering attachments only
+	RouteTableID         string            `json:"route_table_id,omitempty"`          // Associated TGW route table
+}
+
+// TransitGatewayRouteTable represents a transit gateway route table
+type TransitGatewayRouteTable struct {
+	ID                   string                `json:"id"`
+	Name                 string                `json:"name"`
+	State                string                `json:"state"`
+	DefaultAssociation   bool                  `json:"default_association"`
+	DefaultPropagation   bool                  `json:"default_propagation"`
+	Tags                 map[string]string     `json:"tags"`
+	Routes               []TransitGatewayRoute `json:"routes"`
+}
+
+// TransitGatewayRoute represents a route in a transit gateway route table
+type TransitGatewayRoute struct {
+	DestinationCidr string `json:"destination_cidr"`
+	PrefixListID    string `json:"prefix_list_id,omitempty"` // Destination of prefix list references, which have no CIDR
+	AttachmentID    string `json:"attachment_id"`
+	ResourceID      string `json:"resource_id"`
+	ResourceType    string `json:"resource_type"`
+	Type            string `json:"type"`  // "static", "propagated"
+	State           string `json:"state"` // "active", "blackhole"
+}
+
+// Destination returns the route's destination CIDR, or its destination prefix list
+func (r TransitGatewayRoute) Destination() string {
+	if r.DestinationCidr != "" {
+		return r.DestinationCidr
+	}
+	return r.PrefixListID
 }
 
 // InternetGateway represents an AWS Internet Gateway
@@ -89,6 +186,16 @@ type InternetGateway struct {
 	Tags  map[string]string `json:"tags"`
 }
 
+// EgressOnlyInternetGateway represents an egress-only internet gateway, which
+// lets IPv6 traffic leave a VPC but not enter it
+type EgressOnlyInternetGateway struct {
+	ID    string            `json:"id"`
+	Name  string            `json:"name"`
+	VpcID string            `json:"vpc_id"`
+	State string            `json:"state"`
+	Tags  map[string]string `json:"tags"`
+}
+
 // NATGateway represents an AWS NAT Gateway
 type NATGateway struct {
 	ID               string            `json:"id"`
@@ -115,14 +222,57 @@ type RouteTable struct {
 
 // Route represents a route in a route table
 type Route struct {
-	DestinationCidr    string `json:"destination_cidr"`
-	GatewayID          string `json:"gateway_id"`
-	InstanceID         string `json:"instance_id"`
-	NetworkInterfaceID string `json:"network_interface_id"`
-	VpcPeeringID       string `json:"vpc_peering_id"`
-	TransitGatewayID   string `json:"transit_gateway_id"`
-	State              string `json:"state"`
-	Origin             string `json:"origin"`
+	DestinationCidr         string `json:"destination_cidr"`
+	DestinationIpv6Cidr     string `json:"destination_ipv6_cidr,omitempty"`
+	DestinationPrefixListID string `json:"destination_prefix_list_id,omitempty"` // Gateway endpoint routes have no CIDR
+	GatewayID               string `json:"gateway_id"`                           // Internet or virtual private gateway, or "local"
+	NatGatewayID            string `json:"nat_gateway_id,omitempty"`
+	EgressOnlyGatewayID     string `json:"egress_only_gateway_id,omitempty"`
+	CarrierGatewayID        string `json:"carrier_gateway_id,omitempty"`
+	LocalGatewayID          string `json:"local_gateway_id,omitempty"`
+	InstanceID              string `json:"instance_id"`
+	NetworkInterfaceID      string `json:"network_interface_id"`
+	VpcPeeringID            string `json:"vpc_peering_id"`
+	TransitGatewayID        string `json:"transit_gateway_id"`
+	State                   string `json:"state"`
+	Origin                  string `json:"origin"`
+}
+
+// Destination returns the route's IPv4 or IPv6 destination CIDR, or its destination
+// prefix list
+func (r Route) Destination() string {
+	if r.DestinationCidr != "" {
+		return r.DestinationCidr
+	}
+	if r.DestinationIpv6Cidr != "" {
+		return r.DestinationIpv6Cidr
+	}
+	return r.DestinationPrefixListID
+}
+
+// Target returns the gateway, connection or interface a route sends traffic to
+func (r Route) Target() string {
+	switch {
+	case r.GatewayID != "":
+		return r.GatewayID
+	case r.NatGatewayID != "":
+		return r.NatGatewayID
+	case r.EgressOnlyGatewayID != "":
+		return r.EgressOnlyGatewayID
+	case r.CarrierGatewayID != "":
+		return r.CarrierGatewayID
+	case r.LocalGatewayID != "":
+		return r.LocalGatewayID
+	case r.VpcPeeringID != "":
+		return r.VpcPeeringID
+	case r.TransitGatewayID != "":
+		return r.TransitGatewayID
+	case r.InstanceID != "":
+		return r.InstanceID
+	case r.NetworkInterfaceID != "":
+		return r.NetworkInterfaceID
+	}
+	return ""
 }
 
 // SecurityGroup represents an AWS security group
@@ -138,18 +288,56 @@ type SecurityGroup struct {
 
 // SecurityGroupRule represents an AWS security group rule
 type SecurityGroupRule struct {
+	ID                         string            `json:"id,omitempty"` // Security group rule ID, empty when rules were read from the group's permissions
 	IpProtocol                 string            `json:"ip_protocol"`
 	FromPort                   int32             `json:"from_port"`
 	ToPort                     int32             `json:"to_port"`
 	CidrBlocks                 []string          `json:"cidr_blocks"`
 	Ipv6CidrBlocks             []string          `json:"ipv6_cidr_blocks"`
 	PrefixListIds              []string          `json:"prefix_list_ids"`
-	ReferencedGroupId          string            `json:"referenced_group_id"`
-	ReferencedGroupOwnerId     string            `json:"referenced_group_owner_id"`
+	ReferencedGroups           []SecurityGroupReference `json:"referenced_groups,omitempty"`
 	Description                string            `json:"description"`
 	Tags                       map[string]string `json:"tags"`
 }
 
+// SecurityGroupReference represents a security group a rule allows traffic from or to
+type SecurityGroupReference struct {
+	GroupID     string `json:"group_id"`
+	OwnerID     string `json:"owner_id,omitempty"` // Account ID of the group
+	VpcID       string `json:"vpc_id,omitempty"`
+	PeeringID   string `json:"vpc_peering_connection_id,omitempty"` // Set for groups in a peered VPC
+	Description string `json:"description,omitempty"`
+}
+
+// ReferencedGroupIDs returns the IDs of the security groups a rule references
+func (r SecurityGroupRule) ReferencedGroupIDs() []string {
+	var ids []string
+	for _, group := range r.ReferencedGroups {
+		ids = append(ids, group.GroupID)
+	}
+	return ids
+}
+
+// UnmarshalJSON reads a rule, including rules in states saved before rules kept
+// every referenced group, which recorded a single referenced_group_id
+func (r *SecurityGroupRule) UnmarshalJSON(data []byte) error {
+	type rule SecurityGroupRule
+	var decoded struct {
+		rule
+		ReferencedGroupID      string `json:"referenced_group_id"`
+		ReferencedGroupOwnerID string `json:"referenced_group_owner_id"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*r = SecurityGroupRule(decoded.rule)
+	if len(r.ReferencedGroups) == 0 && decoded.ReferencedGroupID != "" {
+		r.ReferencedGroups = []SecurityGroupReference{{GroupID: decoded.ReferencedGroupID, OwnerID: decoded.ReferencedGroupOwnerID}}
+	}
+	return nil
+}
+
 // IAMRole represents an AWS IAM role
 type IAMRole struct {
 	ID                   string              `json:"id"`
@@ -163,6 +351,19 @@ type IAMRole struct {
 	Tags                 map[string]string   `json:"tags"`
 	AttachedPolicies     []IAMPolicy         `json:"attached_policies"`
 	InlinePolicies       []IAMInlinePolicy   `json:"inline_policies"`
+	// InstanceProfiles are the instance profiles that let EC2 instances act with the role
+	InstanceProfiles []IAMInstanceProfile `json:"instance_profiles,omitempty"`
+}
+
+// IAMInstanceProfile represents an IAM instance profile, the container that passes
+// a role to EC2 instances
+type IAMInstanceProfile struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Path       string            `json:"path"`
+	Arn        string            `json:"arn"`
+	CreateDate time.Time         `json:"create_date"`
+	Tags       map[string]string `json:"tags,omitempty"`
 }
 
 // IAMPolicy represents an AWS IAM policy (managed policy)
@@ -221,4 +422,240 @@ type NetworkAclPortRange struct {
 type NetworkAclIcmpType struct {
 	Type int32 `json:"type"`
 	Code int32 `json:"code"`
-}
\ No newline at end of file
+}
+// FlowLog represents a VPC flow log capturing the traffic of a VPC, subnet or
+// network interface
+type FlowLog struct {
+	ID              string            `json:"id"`
+	ResourceID      string            `json:"resource_id"`
+	ResourceType    string            `json:"resource_type"` // "VPC", "Subnet", "NetworkInterface"
+	VpcID           string            `json:"vpc_id"`
+	TrafficType     string            `json:"traffic_type"`     // "ALL", "ACCEPT", "REJECT"
+	DestinationType string            `json:"destination_type"` // "cloud-watch-logs", "s3", "kinesis-data-firehose"
+	Destination     string            `json:"destination"`      // Log group name or destination ARN
+	Status          string            `json:"status"`
+	DeliverStatus   string            `json:"deliver_status"` // "SUCCESS", "FAILED"
+	DeliverError    string            `json:"deliver_error,omitempty"`
+	Tags            map[string]string `json:"tags"`
+}
+
+// FlowLogsFor returns the flow logs capturing a VPC, subnet or network interface
+func (n *Network) FlowLogsFor(resourceID string) []FlowLog {
+	var flowLogs []FlowLog
+	for _, flowLog := range n.FlowLogs {
+		if flowLog.ResourceID == resourceID {
+			flowLogs = append(flowLogs, flowLog)
+		}
+	}
+	return flowLogs
+}
+
+// MeshVirtualGateway represents an App Mesh virtual gateway, the ingress point
+// for traffic entering a service mesh from outside it
+type MeshVirtualGateway struct {
+	Arn       string         `json:"arn"`
+	Name      string         `json:"name"`
+	MeshName  string         `json:"mesh_name"`
+	MeshOwner string         `json:"mesh_owner"`
+	Status    string         `json:"status"`
+	Listeners []MeshListener `json:"listeners"`
+}
+
+// MeshListener represents a port a virtual gateway accepts traffic on
+type MeshListener struct {
+	Port     int32  `json:"port"`
+	Protocol string `json:"protocol"` // "http", "http2", "grpc"
+	TLS      bool   `json:"tls"`
+}
+
+// EndpointService represents a VPC endpoint service (PrivateLink) the account provides
+type EndpointService struct {
+	ID                      string            `json:"id"`
+	Name                    string            `json:"name"`
+	ServiceName             string            `json:"service_name"` // e.g. com.amazonaws.vpce.us-east-1.vpce-svc-0123
+	ServiceType             string            `json:"service_type"` // "Interface" or "GatewayLoadBalancer"
+	State                   string            `json:"state"`
+	AcceptanceRequired      bool              `json:"acceptance_required"`
+	PrivateDNSName          string            `json:"private_dns_name,omitempty"`
+	AvailabilityZones       []string          `json:"availability_zones"`
+	NetworkLoadBalancerArns []string          `json:"network_load_balancer_arns,omitempty"`
+	GatewayLoadBalancerArns []string          `json:"gateway_load_balancer_arns,omitempty"`
+	AllowedPrincipals       []string          `json:"allowed_principals"`
+	Tags                    map[string]string `json:"tags"`
+}
+
+// VpcEndpoint represents an interface or Gateway Load Balancer VPC endpoint, the
+// consumer side of PrivateLink, with the network interfaces it places in subnets
+type VpcEndpoint struct {
+	ID                string                     `json:"id"`
+	Name              string                     `json:"name"`
+	VpcID             string                     `json:"vpc_id"`
+	ServiceName       string                     `json:"service_name"` // e.g. com.amazonaws.us-east-1.s3
+	Type              string                     `json:"type"`         // "Interface" or "GatewayLoadBalancer"
+	State             string                     `json:"state"`
+	PrivateDNSEnabled bool                       `json:"private_dns_enabled"`
+	SubnetIDs         []string                   `json:"subnet_ids"`
+	SecurityGroupIDs  []string                   `json:"security_group_ids"`
+	NetworkInterfaces []EndpointNetworkInterface `json:"network_interfaces"`
+	DNSEntries        []EndpointDNSEntry         `json:"dns_entries,omitempty"`
+	Tags              map[string]string          `json:"tags"`
+}
+
+// EndpointNetworkInterface is a network interface a VPC endpoint created in a subnet
+type EndpointNetworkInterface struct {
+	ID            string   `json:"id"`
+	SubnetID      string   `json:"subnet_id"`
+	PrivateIP     string   `json:"private_ip,omitempty"`
+	Ipv6Addresses []string `json:"ipv6_addresses,omitempty"`
+}
+
+// EndpointDNSEntry is a DNS name resolving to a VPC endpoint's network interfaces
+type EndpointDNSEntry struct {
+	DNSName      string `json:"dns_name"`
+	HostedZoneID string `json:"hosted_zone_id,omitempty"`
+}
+
+// VpcEndpointFor returns the VPC endpoint owning a private IPv4 or IPv6 address
+// and the network interface holding it
+func (n *Network) VpcEndpointFor(address string) (*VpcEndpoint, *EndpointNetworkInterface, bool) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, nil, false
+	}
+	for i := range n.VpcEndpoints {
+		endpoint := &n.VpcEndpoints[i]
+		for j := range endpoint.NetworkInterfaces {
+			eni := &endpoint.NetworkInterfaces[j]
+			for _, held := range append([]string{eni.PrivateIP}, eni.Ipv6Addresses...) {
+				if ip.Equal(net.ParseIP(held)) {
+					return endpoint, eni, true
+				}
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+// NetworkInterface represents an elastic network interface and the resource it
+// was resolved to be attached to
+type NetworkInterface struct {
+	ID                  string            `json:"id"`
+	VpcID               string            `json:"vpc_id"`
+	SubnetID            string            `json:"subnet_id"`
+	AvailabilityZone    string            `json:"availability_zone,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	InterfaceType       string            `json:"interface_type"`   // As reported by EC2, e.g. "interface", "lambda"
+	AttachmentType      string            `json:"attachment_type"`  // e.g. "ec2", "lambda", "nat_gateway", "load_balancer", "rds"
+	AttachedTo          string            `json:"attached_to,omitempty"` // Instance, NAT gateway, load balancer, endpoint or function
+	Status              string            `json:"status"`
+	RequesterManaged    bool              `json:"requester_managed"`
+	PrivateIP           string            `json:"private_ip"`
+	SecondaryPrivateIPs []string          `json:"secondary_private_ips,omitempty"`
+	Ipv4Prefixes        []string          `json:"ipv4_prefixes,omitempty"`
+	Ipv6Addresses       []string          `json:"ipv6_addresses,omitempty"`
+	PublicIP            string            `json:"public_ip,omitempty"`
+	SecurityGroupIDs    []string          `json:"security_group_ids"`
+	Tags                map[string]string `json:"tags"`
+}
+
+// IPv4AddressCount returns how many of its subnet's IPv4 addresses the interface
+// uses: its primary and secondary addresses and 16 for each delegated /28 prefix
+func (eni NetworkInterface) IPv4AddressCount() int {
+	count := len(eni.SecondaryPrivateIPs) + 16*len(eni.Ipv4Prefixes)
+	if eni.PrivateIP != "" {
+		count++
+	}
+	return count
+}
+
+// EKSCluster represents the networking of an EKS cluster's control plane
+type EKSCluster struct {
+	Name                   string            `json:"name"`
+	Arn                    string            `json:"arn"`
+	Version                string            `json:"version"`
+	Status                 string            `json:"status"`
+	VpcID                  string            `json:"vpc_id"`
+	SubnetIDs              []string          `json:"subnet_ids"`
+	SecurityGroupIDs       []string          `json:"security_group_ids"` // Additional security groups
+	ClusterSecurityGroupID string            `json:"cluster_security_group_id"`
+	EndpointPublicAccess   bool              `json:"endpoint_public_access"`
+	EndpointPrivateAccess  bool              `json:"endpoint_private_access"`
+	PublicAccessCidrs      []string          `json:"public_access_cidrs,omitempty"`
+	NetworkInterfaces      []string          `json:"network_interfaces"` // Control plane ENI IDs
+	Tags                   map[string]string `json:"tags"`
+}
+
+// ECSService represents an ECS service whose tasks use awsvpc networking
+type ECSService struct {
+	Arn              string            `json:"arn"`
+	Name             string            `json:"name"`
+	ClusterName      string            `json:"cluster_name"`
+	LaunchType       string            `json:"launch_type"` // "FARGATE", "EC2" or "EXTERNAL", empty with a capacity provider strategy
+	Status           string            `json:"status"`
+	DesiredCount     int32             `json:"desired_count"`
+	RunningCount     int32             `json:"running_count"`
+	VpcID            string            `json:"vpc_id"`
+	SubnetIDs        []string          `json:"subnet_ids"`
+	SecurityGroupIDs []string          `json:"security_group_ids"`
+	AssignPublicIP   bool              `json:"assign_public_ip"`
+	Tags             map[string]string `json:"tags"`
+
+	// taskDefinition is the ARN of the task definition the service runs. It
+	// changes with every deployment, so it is neither saved nor compared.
+	taskDefinition string
+}
+
+// DatabaseSubnetGroup represents an RDS, ElastiCache or Redshift subnet group
+type DatabaseSubnetGroup struct {
+	Name        string   `json:"name"`
+	Service     string   `json:"service"` // "rds", "elasticache" or "redshift"
+	Description string   `json:"description"`
+	VpcID       string   `json:"vpc_id"`
+	SubnetIDs   []string `json:"subnet_ids"`
+}
+
+// Database represents the network placement of an RDS instance or cluster,
+// ElastiCache cluster or Redshift cluster
+type Database struct {
+	ID                 string            `json:"id"`      // Instance or cluster identifier
+	Service            string            `json:"service"` // "rds", "elasticache" or "redshift"
+	Engine             string            `json:"engine"`
+	Status             string            `json:"status"`
+	Endpoint           string            `json:"endpoint"`
+	Port               int32             `json:"port"`
+	VpcID              string            `json:"vpc_id"`
+	SubnetGroup        string            `json:"subnet_group"`
+	SubnetIDs          []string          `json:"subnet_ids"` // The subnet group's subnets
+	SecurityGroupIDs   []string          `json:"security_group_ids"`
+	PubliclyAccessible bool              `json:"publicly_accessible"`
+	Tags               map[string]string `json:"tags"`
+}
+
+// Key identifies a database across services, whose identifiers may collide
+func (d Database) Key() string {
+	return d.Service + ":" + d.ID
+}
+
+// EdgeIngress represents a Global Accelerator accelerator or CloudFront distribution
+// that sends traffic from outside AWS into the scanned VPCs
+type EdgeIngress struct {
+	ID      string       `json:"id"`      // Accelerator ARN or distribution ID
+	Name    string       `json:"name"`
+	Service string       `json:"service"` // "globalaccelerator" or "cloudfront"
+	DNSName string       `json:"dns_name"`
+	Aliases []string     `json:"aliases,omitempty"`
+	Status  string       `json:"status"`
+	Enabled bool         `json:"enabled"`
+	Targets []EdgeTarget `json:"targets"`
+}
+
+// EdgeTarget represents a load balancer or Elastic IP in a scanned VPC that an
+// accelerator endpoint or distribution origin points at
+type EdgeTarget struct {
+	ID        string   `json:"id"`      // Load balancer ARN or Elastic IP allocation ID
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`    // "application", "network", "gateway" or "eip"
+	Address   string   `json:"address"` // Load balancer DNS name or public IP
+	VpcID     string   `json:"vpc_id"`
+	SubnetIDs []string `json:"subnet_ids"`
+}
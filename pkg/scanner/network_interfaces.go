@@ -0,0 +1,173 @@
+package scanner
+
+import (
+	"context"
+	"strings"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Attachment types network interfaces are resolved to
+const (
+	AttachmentEC2            = "ec2"
+	AttachmentLambda         = "lambda"
+	AttachmentNATGateway     = "nat_gateway"
+	AttachmentLoadBalancer   = "load_balancer"
+	AttachmentRDS            = "rds"
+	AttachmentVpcEndpoint    = "vpc_endpoint"
+	AttachmentTransitGateway = "transit_gateway"
+	AttachmentEFS            = "efs"
+	AttachmentECS            = "ecs"
+	AttachmentEKS            = "eks"
+	AttachmentOther          = "other"
+	AttachmentUnattached     = "unattached"
+)
+
+// lambdaENIPrefix starts the description of the network interfaces Lambda
+// creates, followed by the function name and a UUID
+const lambdaENIPrefix = "AWS Lambda VPC ENI-"
+
+// scanNetworkInterfaces scans every network interface in the VPCs and resolves
+// what each one is attached to
+func (s *NetworkScanner) scanNetworkInterfaces(ctx context.Context, vpcIDs []string) ([]NetworkInterface, error) {
+	if len(vpcIDs) == 0 {
+		return []NetworkInterface{}, nil
+	}
+
+	var interfaces []NetworkInterface
+	pages := ec2.NewDescribeNetworkInterfacesPaginator(s.apis.EC2, &ec2.DescribeNetworkInterfacesInput{
+		Filters: []types.Filter{
+			{
+				Name:   &[]string{"vpc-id"}[0],
+				Values: vpcIDs,
+			},
+		},
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, eni := range page.NetworkInterfaces {
+			interfaces = append(interfaces, convertNetworkInterface(eni))
+		}
+	}
+
+	return interfaces, nil
+}
+
+// convertNetworkInterface converts a network interface
+func convertNetworkInterface(eni types.NetworkInterface) NetworkInterface {
+	i := NetworkInterface{
+		ID:               awssdk.ToString(eni.NetworkInterfaceId),
+		VpcID:            awssdk.ToString(eni.VpcId),
+		SubnetID:         awssdk.ToString(eni.SubnetId),
+		AvailabilityZone: awssdk.ToString(eni.AvailabilityZone),
+		Description:      awssdk.ToString(eni.Description),
+		InterfaceType:    string(eni.InterfaceType),
+		Status:           string(eni.Status),
+		RequesterManaged: awssdk.ToBool(eni.RequesterManaged),
+		PrivateIP:        awssdk.ToString(eni.PrivateIpAddress),
+		Tags:             convertTags(eni.TagSet),
+	}
+	i.AttachmentType, i.AttachedTo = classifyNetworkInterface(eni)
+
+	for _, address := range eni.PrivateIpAddresses {
+		if address.PrivateIpAddress != nil && !awssdk.ToBool(address.Primary) {
+			i.SecondaryPrivateIPs = append(i.SecondaryPrivateIPs, *address.PrivateIpAddress)
+		}
+	}
+	for _, prefix := range eni.Ipv4Prefixes {
+		if prefix.Ipv4Prefix != nil {
+			i.Ipv4Prefixes = append(i.Ipv4Prefixes, *prefix.Ipv4Prefix)
+		}
+	}
+	for _, address := range eni.Ipv6Addresses {
+		if address.Ipv6Address != nil {
+			i.Ipv6Addresses = append(i.Ipv6Addresses, *address.Ipv6Address)
+		}
+	}
+	if eni.Association != nil {
+		i.PublicIP = awssdk.ToString(eni.Association.PublicIp)
+	}
+	for _, group := range eni.Groups {
+		if group.GroupId != nil {
+			i.SecurityGroupIDs = append(i.SecurityGroupIDs, *group.GroupId)
+		}
+	}
+
+	return i
+}
+
+// classifyNetworkInterface resolves the type of resource a network interface is
+// attached to, and the resource when it can be told, from its interface type and
+// the descriptions AWS services give the interfaces they create
+func classifyNetworkInterface(eni types.NetworkInterface) (string, string) {
+	description := awssdk.ToString(eni.Description)
+	instanceID := ""
+	if eni.Attachment != nil {
+		instanceID = awssdk.ToString(eni.Attachment.InstanceId)
+	}
+
+	switch eni.InterfaceType {
+	// The API documents both spellings for NAT gateway interfaces
+	case types.NetworkInterfaceTypeNatGateway, "nat_gateway":
+		return AttachmentNATGateway, lastWord(description, "nat-")
+	case types.NetworkInterfaceTypeVpcEndpoint, types.NetworkInterfaceTypeGatewayLoadBalancerEndpoint:
+		return AttachmentVpcEndpoint, lastWord(description, "vpce-")
+	case types.NetworkInterfaceTypeNetworkLoadBalancer, types.NetworkInterfaceTypeGatewayLoadBalancer, types.NetworkInterfaceTypeLoadBalancer:
+		return AttachmentLoadBalancer, strings.TrimPrefix(description, "ELB ")
+	case types.NetworkInterfaceTypeLambda:
+		return AttachmentLambda, lambdaFunction(description)
+	case types.NetworkInterfaceTypeTransitGateway:
+		return AttachmentTransitGateway, lastWord(description, "tgw-attach-")
+	case types.NetworkInterfaceTypeTrunk:
+		return AttachmentEC2, instanceID
+	}
+
+	switch {
+	case strings.HasPrefix(description, "ELB "):
+		return AttachmentLoadBalancer, strings.TrimPrefix(description, "ELB ")
+	case strings.HasPrefix(description, lambdaENIPrefix):
+		return AttachmentLambda, lambdaFunction(description)
+	case strings.HasPrefix(description, "RDSNetworkInterface") || awssdk.ToString(eni.RequesterId) == "amazon-rds":
+		return AttachmentRDS, ""
+	case strings.HasPrefix(description, "EFS mount target for "):
+		fields := strings.Fields(strings.TrimPrefix(description, "EFS mount target for "))
+		if len(fields) > 0 {
+			return AttachmentEFS, fields[0]
+		}
+		return AttachmentEFS, ""
+	case strings.HasPrefix(description, "arn:aws:ecs:"):
+		return AttachmentECS, description
+	case strings.HasPrefix(description, "Amazon EKS "):
+		return AttachmentEKS, strings.TrimPrefix(description, "Amazon EKS ")
+	case instanceID != "":
+		return AttachmentEC2, instanceID
+	case eni.Status == types.NetworkInterfaceStatusAvailable:
+		return AttachmentUnattached, ""
+	}
+	return AttachmentOther, ""
+}
+
+// lastWord returns the last word of a description when it has the ID prefix
+func lastWord(description, prefix string) string {
+	fields := strings.Fields(description)
+	if len(fields) > 0 && strings.HasPrefix(fields[len(fields)-1], prefix) {
+		return fields[len(fields)-1]
+	}
+	return ""
+}
+
+// lambdaFunction returns the function name in a Lambda network interface's
+// description, dropping the UUID that follows it
+func lambdaFunction(description string) string {
+	name := strings.TrimPrefix(description, lambdaENIPrefix)
+	const uuidLength = 36
+	if len(name) > uuidLength+1 && name[len(name)-uuidLength-1] == '-' {
+		return name[:len(name)-uuidLength-1]
+	}
+	return name
+}
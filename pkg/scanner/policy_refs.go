@@ -0,0 +1,116 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// networkFields is Network without its JSON methods
+type networkFields Network
+
+// storedIAMRole is a role as saved in a working state. Its managed policies are
+// referenced by ARN and saved once in the state's policies, since hundreds of roles
+// may attach the same policy, such as AmazonSSMManagedInstanceCore.
+type storedIAMRole struct {
+	IAMRole
+	// AttachedPolicies hides the role's policies, which are saved in the network's policies
+	AttachedPolicies   []IAMPolicy `json:"attached_policies,omitempty"`
+	AttachedPolicyArns []string    `json:"attached_policy_arns"`
+}
+
+// storedNetwork is a network as saved in a working state
+type storedNetwork struct {
+	networkFields
+	IAMRoles []storedIAMRole     `json:"iam_roles"`
+	Policies map[string]IAMPolicy `json:"policies,omitempty"` // Managed policies attached to the roles, by ARN
+}
+
+// MarshalJSON writes the network with each managed policy attached to its IAM roles
+// saved once, in policies, and the roles referencing it by ARN
+func (n Network) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.stored())
+}
+
+// stored returns the network as saved in a working state
+func (n Network) stored() storedNetwork {
+	stored := storedNetwork{networkFields: networkFields(n)}
+	// The state is always written in the current schema, whatever it was loaded from
+	stored.SchemaVersion = NetworkSchemaVersion
+	if n.IAMRoles != nil {
+		stored.IAMRoles = make([]storedIAMRole, len(n.IAMRoles))
+	}
+	for i, role := range n.IAMRoles {
+		stored.IAMRoles[i].IAMRole = role
+		for _, policy := range role.AttachedPolicies {
+			stored.IAMRoles[i].AttachedPolicyArns = append(stored.IAMRoles[i].AttachedPolicyArns, policy.Arn)
+			if stored.Policies == nil {
+				stored.Policies = make(map[string]IAMPolicy)
+			}
+			// Roles scanned at different times may hold different copies, the first is kept
+			if _, exists := stored.Policies[policy.Arn]; !exists {
+				stored.Policies[policy.Arn] = policy
+			}
+		}
+	}
+	return stored
+}
+
+// UnmarshalJSON reads a network, migrating states saved with an older schema
+// version and resolving the managed policies its IAM roles reference
+func (n *Network) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	data, err := migrateState(data)
+	if err != nil {
+		return err
+	}
+
+	var stored storedNetwork
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	stored.resolve(n)
+	return nil
+}
+
+// DecodeNetworkStrict reads a network like json.Unmarshal, but fails on fields the
+// working state schema does not have
+func DecodeNetworkStrict(data []byte) (*Network, error) {
+	data, err := migrateState(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored storedNetwork
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&stored); err != nil {
+		return nil, err
+	}
+
+	network := &Network{}
+	stored.resolve(network)
+	return network, nil
+}
+
+// resolve fills in n from the stored network, replacing its roles' policy references with the policies
+func (stored *storedNetwork) resolve(n *Network) {
+	*n = Network(stored.networkFields)
+	n.IAMRoles = nil
+	if stored.IAMRoles != nil {
+		n.IAMRoles = make([]IAMRole, len(stored.IAMRoles))
+	}
+	for i, role := range stored.IAMRoles {
+		n.IAMRoles[i] = role.IAMRole
+		n.IAMRoles[i].AttachedPolicies = nil
+		for _, arn := range role.AttachedPolicyArns {
+			policy, ok := stored.Policies[arn]
+			if !ok {
+				// Keep the reference to a policy missing from the state
+				policy = IAMPolicy{Arn: arn}
+			}
+			n.IAMRoles[i].AttachedPolicies = append(n.IAMRoles[i].AttachedPolicies, policy)
+		}
+	}
+}
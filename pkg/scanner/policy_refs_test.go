@@ -0,0 +1,56 @@
+package scanner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNetworkSharesManagedPolicies(t *testing.T) {
+	shared := IAMPolicy{Arn: "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore", PolicyName: "AmazonSSMManagedInstanceCore", PolicyDocument: `{"Statement":[]}`}
+	network := Network{
+		Region: "us-east-1",
+		IAMRoles: []IAMRole{
+			{ID: "id-a", Name: "role-a", AttachedPolicies: []IAMPolicy{shared}},
+			{ID: "id-b", Name: "role-b", AttachedPolicies: []IAMPolicy{shared, {Arn: "arn:aws:iam::123456789012:policy/app", PolicyName: "app"}}},
+		},
+	}
+
+	data, err := json.Marshal(network)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if count := strings.Count(string(data), `{\"Statement\":[]}`); count != 1 {
+		t.Errorf("Expected the shared policy document saved once, got %d copies in %s", count, data)
+	}
+	if strings.Contains(string(data), `"attached_policies"`) {
+		t.Errorf("Expected roles to reference their policies by ARN, got %s", data)
+	}
+
+	var loaded Network
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if loaded.Region != "us-east-1" || len(loaded.IAMRoles) != 2 {
+		t.Fatalf("Expected the network back, got %+v", loaded)
+	}
+	if policies := loaded.IAMRoles[1].AttachedPolicies; len(policies) != 2 || policies[0].PolicyDocument != shared.PolicyDocument || policies[1].PolicyName != "app" {
+		t.Errorf("Expected role-b's policies resolved, got %+v", policies)
+	}
+}
+
+func TestNetworkLoadsCopiedPolicies(t *testing.T) {
+	data := []byte(`{"region":"us-east-1","iam_roles":[{"id":"id-a","name":"role-a","attached_policies":[{"arn":"arn:aws:iam::aws:policy/ReadOnlyAccess","policy_name":"ReadOnlyAccess"}]}]}`)
+
+	var loaded Network
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(loaded.IAMRoles) != 1 || len(loaded.IAMRoles[0].AttachedPolicies) != 1 || loaded.IAMRoles[0].AttachedPolicies[0].PolicyName != "ReadOnlyAccess" {
+		t.Errorf("Expected the role's copied policy, got %+v", loaded.IAMRoles)
+	}
+
+	if _, err := DecodeNetworkStrict([]byte(`{"region":"us-east-1","unknown":true}`)); err == nil {
+		t.Error("Expected strict decoding to reject an unknown field")
+	}
+}
@@ -0,0 +1,48 @@
+package scanner
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ProtocolNumber returns the IP protocol number of a rule's protocol, or -1 for all protocols
+func ProtocolNumber(protocol string) int {
+	switch strings.ToLower(protocol) {
+	case "-1", "all":
+		return -1
+	case "tcp":
+		return 6
+	case "udp":
+		return 17
+	case "icmp":
+		return 1
+	case "icmpv6":
+		return 58
+	}
+	if n, err := strconv.Atoi(protocol); err == nil {
+		return n
+	}
+	return 0
+}
+
+// AllowsPort reports whether a security group rule allows traffic of a protocol, such as tcp, to a port
+func (r SecurityGroupRule) AllowsPort(protocol string, port int32) bool {
+	switch ProtocolNumber(r.IpProtocol) {
+	case -1:
+		return true
+	case ProtocolNumber(protocol):
+		return r.FromPort <= port && port <= r.ToPort
+	}
+	return false
+}
+
+// AllowsPorts reports whether a network ACL entry matches traffic of a protocol to any port in a range
+func (e NetworkAclEntry) AllowsPorts(protocol string, from, to int32) bool {
+	switch ProtocolNumber(e.Protocol) {
+	case -1:
+		return true
+	case ProtocolNumber(protocol):
+		return e.PortRange == nil || (e.PortRange.From <= to && from <= e.PortRange.To)
+	}
+	return false
+}
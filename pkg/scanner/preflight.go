@@ -0,0 +1,390 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/appmesh"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/elasticache"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// Permission check outcomes
+const (
+	PermissionAllowed = "allowed"
+	PermissionDenied  = "denied"
+	// PermissionUnknown means the call failed for another reason, such as expired
+	// credentials, throttling or a service that is not available in the region
+	PermissionUnknown = "unknown"
+)
+
+// preflightName names the resources that do not exist which calls needing an ID
+// are made with. AWS checks permissions before looking the resource up, so a
+// not-found error shows the call is allowed.
+const preflightName = "pikaatools-preflight"
+
+// PermissionCheck is the outcome of checking one API action the scanner calls
+type PermissionCheck struct {
+	Action string `json:"action"` // IAM action, e.g. "ec2:DescribeVpcs"
+	// Feature is the flag that needs the action, empty when every scan does
+	Feature string `json:"feature,omitempty"`
+	// Optional actions only add detail; scans continue without them
+	Optional bool   `json:"optional,omitempty"`
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"` // Why the action is denied or could not be checked
+}
+
+// preflightCall is one API action and a harmless call that exercises it
+type preflightCall struct {
+	action   string
+	feature  string
+	optional bool
+	call     func(ctx context.Context) error
+}
+
+// Preflight checks every API action a scan with the scanner's settings calls,
+// using the current credentials. EC2 actions are checked with dry-run requests;
+// other services with calls that list at most one resource or look up a resource
+// that does not exist, so nothing is changed.
+func (s *NetworkScanner) Preflight(ctx context.Context) []PermissionCheck {
+	calls := s.preflightCalls()
+	checks := make([]PermissionCheck, 0, len(calls))
+	for _, call := range calls {
+		if !s.featureEnabled(call.feature) {
+			continue
+		}
+		status, message := permissionStatus(call.call(ctx))
+		checks = append(checks, PermissionCheck{
+			Action:   call.action,
+			Feature:  call.feature,
+			Optional: call.optional,
+			Status:   status,
+			Message:  message,
+		})
+	}
+	return checks
+}
+
+// Actions lists the IAM actions a scan with the scanner's settings calls, sorted
+func (s *NetworkScanner) Actions() []string {
+	var actions []string
+	seen := make(map[string]bool)
+	for _, call := range s.preflightCalls() {
+		if !s.featureEnabled(call.feature) || seen[call.action] {
+			continue
+		}
+		seen[call.action] = true
+		actions = append(actions, call.action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// featureEnabled reports whether the scanner scans the resources of a feature
+// flag, of any of the flags of "--a or --b", or of all of the flags of "--a and --b"
+func (s *NetworkScanner) featureEnabled(feature string) bool {
+	if alternatives := strings.Split(feature, " or "); len(alternatives) > 1 {
+		for _, alternative := range alternatives {
+			if s.featureEnabled(alternative) {
+				return true
+			}
+		}
+		return false
+	}
+	if flags := strings.Split(feature, " and "); len(flags) > 1 {
+		for _, flag := range flags {
+			if !s.featureEnabled(flag) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch feature {
+	case "--with-iam":
+		return s.scanIAM
+	case "--containers":
+		return s.scanContainers
+	case "--databases":
+		return s.scanDatabases
+	case "--edge":
+		return s.scanEdge
+	case "--app-mesh":
+		return s.scanAppMesh
+	}
+	return true
+}
+
+// permissionStatus classifies the error of a preflight call
+func permissionStatus(err error) (string, string) {
+	if err == nil {
+		return PermissionAllowed, ""
+	}
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return PermissionUnknown, err.Error()
+	}
+	switch apiErr.ErrorCode() {
+	case "DryRunOperation":
+		return PermissionAllowed, ""
+	case "UnauthorizedOperation", "AccessDenied", "AccessDeniedException", "AuthorizationError", "UnauthorizedException":
+		return PermissionDenied, apiErr.ErrorMessage()
+	case "InvalidClientTokenId", "ExpiredToken", "ExpiredTokenException", "UnrecognizedClientException", "AuthFailure",
+		"Throttling", "ThrottlingException", "RequestLimitExceeded", "OptInRequired", "SubscriptionRequiredException":
+		return PermissionUnknown, apiErr.ErrorMessage()
+	}
+	// The call was authorized and failed on the made-up resource it named
+	return PermissionAllowed, ""
+}
+
+// preflightCalls lists the API actions the scanner calls, in scan order
+func (s *NetworkScanner) preflightCalls() []preflightCall {
+	dryRun := awssdk.Bool(true)
+	missingID := func(prefix string) *string { return awssdk.String(prefix + "-00000000000000000") }
+
+	return []preflightCall{
+		{action: "sts:GetCallerIdentity", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.STS.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+			return err
+		}},
+
+		// Every scan
+		{action: "ec2:DescribeVpcs", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeDhcpOptions", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeDhcpOptions(ctx, &ec2.DescribeDhcpOptionsInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeSubnets", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:GetSubnetCidrReservations", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.GetSubnetCidrReservations(ctx, &ec2.GetSubnetCidrReservationsInput{DryRun: dryRun, SubnetId: missingID("subnet")})
+			return err
+		}},
+		{action: "ec2:DescribeVpcPeeringConnections", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeVpcPeeringConnections(ctx, &ec2.DescribeVpcPeeringConnectionsInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeTransitGateways", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeTransitGateways(ctx, &ec2.DescribeTransitGatewaysInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeTransitGatewayAttachments", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeTransitGatewayAttachments(ctx, &ec2.DescribeTransitGatewayAttachmentsInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeTransitGatewayPeeringAttachments", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeTransitGatewayPeeringAttachments(ctx, &ec2.DescribeTransitGatewayPeeringAttachmentsInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeTransitGatewayRouteTables", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeTransitGatewayRouteTables(ctx, &ec2.DescribeTransitGatewayRouteTablesInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:SearchTransitGatewayRoutes", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.SearchTransitGatewayRoutes(ctx, &ec2.SearchTransitGatewayRoutesInput{
+				DryRun:                     dryRun,
+				TransitGatewayRouteTableId: missingID("tgw-rtb"),
+				Filters:                    []types.Filter{{Name: awssdk.String("state"), Values: []string{"active"}}},
+			})
+			return err
+		}},
+		{action: "ec2:DescribeInternetGateways", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeEgressOnlyInternetGateways", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeEgressOnlyInternetGateways(ctx, &ec2.DescribeEgressOnlyInternetGatewaysInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeNatGateways", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeNatGateways(ctx, &ec2.DescribeNatGatewaysInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeRouteTables", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeSecurityGroups", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeSecurityGroupRules", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeSecurityGroupRules(ctx, &ec2.DescribeSecurityGroupRulesInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeNetworkAcls", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeNetworkAcls(ctx, &ec2.DescribeNetworkAclsInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeFlowLogs", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeFlowLogs(ctx, &ec2.DescribeFlowLogsInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeNetworkInterfaces", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeVpcEndpointServiceConfigurations", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeVpcEndpointServiceConfigurations(ctx, &ec2.DescribeVpcEndpointServiceConfigurationsInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "ec2:DescribeVpcEndpointServicePermissions", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeVpcEndpointServicePermissions(ctx, &ec2.DescribeVpcEndpointServicePermissionsInput{DryRun: dryRun, ServiceId: missingID("vpce-svc")})
+			return err
+		}},
+		{action: "ec2:DescribeVpcEndpoints", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeVpcEndpoints(ctx, &ec2.DescribeVpcEndpointsInput{DryRun: dryRun})
+			return err
+		}},
+
+		// --with-iam
+		{action: "iam:ListRoles", feature: "--with-iam", call: func(ctx context.Context) error {
+			_, err := s.apis.IAM.ListRoles(ctx, &iam.ListRolesInput{MaxItems: awssdk.Int32(1)})
+			return err
+		}},
+		{action: "iam:ListAttachedRolePolicies", feature: "--with-iam", call: func(ctx context.Context) error {
+			_, err := s.apis.IAM.ListAttachedRolePolicies(ctx, &iam.ListAttachedRolePoliciesInput{RoleName: awssdk.String(preflightName)})
+			return err
+		}},
+		{action: "iam:ListRolePolicies", feature: "--with-iam", call: func(ctx context.Context) error {
+			_, err := s.apis.IAM.ListRolePolicies(ctx, &iam.ListRolePoliciesInput{RoleName: awssdk.String(preflightName)})
+			return err
+		}},
+		{action: "iam:GetRolePolicy", feature: "--with-iam", call: func(ctx context.Context) error {
+			_, err := s.apis.IAM.GetRolePolicy(ctx, &iam.GetRolePolicyInput{RoleName: awssdk.String(preflightName), PolicyName: awssdk.String(preflightName)})
+			return err
+		}},
+		{action: "iam:GetPolicy", feature: "--with-iam", call: func(ctx context.Context) error {
+			_, err := s.apis.IAM.GetPolicy(ctx, &iam.GetPolicyInput{PolicyArn: awssdk.String("arn:aws:iam::aws:policy/" + preflightName)})
+			return err
+		}},
+		{action: "iam:GetPolicyVersion", feature: "--with-iam", call: func(ctx context.Context) error {
+			_, err := s.apis.IAM.GetPolicyVersion(ctx, &iam.GetPolicyVersionInput{PolicyArn: awssdk.String("arn:aws:iam::aws:policy/" + preflightName), VersionId: awssdk.String("v1")})
+			return err
+		}},
+		{action: "iam:ListInstanceProfiles", feature: "--with-iam", optional: true, call: func(ctx context.Context) error {
+			_, err := s.apis.IAM.ListInstanceProfiles(ctx, &iam.ListInstanceProfilesInput{MaxItems: awssdk.Int32(1)})
+			return err
+		}},
+
+		// --containers
+		{action: "eks:ListClusters", feature: "--containers", call: func(ctx context.Context) error {
+			_, err := s.apis.EKS.ListClusters(ctx, &eks.ListClustersInput{MaxResults: awssdk.Int32(1)})
+			return err
+		}},
+		{action: "eks:DescribeCluster", feature: "--containers", call: func(ctx context.Context) error {
+			_, err := s.apis.EKS.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: awssdk.String(preflightName)})
+			return err
+		}},
+		{action: "ecs:ListClusters", feature: "--containers or --app-mesh", call: func(ctx context.Context) error {
+			_, err := s.apis.ECS.ListClusters(ctx, &ecs.ListClustersInput{MaxResults: awssdk.Int32(1)})
+			return err
+		}},
+		{action: "ecs:ListServices", feature: "--containers or --app-mesh", call: func(ctx context.Context) error {
+			_, err := s.apis.ECS.ListServices(ctx, &ecs.ListServicesInput{Cluster: awssdk.String(preflightName)})
+			return err
+		}},
+		{action: "ecs:DescribeServices", feature: "--containers or --app-mesh", call: func(ctx context.Context) error {
+			_, err := s.apis.ECS.DescribeServices(ctx, &ecs.DescribeServicesInput{Cluster: awssdk.String(preflightName), Services: []string{preflightName}})
+			return err
+		}},
+		{action: "ecs:DescribeTaskDefinition", feature: "--app-mesh or --containers and --with-iam", call: func(ctx context.Context) error {
+			_, err := s.apis.ECS.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{TaskDefinition: awssdk.String(preflightName)})
+			return err
+		}},
+
+		// --databases
+		{action: "rds:DescribeDBInstances", feature: "--databases", call: func(ctx context.Context) error {
+			_, err := s.apis.RDS.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{MaxRecords: awssdk.Int32(20)})
+			return err
+		}},
+		{action: "rds:DescribeDBClusters", feature: "--databases", call: func(ctx context.Context) error {
+			_, err := s.apis.RDS.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{MaxRecords: awssdk.Int32(20)})
+			return err
+		}},
+		{action: "rds:DescribeDBSubnetGroups", feature: "--databases", call: func(ctx context.Context) error {
+			_, err := s.apis.RDS.DescribeDBSubnetGroups(ctx, &rds.DescribeDBSubnetGroupsInput{MaxRecords: awssdk.Int32(20)})
+			return err
+		}},
+		{action: "elasticache:DescribeCacheClusters", feature: "--databases", call: func(ctx context.Context) error {
+			_, err := s.apis.ElastiCache.DescribeCacheClusters(ctx, &elasticache.DescribeCacheClustersInput{MaxRecords: awssdk.Int32(20)})
+			return err
+		}},
+		{action: "elasticache:DescribeCacheSubnetGroups", feature: "--databases", call: func(ctx context.Context) error {
+			_, err := s.apis.ElastiCache.DescribeCacheSubnetGroups(ctx, &elasticache.DescribeCacheSubnetGroupsInput{MaxRecords: awssdk.Int32(20)})
+			return err
+		}},
+		{action: "redshift:DescribeClusters", feature: "--databases", call: func(ctx context.Context) error {
+			_, err := s.apis.Redshift.DescribeClusters(ctx, &redshift.DescribeClustersInput{MaxRecords: awssdk.Int32(20)})
+			return err
+		}},
+		{action: "redshift:DescribeClusterSubnetGroups", feature: "--databases", call: func(ctx context.Context) error {
+			_, err := s.apis.Redshift.DescribeClusterSubnetGroups(ctx, &redshift.DescribeClusterSubnetGroupsInput{MaxRecords: awssdk.Int32(20)})
+			return err
+		}},
+
+		// --edge
+		{action: "ec2:DescribeAddresses", feature: "--edge", call: func(ctx context.Context) error {
+			_, err := s.apis.EC2.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{DryRun: dryRun})
+			return err
+		}},
+		{action: "elasticloadbalancing:DescribeLoadBalancers", feature: "--edge", call: func(ctx context.Context) error {
+			_, err := s.apis.ELBv2.DescribeLoadBalancers(ctx, &elasticloadbalancingv2.DescribeLoadBalancersInput{PageSize: awssdk.Int32(1)})
+			return err
+		}},
+		{action: "globalaccelerator:ListAccelerators", feature: "--edge", call: func(ctx context.Context) error {
+			_, err := s.apis.GlobalAccelerator.ListAccelerators(ctx, &globalaccelerator.ListAcceleratorsInput{MaxResults: awssdk.Int32(1)})
+			return err
+		}},
+		{action: "globalaccelerator:ListListeners", feature: "--edge", call: func(ctx context.Context) error {
+			_, err := s.apis.GlobalAccelerator.ListListeners(ctx, &globalaccelerator.ListListenersInput{AcceleratorArn: awssdk.String("arn:aws:globalaccelerator::000000000000:accelerator/" + preflightName)})
+			return err
+		}},
+		{action: "globalaccelerator:ListEndpointGroups", feature: "--edge", call: func(ctx context.Context) error {
+			_, err := s.apis.GlobalAccelerator.ListEndpointGroups(ctx, &globalaccelerator.ListEndpointGroupsInput{ListenerArn: awssdk.String("arn:aws:globalaccelerator::000000000000:accelerator/" + preflightName + "/listener/" + preflightName)})
+			return err
+		}},
+		{action: "cloudfront:ListDistributions", feature: "--edge", call: func(ctx context.Context) error {
+			_, err := s.apis.CloudFront.ListDistributions(ctx, &cloudfront.ListDistributionsInput{MaxItems: awssdk.Int32(1)})
+			return err
+		}},
+		{action: "cloudfront:ListVpcOrigins", feature: "--edge", call: func(ctx context.Context) error {
+			_, err := s.apis.CloudFront.ListVpcOrigins(ctx, &cloudfront.ListVpcOriginsInput{MaxItems: awssdk.Int32(1)})
+			return err
+		}},
+
+		// --app-mesh
+		{action: "appmesh:ListMeshes", feature: "--app-mesh", call: func(ctx context.Context) error {
+			_, err := s.apis.AppMesh.ListMeshes(ctx, &appmesh.ListMeshesInput{Limit: awssdk.Int32(1)})
+			return err
+		}},
+		{action: "appmesh:ListVirtualGateways", feature: "--app-mesh", call: func(ctx context.Context) error {
+			_, err := s.apis.AppMesh.ListVirtualGateways(ctx, &appmesh.ListVirtualGatewaysInput{MeshName: awssdk.String(preflightName)})
+			return err
+		}},
+		{action: "appmesh:DescribeVirtualGateway", feature: "--app-mesh", call: func(ctx context.Context) error {
+			_, err := s.apis.AppMesh.DescribeVirtualGateway(ctx, &appmesh.DescribeVirtualGatewayInput{MeshName: awssdk.String(preflightName), VirtualGatewayName: awssdk.String(preflightName)})
+			return err
+		}},
+	}
+}
@@ -0,0 +1,112 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner/scannertest"
+)
+
+func TestPreflight(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.Errors = map[string]error{
+		"DescribeVpcs":     &smithy.GenericAPIError{Code: "DryRunOperation", Message: "Request would have succeeded, but DryRun flag is set."},
+		"DescribeFlowLogs": &smithy.GenericAPIError{Code: "UnauthorizedOperation", Message: "You are not authorized to perform this operation."},
+	}
+	fakeIAM := &scannertest.FakeIAM{Errors: map[string]error{
+		"ListRoles":     &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized to perform: iam:ListRoles"},
+		"GetRolePolicy": &smithy.GenericAPIError{Code: "NoSuchEntity", Message: "The role cannot be found."},
+	}}
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{
+		EC2:               fakeEC2,
+		IAM:               fakeIAM,
+		STS:               &scannertest.FakeSTS{Err: errors.New("dial tcp: i/o timeout")},
+		AppMesh:           &scannertest.FakeAppMesh{},
+		EKS:               &scannertest.FakeEKS{},
+		ECS:               &scannertest.FakeECS{},
+		RDS:               &scannertest.FakeRDS{},
+		ElastiCache:       &scannertest.FakeElastiCache{},
+		Redshift:          &scannertest.FakeRedshift{},
+		ELBv2:             &scannertest.FakeELBv2{},
+		GlobalAccelerator: &scannertest.FakeGlobalAccelerator{},
+		CloudFront:        &scannertest.FakeCloudFront{},
+	})
+
+	// Only the actions of enabled features are checked
+	for _, check := range s.Preflight(context.Background()) {
+		if check.Feature != "" {
+			t.Errorf("Expected no feature checks without features, got %s", check.Action)
+		}
+	}
+
+	s.SetScanIAM(true)
+	s.SetScanContainers(true)
+	s.SetScanDatabases(true)
+	s.SetScanEdge(true)
+	s.SetScanAppMesh(true)
+	checks := make(map[string]PermissionCheck)
+	for _, check := range s.Preflight(context.Background()) {
+		checks[check.Action] = check
+	}
+
+	expected := map[string]string{
+		"ec2:DescribeVpcs":      PermissionAllowed,
+		"ec2:DescribeSubnets":   PermissionAllowed,
+		"ec2:DescribeFlowLogs":  PermissionDenied,
+		"iam:ListRoles":         PermissionDenied,
+		"iam:GetRolePolicy":     PermissionAllowed,
+		"sts:GetCallerIdentity": PermissionUnknown,
+	}
+	for action, status := range expected {
+		if checks[action].Status != status {
+			t.Errorf("Expected %s to be %s, got %+v", action, status, checks[action])
+		}
+	}
+
+	if check := checks["ec2:DescribeFlowLogs"]; !check.Optional || check.Message != "You are not authorized to perform this operation." {
+		t.Errorf("Expected an optional flow log check with the denial message, got %+v", check)
+	}
+	if check := checks["iam:ListRoles"]; check.Feature != "--with-iam" || check.Optional {
+		t.Errorf("Expected ListRoles to be required by --with-iam, got %+v", check)
+	}
+	if _, ok := checks["cloudfront:ListVpcOrigins"]; !ok || len(checks) < 50 {
+		t.Errorf("Expected every scanner action to be checked, got %d", len(checks))
+	}
+}
+
+func TestActions(t *testing.T) {
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{})
+	actions := s.Actions()
+	if len(actions) == 0 || actions[0] != "ec2:DescribeDhcpOptions" {
+		t.Fatalf("Expected sorted scan actions, got %v", actions)
+	}
+	for _, action := range actions {
+		if action == "iam:ListRoles" || action == "eks:ListClusters" {
+			t.Errorf("Expected no actions of disabled features, got %s", action)
+		}
+	}
+
+	// App Mesh also reads the ECS services running the virtual gateways
+	s.SetScanAppMesh(true)
+	expected := []string{"appmesh:DescribeVirtualGateway", "appmesh:ListMeshes", "appmesh:ListVirtualGateways"}
+	if withMesh := s.Actions(); !reflect.DeepEqual(withMesh[:3], expected) || len(withMesh) != len(actions)+7 {
+		t.Errorf("Expected the App Mesh actions to be added, got %v", withMesh)
+	}
+
+	// Task definitions are read for the task roles only when both containers and IAM roles are scanned
+	s.SetScanAppMesh(false)
+	s.SetScanContainers(true)
+	for _, scanIAM := range []bool{false, true} {
+		s.SetScanIAM(scanIAM)
+		found := false
+		for _, action := range s.Actions() {
+			found = found || action == "ecs:DescribeTaskDefinition"
+		}
+		if found != scanIAM {
+			t.Errorf("With IAM scanning %v, expected ecs:DescribeTaskDefinition %v, got %v", scanIAM, scanIAM, found)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package scanner
+
+// Relink derives subnet route tables, network ACLs and types and the VPC associations
+// again, for networks whose resources were added, changed or removed after the scan
+func (n *Network) Relink() {
+	for i := range n.VPCs {
+		vpc := &n.VPCs[i]
+		vpc.Subnets = nil
+		vpc.SecurityGroups = nil
+		vpc.InternetGateways = nil
+		vpc.EgressOnlyInternetGateways = nil
+		vpc.NATGateways = nil
+		vpc.NetworkAcls = nil
+	}
+	for i := range n.Subnets {
+		n.Subnets[i].RouteTableID = ""
+		n.Subnets[i].NetworkAclID = ""
+	}
+
+	updateSubnetTypes(n)
+	updateVPCAssociations(n)
+	n.Sort()
+}
@@ -0,0 +1,1301 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	meshTypes "github.com/aws/aws-sdk-go-v2/service/appmesh/types"
+	cfTypes "github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	eksTypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	cacheTypes "github.com/aws/aws-sdk-go-v2/service/elasticache/types"
+	elbTypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	gaTypes "github.com/aws/aws-sdk-go-v2/service/globalaccelerator/types"
+	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	redshiftTypes "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner/scannertest"
+)
+
+var (
+	_ EC2API     = (*scannertest.FakeEC2)(nil)
+	_ IAMAPI     = (*scannertest.FakeIAM)(nil)
+	_ STSAPI     = (*scannertest.FakeSTS)(nil)
+	_ AppMeshAPI = (*scannertest.FakeAppMesh)(nil)
+	_ EKSAPI     = (*scannertest.FakeEKS)(nil)
+	_ ECSAPI     = (*scannertest.FakeECS)(nil)
+
+	_ RDSAPI         = (*scannertest.FakeRDS)(nil)
+	_ ElastiCacheAPI = (*scannertest.FakeElastiCache)(nil)
+	_ RedshiftAPI    = (*scannertest.FakeRedshift)(nil)
+
+	_ ELBv2API             = (*scannertest.FakeELBv2)(nil)
+	_ GlobalAcceleratorAPI = (*scannertest.FakeGlobalAccelerator)(nil)
+	_ CloudFrontAPI        = (*scannertest.FakeCloudFront)(nil)
+)
+
+// newFakeEC2 returns two VPCs: vpc-prod with a public and a private subnet, and vpc-dev with one subnet
+func newFakeEC2() *scannertest.FakeEC2 {
+	return &scannertest.FakeEC2{
+		Vpcs: []types.Vpc{
+			{VpcId: awssdk.String("vpc-prod"), CidrBlock: awssdk.String("10.0.0.0/16"), DhcpOptionsId: awssdk.String("dopt-1"),
+				Tags: []types.Tag{{Key: awssdk.String("Environment"), Value: awssdk.String("prod")}}},
+			{VpcId: awssdk.String("vpc-dev"), CidrBlock: awssdk.String("10.1.0.0/16"), DhcpOptionsId: awssdk.String("dopt-1"),
+				Tags: []types.Tag{{Key: awssdk.String("Environment"), Value: awssdk.String("dev")}}},
+		},
+		Subnets: []types.Subnet{
+			{SubnetId: awssdk.String("subnet-public"), VpcId: awssdk.String("vpc-prod"), CidrBlock: awssdk.String("10.0.1.0/24"), AvailabilityZone: awssdk.String("us-east-1a"),
+				AvailableIpAddressCount: awssdk.Int32(200)},
+			{SubnetId: awssdk.String("subnet-private"), VpcId: awssdk.String("vpc-prod"), CidrBlock: awssdk.String("10.0.2.0/24"), AvailabilityZone: awssdk.String("us-east-1a")},
+			{SubnetId: awssdk.String("subnet-dev"), VpcId: awssdk.String("vpc-dev"), CidrBlock: awssdk.String("10.1.1.0/24"), AvailabilityZone: awssdk.String("us-east-1b")},
+		},
+		InternetGateways: []types.InternetGateway{
+			{InternetGatewayId: awssdk.String("igw-prod"), Attachments: []types.InternetGatewayAttachment{{VpcId: awssdk.String("vpc-prod")}}},
+		},
+		RouteTables: []types.RouteTable{
+			{RouteTableId: awssdk.String("rtb-main"), VpcId: awssdk.String("vpc-prod"),
+				Associations: []types.RouteTableAssociation{{Main: awssdk.Bool(true)}}},
+			{RouteTableId: awssdk.String("rtb-public"), VpcId: awssdk.String("vpc-prod"),
+				Associations: []types.RouteTableAssociation{{SubnetId: awssdk.String("subnet-public")}},
+				Routes:       []types.Route{{DestinationCidrBlock: awssdk.String("0.0.0.0/0"), GatewayId: awssdk.String("igw-prod")}}},
+		},
+		VpcPeeringConnections: []types.VpcPeeringConnection{
+			{VpcPeeringConnectionId: awssdk.String("pcx-1"),
+				RequesterVpcInfo: &types.VpcPeeringConnectionVpcInfo{VpcId: awssdk.String("vpc-prod"), Region: awssdk.String("us-east-1")},
+				AccepterVpcInfo:  &types.VpcPeeringConnectionVpcInfo{VpcId: awssdk.String("vpc-other"), Region: awssdk.String("eu-west-1")},
+				Status:           &types.VpcPeeringConnectionStateReason{Code: types.VpcPeeringConnectionStateReasonCodeActive}},
+		},
+	}
+}
+
+// vpcByID returns a scanned VPC by its ID
+func vpcByID(network *Network, id string) VPC {
+	for _, vpc := range network.VPCs {
+		if vpc.ID == id {
+			return vpc
+		}
+	}
+	return VPC{}
+}
+
+func TestScanNetworkWithFakes(t *testing.T) {
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{
+		EC2: newFakeEC2(),
+		STS: &scannertest.FakeSTS{Account: "123456789012"},
+	})
+
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if network.Region != "us-east-1" || network.AccountID != "123456789012" {
+		t.Errorf("Expected region and account to be recorded, got %q and %q", network.Region, network.AccountID)
+	}
+	if len(network.VPCs) != 2 || len(network.Subnets) != 3 {
+		t.Fatalf("Expected 2 VPCs and 3 subnets, got %d and %d", len(network.VPCs), len(network.Subnets))
+	}
+	if len(network.IAMRoles) != 0 {
+		t.Errorf("Expected IAM roles not to be scanned by default, got %d", len(network.IAMRoles))
+	}
+
+	subnetTypes := make(map[string]string)
+	for _, subnet := range network.Subnets {
+		subnetTypes[subnet.ID] = subnet.Type
+		if subnet.ID == "subnet-public" && (subnet.AvailableIPs == nil || *subnet.AvailableIPs != 200 || subnet.TotalIPs != 251) {
+			t.Errorf("Expected 200 of 251 IPs available in subnet-public, got %v of %d", subnet.AvailableIPs, subnet.TotalIPs)
+		}
+	}
+	if subnetTypes["subnet-public"] != "public" || subnetTypes["subnet-private"] != "isolated" {
+		t.Errorf("Expected subnet types from route tables, got %v", subnetTypes)
+	}
+
+	if prod := vpcByID(network, "vpc-prod"); len(prod.Subnets) != 2 || len(prod.InternetGateways) != 1 {
+		t.Errorf("Expected VPC associations to be filled, got %+v", prod)
+	}
+
+	if len(network.PeeringConnections) != 1 || network.PeeringConnections[0].AccepterRegion != "eu-west-1" {
+		t.Errorf("Expected the cross-region peering connection, got %+v", network.PeeringConnections)
+	}
+}
+
+func TestRescanNetwork(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}})
+
+	previous, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Route the private subnet through the internet gateway and add a subnet
+	fakeEC2.RouteTables = append(fakeEC2.RouteTables, types.RouteTable{RouteTableId: awssdk.String("rtb-private"), VpcId: awssdk.String("vpc-prod"),
+		Associations: []types.RouteTableAssociation{{SubnetId: awssdk.String("subnet-private")}},
+		Routes:       []types.Route{{DestinationCidrBlock: awssdk.String("0.0.0.0/0"), GatewayId: awssdk.String("igw-prod")}}})
+	fakeEC2.Subnets = append(fakeEC2.Subnets, types.Subnet{SubnetId: awssdk.String("subnet-new"), VpcId: awssdk.String("vpc-dev"),
+		CidrBlock: awssdk.String("10.1.2.0/24"), AvailabilityZone: awssdk.String("us-east-1b")})
+	fakeEC2.Errors = map[string]error{"DescribeInternetGateways": errors.New("not rescanned")}
+
+	network, err := s.RescanNetwork(context.Background(), "", previous, []string{"route_table"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.RouteTables) != 3 || len(network.InternetGateways) != 1 {
+		t.Fatalf("Expected rescanned route tables and reused internet gateways, got %d and %d",
+			len(network.RouteTables), len(network.InternetGateways))
+	}
+	if len(network.Subnets) != 3 {
+		t.Errorf("Expected subnets to be reused, got %d", len(network.Subnets))
+	}
+	for _, subnet := range network.Subnets {
+		if subnet.ID == "subnet-private" && subnet.Type != "public" {
+			t.Errorf("Expected subnet types to be updated from the rescanned route tables, got %q", subnet.Type)
+		}
+	}
+	for _, subnet := range previous.Subnets {
+		if subnet.ID == "subnet-private" && subnet.Type != "isolated" {
+			t.Errorf("Expected the previous scan to be unchanged, got %q", subnet.Type)
+		}
+	}
+	if prod := vpcByID(network, "vpc-prod"); len(prod.Subnets) != 2 || len(prod.InternetGateways) != 1 {
+		t.Errorf("Expected VPC associations from reused resources, got %+v", prod)
+	}
+}
+
+type recordedProgress struct {
+	resourceTypes []string
+	done, total   int
+}
+
+func (r *recordedProgress) Scanned(resourceType string, done, total int) {
+	r.resourceTypes = append(r.resourceTypes, resourceType)
+	r.done, r.total = done, total
+}
+
+func TestScanProgressAndLogging(t *testing.T) {
+	fakeEKS := &scannertest.FakeEKS{Errors: map[string]error{"ListClusters": errors.New("denied")}}
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: newFakeEC2(), STS: &scannertest.FakeSTS{}, EKS: fakeEKS, ECS: &scannertest.FakeECS{}})
+	s.SetScanContainers(true)
+
+	var logs strings.Builder
+	s.SetLogger(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	progress := &recordedProgress{}
+	s.SetProgress(progress)
+
+	if _, err := s.ScanNetwork(context.Background(), ""); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if progress.total != 17 || progress.done != progress.total || len(progress.resourceTypes) != progress.total {
+		t.Errorf("Expected progress for 17 resource types, got %d of %d: %v", progress.done, progress.total, progress.resourceTypes)
+	}
+	if !strings.Contains(logs.String(), `"msg":"failed to scan EKS clusters"`) {
+		t.Errorf("Expected a warning for the EKS failure, got %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), `"resource_type":"security_group"`) {
+		t.Errorf("Expected per-resource-type debug records, got %s", logs.String())
+	}
+}
+
+func TestScanNetworkTagFilter(t *testing.T) {
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{
+		EC2: newFakeEC2(),
+		STS: &scannertest.FakeSTS{},
+	})
+	s.SetTagFilters(map[string][]string{"Environment": {"dev"}})
+
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.VPCs) != 1 || network.VPCs[0].ID != "vpc-dev" {
+		t.Fatalf("Expected only vpc-dev, got %+v", network.VPCs)
+	}
+	if len(network.Subnets) != 1 || len(network.InternetGateways) != 0 || len(network.PeeringConnections) != 0 {
+		t.Errorf("Expected only resources of vpc-dev, got %d subnets, %d internet gateways, %d peering connections",
+			len(network.Subnets), len(network.InternetGateways), len(network.PeeringConnections))
+	}
+}
+
+func TestScanNetworkIAMRoles(t *testing.T) {
+	trust := func(service string) *string {
+		return awssdk.String(`{"Statement":[{"Effect":"Allow","Principal":{"Service":"` + service + `"},"Action":"sts:AssumeRole"}]}`)
+	}
+	role := func(name, service string) iamTypes.Role {
+		return iamTypes.Role{
+			RoleId: awssdk.String("id-" + name), RoleName: awssdk.String(name), Path: awssdk.String("/"),
+			Arn: awssdk.String("arn:aws:iam::123456789012:role/" + name), CreateDate: awssdk.Time(time.Now()),
+			AssumeRolePolicyDocument: trust(service),
+		}
+	}
+	policyArn := "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"
+
+	fakeIAM := &scannertest.FakeIAM{
+		Roles: []iamTypes.Role{
+			role("web", "ec2.amazonaws.com"),
+			role("etl", "glue.amazonaws.com"),
+			role("handler", "lambda.amazonaws.com"),
+		},
+		AttachedPolicies: map[string][]iamTypes.AttachedPolicy{
+			"web": {{PolicyArn: awssdk.String(policyArn), PolicyName: awssdk.String("AmazonSSMManagedInstanceCore")}},
+		},
+		Policies: []iamTypes.Policy{
+			{Arn: awssdk.String(policyArn), PolicyName: awssdk.String("AmazonSSMManagedInstanceCore"), PolicyId: awssdk.String("ANPA1"),
+				Path: awssdk.String("/"), DefaultVersionId: awssdk.String("v2"), CreateDate: awssdk.Time(time.Now()), UpdateDate: awssdk.Time(time.Now())},
+		},
+		PolicyDocuments: map[string]string{policyArn: `%7B%22Statement%22%3A%5B%5D%7D`},
+		InlinePolicies:  map[string]map[string]string{"handler": {"logs": `{"Statement":[]}`}},
+		InstanceProfiles: []iamTypes.InstanceProfile{
+			{InstanceProfileId: awssdk.String("AIPA1"), InstanceProfileName: awssdk.String("web-profile"), Path: awssdk.String("/"),
+				Arn: awssdk.String("arn:aws:iam::123456789012:instance-profile/web-profile"), CreateDate: awssdk.Time(time.Now()),
+				Roles: []iamTypes.Role{role("web", "ec2.amazonaws.com")}},
+		},
+		PageSize: 1,
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: newFakeEC2(), IAM: fakeIAM, STS: &scannertest.FakeSTS{}})
+	s.SetScanIAM(true)
+
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.IAMRoles) != 2 || network.IAMRoles[0].Name != "handler" || network.IAMRoles[1].Name != "web" {
+		t.Fatalf("Expected the EC2 and Lambda roles across all pages, got %+v", network.IAMRoles)
+	}
+	web := network.IAMRoles[1]
+	if len(web.AttachedPolicies) != 1 || web.AttachedPolicies[0].PolicyDocument != `{"Statement":[]}` {
+		t.Errorf("Expected the decoded managed policy document, got %+v", web.AttachedPolicies)
+	}
+	if len(network.IAMRoles[0].InlinePolicies) != 1 {
+		t.Errorf("Expected the inline policy, got %+v", network.IAMRoles[0].InlinePolicies)
+	}
+	if len(web.InstanceProfiles) != 1 || web.InstanceProfiles[0].Name != "web-profile" || len(network.IAMRoles[0].InstanceProfiles) != 0 {
+		t.Errorf("Expected web-profile on the web role only, got %+v and %+v", web.InstanceProfiles, network.IAMRoles[0].InstanceProfiles)
+	}
+
+	// Roles are kept when instance profiles cannot be listed
+	fakeIAM.Errors = map[string]error{"ListInstanceProfiles": errors.New("AccessDenied")}
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil || len(network.IAMRoles) != 2 || len(network.IAMRoles[1].InstanceProfiles) != 0 {
+		t.Fatalf("Expected the roles without instance profiles, got %+v, %v", network.IAMRoles, err)
+	}
+	fakeIAM.Errors = nil
+
+	s.SetAllIAMRoles(true)
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.IAMRoles) != 3 {
+		t.Errorf("Expected every role with all roles requested, got %d", len(network.IAMRoles))
+	}
+}
+
+func TestScanIAMRolesReferencedByResources(t *testing.T) {
+	role := func(name, service string) iamTypes.Role {
+		return iamTypes.Role{
+			RoleId: awssdk.String("id-" + name), RoleName: awssdk.String(name), Path: awssdk.String("/"),
+			Arn: awssdk.String("arn:aws:iam::123456789012:role/" + name), CreateDate: awssdk.Time(time.Now()),
+			AssumeRolePolicyDocument: awssdk.String(`{"Statement":[{"Effect":"Allow","Principal":{"Service":"` + service + `"},"Action":"sts:AssumeRole"}]}`),
+		}
+	}
+
+	// Only eks-node is a workload role by its trust policy
+	fakeIAM := &scannertest.FakeIAM{
+		Roles: []iamTypes.Role{
+			role("eks-node", "pods.eks.amazonaws.com"),
+			role("bastion", "ssm.amazonaws.com"),
+			role("api-task", "glue.amazonaws.com"),
+			role("api-execution", "glue.amazonaws.com"),
+			role("etl", "glue.amazonaws.com"),
+		},
+		InstanceProfiles: []iamTypes.InstanceProfile{
+			{InstanceProfileId: awssdk.String("AIPA1"), InstanceProfileName: awssdk.String("bastion"), Roles: []iamTypes.Role{role("bastion", "ssm.amazonaws.com")}},
+		},
+	}
+	clusterArn := "arn:aws:ecs:us-east-1:123456789012:cluster/apps"
+	fakeECS := &scannertest.FakeECS{
+		ClusterArns: []string{clusterArn},
+		Services: []ecsTypes.Service{{
+			ServiceArn: awssdk.String("arn:aws:ecs:us-east-1:123456789012:service/apps/api"), ServiceName: awssdk.String("api"),
+			ClusterArn: awssdk.String(clusterArn), TaskDefinition: awssdk.String("arn:aws:ecs:us-east-1:123456789012:task-definition/api:1"),
+			NetworkConfiguration: &ecsTypes.NetworkConfiguration{AwsvpcConfiguration: &ecsTypes.AwsVpcConfiguration{Subnets: []string{"subnet-private"}}},
+		}},
+		TaskDefinitions: []ecsTypes.TaskDefinition{{
+			TaskDefinitionArn: awssdk.String("arn:aws:ecs:us-east-1:123456789012:task-definition/api:1"),
+			TaskRoleArn:       awssdk.String("arn:aws:iam::123456789012:role/api-task"),
+			ExecutionRoleArn:  awssdk.String("api-execution"),
+		}},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: newFakeEC2(), IAM: fakeIAM, STS: &scannertest.FakeSTS{}, EKS: &scannertest.FakeEKS{}, ECS: fakeECS})
+	s.SetScanIAM(true)
+	s.SetScanContainers(true)
+
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var names []string
+	for _, role := range network.IAMRoles {
+		names = append(names, role.Name)
+	}
+	if got := strings.Join(names, ","); got != "api-execution,api-task,bastion,eks-node" {
+		t.Errorf("Expected the EKS, instance profile and ECS task roles, got %s", got)
+	}
+}
+
+func TestScanCache(t *testing.T) {
+	policyArn := "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"
+	fakeIAM := &scannertest.FakeIAM{
+		Roles: []iamTypes.Role{
+			{RoleId: awssdk.String("id-web"), RoleName: awssdk.String("web"), Path: awssdk.String("/"),
+				Arn: awssdk.String("arn:aws:iam::123456789012:role/web"), CreateDate: awssdk.Time(time.Now()),
+				AssumeRolePolicyDocument: awssdk.String(`{"Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}`)},
+		},
+		AttachedPolicies: map[string][]iamTypes.AttachedPolicy{
+			"web": {{PolicyArn: awssdk.String(policyArn), PolicyName: awssdk.String("AmazonSSMManagedInstanceCore")}},
+		},
+		Policies: []iamTypes.Policy{
+			{Arn: awssdk.String(policyArn), PolicyName: awssdk.String("AmazonSSMManagedInstanceCore"), PolicyId: awssdk.String("ANPA1"),
+				Path: awssdk.String("/"), DefaultVersionId: awssdk.String("v2"), CreateDate: awssdk.Time(time.Now()), UpdateDate: awssdk.Time(time.Now())},
+		},
+		PolicyDocuments: map[string]string{policyArn: `{"Statement":[]}`},
+	}
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: newFakeEC2(), IAM: fakeIAM, STS: &scannertest.FakeSTS{}})
+	s.SetScanIAM(true)
+	s.SetCache(NewScanCache(path, time.Hour))
+	if _, err := s.ScanNetwork(context.Background(), ""); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// A fresh cache saved by the first scan answers the policy calls of the next
+	fakeIAM.Errors = map[string]error{"ListAttachedRolePolicies": errors.New("throttled")}
+	cache, err := LoadScanCache(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	s.SetCache(cache)
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.IAMRoles) != 1 || len(network.IAMRoles[0].AttachedPolicies) != 1 {
+		t.Errorf("Expected the role policies from the cache, got %+v", network.IAMRoles)
+	}
+
+	// Results older than the maximum age are fetched again
+	s.SetCache(NewScanCache(path, 0))
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.IAMRoles) != 0 {
+		t.Errorf("Expected the failing policy call to be repeated, got %+v", network.IAMRoles)
+	}
+
+	// Policy versions never change, so their documents outlive the maximum age
+	cache = NewScanCache(path, 0)
+	fakeIAM.Errors = nil
+	if _, err := cachedCall(cache, "policy-version:"+policyArn+"#v2", true, func() (string, error) { return "cached", nil }); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	document, _ := cachedCall(cache, "policy-version:"+policyArn+"#v2", true, func() (string, error) { return "fetched", nil })
+	if hits, _ := cache.Stats(); document != "cached" || hits != 1 {
+		t.Errorf("Expected the immutable document to be reused, got %q with %d hits", document, hits)
+	}
+
+	// Changed resource types drop their results, however fresh
+	cache = NewScanCache(path, time.Hour)
+	cachedCall(cache, "role-policies:id-web", false, func() (string, error) { return "cached", nil })
+	cachedCall(cache, "subnet-cidr-reservations:subnet-1", false, func() (string, error) { return "cached", nil })
+	cachedCall(cache, "policy-version:"+policyArn+"#v2", true, func() (string, error) { return "cached", nil })
+	if dropped := cache.Invalidate([]string{"iam_role", "security_group"}); dropped != 1 {
+		t.Errorf("Expected 1 result dropped, got %d", dropped)
+	}
+	for key, want := range map[string]string{
+		"role-policies:id-web":                "fetched",
+		"subnet-cidr-reservations:subnet-1":   "cached",
+		"policy-version:" + policyArn + "#v2": "cached",
+	} {
+		if value, _ := cachedCall(cache, key, strings.HasPrefix(key, "policy-version:"), func() (string, error) { return "fetched", nil }); value != want {
+			t.Errorf("Expected %s to be %s, got %s", key, want, value)
+		}
+	}
+}
+
+func TestScanNetworkErrors(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.Errors = map[string]error{"DescribeSubnets": errors.New("RequestLimitExceeded")}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{Err: errors.New("denied")}})
+	_, err := s.ScanNetwork(context.Background(), "")
+	if err == nil || !strings.Contains(err.Error(), "failed to scan subnets") {
+		t.Errorf("Expected subnet scan failure, got %v", err)
+	}
+
+	// Optional lookups do not fail the scan
+	fakeEC2.Errors = map[string]error{"GetSubnetCidrReservations": errors.New("denied")}
+	if _, err := s.ScanNetwork(context.Background(), ""); err != nil {
+		t.Errorf("Expected optional lookup failures to be ignored, got %v", err)
+	}
+
+	// Best effort keeps going without the resource types that failed
+	fakeEC2.Errors = map[string]error{"DescribeSubnets": errors.New("UnauthorizedOperation"), "DescribeFlowLogs": errors.New("UnauthorizedOperation")}
+	s.SetBestEffort(true)
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Expected a best-effort scan to succeed, got %v", err)
+	}
+	if len(network.VPCs) == 0 || len(network.RouteTables) == 0 || network.Subnets != nil {
+		t.Errorf("Expected VPCs and route tables without subnets, got %d VPCs, %d route tables and %d subnets", len(network.VPCs), len(network.RouteTables), len(network.Subnets))
+	}
+	if len(network.ScanErrors) != 2 || network.ScanErrors[0].ResourceType != "subnet" || network.ScanErrors[1].ResourceType != "flow_log" ||
+		!strings.Contains(network.ScanErrors[0].Error, "failed to scan subnets: UnauthorizedOperation") {
+		t.Errorf("Expected subnet and flow log scan errors, got %+v", network.ScanErrors)
+	}
+	s.SetBestEffort(false)
+
+	// but a cancelled scan is not returned as complete
+	fakeEC2.Errors = map[string]error{"DescribeFlowLogs": context.Canceled}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.ScanNetwork(ctx, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled scan to fail, got %v", err)
+	}
+}
+
+func TestScanNATGatewayRoutes(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.RouteTables = append(fakeEC2.RouteTables, types.RouteTable{RouteTableId: awssdk.String("rtb-private"), VpcId: awssdk.String("vpc-prod"),
+		Associations: []types.RouteTableAssociation{{SubnetId: awssdk.String("subnet-private")}},
+		Routes: []types.Route{
+			{DestinationCidrBlock: awssdk.String("0.0.0.0/0"), NatGatewayId: awssdk.String("nat-prod")},
+			{DestinationCidrBlock: awssdk.String("192.168.0.0/16"), LocalGatewayId: awssdk.String("lgw-1")},
+			{DestinationCidrBlock: awssdk.String("100.64.0.0/10"), CarrierGatewayId: awssdk.String("cagw-1")},
+		}})
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}})
+	network, err := s.ScanNetwork(context.Background(), "vpc-prod")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, subnet := range network.Subnets {
+		if subnet.ID == "subnet-private" && (subnet.Type != "private" || subnet.TypeRoute == nil || subnet.TypeRoute.NatGatewayID != "nat-prod") {
+			t.Errorf("Expected a default route through the NAT gateway to make the subnet private, got %q via %+v", subnet.Type, subnet.TypeRoute)
+		}
+	}
+	for _, routeTable := range network.RouteTables {
+		if routeTable.ID != "rtb-private" {
+			continue
+		}
+		var targets []string
+		for _, route := range routeTable.Routes {
+			targets = append(targets, route.Target())
+		}
+		if strings.Join(targets, ",") != "nat-prod,cagw-1,lgw-1" {
+			t.Errorf("Expected NAT, local and carrier gateway targets, got %v", targets)
+		}
+	}
+}
+
+func TestScanSecurityGroupRules(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.SecurityGroups = []types.SecurityGroup{
+		{GroupId: awssdk.String("sg-web"), GroupName: awssdk.String("web"), Description: awssdk.String("web"), VpcId: awssdk.String("vpc-prod"),
+			IpPermissions: []types.IpPermission{{IpProtocol: awssdk.String("tcp"), FromPort: awssdk.Int32(443), ToPort: awssdk.Int32(443),
+				IpRanges: []types.IpRange{{CidrIp: awssdk.String("0.0.0.0/0")}, {CidrIp: awssdk.String("10.0.0.0/8")}}}}},
+	}
+	fakeEC2.SecurityGroupRules = []types.SecurityGroupRule{
+		{SecurityGroupRuleId: awssdk.String("sgr-2"), GroupId: awssdk.String("sg-web"), IsEgress: awssdk.Bool(false),
+			IpProtocol: awssdk.String("tcp"), FromPort: awssdk.Int32(443), ToPort: awssdk.Int32(443), CidrIpv4: awssdk.String("10.0.0.0/8"),
+			ReferencedGroupInfo: &types.ReferencedSecurityGroup{GroupId: awssdk.String("sg-lb"), UserId: awssdk.String("123456789012")}},
+		{SecurityGroupRuleId: awssdk.String("sgr-1"), GroupId: awssdk.String("sg-web"), IsEgress: awssdk.Bool(false),
+			IpProtocol: awssdk.String("tcp"), FromPort: awssdk.Int32(443), ToPort: awssdk.Int32(443), CidrIpv4: awssdk.String("0.0.0.0/0"),
+			Description: awssdk.String("public https"), Tags: []types.Tag{{Key: awssdk.String("Owner"), Value: awssdk.String("web")}}},
+		{SecurityGroupRuleId: awssdk.String("sgr-3"), GroupId: awssdk.String("sg-web"), IsEgress: awssdk.Bool(true),
+			IpProtocol: awssdk.String("-1"), FromPort: awssdk.Int32(-1), ToPort: awssdk.Int32(-1), CidrIpv4: awssdk.String("0.0.0.0/0")},
+		{SecurityGroupRuleId: awssdk.String("sgr-other"), GroupId: awssdk.String("sg-other"), IsEgress: awssdk.Bool(false),
+			IpProtocol: awssdk.String("tcp"), FromPort: awssdk.Int32(22), ToPort: awssdk.Int32(22), CidrIpv4: awssdk.String("0.0.0.0/0")},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}})
+	network, err := s.ScanNetwork(context.Background(), "vpc-prod")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.SecurityGroups) != 1 {
+		t.Fatalf("Expected 1 security group, got %d", len(network.SecurityGroups))
+	}
+
+	sg := network.SecurityGroups[0]
+	if len(sg.IngressRules) != 2 || sg.IngressRules[0].ID != "sgr-1" || sg.IngressRules[1].ID != "sgr-2" {
+		t.Fatalf("Expected ingress rules sgr-1 and sgr-2, got %+v", sg.IngressRules)
+	}
+	if rule := sg.IngressRules[0]; rule.Description != "public https" || rule.Tags["Owner"] != "web" {
+		t.Errorf("Expected the rule's description and tags, got %+v", rule)
+	}
+	if refs := sg.IngressRules[1].ReferencedGroupIDs(); len(refs) != 1 || refs[0] != "sg-lb" {
+		t.Errorf("Expected a reference to sg-lb, got %v", refs)
+	}
+	if len(sg.EgressRules) != 1 || sg.EgressRules[0].ID != "sgr-3" || sg.EgressRules[0].FromPort != 0 {
+		t.Errorf("Expected egress rule sgr-3 without ports, got %+v", sg.EgressRules)
+	}
+
+	// Rules are read from the groups' permissions when they cannot be described
+	fakeEC2.Errors = map[string]error{"DescribeSecurityGroupRules": errors.New("UnauthorizedOperation")}
+	network, err = s.ScanNetwork(context.Background(), "vpc-prod")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	rules := network.SecurityGroups[0].IngressRules
+	if len(rules) != 1 || rules[0].ID != "" || len(rules[0].CidrBlocks) != 2 {
+		t.Errorf("Expected the permission's rule without an ID, got %+v", rules)
+	}
+}
+
+func TestScanIPv6(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.Vpcs[0].Ipv6CidrBlockAssociationSet = []types.VpcIpv6CidrBlockAssociation{
+		{Ipv6CidrBlock: awssdk.String("2600:1f18:1:100::/56"), Ipv6CidrBlockState: &types.VpcCidrBlockState{State: types.VpcCidrBlockStateCodeAssociated}},
+		{Ipv6CidrBlock: awssdk.String("2600:1f18:1:200::/56"), Ipv6CidrBlockState: &types.VpcCidrBlockState{State: types.VpcCidrBlockStateCodeDisassociated}},
+	}
+	fakeEC2.Subnets[1].Ipv6CidrBlockAssociationSet = []types.SubnetIpv6CidrBlockAssociation{
+		{Ipv6CidrBlock: awssdk.String("2600:1f18:1:102::/64"), Ipv6CidrBlockState: &types.SubnetCidrBlockState{State: types.SubnetCidrBlockStateCodeAssociated}},
+	}
+	fakeEC2.Subnets[1].AssignIpv6AddressOnCreation = awssdk.Bool(true)
+	fakeEC2.EgressOnlyInternetGateways = []types.EgressOnlyInternetGateway{
+		{EgressOnlyInternetGatewayId: awssdk.String("eigw-prod"),
+			Attachments: []types.InternetGatewayAttachment{{VpcId: awssdk.String("vpc-prod"), State: types.AttachmentStatusAttached}}},
+		{EgressOnlyInternetGatewayId: awssdk.String("eigw-other"),
+			Attachments: []types.InternetGatewayAttachment{{VpcId: awssdk.String("vpc-other"), State: types.AttachmentStatusAttached}}},
+	}
+	fakeEC2.RouteTables = append(fakeEC2.RouteTables, types.RouteTable{RouteTableId: awssdk.String("rtb-private"), VpcId: awssdk.String("vpc-prod"),
+		Associations: []types.RouteTableAssociation{{SubnetId: awssdk.String("subnet-private")}},
+		Routes: []types.Route{{DestinationIpv6CidrBlock: awssdk.String("::/0"), EgressOnlyInternetGatewayId: awssdk.String("eigw-prod")}}})
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}})
+	network, err := s.ScanNetwork(context.Background(), "vpc-prod")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.VPCs[0].Ipv6CidrBlocks) != 1 || network.VPCs[0].Ipv6CidrBlocks[0] != "2600:1f18:1:100::/56" {
+		t.Errorf("Expected the associated IPv6 CIDR block, got %v", network.VPCs[0].Ipv6CidrBlocks)
+	}
+	if len(network.EgressOnlyInternetGateways) != 1 || network.EgressOnlyInternetGateways[0].ID != "eigw-prod" ||
+		len(network.VPCs[0].EgressOnlyInternetGateways) != 1 {
+		t.Errorf("Expected the egress-only internet gateway of vpc-prod, got %+v", network.EgressOnlyInternetGateways)
+	}
+
+	for _, subnet := range network.Subnets {
+		if subnet.ID != "subnet-private" {
+			continue
+		}
+		if len(subnet.Ipv6CidrBlocks) != 1 || !subnet.AssignIpv6Address {
+			t.Errorf("Expected the subnet's IPv6 settings, got %+v", subnet)
+		}
+		if subnet.Type != "private" {
+			t.Errorf("Expected a ::/0 route through the egress-only internet gateway to make the subnet private, got %q", subnet.Type)
+		}
+	}
+
+	for _, routeTable := range network.RouteTables {
+		if routeTable.ID == "rtb-private" {
+			route := routeTable.Routes[0]
+			if route.Destination() != "::/0" || route.EgressOnlyGatewayID != "eigw-prod" {
+				t.Errorf("Expected the IPv6 route, got %+v", route)
+			}
+		}
+	}
+}
+
+func TestScanTransitGatewayRouteTables(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.TransitGateways = []types.TransitGateway{
+		{TransitGatewayId: awssdk.String("tgw-1"), State: types.TransitGatewayStateAvailable},
+	}
+	fakeEC2.TransitGatewayAttachments = []types.TransitGatewayAttachment{
+		{TransitGatewayAttachmentId: awssdk.String("tgw-attach-prod"), TransitGatewayId: awssdk.String("tgw-1"),
+			ResourceId: awssdk.String("vpc-prod"), ResourceType: types.TransitGatewayAttachmentResourceTypeVpc,
+			Association: &types.TransitGatewayAttachmentAssociation{TransitGatewayRouteTableId: awssdk.String("tgw-rtb-1")}},
+	}
+	fakeEC2.TransitGatewayRouteTables = []types.TransitGatewayRouteTable{
+		{TransitGatewayRouteTableId: awssdk.String("tgw-rtb-1"), TransitGatewayId: awssdk.String("tgw-1"),
+			DefaultAssociationRouteTable: awssdk.Bool(true),
+			Tags:                         []types.Tag{{Key: awssdk.String("Name"), Value: awssdk.String("shared")}}},
+		{TransitGatewayRouteTableId: awssdk.String("tgw-rtb-other"), TransitGatewayId: awssdk.String("tgw-other")},
+	}
+	fakeEC2.TransitGatewayRoutes = map[string][]types.TransitGatewayRoute{
+		"tgw-rtb-1": {
+			{DestinationCidrBlock: awssdk.String("10.0.0.0/16"), State: types.TransitGatewayRouteStateActive, Type: types.TransitGatewayRouteTypePropagated,
+				TransitGatewayAttachments: []types.TransitGatewayRouteAttachment{{TransitGatewayAttachmentId: awssdk.String("tgw-attach-prod"),
+					ResourceId: awssdk.String("vpc-prod"), ResourceType: types.TransitGatewayAttachmentResourceTypeVpc}}},
+			{DestinationCidrBlock: awssdk.String("10.9.0.0/16"), State: types.TransitGatewayRouteStateBlackhole, Type: types.TransitGatewayRouteTypeStatic},
+			{DestinationCidrBlock: awssdk.String("10.8.0.0/16"), State: types.TransitGatewayRouteStateDeleted, Type: types.TransitGatewayRouteTypeStatic},
+		},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}})
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.TransitGateways) != 1 {
+		t.Fatalf("Expected 1 transit gateway, got %d", len(network.TransitGateways))
+	}
+	tgw := network.TransitGateways[0]
+	if len(tgw.Attachments) != 1 || tgw.Attachments[0].RouteTableID != "tgw-rtb-1" {
+		t.Errorf("Expected the attachment's route table association, got %+v", tgw.Attachments)
+	}
+	if len(tgw.RouteTables) != 1 {
+		t.Fatalf("Expected only tgw-1's route table, got %+v", tgw.RouteTables)
+	}
+
+	table := tgw.RouteTables[0]
+	if table.Name != "shared" || !table.DefaultAssociation || table.DefaultPropagation {
+		t.Errorf("Unexpected route table: %+v", table)
+	}
+	if len(table.Routes) != 2 {
+		t.Fatalf("Expected the active and blackhole routes, got %+v", table.Routes)
+	}
+	if route := table.Routes[0]; route.AttachmentID != "tgw-attach-prod" || route.ResourceType != "vpc" || route.Type != "propagated" {
+		t.Errorf("Unexpected propagated route: %+v", route)
+	}
+	if route := table.Routes[1]; route.State != "blackhole" || route.AttachmentID != "" {
+		t.Errorf("Unexpected blackhole route: %+v", route)
+	}
+
+	// Failing to list route tables keeps the transit gateway
+	fakeEC2.Errors = map[string]error{"SearchTransitGatewayRoutes": errors.New("throttled")}
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.TransitGateways) != 1 || len(network.TransitGateways[0].RouteTables) != 0 {
+		t.Errorf("Expected the transit gateway without route tables, got %+v", network.TransitGateways)
+	}
+
+	// and failing to list attachments keeps it with the error recorded
+	fakeEC2.Errors = map[string]error{"DescribeTransitGatewayAttachments": errors.New("UnauthorizedOperation")}
+	s.SetBestEffort(true)
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.TransitGateways) != 1 || network.TransitGateways[0].AttachmentsError != "UnauthorizedOperation" || len(network.TransitGateways[0].Attachments) != 0 {
+		t.Errorf("Expected the transit gateway with its attachment error, got %+v", network.TransitGateways)
+	}
+	if len(network.ScanErrors) != 1 || network.ScanErrors[0].ResourceType != "transit_gateway_attachment" {
+		t.Errorf("Expected a transit gateway attachment scan error, got %+v", network.ScanErrors)
+	}
+}
+
+func TestScanDhcpOptions(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.DhcpOptions = []types.DhcpOptions{
+		{DhcpOptionsId: awssdk.String("dopt-1"),
+			Tags: []types.Tag{{Key: awssdk.String("Name"), Value: awssdk.String("corp-dns")}},
+			DhcpConfigurations: []types.DhcpConfiguration{
+				{Key: awssdk.String("domain-name"), Values: []types.AttributeValue{{Value: awssdk.String("corp.example.com")}}},
+				{Key: awssdk.String("domain-name-servers"), Values: []types.AttributeValue{{Value: awssdk.String("10.0.0.2")}, {Value: awssdk.String("10.0.0.3")}}},
+				{Key: awssdk.String("ntp-servers"), Values: []types.AttributeValue{{Value: awssdk.String("169.254.169.123")}}},
+			}},
+		{DhcpOptionsId: awssdk.String("dopt-unused")},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}})
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.DhcpOptions) != 1 {
+		t.Fatalf("Expected only the option set used by the VPCs, got %+v", network.DhcpOptions)
+	}
+	options := network.DhcpOptions[0]
+	if options.ID != "dopt-1" || options.Name != "corp-dns" || options.DomainName != "corp.example.com" {
+		t.Errorf("Unexpected option set: %+v", options)
+	}
+	if len(options.DomainNameServers) != 2 || options.DomainNameServers[1] != "10.0.0.3" {
+		t.Errorf("Expected two DNS servers, got %v", options.DomainNameServers)
+	}
+	if len(options.NtpServers) != 1 || options.NtpServers[0] != "169.254.169.123" {
+		t.Errorf("Expected one NTP server, got %v", options.NtpServers)
+	}
+
+	fakeEC2.Errors = map[string]error{"DescribeDhcpOptions": errors.New("access denied")}
+	if _, err := s.ScanNetwork(context.Background(), ""); err == nil {
+		t.Error("Expected an error when DHCP option sets cannot be described")
+	}
+}
+
+func TestScanFlowLogs(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.FlowLogs = []types.FlowLog{
+		{FlowLogId: awssdk.String("fl-vpc"), ResourceId: awssdk.String("vpc-prod"), TrafficType: types.TrafficTypeAll,
+			LogDestinationType: types.LogDestinationTypeCloudWatchLogs, LogGroupName: awssdk.String("/vpc/prod"),
+			FlowLogStatus: awssdk.String("ACTIVE"), DeliverLogsStatus: awssdk.String("SUCCESS")},
+		{FlowLogId: awssdk.String("fl-subnet"), ResourceId: awssdk.String("subnet-dev"), TrafficType: types.TrafficTypeReject,
+			LogDestinationType: types.LogDestinationTypeS3, LogDestination: awssdk.String("arn:aws:s3:::flow-logs")},
+		{FlowLogId: awssdk.String("fl-eni"), ResourceId: awssdk.String("eni-prod"), TrafficType: types.TrafficTypeAccept},
+		{FlowLogId: awssdk.String("fl-deleted-eni"), ResourceId: awssdk.String("eni-deleted")},
+		{FlowLogId: awssdk.String("fl-tgw"), ResourceId: awssdk.String("tgw-1")},
+	}
+	fakeEC2.NetworkInterfaces = []types.NetworkInterface{
+		{NetworkInterfaceId: awssdk.String("eni-prod"), VpcId: awssdk.String("vpc-prod")},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}})
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	byID := make(map[string]FlowLog)
+	for _, flowLog := range network.FlowLogs {
+		byID[flowLog.ID] = flowLog
+	}
+	if len(byID) != 3 {
+		t.Fatalf("Expected the VPC, subnet and interface flow logs, got %+v", network.FlowLogs)
+	}
+	if f := byID["fl-vpc"]; f.ResourceType != "VPC" || f.VpcID != "vpc-prod" || f.TrafficType != "ALL" || f.Destination != "/vpc/prod" || f.Status != "ACTIVE" {
+		t.Errorf("Unexpected VPC flow log: %+v", f)
+	}
+	if f := byID["fl-subnet"]; f.ResourceType != "Subnet" || f.VpcID != "vpc-dev" || f.DestinationType != "s3" || f.Destination != "arn:aws:s3:::flow-logs" {
+		t.Errorf("Unexpected subnet flow log: %+v", f)
+	}
+	if f := byID["fl-eni"]; f.ResourceType != "NetworkInterface" || f.VpcID != "vpc-prod" {
+		t.Errorf("Unexpected interface flow log: %+v", f)
+	}
+	if got := network.FlowLogsFor("vpc-prod"); len(got) != 1 || got[0].ID != "fl-vpc" {
+		t.Errorf("Expected the flow log of vpc-prod, got %+v", got)
+	}
+	if subset := network.ForVPCs([]string{"vpc-dev"}); len(subset.FlowLogs) != 1 || subset.FlowLogs[0].ID != "fl-subnet" {
+		t.Errorf("Expected only the flow logs of vpc-dev, got %+v", subset.FlowLogs)
+	}
+
+	// Flow logs that cannot be described are unknown, not missing
+	fakeEC2.Errors = map[string]error{"DescribeFlowLogs": errors.New("access denied")}
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if network.FlowLogs != nil {
+		t.Errorf("Expected no flow logs when they cannot be described, got %+v", network.FlowLogs)
+	}
+}
+
+func TestScanEndpointServices(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.EndpointServices = []types.ServiceConfiguration{
+		{ServiceId: awssdk.String("vpce-svc-1"), ServiceName: awssdk.String("com.amazonaws.vpce.us-east-1.vpce-svc-1"),
+			ServiceState: types.ServiceStateAvailable, AcceptanceRequired: awssdk.Bool(true),
+			ServiceType:             []types.ServiceTypeDetail{{ServiceType: types.ServiceTypeInterface}},
+			AvailabilityZones:       []string{"us-east-1a"},
+			NetworkLoadBalancerArns: []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/api/abc"},
+			Tags:                    []types.Tag{{Key: awssdk.String("Name"), Value: awssdk.String("payments-api")}}},
+	}
+	fakeEC2.EndpointServicePrincipals = map[string][]types.AllowedPrincipal{
+		"vpce-svc-1": {{Principal: awssdk.String("arn:aws:iam::210987654321:root")}},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}})
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.EndpointServices) != 1 {
+		t.Fatalf("Expected 1 endpoint service, got %+v", network.EndpointServices)
+	}
+	service := network.EndpointServices[0]
+	if service.Name != "payments-api" || service.ServiceType != "Interface" || service.State != "Available" || !service.AcceptanceRequired {
+		t.Errorf("Unexpected endpoint service: %+v", service)
+	}
+	if len(service.NetworkLoadBalancerArns) != 1 {
+		t.Errorf("Expected the NLB association, got %v", service.NetworkLoadBalancerArns)
+	}
+	if len(service.AllowedPrincipals) != 1 || service.AllowedPrincipals[0] != "arn:aws:iam::210987654321:root" {
+		t.Errorf("Expected the allowed principal, got %v", service.AllowedPrincipals)
+	}
+
+	// Endpoint services are optional, a failure keeps the rest of the scan
+	fakeEC2.Errors = map[string]error{"DescribeVpcEndpointServicePermissions": errors.New("access denied")}
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.EndpointServices) != 0 || len(network.VPCs) != 2 {
+		t.Errorf("Expected the VPCs without endpoint services, got %d services", len(network.EndpointServices))
+	}
+}
+
+func TestScanVpcEndpoints(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.VpcEndpoints = []types.VpcEndpoint{
+		{VpcEndpointId: awssdk.String("vpce-s3"), VpcId: awssdk.String("vpc-prod"), ServiceName: awssdk.String("com.amazonaws.us-east-1.s3"),
+			VpcEndpointType: types.VpcEndpointTypeInterface, State: types.StateAvailable, PrivateDnsEnabled: awssdk.Bool(true),
+			SubnetIds:           []string{"subnet-private"},
+			Groups:              []types.SecurityGroupIdentifier{{GroupId: awssdk.String("sg-web")}},
+			NetworkInterfaceIds: []string{"eni-vpce-s3", "eni-deleted"},
+			DnsEntries: []types.DnsEntry{
+				{DnsName: awssdk.String("vpce-s3-abc.s3.us-east-1.vpce.amazonaws.com"), HostedZoneId: awssdk.String("Z7HUB22UULQXV")},
+			}},
+		{VpcEndpointId: awssdk.String("vpce-gateway"), VpcId: awssdk.String("vpc-prod"), ServiceName: awssdk.String("com.amazonaws.us-east-1.dynamodb"),
+			VpcEndpointType: types.VpcEndpointTypeGateway},
+		{VpcEndpointId: awssdk.String("vpce-other"), VpcId: awssdk.String("vpc-other"), VpcEndpointType: types.VpcEndpointTypeInterface},
+	}
+	fakeEC2.NetworkInterfaces = []types.NetworkInterface{
+		{NetworkInterfaceId: awssdk.String("eni-vpce-s3"), VpcId: awssdk.String("vpc-prod"), SubnetId: awssdk.String("subnet-private"),
+			PrivateIpAddress: awssdk.String("10.0.2.45"), Ipv6Addresses: []types.NetworkInterfaceIpv6Address{{Ipv6Address: awssdk.String("2600:1f18::45")}}},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}})
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.VpcEndpoints) != 1 {
+		t.Fatalf("Expected only the interface endpoint of the scanned VPCs, got %+v", network.VpcEndpoints)
+	}
+	endpoint := network.VpcEndpoints[0]
+	if endpoint.ID != "vpce-s3" || endpoint.Type != "Interface" || !endpoint.PrivateDNSEnabled || len(endpoint.SecurityGroupIDs) != 1 {
+		t.Errorf("Unexpected VPC endpoint: %+v", endpoint)
+	}
+	if len(endpoint.DNSEntries) != 1 || endpoint.DNSEntries[0].HostedZoneID != "Z7HUB22UULQXV" {
+		t.Errorf("Expected the DNS entry, got %+v", endpoint.DNSEntries)
+	}
+	if len(endpoint.NetworkInterfaces) != 2 || endpoint.NetworkInterfaces[0].ID != "eni-deleted" || endpoint.NetworkInterfaces[0].PrivateIP != "" {
+		t.Errorf("Expected the deleted interface without addresses, got %+v", endpoint.NetworkInterfaces)
+	}
+
+	for _, address := range []string{"10.0.2.45", "2600:1f18:0:0::45"} {
+		found, eni, ok := network.VpcEndpointFor(address)
+		if !ok || found.ID != "vpce-s3" || eni.ID != "eni-vpce-s3" || eni.SubnetID != "subnet-private" {
+			t.Errorf("Expected %s to resolve to eni-vpce-s3, got %+v %+v", address, found, eni)
+		}
+	}
+	if _, _, ok := network.VpcEndpointFor("10.0.2.46"); ok {
+		t.Error("Expected no endpoint for an unused address")
+	}
+
+	// VPC endpoints are optional, a failure keeps the rest of the scan
+	fakeEC2.Errors = map[string]error{"DescribeVpcEndpoints": errors.New("access denied")}
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.VpcEndpoints) != 0 || len(network.VPCs) != 2 {
+		t.Errorf("Expected the VPCs without VPC endpoints, got %d endpoints", len(network.VpcEndpoints))
+	}
+}
+
+func TestScanNetworkInterfaces(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.NetworkInterfaces = []types.NetworkInterface{
+		{NetworkInterfaceId: awssdk.String("eni-nat"), VpcId: awssdk.String("vpc-prod"), SubnetId: awssdk.String("subnet-public"),
+			InterfaceType: types.NetworkInterfaceTypeNatGateway, Description: awssdk.String("Interface for NAT Gateway nat-0abc"),
+			Association: &types.NetworkInterfaceAssociation{PublicIp: awssdk.String("54.1.2.3")}},
+		{NetworkInterfaceId: awssdk.String("eni-lambda"), VpcId: awssdk.String("vpc-prod"), InterfaceType: types.NetworkInterfaceTypeLambda,
+			Description: awssdk.String("AWS Lambda VPC ENI-orders-api-0f4e8a1c-2b3d-4e5f-8a9b-0c1d2e3f4a5b")},
+		{NetworkInterfaceId: awssdk.String("eni-elb"), VpcId: awssdk.String("vpc-prod"), Description: awssdk.String("ELB app/web/50dc6c495c0c9188")},
+		{NetworkInterfaceId: awssdk.String("eni-rds"), VpcId: awssdk.String("vpc-prod"), Description: awssdk.String("RDSNetworkInterface"),
+			RequesterId: awssdk.String("amazon-rds"), RequesterManaged: awssdk.Bool(true)},
+		{NetworkInterfaceId: awssdk.String("eni-ec2"), VpcId: awssdk.String("vpc-prod"), SubnetId: awssdk.String("subnet-private"),
+			Status: types.NetworkInterfaceStatusInUse, Attachment: &types.NetworkInterfaceAttachment{InstanceId: awssdk.String("i-0123")},
+			PrivateIpAddress: awssdk.String("10.0.2.10"),
+			PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{
+				{PrivateIpAddress: awssdk.String("10.0.2.10"), Primary: awssdk.Bool(true)},
+				{PrivateIpAddress: awssdk.String("10.0.2.11"), Primary: awssdk.Bool(false)},
+			},
+			Ipv4Prefixes: []types.Ipv4PrefixSpecification{{Ipv4Prefix: awssdk.String("10.0.2.32/28")}},
+			Groups:       []types.GroupIdentifier{{GroupId: awssdk.String("sg-web")}}},
+		{NetworkInterfaceId: awssdk.String("eni-spare"), VpcId: awssdk.String("vpc-prod"), Status: types.NetworkInterfaceStatusAvailable},
+		{NetworkInterfaceId: awssdk.String("eni-elsewhere"), VpcId: awssdk.String("vpc-unscanned")},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}})
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	want := map[string][2]string{
+		"eni-nat":    {AttachmentNATGateway, "nat-0abc"},
+		"eni-lambda": {AttachmentLambda, "orders-api"},
+		"eni-elb":    {AttachmentLoadBalancer, "app/web/50dc6c495c0c9188"},
+		"eni-rds":    {AttachmentRDS, ""},
+		"eni-ec2":    {AttachmentEC2, "i-0123"},
+		"eni-spare":  {AttachmentUnattached, ""},
+	}
+	if len(network.NetworkInterfaces) != len(want) {
+		t.Fatalf("Expected the network interfaces of the scanned VPCs, got %+v", network.NetworkInterfaces)
+	}
+	for _, eni := range network.NetworkInterfaces {
+		if got := [2]string{eni.AttachmentType, eni.AttachedTo}; got != want[eni.ID] {
+			t.Errorf("Expected %s to be attached to %v, got %v", eni.ID, want[eni.ID], got)
+		}
+		switch eni.ID {
+		case "eni-ec2":
+			if eni.IPv4AddressCount() != 18 || len(eni.SecondaryPrivateIPs) != 1 || len(eni.SecurityGroupIDs) != 1 {
+				t.Errorf("Expected 18 addresses on eni-ec2, got %d: %+v", eni.IPv4AddressCount(), eni)
+			}
+		case "eni-nat":
+			if eni.PublicIP != "54.1.2.3" {
+				t.Errorf("Expected the NAT gateway's public IP, got %q", eni.PublicIP)
+			}
+		}
+	}
+
+	// Network interfaces are optional, a failure keeps the rest of the scan
+	fakeEC2.Errors = map[string]error{"DescribeNetworkInterfaces": errors.New("access denied")}
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.NetworkInterfaces) != 0 || len(network.VPCs) != 2 {
+		t.Errorf("Expected the VPCs without network interfaces, got %d interfaces", len(network.NetworkInterfaces))
+	}
+}
+
+func TestScanContainers(t *testing.T) {
+	fakeEC2 := newFakeEC2()
+	fakeEC2.NetworkInterfaces = []types.NetworkInterface{
+		{NetworkInterfaceId: awssdk.String("eni-cp-1"), VpcId: awssdk.String("vpc-prod"), Description: awssdk.String("Amazon EKS prod")},
+		{NetworkInterfaceId: awssdk.String("eni-node"), VpcId: awssdk.String("vpc-prod"), Description: awssdk.String("aws-K8S-i-0123")},
+	}
+	fakeEKS := &scannertest.FakeEKS{
+		Clusters: []eksTypes.Cluster{
+			{Name: awssdk.String("prod"), Version: awssdk.String("1.30"), Status: eksTypes.ClusterStatusActive,
+				ResourcesVpcConfig: &eksTypes.VpcConfigResponse{VpcId: awssdk.String("vpc-prod"),
+					SubnetIds: []string{"subnet-private"}, ClusterSecurityGroupId: awssdk.String("sg-cluster"),
+					EndpointPrivateAccess: true}},
+			{Name: awssdk.String("elsewhere"), ResourcesVpcConfig: &eksTypes.VpcConfigResponse{VpcId: awssdk.String("vpc-other")}},
+		},
+	}
+
+	clusterArn := "arn:aws:ecs:us-east-1:123456789012:cluster/web"
+	fakeECS := &scannertest.FakeECS{ClusterArns: []string{clusterArn}}
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("api-%d", i)
+		fakeECS.Services = append(fakeECS.Services, ecsTypes.Service{
+			ServiceArn: awssdk.String("arn:aws:ecs:us-east-1:123456789012:service/web/" + name), ServiceName: awssdk.String(name),
+			ClusterArn: awssdk.String(clusterArn), LaunchType: ecsTypes.LaunchTypeFargate, DesiredCount: 2, RunningCount: 2,
+			NetworkConfiguration: &ecsTypes.NetworkConfiguration{AwsvpcConfiguration: &ecsTypes.AwsVpcConfiguration{
+				Subnets: []string{"subnet-dev"}, SecurityGroups: []string{"sg-api"}}},
+		})
+	}
+	fakeECS.Services = append(fakeECS.Services, ecsTypes.Service{
+		ServiceArn: awssdk.String("arn:aws:ecs:us-east-1:123456789012:service/web/bridge"), ServiceName: awssdk.String("bridge"),
+		ClusterArn: awssdk.String(clusterArn), LaunchType: ecsTypes.LaunchTypeEc2,
+	})
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{}, EKS: fakeEKS, ECS: fakeECS})
+
+	// Containers are opt-in
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.EKSClusters) != 0 || len(network.ECSServices) != 0 {
+		t.Errorf("Expected no containers without SetScanContainers, got %d clusters and %d services", len(network.EKSClusters), len(network.ECSServices))
+	}
+
+	s.SetScanContainers(true)
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.EKSClusters) != 1 {
+		t.Fatalf("Expected only the cluster in a scanned VPC, got %+v", network.EKSClusters)
+	}
+	cluster := network.EKSClusters[0]
+	if cluster.Name != "prod" || cluster.ClusterSecurityGroupID != "sg-cluster" || !cluster.EndpointPrivateAccess || cluster.EndpointPublicAccess {
+		t.Errorf("Unexpected EKS cluster: %+v", cluster)
+	}
+	if len(cluster.NetworkInterfaces) != 1 || cluster.NetworkInterfaces[0] != "eni-cp-1" {
+		t.Errorf("Expected the control plane ENI, got %v", cluster.NetworkInterfaces)
+	}
+
+	// The bridge-mode service has no awsvpc configuration
+	if len(network.ECSServices) != 12 {
+		t.Fatalf("Expected 12 awsvpc services, got %d", len(network.ECSServices))
+	}
+	service := network.ECSServices[0]
+	if service.ClusterName != "web" || service.VpcID != "vpc-dev" || service.LaunchType != "FARGATE" || service.AssignPublicIP {
+		t.Errorf("Unexpected ECS service: %+v", service)
+	}
+
+	// Scanning one VPC leaves out services in the others
+	network, err = s.ScanNetwork(context.Background(), "vpc-prod")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.EKSClusters) != 1 || len(network.ECSServices) != 0 {
+		t.Errorf("Expected only vpc-prod's containers, got %d clusters and %d services", len(network.EKSClusters), len(network.ECSServices))
+	}
+
+	// A failure is reported and the rest of the scan continues
+	fakeECS.Errors = map[string]error{"ListClusters": errors.New("access denied")}
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.EKSClusters) != 1 || len(network.ECSServices) != 0 {
+		t.Errorf("Expected the EKS cluster without ECS services, got %d clusters and %d services", len(network.EKSClusters), len(network.ECSServices))
+	}
+}
+
+func TestScanDatabases(t *testing.T) {
+	fakeRDS := &scannertest.FakeRDS{
+		DBSubnetGroups: []rdsTypes.DBSubnetGroup{
+			{DBSubnetGroupName: awssdk.String("orders"), VpcId: awssdk.String("vpc-prod"),
+				Subnets: []rdsTypes.Subnet{{SubnetIdentifier: awssdk.String("subnet-private")}}},
+		},
+		DBClusters: []rdsTypes.DBCluster{
+			{DBClusterIdentifier: awssdk.String("orders"), Engine: awssdk.String("aurora-postgresql"), Status: awssdk.String("available"),
+				Endpoint: awssdk.String("orders.cluster-abc.us-east-1.rds.amazonaws.com"), Port: awssdk.Int32(5432),
+				DBSubnetGroup:     awssdk.String("orders"),
+				VpcSecurityGroups: []rdsTypes.VpcSecurityGroupMembership{{VpcSecurityGroupId: awssdk.String("sg-orders-db")}},
+				TagList:           []rdsTypes.Tag{{Key: awssdk.String("Team"), Value: awssdk.String("orders")}}},
+		},
+		DBInstances: []rdsTypes.DBInstance{
+			// A cluster member, shown through its cluster
+			{DBInstanceIdentifier: awssdk.String("orders-1"), DBClusterIdentifier: awssdk.String("orders")},
+			{DBInstanceIdentifier: awssdk.String("legacy"), Engine: awssdk.String("mysql"), PubliclyAccessible: awssdk.Bool(true),
+				Endpoint: &rdsTypes.Endpoint{Address: awssdk.String("legacy.abc.us-east-1.rds.amazonaws.com"), Port: awssdk.Int32(3306)},
+				DBSubnetGroup: &rdsTypes.DBSubnetGroup{DBSubnetGroupName: awssdk.String("orders"), VpcId: awssdk.String("vpc-prod")}},
+		},
+	}
+	fakeElastiCache := &scannertest.FakeElastiCache{
+		CacheSubnetGroups: []cacheTypes.CacheSubnetGroup{
+			{CacheSubnetGroupName: awssdk.String("sessions"), VpcId: awssdk.String("vpc-dev"),
+				Subnets: []cacheTypes.Subnet{{SubnetIdentifier: awssdk.String("subnet-dev")}}},
+		},
+		CacheClusters: []cacheTypes.CacheCluster{
+			{CacheClusterId: awssdk.String("sessions-001"), Engine: awssdk.String("redis"), CacheSubnetGroupName: awssdk.String("sessions"),
+				CacheNodes: []cacheTypes.CacheNode{{Endpoint: &cacheTypes.Endpoint{Address: awssdk.String("sessions-001.cache.amazonaws.com"), Port: awssdk.Int32(6379)}}},
+				SecurityGroups: []cacheTypes.SecurityGroupMembership{{SecurityGroupId: awssdk.String("sg-cache")}}},
+		},
+	}
+	fakeRedshift := &scannertest.FakeRedshift{
+		Errors: map[string]error{"DescribeClusterSubnetGroups": errors.New("access denied")},
+		Clusters: []redshiftTypes.Cluster{
+			{ClusterIdentifier: awssdk.String("warehouse"), VpcId: awssdk.String("vpc-prod")},
+		},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: newFakeEC2(), STS: &scannertest.FakeSTS{},
+		RDS: fakeRDS, ElastiCache: fakeElastiCache, Redshift: fakeRedshift})
+	s.SetScanDatabases(true)
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Redshift failed, the other services are kept
+	if len(network.DatabaseSubnetGroups) != 2 {
+		t.Errorf("Expected the RDS and ElastiCache subnet groups, got %+v", network.DatabaseSubnetGroups)
+	}
+	if len(network.Databases) != 3 {
+		t.Fatalf("Expected the Aurora cluster, standalone instance and cache cluster, got %+v", network.Databases)
+	}
+
+	cluster := network.Databases[1]
+	if cluster.Key() != "rds:orders" || cluster.VpcID != "vpc-prod" || cluster.Port != 5432 || cluster.Tags["Team"] != "orders" {
+		t.Errorf("Unexpected Aurora cluster: %+v", cluster)
+	}
+	if len(cluster.SubnetIDs) != 1 || cluster.SubnetIDs[0] != "subnet-private" {
+		t.Errorf("Expected the cluster in its subnet group's subnets, got %v", cluster.SubnetIDs)
+	}
+	if instance := network.Databases[0]; instance.ID != "legacy" || !instance.PubliclyAccessible || instance.Port != 3306 {
+		t.Errorf("Unexpected DB instance: %+v", instance)
+	}
+	if cache := network.Databases[2]; cache.Endpoint != "sessions-001.cache.amazonaws.com" || cache.VpcID != "vpc-dev" || cache.SecurityGroupIDs[0] != "sg-cache" {
+		t.Errorf("Unexpected cache cluster: %+v", cache)
+	}
+
+	// Scanning one VPC leaves out databases in the others
+	network, err = s.ScanNetwork(context.Background(), "vpc-dev")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.Databases) != 1 || network.Databases[0].Service != "elasticache" {
+		t.Errorf("Expected only the cache cluster in vpc-dev, got %+v", network.Databases)
+	}
+}
+
+func TestScanEdgeIngress(t *testing.T) {
+	albArn := "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/abc"
+	fakeEC2 := newFakeEC2()
+	fakeEC2.Addresses = []types.Address{
+		{AllocationId: awssdk.String("eipalloc-1"), PublicIp: awssdk.String("203.0.113.10"), SubnetId: awssdk.String("subnet-public")},
+		// Associated through a network interface in vpc-dev
+		{AllocationId: awssdk.String("eipalloc-2"), PublicIp: awssdk.String("203.0.113.20"), NetworkInterfaceId: awssdk.String("eni-dev")},
+	}
+	fakeEC2.NetworkInterfaces = []types.NetworkInterface{
+		{NetworkInterfaceId: awssdk.String("eni-dev"), SubnetId: awssdk.String("subnet-dev"), VpcId: awssdk.String("vpc-dev")},
+	}
+	fakeELB := &scannertest.FakeELBv2{
+		LoadBalancers: []elbTypes.LoadBalancer{
+			{LoadBalancerArn: awssdk.String(albArn), LoadBalancerName: awssdk.String("web"), Type: elbTypes.LoadBalancerTypeEnumApplication,
+				DNSName: awssdk.String("web-123.us-east-1.elb.amazonaws.com"), VpcId: awssdk.String("vpc-prod"),
+				AvailabilityZones: []elbTypes.AvailabilityZone{{SubnetId: awssdk.String("subnet-public")}}},
+		},
+	}
+	fakeGA := &scannertest.FakeGlobalAccelerator{
+		Accelerators: []gaTypes.Accelerator{
+			{AcceleratorArn: awssdk.String("arn:aws:globalaccelerator::123456789012:accelerator/web"), Name: awssdk.String("web"),
+				DnsName: awssdk.String("a123.awsglobalaccelerator.com"), Enabled: awssdk.Bool(true), Status: gaTypes.AcceleratorStatusDeployed},
+		},
+		Listeners: map[string][]gaTypes.Listener{
+			"arn:aws:globalaccelerator::123456789012:accelerator/web": {{ListenerArn: awssdk.String("listener-1")}},
+		},
+		EndpointGroups: map[string][]gaTypes.EndpointGroup{
+			"listener-1": {
+				{EndpointGroupRegion: awssdk.String("us-east-1"), EndpointDescriptions: []gaTypes.EndpointDescription{
+					{EndpointId: awssdk.String(albArn)}, {EndpointId: awssdk.String("eipalloc-1")}}},
+				// Endpoints in other regions are outside the scan
+				{EndpointGroupRegion: awssdk.String("eu-west-1"), EndpointDescriptions: []gaTypes.EndpointDescription{
+					{EndpointId: awssdk.String("eipalloc-elsewhere")}}},
+			},
+		},
+	}
+	fakeCloudFront := &scannertest.FakeCloudFront{
+		Distributions: []cfTypes.DistributionSummary{
+			{Id: awssdk.String("E1"), DomainName: awssdk.String("d111.cloudfront.net"), Enabled: awssdk.Bool(true),
+				Aliases: &cfTypes.Aliases{Items: []string{"www.example.com"}},
+				Origins: &cfTypes.Origins{Items: []cfTypes.Origin{
+					{Id: awssdk.String("alb"), DomainName: awssdk.String("dualstack.web-123.us-east-1.elb.amazonaws.com")},
+					{Id: awssdk.String("eip"), DomainName: awssdk.String("ec2-203-0-113-20.compute-1.amazonaws.com")},
+				}}},
+			{Id: awssdk.String("E2"), DomainName: awssdk.String("d222.cloudfront.net"),
+				Origins: &cfTypes.Origins{Items: []cfTypes.Origin{
+					{Id: awssdk.String("vpc"), DomainName: awssdk.String("internal"), VpcOriginConfig: &cfTypes.VpcOriginConfig{VpcOriginId: awssdk.String("vo-1")}},
+				}}},
+			// Origins outside the scanned VPCs are left out
+			{Id: awssdk.String("E3"), DomainName: awssdk.String("d333.cloudfront.net"),
+				Origins: &cfTypes.Origins{Items: []cfTypes.Origin{{Id: awssdk.String("s3"), DomainName: awssdk.String("assets.s3.amazonaws.com")}}}},
+		},
+		VpcOrigins: []cfTypes.VpcOriginSummary{{Id: awssdk.String("vo-1"), OriginEndpointArn: awssdk.String(albArn)}},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: fakeEC2, STS: &scannertest.FakeSTS{},
+		ELBv2: fakeELB, GlobalAccelerator: fakeGA, CloudFront: fakeCloudFront})
+	s.SetScanEdge(true)
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(network.EdgeIngresses) != 3 {
+		t.Fatalf("Expected the accelerator and two distributions, got %+v", network.EdgeIngresses)
+	}
+	accelerator := network.EdgeIngresses[2]
+	if accelerator.Service != "globalaccelerator" || len(accelerator.Targets) != 2 || accelerator.Targets[0].Type != "eip" {
+		t.Errorf("Unexpected accelerator: %+v", accelerator)
+	}
+	distribution := network.EdgeIngresses[0]
+	if distribution.ID != "E1" || len(distribution.Targets) != 2 || distribution.Targets[1].ID != albArn {
+		t.Fatalf("Unexpected distribution: %+v", distribution)
+	}
+	if eip := distribution.Targets[0]; eip.ID != "eipalloc-2" || eip.VpcID != "vpc-dev" || eip.SubnetIDs[0] != "subnet-dev" {
+		t.Errorf("Expected the Elastic IP origin in vpc-dev, got %+v", eip)
+	}
+	if vpcOrigin := network.EdgeIngresses[1]; vpcOrigin.ID != "E2" || vpcOrigin.Targets[0].Name != "web" {
+		t.Errorf("Expected the VPC origin to resolve to the load balancer, got %+v", vpcOrigin)
+	}
+
+	// A failing service is reported and the other is kept
+	fakeGA.Errors = map[string]error{"ListAccelerators": errors.New("access denied")}
+	network, err = s.ScanNetwork(context.Background(), "vpc-dev")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.EdgeIngresses) != 1 || len(network.EdgeIngresses[0].Targets) != 1 {
+		t.Errorf("Expected only the distribution reaching vpc-dev, got %+v", network.EdgeIngresses)
+	}
+}
+
+func TestScanAppMesh(t *testing.T) {
+	gatewayArn := func(name string) *string {
+		return awssdk.String("arn:aws:appmesh:us-east-1:123456789012:mesh/payments/virtualGateway/" + name)
+	}
+	fakeMesh := &scannertest.FakeAppMesh{
+		Meshes: []meshTypes.MeshRef{{MeshName: awssdk.String("payments")}},
+		VirtualGateways: []meshTypes.VirtualGatewayData{
+			{MeshName: awssdk.String("payments"), VirtualGatewayName: awssdk.String("ingress"), Metadata: &meshTypes.ResourceMetadata{Arn: gatewayArn("ingress")}},
+			{MeshName: awssdk.String("payments"), VirtualGatewayName: awssdk.String("on-eks"), Metadata: &meshTypes.ResourceMetadata{Arn: gatewayArn("on-eks")}},
+		},
+	}
+
+	// The ingress gateway's Envoy runs in an ECS service in vpc-prod
+	clusterArn := "arn:aws:ecs:us-east-1:123456789012:cluster/mesh"
+	fakeECS := &scannertest.FakeECS{
+		ClusterArns: []string{clusterArn},
+		Services: []ecsTypes.Service{{
+			ServiceArn: awssdk.String("arn:aws:ecs:us-east-1:123456789012:service/mesh/ingress"), ServiceName: awssdk.String("ingress"),
+			ClusterArn: awssdk.String(clusterArn), TaskDefinition: awssdk.String("arn:aws:ecs:us-east-1:123456789012:task-definition/ingress:3"),
+			NetworkConfiguration: &ecsTypes.NetworkConfiguration{AwsvpcConfiguration: &ecsTypes.AwsVpcConfiguration{Subnets: []string{"subnet-private"}}},
+		}},
+		TaskDefinitions: []ecsTypes.TaskDefinition{{
+			TaskDefinitionArn: awssdk.String("arn:aws:ecs:us-east-1:123456789012:task-definition/ingress:3"),
+			ContainerDefinitions: []ecsTypes.ContainerDefinition{
+				{Name: awssdk.String("app")},
+				{Name: awssdk.String("envoy"), Environment: []ecsTypes.KeyValuePair{{Name: awssdk.String("APPMESH_RESOURCE_ARN"), Value: gatewayArn("ingress")}}},
+			},
+		}},
+	}
+
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: newFakeEC2(), STS: &scannertest.FakeSTS{}, AppMesh: fakeMesh, ECS: fakeECS})
+	s.SetScanAppMesh(true)
+
+	// Scanning every VPC keeps gateways run elsewhere
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.MeshVirtualGateways) != 2 {
+		t.Errorf("Expected every gateway, got %+v", network.MeshVirtualGateways)
+	}
+
+	for vpcID, expected := range map[string]int{"vpc-prod": 1, "vpc-dev": 0} {
+		network, err := s.ScanNetwork(context.Background(), vpcID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(network.MeshVirtualGateways) != expected || (expected == 1 && network.MeshVirtualGateways[0].Name != "ingress") {
+			t.Errorf("Expected %d gateways in %s, got %+v", expected, vpcID, network.MeshVirtualGateways)
+		}
+	}
+
+	// Failures of optional resource types are recorded without best-effort mode
+	fakeMesh.Errors = map[string]error{"ListMeshes": errors.New("access denied")}
+	network, err = s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(network.ScanErrors) != 1 || network.ScanErrors[0].ResourceType != "mesh_virtual_gateway" {
+		t.Errorf("Expected a mesh virtual gateway scan error, got %+v", network.ScanErrors)
+	}
+}
@@ -3,42 +3,180 @@ package scanner
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/url"
+	"os"
+	"sort"
 	"strings"
 	"time"
 
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/Yiu-Kelvin/pikaatools/pkg/aws"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/logging"
 )
 
 // NetworkScanner scans AWS network infrastructure
 type NetworkScanner struct {
-	client  *aws.Client
-	verbose bool
+	apis           APIs
+	region         string
+	verbose        bool
+	scanAppMesh    bool
+	scanContainers bool
+	scanDatabases  bool
+	scanEdge       bool
+	cache          *ScanCache
+	scanIAM        bool
+	allIAMRoles    bool
+	logger         *slog.Logger
+	progress       ProgressReporter
+	tagFilters     map[string][]string
+	timeout        time.Duration
+	bestEffort     bool
+	iamConcurrency int
 }
 
 // NewNetworkScanner creates a new network scanner
 func NewNetworkScanner(client *aws.Client) *NetworkScanner {
+	return NewNetworkScannerFromAPIs(client.Region(), APIs{
+		EC2:               client.EC2,
+		IAM:               client.IAM,
+		STS:               client.STS,
+		AppMesh:           client.AppMesh,
+		EKS:               client.EKS,
+		ECS:               client.ECS,
+		RDS:               client.RDS,
+		ElastiCache:       client.ElastiCache,
+		Redshift:          client.Redshift,
+		ELBv2:             client.ELBv2,
+		GlobalAccelerator: client.GlobalAccelerator,
+		CloudFront:        client.CloudFront,
+	})
+}
+
+// NewNetworkScannerFromAPIs creates a new network scanner for a region using the given service APIs
+func NewNetworkScannerFromAPIs(region string, apis APIs) *NetworkScanner {
 	return &NetworkScanner{
-		client:  client,
+		apis:    apis,
+		region:  region,
 		verbose: false,
 	}
 }
 
+// ProgressReporter is told each time a resource type finishes scanning
+type ProgressReporter interface {
+	Scanned(resourceType string, done, total int)
+}
+
 // SetVerbose enables or disables verbose output
 func (s *NetworkScanner) SetVerbose(verbose bool) {
 	s.verbose = verbose
 }
 
+// SetLogger sets the logger for scan details and warnings. Without one, warnings,
+// and in verbose mode details, are logged as text to stderr.
+func (s *NetworkScanner) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// SetProgress reports scan progress to progress, nil for none
+func (s *NetworkScanner) SetProgress(progress ProgressReporter) {
+	s.progress = progress
+}
+
+// log returns the logger to use
+func (s *NetworkScanner) log() *slog.Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+	logger, _ := logging.New(os.Stderr, logging.FormatText, logging.Level(s.verbose, false))
+	return logger
+}
+
 // ScanNetwork scans the complete network infrastructure
 func (s *NetworkScanner) ScanNetwork(ctx context.Context, vpcID string) (*Network, error) {
+	return s.withTimeout(ctx, func(ctx context.Context, partial *partialScan) (*Network, error) {
+		return s.scanNetwork(ctx, vpcID, nil, nil, partial)
+	})
+}
+
+// RescanNetwork scans VPCs and the given resource types, such as "subnet" or
+// "security_group", and reuses every other resource from a previous scan.
+// Relationships between resources are rebuilt from the combined results.
+func (s *NetworkScanner) RescanNetwork(ctx context.Context, vpcID string, previous *Network, resourceTypes []string) (*Network, error) {
+	selected := make(map[string]bool)
+	for _, resourceType := range resourceTypes {
+		selected[resourceType] = true
+	}
+	return s.withTimeout(ctx, func(ctx context.Context, partial *partialScan) (*Network, error) {
+		return s.scanNetwork(ctx, vpcID, previous, selected, partial)
+	})
+}
+
+// scanNetwork scans VPCs and the selected resource types, reusing the others from
+// previous. A nil previous scan scans everything. What has been scanned so far is
+// recorded in partial.
+func (s *NetworkScanner) scanNetwork(ctx context.Context, vpcID string, previous *Network, selected map[string]bool, partial *partialScan) (*Network, error) {
+	rescan := func(resourceType string) bool {
+		return previous == nil || selected[resourceType]
+	}
+
+	// Resource types this scan fetches, to report progress against
+	steps := []string{"vpc"}
+	for _, resourceType := range []string{"dhcp_options", "subnet", "peering_connection", "transit_gateway", "internet_gateway",
+		"egress_only_internet_gateway", "nat_gateway", "route_table", "security_group", "network_acl", "flow_log", "endpoint_service", "vpc_endpoint", "network_interface"} {
+		if rescan(resourceType) {
+			steps = append(steps, resourceType)
+		}
+	}
+	if previous == nil {
+		if s.scanContainers {
+			steps = append(steps, "eks_cluster", "ecs_service")
+		}
+		if s.scanDatabases {
+			steps = append(steps, "database")
+		}
+		if s.scanEdge {
+			steps = append(steps, "edge_ingress")
+		}
+		if s.scanAppMesh {
+			steps = append(steps, "mesh_virtual_gateway")
+		}
+	}
+	if s.scanIAM && rescan("iam_role") {
+		steps = append(steps, "iam_role")
+	}
+	partial.steps = steps
+	done := 0
+	step := func(resourceType string, count int, start time.Time) {
+		done++
+		// Steps that tolerate failed calls also finish when the scan runs out of time
+		if ctx.Err() == nil {
+			partial.scanned[resourceType] = true
+		}
+		s.log().Debug("scanned resources", "resource_type", resourceType, "count", count, "duration", time.Since(start))
+		if s.progress != nil {
+			s.progress.Scanned(resourceType, done, len(steps))
+		}
+	}
+
 	network := &Network{
-		ScanTime: time.Now(),
-		Region:   s.client.Region(),
+		SchemaVersion: NetworkSchemaVersion,
+		ScanTime:      time.Now(),
+		Region:        s.region,
+	}
+	partial.network = network
+
+	// Identify the account so snapshots can be attributed
+	accountID, err := s.accountID(ctx)
+	if err != nil {
+		// Log error but continue, the account ID is informational
+		s.log().Debug("failed to determine account ID", "error", err)
 	}
+	network.AccountID = accountID
 
 	// Scan VPCs
 	start := time.Now()
@@ -47,10 +185,7 @@ func (s *NetworkScanner) ScanNetwork(ctx context.Context, vpcID string) (*Networ
 		return nil, fmt.Errorf("failed to scan VPCs: %w", err)
 	}
 	network.VPCs = vpcs
-	if s.verbose {
-		duration := time.Since(start)
-		fmt.Printf("Scanned %d VPCs took %v\n", len(vpcs), duration)
-	}
+	step("vpc", len(vpcs), start)
 
 	// Get VPC IDs for filtering other resources
 	vpcIDs := make([]string, len(vpcs))
@@ -58,123 +193,331 @@ func (s *NetworkScanner) ScanNetwork(ctx context.Context, vpcID string) (*Networ
 		vpcIDs[i] = vpc.ID
 	}
 
-	// Scan subnets
-	start = time.Now()
-	subnets, err := s.scanSubnets(ctx, vpcIDs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan subnets: %w", err)
+	// Scan DHCP option sets
+	if rescan("dhcp_options") {
+		start = time.Now()
+		dhcpOptions, err := s.scanDhcpOptions(ctx, vpcs)
+		if err != nil {
+			if err := s.scanFailed(network, "dhcp_options", fmt.Errorf("failed to scan DHCP option sets: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.DhcpOptions = dhcpOptions
+		step("dhcp_options", len(dhcpOptions), start)
+	} else {
+		network.DhcpOptions = previous.DhcpOptions
 	}
-	network.Subnets = subnets
-	if s.verbose {
-		duration := time.Since(start)
-		fmt.Printf("Scanned %d subnets took %v\n", len(subnets), duration)
+
+	// Scan subnets
+	if rescan("subnet") {
+		start = time.Now()
+		subnets, err := s.scanSubnets(ctx, vpcIDs)
+		if err != nil {
+			if err := s.scanFailed(network, "subnet", fmt.Errorf("failed to scan subnets: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.Subnets = subnets
+
+		// Scan subnet CIDR reservations
+		s.scanSubnetCidrReservations(ctx, network.Subnets)
+		step("subnet", len(network.Subnets), start)
+	} else {
+		network.Subnets = append([]Subnet(nil), previous.Subnets...)
 	}
 
 	// Scan peering connections
-	start = time.Now()
-	peeringConnections, err := s.scanPeeringConnections(ctx, vpcIDs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan peering connections: %w", err)
-	}
-	network.PeeringConnections = peeringConnections
-	if s.verbose {
-		duration := time.Since(start)
-		fmt.Printf("Scanned %d peering connections took %v\n", len(peeringConnections), duration)
+	if rescan("peering_connection") {
+		start = time.Now()
+		peeringConnections, err := s.scanPeeringConnections(ctx, vpcIDs)
+		if err != nil {
+			if err := s.scanFailed(network, "peering_connection", fmt.Errorf("failed to scan peering connections: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.PeeringConnections = peeringConnections
+		step("peering_connection", len(peeringConnections), start)
+	} else {
+		network.PeeringConnections = previous.PeeringConnections
 	}
 
 	// Scan transit gateways
-	start = time.Now()
-	transitGateways, err := s.scanTransitGateways(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan transit gateways: %w", err)
-	}
-	network.TransitGateways = transitGateways
-	if s.verbose {
-		duration := time.Since(start)
-		fmt.Printf("Scanned %d transit gateways took %v\n", len(transitGateways), duration)
+	if rescan("transit_gateway") {
+		start = time.Now()
+		transitGateways, err := s.scanTransitGateways(ctx)
+		if err != nil {
+			if err := s.scanFailed(network, "transit_gateway", fmt.Errorf("failed to scan transit gateways: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.TransitGateways = transitGateways
+		for _, tgw := range transitGateways {
+			if tgw.AttachmentsError != "" {
+				s.recordScanError(network, "transit_gateway_attachment", fmt.Errorf("failed to scan attachments of %s: %s", tgw.ID, tgw.AttachmentsError))
+			}
+		}
+		step("transit_gateway", len(transitGateways), start)
+	} else {
+		network.TransitGateways = previous.TransitGateways
 	}
 
 	// Scan internet gateways
-	start = time.Now()
-	internetGateways, err := s.scanInternetGateways(ctx, vpcIDs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan internet gateways: %w", err)
+	if rescan("internet_gateway") {
+		start = time.Now()
+		internetGateways, err := s.scanInternetGateways(ctx, vpcIDs)
+		if err != nil {
+			if err := s.scanFailed(network, "internet_gateway", fmt.Errorf("failed to scan internet gateways: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.InternetGateways = internetGateways
+		step("internet_gateway", len(internetGateways), start)
+	} else {
+		network.InternetGateways = previous.InternetGateways
 	}
-	network.InternetGateways = internetGateways
-	if s.verbose {
-		duration := time.Since(start)
-		fmt.Printf("Scanned %d internet gateways took %v\n", len(internetGateways), duration)
+
+	// Scan egress-only internet gateways
+	if rescan("egress_only_internet_gateway") {
+		start = time.Now()
+		egressOnlyGateways, err := s.scanEgressOnlyInternetGateways(ctx, vpcIDs)
+		if err != nil {
+			if err := s.scanFailed(network, "egress_only_internet_gateway", fmt.Errorf("failed to scan egress-only internet gateways: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.EgressOnlyInternetGateways = egressOnlyGateways
+		step("egress_only_internet_gateway", len(egressOnlyGateways), start)
+	} else {
+		network.EgressOnlyInternetGateways = previous.EgressOnlyInternetGateways
 	}
 
 	// Scan NAT gateways
-	start = time.Now()
-	natGateways, err := s.scanNATGateways(ctx, vpcIDs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan NAT gateways: %w", err)
-	}
-	network.NATGateways = natGateways
-	if s.verbose {
-		duration := time.Since(start)
-		fmt.Printf("Scanned %d NAT gateways took %v\n", len(natGateways), duration)
+	if rescan("nat_gateway") {
+		start = time.Now()
+		natGateways, err := s.scanNATGateways(ctx, vpcIDs)
+		if err != nil {
+			if err := s.scanFailed(network, "nat_gateway", fmt.Errorf("failed to scan NAT gateways: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.NATGateways = natGateways
+		step("nat_gateway", len(natGateways), start)
+	} else {
+		network.NATGateways = previous.NATGateways
 	}
 
 	// Scan route tables
-	start = time.Now()
-	routeTables, err := s.scanRouteTables(ctx, vpcIDs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan route tables: %w", err)
-	}
-	network.RouteTables = routeTables
-	if s.verbose {
-		duration := time.Since(start)
-		fmt.Printf("Scanned %d route tables took %v\n", len(routeTables), duration)
+	if rescan("route_table") {
+		start = time.Now()
+		routeTables, err := s.scanRouteTables(ctx, vpcIDs)
+		if err != nil {
+			if err := s.scanFailed(network, "route_table", fmt.Errorf("failed to scan route tables: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.RouteTables = routeTables
+		step("route_table", len(routeTables), start)
+	} else {
+		network.RouteTables = previous.RouteTables
 	}
 
 	// Scan security groups
-	start = time.Now()
-	securityGroups, err := s.scanSecurityGroups(ctx, vpcIDs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan security groups: %w", err)
-	}
-	network.SecurityGroups = securityGroups
-	if s.verbose {
-		duration := time.Since(start)
-		fmt.Printf("Scanned %d security groups took %v\n", len(securityGroups), duration)
+	if rescan("security_group") {
+		start = time.Now()
+		securityGroups, err := s.scanSecurityGroups(ctx, vpcIDs)
+		if err != nil {
+			if err := s.scanFailed(network, "security_group", fmt.Errorf("failed to scan security groups: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.SecurityGroups = securityGroups
+		step("security_group", len(securityGroups), start)
+	} else {
+		network.SecurityGroups = previous.SecurityGroups
 	}
 
 	// Scan network ACLs
-	start = time.Now()
-	networkAcls, err := s.scanNetworkAcls(ctx, vpcIDs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan network ACLs: %w", err)
+	if rescan("network_acl") {
+		start = time.Now()
+		networkAcls, err := s.scanNetworkAcls(ctx, vpcIDs)
+		if err != nil {
+			if err := s.scanFailed(network, "network_acl", fmt.Errorf("failed to scan network ACLs: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.NetworkAcls = networkAcls
+		step("network_acl", len(networkAcls), start)
+	} else {
+		network.NetworkAcls = previous.NetworkAcls
+	}
+
+	// Scan flow logs
+	if rescan("flow_log") {
+		start = time.Now()
+		flowLogs, err := s.scanFlowLogs(ctx, vpcIDs, network.Subnets)
+		if err != nil {
+			// Log error but continue, flow logs are left unknown rather than missing
+			s.log().Warn("failed to scan flow logs", "error", err)
+			s.recordScanError(network, "flow_log", err)
+		}
+		network.FlowLogs = flowLogs
+		step("flow_log", len(flowLogs), start)
+	} else {
+		network.FlowLogs = previous.FlowLogs
+	}
+
+	// Scan VPC endpoint services
+	if rescan("endpoint_service") {
+		start = time.Now()
+		endpointServices, err := s.scanEndpointServices(ctx)
+		if err != nil {
+			// Log error but continue, endpoint services need their own permissions
+			s.log().Debug("failed to scan VPC endpoint services", "error", err)
+			s.recordScanError(network, "endpoint_service", err)
+		}
+		network.EndpointServices = endpointServices
+		step("endpoint_service", len(endpointServices), start)
+	} else {
+		network.EndpointServices = previous.EndpointServices
+	}
+
+	// Scan PrivateLink VPC endpoints
+	if rescan("vpc_endpoint") {
+		start = time.Now()
+		vpcEndpoints, err := s.scanVpcEndpoints(ctx, vpcIDs)
+		if err != nil {
+			// Log error but continue, endpoints only help resolve private addresses
+			s.log().Warn("failed to scan VPC endpoints", "error", err)
+			s.recordScanError(network, "vpc_endpoint", err)
+		}
+		network.VpcEndpoints = vpcEndpoints
+		step("vpc_endpoint", len(vpcEndpoints), start)
+	} else {
+		network.VpcEndpoints = previous.VpcEndpoints
+	}
+
+	// Scan network interfaces
+	if rescan("network_interface") {
+		start = time.Now()
+		networkInterfaces, err := s.scanNetworkInterfaces(ctx, vpcIDs)
+		if err != nil {
+			// Log error but continue, the interface inventory is optional
+			s.log().Warn("failed to scan network interfaces", "error", err)
+			s.recordScanError(network, "network_interface", err)
+		}
+		network.NetworkInterfaces = networkInterfaces
+		step("network_interface", len(networkInterfaces), start)
+	} else {
+		network.NetworkInterfaces = previous.NetworkInterfaces
+	}
+
+	// Discovery of container, database, edge and App Mesh resources is
+	// not tracked by resource type and is only refreshed by full scans
+	if previous != nil {
+		network.EKSClusters = previous.EKSClusters
+		network.ECSServices = previous.ECSServices
+		network.DatabaseSubnetGroups = previous.DatabaseSubnetGroups
+		network.Databases = previous.Databases
+		network.EdgeIngresses = previous.EdgeIngresses
+		network.MeshVirtualGateways = previous.MeshVirtualGateways
+	}
+
+	// Scan EKS clusters and ECS services
+	if s.scanContainers && previous == nil {
+		start = time.Now()
+		eksClusters, err := s.scanEKSClusters(ctx, vpcIDs)
+		if err != nil {
+			// Log error but continue, container discovery is optional
+			s.log().Warn("failed to scan EKS clusters", "error", err)
+			s.recordScanError(network, "eks_cluster", err)
+		}
+		network.EKSClusters = eksClusters
+		step("eks_cluster", len(eksClusters), start)
+		
+		start = time.Now()
+		ecsServices, err := s.scanECSServices(ctx, network.Subnets)
+		if err != nil {
+			s.log().Warn("failed to scan ECS services", "error", err)
+			s.recordScanError(network, "ecs_service", err)
+		}
+		network.ECSServices = ecsServices
+		step("ecs_service", len(ecsServices), start)
+	}
+
+	// Scan RDS, ElastiCache and Redshift placement
+	if s.scanDatabases && previous == nil {
+		start = time.Now()
+		s.scanDatabasePlacement(ctx, network, vpcIDs)
+		step("database", len(network.Databases), start)
 	}
-	network.NetworkAcls = networkAcls
-	if s.verbose {
-		duration := time.Since(start)
-		fmt.Printf("Scanned %d network ACLs took %v\n", len(networkAcls), duration)
+
+	// Scan Global Accelerator and CloudFront ingress
+	if s.scanEdge && previous == nil {
+		start = time.Now()
+		s.scanEdgeIngress(ctx, network, vpcIDs)
+		step("edge_ingress", len(network.EdgeIngresses), start)
 	}
 
 	// Scan IAM roles
-	start = time.Now()
-	iamRoles, err := s.scanIAMRoles(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan IAM roles: %w", err)
+	if s.scanIAM && !rescan("iam_role") {
+		network.IAMRoles = previous.IAMRoles
+	} else if s.scanIAM {
+		start = time.Now()
+		iamRoles, err := s.scanIAMRoles(ctx, network)
+		if err != nil {
+			if err := s.scanFailed(network, "iam_role", fmt.Errorf("failed to scan IAM roles: %w", err)); err != nil {
+				return nil, err
+			}
+		}
+		network.IAMRoles = iamRoles
+		step("iam_role", len(iamRoles), start)
 	}
-	network.IAMRoles = iamRoles
-	if s.verbose {
-		duration := time.Since(start)
-		fmt.Printf("Scanned %d IAM roles took %v\n", len(iamRoles), duration)
+
+	// Scan App Mesh virtual gateways
+	if s.scanAppMesh && previous == nil {
+		start = time.Now()
+		meshGateways, err := s.scanMeshVirtualGateways(ctx, network, vpcID == "" && len(s.tagFilters) == 0)
+		if err != nil {
+			// Log error but continue, App Mesh discovery is optional
+			s.log().Warn("failed to scan App Mesh virtual gateways", "error", err)
+			s.recordScanError(network, "mesh_virtual_gateway", err)
+		}
+		network.MeshVirtualGateways = meshGateways
+		step("mesh_virtual_gateway", len(meshGateways), start)
 	}
 
-	// Update subnet types based on route tables
-	s.updateSubnetTypes(network)
+	// Optional resources tolerate failed calls, so make sure a cancelled scan is
+	// not returned as a complete one
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("scan cancelled: %w", err)
+	}
 
-	// Update VPC associations
-	s.updateVPCAssociations(network)
+	s.finishNetwork(network)
+
+	// Keep per-resource results for the next scan
+	if s.cache != nil {
+		hits, misses := s.cache.Stats()
+		s.log().Debug("scan cache used", "reused", hits, "fetched", misses)
+		if err := s.cache.Save(); err != nil {
+			s.log().Warn("failed to save scan cache", "error", err)
+		}
+	}
 
 	return network, nil
 }
 
+// finishNetwork derives subnet types and VPC associations from the scanned resources
+func (s *NetworkScanner) finishNetwork(network *Network) {
+	// Update subnet types based on route tables
+	updateSubnetTypes(network)
+
+	// Update VPC associations
+	updateVPCAssociations(network)
+	
+	// Keep the output stable between scans of an unchanged network
+	network.Sort()
+}
+
 // scanVPCs scans VPCs
 func (s *NetworkScanner) scanVPCs(ctx context.Context, vpcID string) ([]VPC, error) {
 	input := &ec2.DescribeVpcsInput{}
@@ -182,8 +525,9 @@ func (s *NetworkScanner) scanVPCs(ctx context.Context, vpcID string) ([]VPC, err
 	if vpcID != "" {
 		input.VpcIds = []string{vpcID}
 	}
+	input.Filters = s.vpcTagFilters()
 
-	result, err := s.client.EC2.DescribeVpcs(ctx, input)
+	result, err := s.apis.EC2.DescribeVpcs(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -193,13 +537,19 @@ func (s *NetworkScanner) scanVPCs(ctx context.Context, vpcID string) ([]VPC, err
 		start := time.Now()
 		
 		v := VPC{
-			ID:            *vpc.VpcId,
-			CidrBlock:     *vpc.CidrBlock,
+			ID:            awssdk.ToString(vpc.VpcId),
+			CidrBlock:     awssdk.ToString(vpc.CidrBlock),
 			State:         string(vpc.State),
 			IsDefault:     vpc.IsDefault != nil && *vpc.IsDefault,
-			DhcpOptionsID: *vpc.DhcpOptionsId,
+			DhcpOptionsID: awssdk.ToString(vpc.DhcpOptionsId),
 			Tags:          convertTags(vpc.Tags),
 		}
+		for _, association := range vpc.Ipv6CidrBlockAssociationSet {
+			if association.Ipv6CidrBlock != nil && association.Ipv6CidrBlockState != nil &&
+				association.Ipv6CidrBlockState.State == types.VpcCidrBlockStateCodeAssociated {
+				v.Ipv6CidrBlocks = append(v.Ipv6CidrBlocks, *association.Ipv6CidrBlock)
+			}
+		}
 		
 		// Get name from tags
 		if name, ok := v.Tags["Name"]; ok {
@@ -208,15 +558,81 @@ func (s *NetworkScanner) scanVPCs(ctx context.Context, vpcID string) ([]VPC, err
 		
 		vpcs = append(vpcs, v)
 		
-		if s.verbose {
-			duration := time.Since(start)
-			fmt.Printf("Scanned vpc %s took %v\n", v.ID, duration)
-		}
+		s.log().Debug("scanned VPC", "vpc_id", v.ID, "duration", time.Since(start))
 	}
 
 	return vpcs, nil
 }
 
+// scanDhcpOptions scans the DHCP option sets associated with the VPCs
+func (s *NetworkScanner) scanDhcpOptions(ctx context.Context, vpcs []VPC) ([]DhcpOptions, error) {
+	// VPCs without an option set report "default"
+	var ids []string
+	seen := make(map[string]bool)
+	for _, vpc := range vpcs {
+		if vpc.DhcpOptionsID == "" || vpc.DhcpOptionsID == "default" || seen[vpc.DhcpOptionsID] {
+			continue
+		}
+		seen[vpc.DhcpOptionsID] = true
+		ids = append(ids, vpc.DhcpOptionsID)
+	}
+	if len(ids) == 0 {
+		return []DhcpOptions{}, nil
+	}
+	sort.Strings(ids)
+
+	input := &ec2.DescribeDhcpOptionsInput{
+		DhcpOptionsIds: ids,
+	}
+
+	result, err := s.apis.EC2.DescribeDhcpOptions(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []DhcpOptions
+	for _, set := range result.DhcpOptions {
+		o := DhcpOptions{
+			ID:   awssdk.ToString(set.DhcpOptionsId),
+			Tags: convertTags(set.Tags),
+		}
+		
+		// Get name from tags
+		if name, ok := o.Tags["Name"]; ok {
+			o.Name = name
+		}
+		
+		for _, config := range set.DhcpConfigurations {
+			if config.Key == nil {
+				continue
+			}
+			var values []string
+			for _, value := range config.Values {
+				if value.Value != nil {
+					values = append(values, *value.Value)
+				}
+			}
+			
+			switch *config.Key {
+			case "domain-name":
+				o.DomainName = strings.Join(values, " ")
+			case "domain-name-servers":
+				o.DomainNameServers = values
+			case "ntp-servers":
+				o.NtpServers = values
+			case "netbios-name-servers":
+				o.NetbiosNameServers = values
+			case "netbios-node-type":
+				o.NetbiosNodeType = strings.Join(values, " ")
+			}
+		}
+		
+		options = append(options, o)
+	}
+
+	return options, nil
+}
+
 // scanSubnets scans subnets
 func (s *NetworkScanner) scanSubnets(ctx context.Context, vpcIDs []string) ([]Subnet, error) {
 	if len(vpcIDs) == 0 {
@@ -232,7 +648,7 @@ func (s *NetworkScanner) scanSubnets(ctx context.Context, vpcIDs []string) ([]Su
 		},
 	}
 
-	result, err := s.client.EC2.DescribeSubnets(ctx, input)
+	result, err := s.apis.EC2.DescribeSubnets(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -240,14 +656,26 @@ func (s *NetworkScanner) scanSubnets(ctx context.Context, vpcIDs []string) ([]Su
 	var subnets []Subnet
 	for _, subnet := range result.Subnets {
 		s := Subnet{
-			ID:               *subnet.SubnetId,
-			VpcID:            *subnet.VpcId,
-			CidrBlock:        *subnet.CidrBlock,
-			AvailabilityZone: *subnet.AvailabilityZone,
+			ID:               awssdk.ToString(subnet.SubnetId),
+			VpcID:            awssdk.ToString(subnet.VpcId),
+			CidrBlock:        awssdk.ToString(subnet.CidrBlock),
+			AvailabilityZone: awssdk.ToString(subnet.AvailabilityZone),
 			State:            string(subnet.State),
 			MapPublicIP:      subnet.MapPublicIpOnLaunch != nil && *subnet.MapPublicIpOnLaunch,
+			AssignIpv6Address: subnet.AssignIpv6AddressOnCreation != nil && *subnet.AssignIpv6AddressOnCreation,
 			Tags:             convertTags(subnet.Tags),
 		}
+		for _, association := range subnet.Ipv6CidrBlockAssociationSet {
+			if association.Ipv6CidrBlock != nil && association.Ipv6CidrBlockState != nil &&
+				association.Ipv6CidrBlockState.State == types.SubnetCidrBlockStateCodeAssociated {
+				s.Ipv6CidrBlocks = append(s.Ipv6CidrBlocks, *association.Ipv6CidrBlock)
+			}
+		}
+		s.TotalIPs = s.UsableIPv4AddressCount()
+		if subnet.AvailableIpAddressCount != nil {
+			available := int(*subnet.AvailableIpAddressCount)
+			s.AvailableIPs = &available
+		}
 		
 		// Get name from tags
 		if name, ok := s.Tags["Name"]; ok {
@@ -260,6 +688,63 @@ func (s *NetworkScanner) scanSubnets(ctx context.Context, vpcIDs []string) ([]Su
 	return subnets, nil
 }
 
+// scanSubnetCidrReservations attaches CIDR reservations to each subnet
+func (s *NetworkScanner) scanSubnetCidrReservations(ctx context.Context, subnets []Subnet) {
+	for i := range subnets {
+		reservations, err := cachedCall(s.cache, "subnet-cidr-reservations:"+subnets[i].ID, false, func() ([]SubnetCidrReservation, error) {
+			return s.getSubnetCidrReservations(ctx, subnets[i].ID)
+		})
+		if err != nil {
+			// Log error but continue, reservations are optional detail
+			s.log().Debug("failed to get subnet CIDR reservations", "subnet_id", subnets[i].ID, "error", err)
+			continue
+		}
+		subnets[i].CidrReservations = reservations
+	}
+}
+
+// getSubnetCidrReservations gets the IPv4 and IPv6 CIDR reservations of a subnet
+func (s *NetworkScanner) getSubnetCidrReservations(ctx context.Context, subnetID string) ([]SubnetCidrReservation, error) {
+	input := &ec2.GetSubnetCidrReservationsInput{
+		SubnetId: &subnetID,
+	}
+
+	var reservations []SubnetCidrReservation
+	for {
+		result, err := s.apis.EC2.GetSubnetCidrReservations(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		all := append(result.SubnetIpv4CidrReservations, result.SubnetIpv6CidrReservations...)
+		for _, res := range all {
+			r := SubnetCidrReservation{
+				ReservationType: string(res.ReservationType),
+				Tags:            convertTags(res.Tags),
+			}
+
+			if res.SubnetCidrReservationId != nil {
+				r.ID = *res.SubnetCidrReservationId
+			}
+			if res.Cidr != nil {
+				r.Cidr = *res.Cidr
+			}
+			if res.Description != nil {
+				r.Description = *res.Description
+			}
+
+			reservations = append(reservations, r)
+		}
+
+		if result.NextToken == nil {
+			break
+		}
+		input.NextToken = result.NextToken
+	}
+
+	return reservations, nil
+}
+
 // scanPeeringConnections scans VPC peering connections
 func (s *NetworkScanner) scanPeeringConnections(ctx context.Context, vpcIDs []string) ([]PeeringConnection, error) {
 	if len(vpcIDs) == 0 {
@@ -268,7 +753,7 @@ func (s *NetworkScanner) scanPeeringConnections(ctx context.Context, vpcIDs []st
 
 	input := &ec2.DescribeVpcPeeringConnectionsInput{}
 
-	result, err := s.client.EC2.DescribeVpcPeeringConnections(ctx, input)
+	result, err := s.apis.EC2.DescribeVpcPeeringConnections(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -299,12 +784,21 @@ func (s *NetworkScanner) scanPeeringConnections(ctx context.Context, vpcIDs []st
 		}
 
 		pc := PeeringConnection{
-			ID:             *conn.VpcPeeringConnectionId,
+			ID:             awssdk.ToString(conn.VpcPeeringConnectionId),
 			RequesterVpcID: requesterVpcID,
 			AccepterVpcID:  accepterVpcID,
-			Status:         string(conn.Status.Code),
 			Tags:           convertTags(conn.Tags),
 		}
+		if conn.Status != nil {
+			pc.Status = string(conn.Status.Code)
+		}
+		
+		if conn.RequesterVpcInfo != nil && conn.RequesterVpcInfo.Region != nil {
+			pc.RequesterRegion = *conn.RequesterVpcInfo.Region
+		}
+		if conn.AccepterVpcInfo != nil && conn.AccepterVpcInfo.Region != nil {
+			pc.AccepterRegion = *conn.AccepterVpcInfo.Region
+		}
 		
 		// Get name from tags
 		if name, ok := pc.Tags["Name"]; ok {
@@ -321,7 +815,7 @@ func (s *NetworkScanner) scanPeeringConnections(ctx context.Context, vpcIDs []st
 func (s *NetworkScanner) scanTransitGateways(ctx context.Context) ([]TransitGateway, error) {
 	input := &ec2.DescribeTransitGatewaysInput{}
 
-	result, err := s.client.EC2.DescribeTransitGateways(ctx, input)
+	result, err := s.apis.EC2.DescribeTransitGateways(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -329,7 +823,7 @@ func (s *NetworkScanner) scanTransitGateways(ctx context.Context) ([]TransitGate
 	var tgws []TransitGateway
 	for _, tgw := range result.TransitGateways {
 		t := TransitGateway{
-			ID:    *tgw.TransitGatewayId,
+			ID:    awssdk.ToString(tgw.TransitGatewayId),
 			State: string(tgw.State),
 			Tags:  convertTags(tgw.Tags),
 		}
@@ -339,14 +833,21 @@ func (s *NetworkScanner) scanTransitGateways(ctx context.Context) ([]TransitGate
 			t.Name = name
 		}
 		
-		// Get attachments
+		// Get attachments, keeping the transit gateway without them when they fail
 		attachments, err := s.scanTransitGatewayAttachments(ctx, t.ID)
 		if err != nil {
-			// Log error but continue
-			continue
+			s.log().Warn("failed to scan transit gateway attachments", "transit_gateway_id", t.ID, "error", err)
+			t.AttachmentsError = err.Error()
 		}
 		t.Attachments = attachments
 		
+		// Route tables are needed for path tracing only, so a failure is not fatal
+		routeTables, err := s.scanTransitGatewayRouteTables(ctx, t.ID)
+		if err != nil {
+			s.log().Debug("failed to scan transit gateway route tables", "transit_gateway_id", t.ID, "error", err)
+		}
+		t.RouteTables = routeTables
+		
 		tgws = append(tgws, t)
 	}
 
@@ -364,7 +865,7 @@ func (s *NetworkScanner) scanTransitGatewayAttachments(ctx context.Context, tgwI
 		},
 	}
 
-	result, err := s.client.EC2.DescribeTransitGatewayAttachments(ctx, input)
+	result, err := s.apis.EC2.DescribeTransitGatewayAttachments(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -372,8 +873,8 @@ func (s *NetworkScanner) scanTransitGatewayAttachments(ctx context.Context, tgwI
 	var attachments []TransitGatewayAttachment
 	for _, att := range result.TransitGatewayAttachments {
 		a := TransitGatewayAttachment{
-			ID:               *att.TransitGatewayAttachmentId,
-			TransitGatewayID: *att.TransitGatewayId,
+			ID:               awssdk.ToString(att.TransitGatewayAttachmentId),
+			TransitGatewayID: awssdk.ToString(att.TransitGatewayId),
 			ResourceType:     string(att.ResourceType),
 			State:            string(att.State),
 			Tags:             convertTags(att.Tags),
@@ -382,6 +883,13 @@ func (s *NetworkScanner) scanTransitGatewayAttachments(ctx context.Context, tgwI
 		if att.ResourceId != nil {
 			a.ResourceID = *att.ResourceId
 		}
+		if att.Association != nil && att.Association.TransitGatewayRouteTableId != nil {
+			a.RouteTableID = *att.Association.TransitGatewayRouteTableId
+		}
+		
+		if att.ResourceType == types.TransitGatewayAttachmentResourceTypePeering {
+			s.resolveTransitGatewayPeer(ctx, &a)
+		}
 		
 		attachments = append(attachments, a)
 	}
@@ -389,11 +897,137 @@ func (s *NetworkScanner) scanTransitGatewayAttachments(ctx context.Context, tgwI
 	return attachments, nil
 }
 
+// scanTransitGatewayRouteTables scans a transit gateway's route tables and their active and blackhole routes
+func (s *NetworkScanner) scanTransitGatewayRouteTables(ctx context.Context, tgwID string) ([]TransitGatewayRouteTable, error) {
+	input := &ec2.DescribeTransitGatewayRouteTablesInput{
+		Filters: []types.Filter{
+			{
+				Name:   &[]string{"transit-gateway-id"}[0],
+				Values: []string{tgwID},
+			},
+		},
+	}
+
+	result, err := s.apis.EC2.DescribeTransitGatewayRouteTables(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var routeTables []TransitGatewayRouteTable
+	for _, rt := range result.TransitGatewayRouteTables {
+		r := TransitGatewayRouteTable{
+			ID:    awssdk.ToString(rt.TransitGatewayRouteTableId),
+			State: string(rt.State),
+			Tags:  convertTags(rt.Tags),
+		}
+		if rt.DefaultAssociationRouteTable != nil {
+			r.DefaultAssociation = *rt.DefaultAssociationRouteTable
+		}
+		if rt.DefaultPropagationRouteTable != nil {
+			r.DefaultPropagation = *rt.DefaultPropagationRouteTable
+		}
+		
+		// Get name from tags
+		if name, ok := r.Tags["Name"]; ok {
+			r.Name = name
+		}
+		
+		routes, err := cachedCall(s.cache, "transit-gateway-routes:"+r.ID, false, func() ([]TransitGatewayRoute, error) {
+			return s.searchTransitGatewayRoutes(ctx, r.ID)
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.Routes = routes
+		
+		routeTables = append(routeTables, r)
+	}
+
+	return routeTables, nil
+}
+
+// searchTransitGatewayRoutes lists the active and blackhole routes of a transit gateway route table
+func (s *NetworkScanner) searchTransitGatewayRoutes(ctx context.Context, routeTableID string) ([]TransitGatewayRoute, error) {
+	// SearchTransitGatewayRoutes requires a filter, the route states select every route
+	result, err := s.apis.EC2.SearchTransitGatewayRoutes(ctx, &ec2.SearchTransitGatewayRoutesInput{
+		TransitGatewayRouteTableId: &routeTableID,
+		Filters: []types.Filter{
+			{
+				Name:   &[]string{"state"}[0],
+				Values: []string{"active", "blackhole"},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []TransitGatewayRoute
+	for _, route := range result.Routes {
+		r := TransitGatewayRoute{
+			Type:  string(route.Type),
+			State: string(route.State),
+		}
+		if route.DestinationCidrBlock != nil {
+			r.DestinationCidr = *route.DestinationCidrBlock
+		}
+		if route.PrefixListId != nil {
+			r.PrefixListID = *route.PrefixListId
+		}
+		if len(route.TransitGatewayAttachments) > 0 {
+			attachment := route.TransitGatewayAttachments[0]
+			if attachment.TransitGatewayAttachmentId != nil {
+				r.AttachmentID = *attachment.TransitGatewayAttachmentId
+			}
+			if attachment.ResourceId != nil {
+				r.ResourceID = *attachment.ResourceId
+			}
+			r.ResourceType = string(attachment.ResourceType)
+		}
+		routes = append(routes, r)
+	}
+
+	return routes, nil
+}
+
+// resolveTransitGatewayPeer fills in the transit gateway and region on the other side of a peering attachment
+func (s *NetworkScanner) resolveTransitGatewayPeer(ctx context.Context, attachment *TransitGatewayAttachment) {
+	result, err := s.apis.EC2.DescribeTransitGatewayPeeringAttachments(ctx, &ec2.DescribeTransitGatewayPeeringAttachmentsInput{
+		TransitGatewayAttachmentIds: []string{attachment.ID},
+	})
+	if err != nil {
+		// Log error but continue, the peer is informational
+		s.log().Debug("failed to describe transit gateway peering attachment", "attachment_id", attachment.ID, "error", err)
+		return
+	}
+	if len(result.TransitGatewayPeeringAttachments) == 0 {
+		return
+	}
+
+	peering := result.TransitGatewayPeeringAttachments[0]
+
+	// The peer is whichever side is not this transit gateway
+	peer := peering.AccepterTgwInfo
+	if peer != nil && peer.TransitGatewayId != nil && *peer.TransitGatewayId == attachment.TransitGatewayID {
+		peer = peering.RequesterTgwInfo
+	}
+	if peer == nil {
+		return
+	}
+
+	if peer.TransitGatewayId != nil {
+		attachment.PeerTransitGatewayID = *peer.TransitGatewayId
+	}
+	if peer.Region != nil {
+		attachment.PeerRegion = *peer.Region
+	}
+}
+
 // scanInternetGateways scans internet gateways
 func (s *NetworkScanner) scanInternetGateways(ctx context.Context, vpcIDs []string) ([]InternetGateway, error) {
 	input := &ec2.DescribeInternetGatewaysInput{}
 
-	result, err := s.client.EC2.DescribeInternetGateways(ctx, input)
+	result, err := s.apis.EC2.DescribeInternetGateways(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -420,7 +1054,7 @@ func (s *NetworkScanner) scanInternetGateways(ctx context.Context, vpcIDs []stri
 			}
 			
 			ig := InternetGateway{
-				ID:    *igw.InternetGatewayId,
+				ID:    awssdk.ToString(igw.InternetGatewayId),
 				VpcID: vpcID,
 				State: string(attachment.State),
 				Tags:  convertTags(igw.Tags),
@@ -438,6 +1072,50 @@ func (s *NetworkScanner) scanInternetGateways(ctx context.Context, vpcIDs []stri
 	return igws, nil
 }
 
+// scanEgressOnlyInternetGateways scans the egress-only internet gateways attached to the VPCs
+func (s *NetworkScanner) scanEgressOnlyInternetGateways(ctx context.Context, vpcIDs []string) ([]EgressOnlyInternetGateway, error) {
+	if len(vpcIDs) == 0 {
+		return []EgressOnlyInternetGateway{}, nil
+	}
+	relevant := make(map[string]bool)
+	for _, id := range vpcIDs {
+		relevant[id] = true
+	}
+
+	var gateways []EgressOnlyInternetGateway
+	input := &ec2.DescribeEgressOnlyInternetGatewaysInput{}
+	for {
+		result, err := s.apis.EC2.DescribeEgressOnlyInternetGateways(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, gateway := range result.EgressOnlyInternetGateways {
+			for _, attachment := range gateway.Attachments {
+				if attachment.VpcId == nil || !relevant[*attachment.VpcId] {
+					continue
+				}
+
+				g := EgressOnlyInternetGateway{
+					ID:    awssdk.ToString(gateway.EgressOnlyInternetGatewayId),
+					VpcID: *attachment.VpcId,
+					State: string(attachment.State),
+					Tags:  convertTags(gateway.Tags),
+				}
+				if name, ok := g.Tags["Name"]; ok {
+					g.Name = name
+				}
+				gateways = append(gateways, g)
+			}
+		}
+
+		if result.NextToken == nil {
+			return gateways, nil
+		}
+		input.NextToken = result.NextToken
+	}
+}
+
 // scanNATGateways scans NAT gateways
 func (s *NetworkScanner) scanNATGateways(ctx context.Context, vpcIDs []string) ([]NATGateway, error) {
 	if len(vpcIDs) == 0 {
@@ -446,7 +1124,7 @@ func (s *NetworkScanner) scanNATGateways(ctx context.Context, vpcIDs []string) (
 
 	input := &ec2.DescribeNatGatewaysInput{}
 
-	result, err := s.client.EC2.DescribeNatGateways(ctx, input)
+	result, err := s.apis.EC2.DescribeNatGateways(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -472,9 +1150,9 @@ func (s *NetworkScanner) scanNATGateways(ctx context.Context, vpcIDs []string) (
 		}
 		
 		ng := NATGateway{
-			ID:               *nat.NatGatewayId,
+			ID:               awssdk.ToString(nat.NatGatewayId),
 			VpcID:            vpcID,
-			SubnetID:         *nat.SubnetId,
+			SubnetID:         awssdk.ToString(nat.SubnetId),
 			State:            string(nat.State),
 			ConnectivityType: string(nat.ConnectivityType),
 			Tags:             convertTags(nat.Tags),
@@ -516,7 +1194,7 @@ func (s *NetworkScanner) scanRouteTables(ctx context.Context, vpcIDs []string) (
 		},
 	}
 
-	result, err := s.client.EC2.DescribeRouteTables(ctx, input)
+	result, err := s.apis.EC2.DescribeRouteTables(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -524,8 +1202,8 @@ func (s *NetworkScanner) scanRouteTables(ctx context.Context, vpcIDs []string) (
 	var routeTables []RouteTable
 	for _, rt := range result.RouteTables {
 		r := RouteTable{
-			ID:    *rt.RouteTableId,
-			VpcID: *rt.VpcId,
+			ID:    awssdk.ToString(rt.RouteTableId),
+			VpcID: awssdk.ToString(rt.VpcId),
 			Tags:  convertTags(rt.Tags),
 		}
 		
@@ -554,9 +1232,27 @@ func (s *NetworkScanner) scanRouteTables(ctx context.Context, vpcIDs []string) (
 			if route.DestinationCidrBlock != nil {
 				ro.DestinationCidr = *route.DestinationCidrBlock
 			}
+			if route.DestinationIpv6CidrBlock != nil {
+				ro.DestinationIpv6Cidr = *route.DestinationIpv6CidrBlock
+			}
+			if route.DestinationPrefixListId != nil {
+				ro.DestinationPrefixListID = *route.DestinationPrefixListId
+			}
 			if route.GatewayId != nil {
 				ro.GatewayID = *route.GatewayId
 			}
+			if route.NatGatewayId != nil {
+				ro.NatGatewayID = *route.NatGatewayId
+			}
+			if route.EgressOnlyInternetGatewayId != nil {
+				ro.EgressOnlyGatewayID = *route.EgressOnlyInternetGatewayId
+			}
+			if route.CarrierGatewayId != nil {
+				ro.CarrierGatewayID = *route.CarrierGatewayId
+			}
+			if route.LocalGatewayId != nil {
+				ro.LocalGatewayID = *route.LocalGatewayId
+			}
 			if route.InstanceId != nil {
 				ro.InstanceID = *route.InstanceId
 			}
@@ -594,125 +1290,228 @@ func (s *NetworkScanner) scanSecurityGroups(ctx context.Context, vpcIDs []string
 		},
 	}
 
-	result, err := s.client.EC2.DescribeSecurityGroups(ctx, input)
+	result, err := s.apis.EC2.DescribeSecurityGroups(ctx, input)
 	if err != nil {
 		return nil, err
 	}
 
+	// Rules from DescribeSecurityGroupRules carry stable rule IDs, per-rule
+	// descriptions and tags, which the groups' permissions lack
+	groupIDs := make([]string, 0, len(result.SecurityGroups))
+	for _, sg := range result.SecurityGroups {
+		if sg.GroupId != nil {
+			groupIDs = append(groupIDs, *sg.GroupId)
+		}
+	}
+	rules, err := s.scanSecurityGroupRules(ctx, groupIDs)
+	if err != nil {
+		// Log error but continue with the rules in the groups' permissions
+		s.log().Warn("failed to describe security group rules, recording rules without IDs", "error", err)
+	}
+
 	var securityGroups []SecurityGroup
 	for _, sg := range result.SecurityGroups {
 		s := SecurityGroup{
-			ID:          *sg.GroupId,
-			Name:        *sg.GroupName,
-			Description: *sg.Description,
-			VpcID:       *sg.VpcId,
+			ID:          awssdk.ToString(sg.GroupId),
+			Name:        awssdk.ToString(sg.GroupName),
+			Description: awssdk.ToString(sg.Description),
+			VpcID:       awssdk.ToString(sg.VpcId),
 			Tags:        convertTags(sg.Tags),
 		}
 
-		// Convert ingress rules
-		for _, rule := range sg.IpPermissions {
-			sgRule := SecurityGroupRule{
-				IpProtocol: *rule.IpProtocol,
-			}
+		if rules != nil {
+			s.IngressRules = rules[s.ID].ingress
+			s.EgressRules = rules[s.ID].egress
+		} else {
+			s.IngressRules = convertIpPermissions(sg.IpPermissions)
+			s.EgressRules = convertIpPermissions(sg.IpPermissionsEgress)
+		}
 
-			if rule.FromPort != nil {
-				sgRule.FromPort = *rule.FromPort
-			}
-			if rule.ToPort != nil {
-				sgRule.ToPort = *rule.ToPort
-			}
+		securityGroups = append(securityGroups, s)
+	}
 
-			// Convert IP ranges
-			for _, ipRange := range rule.IpRanges {
-				if ipRange.CidrIp != nil {
-					sgRule.CidrBlocks = append(sgRule.CidrBlocks, *ipRange.CidrIp)
-				}
-			}
+	return securityGroups, nil
+}
 
-			// Convert IPv6 ranges
-			for _, ipv6Range := range rule.Ipv6Ranges {
-				if ipv6Range.CidrIpv6 != nil {
-					sgRule.Ipv6CidrBlocks = append(sgRule.Ipv6CidrBlocks, *ipv6Range.CidrIpv6)
-				}
-			}
+// groupRules holds a security group's rules by direction
+type groupRules struct {
+	ingress []SecurityGroupRule
+	egress  []SecurityGroupRule
+}
 
-			// Convert prefix lists
-			for _, prefixList := range rule.PrefixListIds {
-				if prefixList.PrefixListId != nil {
-					sgRule.PrefixListIds = append(sgRule.PrefixListIds, *prefixList.PrefixListId)
-				}
+// securityGroupRuleBatch is how many group IDs each DescribeSecurityGroupRules call filters on
+const securityGroupRuleBatch = 200
+
+// scanSecurityGroupRules describes the rules of security groups, keyed by group ID.
+// Each rule has a single source, so a permission allowing two CIDR blocks is two rules.
+func (s *NetworkScanner) scanSecurityGroupRules(ctx context.Context, groupIDs []string) (map[string]groupRules, error) {
+	rules := make(map[string]groupRules)
+	for start := 0; start < len(groupIDs); start += securityGroupRuleBatch {
+		end := start + securityGroupRuleBatch
+		if end > len(groupIDs) {
+			end = len(groupIDs)
+		}
+
+		input := &ec2.DescribeSecurityGroupRulesInput{
+			Filters: []types.Filter{
+				{
+					Name:   &[]string{"group-id"}[0],
+					Values: groupIDs[start:end],
+				},
+			},
+		}
+		for {
+			result, err := s.apis.EC2.DescribeSecurityGroupRules(ctx, input)
+			if err != nil {
+				return nil, err
 			}
 
-			// Convert user ID group pairs (referenced security groups)
-			for _, userIdGroupPair := range rule.UserIdGroupPairs {
-				if userIdGroupPair.GroupId != nil {
-					sgRule.ReferencedGroupId = *userIdGroupPair.GroupId
+			for _, rule := range result.SecurityGroupRules {
+				if rule.GroupId == nil {
+					continue
 				}
-				if userIdGroupPair.UserId != nil {
-					sgRule.ReferencedGroupOwnerId = *userIdGroupPair.UserId
-				}
-				if userIdGroupPair.Description != nil {
-					sgRule.Description = *userIdGroupPair.Description
+				group := rules[*rule.GroupId]
+				if rule.IsEgress != nil && *rule.IsEgress {
+					group.egress = append(group.egress, convertSecurityGroupRule(rule))
+				} else {
+					group.ingress = append(group.ingress, convertSecurityGroupRule(rule))
 				}
+				rules[*rule.GroupId] = group
 			}
 
-			s.IngressRules = append(s.IngressRules, sgRule)
+			if result.NextToken == nil {
+				break
+			}
+			input.NextToken = result.NextToken
 		}
+	}
 
-		// Convert egress rules
-		for _, rule := range sg.IpPermissionsEgress {
-			sgRule := SecurityGroupRule{
-				IpProtocol: *rule.IpProtocol,
-			}
+	for groupID, group := range rules {
+		sort.Slice(group.ingress, func(i, j int) bool { return group.ingress[i].ID < group.ingress[j].ID })
+		sort.Slice(group.egress, func(i, j int) bool { return group.egress[i].ID < group.egress[j].ID })
+		rules[groupID] = group
+	}
+	return rules, nil
+}
 
-			if rule.FromPort != nil {
-				sgRule.FromPort = *rule.FromPort
-			}
-			if rule.ToPort != nil {
-				sgRule.ToPort = *rule.ToPort
-			}
+// convertSecurityGroupRule converts a rule returned by DescribeSecurityGroupRules
+func convertSecurityGroupRule(rule types.SecurityGroupRule) SecurityGroupRule {
+	r := SecurityGroupRule{
+		Tags: convertTags(rule.Tags),
+	}
+	if rule.SecurityGroupRuleId != nil {
+		r.ID = *rule.SecurityGroupRuleId
+	}
+	if rule.IpProtocol != nil {
+		r.IpProtocol = *rule.IpProtocol
+	}
+	// All-traffic rules report -1 ports, which the groups' permissions leave out
+	if r.IpProtocol != "-1" {
+		if rule.FromPort != nil {
+			r.FromPort = *rule.FromPort
+		}
+		if rule.ToPort != nil {
+			r.ToPort = *rule.ToPort
+		}
+	}
+	if rule.Description != nil {
+		r.Description = *rule.Description
+	}
 
-			// Convert IP ranges
-			for _, ipRange := range rule.IpRanges {
-				if ipRange.CidrIp != nil {
-					sgRule.CidrBlocks = append(sgRule.CidrBlocks, *ipRange.CidrIp)
-				}
-			}
+	if rule.CidrIpv4 != nil {
+		r.CidrBlocks = []string{*rule.CidrIpv4}
+	}
+	if rule.CidrIpv6 != nil {
+		r.Ipv6CidrBlocks = []string{*rule.CidrIpv6}
+	}
+	if rule.PrefixListId != nil {
+		r.PrefixListIds = []string{*rule.PrefixListId}
+	}
+	if info := rule.ReferencedGroupInfo; info != nil && info.GroupId != nil {
+		reference := SecurityGroupReference{GroupID: *info.GroupId, Description: r.Description}
+		if info.UserId != nil {
+			reference.OwnerID = *info.UserId
+		}
+		if info.VpcId != nil {
+			reference.VpcID = *info.VpcId
+		}
+		if info.VpcPeeringConnectionId != nil {
+			reference.PeeringID = *info.VpcPeeringConnectionId
+		}
+		r.ReferencedGroups = []SecurityGroupReference{reference}
+	}
+	return r
+}
 
-			// Convert IPv6 ranges
-			for _, ipv6Range := range rule.Ipv6Ranges {
-				if ipv6Range.CidrIpv6 != nil {
-					sgRule.Ipv6CidrBlocks = append(sgRule.Ipv6CidrBlocks, *ipv6Range.CidrIpv6)
-				}
-			}
+// convertIpPermissions converts a security group's permissions to rules without IDs
+func convertIpPermissions(permissions []types.IpPermission) []SecurityGroupRule {
+	var rules []SecurityGroupRule
+	for _, rule := range permissions {
+		sgRule := SecurityGroupRule{
+			IpProtocol: awssdk.ToString(rule.IpProtocol),
+		}
 
-			// Convert prefix lists
-			for _, prefixList := range rule.PrefixListIds {
-				if prefixList.PrefixListId != nil {
-					sgRule.PrefixListIds = append(sgRule.PrefixListIds, *prefixList.PrefixListId)
-				}
+		if rule.FromPort != nil {
+			sgRule.FromPort = *rule.FromPort
+		}
+		if rule.ToPort != nil {
+			sgRule.ToPort = *rule.ToPort
+		}
+
+		// Convert IP ranges
+		for _, ipRange := range rule.IpRanges {
+			if ipRange.CidrIp != nil {
+				sgRule.CidrBlocks = append(sgRule.CidrBlocks, *ipRange.CidrIp)
 			}
+		}
 
-			// Convert user ID group pairs (referenced security groups)
-			for _, userIdGroupPair := range rule.UserIdGroupPairs {
-				if userIdGroupPair.GroupId != nil {
-					sgRule.ReferencedGroupId = *userIdGroupPair.GroupId
-				}
-				if userIdGroupPair.UserId != nil {
-					sgRule.ReferencedGroupOwnerId = *userIdGroupPair.UserId
-				}
-				if userIdGroupPair.Description != nil {
-					sgRule.Description = *userIdGroupPair.Description
-				}
+		// Convert IPv6 ranges
+		for _, ipv6Range := range rule.Ipv6Ranges {
+			if ipv6Range.CidrIpv6 != nil {
+				sgRule.Ipv6CidrBlocks = append(sgRule.Ipv6CidrBlocks, *ipv6Range.CidrIpv6)
 			}
+		}
 
-			s.EgressRules = append(s.EgressRules, sgRule)
+		// Convert prefix lists
+		for _, prefixList := range rule.PrefixListIds {
+			if prefixList.PrefixListId != nil {
+				sgRule.PrefixListIds = append(sgRule.PrefixListIds, *prefixList.PrefixListId)
+			}
 		}
 
-		securityGroups = append(securityGroups, s)
+		// Convert user ID group pairs (referenced security groups)
+		convertGroupPairs(&sgRule, rule.UserIdGroupPairs)
+
+		rules = append(rules, sgRule)
 	}
+	return rules
+}
 
-	return securityGroups, nil
+// convertGroupPairs adds the security groups referenced by a rule's user ID group pairs
+func convertGroupPairs(sgRule *SecurityGroupRule, pairs []types.UserIdGroupPair) {
+	for _, pair := range pairs {
+		if pair.GroupId == nil {
+			continue
+		}
+		reference := SecurityGroupReference{GroupID: *pair.GroupId}
+		if pair.UserId != nil {
+			reference.OwnerID = *pair.UserId
+		}
+		if pair.VpcId != nil {
+			reference.VpcID = *pair.VpcId
+		}
+		if pair.VpcPeeringConnectionId != nil {
+			reference.PeeringID = *pair.VpcPeeringConnectionId
+		}
+		if pair.Description != nil {
+			reference.Description = *pair.Description
+			// The rule takes the first description
+			if sgRule.Description == "" {
+				sgRule.Description = *pair.Description
+			}
+		}
+		sgRule.ReferencedGroups = append(sgRule.ReferencedGroups, reference)
+	}
 }
 
 // scanNetworkAcls scans network ACLs and their entries
@@ -730,7 +1529,7 @@ func (s *NetworkScanner) scanNetworkAcls(ctx context.Context, vpcIDs []string) (
 		},
 	}
 
-	result, err := s.client.EC2.DescribeNetworkAcls(ctx, input)
+	result, err := s.apis.EC2.DescribeNetworkAcls(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -738,8 +1537,8 @@ func (s *NetworkScanner) scanNetworkAcls(ctx context.Context, vpcIDs []string) (
 	var networkAcls []NetworkAcl
 	for _, nacl := range result.NetworkAcls {
 		n := NetworkAcl{
-			ID:        *nacl.NetworkAclId,
-			VpcID:     *nacl.VpcId,
+			ID:        awssdk.ToString(nacl.NetworkAclId),
+			VpcID:     awssdk.ToString(nacl.VpcId),
 			IsDefault: nacl.IsDefault != nil && *nacl.IsDefault,
 			Tags:      convertTags(nacl.Tags),
 		}
@@ -759,8 +1558,8 @@ func (s *NetworkScanner) scanNetworkAcls(ctx context.Context, vpcIDs []string) (
 		// Convert entries
 		for _, entry := range nacl.Entries {
 			e := NetworkAclEntry{
-				RuleNumber: *entry.RuleNumber,
-				Protocol:   *entry.Protocol,
+				RuleNumber: awssdk.ToInt32(entry.RuleNumber),
+				Protocol:   awssdk.ToString(entry.Protocol),
 				RuleAction: string(entry.RuleAction),
 				Egress:     entry.Egress != nil && *entry.Egress,
 			}
@@ -775,16 +1574,16 @@ func (s *NetworkScanner) scanNetworkAcls(ctx context.Context, vpcIDs []string) (
 			// Handle port range
 			if entry.PortRange != nil {
 				e.PortRange = &NetworkAclPortRange{
-					From: *entry.PortRange.From,
-					To:   *entry.PortRange.To,
+					From: awssdk.ToInt32(entry.PortRange.From),
+					To:   awssdk.ToInt32(entry.PortRange.To),
 				}
 			}
 
 			// Handle ICMP type
 			if entry.IcmpTypeCode != nil {
 				e.IcmpType = &NetworkAclIcmpType{
-					Type: *entry.IcmpTypeCode.Type,
-					Code: *entry.IcmpTypeCode.Code,
+					Type: awssdk.ToInt32(entry.IcmpTypeCode.Type),
+					Code: awssdk.ToInt32(entry.IcmpTypeCode.Code),
 				}
 			}
 
@@ -798,7 +1597,7 @@ func (s *NetworkScanner) scanNetworkAcls(ctx context.Context, vpcIDs []string) (
 }
 
 // updateSubnetTypes determines subnet types based on route tables
-func (s *NetworkScanner) updateSubnetTypes(network *Network) {
+func updateSubnetTypes(network *Network) {
 	// Create a map of route table ID to route table
 	routeTableMap := make(map[string]*RouteTable)
 	for i := range network.RouteTables {
@@ -850,45 +1649,64 @@ func (s *NetworkScanner) updateSubnetTypes(network *Network) {
 		
 		// Determine subnet type based on routes
 		if routeTable != nil {
-			subnet.Type = determineSubnetType(routeTable, network.InternetGateways)
+			subnet.Type, subnet.TypeRoute = classifySubnet(routeTable, network.InternetGateways)
 		} else {
-			subnet.Type = "isolated"
+			subnet.Type, subnet.TypeRoute = "isolated", nil
 		}
 	}
 }
 
-// determineSubnetType determines if a subnet is public, private, or isolated
-func determineSubnetType(routeTable *RouteTable, igws []InternetGateway) string {
-	hasIGWRoute := false
-	hasNATRoute := false
+// classifySubnet determines a subnet's type from its route table and returns the
+// route that decided it. Any route to an attached internet gateway makes the
+// subnet public, even one for only part of the internet, and a default route
+// (0.0.0.0/0 or ::/0) is preferred as the deciding route. Otherwise a default
+// route through a NAT gateway or, for IPv6, an egress-only internet gateway
+// makes it private, as instances can reach the internet but not be reached.
+func classifySubnet(routeTable *RouteTable, igws []InternetGateway) (string, *Route) {
+	attached := make(map[string]bool)
+	for _, igw := range igws {
+		attached[igw.ID] = true
+	}
 	
-	for _, route := range routeTable.Routes {
-		// Check for internet gateway route
-		if strings.HasPrefix(route.GatewayID, "igw-") {
-			for _, igw := range igws {
-				if igw.ID == route.GatewayID && route.DestinationCidr == "0.0.0.0/0" {
-					hasIGWRoute = true
-					break
-				}
+	var igwRoute, natRoute *Route
+	for i := range routeTable.Routes {
+		route := &routeTable.Routes[i]
+		if route.State == "blackhole" {
+			continue
+		}
+		
+		// Check for internet gateway routes
+		if strings.HasPrefix(route.GatewayID, "igw-") && attached[route.GatewayID] {
+			if igwRoute == nil || (isDefaultRoute(*route) && !isDefaultRoute(*igwRoute)) {
+				igwRoute = route
 			}
+			continue
 		}
 		
-		// Check for NAT gateway route
-		if strings.HasPrefix(route.GatewayID, "nat-") && route.DestinationCidr == "0.0.0.0/0" {
-			hasNATRoute = true
+		// Check for NAT gateway and egress-only internet gateway routes
+		if isDefaultRoute(*route) && natRoute == nil &&
+			(route.NatGatewayID != "" || route.EgressOnlyGatewayID != "") {
+			natRoute = route
 		}
 	}
 	
-	if hasIGWRoute {
-		return "public"
-	} else if hasNATRoute {
-		return "private"
-	}
-	return "isolated"
+	if igwRoute != nil {
+		deciding := *igwRoute
+		return "public", &deciding
+	} else if natRoute != nil {
+		deciding := *natRoute
+		return "private", &deciding
+	}
+	return "isolated", nil
+}
+
+// isDefaultRoute reports whether a route is the IPv4 or IPv6 default route
+func isDefaultRoute(route Route) bool {
+	return route.DestinationCidr == "0.0.0.0/0" || route.DestinationIpv6Cidr == "::/0"
 }
 
 // updateVPCAssociations updates VPC associations with subnets and other resources
-func (s *NetworkScanner) updateVPCAssociations(network *Network) {
+func updateVPCAssociations(network *Network) {
 	// Create maps for quick lookup
 	vpcMap := make(map[string]*VPC)
 	for i := range network.VPCs {
@@ -909,6 +1727,13 @@ func (s *NetworkScanner) updateVPCAssociations(network *Network) {
 		}
 	}
 	
+	// Associate egress-only internet gateways with VPCs
+	for _, eigw := range network.EgressOnlyInternetGateways {
+		if vpc, exists := vpcMap[eigw.VpcID]; exists {
+			vpc.EgressOnlyInternetGateways = append(vpc.EgressOnlyInternetGateways, eigw.ID)
+		}
+	}
+	
 	// Associate NAT gateways with VPCs
 	for _, nat := range network.NATGateways {
 		if vpc, exists := vpcMap[nat.VpcID]; exists {
@@ -942,14 +1767,16 @@ func convertTags(tags []types.Tag) map[string]string {
 	return result
 }
 
-// scanIAMRoles scans IAM roles and their attached policies
-func (s *NetworkScanner) scanIAMRoles(ctx context.Context) ([]IAMRole, error) {
+// scanIAMRoles scans IAM roles and their attached policies. Unless all roles are
+// requested, only roles assumable by EC2, ECS, EKS or Lambda, roles in an instance
+// profile and the task roles of the network's ECS services are included.
+func (s *NetworkScanner) scanIAMRoles(ctx context.Context, network *Network) ([]IAMRole, error) {
 	// List all roles
 	listRolesInput := &iam.ListRolesInput{}
 	
 	var allRoles []iamTypes.Role
 	for {
-		result, err := s.client.IAM.ListRoles(ctx, listRolesInput)
+		result, err := s.apis.IAM.ListRoles(ctx, listRolesInput)
 		if err != nil {
 			return nil, err
 		}
@@ -962,14 +1789,21 @@ func (s *NetworkScanner) scanIAMRoles(ctx context.Context) ([]IAMRole, error) {
 		listRolesInput.Marker = result.Marker
 	}
 
-	var iamRoles []IAMRole
+	profiles, err := s.scanInstanceProfiles(ctx)
+	if err != nil {
+		// Log error but continue, roles are still useful without their profiles
+		s.log().Warn("failed to list instance profiles", "error", err)
+	}
+	taskRoles := s.ecsTaskRoles(ctx, network.ECSServices)
+
+	var candidates []IAMRole
 	for _, role := range allRoles {
 		r := IAMRole{
-			ID:                   *role.RoleId,
-			Name:                 *role.RoleName,
-			Path:                 *role.Path,
-			Arn:                  *role.Arn,
-			CreateDate:           *role.CreateDate,
+			ID:                   awssdk.ToString(role.RoleId),
+			Name:                 awssdk.ToString(role.RoleName),
+			Path:                 awssdk.ToString(role.Path),
+			Arn:                  awssdk.ToString(role.Arn),
+			CreateDate:           awssdk.ToTime(role.CreateDate),
 			AssumeRolePolicyDocument: "",
 			MaxSessionDuration:   int32(3600), // Default
 		}
@@ -989,98 +1823,188 @@ func (s *NetworkScanner) scanIAMRoles(ctx context.Context) ([]IAMRole, error) {
 			}
 		}
 		
+		// Skip roles unrelated to network workloads before fetching their policies
+		if !s.allIAMRoles && !isWorkloadRole(r.AssumeRolePolicyDocument) && len(profiles[r.Name]) == 0 && !taskRoles[r.Name] {
+			continue
+		}
+		
 		// Get role tags
 		r.Tags = convertIAMTags(role.Tags)
 		
-		// Get attached managed policies
-		attachedPolicies, err := s.getAttachedRolePolicies(ctx, *role.RoleName)
+		candidates = append(candidates, r)
+	}
+
+	// Get attached managed and inline policies of several roles at once, keyed by
+	// role ID so a recreated role is fetched again
+	managed := newManagedPolicies()
+	fetched := make([]bool, len(candidates))
+	s.forEachRole(len(candidates), func(i int) {
+		r := &candidates[i]
+		policies, err := cachedCall(s.cache, "role-policies:"+r.ID, false, func() (rolePolicies, error) {
+			return s.getRolePolicies(ctx, r.Name, managed)
+		})
 		if err != nil {
 			// Log error but continue
-			continue
+			return
 		}
-		r.AttachedPolicies = attachedPolicies
-		
-		// Get inline policies
-		inlinePolicies, err := s.getInlineRolePolicies(ctx, *role.RoleName)
-		if err != nil {
-			// Log error but continue
-			continue
+		r.AttachedPolicies = policies.Attached
+		r.InlinePolicies = policies.Inline
+		fetched[i] = true
+	})
+
+	var iamRoles []IAMRole
+	for i, r := range candidates {
+		if fetched[i] {
+			iamRoles = append(iamRoles, r)
 		}
-		r.InlinePolicies = inlinePolicies
-		
-		iamRoles = append(iamRoles, r)
+	}
+
+	for i := range iamRoles {
+		iamRoles[i].InstanceProfiles = profiles[iamRoles[i].Name]
 	}
 
 	return iamRoles, nil
 }
 
-// getAttachedRolePolicies gets managed policies attached to a role
-func (s *NetworkScanner) getAttachedRolePolicies(ctx context.Context, roleName string) ([]IAMPolicy, error) {
+// scanInstanceProfiles lists the account's instance profiles, keyed by the name of the role each carries
+func (s *NetworkScanner) scanInstanceProfiles(ctx context.Context) (map[string][]IAMInstanceProfile, error) {
+	input := &iam.ListInstanceProfilesInput{}
+
+	profiles := make(map[string][]IAMInstanceProfile)
+	for {
+		result, err := s.apis.IAM.ListInstanceProfiles(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, profile := range result.InstanceProfiles {
+			p := IAMInstanceProfile{
+				ID:         awssdk.ToString(profile.InstanceProfileId),
+				Name:       awssdk.ToString(profile.InstanceProfileName),
+				Path:       awssdk.ToString(profile.Path),
+				Arn:        awssdk.ToString(profile.Arn),
+				CreateDate: awssdk.ToTime(profile.CreateDate),
+				Tags:       convertIAMTags(profile.Tags),
+			}
+			for _, role := range profile.Roles {
+				if role.RoleName != nil {
+					profiles[*role.RoleName] = append(profiles[*role.RoleName], p)
+				}
+			}
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+		input.Marker = result.Marker
+	}
+	return profiles, nil
+}
+
+// rolePolicies holds the managed and inline policies of a role
+type rolePolicies struct {
+	Attached []IAMPolicy       `json:"attached"`
+	Inline   []IAMInlinePolicy `json:"inline"`
+}
+
+// getRolePolicies gets the managed and inline policies of a role
+func (s *NetworkScanner) getRolePolicies(ctx context.Context, roleName string, managed *managedPolicies) (rolePolicies, error) {
+	attached, err := s.getAttachedRolePolicies(ctx, roleName, managed)
+	if err != nil {
+		return rolePolicies{}, err
+	}
+	inline, err := s.getInlineRolePolicies(ctx, roleName)
+	if err != nil {
+		return rolePolicies{}, err
+	}
+	return rolePolicies{Attached: attached, Inline: inline}, nil
+}
+
+// getAttachedRolePolicies gets managed policies attached to a role, fetching each
+// policy only once across the roles of a scan
+func (s *NetworkScanner) getAttachedRolePolicies(ctx context.Context, roleName string, managed *managedPolicies) ([]IAMPolicy, error) {
 	input := &iam.ListAttachedRolePoliciesInput{
 		RoleName: &roleName,
 	}
 
-	result, err := s.client.IAM.ListAttachedRolePolicies(ctx, input)
+	result, err := s.apis.IAM.ListAttachedRolePolicies(ctx, input)
 	if err != nil {
 		return nil, err
 	}
 
 	var policies []IAMPolicy
 	for _, attachedPolicy := range result.AttachedPolicies {
-		// Get policy details
-		getPolicyInput := &iam.GetPolicyInput{
-			PolicyArn: attachedPolicy.PolicyArn,
-		}
-		
-		policyResult, err := s.client.IAM.GetPolicy(ctx, getPolicyInput)
+		policyArn := awssdk.ToString(attachedPolicy.PolicyArn)
+		p, err := managed.get(policyArn, func() (IAMPolicy, error) {
+			return s.getManagedPolicy(ctx, policyArn)
+		})
 		if err != nil {
 			continue // Skip this policy if we can't get details
 		}
 		
-		policy := policyResult.Policy
-		p := IAMPolicy{
-			Arn:              *policy.Arn,
-			PolicyName:       *policy.PolicyName,
-			PolicyId:         *policy.PolicyId,
-			Path:             *policy.Path,
-			DefaultVersionId: *policy.DefaultVersionId,
-			IsAttachable:     policy.IsAttachable,
-			CreateDate:       *policy.CreateDate,
-			UpdateDate:       *policy.UpdateDate,
-		}
-		
-		if policy.Description != nil {
-			p.Description = *policy.Description
-		}
-		if policy.AttachmentCount != nil {
-			p.AttachmentCount = *policy.AttachmentCount
-		}
-		if policy.PermissionsBoundaryUsageCount != nil {
-			p.PermissionsBoundaryUsageCount = *policy.PermissionsBoundaryUsageCount
-		}
-		
-		// Get policy tags
-		p.Tags = convertIAMTags(policy.Tags)
-		
-		// Get policy document
-		policyDocument, err := s.getPolicyDocument(ctx, *policy.Arn, *policy.DefaultVersionId)
-		if err == nil {
-			p.PolicyDocument = policyDocument
-		}
-		
 		policies = append(policies, p)
 	}
 
 	return policies, nil
 }
 
+// getManagedPolicy gets a managed policy's details and default version document
+func (s *NetworkScanner) getManagedPolicy(ctx context.Context, policyArn string) (IAMPolicy, error) {
+	getPolicyInput := &iam.GetPolicyInput{
+		PolicyArn: &policyArn,
+	}
+
+	policyResult, err := s.apis.IAM.GetPolicy(ctx, getPolicyInput)
+	if err != nil {
+		return IAMPolicy{}, err
+	}
+
+	policy := policyResult.Policy
+	if policy == nil {
+		return IAMPolicy{}, fmt.Errorf("no details returned for policy %s", policyArn)
+	}
+	p := IAMPolicy{
+		Arn:              awssdk.ToString(policy.Arn),
+		PolicyName:       awssdk.ToString(policy.PolicyName),
+		PolicyId:         awssdk.ToString(policy.PolicyId),
+		Path:             awssdk.ToString(policy.Path),
+		DefaultVersionId: awssdk.ToString(policy.DefaultVersionId),
+		IsAttachable:     policy.IsAttachable,
+		CreateDate:       awssdk.ToTime(policy.CreateDate),
+		UpdateDate:       awssdk.ToTime(policy.UpdateDate),
+	}
+	
+	if policy.Description != nil {
+		p.Description = *policy.Description
+	}
+	if policy.AttachmentCount != nil {
+		p.AttachmentCount = *policy.AttachmentCount
+	}
+	if policy.PermissionsBoundaryUsageCount != nil {
+		p.PermissionsBoundaryUsageCount = *policy.PermissionsBoundaryUsageCount
+	}
+	
+	// Get policy tags
+	p.Tags = convertIAMTags(policy.Tags)
+	
+	// Get policy document, policy versions never change once created
+	policyDocument, err := cachedCall(s.cache, "policy-version:"+p.Arn+"#"+p.DefaultVersionId, true, func() (string, error) {
+		return s.getPolicyDocument(ctx, p.Arn, p.DefaultVersionId)
+	})
+	if err == nil {
+		p.PolicyDocument = policyDocument
+	}
+
+	return p, nil
+}
+
 // getInlineRolePolicies gets inline policies for a role
 func (s *NetworkScanner) getInlineRolePolicies(ctx context.Context, roleName string) ([]IAMInlinePolicy, error) {
 	input := &iam.ListRolePoliciesInput{
 		RoleName: &roleName,
 	}
 
-	result, err := s.client.IAM.ListRolePolicies(ctx, input)
+	result, err := s.apis.IAM.ListRolePolicies(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -1093,7 +2017,7 @@ func (s *NetworkScanner) getInlineRolePolicies(ctx context.Context, roleName str
 			PolicyName: &policyName,
 		}
 		
-		policyResult, err := s.client.IAM.GetRolePolicy(ctx, getPolicyInput)
+		policyResult, err := s.apis.IAM.GetRolePolicy(ctx, getPolicyInput)
 		if err != nil {
 			continue // Skip this policy if we can't get the document
 		}
@@ -1124,12 +2048,12 @@ func (s *NetworkScanner) getPolicyDocument(ctx context.Context, policyArn, versi
 		VersionId: &versionId,
 	}
 
-	result, err := s.client.IAM.GetPolicyVersion(ctx, input)
+	result, err := s.apis.IAM.GetPolicyVersion(ctx, input)
 	if err != nil {
 		return "", err
 	}
 
-	if result.PolicyVersion.Document != nil {
+	if result.PolicyVersion != nil && result.PolicyVersion.Document != nil {
 		decoded, err := url.QueryUnescape(*result.PolicyVersion.Document)
 		if err != nil {
 			return *result.PolicyVersion.Document, nil
@@ -1,8 +1,14 @@
 package scanner
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	meshTypes "github.com/aws/aws-sdk-go-v2/service/appmesh/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
 func TestConvertTags(t *testing.T) {
@@ -18,7 +24,7 @@ func TestConvertTags(t *testing.T) {
 	// This demonstrates the testing structure for when we have more complex logic
 }
 
-func TestDetermineSubnetType(t *testing.T) {
+func TestClassifySubnet(t *testing.T) {
 	tests := []struct {
 		name     string
 		routes   []Route
@@ -47,13 +53,42 @@ func TestDetermineSubnetType(t *testing.T) {
 			routes: []Route{
 				{
 					DestinationCidr: "0.0.0.0/0",
-					GatewayID:       "nat-12345",
+					NatGatewayID:    "nat-12345",
 					State:           "active",
 				},
 			},
 			igws:     []InternetGateway{},
 			expected: "private",
 		},
+		{
+			name: "Public subnet with IPv6 IGW route",
+			routes: []Route{
+				{
+					DestinationIpv6Cidr: "::/0",
+					GatewayID:           "igw-12345",
+					State:               "active",
+				},
+			},
+			igws: []InternetGateway{
+				{
+					ID:    "igw-12345",
+					State: "available",
+				},
+			},
+			expected: "public",
+		},
+		{
+			name: "Private subnet with egress-only IGW route",
+			routes: []Route{
+				{
+					DestinationIpv6Cidr: "::/0",
+					EgressOnlyGatewayID: "eigw-12345",
+					State:               "active",
+				},
+			},
+			igws:     []InternetGateway{},
+			expected: "private",
+		},
 		{
 			name: "Isolated subnet",
 			routes: []Route{
@@ -74,7 +109,7 @@ func TestDetermineSubnetType(t *testing.T) {
 				Routes: tt.routes,
 			}
 			
-			result := determineSubnetType(routeTable, tt.igws)
+			result, _ := classifySubnet(routeTable, tt.igws)
 			if result != tt.expected {
 				t.Errorf("Expected %s, got %s", tt.expected, result)
 			}
@@ -82,6 +117,40 @@ func TestDetermineSubnetType(t *testing.T) {
 	}
 }
 
+func TestClassifySubnetDecidingRoute(t *testing.T) {
+	igws := []InternetGateway{{ID: "igw-12345"}}
+	local := Route{DestinationCidr: "10.0.0.0/16", GatewayID: "local", State: "active"}
+	partial := Route{DestinationCidr: "203.0.113.0/24", GatewayID: "igw-12345", State: "active"}
+	ipv6Default := Route{DestinationIpv6Cidr: "::/0", GatewayID: "igw-12345", State: "active"}
+	nat := Route{DestinationCidr: "0.0.0.0/0", NatGatewayID: "nat-12345", State: "active"}
+	blackhole := Route{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-12345", State: "blackhole"}
+
+	tests := []struct {
+		name     string
+		routes   []Route
+		expected string
+		deciding *Route
+	}{
+		{"partial prefix to the IGW", []Route{local, nat, partial}, "public", &partial},
+		{"default route preferred", []Route{local, partial, ipv6Default}, "public", &ipv6Default},
+		{"NAT default route", []Route{local, nat}, "private", &nat},
+		{"blackhole IGW route", []Route{local, blackhole}, "isolated", nil},
+		{"detached IGW", []Route{local, {DestinationCidr: "0.0.0.0/0", GatewayID: "igw-gone"}}, "isolated", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subnetType, route := classifySubnet(&RouteTable{Routes: tt.routes}, igws)
+			if subnetType != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, subnetType)
+			}
+			if (route == nil) != (tt.deciding == nil) || (route != nil && *route != *tt.deciding) {
+				t.Errorf("Expected deciding route %+v, got %+v", tt.deciding, route)
+			}
+		})
+	}
+}
+
 func TestNetworkStructure(t *testing.T) {
 	// Test basic network structure
 	network := &Network{
@@ -98,6 +167,46 @@ func TestNetworkStructure(t *testing.T) {
 	}
 }
 
+func TestNetworkForVPCs(t *testing.T) {
+	network := &Network{
+		VPCs: []VPC{{ID: "vpc-a", DhcpOptionsID: "dopt-a"}, {ID: "vpc-b", DhcpOptionsID: "dopt-b"}},
+		DhcpOptions: []DhcpOptions{{ID: "dopt-a"}, {ID: "dopt-b"}},
+		Subnets: []Subnet{{ID: "subnet-a", VpcID: "vpc-a"}, {ID: "subnet-b", VpcID: "vpc-b"}},
+		PeeringConnections: []PeeringConnection{
+			{ID: "pcx-ab", RequesterVpcID: "vpc-b", AccepterVpcID: "vpc-a"},
+			{ID: "pcx-bc", RequesterVpcID: "vpc-b", AccepterVpcID: "vpc-c"},
+		},
+		SecurityGroups:  []SecurityGroup{{ID: "sg-a", VpcID: "vpc-a"}, {ID: "sg-b", VpcID: "vpc-b"}},
+		TransitGateways: []TransitGateway{{ID: "tgw-shared"}},
+		IAMRoles:        []IAMRole{{Name: "web"}},
+		EdgeIngresses: []EdgeIngress{
+			{ID: "cf-1", Targets: []EdgeTarget{{ID: "lb-a", VpcID: "vpc-a"}, {ID: "lb-b", VpcID: "vpc-b"}}},
+			{ID: "cf-2", Targets: []EdgeTarget{{ID: "lb-b2", VpcID: "vpc-b"}}},
+		},
+		Region: "us-east-1",
+	}
+
+	subset := network.ForVPCs([]string{"vpc-a"})
+	if len(subset.VPCs) != 1 || subset.VPCs[0].ID != "vpc-a" || len(subset.DhcpOptions) != 1 || subset.DhcpOptions[0].ID != "dopt-a" {
+		t.Errorf("Expected vpc-a and its DHCP options, got %+v and %+v", subset.VPCs, subset.DhcpOptions)
+	}
+	if len(subset.Subnets) != 1 || subset.Subnets[0].ID != "subnet-a" || len(subset.SecurityGroups) != 1 || subset.SecurityGroups[0].ID != "sg-a" {
+		t.Errorf("Expected only vpc-a's subnets and security groups, got %+v and %+v", subset.Subnets, subset.SecurityGroups)
+	}
+	if len(subset.PeeringConnections) != 1 || subset.PeeringConnections[0].ID != "pcx-ab" {
+		t.Errorf("Expected the peering connection accepted by vpc-a, got %+v", subset.PeeringConnections)
+	}
+	if len(subset.TransitGateways) != 1 || len(subset.IAMRoles) != 1 || subset.Region != "us-east-1" {
+		t.Errorf("Expected account-wide resources to be kept, got %+v", subset)
+	}
+	if len(subset.EdgeIngresses) != 1 || len(subset.EdgeIngresses[0].Targets) != 1 || subset.EdgeIngresses[0].Targets[0].ID != "lb-a" {
+		t.Errorf("Expected cf-1 with only its vpc-a target, got %+v", subset.EdgeIngresses)
+	}
+	if len(network.EdgeIngresses[0].Targets) != 2 {
+		t.Error("Expected the original network to be unchanged")
+	}
+}
+
 func TestIAMStructure(t *testing.T) {
 	// Test IAM role structure
 	role := IAMRole{
@@ -144,8 +253,7 @@ func TestSecurityGroupRuleStructure(t *testing.T) {
 		CidrBlocks:                 []string{"0.0.0.0/0"},
 		Ipv6CidrBlocks:             []string{"::/0"},
 		PrefixListIds:              []string{"pl-12345"},
-		ReferencedGroupId:          "sg-12345",
-		ReferencedGroupOwnerId:     "123456789012",
+		ReferencedGroups:           []SecurityGroupReference{{GroupID: "sg-12345", OwnerID: "123456789012"}},
 		Description:                "Allow HTTP traffic",
 		Tags:                       map[string]string{"Name": "HTTP rule"},
 	}
@@ -171,6 +279,45 @@ func TestSecurityGroupRuleStructure(t *testing.T) {
 	}
 }
 
+func TestConvertGroupPairs(t *testing.T) {
+	var rule SecurityGroupRule
+	convertGroupPairs(&rule, []types.UserIdGroupPair{
+		{GroupId: awssdk.String("sg-web"), UserId: awssdk.String("111111111111"), Description: awssdk.String("from web")},
+		{GroupId: awssdk.String("sg-batch"), UserId: awssdk.String("222222222222"), VpcPeeringConnectionId: awssdk.String("pcx-1")},
+	})
+
+	expected := []SecurityGroupReference{
+		{GroupID: "sg-web", OwnerID: "111111111111", Description: "from web"},
+		{GroupID: "sg-batch", OwnerID: "222222222222", PeeringID: "pcx-1"},
+	}
+	if !reflect.DeepEqual(rule.ReferencedGroups, expected) {
+		t.Errorf("Expected every referenced group, got %+v", rule.ReferencedGroups)
+	}
+	if rule.Description != "from web" {
+		t.Errorf("Expected the rule to take the first description, got %q", rule.Description)
+	}
+}
+
+func TestSecurityGroupRuleLegacyJSON(t *testing.T) {
+	var rule SecurityGroupRule
+	data := `{"ip_protocol": "tcp", "from_port": 443, "to_port": 443, "referenced_group_id": "sg-web", "referenced_group_owner_id": "111111111111"}`
+	if err := json.Unmarshal([]byte(data), &rule); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if rule.FromPort != 443 || len(rule.ReferencedGroups) != 1 || rule.ReferencedGroups[0] != (SecurityGroupReference{GroupID: "sg-web", OwnerID: "111111111111"}) {
+		t.Errorf("Expected the legacy reference to be read, got %+v", rule)
+	}
+
+	encoded, err := json.Marshal(rule)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded SecurityGroupRule
+	if err := json.Unmarshal(encoded, &decoded); err != nil || !reflect.DeepEqual(decoded.ReferencedGroups, rule.ReferencedGroups) {
+		t.Errorf("Expected the rule to round-trip, got %+v, %v", decoded, err)
+	}
+}
+
 func TestSecurityGroupWithRules(t *testing.T) {
 	// Test SecurityGroup with rules
 	sg := SecurityGroup{
@@ -221,7 +368,7 @@ func TestSecurityGroupWithRules(t *testing.T) {
 func TestNetworkScannerVerbose(t *testing.T) {
 	// Test that NetworkScanner can toggle verbose mode
 	scanner := &NetworkScanner{
-		client:  nil, // Not testing actual scanning, just the verbose flag
+		apis:    APIs{}, // Not testing actual scanning, just the verbose flag
 		verbose: false,
 	}
 	
@@ -353,4 +500,137 @@ func TestNetworkWithNacls(t *testing.T) {
 	if network.NetworkAcls[0].ID != "acl-12345" {
 		t.Errorf("Expected Network ACL ID 'acl-12345', got %s", network.NetworkAcls[0].ID)
 	}
-}
\ No newline at end of file
+}
+
+func TestSubnetAddressCounts(t *testing.T) {
+	subnet := Subnet{
+		ID:        "subnet-12345",
+		CidrBlock: "10.0.1.0/24",
+		CidrReservations: []SubnetCidrReservation{
+			{ID: "scr-1", Cidr: "10.0.1.0/28", ReservationType: "prefix"},
+			{ID: "scr-2", Cidr: "10.0.1.64/27", ReservationType: "explicit"},
+			{ID: "scr-3", Cidr: "2600:1f18::/80", ReservationType: "prefix"},
+		},
+	}
+
+	if usable := subnet.UsableIPv4AddressCount(); usable != 251 {
+		t.Errorf("Expected 251 usable addresses, got %d", usable)
+	}
+
+	// IPv6 reservations do not consume IPv4 space
+	if reserved := subnet.ReservedIPv4AddressCount(); reserved != 48 {
+		t.Errorf("Expected 48 reserved addresses, got %d", reserved)
+	}
+
+	if _, ok := subnet.Utilization(); ok {
+		t.Error("Expected no utilization without an available address count")
+	}
+	// The 48 reserved addresses are counted as available but cannot be assigned
+	available := 91
+	subnet.AvailableIPs = &available
+	if free := subnet.FreeIPv4AddressCount(); free != 43 {
+		t.Errorf("Expected 43 free addresses, got %d", free)
+	}
+	if utilization, ok := subnet.Utilization(); !ok || utilization < 82.8 || utilization > 82.9 {
+		t.Errorf("Expected 160 used and 48 reserved of 251 addresses to be 82.9%% used, got %.2f", utilization)
+	}
+
+	// Without reservations every available address is free
+	subnet.CidrReservations = nil
+	if utilization, ok := subnet.Utilization(); !ok || utilization < 63.7 || utilization > 63.8 {
+		t.Errorf("Expected 160 of 251 addresses to be 63.7%% used, got %.2f", utilization)
+	}
+
+	// More reserved addresses than available ones leave nothing free
+	subnet.CidrReservations = []SubnetCidrReservation{{ID: "scr-4", Cidr: "10.0.1.128/25", ReservationType: "explicit"}}
+	if free := subnet.FreeIPv4AddressCount(); free != 0 {
+		t.Errorf("Expected no free addresses, got %d", free)
+	}
+}
+
+func TestConvertMeshVirtualGateway(t *testing.T) {
+	data := &meshTypes.VirtualGatewayData{
+		MeshName:           awssdk.String("payments"),
+		VirtualGatewayName: awssdk.String("ingress"),
+		Metadata: &meshTypes.ResourceMetadata{
+			Arn:       awssdk.String("arn:aws:appmesh:us-east-1:111111111111:mesh/payments/virtualGateway/ingress"),
+			MeshOwner: awssdk.String("111111111111"),
+		},
+		Status: &meshTypes.VirtualGatewayStatus{Status: meshTypes.VirtualGatewayStatusCodeActive},
+		Spec: &meshTypes.VirtualGatewaySpec{
+			Listeners: []meshTypes.VirtualGatewayListener{
+				{PortMapping: &meshTypes.VirtualGatewayPortMapping{Port: awssdk.Int32(8443), Protocol: meshTypes.VirtualGatewayPortProtocolHttp2},
+					Tls: &meshTypes.VirtualGatewayListenerTls{Mode: meshTypes.VirtualGatewayListenerTlsModeStrict}},
+				{PortMapping: &meshTypes.VirtualGatewayPortMapping{Port: awssdk.Int32(8080), Protocol: meshTypes.VirtualGatewayPortProtocolHttp}},
+			},
+		},
+	}
+
+	gateway := convertMeshVirtualGateway(data)
+
+	if gateway.Name != "ingress" || gateway.MeshName != "payments" || gateway.Status != "active" {
+		t.Errorf("Unexpected gateway: %+v", gateway)
+	}
+
+	if len(gateway.Listeners) != 2 {
+		t.Fatalf("Expected 2 listeners, got %d", len(gateway.Listeners))
+	}
+
+	if gateway.Listeners[0].Port != 8443 || gateway.Listeners[0].Protocol != "http2" || !gateway.Listeners[0].TLS {
+		t.Errorf("Unexpected TLS listener: %+v", gateway.Listeners[0])
+	}
+
+	if gateway.Listeners[1].TLS {
+		t.Error("Expected listener without TLS configuration to report TLS disabled")
+	}
+}
+
+func TestParseTagFilters(t *testing.T) {
+	filters, err := ParseTagFilters([]string{"Environment=prod", "Team=payments", "Environment=staging"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(filters) != 2 {
+		t.Fatalf("Expected 2 tag keys, got %d", len(filters))
+	}
+	if len(filters["Environment"]) != 2 || filters["Environment"][1] != "staging" {
+		t.Errorf("Expected repeated key to collect both values, got %v", filters["Environment"])
+	}
+
+	s := &NetworkScanner{}
+	s.SetTagFilters(filters)
+	ec2Filters := s.vpcTagFilters()
+	if len(ec2Filters) != 2 || *ec2Filters[0].Name != "tag:Environment" || *ec2Filters[1].Name != "tag:Team" {
+		t.Errorf("Expected sorted tag:key filters, got %v", ec2Filters)
+	}
+
+	for _, selector := range []string{"Environment", "=prod"} {
+		if _, err := ParseTagFilters([]string{selector}); err == nil {
+			t.Errorf("Expected error for selector %q", selector)
+		}
+	}
+}
+
+func TestIsWorkloadRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		trustPolicy string
+		expected    bool
+	}{
+		{"ec2", `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}`, true},
+		{"service list", `{"Statement":[{"Effect":"Allow","Principal":{"Service":["events.amazonaws.com","ecs-tasks.amazonaws.com"]},"Action":"sts:AssumeRole"}]}`, true},
+		{"eks pod identity", `{"Statement":[{"Effect":"Allow","Principal":{"Service":"pods.eks.amazonaws.com"},"Action":["sts:AssumeRole","sts:TagSession"]}]}`, true},
+		{"eks cluster", `{"Statement":[{"Effect":"Allow","Principal":{"Service":"eks.amazonaws.com"},"Action":"sts:AssumeRole"}]}`, true},
+		{"other service", `{"Statement":[{"Effect":"Allow","Principal":{"Service":"glue.amazonaws.com"},"Action":"sts:AssumeRole"}]}`, false},
+		{"account principal", `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"},"Action":"sts:AssumeRole"}]}`, false},
+		{"denied", `{"Statement":[{"Effect":"Deny","Principal":{"Service":"lambda.amazonaws.com"},"Action":"sts:AssumeRole"}]}`, false},
+		{"invalid", `not json`, false},
+	}
+
+	for _, test := range tests {
+		if got := isWorkloadRole(test.trustPolicy); got != test.expected {
+			t.Errorf("%s: isWorkloadRole() = %v, expected %v", test.name, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package scannertest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/appmesh"
+	meshTypes "github.com/aws/aws-sdk-go-v2/service/appmesh/types"
+)
+
+// FakeAppMesh is an in-memory App Mesh API
+type FakeAppMesh struct {
+	Meshes          []meshTypes.MeshRef
+	VirtualGateways []meshTypes.VirtualGatewayData
+
+	// Errors makes an operation fail, keyed by operation name such as "ListMeshes"
+	Errors map[string]error
+}
+
+// ListMeshes returns every mesh
+func (f *FakeAppMesh) ListMeshes(ctx context.Context, params *appmesh.ListMeshesInput, optFns ...func(*appmesh.Options)) (*appmesh.ListMeshesOutput, error) {
+	if err := f.Errors["ListMeshes"]; err != nil {
+		return nil, err
+	}
+	return &appmesh.ListMeshesOutput{Meshes: f.Meshes}, nil
+}
+
+// ListVirtualGateways returns the virtual gateways of a mesh
+func (f *FakeAppMesh) ListVirtualGateways(ctx context.Context, params *appmesh.ListVirtualGatewaysInput, optFns ...func(*appmesh.Options)) (*appmesh.ListVirtualGatewaysOutput, error) {
+	if err := f.Errors["ListVirtualGateways"]; err != nil {
+		return nil, err
+	}
+
+	output := &appmesh.ListVirtualGatewaysOutput{}
+	for _, gateway := range f.VirtualGateways {
+		if deref(gateway.MeshName) != deref(params.MeshName) {
+			continue
+		}
+		ref := meshTypes.VirtualGatewayRef{
+			MeshName:           gateway.MeshName,
+			VirtualGatewayName: gateway.VirtualGatewayName,
+		}
+		if gateway.Metadata != nil {
+			ref.Arn = gateway.Metadata.Arn
+			ref.MeshOwner = gateway.Metadata.MeshOwner
+		}
+		output.VirtualGateways = append(output.VirtualGateways, ref)
+	}
+	return output, nil
+}
+
+// DescribeVirtualGateway returns a virtual gateway by mesh and name
+func (f *FakeAppMesh) DescribeVirtualGateway(ctx context.Context, params *appmesh.DescribeVirtualGatewayInput, optFns ...func(*appmesh.Options)) (*appmesh.DescribeVirtualGatewayOutput, error) {
+	if err := f.Errors["DescribeVirtualGateway"]; err != nil {
+		return nil, err
+	}
+
+	for i := range f.VirtualGateways {
+		gateway := &f.VirtualGateways[i]
+		if deref(gateway.MeshName) == deref(params.MeshName) && deref(gateway.VirtualGatewayName) == deref(params.VirtualGatewayName) {
+			return &appmesh.DescribeVirtualGatewayOutput{VirtualGateway: gateway}, nil
+		}
+	}
+	return nil, fmt.Errorf("scannertest: no virtual gateway %q in mesh %q", deref(params.VirtualGatewayName), deref(params.MeshName))
+}
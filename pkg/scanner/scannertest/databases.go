@@ -0,0 +1,96 @@
+package scannertest
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/elasticache"
+	cacheTypes "github.com/aws/aws-sdk-go-v2/service/elasticache/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	redshiftTypes "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+)
+
+// FakeRDS is an in-memory RDS API
+type FakeRDS struct {
+	DBInstances    []rdsTypes.DBInstance
+	DBClusters     []rdsTypes.DBCluster
+	DBSubnetGroups []rdsTypes.DBSubnetGroup
+
+	// Errors makes an operation fail, keyed by operation name such as "DescribeDBInstances"
+	Errors map[string]error
+}
+
+// DescribeDBInstances returns every DB instance
+func (f *FakeRDS) DescribeDBInstances(ctx context.Context, params *rds.DescribeDBInstancesInput, optFns ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error) {
+	if err := f.Errors["DescribeDBInstances"]; err != nil {
+		return nil, err
+	}
+	return &rds.DescribeDBInstancesOutput{DBInstances: f.DBInstances}, nil
+}
+
+// DescribeDBClusters returns every DB cluster
+func (f *FakeRDS) DescribeDBClusters(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+	if err := f.Errors["DescribeDBClusters"]; err != nil {
+		return nil, err
+	}
+	return &rds.DescribeDBClustersOutput{DBClusters: f.DBClusters}, nil
+}
+
+// DescribeDBSubnetGroups returns every DB subnet group
+func (f *FakeRDS) DescribeDBSubnetGroups(ctx context.Context, params *rds.DescribeDBSubnetGroupsInput, optFns ...func(*rds.Options)) (*rds.DescribeDBSubnetGroupsOutput, error) {
+	if err := f.Errors["DescribeDBSubnetGroups"]; err != nil {
+		return nil, err
+	}
+	return &rds.DescribeDBSubnetGroupsOutput{DBSubnetGroups: f.DBSubnetGroups}, nil
+}
+
+// FakeElastiCache is an in-memory ElastiCache API
+type FakeElastiCache struct {
+	CacheClusters     []cacheTypes.CacheCluster
+	CacheSubnetGroups []cacheTypes.CacheSubnetGroup
+
+	// Errors makes an operation fail, keyed by operation name such as "DescribeCacheClusters"
+	Errors map[string]error
+}
+
+// DescribeCacheClusters returns every cache cluster
+func (f *FakeElastiCache) DescribeCacheClusters(ctx context.Context, params *elasticache.DescribeCacheClustersInput, optFns ...func(*elasticache.Options)) (*elasticache.DescribeCacheClustersOutput, error) {
+	if err := f.Errors["DescribeCacheClusters"]; err != nil {
+		return nil, err
+	}
+	return &elasticache.DescribeCacheClustersOutput{CacheClusters: f.CacheClusters}, nil
+}
+
+// DescribeCacheSubnetGroups returns every cache subnet group
+func (f *FakeElastiCache) DescribeCacheSubnetGroups(ctx context.Context, params *elasticache.DescribeCacheSubnetGroupsInput, optFns ...func(*elasticache.Options)) (*elasticache.DescribeCacheSubnetGroupsOutput, error) {
+	if err := f.Errors["DescribeCacheSubnetGroups"]; err != nil {
+		return nil, err
+	}
+	return &elasticache.DescribeCacheSubnetGroupsOutput{CacheSubnetGroups: f.CacheSubnetGroups}, nil
+}
+
+// FakeRedshift is an in-memory Redshift API
+type FakeRedshift struct {
+	Clusters            []redshiftTypes.Cluster
+	ClusterSubnetGroups []redshiftTypes.ClusterSubnetGroup
+
+	// Errors makes an operation fail, keyed by operation name such as "DescribeClusters"
+	Errors map[string]error
+}
+
+// DescribeClusters returns every cluster
+func (f *FakeRedshift) DescribeClusters(ctx context.Context, params *redshift.DescribeClustersInput, optFns ...func(*redshift.Options)) (*redshift.DescribeClustersOutput, error) {
+	if err := f.Errors["DescribeClusters"]; err != nil {
+		return nil, err
+	}
+	return &redshift.DescribeClustersOutput{Clusters: f.Clusters}, nil
+}
+
+// DescribeClusterSubnetGroups returns every cluster subnet group
+func (f *FakeRedshift) DescribeClusterSubnetGroups(ctx context.Context, params *redshift.DescribeClusterSubnetGroupsInput, optFns ...func(*redshift.Options)) (*redshift.DescribeClusterSubnetGroupsOutput, error) {
+	if err := f.Errors["DescribeClusterSubnetGroups"]; err != nil {
+		return nil, err
+	}
+	return &redshift.DescribeClusterSubnetGroupsOutput{ClusterSubnetGroups: f.ClusterSubnetGroups}, nil
+}
@@ -0,0 +1,547 @@
+// Package scannertest provides in-memory fakes of the AWS APIs used by the network
+// scanner, so scanner logic can be tested without credentials.
+package scannertest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// FakeEC2 is an in-memory EC2 API. It honours the ID lists and filters the
+// scanner sends (vpc-id, transit-gateway-id, state, description, network-interface-id, group-id and tag:<key>) and returns an error
+// for any other filter, so tests notice when the scanner starts relying on one.
+type FakeEC2 struct {
+	Vpcs                             []types.Vpc
+	DhcpOptions                      []types.DhcpOptions
+	Subnets                          []types.Subnet
+	SubnetCidrReservations           map[string][]types.SubnetCidrReservation // by subnet ID
+	VpcPeeringConnections            []types.VpcPeeringConnection
+	TransitGateways                  []types.TransitGateway
+	TransitGatewayAttachments        []types.TransitGatewayAttachment
+	TransitGatewayPeeringAttachments []types.TransitGatewayPeeringAttachment
+	TransitGatewayRouteTables        []types.TransitGatewayRouteTable
+	TransitGatewayRoutes             map[string][]types.TransitGatewayRoute // by TGW route table ID
+	InternetGateways                 []types.InternetGateway
+	EgressOnlyInternetGateways       []types.EgressOnlyInternetGateway
+	NatGateways                      []types.NatGateway
+	RouteTables                      []types.RouteTable
+	SecurityGroups                   []types.SecurityGroup
+	SecurityGroupRules               []types.SecurityGroupRule
+	NetworkAcls                      []types.NetworkAcl
+	NetworkInterfaces                []types.NetworkInterface
+	FlowLogs                         []types.FlowLog
+	EndpointServices                 []types.ServiceConfiguration
+	EndpointServicePrincipals        map[string][]types.AllowedPrincipal // by endpoint service ID
+	VpcEndpoints                     []types.VpcEndpoint
+	Addresses                        []types.Address
+
+	// Errors makes an operation fail, keyed by operation name such as "DescribeSubnets"
+	Errors map[string]error
+}
+
+// DescribeVpcs returns the VPCs matching the VPC IDs and filters
+func (f *FakeEC2) DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	if err := f.Errors["DescribeVpcs"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeVpcsOutput{}
+	for _, vpc := range f.Vpcs {
+		if !containsID(params.VpcIds, vpc.VpcId) {
+			continue
+		}
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			if name == "vpc-id" {
+				return values(vpc.VpcId)
+			}
+			return tagValues(vpc.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.Vpcs = append(output.Vpcs, vpc)
+		}
+	}
+	return output, nil
+}
+
+// DescribeDhcpOptions returns the DHCP option sets matching the option set IDs
+func (f *FakeEC2) DescribeDhcpOptions(ctx context.Context, params *ec2.DescribeDhcpOptionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeDhcpOptionsOutput, error) {
+	if err := f.Errors["DescribeDhcpOptions"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filters); err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeDhcpOptionsOutput{}
+	for _, options := range f.DhcpOptions {
+		if containsID(params.DhcpOptionsIds, options.DhcpOptionsId) {
+			output.DhcpOptions = append(output.DhcpOptions, options)
+		}
+	}
+	return output, nil
+}
+
+// DescribeSubnets returns the subnets matching the filters
+func (f *FakeEC2) DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	if err := f.Errors["DescribeSubnets"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeSubnetsOutput{}
+	for _, subnet := range f.Subnets {
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			if name == "vpc-id" {
+				return values(subnet.VpcId)
+			}
+			return tagValues(subnet.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.Subnets = append(output.Subnets, subnet)
+		}
+	}
+	return output, nil
+}
+
+// GetSubnetCidrReservations returns the CIDR reservations of a subnet
+func (f *FakeEC2) GetSubnetCidrReservations(ctx context.Context, params *ec2.GetSubnetCidrReservationsInput, optFns ...func(*ec2.Options)) (*ec2.GetSubnetCidrReservationsOutput, error) {
+	if err := f.Errors["GetSubnetCidrReservations"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.GetSubnetCidrReservationsOutput{}
+	if params.SubnetId == nil {
+		return output, nil
+	}
+	for _, reservation := range f.SubnetCidrReservations[*params.SubnetId] {
+		if reservation.Cidr != nil && strings.Contains(*reservation.Cidr, ":") {
+			output.SubnetIpv6CidrReservations = append(output.SubnetIpv6CidrReservations, reservation)
+		} else {
+			output.SubnetIpv4CidrReservations = append(output.SubnetIpv4CidrReservations, reservation)
+		}
+	}
+	return output, nil
+}
+
+// DescribeVpcPeeringConnections returns every peering connection
+func (f *FakeEC2) DescribeVpcPeeringConnections(ctx context.Context, params *ec2.DescribeVpcPeeringConnectionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcPeeringConnectionsOutput, error) {
+	if err := f.Errors["DescribeVpcPeeringConnections"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filters); err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeVpcPeeringConnectionsOutput{VpcPeeringConnections: f.VpcPeeringConnections}, nil
+}
+
+// DescribeTransitGateways returns every transit gateway
+func (f *FakeEC2) DescribeTransitGateways(ctx context.Context, params *ec2.DescribeTransitGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeTransitGatewaysOutput, error) {
+	if err := f.Errors["DescribeTransitGateways"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filters); err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeTransitGatewaysOutput{TransitGateways: f.TransitGateways}, nil
+}
+
+// DescribeTransitGatewayAttachments returns the attachments matching the filters
+func (f *FakeEC2) DescribeTransitGatewayAttachments(ctx context.Context, params *ec2.DescribeTransitGatewayAttachmentsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeTransitGatewayAttachmentsOutput, error) {
+	if err := f.Errors["DescribeTransitGatewayAttachments"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeTransitGatewayAttachmentsOutput{}
+	for _, attachment := range f.TransitGatewayAttachments {
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			if name == "transit-gateway-id" {
+				return values(attachment.TransitGatewayId)
+			}
+			return tagValues(attachment.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.TransitGatewayAttachments = append(output.TransitGatewayAttachments, attachment)
+		}
+	}
+	return output, nil
+}
+
+// DescribeTransitGatewayPeeringAttachments returns the peering attachments matching the attachment IDs
+func (f *FakeEC2) DescribeTransitGatewayPeeringAttachments(ctx context.Context, params *ec2.DescribeTransitGatewayPeeringAttachmentsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeTransitGatewayPeeringAttachmentsOutput, error) {
+	if err := f.Errors["DescribeTransitGatewayPeeringAttachments"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filters); err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeTransitGatewayPeeringAttachmentsOutput{}
+	for _, attachment := range f.TransitGatewayPeeringAttachments {
+		if containsID(params.TransitGatewayAttachmentIds, attachment.TransitGatewayAttachmentId) {
+			output.TransitGatewayPeeringAttachments = append(output.TransitGatewayPeeringAttachments, attachment)
+		}
+	}
+	return output, nil
+}
+
+// DescribeTransitGatewayRouteTables returns the TGW route tables matching the filters
+func (f *FakeEC2) DescribeTransitGatewayRouteTables(ctx context.Context, params *ec2.DescribeTransitGatewayRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeTransitGatewayRouteTablesOutput, error) {
+	if err := f.Errors["DescribeTransitGatewayRouteTables"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeTransitGatewayRouteTablesOutput{}
+	for _, routeTable := range f.TransitGatewayRouteTables {
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			if name == "transit-gateway-id" {
+				return values(routeTable.TransitGatewayId)
+			}
+			return tagValues(routeTable.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.TransitGatewayRouteTables = append(output.TransitGatewayRouteTables, routeTable)
+		}
+	}
+	return output, nil
+}
+
+// SearchTransitGatewayRoutes returns the routes of a TGW route table matching the
+// filters. Like EC2, it requires at least one filter.
+func (f *FakeEC2) SearchTransitGatewayRoutes(ctx context.Context, params *ec2.SearchTransitGatewayRoutesInput, optFns ...func(*ec2.Options)) (*ec2.SearchTransitGatewayRoutesOutput, error) {
+	if err := f.Errors["SearchTransitGatewayRoutes"]; err != nil {
+		return nil, err
+	}
+	if len(params.Filters) == 0 {
+		return nil, fmt.Errorf("scannertest: SearchTransitGatewayRoutes requires a filter")
+	}
+
+	output := &ec2.SearchTransitGatewayRoutesOutput{}
+	for _, route := range f.TransitGatewayRoutes[deref(params.TransitGatewayRouteTableId)] {
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			if name == "state" {
+				return []string{string(route.State)}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.Routes = append(output.Routes, route)
+		}
+	}
+	return output, nil
+}
+
+// DescribeInternetGateways returns every internet gateway
+func (f *FakeEC2) DescribeInternetGateways(ctx context.Context, params *ec2.DescribeInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInternetGatewaysOutput, error) {
+	if err := f.Errors["DescribeInternetGateways"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filters); err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeInternetGatewaysOutput{InternetGateways: f.InternetGateways}, nil
+}
+
+// DescribeEgressOnlyInternetGateways returns every egress-only internet gateway
+func (f *FakeEC2) DescribeEgressOnlyInternetGateways(ctx context.Context, params *ec2.DescribeEgressOnlyInternetGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+	if err := f.Errors["DescribeEgressOnlyInternetGateways"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filters); err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeEgressOnlyInternetGatewaysOutput{EgressOnlyInternetGateways: f.EgressOnlyInternetGateways}, nil
+}
+
+// DescribeNatGateways returns every NAT gateway
+func (f *FakeEC2) DescribeNatGateways(ctx context.Context, params *ec2.DescribeNatGatewaysInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNatGatewaysOutput, error) {
+	if err := f.Errors["DescribeNatGateways"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filter); err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeNatGatewaysOutput{NatGateways: f.NatGateways}, nil
+}
+
+// DescribeRouteTables returns the route tables matching the filters
+func (f *FakeEC2) DescribeRouteTables(ctx context.Context, params *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+	if err := f.Errors["DescribeRouteTables"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeRouteTablesOutput{}
+	for _, routeTable := range f.RouteTables {
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			if name == "vpc-id" {
+				return values(routeTable.VpcId)
+			}
+			return tagValues(routeTable.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.RouteTables = append(output.RouteTables, routeTable)
+		}
+	}
+	return output, nil
+}
+
+// DescribeSecurityGroups returns the security groups matching the filters
+func (f *FakeEC2) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	if err := f.Errors["DescribeSecurityGroups"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeSecurityGroupsOutput{}
+	for _, group := range f.SecurityGroups {
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			if name == "vpc-id" {
+				return values(group.VpcId)
+			}
+			return tagValues(group.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.SecurityGroups = append(output.SecurityGroups, group)
+		}
+	}
+	return output, nil
+}
+
+// DescribeSecurityGroupRules returns the security group rules matching the filters
+func (f *FakeEC2) DescribeSecurityGroupRules(ctx context.Context, params *ec2.DescribeSecurityGroupRulesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	if err := f.Errors["DescribeSecurityGroupRules"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeSecurityGroupRulesOutput{}
+	for _, rule := range f.SecurityGroupRules {
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			if name == "group-id" {
+				return values(rule.GroupId)
+			}
+			return tagValues(rule.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.SecurityGroupRules = append(output.SecurityGroupRules, rule)
+		}
+	}
+	return output, nil
+}
+
+// DescribeNetworkAcls returns the network ACLs matching the filters
+func (f *FakeEC2) DescribeNetworkAcls(ctx context.Context, params *ec2.DescribeNetworkAclsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkAclsOutput, error) {
+	if err := f.Errors["DescribeNetworkAcls"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeNetworkAclsOutput{}
+	for _, acl := range f.NetworkAcls {
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			if name == "vpc-id" {
+				return values(acl.VpcId)
+			}
+			return tagValues(acl.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.NetworkAcls = append(output.NetworkAcls, acl)
+		}
+	}
+	return output, nil
+}
+
+// matchFilters reports whether a resource matches every filter. Values of one
+// filter match any of them, like EC2. lookup returns the resource's values for
+// a filter name, or nil when the resource has none.
+func matchFilters(filters []types.Filter, lookup func(name string) []string) (bool, error) {
+	for _, filter := range filters {
+		if filter.Name == nil {
+			continue
+		}
+		name := *filter.Name
+		if name != "vpc-id" && name != "transit-gateway-id" && name != "state" && name != "description" && name != "network-interface-id" && name != "group-id" &&
+			!strings.HasPrefix(name, "tag:") {
+			return false, fmt.Errorf("scannertest: unsupported filter %q", name)
+		}
+
+		matched := false
+		for _, value := range lookup(name) {
+			for _, want := range filter.Values {
+				if value == want {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// rejectFilters fails for operations the scanner calls without filters
+func rejectFilters(filters []types.Filter) error {
+	if len(filters) > 0 && filters[0].Name != nil {
+		return fmt.Errorf("scannertest: unsupported filter %q", *filters[0].Name)
+	}
+	return nil
+}
+
+// containsID reports whether id is in ids. An empty list matches every ID.
+func containsID(ids []string, id *string) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	for _, want := range ids {
+		if id != nil && *id == want {
+			return true
+		}
+	}
+	return false
+}
+
+// values wraps an optional string as a filter value list
+func values(value *string) []string {
+	if value == nil {
+		return nil
+	}
+	return []string{*value}
+}
+
+// tagValues returns the value of the tag named by a tag:<key> filter
+func tagValues(tags []types.Tag, name string) []string {
+	key, ok := strings.CutPrefix(name, "tag:")
+	if !ok {
+		return nil
+	}
+	for _, tag := range tags {
+		if tag.Key != nil && *tag.Key == key {
+			return values(tag.Value)
+		}
+	}
+	return nil
+}
+
+// DescribeFlowLogs returns every flow log
+func (f *FakeEC2) DescribeFlowLogs(ctx context.Context, params *ec2.DescribeFlowLogsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeFlowLogsOutput, error) {
+	if err := f.Errors["DescribeFlowLogs"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filter); err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeFlowLogsOutput{FlowLogs: f.FlowLogs}, nil
+}
+
+// DescribeNetworkInterfaces returns the network interfaces matching the filters
+func (f *FakeEC2) DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	if err := f.Errors["DescribeNetworkInterfaces"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeNetworkInterfacesOutput{}
+	for _, eni := range f.NetworkInterfaces {
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			switch name {
+			case "vpc-id":
+				return values(eni.VpcId)
+			case "description":
+				return values(eni.Description)
+			case "network-interface-id":
+				return values(eni.NetworkInterfaceId)
+			}
+			return tagValues(eni.TagSet, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.NetworkInterfaces = append(output.NetworkInterfaces, eni)
+		}
+	}
+	return output, nil
+}
+
+// DescribeVpcEndpointServiceConfigurations returns every endpoint service configuration
+func (f *FakeEC2) DescribeVpcEndpointServiceConfigurations(ctx context.Context, params *ec2.DescribeVpcEndpointServiceConfigurationsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointServiceConfigurationsOutput, error) {
+	if err := f.Errors["DescribeVpcEndpointServiceConfigurations"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filters); err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeVpcEndpointServiceConfigurationsOutput{ServiceConfigurations: f.EndpointServices}, nil
+}
+
+// DescribeVpcEndpointServicePermissions returns the principals allowed to connect to an endpoint service
+func (f *FakeEC2) DescribeVpcEndpointServicePermissions(ctx context.Context, params *ec2.DescribeVpcEndpointServicePermissionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointServicePermissionsOutput, error) {
+	if err := f.Errors["DescribeVpcEndpointServicePermissions"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filters); err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeVpcEndpointServicePermissionsOutput{AllowedPrincipals: f.EndpointServicePrincipals[deref(params.ServiceId)]}, nil
+}
+
+// DescribeVpcEndpoints returns the VPC endpoints matching the filters
+func (f *FakeEC2) DescribeVpcEndpoints(ctx context.Context, params *ec2.DescribeVpcEndpointsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+	if err := f.Errors["DescribeVpcEndpoints"]; err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeVpcEndpointsOutput{}
+	for _, endpoint := range f.VpcEndpoints {
+		ok, err := matchFilters(params.Filters, func(name string) []string {
+			if name == "vpc-id" {
+				return values(endpoint.VpcId)
+			}
+			return tagValues(endpoint.Tags, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			output.VpcEndpoints = append(output.VpcEndpoints, endpoint)
+		}
+	}
+	return output, nil
+}
+
+// DescribeAddresses returns every Elastic IP
+func (f *FakeEC2) DescribeAddresses(ctx context.Context, params *ec2.DescribeAddressesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	if err := f.Errors["DescribeAddresses"]; err != nil {
+		return nil, err
+	}
+	if err := rejectFilters(params.Filters); err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeAddressesOutput{Addresses: f.Addresses}, nil
+}
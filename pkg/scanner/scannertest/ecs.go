@@ -0,0 +1,77 @@
+package scannertest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// FakeECS is an in-memory ECS API
+type FakeECS struct {
+	ClusterArns []string
+	Services    []ecsTypes.Service // matched to clusters by ClusterArn
+	// TaskDefinitions are matched to services by TaskDefinitionArn
+	TaskDefinitions []ecsTypes.TaskDefinition
+
+	// Errors makes an operation fail, keyed by operation name such as "ListServices"
+	Errors map[string]error
+}
+
+// ListClusters returns the ARN of every cluster
+func (f *FakeECS) ListClusters(ctx context.Context, params *ecs.ListClustersInput, optFns ...func(*ecs.Options)) (*ecs.ListClustersOutput, error) {
+	if err := f.Errors["ListClusters"]; err != nil {
+		return nil, err
+	}
+	return &ecs.ListClustersOutput{ClusterArns: f.ClusterArns}, nil
+}
+
+// ListServices returns the ARNs of a cluster's services
+func (f *FakeECS) ListServices(ctx context.Context, params *ecs.ListServicesInput, optFns ...func(*ecs.Options)) (*ecs.ListServicesOutput, error) {
+	if err := f.Errors["ListServices"]; err != nil {
+		return nil, err
+	}
+
+	output := &ecs.ListServicesOutput{}
+	for _, service := range f.Services {
+		if deref(service.ClusterArn) == deref(params.Cluster) {
+			output.ServiceArns = append(output.ServiceArns, deref(service.ServiceArn))
+		}
+	}
+	return output, nil
+}
+
+// DescribeServices returns a cluster's services by ARN, at most ten per call like ECS
+func (f *FakeECS) DescribeServices(ctx context.Context, params *ecs.DescribeServicesInput, optFns ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error) {
+	if err := f.Errors["DescribeServices"]; err != nil {
+		return nil, err
+	}
+	if len(params.Services) > 10 {
+		return nil, fmt.Errorf("scannertest: DescribeServices accepts at most 10 services, got %d", len(params.Services))
+	}
+
+	output := &ecs.DescribeServicesOutput{}
+	for _, arn := range params.Services {
+		for _, service := range f.Services {
+			if deref(service.ClusterArn) == deref(params.Cluster) && deref(service.ServiceArn) == arn {
+				output.Services = append(output.Services, service)
+			}
+		}
+	}
+	return output, nil
+}
+
+// DescribeTaskDefinition returns a task definition by ARN
+func (f *FakeECS) DescribeTaskDefinition(ctx context.Context, params *ecs.DescribeTaskDefinitionInput, optFns ...func(*ecs.Options)) (*ecs.DescribeTaskDefinitionOutput, error) {
+	if err := f.Errors["DescribeTaskDefinition"]; err != nil {
+		return nil, err
+	}
+
+	for i := range f.TaskDefinitions {
+		if deref(f.TaskDefinitions[i].TaskDefinitionArn) == deref(params.TaskDefinition) {
+			return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: &f.TaskDefinitions[i]}, nil
+		}
+	}
+	return nil, fmt.Errorf("scannertest: no task definition %q", deref(params.TaskDefinition))
+}
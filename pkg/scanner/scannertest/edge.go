@@ -0,0 +1,87 @@
+package scannertest
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	cfTypes "github.com/aws/aws-sdk-go-v2/service/cloudfront/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbTypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
+	gaTypes "github.com/aws/aws-sdk-go-v2/service/globalaccelerator/types"
+)
+
+// FakeELBv2 is an in-memory Elastic Load Balancing v2 API
+type FakeELBv2 struct {
+	LoadBalancers []elbTypes.LoadBalancer
+
+	// Errors makes an operation fail, keyed by operation name such as "DescribeLoadBalancers"
+	Errors map[string]error
+}
+
+// DescribeLoadBalancers returns every load balancer
+func (f *FakeELBv2) DescribeLoadBalancers(ctx context.Context, params *elasticloadbalancingv2.DescribeLoadBalancersInput, optFns ...func(*elasticloadbalancingv2.Options)) (*elasticloadbalancingv2.DescribeLoadBalancersOutput, error) {
+	if err := f.Errors["DescribeLoadBalancers"]; err != nil {
+		return nil, err
+	}
+	return &elasticloadbalancingv2.DescribeLoadBalancersOutput{LoadBalancers: f.LoadBalancers}, nil
+}
+
+// FakeGlobalAccelerator is an in-memory Global Accelerator API
+type FakeGlobalAccelerator struct {
+	Accelerators   []gaTypes.Accelerator
+	Listeners      map[string][]gaTypes.Listener      // by accelerator ARN
+	EndpointGroups map[string][]gaTypes.EndpointGroup // by listener ARN
+
+	// Errors makes an operation fail, keyed by operation name such as "ListAccelerators"
+	Errors map[string]error
+}
+
+// ListAccelerators returns every accelerator
+func (f *FakeGlobalAccelerator) ListAccelerators(ctx context.Context, params *globalaccelerator.ListAcceleratorsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListAcceleratorsOutput, error) {
+	if err := f.Errors["ListAccelerators"]; err != nil {
+		return nil, err
+	}
+	return &globalaccelerator.ListAcceleratorsOutput{Accelerators: f.Accelerators}, nil
+}
+
+// ListListeners returns the listeners of an accelerator
+func (f *FakeGlobalAccelerator) ListListeners(ctx context.Context, params *globalaccelerator.ListListenersInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListListenersOutput, error) {
+	if err := f.Errors["ListListeners"]; err != nil {
+		return nil, err
+	}
+	return &globalaccelerator.ListListenersOutput{Listeners: f.Listeners[deref(params.AcceleratorArn)]}, nil
+}
+
+// ListEndpointGroups returns the endpoint groups of a listener
+func (f *FakeGlobalAccelerator) ListEndpointGroups(ctx context.Context, params *globalaccelerator.ListEndpointGroupsInput, optFns ...func(*globalaccelerator.Options)) (*globalaccelerator.ListEndpointGroupsOutput, error) {
+	if err := f.Errors["ListEndpointGroups"]; err != nil {
+		return nil, err
+	}
+	return &globalaccelerator.ListEndpointGroupsOutput{EndpointGroups: f.EndpointGroups[deref(params.ListenerArn)]}, nil
+}
+
+// FakeCloudFront is an in-memory CloudFront API
+type FakeCloudFront struct {
+	Distributions []cfTypes.DistributionSummary
+	VpcOrigins    []cfTypes.VpcOriginSummary
+
+	// Errors makes an operation fail, keyed by operation name such as "ListDistributions"
+	Errors map[string]error
+}
+
+// ListDistributions returns every distribution
+func (f *FakeCloudFront) ListDistributions(ctx context.Context, params *cloudfront.ListDistributionsInput, optFns ...func(*cloudfront.Options)) (*cloudfront.ListDistributionsOutput, error) {
+	if err := f.Errors["ListDistributions"]; err != nil {
+		return nil, err
+	}
+	return &cloudfront.ListDistributionsOutput{DistributionList: &cfTypes.DistributionList{Items: f.Distributions}}, nil
+}
+
+// ListVpcOrigins returns every VPC origin
+func (f *FakeCloudFront) ListVpcOrigins(ctx context.Context, params *cloudfront.ListVpcOriginsInput, optFns ...func(*cloudfront.Options)) (*cloudfront.ListVpcOriginsOutput, error) {
+	if err := f.Errors["ListVpcOrigins"]; err != nil {
+		return nil, err
+	}
+	return &cloudfront.ListVpcOriginsOutput{VpcOriginList: &cfTypes.VpcOriginList{Items: f.VpcOrigins}}, nil
+}
@@ -0,0 +1,44 @@
+package scannertest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	eksTypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+)
+
+// FakeEKS is an in-memory EKS API
+type FakeEKS struct {
+	Clusters []eksTypes.Cluster
+
+	// Errors makes an operation fail, keyed by operation name such as "ListClusters"
+	Errors map[string]error
+}
+
+// ListClusters returns the name of every cluster
+func (f *FakeEKS) ListClusters(ctx context.Context, params *eks.ListClustersInput, optFns ...func(*eks.Options)) (*eks.ListClustersOutput, error) {
+	if err := f.Errors["ListClusters"]; err != nil {
+		return nil, err
+	}
+
+	output := &eks.ListClustersOutput{}
+	for _, cluster := range f.Clusters {
+		output.Clusters = append(output.Clusters, deref(cluster.Name))
+	}
+	return output, nil
+}
+
+// DescribeCluster returns a cluster by name
+func (f *FakeEKS) DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	if err := f.Errors["DescribeCluster"]; err != nil {
+		return nil, err
+	}
+
+	for i := range f.Clusters {
+		if deref(f.Clusters[i].Name) == deref(params.Name) {
+			return &eks.DescribeClusterOutput{Cluster: &f.Clusters[i]}, nil
+		}
+	}
+	return nil, fmt.Errorf("scannertest: no EKS cluster %q", deref(params.Name))
+}
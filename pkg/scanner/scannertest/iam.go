@@ -0,0 +1,144 @@
+package scannertest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// FakeIAM is an in-memory IAM API
+type FakeIAM struct {
+	Roles            []iamTypes.Role
+	AttachedPolicies map[string][]iamTypes.AttachedPolicy // by role name
+	Policies         []iamTypes.Policy
+	PolicyDocuments  map[string]string            // default version document by policy ARN
+	InlinePolicies   map[string]map[string]string // policy name to document, by role name
+	InstanceProfiles []iamTypes.InstanceProfile
+
+	// PageSize splits ListRoles into pages of this many roles, 0 returns every role at once
+	PageSize int
+
+	// Errors makes an operation fail, keyed by operation name such as "ListRoles"
+	Errors map[string]error
+}
+
+// ListRoles returns a page of roles, continuing from the marker
+func (f *FakeIAM) ListRoles(ctx context.Context, params *iam.ListRolesInput, optFns ...func(*iam.Options)) (*iam.ListRolesOutput, error) {
+	if err := f.Errors["ListRoles"]; err != nil {
+		return nil, err
+	}
+
+	start := 0
+	if params.Marker != nil {
+		marker, err := strconv.Atoi(*params.Marker)
+		if err != nil || marker < 0 || marker > len(f.Roles) {
+			return nil, fmt.Errorf("scannertest: invalid marker %q", *params.Marker)
+		}
+		start = marker
+	}
+
+	end := len(f.Roles)
+	if f.PageSize > 0 && start+f.PageSize < end {
+		end = start + f.PageSize
+	}
+
+	output := &iam.ListRolesOutput{Roles: f.Roles[start:end]}
+	if end < len(f.Roles) {
+		marker := strconv.Itoa(end)
+		output.IsTruncated = true
+		output.Marker = &marker
+	}
+	return output, nil
+}
+
+// ListAttachedRolePolicies returns the managed policies attached to a role
+func (f *FakeIAM) ListAttachedRolePolicies(ctx context.Context, params *iam.ListAttachedRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error) {
+	if err := f.Errors["ListAttachedRolePolicies"]; err != nil {
+		return nil, err
+	}
+	return &iam.ListAttachedRolePoliciesOutput{AttachedPolicies: f.AttachedPolicies[deref(params.RoleName)]}, nil
+}
+
+// ListRolePolicies returns the names of a role's inline policies in sorted order
+func (f *FakeIAM) ListRolePolicies(ctx context.Context, params *iam.ListRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error) {
+	if err := f.Errors["ListRolePolicies"]; err != nil {
+		return nil, err
+	}
+
+	output := &iam.ListRolePoliciesOutput{}
+	for name := range f.InlinePolicies[deref(params.RoleName)] {
+		output.PolicyNames = append(output.PolicyNames, name)
+	}
+	sort.Strings(output.PolicyNames)
+	return output, nil
+}
+
+// GetRolePolicy returns the document of a role's inline policy
+func (f *FakeIAM) GetRolePolicy(ctx context.Context, params *iam.GetRolePolicyInput, optFns ...func(*iam.Options)) (*iam.GetRolePolicyOutput, error) {
+	if err := f.Errors["GetRolePolicy"]; err != nil {
+		return nil, err
+	}
+
+	document, ok := f.InlinePolicies[deref(params.RoleName)][deref(params.PolicyName)]
+	if !ok {
+		return nil, fmt.Errorf("scannertest: no inline policy %q on role %q", deref(params.PolicyName), deref(params.RoleName))
+	}
+	return &iam.GetRolePolicyOutput{
+		RoleName:       params.RoleName,
+		PolicyName:     params.PolicyName,
+		PolicyDocument: &document,
+	}, nil
+}
+
+// GetPolicy returns a managed policy by ARN
+func (f *FakeIAM) GetPolicy(ctx context.Context, params *iam.GetPolicyInput, optFns ...func(*iam.Options)) (*iam.GetPolicyOutput, error) {
+	if err := f.Errors["GetPolicy"]; err != nil {
+		return nil, err
+	}
+
+	for i := range f.Policies {
+		if deref(f.Policies[i].Arn) == deref(params.PolicyArn) {
+			return &iam.GetPolicyOutput{Policy: &f.Policies[i]}, nil
+		}
+	}
+	return nil, fmt.Errorf("scannertest: no policy %q", deref(params.PolicyArn))
+}
+
+// GetPolicyVersion returns a managed policy's document, whatever the version
+func (f *FakeIAM) GetPolicyVersion(ctx context.Context, params *iam.GetPolicyVersionInput, optFns ...func(*iam.Options)) (*iam.GetPolicyVersionOutput, error) {
+	if err := f.Errors["GetPolicyVersion"]; err != nil {
+		return nil, err
+	}
+
+	document, ok := f.PolicyDocuments[deref(params.PolicyArn)]
+	if !ok {
+		return nil, fmt.Errorf("scannertest: no document for policy %q", deref(params.PolicyArn))
+	}
+	return &iam.GetPolicyVersionOutput{
+		PolicyVersion: &iamTypes.PolicyVersion{
+			VersionId:        params.VersionId,
+			Document:         &document,
+			IsDefaultVersion: true,
+		},
+	}, nil
+}
+
+// ListInstanceProfiles returns every instance profile at once
+func (f *FakeIAM) ListInstanceProfiles(ctx context.Context, params *iam.ListInstanceProfilesInput, optFns ...func(*iam.Options)) (*iam.ListInstanceProfilesOutput, error) {
+	if err := f.Errors["ListInstanceProfiles"]; err != nil {
+		return nil, err
+	}
+	return &iam.ListInstanceProfilesOutput{InstanceProfiles: f.InstanceProfiles}, nil
+}
+
+// deref returns the value of an optional string
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
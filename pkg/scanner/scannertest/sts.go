@@ -0,0 +1,23 @@
+package scannertest
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// FakeSTS is an in-memory STS API reporting a fixed account
+type FakeSTS struct {
+	Account string
+
+	// Err makes GetCallerIdentity fail
+	Err error
+}
+
+// GetCallerIdentity returns the fake account
+func (f *FakeSTS) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return &sts.GetCallerIdentityOutput{Account: &f.Account}, nil
+}
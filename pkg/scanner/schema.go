@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// NetworkSchemaVersion is the version of the working state schema.
+// Bump it and add a migration whenever a field is renamed, moved or removed.
+const NetworkSchemaVersion = 2
+
+// migrations upgrade a working state's top-level fields from each schema version to
+// the next, starting at version 1. States saved before versioning are version 1.
+var migrations = []func(state map[string]json.RawMessage) error{
+	migrateSharedPolicies, // 1 → 2
+}
+
+// SchemaVersionError is returned when a working state was saved by a newer version
+// of pikaatools than the one loading it
+type SchemaVersionError struct {
+	Version int
+}
+
+// Error returns a human-readable description of the error
+func (e *SchemaVersionError) Error() string {
+	return fmt.Sprintf("saved with schema version %d, but this version of pikaatools reads up to schema version %d; upgrade pikaatools to load it", e.Version, NetworkSchemaVersion)
+}
+
+// migrateState upgrades a working state saved with an older schema version to the
+// current one, returning states already in it unchanged
+func migrateState(data []byte) ([]byte, error) {
+	var header struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	version := header.SchemaVersion
+	if version == 0 {
+		version = 1
+	}
+	if version > NetworkSchemaVersion {
+		return nil, &SchemaVersionError{Version: version}
+	}
+	if version == NetworkSchemaVersion {
+		return data, nil
+	}
+
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	for ; version < NetworkSchemaVersion; version++ {
+		if err := migrations[version-1](state); err != nil {
+			return nil, fmt.Errorf("failed to migrate from schema version %d: %w", version, err)
+		}
+	}
+	state["schema_version"] = json.RawMessage(fmt.Sprint(NetworkSchemaVersion))
+	return json.Marshal(state)
+}
+
+// migrateSharedPolicies moves the copy of its managed policies each IAM role held
+// into the network's policies, leaving the roles referencing them by ARN
+func migrateSharedPolicies(state map[string]json.RawMessage) error {
+	var roles []map[string]json.RawMessage
+	if raw, ok := state["iam_roles"]; ok {
+		if err := json.Unmarshal(raw, &roles); err != nil {
+			return err
+		}
+	}
+
+	policies := make(map[string]json.RawMessage)
+	for _, role := range roles {
+		if role == nil {
+			continue
+		}
+		var attached []json.RawMessage
+		if raw, ok := role["attached_policies"]; ok {
+			if err := json.Unmarshal(raw, &attached); err != nil {
+				return err
+			}
+		}
+
+		var arns []string
+		for _, policy := range attached {
+			var ref struct {
+				Arn string `json:"arn"`
+			}
+			if err := json.Unmarshal(policy, &ref); err != nil {
+				return err
+			}
+			arns = append(arns, ref.Arn)
+			// Roles scanned at different times may hold different copies, the first is kept
+			if _, exists := policies[ref.Arn]; !exists {
+				policies[ref.Arn] = policy
+			}
+		}
+
+		delete(role, "attached_policies")
+		raw, err := json.Marshal(arns)
+		if err != nil {
+			return err
+		}
+		role["attached_policy_arns"] = raw
+	}
+
+	if roles != nil {
+		raw, err := json.Marshal(roles)
+		if err != nil {
+			return err
+		}
+		state["iam_roles"] = raw
+	}
+	if len(policies) > 0 {
+		raw, err := json.Marshal(policies)
+		if err != nil {
+			return err
+		}
+		state["policies"] = raw
+	}
+	return nil
+}
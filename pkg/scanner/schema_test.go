@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMigrateState(t *testing.T) {
+	unversioned := []byte(`{"region":"us-east-1","iam_roles":[
+		{"name":"role-a","attached_policies":[{"arn":"arn:aws:iam::aws:policy/ReadOnlyAccess","policy_document":"{}"}]},
+		{"name":"role-b","attached_policies":[{"arn":"arn:aws:iam::aws:policy/ReadOnlyAccess","policy_document":"{}"}]},
+		{"name":"role-c","attached_policies":null}
+	]}`)
+
+	migrated, err := migrateState(unversioned)
+	if err != nil {
+		t.Fatalf("migrateState failed: %v", err)
+	}
+	var state struct {
+		SchemaVersion int                  `json:"schema_version"`
+		IAMRoles      []storedIAMRole      `json:"iam_roles"`
+		Policies      map[string]IAMPolicy `json:"policies"`
+	}
+	if err := json.Unmarshal(migrated, &state); err != nil {
+		t.Fatalf("Failed to read migrated state: %v", err)
+	}
+	if state.SchemaVersion != NetworkSchemaVersion || len(state.Policies) != 1 || len(state.IAMRoles) != 3 {
+		t.Fatalf("Expected a current state with one shared policy, got %s", migrated)
+	}
+	if len(state.IAMRoles[1].AttachedPolicyArns) != 1 || len(state.IAMRoles[1].AttachedPolicies) != 0 || len(state.IAMRoles[2].AttachedPolicyArns) != 0 {
+		t.Errorf("Expected the roles to reference their policies, got %+v", state.IAMRoles)
+	}
+
+	current, err := json.Marshal(Network{Region: "us-east-1"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if unchanged, err := migrateState(current); err != nil || string(unchanged) != string(current) {
+		t.Errorf("Expected a current state unchanged, got %s, %v", unchanged, err)
+	}
+
+	var network Network
+	err = json.Unmarshal([]byte(`{"schema_version":999}`), &network)
+	var versionErr *SchemaVersionError
+	if !errors.As(err, &versionErr) || versionErr.Version != 999 {
+		t.Errorf("Expected a schema version error for a newer state, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package scanner
+
+import (
+	"sort"
+	"strings"
+)
+
+// Sort orders every collection in the network by name and then ID, and nested
+// lists such as routes, rules and ID references by their own keys, so scans of an
+// unchanged network render, export and diff the same way whatever order the AWS
+// APIs returned them in
+func (n *Network) Sort() {
+	sortByName(n.VPCs, func(vpc VPC) (string, string) { return vpc.Name, vpc.ID })
+	sortByName(n.Subnets, func(subnet Subnet) (string, string) { return subnet.Name, subnet.ID })
+	sortByName(n.PeeringConnections, func(peering PeeringConnection) (string, string) { return peering.Name, peering.ID })
+	sortByName(n.TransitGateways, func(tgw TransitGateway) (string, string) { return tgw.Name, tgw.ID })
+	sortByName(n.InternetGateways, func(igw InternetGateway) (string, string) { return igw.Name, igw.ID })
+	sortByName(n.EgressOnlyInternetGateways, func(eigw EgressOnlyInternetGateway) (string, string) { return eigw.Name, eigw.ID })
+	sortByName(n.NATGateways, func(nat NATGateway) (string, string) { return nat.Name, nat.ID })
+	sortByName(n.RouteTables, func(routeTable RouteTable) (string, string) { return routeTable.Name, routeTable.ID })
+	sortByName(n.SecurityGroups, func(sg SecurityGroup) (string, string) { return sg.Name, sg.ID })
+	sortByName(n.NetworkAcls, func(acl NetworkAcl) (string, string) { return acl.Name, acl.ID })
+	sortByName(n.FlowLogs, func(flowLog FlowLog) (string, string) { return "", flowLog.ID })
+	sortByName(n.IAMRoles, func(role IAMRole) (string, string) { return role.Name, role.ID })
+	sortByName(n.MeshVirtualGateways, func(gateway MeshVirtualGateway) (string, string) { return gateway.Name, gateway.Arn })
+	sortByName(n.DhcpOptions, func(options DhcpOptions) (string, string) { return options.Name, options.ID })
+	sortByName(n.EndpointServices, func(service EndpointService) (string, string) { return service.Name, service.ID })
+	sortByName(n.VpcEndpoints, func(endpoint VpcEndpoint) (string, string) { return endpoint.Name, endpoint.ID })
+	sortByName(n.NetworkInterfaces, func(eni NetworkInterface) (string, string) { return "", eni.ID })
+	sortByName(n.EKSClusters, func(cluster EKSCluster) (string, string) { return cluster.Name, cluster.Arn })
+	sortByName(n.ECSServices, func(service ECSService) (string, string) { return service.Name, service.Arn })
+	sortByName(n.DatabaseSubnetGroups, func(group DatabaseSubnetGroup) (string, string) { return group.Name, group.Service })
+	sortByName(n.Databases, func(database Database) (string, string) { return database.ID, database.Service })
+	sortByName(n.EdgeIngresses, func(ingress EdgeIngress) (string, string) { return ingress.Name, ingress.ID })
+
+	// ID references follow the order of the resources they refer to
+	subnets := positions(len(n.Subnets), func(i int) string { return n.Subnets[i].ID })
+	igws := positions(len(n.InternetGateways), func(i int) string { return n.InternetGateways[i].ID })
+	eigws := positions(len(n.EgressOnlyInternetGateways), func(i int) string { return n.EgressOnlyInternetGateways[i].ID })
+	nats := positions(len(n.NATGateways), func(i int) string { return n.NATGateways[i].ID })
+	groups := positions(len(n.SecurityGroups), func(i int) string { return n.SecurityGroups[i].ID })
+	acls := positions(len(n.NetworkAcls), func(i int) string { return n.NetworkAcls[i].ID })
+	for i := range n.VPCs {
+		vpc := &n.VPCs[i]
+		sortIDs(vpc.Subnets, subnets)
+		sortIDs(vpc.InternetGateways, igws)
+		sortIDs(vpc.EgressOnlyInternetGateways, eigws)
+		sortIDs(vpc.NATGateways, nats)
+		sortIDs(vpc.SecurityGroups, groups)
+		sortIDs(vpc.NetworkAcls, acls)
+	}
+
+	for i := range n.Subnets {
+		sortByName(n.Subnets[i].CidrReservations, func(reservation SubnetCidrReservation) (string, string) { return "", reservation.ID })
+	}
+	for i := range n.TransitGateways {
+		tgw := &n.TransitGateways[i]
+		sortByName(tgw.Attachments, func(attachment TransitGatewayAttachment) (string, string) { return "", attachment.ID })
+		sortByName(tgw.RouteTables, func(routeTable TransitGatewayRouteTable) (string, string) { return routeTable.Name, routeTable.ID })
+		for j := range tgw.RouteTables {
+			sortByName(tgw.RouteTables[j].Routes, func(route TransitGatewayRoute) (string, string) { return route.Destination(), route.AttachmentID })
+		}
+	}
+	for i := range n.RouteTables {
+		routeTable := &n.RouteTables[i]
+		sortByName(routeTable.Routes, func(route Route) (string, string) { return route.Destination(), route.Target() })
+		sortIDs(routeTable.Associations, subnets)
+	}
+	for i := range n.SecurityGroups {
+		sortRules(n.SecurityGroups[i].IngressRules)
+		sortRules(n.SecurityGroups[i].EgressRules)
+	}
+	for i := range n.NetworkAcls {
+		acl := &n.NetworkAcls[i]
+		sort.SliceStable(acl.Entries, func(a, b int) bool {
+			if acl.Entries[a].Egress != acl.Entries[b].Egress {
+				return !acl.Entries[a].Egress
+			}
+			return acl.Entries[a].RuleNumber < acl.Entries[b].RuleNumber
+		})
+		sortIDs(acl.Associations, subnets)
+	}
+	for i := range n.IAMRoles {
+		role := &n.IAMRoles[i]
+		sortByName(role.AttachedPolicies, func(policy IAMPolicy) (string, string) { return policy.PolicyName, policy.Arn })
+		sortByName(role.InlinePolicies, func(policy IAMInlinePolicy) (string, string) { return policy.PolicyName, "" })
+		sortByName(role.InstanceProfiles, func(profile IAMInstanceProfile) (string, string) { return profile.Name, profile.ID })
+	}
+	for i := range n.DatabaseSubnetGroups {
+		sortIDs(n.DatabaseSubnetGroups[i].SubnetIDs, subnets)
+	}
+	for i := range n.Databases {
+		sortIDs(n.Databases[i].SubnetIDs, subnets)
+	}
+	for i := range n.EKSClusters {
+		sortIDs(n.EKSClusters[i].SubnetIDs, subnets)
+	}
+	for i := range n.ECSServices {
+		sortIDs(n.ECSServices[i].SubnetIDs, subnets)
+	}
+	for i := range n.NetworkInterfaces {
+		sortIDs(n.NetworkInterfaces[i].SecurityGroupIDs, groups)
+	}
+	for i := range n.VpcEndpoints {
+		endpoint := &n.VpcEndpoints[i]
+		sortIDs(endpoint.SubnetIDs, subnets)
+		sortIDs(endpoint.SecurityGroupIDs, groups)
+		sortByName(endpoint.NetworkInterfaces, func(eni EndpointNetworkInterface) (string, string) { return "", eni.ID })
+		sortByName(endpoint.DNSEntries, func(entry EndpointDNSEntry) (string, string) { return entry.DNSName, entry.HostedZoneID })
+	}
+	for i := range n.EdgeIngresses {
+		ingress := &n.EdgeIngresses[i]
+		sortByName(ingress.Targets, func(target EdgeTarget) (string, string) { return target.Name, target.ID })
+		for j := range ingress.Targets {
+			sortIDs(ingress.Targets[j].SubnetIDs, subnets)
+		}
+	}
+}
+
+// sortByName sorts items by a name and then an ID, keeping the order of equal items
+func sortByName[T any](items []T, key func(T) (string, string)) {
+	sort.SliceStable(items, func(i, j int) bool {
+		nameI, idI := key(items[i])
+		nameJ, idJ := key(items[j])
+		if nameI != nameJ {
+			return nameI < nameJ
+		}
+		return idI < idJ
+	})
+}
+
+// positions maps the IDs of a sorted collection to their index
+func positions(count int, id func(int) string) map[string]int {
+	index := make(map[string]int, count)
+	for i := 0; i < count; i++ {
+		index[id(i)] = i
+	}
+	return index
+}
+
+// sortIDs sorts ID references in the order of the resources they refer to, with IDs
+// of resources that were not scanned last in ID order
+func sortIDs(ids []string, index map[string]int) {
+	sort.SliceStable(ids, func(i, j int) bool {
+		posI, knownI := index[ids[i]]
+		posJ, knownJ := index[ids[j]]
+		switch {
+		case knownI && knownJ:
+			return posI < posJ
+		case knownI != knownJ:
+			return knownI
+		}
+		return ids[i] < ids[j]
+	})
+}
+
+// sortRules sorts security group rules by rule ID, or by what they allow when they
+// were read from the group's permissions without IDs
+func sortRules(rules []SecurityGroupRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].ID != rules[j].ID {
+			return rules[i].ID < rules[j].ID
+		}
+		if rules[i].IpProtocol != rules[j].IpProtocol {
+			return rules[i].IpProtocol < rules[j].IpProtocol
+		}
+		if rules[i].FromPort != rules[j].FromPort {
+			return rules[i].FromPort < rules[j].FromPort
+		}
+		if rules[i].ToPort != rules[j].ToPort {
+			return rules[i].ToPort < rules[j].ToPort
+		}
+		return ruleSources(rules[i]) < ruleSources(rules[j])
+	})
+}
+
+// ruleSources joins the sources or destinations of a security group rule
+func ruleSources(rule SecurityGroupRule) string {
+	var sources []string
+	sources = append(sources, rule.CidrBlocks...)
+	sources = append(sources, rule.Ipv6CidrBlocks...)
+	sources = append(sources, rule.PrefixListIds...)
+	sources = append(sources, rule.ReferencedGroupIDs()...)
+	return strings.Join(sources, ",")
+}
@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNetworkSort(t *testing.T) {
+	network := &Network{
+		VPCs: []VPC{
+			{ID: "vpc-3", Name: "staging"},
+			{ID: "vpc-2"},
+			{ID: "vpc-1", Name: "production", Subnets: []string{"subnet-gone", "subnet-b", "subnet-a"}},
+		},
+		Subnets: []Subnet{
+			{ID: "subnet-a", Name: "web"},
+			{ID: "subnet-b", Name: "app"},
+		},
+		RouteTables: []RouteTable{
+			{ID: "rtb-1", Routes: []Route{
+				{DestinationCidr: "10.0.0.0/16", GatewayID: "local"},
+				{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-1"},
+			}, Associations: []string{"subnet-a", "subnet-b"}},
+		},
+		SecurityGroups: []SecurityGroup{
+			{ID: "sg-1", IngressRules: []SecurityGroupRule{
+				{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"10.0.0.0/8"}},
+				{IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"10.0.0.0/8"}},
+				{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+			}},
+		},
+		NetworkAcls: []NetworkAcl{
+			{ID: "acl-1", Entries: []NetworkAclEntry{
+				{RuleNumber: 100, Egress: true},
+				{RuleNumber: 32767},
+				{RuleNumber: 100},
+			}},
+		},
+	}
+	network.Sort()
+
+	var vpcs []string
+	for _, vpc := range network.VPCs {
+		vpcs = append(vpcs, vpc.ID)
+	}
+	if !reflect.DeepEqual(vpcs, []string{"vpc-2", "vpc-1", "vpc-3"}) {
+		t.Errorf("Expected VPCs by name and then ID, got %v", vpcs)
+	}
+	if subnets := network.VPCs[1].Subnets; !reflect.DeepEqual(subnets, []string{"subnet-b", "subnet-a", "subnet-gone"}) {
+		t.Errorf("Expected subnet references in subnet order with unknown IDs last, got %v", subnets)
+	}
+	if associations := network.RouteTables[0].Associations; !reflect.DeepEqual(associations, []string{"subnet-b", "subnet-a"}) {
+		t.Errorf("Expected route table associations in subnet order, got %v", associations)
+	}
+	if routes := network.RouteTables[0].Routes; routes[0].DestinationCidr != "0.0.0.0/0" {
+		t.Errorf("Expected routes by destination, got %+v", routes)
+	}
+
+	if rules := network.SecurityGroups[0].IngressRules; rules[0].FromPort != 22 || rules[1].CidrBlocks[0] != "0.0.0.0/0" {
+		t.Errorf("Expected rules by protocol, ports and sources, got %+v", rules)
+	}
+
+	entries := network.NetworkAcls[0].Entries
+	if entries[0].RuleNumber != 100 || entries[0].Egress || entries[1].RuleNumber != 32767 || !entries[2].Egress {
+		t.Errorf("Expected inbound entries by rule number before outbound entries, got %+v", entries)
+	}
+}
@@ -0,0 +1,134 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner/scannertest"
+)
+
+// newSparseEC2 returns resources with only the fields the fake needs to filter
+// them set, like resources AWS returns with optional fields left out
+func newSparseEC2() *scannertest.FakeEC2 {
+	vpcID := awssdk.String("vpc-sparse")
+	return &scannertest.FakeEC2{
+		Vpcs: []types.Vpc{{VpcId: vpcID}},
+		Subnets: []types.Subnet{
+			// IPv6-only subnets have no IPv4 CIDR block
+			{SubnetId: awssdk.String("subnet-ipv6"), VpcId: vpcID, Ipv6CidrBlockAssociationSet: []types.SubnetIpv6CidrBlockAssociation{
+				{Ipv6CidrBlock: awssdk.String("2600:1f18::/64"), Ipv6CidrBlockState: &types.SubnetCidrBlockState{State: types.SubnetCidrBlockStateCodeAssociated}},
+			}},
+			{VpcId: vpcID},
+		},
+		VpcPeeringConnections:      []types.VpcPeeringConnection{{RequesterVpcInfo: &types.VpcPeeringConnectionVpcInfo{VpcId: vpcID}}},
+		TransitGateways:            []types.TransitGateway{{}},
+		InternetGateways:           []types.InternetGateway{{Attachments: []types.InternetGatewayAttachment{{VpcId: vpcID}}}},
+		EgressOnlyInternetGateways: []types.EgressOnlyInternetGateway{{Attachments: []types.InternetGatewayAttachment{{VpcId: vpcID}}}},
+		NatGateways:                []types.NatGateway{{VpcId: vpcID, NatGatewayAddresses: []types.NatGatewayAddress{{}}}},
+		RouteTables:                []types.RouteTable{{VpcId: vpcID, Associations: []types.RouteTableAssociation{{}}, Routes: []types.Route{{}}}},
+		SecurityGroups:             []types.SecurityGroup{{VpcId: vpcID, IpPermissions: []types.IpPermission{{IpRanges: []types.IpRange{{}}, UserIdGroupPairs: []types.UserIdGroupPair{{}}}}}, {GroupId: awssdk.String("sg-sparse"), VpcId: vpcID}},
+		SecurityGroupRules:         []types.SecurityGroupRule{{GroupId: awssdk.String("sg-sparse"), ReferencedGroupInfo: &types.ReferencedSecurityGroup{}}},
+		NetworkAcls:                []types.NetworkAcl{{VpcId: vpcID, Entries: []types.NetworkAclEntry{{PortRange: &types.PortRange{}, IcmpTypeCode: &types.IcmpTypeCode{}}}}},
+		FlowLogs:                   []types.FlowLog{{}},
+	}
+}
+
+func TestScanSparseResources(t *testing.T) {
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{
+		EC2: newSparseEC2(),
+		IAM: &scannertest.FakeIAM{
+			Roles:            []iamTypes.Role{{}},
+			Policies:         []iamTypes.Policy{{}},
+			InstanceProfiles: []iamTypes.InstanceProfile{{Roles: []iamTypes.Role{{}}}},
+		},
+		STS: &scannertest.FakeSTS{},
+	})
+	s.SetScanIAM(true)
+	s.SetAllIAMRoles(true)
+
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ScanNetwork failed: %v", err)
+	}
+
+	if len(network.VPCs) != 1 || network.VPCs[0].CidrBlock != "" || network.VPCs[0].DhcpOptionsID != "" {
+		t.Errorf("Expected a VPC without a CIDR block or DHCP option set, got %+v", network.VPCs)
+	}
+	var ipv6Only *Subnet
+	for i := range network.Subnets {
+		if network.Subnets[i].ID == "subnet-ipv6" {
+			ipv6Only = &network.Subnets[i]
+		}
+	}
+	if ipv6Only == nil || ipv6Only.CidrBlock != "" || len(ipv6Only.Ipv6CidrBlocks) != 1 || ipv6Only.UsableIPv4AddressCount() != 0 {
+		t.Fatalf("Expected an IPv6-only subnet without IPv4 addresses, got %+v", ipv6Only)
+	}
+	if len(network.PeeringConnections) != 1 || network.PeeringConnections[0].Status != "" {
+		t.Errorf("Expected a peering connection without a status, got %+v", network.PeeringConnections)
+	}
+	if len(network.SecurityGroups) != 2 || len(network.NetworkAcls) != 1 || len(network.NetworkAcls[0].Entries) != 1 {
+		t.Errorf("Expected the sparse security groups and network ACL entry, got %+v and %+v", network.SecurityGroups, network.NetworkAcls)
+	}
+	if len(network.IAMRoles) != 1 {
+		t.Errorf("Expected the sparse IAM role, got %+v", network.IAMRoles)
+	}
+}
+
+// FuzzConvertIpPermissions converts permissions with any combination of fields left out
+func FuzzConvertIpPermissions(f *testing.F) {
+	f.Add(uint16(0), "tcp", "10.0.0.0/8", int32(443))
+	f.Add(uint16(0xffff), "-1", "", int32(-1))
+	f.Add(uint16(0x5555), "icmpv6", "::/0", int32(0))
+
+	f.Fuzz(func(t *testing.T, present uint16, protocol, cidr string, port int32) {
+		optional := func(bit uint, value string) *string {
+			if present&(1<<bit) == 0 {
+				return nil
+			}
+			return &value
+		}
+		optionalPort := func(bit uint) *int32 {
+			if present&(1<<bit) == 0 {
+				return nil
+			}
+			return &port
+		}
+
+		permission := types.IpPermission{
+			IpProtocol:       optional(0, protocol),
+			FromPort:         optionalPort(1),
+			ToPort:           optionalPort(2),
+			IpRanges:         []types.IpRange{{CidrIp: optional(3, cidr)}},
+			Ipv6Ranges:       []types.Ipv6Range{{CidrIpv6: optional(4, cidr)}},
+			PrefixListIds:    []types.PrefixListId{{PrefixListId: optional(5, "pl-1")}},
+			UserIdGroupPairs: []types.UserIdGroupPair{{GroupId: optional(6, "sg-1"), UserId: optional(7, "123456789012"), Description: optional(8, cidr)}},
+		}
+		rules := convertIpPermissions([]types.IpPermission{permission})
+		if len(rules) != 1 {
+			t.Fatalf("Expected one rule, got %d", len(rules))
+		}
+
+		rule := convertSecurityGroupRule(types.SecurityGroupRule{
+			SecurityGroupRuleId: optional(9, "sgr-1"),
+			IpProtocol:          optional(0, protocol),
+			FromPort:            optionalPort(1),
+			ToPort:              optionalPort(2),
+			CidrIpv4:            optional(3, cidr),
+			CidrIpv6:            optional(4, cidr),
+			PrefixListId:        optional(5, "pl-1"),
+			Description:         optional(8, cidr),
+			ReferencedGroupInfo: &types.ReferencedSecurityGroup{GroupId: optional(6, "sg-1"), VpcId: optional(10, "vpc-1")},
+		})
+		if present&(1<<3) != 0 && (len(rule.CidrBlocks) != 1 || rule.CidrBlocks[0] != cidr) {
+			t.Errorf("Expected CIDR block %q, got %v", cidr, rule.CidrBlocks)
+		}
+
+		flowLog := convertFlowLog(types.FlowLog{FlowLogId: optional(11, "fl-1"), ResourceId: optional(12, cidr), LogDestination: optional(13, cidr)})
+		if present&(1<<12) == 0 && flowLog.ResourceID != "" {
+			t.Errorf("Expected no resource ID, got %q", flowLog.ResourceID)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// WriteJSON writes the network to w as json.MarshalIndent does with two-space
+// indentation, but encodes its resources one at a time, so exporting accounts with
+// tens of thousands of resources does not hold the whole document in memory
+func (n *Network) WriteJSON(w io.Writer) error {
+	stored := n.stored()
+	out := bufio.NewWriter(w)
+	first := true
+
+	// Fields are written in the order encoding/json writes them: the network's own
+	// fields, then the IAM roles and policies storedNetwork replaces them with
+	fieldsType := reflect.TypeOf(stored.networkFields)
+	fields := reflect.ValueOf(stored.networkFields)
+	for i := 0; i < fieldsType.NumField(); i++ {
+		if fieldsType.Field(i).Name == "IAMRoles" {
+			continue
+		}
+		name, omitEmpty := jsonField(fieldsType.Field(i))
+		if err := writeJSONField(out, &first, name, fields.Field(i), omitEmpty); err != nil {
+			return err
+		}
+	}
+	if err := writeJSONField(out, &first, "iam_roles", reflect.ValueOf(stored.IAMRoles), false); err != nil {
+		return err
+	}
+	if err := writeJSONField(out, &first, "policies", reflect.ValueOf(stored.Policies), true); err != nil {
+		return err
+	}
+
+	if first {
+		out.WriteString("{}")
+	} else {
+		out.WriteString("\n}")
+	}
+	return out.Flush()
+}
+
+// jsonField returns the key a struct field is encoded with and whether it is left out when empty
+func jsonField(field reflect.StructField) (string, bool) {
+	name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(","+options+",", ",omitempty,")
+}
+
+// writeJSONField writes one field of the network, encoding the elements of lists one at a time
+func writeJSONField(out *bufio.Writer, first *bool, name string, value reflect.Value, omitEmpty bool) error {
+	if omitEmpty && isEmptyJSONValue(value) {
+		return nil
+	}
+	if *first {
+		out.WriteString("{")
+		*first = false
+	} else {
+		out.WriteString(",")
+	}
+	key, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+	out.WriteString("\n  ")
+	out.Write(key)
+	out.WriteString(": ")
+
+	if value.Kind() != reflect.Slice || value.IsNil() || value.Len() == 0 {
+		data, err := json.MarshalIndent(value.Interface(), "  ", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	}
+
+	out.WriteString("[")
+	for i := 0; i < value.Len(); i++ {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		data, err := json.MarshalIndent(value.Index(i).Interface(), "    ", "  ")
+		if err != nil {
+			return err
+		}
+		out.WriteString("\n    ")
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+	_, err = out.WriteString("\n  ]")
+	return err
+}
+
+// isEmptyJSONValue reports whether encoding/json leaves out a field with omitempty
+func isEmptyJSONValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return value.IsNil()
+	}
+	return false
+}
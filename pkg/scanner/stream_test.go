@@ -0,0 +1,40 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner/scannertest"
+)
+
+func TestWriteJSONMatchesMarshalIndent(t *testing.T) {
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: newFakeEC2(), STS: &scannertest.FakeSTS{}})
+	scanned, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ScanNetwork failed: %v", err)
+	}
+	scanned.IAMRoles = []IAMRole{
+		{Name: "role-a", AttachedPolicies: []IAMPolicy{{Arn: "arn:aws:iam::aws:policy/ReadOnlyAccess", PolicyDocument: `{"Statement":"<all>"}`}}},
+		{Name: "role-b"},
+	}
+
+	for name, network := range map[string]*Network{
+		"scanned": scanned,
+		"empty":   {},
+		"sparse":  {VPCs: []VPC{}, Region: "us-east-1", Incomplete: []string{"subnets"}},
+	} {
+		expected, err := json.MarshalIndent(network, "", "  ")
+		if err != nil {
+			t.Fatalf("MarshalIndent failed: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := network.WriteJSON(&buf); err != nil {
+			t.Fatalf("WriteJSON failed: %v", err)
+		}
+		if buf.String() != string(expected) {
+			t.Errorf("Expected the %s network written like MarshalIndent\nexpected: %s\ngot: %s", name, expected, buf.String())
+		}
+	}
+}
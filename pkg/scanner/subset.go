@@ -0,0 +1,128 @@
+package scanner
+
+// ForVPCs returns the part of the network a scan limited to the given VPCs would
+// have found: resources and flow logs in those VPCs, the peering connections, DHCP option sets
+// and edge targets that involve them, and the account-wide transit gateways,
+// endpoint services, App Mesh gateways and IAM roles
+func (n *Network) ForVPCs(vpcIDs []string) *Network {
+	in := make(map[string]bool, len(vpcIDs))
+	for _, id := range vpcIDs {
+		in[id] = true
+	}
+
+	subset := &Network{
+		TransitGateways:     n.TransitGateways,
+		EndpointServices:    n.EndpointServices,
+		MeshVirtualGateways: n.MeshVirtualGateways,
+		IAMRoles:            n.IAMRoles,
+		SchemaVersion:       n.SchemaVersion,
+		ScanTime:            n.ScanTime,
+		Region:              n.Region,
+		AccountID:           n.AccountID,
+	}
+
+	dhcpOptions := make(map[string]bool)
+	for _, vpc := range n.VPCs {
+		if in[vpc.ID] {
+			subset.VPCs = append(subset.VPCs, vpc)
+			dhcpOptions[vpc.DhcpOptionsID] = true
+		}
+	}
+	for _, options := range n.DhcpOptions {
+		if dhcpOptions[options.ID] {
+			subset.DhcpOptions = append(subset.DhcpOptions, options)
+		}
+	}
+	for _, subnet := range n.Subnets {
+		if in[subnet.VpcID] {
+			subset.Subnets = append(subset.Subnets, subnet)
+		}
+	}
+	for _, peering := range n.PeeringConnections {
+		if in[peering.RequesterVpcID] || in[peering.AccepterVpcID] {
+			subset.PeeringConnections = append(subset.PeeringConnections, peering)
+		}
+	}
+	for _, igw := range n.InternetGateways {
+		if in[igw.VpcID] {
+			subset.InternetGateways = append(subset.InternetGateways, igw)
+		}
+	}
+	for _, eigw := range n.EgressOnlyInternetGateways {
+		if in[eigw.VpcID] {
+			subset.EgressOnlyInternetGateways = append(subset.EgressOnlyInternetGateways, eigw)
+		}
+	}
+	for _, nat := range n.NATGateways {
+		if in[nat.VpcID] {
+			subset.NATGateways = append(subset.NATGateways, nat)
+		}
+	}
+	for _, routeTable := range n.RouteTables {
+		if in[routeTable.VpcID] {
+			subset.RouteTables = append(subset.RouteTables, routeTable)
+		}
+	}
+	for _, sg := range n.SecurityGroups {
+		if in[sg.VpcID] {
+			subset.SecurityGroups = append(subset.SecurityGroups, sg)
+		}
+	}
+	for _, nacl := range n.NetworkAcls {
+		if in[nacl.VpcID] {
+			subset.NetworkAcls = append(subset.NetworkAcls, nacl)
+		}
+	}
+	if n.FlowLogs != nil {
+		subset.FlowLogs = []FlowLog{}
+		for _, flowLog := range n.FlowLogs {
+			if in[flowLog.VpcID] {
+				subset.FlowLogs = append(subset.FlowLogs, flowLog)
+			}
+		}
+	}
+	for _, endpoint := range n.VpcEndpoints {
+		if in[endpoint.VpcID] {
+			subset.VpcEndpoints = append(subset.VpcEndpoints, endpoint)
+		}
+	}
+	for _, eni := range n.NetworkInterfaces {
+		if in[eni.VpcID] {
+			subset.NetworkInterfaces = append(subset.NetworkInterfaces, eni)
+		}
+	}
+	for _, cluster := range n.EKSClusters {
+		if in[cluster.VpcID] {
+			subset.EKSClusters = append(subset.EKSClusters, cluster)
+		}
+	}
+	for _, service := range n.ECSServices {
+		if in[service.VpcID] {
+			subset.ECSServices = append(subset.ECSServices, service)
+		}
+	}
+	for _, group := range n.DatabaseSubnetGroups {
+		if in[group.VpcID] {
+			subset.DatabaseSubnetGroups = append(subset.DatabaseSubnetGroups, group)
+		}
+	}
+	for _, database := range n.Databases {
+		if in[database.VpcID] {
+			subset.Databases = append(subset.Databases, database)
+		}
+	}
+	for _, ingress := range n.EdgeIngresses {
+		var targets []EdgeTarget
+		for _, target := range ingress.Targets {
+			if in[target.VpcID] {
+				targets = append(targets, target)
+			}
+		}
+		if len(targets) > 0 {
+			ingress.Targets = targets
+			subset.EdgeIngresses = append(subset.EdgeIngresses, ingress)
+		}
+	}
+
+	return subset
+}
@@ -0,0 +1,56 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// partialScan records what a scan has scanned so far, so a scan that runs out of
+// time can return it
+type partialScan struct {
+	network *Network
+	steps   []string        // Resource types the scan fetches
+	scanned map[string]bool // Resource types finished before the timeout
+}
+
+// SetTimeout limits each scan to timeout. A scan that runs out of time returns the
+// resources scanned by then, with the resource types it did not finish listed in
+// Network.Incomplete. Zero never times out.
+func (s *NetworkScanner) SetTimeout(timeout time.Duration) {
+	s.timeout = timeout
+}
+
+// withTimeout runs a scan under the scan timeout, returning its partial results
+// with a warning when the timeout is reached
+func (s *NetworkScanner) withTimeout(ctx context.Context, scan func(context.Context, *partialScan) (*Network, error)) (*Network, error) {
+	partial := &partialScan{scanned: make(map[string]bool)}
+	if s.timeout <= 0 {
+		return scan(ctx, partial)
+	}
+
+	scanCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	network, err := scan(scanCtx, partial)
+	// Only this scan's own deadline returns partial results, not a cancelled parent
+	if err == nil || ctx.Err() != nil || !errors.Is(scanCtx.Err(), context.DeadlineExceeded) {
+		return network, err
+	}
+	if partial.network == nil || !partial.scanned["vpc"] {
+		return nil, fmt.Errorf("scan timed out after %s before VPCs were scanned: %w", s.timeout, err)
+	}
+
+	network = partial.network
+	for _, step := range partial.steps {
+		if !partial.scanned[step] {
+			network.Incomplete = append(network.Incomplete, step)
+		}
+	}
+	s.finishNetwork(network)
+	if len(network.Incomplete) > 0 {
+		s.log().Warn("scan timed out, results are incomplete", "timeout", s.timeout, "incomplete", network.Incomplete)
+	}
+	return network, nil
+}
@@ -0,0 +1,48 @@
+package scanner
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner/scannertest"
+)
+
+// hangingEC2 never answers DescribeRouteTables, like an API call that hangs
+type hangingEC2 struct {
+	*scannertest.FakeEC2
+}
+
+func (h hangingEC2) DescribeRouteTables(ctx context.Context, params *ec2.DescribeRouteTablesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRouteTablesOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestScanTimeout(t *testing.T) {
+	s := NewNetworkScannerFromAPIs("us-east-1", APIs{EC2: hangingEC2{newFakeEC2()}, STS: &scannertest.FakeSTS{}})
+	s.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.SetTimeout(50 * time.Millisecond)
+
+	network, err := s.ScanNetwork(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Expected partial results, got %v", err)
+	}
+	if len(network.VPCs) == 0 || len(network.Subnets) == 0 || len(network.InternetGateways) == 0 {
+		t.Errorf("Expected the resources scanned before the timeout, got %+v", network)
+	}
+	expected := []string{"route_table", "security_group", "network_acl", "flow_log", "endpoint_service", "vpc_endpoint", "network_interface"}
+	if !reflect.DeepEqual(network.Incomplete, expected) {
+		t.Errorf("Expected incomplete %v, got %v", expected, network.Incomplete)
+	}
+
+	// Cancelling the scan is not a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.ScanNetwork(ctx, ""); err == nil {
+		t.Error("Expected a cancelled scan to fail")
+	}
+}
@@ -0,0 +1,139 @@
+package scanner
+
+import (
+	"context"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// scanVpcEndpoints scans the PrivateLink VPC endpoints in the VPCs with the
+// addresses of their network interfaces. Gateway endpoints have no network
+// interfaces and are left out.
+func (s *NetworkScanner) scanVpcEndpoints(ctx context.Context, vpcIDs []string) ([]VpcEndpoint, error) {
+	if len(vpcIDs) == 0 {
+		return []VpcEndpoint{}, nil
+	}
+
+	var endpoints []VpcEndpoint
+	var interfaceIDs []string
+	pages := ec2.NewDescribeVpcEndpointsPaginator(s.apis.EC2, &ec2.DescribeVpcEndpointsInput{
+		Filters: []types.Filter{
+			{
+				Name:   &[]string{"vpc-id"}[0],
+				Values: vpcIDs,
+			},
+		},
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, vpce := range page.VpcEndpoints {
+			if vpce.VpcEndpointType == types.VpcEndpointTypeGateway {
+				continue
+			}
+			endpoints = append(endpoints, convertVpcEndpoint(vpce))
+			interfaceIDs = append(interfaceIDs, vpce.NetworkInterfaceIds...)
+		}
+	}
+
+	interfaces, err := s.endpointInterfaces(ctx, interfaceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range endpoints {
+		for j := range endpoints[i].NetworkInterfaces {
+			if eni, exists := interfaces[endpoints[i].NetworkInterfaces[j].ID]; exists {
+				endpoints[i].NetworkInterfaces[j] = eni
+			}
+		}
+	}
+
+	return endpoints, nil
+}
+
+// endpointInterfaces looks up the subnets and addresses of VPC endpoint network
+// interfaces. Deleted interfaces are left out.
+func (s *NetworkScanner) endpointInterfaces(ctx context.Context, ids []string) (map[string]EndpointNetworkInterface, error) {
+	interfaces := make(map[string]EndpointNetworkInterface)
+
+	for start := 0; start < len(ids); start += interfaceBatchSize {
+		end := start + interfaceBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		// A filter, unlike NetworkInterfaceIds, does not fail on deleted interfaces
+		pages := ec2.NewDescribeNetworkInterfacesPaginator(s.apis.EC2, &ec2.DescribeNetworkInterfacesInput{
+			Filters: []types.Filter{
+				{
+					Name:   &[]string{"network-interface-id"}[0],
+					Values: ids[start:end],
+				},
+			},
+		})
+		for pages.HasMorePages() {
+			page, err := pages.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, eni := range page.NetworkInterfaces {
+				e := EndpointNetworkInterface{
+					ID:        awssdk.ToString(eni.NetworkInterfaceId),
+					SubnetID:  awssdk.ToString(eni.SubnetId),
+					PrivateIP: awssdk.ToString(eni.PrivateIpAddress),
+				}
+				for _, address := range eni.Ipv6Addresses {
+					if address.Ipv6Address != nil {
+						e.Ipv6Addresses = append(e.Ipv6Addresses, *address.Ipv6Address)
+					}
+				}
+				interfaces[e.ID] = e
+			}
+		}
+	}
+
+	return interfaces, nil
+}
+
+// convertVpcEndpoint converts a VPC endpoint. Its network interfaces only have
+// their IDs until they are looked up.
+func convertVpcEndpoint(vpce types.VpcEndpoint) VpcEndpoint {
+	endpoint := VpcEndpoint{
+		ID:                awssdk.ToString(vpce.VpcEndpointId),
+		VpcID:             awssdk.ToString(vpce.VpcId),
+		ServiceName:       awssdk.ToString(vpce.ServiceName),
+		Type:              string(vpce.VpcEndpointType),
+		State:             string(vpce.State),
+		PrivateDNSEnabled: awssdk.ToBool(vpce.PrivateDnsEnabled),
+		SubnetIDs:         vpce.SubnetIds,
+		Tags:              convertTags(vpce.Tags),
+	}
+	for _, group := range vpce.Groups {
+		if group.GroupId != nil {
+			endpoint.SecurityGroupIDs = append(endpoint.SecurityGroupIDs, *group.GroupId)
+		}
+	}
+	for _, id := range vpce.NetworkInterfaceIds {
+		endpoint.NetworkInterfaces = append(endpoint.NetworkInterfaces, EndpointNetworkInterface{ID: id})
+	}
+	for _, entry := range vpce.DnsEntries {
+		if entry.DnsName != nil {
+			endpoint.DNSEntries = append(endpoint.DNSEntries, EndpointDNSEntry{
+				DNSName:      *entry.DnsName,
+				HostedZoneID: awssdk.ToString(entry.HostedZoneId),
+			})
+		}
+	}
+
+	// Get name from tags
+	if name, ok := endpoint.Tags["Name"]; ok {
+		endpoint.Name = name
+	}
+
+	return endpoint
+}
@@ -0,0 +1,205 @@
+// Package schedule parses cron schedules and finds the times they fire.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// descriptors are the named schedules accepted in place of five fields
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field is the range of values one cron field accepts
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Schedule is a parsed cron schedule
+type Schedule struct {
+	spec string
+	// every is the interval of an @every schedule, zero for cron fields
+	every time.Duration
+
+	minutes, hours, days, months, weekdays map[int]bool
+	// anyDay and anyWeekday record unrestricted day fields, since cron fires on
+	// either day field matching when both are restricted
+	anyDay, anyWeekday bool
+}
+
+// Parse parses a standard five-field cron expression (minute hour day-of-month
+// month day-of-week) with *, lists, ranges and steps, a descriptor such as
+// @hourly or @daily, or "@every <duration>". Times are in the local time zone.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if interval, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || every < time.Minute {
+			return nil, fmt.Errorf("invalid schedule %q, @every needs a duration of at least 1m", spec)
+		}
+		return &Schedule{spec: spec, every: every}, nil
+	}
+
+	expression := spec
+	if descriptor, ok := descriptors[spec]; ok {
+		expression = descriptor
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("invalid schedule %q, expected 5 fields (minute hour day-of-month month day-of-week)", spec)
+	}
+
+	values := make([]map[int]bool, len(fields))
+	for i, part := range parts {
+		parsed, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		values[i] = parsed
+	}
+
+	// Sunday may be written as 7
+	if values[4][7] {
+		values[4][0] = true
+	}
+
+	return &Schedule{
+		spec:       spec,
+		minutes:    values[0],
+		hours:      values[1],
+		days:       values[2],
+		months:     values[3],
+		weekdays:   values[4],
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+	}, nil
+}
+
+// parseField parses a comma-separated list of *, n, a-b and either with a /step
+func parseField(value string, f field) (map[int]bool, error) {
+	max := f.max
+	if f.name == "day of week" {
+		max = 7
+	}
+
+	result := make(map[int]bool)
+	for _, item := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step %q in %s field", stepPart, f.name)
+			}
+			step = n
+		}
+
+		low, high := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid value %q in %s field", from, f.name)
+			}
+			if high, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid value %q in %s field", to, f.name)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q in %s field", rangePart, f.name)
+			}
+			low, high = n, n
+			// A single value with a step runs to the end of the range
+			if hasStep {
+				high = f.max
+			}
+		}
+
+		if low < f.min || high > max || low > high {
+			return nil, fmt.Errorf("%s field value %q is outside %d-%d", f.name, rangePart, f.min, f.max)
+		}
+		for n := low; n <= high; n += step {
+			result[n] = true
+		}
+	}
+	return result, nil
+}
+
+// String returns the schedule as it was written
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first time after the given time the schedule fires, or the
+// zero time when it never does (such as on February 30th)
+func (s *Schedule) Next(after time.Time) time.Time {
+	if s.every > 0 {
+		return after.Truncate(time.Second).Add(s.every)
+	}
+
+	// Start at the next whole minute
+	t := after.Truncate(time.Minute).Add(time.Minute)
+
+	// Every combination of fields repeats within a few years
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the schedule fires on the day of t. When both day
+// fields are restricted either may match, as in standard cron.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day := s.days[t.Day()]
+	weekday := s.weekdays[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
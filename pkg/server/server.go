@@ -0,0 +1,375 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/reach"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+// maxJobs is the number of scan jobs kept in memory. The oldest finished jobs are dropped first.
+const maxJobs = 100
+
+// ScanFunc scans the network, optionally limited to one VPC
+type ScanFunc func(ctx context.Context, vpcID string) (*scanner.Network, error)
+
+// JobStatus is the state of a background scan job
+type JobStatus string
+
+const (
+	// JobRunning means the scan is in progress
+	JobRunning JobStatus = "running"
+	// JobSucceeded means the scan finished and its result is available
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed means the scan returned an error
+	JobFailed JobStatus = "failed"
+)
+
+// Job is a background scan
+type Job struct {
+	ID         string           `json:"id"`
+	VpcID      string           `json:"vpc_id,omitempty"`
+	Status     JobStatus        `json:"status"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Result     *scanner.Network `json:"result,omitempty"`
+}
+
+// cachedScan is a scan result reused until it is older than the cache TTL
+type cachedScan struct {
+	network   *scanner.Network
+	scannedAt time.Time
+}
+
+// Server exposes scan, diff and reachability operations over an HTTP/JSON API
+type Server struct {
+	scan     ScanFunc
+	cacheTTL time.Duration
+	verbose  bool
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	nextID int
+	cache  map[string]cachedScan
+
+	// jobCtx outlives individual requests so background scans are not cancelled
+	// when the request that started them completes
+	jobCtx context.Context
+	wg     sync.WaitGroup
+}
+
+// NewServer creates an API server. Scan results are cached per VPC filter for cacheTTL.
+func NewServer(ctx context.Context, scan ScanFunc, cacheTTL time.Duration) *Server {
+	return &Server{
+		scan:     scan,
+		cacheTTL: cacheTTL,
+		jobs:     make(map[string]*Job),
+		cache:    make(map[string]cachedScan),
+		jobCtx:   ctx,
+	}
+}
+
+// SetVerbose enables request logging
+func (s *Server) SetVerbose(verbose bool) {
+	s.verbose = verbose
+}
+
+// Handler returns the HTTP handler for the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /api/v1/network", s.handleNetwork)
+	mux.HandleFunc("POST /api/v1/scans", s.handleStartScan)
+	mux.HandleFunc("GET /api/v1/scans", s.handleListScans)
+	mux.HandleFunc("GET /api/v1/scans/{id}", s.handleGetScan)
+	mux.HandleFunc("POST /api/v1/diff", s.handleDiff)
+	mux.HandleFunc("POST /api/v1/reach", s.handleReach)
+
+	if !s.verbose {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		mux.ServeHTTP(w, r)
+		slog.Info("handled request", "method", r.Method, "path", r.URL.Path, "duration", time.Since(start))
+	})
+}
+
+// Wait blocks until all background scan jobs have finished
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// apiError is the body of an error response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{Error: err.Error()})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// cachedNetwork returns a cached scan for the VPC filter, scanning when the cache is stale
+func (s *Server) cachedNetwork(ctx context.Context, vpcID string, refresh bool) (*scanner.Network, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[vpcID]
+	s.mu.Unlock()
+
+	if ok && !refresh && time.Since(cached.scannedAt) < s.cacheTTL {
+		return cached.network, nil
+	}
+
+	network, err := s.scan(ctx, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.storeCache(vpcID, network)
+	return network, nil
+}
+
+func (s *Server) storeCache(vpcID string, network *scanner.Network) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache[vpcID] = cachedScan{network: network, scannedAt: time.Now()}
+}
+
+// handleNetwork returns the current network, from the cache when fresh enough.
+// Pass refresh=true to force a new scan.
+func (s *Server) handleNetwork(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	network, err := s.cachedNetwork(r.Context(), query.Get("vpc_id"), query.Get("refresh") == "true")
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to scan network: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, network)
+}
+
+// scanRequest is the body of a scan job request
+type scanRequest struct {
+	VpcID string `json:"vpc_id"`
+}
+
+// handleStartScan starts a background scan job and returns it immediately
+func (s *Server) handleStartScan(w http.ResponseWriter, r *http.Request) {
+	var request scanRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid scan request: %w", err))
+			return
+		}
+	}
+
+	job := s.startJob(request.VpcID)
+
+	w.Header().Set("Location", "/api/v1/scans/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) startJob(vpcID string) Job {
+	s.mu.Lock()
+	s.nextID++
+	job := &Job{
+		ID:        fmt.Sprintf("scan-%d", s.nextID),
+		VpcID:     vpcID,
+		Status:    JobRunning,
+		StartedAt: time.Now(),
+	}
+	s.jobs[job.ID] = job
+	s.pruneJobs()
+	snapshot := *job
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		network, err := s.scan(s.jobCtx, vpcID)
+		if err == nil {
+			s.storeCache(vpcID, network)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		finished := time.Now()
+		job.FinishedAt = &finished
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = JobSucceeded
+		job.Result = network
+	}()
+
+	return snapshot
+}
+
+// pruneJobs drops the oldest finished jobs beyond maxJobs. The caller must hold s.mu.
+func (s *Server) pruneJobs() {
+	if len(s.jobs) <= maxJobs {
+		return
+	}
+
+	var finished []*Job
+	for _, job := range s.jobs {
+		if job.Status != JobRunning {
+			finished = append(finished, job)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].StartedAt.Before(finished[j].StartedAt) })
+
+	for _, job := range finished {
+		if len(s.jobs) <= maxJobs {
+			return
+		}
+		delete(s.jobs, job.ID)
+	}
+}
+
+// handleListScans lists scan jobs without their results
+func (s *Server) handleListScans(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		summary := *job
+		summary.Result = nil
+		jobs = append(jobs, summary)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.Before(jobs[j].StartedAt) })
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// handleGetScan returns a scan job including its result once finished
+func (s *Server) handleGetScan(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	var snapshot Job
+	if ok {
+		snapshot = *job
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("scan job %s not found", r.PathValue("id")))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// handleDiff compares the current network against a baseline working state sent as the request body
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	var baseline scanner.Network
+	if err := json.NewDecoder(r.Body).Decode(&baseline); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid baseline working state: %w", err))
+		return
+	}
+
+	query := r.URL.Query()
+	current, err := s.cachedNetwork(r.Context(), query.Get("vpc_id"), query.Get("refresh") == "true")
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to scan network: %w", err))
+		return
+	}
+
+	differences := watch.NewComparator(false).Compare(&baseline, current)
+	writeJSON(w, http.StatusOK, watch.NewDiffReport(differences))
+}
+
+// reachRequest is the body of a reachability request
+type reachRequest struct {
+	Target   string   `json:"target"`
+	Port     int32    `json:"port"`
+	Protocol string   `json:"protocol"`
+	Subnets  []string `json:"subnets"`
+	VpcID    string   `json:"vpc_id"`
+}
+
+// handleReach lists the sources that can reach a port on a target in the current network,
+// the same analysis as the blast-radius command
+func (s *Server) handleReach(w http.ResponseWriter, r *http.Request) {
+	var request reachRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid reach request: %w", err))
+		return
+	}
+	if request.Target == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid reach request: target is required"))
+		return
+	}
+	if request.Port < 1 || request.Port > 65535 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid reach request: port must be between 1 and 65535"))
+		return
+	}
+	if len(request.Subnets) > 0 && !strings.HasPrefix(request.Target, "sg-") {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid reach request: subnets can only be used with a security group target"))
+		return
+	}
+	if request.Protocol == "" {
+		request.Protocol = "tcp"
+	}
+
+	network, err := s.cachedNetwork(r.Context(), request.VpcID, r.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to scan network: %w", err))
+		return
+	}
+
+	target, err := reachTarget(network, request)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	report, err := reach.Analyze(network, target, request.Protocol, request.Port)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid reach request: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// reachTarget resolves the target from the scanned network. Unlike the blast-radius
+// command it does not look up instances and network interfaces the scan does not have.
+func reachTarget(network *scanner.Network, request reachRequest) (reach.Target, error) {
+	if strings.HasPrefix(request.Target, "sg-") {
+		return reach.SecurityGroupTarget(network, request.Target, request.Subnets)
+	}
+	if strings.HasPrefix(request.Target, "vpce-") || net.ParseIP(request.Target) != nil {
+		return reach.VpcEndpointTarget(network, request.Target)
+	}
+	if target, ok := reach.NetworkInterfaceTarget(network, request.Target); ok {
+		return target, nil
+	}
+	return reach.Target{}, fmt.Errorf("%s is not in the scanned network", request.Target)
+}
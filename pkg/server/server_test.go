@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/reach"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+func newTestServer(t *testing.T, scan ScanFunc) (*Server, *httptest.Server) {
+	s := NewServer(context.Background(), scan, time.Minute)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func TestNetworkIsCached(t *testing.T) {
+	var scans int32
+	_, ts := newTestServer(t, func(ctx context.Context, vpcID string) (*scanner.Network, error) {
+		atomic.AddInt32(&scans, 1)
+		return &scanner.Network{Region: "us-east-1", VPCs: []scanner.VPC{{ID: "vpc-1"}}}, nil
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(ts.URL + "/api/v1/network")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.StatusCode)
+		}
+	}
+
+	if scans != 1 {
+		t.Errorf("Expected 1 scan with caching, got %d", scans)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/v1/network?refresh=true")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if scans != 2 {
+		t.Errorf("Expected refresh to rescan, got %d scans", scans)
+	}
+}
+
+func TestScanJob(t *testing.T) {
+	s, ts := newTestServer(t, func(ctx context.Context, vpcID string) (*scanner.Network, error) {
+		if vpcID == "vpc-missing" {
+			return nil, errors.New("vpc not found")
+		}
+		return &scanner.Network{Region: "us-east-1"}, nil
+	})
+
+	jobs := make(map[string]Job)
+	for _, body := range []string{`{"vpc_id": "vpc-1"}`, `{"vpc_id": "vpc-missing"}`} {
+		resp, err := http.Post(ts.URL+"/api/v1/scans", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var job Job
+		json.NewDecoder(resp.Body).Decode(&job)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusAccepted || job.Status != JobRunning {
+			t.Fatalf("Expected an accepted running job, got %d %+v", resp.StatusCode, job)
+		}
+		jobs[job.VpcID] = job
+	}
+
+	s.Wait()
+
+	for vpcID, expected := range map[string]JobStatus{"vpc-1": JobSucceeded, "vpc-missing": JobFailed} {
+		resp, err := http.Get(ts.URL + "/api/v1/scans/" + jobs[vpcID].ID)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var job Job
+		json.NewDecoder(resp.Body).Decode(&job)
+		resp.Body.Close()
+
+		if job.Status != expected {
+			t.Errorf("Expected job for %s to be %s, got %s", vpcID, expected, job.Status)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/api/v1/scans/scan-999")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown job, got %d", resp.StatusCode)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	_, ts := newTestServer(t, func(ctx context.Context, vpcID string) (*scanner.Network, error) {
+		return &scanner.Network{VPCs: []scanner.VPC{{ID: "vpc-1"}, {ID: "vpc-2"}}}, nil
+	})
+
+	baseline := `{"vpcs": [{"id": "vpc-1"}]}`
+	resp, err := http.Post(ts.URL+"/api/v1/diff", "application/json", strings.NewReader(baseline))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var report watch.DiffReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode diff report: %v", err)
+	}
+
+	if report.Summary.Added != 1 || report.Differences[0].ResourceID != "vpc-2" {
+		t.Errorf("Expected vpc-2 to be added, got %+v", report)
+	}
+}
+
+func TestReach(t *testing.T) {
+	_, ts := newTestServer(t, func(ctx context.Context, vpcID string) (*scanner.Network, error) {
+		return &scanner.Network{
+			VPCs:    []scanner.VPC{{ID: "vpc-1", CidrBlock: "10.0.0.0/16"}},
+			Subnets: []scanner.Subnet{{ID: "subnet-1", VpcID: "vpc-1", CidrBlock: "10.0.1.0/24"}},
+			SecurityGroups: []scanner.SecurityGroup{
+				{ID: "sg-web", VpcID: "vpc-1", IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"10.0.0.0/16"}},
+				}},
+			},
+		}, nil
+	})
+
+	resp, err := http.Post(ts.URL+"/api/v1/reach", "application/json", strings.NewReader(`{"target": "sg-web", "port": 443}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	var report reach.Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode reach report: %v", err)
+	}
+	if report.Target != "sg-web" || report.Protocol != "tcp" || len(report.Paths) != 1 || report.Paths[0].Source != "10.0.0.0/16" || report.Paths[0].SubnetID != "subnet-1" {
+		t.Errorf("Expected 10.0.0.0/16 to reach sg-web in subnet-1, got %+v", report)
+	}
+
+	for body, status := range map[string]int{
+		`{"target": "sg-web"}`: http.StatusBadRequest,
+		`{"target": "sg-web", "port": 443, "protocol": "icmp"}`:   http.StatusBadRequest,
+		`{"target": "i-1", "port": 443, "subnets": ["subnet-1"]}`: http.StatusBadRequest,
+		`{"target": "sg-missing", "port": 443}`:                   http.StatusNotFound,
+		`{"target": "i-missing", "port": 443}`:                    http.StatusNotFound,
+	} {
+		resp, err := http.Post(ts.URL+"/api/v1/reach", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected %d, got %d", body, status, resp.StatusCode)
+		}
+	}
+}
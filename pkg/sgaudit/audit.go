@@ -0,0 +1,212 @@
+// Package sgaudit labels security group rules as used or unused from observed traffic.
+package sgaudit
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/flows"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// Rule statuses
+const (
+	StatusUsed   = "used"
+	StatusUnused = "unused"
+	// StatusUnverified marks rules traffic cannot be matched against, such as prefix list rules
+	StatusUnverified = "unverified"
+)
+
+// Notes explaining a status
+const (
+	NoteOpenToInternet = "open to the internet"
+	NoteIdleGroup      = "no traffic observed on any interface in the group"
+	NotePrefixList     = "prefix list sources are not matched against traffic"
+)
+
+// Finding is the usage of one security group rule over the audit period
+type Finding struct {
+	GroupID   string     `json:"group_id"`
+	GroupName string     `json:"group_name"`
+	VpcID     string     `json:"vpc_id"`
+	Direction string     `json:"direction"`
+	Rule      string     `json:"rule"`
+	Status    string     `json:"status"`
+	Flows     int        `json:"flows"`
+	Bytes     int64      `json:"bytes"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
+	Notes     []string   `json:"notes,omitempty"`
+}
+
+// Report is the result of a security group rule usage audit
+type Report struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Used       int       `json:"used"`
+	Unused     int       `json:"unused"`
+	Unverified int       `json:"unverified"`
+	Rules      []Finding `json:"rules"`
+}
+
+// Analyze labels every security group rule in the network as used, unused or
+// unverified from the rule usage of a flow log report
+func Analyze(network *scanner.Network, traffic flows.Report) Report {
+	report := Report{Start: traffic.Start, End: traffic.End}
+
+	usage := make(map[string]flows.RuleUsage)
+	for _, rule := range traffic.Rules {
+		usage[rule.GroupID+"|"+rule.Direction+"|"+rule.Rule] = rule
+	}
+
+	active := make(map[string]bool)
+	for _, talker := range traffic.SecurityGroups {
+		active[talker.ID] = true
+	}
+
+	audit := func(sg scanner.SecurityGroup, direction string, rule scanner.SecurityGroupRule) {
+		label := flows.RuleLabel(rule)
+		finding := Finding{
+			GroupID:   sg.ID,
+			GroupName: sg.Name,
+			VpcID:     sg.VpcID,
+			Direction: direction,
+			Rule:      label,
+		}
+
+		observed := usage[sg.ID+"|"+direction+"|"+label]
+		finding.Flows = observed.Flows
+		finding.Bytes = observed.Bytes
+		finding.LastSeen = observed.LastSeen
+
+		switch {
+		case observed.Flows > 0:
+			finding.Status = StatusUsed
+			report.Used++
+		case len(rule.PrefixListIds) > 0 && len(rule.CidrBlocks) == 0 && len(rule.Ipv6CidrBlocks) == 0 && len(rule.ReferencedGroups) == 0:
+			finding.Status = StatusUnverified
+			finding.Notes = append(finding.Notes, NotePrefixList)
+			report.Unverified++
+		default:
+			finding.Status = StatusUnused
+			if !active[sg.ID] {
+				finding.Notes = append(finding.Notes, NoteIdleGroup)
+			}
+			report.Unused++
+		}
+
+		if direction == flows.DirectionIngress && openToInternet(rule) {
+			finding.Notes = append(finding.Notes, NoteOpenToInternet)
+		}
+
+		report.Rules = append(report.Rules, finding)
+	}
+
+	for _, sg := range network.SecurityGroups {
+		for _, rule := range sg.IngressRules {
+			audit(sg, flows.DirectionIngress, rule)
+		}
+		for _, rule := range sg.EgressRules {
+			audit(sg, flows.DirectionEgress, rule)
+		}
+	}
+
+	return report
+}
+
+// openToInternet reports whether a rule allows every IPv4 or IPv6 address
+func openToInternet(rule scanner.SecurityGroupRule) bool {
+	for _, block := range rule.CidrBlocks {
+		if block == "0.0.0.0/0" {
+			return true
+		}
+	}
+	for _, block := range rule.Ipv6CidrBlocks {
+		if block == "::/0" {
+			return true
+		}
+	}
+	return false
+}
+
+// UnusedRules returns the rules no traffic was attributed to, the candidates for tightening
+func (r Report) UnusedRules() []Finding {
+	var unused []Finding
+	for _, finding := range r.Rules {
+		if finding.Status == StatusUnused {
+			unused = append(unused, finding)
+		}
+	}
+	return unused
+}
+
+// Text renders the report as a table
+func (r Report) Text() string {
+	var result strings.Builder
+
+	result.WriteString(fmt.Sprintf("Security Group Rule Usage %s - %s\n\n", r.Start.Format("2006-01-02 15:04:05"), r.End.Format("2006-01-02 15:04:05")))
+	result.WriteString(fmt.Sprintf("%-22s %-8s %-40s %-11s %8s %10s  %s\n", "GROUP", "DIRECTION", "RULE", "STATUS", "FLOWS", "BYTES", "LAST SEEN"))
+
+	for _, finding := range r.Rules {
+		lastSeen := "-"
+		if finding.LastSeen != nil {
+			lastSeen = finding.LastSeen.Format("2006-01-02 15:04:05")
+		}
+		result.WriteString(fmt.Sprintf("%-22s %-8s %-40s %-11s %8d %10s  %s\n",
+			finding.GroupID, finding.Direction, finding.Rule, finding.Status, finding.Flows, flows.FormatBytes(finding.Bytes), lastSeen))
+		for _, note := range finding.Notes {
+			result.WriteString(fmt.Sprintf("  - %s\n", note))
+		}
+	}
+
+	result.WriteString("\nSummary:\n")
+	result.WriteString(fmt.Sprintf("  Used: %d\n", r.Used))
+	result.WriteString(fmt.Sprintf("  Unused: %d\n", r.Unused))
+	result.WriteString(fmt.Sprintf("  Unverified: %d\n", r.Unverified))
+
+	return result.String()
+}
+
+// WriteCSV writes the unused rules as CSV, one row per rule to review for removal
+func (r Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	rows := [][]string{{"group_id", "group_name", "vpc_id", "direction", "rule", "status", "notes"}}
+	for _, finding := range r.UnusedRules() {
+		rows = append(rows, []string{
+			finding.GroupID,
+			finding.GroupName,
+			finding.VpcID,
+			finding.Direction,
+			finding.Rule,
+			finding.Status,
+			strings.Join(finding.Notes, "; "),
+		})
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write unused rules: %w", err)
+	}
+	return nil
+}
+
+// ParsePeriod parses an audit period as a number of days, e.g. "30d", or a Go
+// duration such as "36h"
+func ParsePeriod(period string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(period, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid period %q, expected e.g. 30d or 36h", period)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	length, err := time.ParseDuration(period)
+	if err != nil || length <= 0 {
+		return 0, fmt.Errorf("invalid period %q, expected e.g. 30d or 36h", period)
+	}
+	return length, nil
+}
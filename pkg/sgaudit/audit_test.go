@@ -0,0 +1,127 @@
+package sgaudit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/flows"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func auditNetwork() *scanner.Network {
+	return &scanner.Network{
+		SecurityGroups: []scanner.SecurityGroup{
+			{
+				ID:    "sg-web",
+				Name:  "web",
+				VpcID: "vpc-1",
+				IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+					{IpProtocol: "tcp", FromPort: 22, ToPort: 22, Ipv6CidrBlocks: []string{"::/0"}},
+					{IpProtocol: "tcp", FromPort: 443, ToPort: 443, PrefixListIds: []string{"pl-123"}},
+				},
+			},
+			{
+				ID:    "sg-batch",
+				Name:  "batch",
+				VpcID: "vpc-1",
+				EgressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "-1", CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+			},
+		},
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	lastSeen := time.Unix(1700000000, 0)
+	traffic := flows.Report{
+		SecurityGroups: []flows.Talker{{ID: "sg-web", Bytes: 100}},
+		Rules: []flows.RuleUsage{
+			{GroupID: "sg-web", Direction: flows.DirectionIngress, Rule: "tcp 443-443 0.0.0.0/0", Flows: 3, Bytes: 100, LastSeen: &lastSeen},
+		},
+	}
+
+	report := Analyze(auditNetwork(), traffic)
+
+	if report.Used != 1 || report.Unused != 2 || report.Unverified != 1 {
+		t.Errorf("Used/Unused/Unverified = %d/%d/%d, want 1/2/1", report.Used, report.Unused, report.Unverified)
+	}
+
+	findings := make(map[string]Finding)
+	for _, finding := range report.Rules {
+		findings[finding.GroupID+" "+finding.Rule] = finding
+	}
+
+	https := findings["sg-web tcp 443-443 0.0.0.0/0"]
+	if https.Status != StatusUsed || https.Flows != 3 || https.LastSeen == nil || !https.LastSeen.Equal(lastSeen) {
+		t.Errorf("unexpected HTTPS finding: %+v", https)
+	}
+	if len(https.Notes) != 1 || https.Notes[0] != NoteOpenToInternet {
+		t.Errorf("HTTPS notes = %v, want open to the internet", https.Notes)
+	}
+
+	ssh := findings["sg-web tcp 22-22 ::/0"]
+	if ssh.Status != StatusUnused || len(ssh.Notes) != 1 || ssh.Notes[0] != NoteOpenToInternet {
+		t.Errorf("unexpected SSH finding: %+v", ssh)
+	}
+
+	prefixList := findings["sg-web tcp 443-443 pl-123"]
+	if prefixList.Status != StatusUnverified {
+		t.Errorf("prefix list rule status = %s, want unverified", prefixList.Status)
+	}
+
+	egress := findings["sg-batch -1 all 0.0.0.0/0"]
+	if egress.Status != StatusUnused || len(egress.Notes) != 1 || egress.Notes[0] != NoteIdleGroup {
+		t.Errorf("unexpected idle group finding: %+v", egress)
+	}
+
+	text := report.Text()
+	for _, want := range []string{"sg-web", "unverified", "  - " + NoteOpenToInternet, "Unused: 2"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text report missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	report := Analyze(auditNetwork(), flows.Report{})
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header and 3 unused rules, got:\n%s", buf.String())
+	}
+	if lines[0] != "group_id,group_name,vpc_id,direction,rule,status,notes" {
+		t.Errorf("unexpected header: %s", lines[0])
+	}
+	if lines[1] != "sg-web,web,vpc-1,ingress,tcp 443-443 0.0.0.0/0,unused,no traffic observed on any interface in the group; open to the internet" {
+		t.Errorf("unexpected row: %s", lines[1])
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	valid := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"1d":  24 * time.Hour,
+		"36h": 36 * time.Hour,
+	}
+	for period, want := range valid {
+		got, err := ParsePeriod(period)
+		if err != nil || got != want {
+			t.Errorf("ParsePeriod(%q) = %v, %v, want %v", period, got, err, want)
+		}
+	}
+
+	for _, period := range []string{"", "d", "-1d", "0d", "30 days", "-5h"} {
+		if _, err := ParsePeriod(period); err == nil {
+			t.Errorf("ParsePeriod(%q) expected an error", period)
+		}
+	}
+}
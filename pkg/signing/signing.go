@@ -0,0 +1,268 @@
+// Package signing signs working state snapshots and verifies them on load, so
+// baselines used for compliance cannot be changed unnoticed. Signatures are kept
+// in a detached file next to the snapshot, leaving the snapshot itself unchanged.
+package signing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmsTypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// AlgorithmHMACSHA256 is the algorithm of signatures made with a shared HMAC key
+const AlgorithmHMACSHA256 = "HMAC-SHA256"
+
+// SignatureSuffix is appended to a snapshot's name to name its signature file
+const SignatureSuffix = ".sig"
+
+// minHMACKeyLength is the shortest HMAC key accepted, in bytes
+const minHMACKeyLength = 32
+
+// ErrInvalidSignature is returned when a snapshot does not match its signature
+var ErrInvalidSignature = errors.New("signature does not match the snapshot")
+
+// Signature is a detached signature of a snapshot
+type Signature struct {
+	// Algorithm is HMAC-SHA256 or the KMS signing algorithm, such as ECDSA_SHA_256
+	Algorithm string `json:"algorithm"`
+	// KeyID is the KMS key that made the signature
+	KeyID string `json:"key_id,omitempty"`
+	// Digest is the hex SHA-256 digest of the signed snapshot
+	Digest   string    `json:"digest"`
+	Value    string    `json:"signature"`
+	SignedAt time.Time `json:"signed_at"`
+}
+
+// Signer signs snapshots and verifies their signatures
+type Signer interface {
+	Sign(ctx context.Context, data []byte) (*Signature, error)
+	Verify(ctx context.Context, data []byte, signature *Signature) error
+}
+
+// Marshal encodes a signature for its signature file
+func (s *Signature) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signature: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Parse decodes a signature file
+func Parse(data []byte) (*Signature, error) {
+	var signature Signature
+	if err := json.Unmarshal(data, &signature); err != nil {
+		return nil, fmt.Errorf("failed to parse signature: %w", err)
+	}
+	if signature.Algorithm == "" || signature.Value == "" {
+		return nil, fmt.Errorf("failed to parse signature: missing algorithm or signature value")
+	}
+	return &signature, nil
+}
+
+// SignatureLocation returns where the signature of a snapshot is kept: the
+// snapshot's file name, S3 key or URL path with .sig appended
+func SignatureLocation(location string) string {
+	if parsed, err := url.Parse(location); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		parsed.Path += SignatureSuffix
+		parsed.RawPath = ""
+		return parsed.String()
+	}
+	return location + SignatureSuffix
+}
+
+// digest returns the SHA-256 digest of a snapshot
+func digest(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// checkDigest compares a snapshot's digest with the one recorded in its signature
+func checkDigest(data []byte, signature *Signature) ([]byte, error) {
+	sum := digest(data)
+	if signature.Digest != "" && !strings.EqualFold(signature.Digest, hex.EncodeToString(sum)) {
+		return nil, fmt.Errorf("%w: digest differs", ErrInvalidSignature)
+	}
+	return sum, nil
+}
+
+// HMACSigner signs snapshots with a shared secret key
+type HMACSigner struct {
+	key []byte
+}
+
+// NewHMACSigner creates a signer with a secret key of at least 32 bytes
+func NewHMACSigner(key []byte) (*HMACSigner, error) {
+	if len(key) < minHMACKeyLength {
+		return nil, fmt.Errorf("HMAC key must be at least %d bytes, got %d", minHMACKeyLength, len(key))
+	}
+	return &HMACSigner{key: key}, nil
+}
+
+// LoadHMACKey reads a secret key from a file, ignoring surrounding whitespace
+func LoadHMACKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HMAC key file %s: %w", path, err)
+	}
+	return []byte(strings.TrimSpace(string(data))), nil
+}
+
+// Sign signs a snapshot with HMAC-SHA256
+func (s *HMACSigner) Sign(ctx context.Context, data []byte) (*Signature, error) {
+	return &Signature{
+		Algorithm: AlgorithmHMACSHA256,
+		Digest:    hex.EncodeToString(digest(data)),
+		Value:     base64.StdEncoding.EncodeToString(s.mac(data)),
+		SignedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// Verify checks an HMAC-SHA256 signature of a snapshot
+func (s *HMACSigner) Verify(ctx context.Context, data []byte, signature *Signature) error {
+	if signature.Algorithm != AlgorithmHMACSHA256 {
+		return fmt.Errorf("signature algorithm %s cannot be verified with an HMAC key", signature.Algorithm)
+	}
+	if _, err := checkDigest(data, signature); err != nil {
+		return err
+	}
+
+	value, err := base64.StdEncoding.DecodeString(signature.Value)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if !hmac.Equal(value, s.mac(data)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// mac returns the HMAC-SHA256 of a snapshot
+func (s *HMACSigner) mac(data []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// KMSAPI is the subset of the KMS API used to sign with an asymmetric key
+type KMSAPI interface {
+	Sign(ctx context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error)
+	Verify(ctx context.Context, params *kms.VerifyInput, optFns ...func(*kms.Options)) (*kms.VerifyOutput, error)
+	GetPublicKey(ctx context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error)
+}
+
+// KMSSigner signs snapshots with an asymmetric KMS key, so snapshots can be
+// verified by anyone allowed kms:Verify without sharing a secret
+type KMSSigner struct {
+	api       KMSAPI
+	keyID     string
+	algorithm string
+}
+
+// NewKMSSigner creates a signer using a KMS key ID, ARN or alias
+func NewKMSSigner(api KMSAPI, keyID string) *KMSSigner {
+	return &KMSSigner{api: api, keyID: keyID}
+}
+
+// SetAlgorithm sets the KMS signing algorithm, such as RSASSA_PSS_SHA_256. By
+// default the first algorithm the key supports is used.
+func (s *KMSSigner) SetAlgorithm(algorithm string) {
+	s.algorithm = algorithm
+}
+
+// signingAlgorithm returns the algorithm to sign with, looking up the key's
+// supported algorithms when none is set
+func (s *KMSSigner) signingAlgorithm(ctx context.Context) (string, error) {
+	if s.algorithm != "" {
+		return s.algorithm, nil
+	}
+
+	result, err := s.api.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: &s.keyID})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up KMS key %s: %w", s.keyID, err)
+	}
+	if len(result.SigningAlgorithms) == 0 {
+		return "", fmt.Errorf("KMS key %s is not a signing key", s.keyID)
+	}
+	s.algorithm = string(result.SigningAlgorithms[0])
+	return s.algorithm, nil
+}
+
+// Sign signs a snapshot's SHA-256 digest with the KMS key
+func (s *KMSSigner) Sign(ctx context.Context, data []byte) (*Signature, error) {
+	algorithm, err := s.signingAlgorithm(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := digest(data)
+	result, err := s.api.Sign(ctx, &kms.SignInput{
+		KeyId:            &s.keyID,
+		Message:          sum,
+		MessageType:      kmsTypes.MessageTypeDigest,
+		SigningAlgorithm: kmsTypes.SigningAlgorithmSpec(algorithm),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with KMS key %s: %w", s.keyID, err)
+	}
+
+	keyID := s.keyID
+	if result.KeyId != nil {
+		keyID = *result.KeyId
+	}
+	return &Signature{
+		Algorithm: algorithm,
+		KeyID:     keyID,
+		Digest:    hex.EncodeToString(sum),
+		Value:     base64.StdEncoding.EncodeToString(result.Signature),
+		SignedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// Verify checks a KMS signature of a snapshot. The configured key is always
+// used, never the key recorded in the signature.
+func (s *KMSSigner) Verify(ctx context.Context, data []byte, signature *Signature) error {
+	if signature.Algorithm == AlgorithmHMACSHA256 {
+		return fmt.Errorf("signature algorithm %s cannot be verified with a KMS key", signature.Algorithm)
+	}
+	sum, err := checkDigest(data, signature)
+	if err != nil {
+		return err
+	}
+
+	value, err := base64.StdEncoding.DecodeString(signature.Value)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	result, err := s.api.Verify(ctx, &kms.VerifyInput{
+		KeyId:            &s.keyID,
+		Message:          sum,
+		MessageType:      kmsTypes.MessageTypeDigest,
+		Signature:        value,
+		SigningAlgorithm: kmsTypes.SigningAlgorithmSpec(signature.Algorithm),
+	})
+	if err != nil {
+		var invalid *kmsTypes.KMSInvalidSignatureException
+		if errors.As(err, &invalid) {
+			return ErrInvalidSignature
+		}
+		return fmt.Errorf("failed to verify with KMS key %s: %w", s.keyID, err)
+	}
+	if !result.SignatureValid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmsTypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+// fakeKMS signs by prefixing the digest with the key ID
+type fakeKMS struct {
+	algorithms []kmsTypes.SigningAlgorithmSpec
+	signed     *kms.SignInput
+}
+
+func (f *fakeKMS) Sign(ctx context.Context, params *kms.SignInput, optFns ...func(*kms.Options)) (*kms.SignOutput, error) {
+	f.signed = params
+	keyArn := "arn:aws:kms:us-east-1:123456789012:key/" + *params.KeyId
+	return &kms.SignOutput{KeyId: &keyArn, Signature: append([]byte(*params.KeyId), params.Message...)}, nil
+}
+
+func (f *fakeKMS) Verify(ctx context.Context, params *kms.VerifyInput, optFns ...func(*kms.Options)) (*kms.VerifyOutput, error) {
+	if !bytes.Equal(params.Signature, append([]byte(*params.KeyId), params.Message...)) {
+		return nil, &kmsTypes.KMSInvalidSignatureException{}
+	}
+	return &kms.VerifyOutput{SignatureValid: true}, nil
+}
+
+func (f *fakeKMS) GetPublicKey(ctx context.Context, params *kms.GetPublicKeyInput, optFns ...func(*kms.Options)) (*kms.GetPublicKeyOutput, error) {
+	return &kms.GetPublicKeyOutput{SigningAlgorithms: f.algorithms}, nil
+}
+
+func TestHMACSigner(t *testing.T) {
+	signer, err := NewHMACSigner(testKey)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	data := []byte(`{"region": "us-east-1"}`)
+
+	signature, err := signer.Sign(ctx, data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if signature.Algorithm != AlgorithmHMACSHA256 {
+		t.Errorf("Expected algorithm %s, got %s", AlgorithmHMACSHA256, signature.Algorithm)
+	}
+
+	// The signature survives its signature file
+	encoded, err := signature.Marshal()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	parsed, err := Parse(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := signer.Verify(ctx, data, parsed); err != nil {
+		t.Errorf("Expected the signature to verify, got %v", err)
+	}
+
+	if err := signer.Verify(ctx, []byte(`{"region": "us-west-2"}`), parsed); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a tampered snapshot to fail verification, got %v", err)
+	}
+
+	// A digest recomputed for tampered data does not help without the key
+	forged := *parsed
+	forged.Digest = ""
+	if err := signer.Verify(ctx, []byte(`{"region": "us-west-2"}`), &forged); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a forged signature to fail verification, got %v", err)
+	}
+
+	other, _ := NewHMACSigner([]byte("another key of at least 32 bytes!"))
+	if err := other.Verify(ctx, data, parsed); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected another key to fail verification, got %v", err)
+	}
+}
+
+func TestNewHMACSignerShortKey(t *testing.T) {
+	if _, err := NewHMACSigner([]byte("short")); err == nil {
+		t.Error("Expected an error for a short key")
+	}
+}
+
+func TestLoadHMACKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, append(testKey, '\n'), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := LoadHMACKey(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(key, testKey) {
+		t.Errorf("Expected the trailing newline to be trimmed, got %q", key)
+	}
+}
+
+func TestKMSSigner(t *testing.T) {
+	api := &fakeKMS{algorithms: []kmsTypes.SigningAlgorithmSpec{kmsTypes.SigningAlgorithmSpecEcdsaSha256}}
+	signer := NewKMSSigner(api, "alias/baselines")
+	ctx := context.Background()
+	data := []byte(`{"region": "us-east-1"}`)
+
+	signature, err := signer.Sign(ctx, data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if signature.Algorithm != "ECDSA_SHA_256" || api.signed.MessageType != kmsTypes.MessageTypeDigest {
+		t.Errorf("Expected an ECDSA_SHA_256 signature of the digest, got %s of %s", signature.Algorithm, api.signed.MessageType)
+	}
+	if signature.KeyID != "arn:aws:kms:us-east-1:123456789012:key/alias/baselines" {
+		t.Errorf("Expected the key ARN to be recorded, got %s", signature.KeyID)
+	}
+
+	if err := signer.Verify(ctx, data, signature); err != nil {
+		t.Errorf("Expected the signature to verify, got %v", err)
+	}
+	if err := NewKMSSigner(api, "alias/other").Verify(ctx, data, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected another key to fail verification, got %v", err)
+	}
+
+	hmacSignature, _ := (&HMACSigner{key: testKey}).Sign(ctx, data)
+	if err := signer.Verify(ctx, data, hmacSignature); err == nil {
+		t.Error("Expected an HMAC signature to be rejected by a KMS signer")
+	}
+}
+
+func TestSignatureLocation(t *testing.T) {
+	tests := map[string]string{
+		"working_state.json":                           "working_state.json.sig",
+		"s3://bucket/prod/state.json":                  "s3://bucket/prod/state.json.sig",
+		"s3://bucket/prod/state.json?region=eu-west-1": "s3://bucket/prod/state.json.sig?region=eu-west-1",
+		"https://example.com/state.json":               "https://example.com/state.json.sig",
+	}
+	for location, want := range tests {
+		if got := SignatureLocation(location); got != want {
+			t.Errorf("SignatureLocation(%q) = %q, want %q", location, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package statefile compresses working state files by their extension and
+// decompresses them transparently on load, since the states of large accounts
+// run to hundreds of megabytes of JSON.
+package statefile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Compress compresses the contents of a file named filename by its extension:
+// gzip for .gz, zstd for .zst and .zstd, and unchanged for any other name
+func Compress(filename string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(filename, &buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress %s: %w", filename, err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress %s: %w", filename, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// NewWriter returns a writer compressing what is written to it into w, as Compress
+// does for a file named filename. Close flushes it without closing w.
+func NewWriter(filename string, w io.Writer) (io.WriteCloser, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gz":
+		return gzip.NewWriter(w), nil
+	case ".zst", ".zstd":
+		writer, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+		return writer, nil
+	}
+	return nopCloser{w}, nil
+}
+
+// nopCloser is a writer that writes uncompressed
+type nopCloser struct {
+	io.Writer
+}
+
+// Close does nothing
+func (nopCloser) Close() error {
+	return nil
+}
+
+// IsStateFile reports whether a file name is that of a JSON working state, either
+// uncompressed or with a compression extension Compress recognizes
+func IsStateFile(filename string) bool {
+	name := strings.ToLower(filename)
+	for _, ext := range []string{".gz", ".zst", ".zstd"} {
+		if trimmed, found := strings.CutSuffix(name, ext); found {
+			name = trimmed
+			break
+		}
+	}
+	return filepath.Ext(name) == ".json"
+}
+
+// Decompress returns the contents of a state file decompressed when they are gzip
+// or zstd compressed, recognized by their magic number rather than the file name,
+// and unchanged otherwise
+func Decompress(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip header: %w", err)
+		}
+		defer reader.Close()
+		decompressed, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to gunzip: %w", err)
+		}
+		return decompressed, nil
+	case bytes.HasPrefix(data, zstdMagic):
+		reader, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd reader: %w", err)
+		}
+		defer reader.Close()
+		decompressed, err := reader.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to zstd decompress: %w", err)
+		}
+		return decompressed, nil
+	}
+	return data, nil
+}
@@ -0,0 +1,53 @@
+package statefile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"vpcs":[{"id":"vpc-12345"}]}`), 100)
+
+	for _, filename := range []string{"state.json.gz", "state.json.zst", "STATE.JSON.ZSTD", "state.json"} {
+		compressed, err := Compress(filename, data)
+		if err != nil {
+			t.Fatalf("Compress(%s) failed: %v", filename, err)
+		}
+		if filename == "state.json" && !bytes.Equal(compressed, data) {
+			t.Errorf("Expected %s written uncompressed", filename)
+		}
+		if filename != "state.json" && len(compressed) >= len(data) {
+			t.Errorf("Expected %s compressed, got %d bytes from %d", filename, len(compressed), len(data))
+		}
+
+		decompressed, err := Decompress(compressed)
+		if err != nil {
+			t.Fatalf("Decompress(%s) failed: %v", filename, err)
+		}
+		if !bytes.Equal(decompressed, data) {
+			t.Errorf("Expected %s to round trip", filename)
+		}
+	}
+}
+
+func TestDecompressCorrupt(t *testing.T) {
+	if _, err := Decompress([]byte{0x1f, 0x8b, 0x00}); err == nil {
+		t.Error("Expected an error for a truncated gzip file")
+	}
+}
+
+func TestIsStateFile(t *testing.T) {
+	for filename, expected := range map[string]bool{
+		"state.json":      true,
+		"state.json.gz":   true,
+		"STATE.JSON.ZST":  true,
+		"state.json.zstd": true,
+		"notes.txt":       false,
+		"logs.gz":         false,
+		"state.json.bz2":  false,
+	} {
+		if got := IsStateFile(filename); got != expected {
+			t.Errorf("IsStateFile(%s) = %v, expected %v", filename, got, expected)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package tagaudit
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// keyAliases maps normalized abbreviations to the key they abbreviate
+var keyAliases = map[string]string{
+	"env":  "environment",
+	"app":  "application",
+	"svc":  "service",
+	"proj": "project",
+	"dept": "department",
+}
+
+// Suggestion actions
+const (
+	ActionRenameKey      = "rename-key"
+	ActionNormalizeValue = "normalize-value"
+)
+
+// TaggedResource is a scanned resource and its tags
+type TaggedResource struct {
+	VpcID        string // Empty for account-wide resources
+	ResourceType string
+	ResourceID   string
+	Tags         map[string]string
+}
+
+// KeyStats inventories one tag key
+type KeyStats struct {
+	Key    string         `json:"key"`
+	Count  int            `json:"count"`
+	Values map[string]int `json:"values"`
+}
+
+// KeyGroup is a set of tag keys that appear to mean the same thing
+type KeyGroup struct {
+	Canonical string   `json:"canonical"`
+	Variants  []string `json:"variants"`
+}
+
+// Suggestion is a proposed tag change on one resource
+type Suggestion struct {
+	ResourceType   string `json:"resource_type"`
+	ResourceID     string `json:"resource_id"`
+	Action         string `json:"action"`
+	Key            string `json:"key"`
+	Value          string `json:"value"`
+	SuggestedKey   string `json:"suggested_key"`
+	SuggestedValue string `json:"suggested_value"`
+}
+
+// Report is the result of a tag audit
+type Report struct {
+	Resources   int          `json:"resources"`
+	Untagged    int          `json:"untagged"`
+	Keys        []KeyStats   `json:"keys"`
+	KeyGroups   []KeyGroup   `json:"near_duplicate_keys"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// Resources lists every taggable resource in the network
+func Resources(network *scanner.Network) []TaggedResource {
+	var resources []TaggedResource
+	add := func(vpcID, resourceType, id string, tags map[string]string) {
+		resources = append(resources, TaggedResource{VpcID: vpcID, ResourceType: resourceType, ResourceID: id, Tags: tags})
+	}
+
+	for _, vpc := range network.VPCs {
+		add(vpc.ID, "VPC", vpc.ID, vpc.Tags)
+	}
+	for _, subnet := range network.Subnets {
+		add(subnet.VpcID, "Subnet", subnet.ID, subnet.Tags)
+	}
+	for _, pc := range network.PeeringConnections {
+		add(pc.RequesterVpcID, "PeeringConnection", pc.ID, pc.Tags)
+	}
+	for _, tgw := range network.TransitGateways {
+		add("", "TransitGateway", tgw.ID, tgw.Tags)
+		for _, attachment := range tgw.Attachments {
+			attachmentVPC := ""
+			if attachment.ResourceType == "vpc" {
+				attachmentVPC = attachment.ResourceID
+			}
+			add(attachmentVPC, "TransitGatewayAttachment", attachment.ID, attachment.Tags)
+		}
+	}
+	// Internet gateways are listed once per attachment
+	seenIGWs := make(map[string]bool)
+	for _, igw := range network.InternetGateways {
+		if !seenIGWs[igw.ID] {
+			seenIGWs[igw.ID] = true
+			add(igw.VpcID, "InternetGateway", igw.ID, igw.Tags)
+		}
+	}
+	for _, nat := range network.NATGateways {
+		add(nat.VpcID, "NATGateway", nat.ID, nat.Tags)
+	}
+	for _, rt := range network.RouteTables {
+		add(rt.VpcID, "RouteTable", rt.ID, rt.Tags)
+	}
+	for _, sg := range network.SecurityGroups {
+		add(sg.VpcID, "SecurityGroup", sg.ID, sg.Tags)
+	}
+	for _, nacl := range network.NetworkAcls {
+		add(nacl.VpcID, "NetworkAcl", nacl.ID, nacl.Tags)
+	}
+	for _, options := range network.DhcpOptions {
+		add("", "DhcpOptions", options.ID, options.Tags)
+	}
+	for _, service := range network.EndpointServices {
+		add("", "EndpointService", service.ID, service.Tags)
+	}
+	for _, cluster := range network.EKSClusters {
+		add(cluster.VpcID, "EKSCluster", cluster.Name, cluster.Tags)
+	}
+	for _, service := range network.ECSServices {
+		add(service.VpcID, "ECSService", service.Arn, service.Tags)
+	}
+	for _, database := range network.Databases {
+		// ElastiCache tags are not scanned, so cache clusters cannot be audited
+		if database.Service != "elasticache" {
+			add(database.VpcID, "Database", database.Key(), database.Tags)
+		}
+	}
+	for _, role := range network.IAMRoles {
+		add("", "IAMRole", role.Name, role.Tags)
+	}
+
+	return resources
+}
+
+// normalizeKey reduces a tag key to a form shared by its near-duplicates
+func normalizeKey(key string) string {
+	var normalized strings.Builder
+	for _, r := range strings.ToLower(key) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			normalized.WriteRune(r)
+		}
+	}
+
+	if alias, ok := keyAliases[normalized.String()]; ok {
+		return alias
+	}
+	return normalized.String()
+}
+
+// mostCommon returns the entry with the highest count, breaking ties alphabetically
+func mostCommon(counts map[string]int) string {
+	best := ""
+	for candidate, count := range counts {
+		if best == "" || count > counts[best] || (count == counts[best] && candidate < best) {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// Audit inventories tags across resources and suggests normalizations
+func Audit(resources []TaggedResource) Report {
+	report := Report{Resources: len(resources)}
+
+	stats := make(map[string]*KeyStats)
+	for _, resource := range resources {
+		tagged := false
+		for key, value := range resource.Tags {
+			// AWS-managed tags cannot be changed
+			if strings.HasPrefix(key, "aws:") {
+				continue
+			}
+			tagged = true
+
+			if stats[key] == nil {
+				stats[key] = &KeyStats{Key: key, Values: make(map[string]int)}
+			}
+			stats[key].Count++
+			stats[key].Values[value]++
+		}
+		if !tagged {
+			report.Untagged++
+		}
+	}
+
+	for _, keyStats := range stats {
+		report.Keys = append(report.Keys, *keyStats)
+	}
+	sort.Slice(report.Keys, func(i, j int) bool { return report.Keys[i].Key < report.Keys[j].Key })
+
+	// Group keys by normalized form, the most used spelling is canonical
+	groups := make(map[string]map[string]int)
+	for _, keyStats := range report.Keys {
+		normalized := normalizeKey(keyStats.Key)
+		if groups[normalized] == nil {
+			groups[normalized] = make(map[string]int)
+		}
+		groups[normalized][keyStats.Key] = keyStats.Count
+	}
+
+	canonicalKeys := make(map[string]string)
+	for _, counts := range groups {
+		canonical := mostCommon(counts)
+		var variants []string
+		for key := range counts {
+			canonicalKeys[key] = canonical
+			if key != canonical {
+				variants = append(variants, key)
+			}
+		}
+		if len(variants) > 0 {
+			sort.Strings(variants)
+			report.KeyGroups = append(report.KeyGroups, KeyGroup{Canonical: canonical, Variants: variants})
+		}
+	}
+	sort.Slice(report.KeyGroups, func(i, j int) bool { return report.KeyGroups[i].Canonical < report.KeyGroups[j].Canonical })
+
+	// Values differing only by case are normalized to the most used spelling per canonical key
+	valueCounts := make(map[string]map[string]int)
+	for _, keyStats := range report.Keys {
+		canonical := canonicalKeys[keyStats.Key]
+		if valueCounts[canonical] == nil {
+			valueCounts[canonical] = make(map[string]int)
+		}
+		for value, count := range keyStats.Values {
+			valueCounts[canonical][value] += count
+		}
+	}
+
+	canonicalValues := make(map[string]map[string]string)
+	for canonical, counts := range valueCounts {
+		byLower := make(map[string]map[string]int)
+		for value, count := range counts {
+			lower := strings.ToLower(value)
+			if byLower[lower] == nil {
+				byLower[lower] = make(map[string]int)
+			}
+			byLower[lower][value] = count
+		}
+
+		canonicalValues[canonical] = make(map[string]string)
+		for _, spellings := range byLower {
+			preferred := mostCommon(spellings)
+			for value := range spellings {
+				canonicalValues[canonical][value] = preferred
+			}
+		}
+	}
+
+	for _, resource := range resources {
+		keys := make([]string, 0, len(resource.Tags))
+		for key := range resource.Tags {
+			if !strings.HasPrefix(key, "aws:") {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := resource.Tags[key]
+			suggestedKey := canonicalKeys[key]
+			suggestedValue := canonicalValues[suggestedKey][value]
+
+			action := ""
+			switch {
+			case suggestedKey != key:
+				action = ActionRenameKey
+			case suggestedValue != value:
+				action = ActionNormalizeValue
+			default:
+				continue
+			}
+
+			report.Suggestions = append(report.Suggestions, Suggestion{
+				ResourceType:   resource.ResourceType,
+				ResourceID:     resource.ResourceID,
+				Action:         action,
+				Key:            key,
+				Value:          value,
+				SuggestedKey:   suggestedKey,
+				SuggestedValue: suggestedValue,
+			})
+		}
+	}
+
+	return report
+}
+
+// Text renders the report for the terminal
+func (r Report) Text() string {
+	var result strings.Builder
+
+	result.WriteString("Tag Audit\n\n")
+	result.WriteString(fmt.Sprintf("Resources: %d (%d untagged)\n", r.Resources, r.Untagged))
+	result.WriteString(fmt.Sprintf("Tag keys: %d\n\n", len(r.Keys)))
+
+	result.WriteString(fmt.Sprintf("%-30s %-10s %s\n", "KEY", "RESOURCES", "VALUES"))
+	for _, keyStats := range r.Keys {
+		result.WriteString(fmt.Sprintf("%-30s %-10d %d\n", keyStats.Key, keyStats.Count, len(keyStats.Values)))
+	}
+
+	if len(r.KeyGroups) > 0 {
+		result.WriteString("\nNear-duplicate keys:\n")
+		for _, group := range r.KeyGroups {
+			result.WriteString(fmt.Sprintf("  %s <- %s\n", group.Canonical, strings.Join(group.Variants, ", ")))
+		}
+	}
+
+	result.WriteString(fmt.Sprintf("\nSuggested changes: %d\n", len(r.Suggestions)))
+	for _, suggestion := range r.Suggestions {
+		result.WriteString(fmt.Sprintf("  %s %s: %s=%s -> %s=%s\n",
+			suggestion.ResourceType, suggestion.ResourceID,
+			suggestion.Key, suggestion.Value,
+			suggestion.SuggestedKey, suggestion.SuggestedValue))
+	}
+
+	return result.String()
+}
+
+// WriteCSV writes the suggested changes as a remediation plan
+func (r Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"resource_type", "resource_id", "action", "key", "value", "suggested_key", "suggested_value"}); err != nil {
+		return fmt.Errorf("failed to write remediation plan: %w", err)
+	}
+	for _, suggestion := range r.Suggestions {
+		record := []string{
+			suggestion.ResourceType,
+			suggestion.ResourceID,
+			suggestion.Action,
+			suggestion.Key,
+			suggestion.Value,
+			suggestion.SuggestedKey,
+			suggestion.SuggestedValue,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write remediation plan: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write remediation plan: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package tagaudit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	resources := []TaggedResource{
+		{ResourceType: "VPC", ResourceID: "vpc-1", Tags: map[string]string{"Environment": "prod", "Name": "main"}},
+		{ResourceType: "Subnet", ResourceID: "subnet-1", Tags: map[string]string{"Environment": "prod"}},
+		{ResourceType: "Subnet", ResourceID: "subnet-2", Tags: map[string]string{"env": "Prod"}},
+		{ResourceType: "SecurityGroup", ResourceID: "sg-1", Tags: map[string]string{"aws:cloudformation:stack-name": "net"}},
+	}
+
+	report := Audit(resources)
+
+	if report.Resources != 4 || report.Untagged != 1 {
+		t.Errorf("Expected 4 resources with 1 untagged, got %d and %d", report.Resources, report.Untagged)
+	}
+
+	if len(report.KeyGroups) != 1 || report.KeyGroups[0].Canonical != "Environment" || report.KeyGroups[0].Variants[0] != "env" {
+		t.Fatalf("Expected env to be grouped under Environment, got %+v", report.KeyGroups)
+	}
+
+	if len(report.Suggestions) != 1 {
+		t.Fatalf("Expected 1 suggestion, got %+v", report.Suggestions)
+	}
+
+	suggestion := report.Suggestions[0]
+	if suggestion.ResourceID != "subnet-2" || suggestion.Action != ActionRenameKey ||
+		suggestion.SuggestedKey != "Environment" || suggestion.SuggestedValue != "prod" {
+		t.Errorf("Unexpected suggestion: %+v", suggestion)
+	}
+
+	var plan bytes.Buffer
+	if err := report.WriteCSV(&plan); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	if !strings.Contains(plan.String(), "Subnet,subnet-2,rename-key,env,Prod,Environment,prod") {
+		t.Errorf("Unexpected remediation plan:\n%s", plan.String())
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := map[string]string{
+		"Cost-Center": "costcenter",
+		"cost_center": "costcenter",
+		"ENV":         "environment",
+		"App":         "application",
+	}
+
+	for input, want := range tests {
+		if got := normalizeKey(input); got != want {
+			t.Errorf("normalizeKey(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
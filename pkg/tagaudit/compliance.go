@@ -0,0 +1,227 @@
+package tagaudit
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Compliance problems
+const (
+	ProblemMissing = "missing"
+	ProblemInvalid = "invalid"
+)
+
+// Issue is a required tag a resource is missing or a tag value violating its pattern
+type Issue struct {
+	VpcID        string `json:"vpc_id"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+	Key          string `json:"key"`
+	Problem      string `json:"problem"`
+	Value        string `json:"value,omitempty"`
+	Pattern      string `json:"pattern,omitempty"`
+}
+
+// ComplianceGroup is the noncompliant resources of one type in one VPC
+type ComplianceGroup struct {
+	VpcID        string  `json:"vpc_id"`
+	ResourceType string  `json:"resource_type"`
+	Resources    int     `json:"noncompliant_resources"`
+	Issues       []Issue `json:"issues"`
+}
+
+// ComplianceReport is the result of a tag compliance check
+type ComplianceReport struct {
+	Required     []string          `json:"required"`
+	Patterns     map[string]string `json:"patterns,omitempty"`
+	Resources    int               `json:"resources"`
+	Noncompliant int               `json:"noncompliant_resources"`
+	Groups       []ComplianceGroup `json:"groups"`
+}
+
+// ParsePatterns parses Key=regex pairs into anchored patterns matching whole tag values
+func ParsePatterns(pairs []string) (map[string]*regexp.Regexp, error) {
+	patterns := make(map[string]*regexp.Regexp)
+	for _, pair := range pairs {
+		key, expr, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid tag pattern %q, expected Key=regex", pair)
+		}
+		if _, exists := patterns[key]; exists {
+			return nil, fmt.Errorf("duplicate tag pattern for key %s", key)
+		}
+		pattern, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag pattern for key %s: %w", key, err)
+		}
+		patterns[key] = pattern
+	}
+	return patterns, nil
+}
+
+// Check reports the resources missing required tags or with tag values violating a pattern.
+// A pattern on a key that is not required only applies to resources carrying the tag.
+func Check(resources []TaggedResource, required []string, patterns map[string]*regexp.Regexp) ComplianceReport {
+	report := ComplianceReport{Required: required, Resources: len(resources)}
+	if len(patterns) > 0 {
+		report.Patterns = make(map[string]string)
+		for key, pattern := range patterns {
+			report.Patterns[key] = patternSource(pattern)
+		}
+	}
+
+	keys := append([]string{}, required...)
+	for key := range patterns {
+		if !contains(required, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys[len(required):])
+
+	type groupKey struct{ vpcID, resourceType string }
+	groups := make(map[groupKey]*ComplianceGroup)
+
+	for _, resource := range resources {
+		var issues []Issue
+		for _, key := range keys {
+			value, ok := resource.Tags[key]
+			issue := Issue{
+				VpcID:        resource.VpcID,
+				ResourceType: resource.ResourceType,
+				ResourceID:   resource.ResourceID,
+				Key:          key,
+			}
+			switch {
+			case !ok && contains(required, key):
+				issue.Problem = ProblemMissing
+			case ok && patterns[key] != nil && !patterns[key].MatchString(value):
+				issue.Problem = ProblemInvalid
+				issue.Value = value
+				issue.Pattern = patternSource(patterns[key])
+			default:
+				continue
+			}
+			issues = append(issues, issue)
+		}
+		if len(issues) == 0 {
+			continue
+		}
+
+		report.Noncompliant++
+		k := groupKey{resource.VpcID, resource.ResourceType}
+		if groups[k] == nil {
+			groups[k] = &ComplianceGroup{VpcID: resource.VpcID, ResourceType: resource.ResourceType}
+		}
+		groups[k].Resources++
+		groups[k].Issues = append(groups[k].Issues, issues...)
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group.Issues, func(i, j int) bool { return group.Issues[i].ResourceID < group.Issues[j].ResourceID })
+		report.Groups = append(report.Groups, *group)
+	}
+	// Account-wide resources without a VPC are listed last
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.VpcID != b.VpcID {
+			if a.VpcID == "" || b.VpcID == "" {
+				return b.VpcID == ""
+			}
+			return a.VpcID < b.VpcID
+		}
+		return a.ResourceType < b.ResourceType
+	})
+
+	return report
+}
+
+// patternSource returns the expression a pattern was parsed from, without its anchors
+func patternSource(pattern *regexp.Regexp) string {
+	return strings.TrimSuffix(strings.TrimPrefix(pattern.String(), "^(?:"), ")$")
+}
+
+// contains reports whether a key is in a list of keys
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Compliant reports whether every resource has the required tags with valid values
+func (r ComplianceReport) Compliant() bool {
+	return r.Noncompliant == 0
+}
+
+// Text renders the compliance report for the terminal
+func (r ComplianceReport) Text() string {
+	var result strings.Builder
+
+	result.WriteString("Tag Compliance\n\n")
+	result.WriteString(fmt.Sprintf("Required tags: %s\n", strings.Join(r.Required, ", ")))
+	if len(r.Patterns) > 0 {
+		keys := make([]string, 0, len(r.Patterns))
+		for key := range r.Patterns {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			result.WriteString(fmt.Sprintf("Pattern: %s=%s\n", key, r.Patterns[key]))
+		}
+	}
+	result.WriteString(fmt.Sprintf("Resources: %d (%d noncompliant)\n", r.Resources, r.Noncompliant))
+
+	for _, group := range r.Groups {
+		vpc := group.VpcID
+		if vpc == "" {
+			vpc = "(no VPC)"
+		}
+		result.WriteString(fmt.Sprintf("\n%s %s: %d noncompliant\n", vpc, group.ResourceType, group.Resources))
+		for _, issue := range group.Issues {
+			if issue.Problem == ProblemMissing {
+				result.WriteString(fmt.Sprintf("  %s: missing %s\n", issue.ResourceID, issue.Key))
+			} else {
+				result.WriteString(fmt.Sprintf("  %s: %s=%q does not match %s\n", issue.ResourceID, issue.Key, issue.Value, issue.Pattern))
+			}
+		}
+	}
+
+	return result.String()
+}
+
+// WriteCSV writes one row per compliance issue for remediation tracking
+func (r ComplianceReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"vpc_id", "resource_type", "resource_id", "key", "problem", "value", "pattern"}); err != nil {
+		return fmt.Errorf("failed to write compliance report: %w", err)
+	}
+	for _, group := range r.Groups {
+		for _, issue := range group.Issues {
+			record := []string{
+				issue.VpcID,
+				issue.ResourceType,
+				issue.ResourceID,
+				issue.Key,
+				issue.Problem,
+				issue.Value,
+				issue.Pattern,
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write compliance report: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write compliance report: %w", err)
+	}
+	return nil
+}
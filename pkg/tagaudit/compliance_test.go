@@ -0,0 +1,66 @@
+package tagaudit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	resources := []TaggedResource{
+		{VpcID: "vpc-1", ResourceType: "VPC", ResourceID: "vpc-1", Tags: map[string]string{"Name": "main", "Environment": "prod"}},
+		{VpcID: "vpc-1", ResourceType: "Subnet", ResourceID: "subnet-2", Tags: map[string]string{"Name": "b", "Environment": "Production"}},
+		{VpcID: "vpc-1", ResourceType: "Subnet", ResourceID: "subnet-1", Tags: map[string]string{"Environment": "dev", "Owner": "x"}},
+		{ResourceType: "TransitGateway", ResourceID: "tgw-1", Tags: map[string]string{"Name": "hub", "Environment": "prod", "Owner": "bad owner"}},
+	}
+	patterns, err := ParsePatterns([]string{"Environment=prod|dev", "Owner=[a-z]+"})
+	if err != nil {
+		t.Fatalf("ParsePatterns failed: %v", err)
+	}
+
+	report := Check(resources, []string{"Name", "Environment"}, patterns)
+
+	if report.Resources != 4 || report.Noncompliant != 3 || report.Compliant() {
+		t.Fatalf("Expected 3 of 4 resources to be noncompliant, got %d of %d", report.Noncompliant, report.Resources)
+	}
+	if len(report.Groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %+v", report.Groups)
+	}
+
+	subnets := report.Groups[0]
+	if subnets.VpcID != "vpc-1" || subnets.ResourceType != "Subnet" || subnets.Resources != 2 || len(subnets.Issues) != 2 {
+		t.Fatalf("Unexpected subnet group: %+v", subnets)
+	}
+	if issue := subnets.Issues[0]; issue.ResourceID != "subnet-1" || issue.Key != "Name" || issue.Problem != ProblemMissing {
+		t.Errorf("Expected subnet-1 to miss Name, got %+v", issue)
+	}
+	if issue := subnets.Issues[1]; issue.ResourceID != "subnet-2" || issue.Problem != ProblemInvalid || issue.Value != "Production" || issue.Pattern != "prod|dev" {
+		t.Errorf("Expected subnet-2 to have an invalid Environment, got %+v", issue)
+	}
+
+	// Owner is not required, so only the present, invalid value is reported
+	if tgw := report.Groups[1]; tgw.VpcID != "" || len(tgw.Issues) != 1 || tgw.Issues[0].Key != "Owner" {
+		t.Errorf("Expected the account-wide group last with the invalid Owner, got %+v", tgw)
+	}
+
+	if text := report.Text(); !strings.Contains(text, "(no VPC) TransitGateway: 1 noncompliant") {
+		t.Errorf("Expected the account-wide group in the text report:\n%s", text)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[2] != "vpc-1,Subnet,subnet-2,Environment,invalid,Production,prod|dev" {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+}
+
+func TestParsePatternsInvalid(t *testing.T) {
+	for _, pairs := range [][]string{{"Environment"}, {"=prod"}, {"Owner=("}, {"A=x", "A=y"}} {
+		if _, err := ParsePatterns(pairs); err == nil {
+			t.Errorf("Expected an error for %v", pairs)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package validate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/statefile"
+)
+
+// Severity indicates how serious a validation issue is
+type Severity string
+
+const (
+	// SeverityError marks issues that make the state unsafe to diff against
+	SeverityError Severity = "error"
+	// SeverityWarning marks references that cannot be resolved but may be legitimate
+	SeverityWarning Severity = "warning"
+)
+
+// Issue represents a single problem found in a state file
+type Issue struct {
+	Severity     Severity `json:"severity"`
+	ResourceType string   `json:"resource_type"`
+	ResourceID   string   `json:"resource_id"`
+	Message      string   `json:"message"`
+}
+
+// String returns a human-readable representation of the issue
+func (i Issue) String() string {
+	return fmt.Sprintf("[%s] %s %s: %s", i.Severity, i.ResourceType, i.ResourceID, i.Message)
+}
+
+// externalTargetPrefixes lists route target prefixes for resources that are not scanned
+var externalTargetPrefixes = []string{"vgw-", "vpce-", "lgw-", "cagw-", "eigw-", "i-", "eni-"}
+
+// ValidateFile validates a state file against the Network schema and checks its referential integrity
+func ValidateFile(filename string) ([]Issue, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file %s: %w", filename, err)
+	}
+	if data, err = statefile.Decompress(data); err != nil {
+		return nil, fmt.Errorf("failed to decompress state file %s: %w", filename, err)
+	}
+
+	network, err := scanner.DecodeNetworkStrict(data)
+	if err != nil {
+		return []Issue{{
+			Severity:     SeverityError,
+			ResourceType: "State",
+			ResourceID:   filename,
+			Message:      fmt.Sprintf("does not match the state schema: %v", err),
+		}}, nil
+	}
+
+	return Validate(network), nil
+}
+
+// Validate checks the referential integrity of a network state
+func Validate(network *scanner.Network) []Issue {
+	v := &validator{network: network}
+	v.index()
+	v.checkVPCs()
+	v.checkSubnets()
+	v.checkRouteTables()
+	v.checkGateways()
+	v.checkPeeringConnections()
+	v.checkTransitGateways()
+	v.checkSecurityGroups()
+	v.checkNetworkAcls()
+	return v.issues
+}
+
+// HasErrors reports whether any issue has error severity
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// validator holds the lookup tables used while validating a network
+type validator struct {
+	network *scanner.Network
+	issues  []Issue
+
+	vpcs             map[string]bool
+	subnets          map[string]bool
+	routeTables      map[string]bool
+	securityGroups   map[string]bool
+	networkAcls      map[string]bool
+	internetGateways map[string]bool
+	natGateways      map[string]bool
+	peerings         map[string]bool
+	transitGateways  map[string]bool
+}
+
+func (v *validator) addIssue(severity Severity, resourceType, resourceID, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{
+		Severity:     severity,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Message:      fmt.Sprintf(format, args...),
+	})
+}
+
+// indexIDs builds a set of IDs and reports empty and duplicate IDs
+func (v *validator) indexIDs(resourceType string, ids []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, id := range ids {
+		if id == "" {
+			v.addIssue(SeverityError, resourceType, "", "missing resource ID")
+			continue
+		}
+		if set[id] {
+			v.addIssue(SeverityError, resourceType, id, "duplicate resource ID")
+		}
+		set[id] = true
+	}
+	return set
+}
+
+func (v *validator) index() {
+	n := v.network
+
+	ids := make([]string, 0, len(n.VPCs))
+	for _, vpc := range n.VPCs {
+		ids = append(ids, vpc.ID)
+	}
+	v.vpcs = v.indexIDs("VPC", ids)
+
+	ids = ids[:0]
+	for _, subnet := range n.Subnets {
+		ids = append(ids, subnet.ID)
+	}
+	v.subnets = v.indexIDs("Subnet", ids)
+
+	ids = ids[:0]
+	for _, rt := range n.RouteTables {
+		ids = append(ids, rt.ID)
+	}
+	v.routeTables = v.indexIDs("RouteTable", ids)
+
+	ids = ids[:0]
+	for _, sg := range n.SecurityGroups {
+		ids = append(ids, sg.ID)
+	}
+	v.securityGroups = v.indexIDs("SecurityGroup", ids)
+
+	ids = ids[:0]
+	for _, nacl := range n.NetworkAcls {
+		ids = append(ids, nacl.ID)
+	}
+	v.networkAcls = v.indexIDs("NetworkACL", ids)
+
+	// An internet gateway appears once per VPC attachment, so duplicates are expected
+	v.internetGateways = make(map[string]bool)
+	for _, igw := range n.InternetGateways {
+		v.internetGateways[igw.ID] = true
+	}
+
+	ids = ids[:0]
+	for _, nat := range n.NATGateways {
+		ids = append(ids, nat.ID)
+	}
+	v.natGateways = v.indexIDs("NATGateway", ids)
+
+	ids = ids[:0]
+	for _, pc := range n.PeeringConnections {
+		ids = append(ids, pc.ID)
+	}
+	v.peerings = v.indexIDs("PeeringConnection", ids)
+
+	ids = ids[:0]
+	for _, tgw := range n.TransitGateways {
+		ids = append(ids, tgw.ID)
+	}
+	v.transitGateways = v.indexIDs("TransitGateway", ids)
+}
+
+// checkVPCRef reports a resource whose VPC is not part of the state
+func (v *validator) checkVPCRef(resourceType, resourceID, vpcID string) {
+	if vpcID == "" {
+		v.addIssue(SeverityError, resourceType, resourceID, "missing VPC ID")
+	} else if !v.vpcs[vpcID] {
+		v.addIssue(SeverityError, resourceType, resourceID, "references unknown VPC %s", vpcID)
+	}
+}
+
+func (v *validator) checkVPCs() {
+	for _, vpc := range v.network.VPCs {
+		for _, id := range vpc.Subnets {
+			if !v.subnets[id] {
+				v.addIssue(SeverityError, "VPC", vpc.ID, "references unknown subnet %s", id)
+			}
+		}
+		for _, id := range vpc.SecurityGroups {
+			if !v.securityGroups[id] {
+				v.addIssue(SeverityError, "VPC", vpc.ID, "references unknown security group %s", id)
+			}
+		}
+		for _, id := range vpc.InternetGateways {
+			if !v.internetGateways[id] {
+				v.addIssue(SeverityError, "VPC", vpc.ID, "references unknown internet gateway %s", id)
+			}
+		}
+		for _, id := range vpc.NATGateways {
+			if !v.natGateways[id] {
+				v.addIssue(SeverityError, "VPC", vpc.ID, "references unknown NAT gateway %s", id)
+			}
+		}
+		for _, id := range vpc.NetworkAcls {
+			if !v.networkAcls[id] {
+				v.addIssue(SeverityError, "VPC", vpc.ID, "references unknown network ACL %s", id)
+			}
+		}
+	}
+}
+
+func (v *validator) checkSubnets() {
+	for _, subnet := range v.network.Subnets {
+		v.checkVPCRef("Subnet", subnet.ID, subnet.VpcID)
+
+		if subnet.RouteTableID != "" && !v.routeTables[subnet.RouteTableID] {
+			v.addIssue(SeverityError, "Subnet", subnet.ID, "references unknown route table %s", subnet.RouteTableID)
+		}
+		if subnet.NetworkAclID != "" && !v.networkAcls[subnet.NetworkAclID] {
+			v.addIssue(SeverityError, "Subnet", subnet.ID, "references unknown network ACL %s", subnet.NetworkAclID)
+		}
+	}
+}
+
+func (v *validator) checkRouteTables() {
+	for _, rt := range v.network.RouteTables {
+		v.checkVPCRef("RouteTable", rt.ID, rt.VpcID)
+
+		for _, subnetID := range rt.Associations {
+			if !v.subnets[subnetID] {
+				v.addIssue(SeverityError, "RouteTable", rt.ID, "associated with unknown subnet %s", subnetID)
+			}
+		}
+
+		for _, route := range rt.Routes {
+			v.checkRouteTarget(rt.ID, route)
+		}
+	}
+}
+
+// checkRouteTarget reports route targets that should be part of the state but are not
+func (v *validator) checkRouteTarget(routeTableID string, route scanner.Route) {
+	unresolved := func(target string) {
+		v.addIssue(SeverityError, "RouteTable", routeTableID, "route to %s targets unknown %s", route.Destination(), target)
+	}
+
+	switch {
+	case route.GatewayID == "local":
+	case strings.HasPrefix(route.GatewayID, "igw-"):
+		if !v.internetGateways[route.GatewayID] {
+			unresolved(route.GatewayID)
+		}
+	case route.GatewayID != "" && !isExternalTarget(route.GatewayID):
+		v.addIssue(SeverityWarning, "RouteTable", routeTableID, "route to %s targets unrecognized gateway %s", route.Destination(), route.GatewayID)
+	}
+
+	if route.NatGatewayID != "" && !v.natGateways[route.NatGatewayID] {
+		unresolved(route.NatGatewayID)
+	}
+	if route.VpcPeeringID != "" && !v.peerings[route.VpcPeeringID] {
+		unresolved(route.VpcPeeringID)
+	}
+
+	// Transit gateways shared from other accounts are not returned by DescribeTransitGateways
+	if route.TransitGatewayID != "" && !v.transitGateways[route.TransitGatewayID] {
+		v.addIssue(SeverityWarning, "RouteTable", routeTableID, "route to %s targets transit gateway %s which is not in the state", route.Destination(), route.TransitGatewayID)
+	}
+}
+
+// isExternalTarget reports whether a route target is a resource type pikaatools does not scan
+func isExternalTarget(target string) bool {
+	for _, prefix := range externalTargetPrefixes {
+		if strings.HasPrefix(target, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) checkGateways() {
+	for _, igw := range v.network.InternetGateways {
+		v.checkVPCRef("InternetGateway", igw.ID, igw.VpcID)
+	}
+
+	for _, nat := range v.network.NATGateways {
+		v.checkVPCRef("NATGateway", nat.ID, nat.VpcID)
+		if !v.subnets[nat.SubnetID] {
+			v.addIssue(SeverityError, "NATGateway", nat.ID, "placed in unknown subnet %s", nat.SubnetID)
+		}
+	}
+}
+
+func (v *validator) checkPeeringConnections() {
+	for _, pc := range v.network.PeeringConnections {
+		// The peer VPC may live in another account or region, but one side must be local
+		if !v.vpcs[pc.RequesterVpcID] && !v.vpcs[pc.AccepterVpcID] {
+			v.addIssue(SeverityError, "PeeringConnection", pc.ID, "neither %s nor %s is in the state", pc.RequesterVpcID, pc.AccepterVpcID)
+		}
+	}
+}
+
+func (v *validator) checkTransitGateways() {
+	for _, tgw := range v.network.TransitGateways {
+		for _, att := range tgw.Attachments {
+			if att.TransitGatewayID != "" && att.TransitGatewayID != tgw.ID {
+				v.addIssue(SeverityError, "TransitGateway", tgw.ID, "attachment %s belongs to %s", att.ID, att.TransitGatewayID)
+			}
+			if att.ResourceType == "vpc" && !v.vpcs[att.ResourceID] {
+				v.addIssue(SeverityWarning, "TransitGateway", tgw.ID, "attachment %s targets VPC %s which is not in the state", att.ID, att.ResourceID)
+			}
+		}
+	}
+}
+
+func (v *validator) checkSecurityGroups() {
+	for _, sg := range v.network.SecurityGroups {
+		v.checkVPCRef("SecurityGroup", sg.ID, sg.VpcID)
+
+		rules := append(append([]scanner.SecurityGroupRule{}, sg.IngressRules...), sg.EgressRules...)
+		for _, rule := range rules {
+			// Groups in other VPCs or accounts are legitimately absent
+			for _, groupID := range rule.ReferencedGroupIDs() {
+				if !v.securityGroups[groupID] {
+					v.addIssue(SeverityWarning, "SecurityGroup", sg.ID, "rule references security group %s which is not in the state", groupID)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) checkNetworkAcls() {
+	for _, nacl := range v.network.NetworkAcls {
+		v.checkVPCRef("NetworkACL", nacl.ID, nacl.VpcID)
+
+		for _, subnetID := range nacl.Associations {
+			if !v.subnets[subnetID] {
+				v.addIssue(SeverityError, "NetworkACL", nacl.ID, "associated with unknown subnet %s", subnetID)
+			}
+		}
+	}
+}
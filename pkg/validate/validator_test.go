@@ -0,0 +1,146 @@
+package validate
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func validNetwork() *scanner.Network {
+	return &scanner.Network{
+		Region: "us-east-1",
+		VPCs: []scanner.VPC{
+			{
+				ID:               "vpc-12345",
+				Subnets:          []string{"subnet-12345"},
+				InternetGateways: []string{"igw-12345"},
+				NATGateways:      []string{"nat-12345"},
+			},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-12345", VpcID: "vpc-12345", RouteTableID: "rtb-12345"},
+		},
+		InternetGateways: []scanner.InternetGateway{
+			{ID: "igw-12345", VpcID: "vpc-12345"},
+		},
+		NATGateways: []scanner.NATGateway{
+			{ID: "nat-12345", VpcID: "vpc-12345", SubnetID: "subnet-12345"},
+		},
+		RouteTables: []scanner.RouteTable{
+			{
+				ID:           "rtb-12345",
+				VpcID:        "vpc-12345",
+				Associations: []string{"subnet-12345"},
+				Routes: []scanner.Route{
+					{DestinationCidr: "10.0.0.0/16", GatewayID: "local"},
+					{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-12345"},
+					{DestinationCidr: "192.168.0.0/16", GatewayID: "vgw-12345"},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateValidNetwork(t *testing.T) {
+	issues := Validate(validNetwork())
+	if len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+}
+
+func TestValidateDanglingReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(n *scanner.Network)
+		severity Severity
+		contains string
+	}{
+		{
+			name: "VPC lists unknown subnet",
+			mutate: func(n *scanner.Network) {
+				n.VPCs[0].Subnets = append(n.VPCs[0].Subnets, "subnet-missing")
+			},
+			severity: SeverityError,
+			contains: "subnet-missing",
+		},
+		{
+			name: "Subnet in unknown VPC",
+			mutate: func(n *scanner.Network) {
+				n.Subnets[0].VpcID = "vpc-missing"
+			},
+			severity: SeverityError,
+			contains: "vpc-missing",
+		},
+		{
+			name: "Route to unknown internet gateway",
+			mutate: func(n *scanner.Network) {
+				n.RouteTables[0].Routes[1].GatewayID = "igw-missing"
+			},
+			severity: SeverityError,
+			contains: "igw-missing",
+		},
+		{
+			name: "Route to transit gateway not in state",
+			mutate: func(n *scanner.Network) {
+				n.RouteTables[0].Routes = append(n.RouteTables[0].Routes, scanner.Route{
+					DestinationCidr:  "172.16.0.0/12",
+					TransitGatewayID: "tgw-shared",
+				})
+			},
+			severity: SeverityWarning,
+			contains: "tgw-shared",
+		},
+		{
+			name: "Duplicate subnet ID",
+			mutate: func(n *scanner.Network) {
+				n.Subnets = append(n.Subnets, n.Subnets[0])
+			},
+			severity: SeverityError,
+			contains: "duplicate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := validNetwork()
+			tt.mutate(network)
+
+			issues := Validate(network)
+			if len(issues) != 1 {
+				t.Fatalf("Expected 1 issue, got %v", issues)
+			}
+
+			if issues[0].Severity != tt.severity {
+				t.Errorf("Expected severity %s, got %s", tt.severity, issues[0].Severity)
+			}
+
+			if !strings.Contains(issues[0].String(), tt.contains) {
+				t.Errorf("Expected issue to mention %s, got %s", tt.contains, issues[0])
+			}
+		})
+	}
+}
+
+func TestValidateFileUnknownField(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test_invalid_state_*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(`{"vpcs": [], "unexpected_field": true}`); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	issues, err := ValidateFile(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !HasErrors(issues) {
+		t.Error("Expected schema error for unknown field")
+	}
+}
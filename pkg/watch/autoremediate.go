@@ -0,0 +1,581 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"gopkg.in/yaml.v3"
+)
+
+// Drift classes an auto-remediation policy can allow reverting. Only rules,
+// routes and entries added since the baseline are reverted, by deleting them.
+const (
+	DriftIngressRuleAdded     = "security_group_ingress_added"
+	DriftEgressRuleAdded      = "security_group_egress_added"
+	DriftRouteAdded           = "route_added"
+	DriftNetworkAclEntryAdded = "network_acl_entry_added"
+)
+
+// driftClasses are the known drift classes, with whether they select by port
+var driftClasses = map[string]bool{
+	DriftIngressRuleAdded:     true,
+	DriftEgressRuleAdded:      true,
+	DriftRouteAdded:           false,
+	DriftNetworkAclEntryAdded: true,
+}
+
+// DefaultMaxRemediations is how many changes a scan may revert when the policy
+// does not say
+const DefaultMaxRemediations = 10
+
+// Remediation statuses recorded in the audit log
+const (
+	RemediationReverted = "reverted"
+	RemediationPlanned  = "planned"
+	RemediationFailed   = "failed"
+	RemediationSkipped  = "skipped"
+)
+
+// RemediationPolicy lists the drift classes watch may revert automatically
+type RemediationPolicy struct {
+	Rules []RemediationRule `yaml:"rules"`
+	// MaxActions is the most changes reverted after one scan. A scan finding
+	// more reverts none, since mass drift more likely means a stale baseline.
+	MaxActions int `yaml:"max_actions"`
+}
+
+// RemediationRule allows reverting one drift class. Every selector that is set
+// must match: the resource's ID and tags, a source or destination CIDR of the
+// added rule, route or entry, and a port it allows.
+type RemediationRule struct {
+	Name       string            `yaml:"name"`
+	Class      string            `yaml:"class"`
+	ResourceID string            `yaml:"resource_id"`
+	Tags       map[string]string `yaml:"tags"`
+	CIDRs      []string          `yaml:"cidrs"`
+	Ports      []int32           `yaml:"ports"`
+}
+
+// LoadRemediationPolicy loads an auto-remediation policy from a YAML file
+func LoadRemediationPolicy(filename string) (*RemediationPolicy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remediation policy %s: %w", filename, err)
+	}
+
+	var policy RemediationPolicy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse remediation policy %s: %w", filename, err)
+	}
+	if err := policy.Validate(filename); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Validate checks the policy's rules and fills in defaults. The source names
+// where the policy came from.
+func (p *RemediationPolicy) Validate(source string) error {
+	if len(p.Rules) == 0 {
+		return fmt.Errorf("remediation policy %s has no rules", source)
+	}
+	if p.MaxActions < 0 {
+		return fmt.Errorf("remediation policy %s has a negative max_actions", source)
+	}
+	if p.MaxActions == 0 {
+		p.MaxActions = DefaultMaxRemediations
+	}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		byPort, ok := driftClasses[rule.Class]
+		if !ok {
+			return fmt.Errorf("remediation rule %q in %s has unknown class %q, expected %s, %s, %s or %s", rule.Name, source, rule.Class,
+				DriftIngressRuleAdded, DriftEgressRuleAdded, DriftRouteAdded, DriftNetworkAclEntryAdded)
+		}
+		if len(rule.Ports) > 0 && !byPort {
+			return fmt.Errorf("remediation rule %q in %s selects ports, which %s does not support", rule.Name, source, rule.Class)
+		}
+		for _, cidr := range rule.CIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("remediation rule %q in %s has invalid CIDR %q", rule.Name, source, cidr)
+			}
+		}
+	}
+	return nil
+}
+
+// matchesResource reports whether the rule's resource selectors match a resource
+func (rule RemediationRule) matchesResource(resourceID string, tags map[string]string) bool {
+	if rule.ResourceID != "" && rule.ResourceID != resourceID {
+		return false
+	}
+	for key, value := range rule.Tags {
+		if actual, ok := tags[key]; !ok || (value != "*" && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchingCIDRs returns the given CIDRs the rule selects, all of them when it
+// does not select by CIDR
+func (rule RemediationRule) matchingCIDRs(cidrs []string) []string {
+	if len(rule.CIDRs) == 0 {
+		return cidrs
+	}
+	var matching []string
+	for _, cidr := range cidrs {
+		for _, selected := range rule.CIDRs {
+			if cidr == selected {
+				matching = append(matching, cidr)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// matchesPorts reports whether traffic of a protocol over a port range reaches
+// one of the rule's ports
+func (rule RemediationRule) matchesPorts(protocol string, from, to int32) bool {
+	if len(rule.Ports) == 0 || protocol == "-1" {
+		return true
+	}
+	switch protocol {
+	case "tcp", "udp", "6", "17":
+	default:
+		return false
+	}
+	for _, port := range rule.Ports {
+		if port >= from && port <= to {
+			return true
+		}
+	}
+	return false
+}
+
+// RemediationAction is an automatic revert of one drifted rule, route or entry,
+// as recorded in the audit log
+type RemediationAction struct {
+	Time         time.Time `json:"time"`
+	Region       string    `json:"region"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	Class        string    `json:"class"`
+	// Rule is the name of the policy rule that allowed the revert
+	Rule string `json:"rule"`
+	// Change names the drifted element, such as "IngressRules[sgr-1]"
+	Change string `json:"change"`
+	// Command is the AWS CLI equivalent of the revert
+	Command string `json:"command"`
+	DryRun  bool   `json:"dry_run"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ec2RemediationAPI is the subset of the EC2 API used to revert drift
+type ec2RemediationAPI interface {
+	RevokeSecurityGroupIngress(ctx context.Context, params *ec2.RevokeSecurityGroupIngressInput, optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupIngressOutput, error)
+	RevokeSecurityGroupEgress(ctx context.Context, params *ec2.RevokeSecurityGroupEgressInput, optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupEgressOutput, error)
+	DeleteRoute(ctx context.Context, params *ec2.DeleteRouteInput, optFns ...func(*ec2.Options)) (*ec2.DeleteRouteOutput, error)
+	DeleteNetworkAclEntry(ctx context.Context, params *ec2.DeleteNetworkAclEntryInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkAclEntryOutput, error)
+}
+
+// plannedRemediation is an action with the API call performing it
+type plannedRemediation struct {
+	action RemediationAction
+	apply  func(ctx context.Context, api ec2RemediationAPI) error
+}
+
+// AutoRemediator reverts the drift classes a policy allows after each scan,
+// recording every action in an audit log
+type AutoRemediator struct {
+	api      ec2RemediationAPI
+	policy   *RemediationPolicy
+	region   string
+	dryRun   bool
+	auditLog string
+	logger   *slog.Logger
+}
+
+// NewAutoRemediator creates a remediator reverting drift in a region with the
+// EC2 client
+func NewAutoRemediator(client *ec2.Client, policy *RemediationPolicy, region string) *AutoRemediator {
+	return &AutoRemediator{api: client, policy: policy, region: region, logger: slog.Default()}
+}
+
+// SetDryRun only records and logs what would be reverted
+func (a *AutoRemediator) SetDryRun(dryRun bool) {
+	a.dryRun = dryRun
+}
+
+// SetAuditLog appends every action to a JSONL audit log at path
+func (a *AutoRemediator) SetAuditLog(path string) {
+	a.auditLog = path
+}
+
+// SetLogger sets the logger actions are reported to
+func (a *AutoRemediator) SetLogger(logger *slog.Logger) {
+	a.logger = logger
+}
+
+// Remediate reverts the differences between baseline and current that the
+// policy allows, returning the actions taken. Nothing is reverted when there
+// are more than the policy's maximum, or when the audit log cannot be written.
+func (a *AutoRemediator) Remediate(ctx context.Context, differences []Difference, baseline, current *scanner.Network) ([]RemediationAction, error) {
+	plan := a.plan(differences, baseline, current)
+	if len(plan) == 0 {
+		return nil, nil
+	}
+
+	var audit *os.File
+	if a.auditLog != "" {
+		var err error
+		if audit, err = os.OpenFile(a.auditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+			return nil, fmt.Errorf("failed to open remediation audit log %s, reverting nothing: %w", a.auditLog, err)
+		}
+		defer audit.Close()
+	}
+
+	var tooMany error
+	if len(plan) > a.policy.MaxActions {
+		tooMany = fmt.Errorf("%d changes to revert exceed the policy's max_actions of %d, reverting nothing", len(plan), a.policy.MaxActions)
+	}
+
+	actions := make([]RemediationAction, 0, len(plan))
+	var failed int
+	for _, planned := range plan {
+		action := planned.action
+		action.Time = time.Now().UTC()
+		switch {
+		case tooMany != nil:
+			action.Status, action.Error = RemediationSkipped, tooMany.Error()
+		case a.dryRun:
+			action.Status = RemediationPlanned
+		default:
+			if err := planned.apply(ctx, a.api); err != nil {
+				action.Status, action.Error = RemediationFailed, err.Error()
+				failed++
+			} else {
+				action.Status = RemediationReverted
+			}
+		}
+
+		a.logger.Warn("auto-remediation", "status", action.Status, "rule", action.Rule, "resource_type", action.ResourceType,
+			"resource_id", action.ResourceID, "change", action.Change, "command", action.Command)
+		if audit != nil {
+			line, err := json.Marshal(action)
+			if err == nil {
+				_, err = audit.Write(append(line, '\n'))
+			}
+			if err != nil {
+				a.logger.Error("failed to write remediation audit log", "file", a.auditLog, "error", err)
+			}
+		}
+		actions = append(actions, action)
+	}
+
+	if tooMany != nil {
+		return actions, tooMany
+	}
+	if failed > 0 {
+		return actions, fmt.Errorf("failed to revert %d of %d changes", failed, len(plan))
+	}
+	return actions, nil
+}
+
+// plan returns the reverts the policy allows for modified security groups,
+// route tables and network ACLs. Changes suppressed by ignore rules are left alone.
+func (a *AutoRemediator) plan(differences []Difference, baseline, current *scanner.Network) []plannedRemediation {
+	var plan []plannedRemediation
+	for _, diff := range differences {
+		if diff.Type != Modified {
+			continue
+		}
+		switch diff.ResourceType {
+		case "SecurityGroup":
+			before, after := findByID(baseline.SecurityGroups, diff.ResourceID), findByID(current.SecurityGroups, diff.ResourceID)
+			if before != nil && after != nil {
+				plan = append(plan, a.planSecurityGroup(before.(scanner.SecurityGroup), after.(scanner.SecurityGroup), diff.Details)...)
+			}
+		case "RouteTable":
+			before, after := findByID(baseline.RouteTables, diff.ResourceID), findByID(current.RouteTables, diff.ResourceID)
+			if before != nil && after != nil {
+				plan = append(plan, a.planRouteTable(before.(scanner.RouteTable), after.(scanner.RouteTable), diff.Details)...)
+			}
+		case "NetworkACL":
+			before, after := findByID(baseline.NetworkAcls, diff.ResourceID), findByID(current.NetworkAcls, diff.ResourceID)
+			if before != nil && after != nil {
+				plan = append(plan, a.planNetworkAcl(before.(scanner.NetworkAcl), after.(scanner.NetworkAcl), diff.Details)...)
+			}
+		}
+	}
+	return plan
+}
+
+// rules returns the policy rules of a drift class that select a resource
+func (a *AutoRemediator) rules(class, resourceID string, tags map[string]string) []RemediationRule {
+	var rules []RemediationRule
+	for _, rule := range a.policy.Rules {
+		if rule.Class == class && rule.matchesResource(resourceID, tags) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// action returns the audit record of reverting a change
+func (a *AutoRemediator) action(resourceType, resourceID string, rule RemediationRule, change string, command []string) RemediationAction {
+	return RemediationAction{
+		Region:       a.region,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Class:        rule.Class,
+		Rule:         rule.Name,
+		Change:       change,
+		Command:      remediator{format: RemediationCLI, region: a.region}.cli(command),
+		DryRun:       a.dryRun,
+	}
+}
+
+// planSecurityGroup revokes the rules added to a security group that the policy
+// allows. Of a rule with several sources, only the selected sources are revoked.
+// Rules allowing what a baseline rule already allowed are left alone, and nothing
+// is revoked against a baseline saved without rule IDs.
+func (a *AutoRemediator) planSecurityGroup(baseline, current scanner.SecurityGroup, details []string) []plannedRemediation {
+	var plan []plannedRemediation
+	for _, direction := range []struct {
+		field, name, class string
+		baseline, current  []scanner.SecurityGroupRule
+	}{
+		{"IngressRules", "ingress", DriftIngressRuleAdded, baseline.IngressRules, current.IngressRules},
+		{"EgressRules", "egress", DriftEgressRuleAdded, baseline.EgressRules, current.EgressRules},
+	} {
+		rules := a.rules(direction.class, current.ID, current.Tags)
+		if len(rules) == 0 {
+			continue
+		}
+		if !securityGroupRuleIDsKnown(direction.baseline, nil) {
+			a.logger.Warn("not reverting security group rules against a baseline saved without rule IDs",
+				"resource_id", current.ID, "direction", direction.name)
+			continue
+		}
+
+		identity := securityGroupRuleIdentities(direction.baseline, direction.current)
+		existing := make(map[string]bool)
+		allowed := make(map[string]bool)
+		for _, rule := range direction.baseline {
+			existing[identity(rule)] = true
+			allowed[securityGroupRuleKey(rule)] = true
+		}
+
+		for _, added := range direction.current {
+			key := identity(added)
+			change := fmt.Sprintf("%s[%s]", direction.field, key)
+			if existing[key] || allowed[securityGroupRuleKey(added)] || !detailChanged(details, change) {
+				continue
+			}
+			for _, rule := range rules {
+				revoked, ok := selectSecurityGroupRule(rule, added)
+				if !ok {
+					continue
+				}
+				remove := remediator{}.securityGroupRule(current.ID, direction.name, revoked).remove
+				plan = append(plan, plannedRemediation{
+					action: a.action("SecurityGroup", current.ID, rule, change, remove),
+					apply:  revokeSecurityGroupRule(current.ID, direction.name, revoked),
+				})
+				break
+			}
+		}
+	}
+	return plan
+}
+
+// selectSecurityGroupRule returns the part of an added rule a policy rule selects
+func selectSecurityGroupRule(rule RemediationRule, added scanner.SecurityGroupRule) (scanner.SecurityGroupRule, bool) {
+	if !rule.matchesPorts(added.IpProtocol, added.FromPort, added.ToPort) {
+		return added, false
+	}
+	if len(rule.CIDRs) == 0 {
+		return added, true
+	}
+
+	selected := added
+	selected.CidrBlocks = rule.matchingCIDRs(added.CidrBlocks)
+	selected.Ipv6CidrBlocks = rule.matchingCIDRs(added.Ipv6CidrBlocks)
+	selected.PrefixListIds, selected.ReferencedGroups = nil, nil
+	if len(selected.CidrBlocks)+len(selected.Ipv6CidrBlocks) == 0 {
+		return added, false
+	}
+	if len(selected.CidrBlocks)+len(selected.Ipv6CidrBlocks) != len(added.CidrBlocks)+len(added.Ipv6CidrBlocks)+len(added.PrefixListIds)+len(added.ReferencedGroups) {
+		// Only some sources are revoked, by permission rather than by rule ID
+		selected.ID = ""
+	}
+	return selected, true
+}
+
+// revokeSecurityGroupRule returns the API call revoking a rule, by its ID when known
+func revokeSecurityGroupRule(groupID, direction string, rule scanner.SecurityGroupRule) func(context.Context, ec2RemediationAPI) error {
+	return func(ctx context.Context, api ec2RemediationAPI) error {
+		var ruleIDs []string
+		var permissions []ec2Types.IpPermission
+		if rule.ID != "" {
+			ruleIDs = []string{rule.ID}
+		} else {
+			permissions = []ec2Types.IpPermission{ec2IpPermission(rule)}
+		}
+
+		var err error
+		if direction == "ingress" {
+			_, err = api.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+				GroupId: aws.String(groupID), SecurityGroupRuleIds: ruleIDs, IpPermissions: permissions,
+			})
+		} else {
+			_, err = api.RevokeSecurityGroupEgress(ctx, &ec2.RevokeSecurityGroupEgressInput{
+				GroupId: aws.String(groupID), SecurityGroupRuleIds: ruleIDs, IpPermissions: permissions,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to revoke %s rule of %s: %w", direction, groupID, err)
+		}
+		return nil
+	}
+}
+
+// ec2IpPermission converts a scanned rule back to an EC2 permission
+func ec2IpPermission(rule scanner.SecurityGroupRule) ec2Types.IpPermission {
+	permission := ec2Types.IpPermission{IpProtocol: aws.String(rule.IpProtocol)}
+	if rule.IpProtocol != "-1" {
+		permission.FromPort, permission.ToPort = aws.Int32(rule.FromPort), aws.Int32(rule.ToPort)
+	}
+	for _, cidr := range rule.CidrBlocks {
+		permission.IpRanges = append(permission.IpRanges, ec2Types.IpRange{CidrIp: aws.String(cidr)})
+	}
+	for _, cidr := range rule.Ipv6CidrBlocks {
+		permission.Ipv6Ranges = append(permission.Ipv6Ranges, ec2Types.Ipv6Range{CidrIpv6: aws.String(cidr)})
+	}
+	for _, prefixList := range rule.PrefixListIds {
+		permission.PrefixListIds = append(permission.PrefixListIds, ec2Types.PrefixListId{PrefixListId: aws.String(prefixList)})
+	}
+	for _, group := range rule.ReferencedGroups {
+		pair := ec2Types.UserIdGroupPair{GroupId: aws.String(group.GroupID)}
+		if group.OwnerID != "" {
+			pair.UserId = aws.String(group.OwnerID)
+		}
+		if group.PeeringID != "" {
+			pair.VpcPeeringConnectionId = aws.String(group.PeeringID)
+		}
+		permission.UserIdGroupPairs = append(permission.UserIdGroupPairs, pair)
+	}
+	return permission
+}
+
+// planRouteTable deletes the routes added to a route table that the policy allows
+func (a *AutoRemediator) planRouteTable(baseline, current scanner.RouteTable, details []string) []plannedRemediation {
+	rules := a.rules(DriftRouteAdded, current.ID, current.Tags)
+	if len(rules) == 0 {
+		return nil
+	}
+	existing := make(map[string]bool)
+	for _, route := range baseline.Routes {
+		existing[route.Destination()] = true
+	}
+
+	var plan []plannedRemediation
+	for _, added := range current.Routes {
+		change := fmt.Sprintf("Routes[%s]", added.Destination())
+		if existing[added.Destination()] || !revertibleRoute(added) || !detailChanged(details, change) {
+			continue
+		}
+		for _, rule := range rules {
+			if len(rule.matchingCIDRs([]string{added.Destination()})) == 0 {
+				continue
+			}
+			route := added
+			plan = append(plan, plannedRemediation{
+				action: a.action("RouteTable", current.ID, rule, change, routeArgs("delete-route", current.ID, route)),
+				apply: func(ctx context.Context, api ec2RemediationAPI) error {
+					input := &ec2.DeleteRouteInput{RouteTableId: aws.String(current.ID)}
+					if route.DestinationCidr != "" {
+						input.DestinationCidrBlock = aws.String(route.DestinationCidr)
+					} else {
+						input.DestinationIpv6CidrBlock = aws.String(route.DestinationIpv6Cidr)
+					}
+					if _, err := api.DeleteRoute(ctx, input); err != nil {
+						return fmt.Errorf("failed to delete route %s from %s: %w", route.Destination(), current.ID, err)
+					}
+					return nil
+				},
+			})
+			break
+		}
+	}
+	return plan
+}
+
+// planNetworkAcl deletes the allow entries added to a network ACL that the
+// policy allows. Added deny entries only restrict traffic and are left alone.
+func (a *AutoRemediator) planNetworkAcl(baseline, current scanner.NetworkAcl, details []string) []plannedRemediation {
+	rules := a.rules(DriftNetworkAclEntryAdded, current.ID, current.Tags)
+	if len(rules) == 0 {
+		return nil
+	}
+	existing := make(map[string]bool)
+	for _, entry := range baseline.Entries {
+		existing[networkAclEntryKey(entry)] = true
+	}
+
+	var plan []plannedRemediation
+	for _, added := range current.Entries {
+		key := networkAclEntryKey(added)
+		change := fmt.Sprintf("Entries[%s]", key)
+		if existing[key] || !strings.EqualFold(added.RuleAction, "allow") || added.RuleNumber == defaultNetworkAclRule || !detailChanged(details, change) {
+			continue
+		}
+		cidr := added.CidrBlock
+		if cidr == "" {
+			cidr = added.Ipv6CidrBlock
+		}
+		for _, rule := range rules {
+			var from, to int32
+			if added.PortRange != nil {
+				from, to = added.PortRange.From, added.PortRange.To
+			}
+			if len(rule.matchingCIDRs([]string{cidr})) == 0 || !rule.matchesPorts(added.Protocol, from, to) {
+				continue
+			}
+			entry := added
+			remove := remediator{}.networkAclEntry(current.ID, entry).remove
+			plan = append(plan, plannedRemediation{
+				action: a.action("NetworkACL", current.ID, rule, change, remove),
+				apply: func(ctx context.Context, api ec2RemediationAPI) error {
+					_, err := api.DeleteNetworkAclEntry(ctx, &ec2.DeleteNetworkAclEntryInput{
+						NetworkAclId: aws.String(current.ID),
+						RuleNumber:   aws.Int32(entry.RuleNumber),
+						Egress:       aws.Bool(entry.Egress),
+					})
+					if err != nil {
+						return fmt.Errorf("failed to delete entry %s from %s: %w", key, current.ID, err)
+					}
+					return nil
+				},
+			})
+			break
+		}
+	}
+	return plan
+}
@@ -0,0 +1,254 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// fakeEC2Remediation records the reverts made through it
+type fakeEC2Remediation struct {
+	calls []string
+	err   error
+}
+
+func (f *fakeEC2Remediation) RevokeSecurityGroupIngress(ctx context.Context, params *ec2.RevokeSecurityGroupIngressInput, optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupIngressOutput, error) {
+	call := "revoke-ingress " + *params.GroupId + " " + strings.Join(params.SecurityGroupRuleIds, ",")
+	for _, permission := range params.IpPermissions {
+		for _, ipRange := range permission.IpRanges {
+			call += " " + *ipRange.CidrIp
+		}
+	}
+	f.calls = append(f.calls, call)
+	return &ec2.RevokeSecurityGroupIngressOutput{}, f.err
+}
+
+func (f *fakeEC2Remediation) RevokeSecurityGroupEgress(ctx context.Context, params *ec2.RevokeSecurityGroupEgressInput, optFns ...func(*ec2.Options)) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+	f.calls = append(f.calls, "revoke-egress "+*params.GroupId)
+	return &ec2.RevokeSecurityGroupEgressOutput{}, f.err
+}
+
+func (f *fakeEC2Remediation) DeleteRoute(ctx context.Context, params *ec2.DeleteRouteInput, optFns ...func(*ec2.Options)) (*ec2.DeleteRouteOutput, error) {
+	f.calls = append(f.calls, "delete-route "+*params.RouteTableId+" "+*params.DestinationCidrBlock)
+	return &ec2.DeleteRouteOutput{}, f.err
+}
+
+func (f *fakeEC2Remediation) DeleteNetworkAclEntry(ctx context.Context, params *ec2.DeleteNetworkAclEntryInput, optFns ...func(*ec2.Options)) (*ec2.DeleteNetworkAclEntryOutput, error) {
+	f.calls = append(f.calls, "delete-entry "+*params.NetworkAclId)
+	return &ec2.DeleteNetworkAclEntryOutput{}, f.err
+}
+
+func autoRemediationNetworks() (*scanner.Network, *scanner.Network) {
+	baseline := &scanner.Network{
+		SecurityGroups: []scanner.SecurityGroup{{ID: "sg-1", Tags: map[string]string{"env": "prod"}}},
+		RouteTables:    []scanner.RouteTable{{ID: "rtb-1"}},
+		NetworkAcls:    []scanner.NetworkAcl{{ID: "acl-1"}},
+	}
+	current := &scanner.Network{
+		SecurityGroups: []scanner.SecurityGroup{{ID: "sg-1", Tags: map[string]string{"env": "prod"}, IngressRules: []scanner.SecurityGroupRule{
+			{ID: "sgr-ssh", IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"0.0.0.0/0"}},
+			{ID: "sgr-https", IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+			{IpProtocol: "tcp", FromPort: 3389, ToPort: 3389, CidrBlocks: []string{"0.0.0.0/0", "10.0.0.0/8"}},
+		}}},
+		RouteTables: []scanner.RouteTable{{ID: "rtb-1", Routes: []scanner.Route{
+			{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-1", Origin: "CreateRoute"},
+		}}},
+		NetworkAcls: []scanner.NetworkAcl{{ID: "acl-1", Entries: []scanner.NetworkAclEntry{
+			{RuleNumber: 90, Protocol: "-1", RuleAction: "deny", CidrBlock: "0.0.0.0/0"},
+			{RuleNumber: 95, Protocol: "6", RuleAction: "allow", CidrBlock: "0.0.0.0/0", PortRange: &scanner.NetworkAclPortRange{From: 22, To: 22}},
+		}}},
+	}
+	return baseline, current
+}
+
+func newTestAutoRemediator(t *testing.T, api *fakeEC2Remediation, rules ...RemediationRule) *AutoRemediator {
+	t.Helper()
+	policy := &RemediationPolicy{Rules: rules}
+	if err := policy.Validate("test"); err != nil {
+		t.Fatalf("invalid policy: %v", err)
+	}
+	remediator := &AutoRemediator{api: api, policy: policy, region: "us-east-1", logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	remediator.SetAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	return remediator
+}
+
+func TestAutoRemediatorRevertsAllowedDrift(t *testing.T) {
+	api := &fakeEC2Remediation{}
+	remediator := newTestAutoRemediator(t, api,
+		RemediationRule{Name: "no-public-admin", Class: DriftIngressRuleAdded, Tags: map[string]string{"env": "prod"}, CIDRs: []string{"0.0.0.0/0"}, Ports: []int32{22, 3389}},
+		RemediationRule{Class: DriftRouteAdded, CIDRs: []string{"0.0.0.0/0"}},
+		RemediationRule{Class: DriftNetworkAclEntryAdded, Ports: []int32{22}},
+	)
+
+	baseline, current := autoRemediationNetworks()
+	actions, err := remediator.Remediate(context.Background(), NewComparator(false).Compare(baseline, current), baseline, current)
+	if err != nil {
+		t.Fatalf("Remediate failed: %v", err)
+	}
+
+	want := []string{
+		"revoke-ingress sg-1 sgr-ssh",
+		"revoke-ingress sg-1  0.0.0.0/0",
+		"delete-entry acl-1",
+		"delete-route rtb-1 0.0.0.0/0",
+	}
+	if strings.Join(api.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected calls:\n%s\nwant:\n%s", strings.Join(api.calls, "\n"), strings.Join(want, "\n"))
+	}
+	if len(actions) != 4 || actions[0].Rule != "no-public-admin" || actions[0].Status != RemediationReverted {
+		t.Errorf("unexpected actions %+v", actions)
+	}
+	if actions[3].Command != "aws ec2 delete-route --route-table-id rtb-1 --destination-cidr-block 0.0.0.0/0 --region us-east-1" {
+		t.Errorf("unexpected command %q", actions[3].Command)
+	}
+
+	data, err := os.ReadFile(remediator.auditLog)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 audit lines, got %d", len(lines))
+	}
+	var logged RemediationAction
+	if err := json.Unmarshal([]byte(lines[2]), &logged); err != nil || logged.Change != "Entries[ingress #95]" || logged.Class != DriftNetworkAclEntryAdded {
+		t.Errorf("unexpected audit entry %s: %v", lines[2], err)
+	}
+}
+
+func TestAutoRemediatorDryRun(t *testing.T) {
+	api := &fakeEC2Remediation{}
+	remediator := newTestAutoRemediator(t, api, RemediationRule{Class: DriftRouteAdded})
+	remediator.SetDryRun(true)
+
+	baseline, current := autoRemediationNetworks()
+	actions, err := remediator.Remediate(context.Background(), NewComparator(false).Compare(baseline, current), baseline, current)
+	if err != nil {
+		t.Fatalf("Remediate failed: %v", err)
+	}
+	if len(api.calls) != 0 {
+		t.Errorf("dry run should not call EC2, got %v", api.calls)
+	}
+	if len(actions) != 1 || actions[0].Status != RemediationPlanned || !actions[0].DryRun {
+		t.Errorf("unexpected actions %+v", actions)
+	}
+}
+
+func TestAutoRemediatorMaxActions(t *testing.T) {
+	api := &fakeEC2Remediation{}
+	remediator := newTestAutoRemediator(t, api, RemediationRule{Class: DriftIngressRuleAdded})
+	remediator.policy.MaxActions = 2
+
+	baseline, current := autoRemediationNetworks()
+	actions, err := remediator.Remediate(context.Background(), NewComparator(false).Compare(baseline, current), baseline, current)
+	if err == nil || !strings.Contains(err.Error(), "exceed the policy's max_actions of 2") {
+		t.Errorf("expected a max_actions error, got %v", err)
+	}
+	if len(api.calls) != 0 {
+		t.Errorf("nothing should be reverted, got %v", api.calls)
+	}
+	for _, action := range actions {
+		if action.Status != RemediationSkipped {
+			t.Errorf("expected skipped actions, got %+v", action)
+		}
+	}
+}
+
+func TestAutoRemediatorSkipsUnselectedDrift(t *testing.T) {
+	api := &fakeEC2Remediation{}
+	remediator := newTestAutoRemediator(t, api,
+		RemediationRule{Class: DriftIngressRuleAdded, Tags: map[string]string{"env": "dev"}},
+		RemediationRule{Class: DriftRouteAdded, ResourceID: "rtb-other"},
+	)
+
+	baseline, current := autoRemediationNetworks()
+	actions, err := remediator.Remediate(context.Background(), NewComparator(false).Compare(baseline, current), baseline, current)
+	if err != nil || len(actions) != 0 || len(api.calls) != 0 {
+		t.Errorf("expected nothing reverted, got %+v, %v, %v", actions, api.calls, err)
+	}
+}
+
+func TestAutoRemediatorLeavesRulesTheBaselineAllowed(t *testing.T) {
+	api := &fakeEC2Remediation{}
+	remediator := newTestAutoRemediator(t, api, RemediationRule{Class: DriftIngressRuleAdded, CIDRs: []string{"0.0.0.0/0"}})
+	var logs bytes.Buffer
+	remediator.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// Baselines saved without rule IDs cannot tell added rules from existing ones
+	baseline, current := autoRemediationNetworks()
+	baseline.SecurityGroups[0].IngressRules = []scanner.SecurityGroupRule{
+		{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+	}
+	if plan := remediator.plan(NewComparator(false).Compare(baseline, current), baseline, current); len(plan) != 0 {
+		t.Errorf("expected an empty plan against a baseline without rule IDs, got %+v", plan)
+	}
+	if !strings.Contains(logs.String(), "baseline saved without rule IDs") || !strings.Contains(logs.String(), "resource_id=sg-1") {
+		t.Errorf("expected the refusal to be logged, got %q", logs.String())
+	}
+
+	// A rule recreated with a new ID allows nothing new
+	baseline.SecurityGroups[0].IngressRules = []scanner.SecurityGroupRule{
+		{ID: "sgr-old", IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"0.0.0.0/0"}},
+		{ID: "sgr-https", IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+	}
+	plan := remediator.plan(NewComparator(false).Compare(baseline, current), baseline, current)
+	if len(plan) != 1 || plan[0].action.Change != "IngressRules[tcp 3389-3389 0.0.0.0/0,10.0.0.0/8]" {
+		t.Errorf("expected only the added RDP rule to be reverted, got %+v", plan)
+	}
+}
+
+func TestAutoRemediatorReportsFailures(t *testing.T) {
+	api := &fakeEC2Remediation{err: errors.New("UnauthorizedOperation")}
+	remediator := newTestAutoRemediator(t, api, RemediationRule{Class: DriftRouteAdded})
+
+	baseline, current := autoRemediationNetworks()
+	actions, err := remediator.Remediate(context.Background(), NewComparator(false).Compare(baseline, current), baseline, current)
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if len(actions) != 1 || actions[0].Status != RemediationFailed || !strings.Contains(actions[0].Error, "UnauthorizedOperation") {
+		t.Errorf("unexpected actions %+v", actions)
+	}
+}
+
+func TestLoadRemediationPolicy(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	os.WriteFile(valid, []byte(`
+rules:
+  - name: no-public-ssh
+    class: security_group_ingress_added
+    cidrs: ["0.0.0.0/0", "::/0"]
+    ports: [22]
+`), 0644)
+	policy, err := LoadRemediationPolicy(valid)
+	if err != nil {
+		t.Fatalf("LoadRemediationPolicy failed: %v", err)
+	}
+	if policy.MaxActions != DefaultMaxRemediations || policy.Rules[0].Name != "no-public-ssh" || len(policy.Rules[0].CIDRs) != 2 {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+
+	for name, content := range map[string]string{
+		"unknown class": "rules:\n  - class: subnet_added\n",
+		"route ports":   "rules:\n  - class: route_added\n    ports: [22]\n",
+		"invalid cidr":  "rules:\n  - class: route_added\n    cidrs: [\"0.0.0.0\"]\n",
+		"no rules":      "max_actions: 5\n",
+	} {
+		file := filepath.Join(dir, "invalid.yaml")
+		os.WriteFile(file, []byte(content), 0644)
+		if _, err := LoadRemediationPolicy(file); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
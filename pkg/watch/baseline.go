@@ -0,0 +1,164 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/signing"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/statefile"
+)
+
+// baselineHTTPClient downloads baselines served over HTTP(S)
+var baselineHTTPClient = &http.Client{Timeout: time.Minute}
+
+// S3ObjectAPI is the subset of the S3 API used to read remote baselines
+type S3ObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// SetS3 sets the S3 client remote s3:// baselines are read with
+func (c *Comparator) SetS3(api S3ObjectAPI) {
+	c.s3 = api
+}
+
+// IsRemoteBaseline reports whether a baseline location is an s3:// or http(s):// URL
+// rather than a local file
+func IsRemoteBaseline(location string) bool {
+	for _, scheme := range []string{"s3://", "https://", "http://"} {
+		if strings.HasPrefix(strings.ToLower(location), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetVerifier requires baselines loaded with LoadBaseline to carry a valid
+// signature from the signer, kept next to them with a .sig suffix
+func (c *Comparator) SetVerifier(verifier signing.Signer) {
+	c.verifier = verifier
+}
+
+// LoadBaseline loads a working state from a local file, an s3://bucket/key URL
+// or an http(s):// URL, verifying its signature when a verifier is set. A bucket
+// outside the client's region is read with a ?region= query parameter, such as
+// s3://bucket/key?region=eu-west-1.
+func (c *Comparator) LoadBaseline(ctx context.Context, location string) (*scanner.Network, error) {
+	data, err := c.readBaseline(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.verifier != nil {
+		if err := c.verifyBaseline(ctx, location, data); err != nil {
+			return nil, err
+		}
+	}
+	// Signatures cover the file as saved, so compressed baselines are decompressed after verifying
+	if data, err = statefile.Decompress(data); err != nil {
+		return nil, fmt.Errorf("failed to decompress working state %s: %w", location, err)
+	}
+	return decodeWorkingState(data, location)
+}
+
+// verifyBaseline checks a baseline against its detached signature
+func (c *Comparator) verifyBaseline(ctx context.Context, location string, data []byte) error {
+	signatureLocation := signing.SignatureLocation(location)
+	signatureData, err := c.readBaseline(ctx, signatureLocation)
+	if err != nil {
+		return fmt.Errorf("failed to read signature of baseline %s: %w", location, err)
+	}
+
+	signature, err := signing.Parse(signatureData)
+	if err != nil {
+		return fmt.Errorf("invalid signature %s: %w", signatureLocation, err)
+	}
+	if err := c.verifier.Verify(ctx, data, signature); err != nil {
+		return fmt.Errorf("failed to verify baseline %s: %w", location, err)
+	}
+	return nil
+}
+
+// readBaseline reads the raw contents of a local file or remote URL
+func (c *Comparator) readBaseline(ctx context.Context, location string) ([]byte, error) {
+	if !IsRemoteBaseline(location) {
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read working state file %s: %w", location, err)
+		}
+		return data, nil
+	}
+
+	parsed, err := url.Parse(location)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid baseline URL %q", location)
+	}
+	if strings.EqualFold(parsed.Scheme, "s3") {
+		return c.readS3Baseline(ctx, parsed)
+	}
+	return readHTTPBaseline(ctx, location)
+}
+
+// readS3Baseline downloads a baseline object from S3
+func (c *Comparator) readS3Baseline(ctx context.Context, location *url.URL) ([]byte, error) {
+	bucket := location.Host
+	key := strings.TrimPrefix(location.Path, "/")
+	uri := fmt.Sprintf("s3://%s/%s", bucket, key)
+	if key == "" {
+		return nil, fmt.Errorf("invalid baseline URL %q, expected s3://bucket/key", location.String())
+	}
+	if c.s3 == nil {
+		return nil, fmt.Errorf("failed to read baseline %s: no S3 client configured", uri)
+	}
+
+	var optFns []func(*s3.Options)
+	if region := location.Query().Get("region"); region != "" {
+		optFns = append(optFns, func(o *s3.Options) { o.Region = region })
+	}
+
+	result, err := c.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	}, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline %s: %w", uri, err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline %s: %w", uri, err)
+	}
+	return data, nil
+}
+
+// readHTTPBaseline downloads a baseline served over HTTP(S)
+func readHTTPBaseline(ctx context.Context, location string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid baseline URL %q: %w", location, err)
+	}
+
+	response, err := baselineHTTPClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download baseline %s: %w", location, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download baseline %s: %s", location, response.Status)
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download baseline %s: %w", location, err)
+	}
+	return data, nil
+}
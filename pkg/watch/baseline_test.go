@@ -0,0 +1,163 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/signing"
+)
+
+const testBaselineJSON = `{"scan_time": "2024-01-01T00:00:00Z", "region": "us-east-1", "vpcs": [{"id": "vpc-1"}]}`
+
+type regionRecordingS3 struct {
+	fakeS3
+	region string
+}
+
+func (f *regionRecordingS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	options := s3.Options{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	f.region = options.Region
+	return f.fakeS3.GetObject(ctx, params, optFns...)
+}
+
+func TestIsRemoteBaseline(t *testing.T) {
+	tests := map[string]bool{
+		"working_state.json":                false,
+		"/tmp/baselines/prod.json":          false,
+		"s3://bucket/baseline.json":         true,
+		"S3://bucket/baseline.json":         true,
+		"https://example.com/baseline.json": true,
+		"http://localhost:8000/state.json":  true,
+	}
+	for location, want := range tests {
+		if got := IsRemoteBaseline(location); got != want {
+			t.Errorf("IsRemoteBaseline(%q) = %v, want %v", location, got, want)
+		}
+	}
+}
+
+func TestLoadBaselineS3(t *testing.T) {
+	api := &regionRecordingS3{fakeS3: fakeS3{objects: map[string][]byte{
+		"baselines/prod.json": []byte(testBaselineJSON),
+	}}}
+	comparator := NewComparator(false)
+	comparator.SetS3(api)
+
+	network, err := comparator.LoadBaseline(context.Background(), "s3://bucket/baselines/prod.json?region=eu-west-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(network.VPCs) != 1 || network.VPCs[0].ID != "vpc-1" {
+		t.Errorf("Expected the baseline's VPC, got %+v", network.VPCs)
+	}
+	if api.region != "eu-west-1" {
+		t.Errorf("Expected the bucket to be read in eu-west-1, got %q", api.region)
+	}
+
+	if _, err := comparator.LoadBaseline(context.Background(), "s3://bucket/missing.json"); err == nil {
+		t.Error("Expected an error for a missing object")
+	}
+	if _, err := comparator.LoadBaseline(context.Background(), "s3://bucket/"); err == nil {
+		t.Error("Expected an error for a URL without a key")
+	}
+	if _, err := NewComparator(false).LoadBaseline(context.Background(), "s3://bucket/baselines/prod.json"); err == nil {
+		t.Error("Expected an error without an S3 client")
+	}
+}
+
+func TestLoadBaselineHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/baseline.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testBaselineJSON))
+	}))
+	defer server.Close()
+
+	comparator := NewComparator(false)
+	network, err := comparator.LoadBaseline(context.Background(), server.URL+"/baseline.json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if network.Region != "us-east-1" || len(network.VPCs) != 1 {
+		t.Errorf("Expected the served baseline, got %+v", network)
+	}
+
+	if _, err := comparator.LoadBaseline(context.Background(), server.URL+"/missing.json"); err == nil {
+		t.Error("Expected an error for a 404 response")
+	}
+}
+
+func TestLoadBaselineLocalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := os.WriteFile(path, []byte(testBaselineJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	network, err := NewComparator(false).LoadBaseline(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(network.VPCs) != 1 {
+		t.Errorf("Expected the local baseline, got %+v", network)
+	}
+
+	// Baselines saved by a newer pikaatools fail like any other working state
+	if err := os.WriteFile(path, []byte(`{"schema_version": 999, "region": "us-east-1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewComparator(false).LoadBaseline(context.Background(), path)
+	var versionErr *scanner.SchemaVersionError
+	if !errors.As(err, &versionErr) || !strings.Contains(err.Error(), "upgrade pikaatools") {
+		t.Errorf("Expected a schema version error, got %v", err)
+	}
+}
+
+func TestLoadBaselineVerified(t *testing.T) {
+	signer, err := signing.NewHMACSigner([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "baseline.json")
+	if err := os.WriteFile(path, []byte(testBaselineJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	comparator := NewComparator(false)
+	comparator.SetVerifier(signer)
+
+	if _, err := comparator.LoadBaseline(ctx, path); err == nil {
+		t.Fatal("Expected an unsigned baseline to be rejected")
+	}
+
+	signature, _ := signer.Sign(ctx, []byte(testBaselineJSON))
+	encoded, _ := signature.Marshal()
+	if err := os.WriteFile(path+".sig", encoded, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comparator.LoadBaseline(ctx, path); err != nil {
+		t.Fatalf("Expected a signed baseline to load, got %v", err)
+	}
+
+	tampered := strings.Replace(testBaselineJSON, "vpc-1", "vpc-2", 1)
+	if err := os.WriteFile(path, []byte(tampered), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comparator.LoadBaseline(ctx, path); !errors.Is(err, signing.ErrInvalidSignature) {
+		t.Errorf("Expected a tampered baseline to be rejected, got %v", err)
+	}
+}
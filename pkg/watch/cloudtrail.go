@@ -0,0 +1,257 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctTypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// ec2EventSource is the CloudTrail event source of EC2 and VPC API calls
+	ec2EventSource = "ec2.amazonaws.com"
+	// iamEventSource is the CloudTrail event source of IAM API calls
+	iamEventSource = "iam.amazonaws.com"
+	// DefaultEventLookback is how far back each poll looks for events. CloudTrail
+	// usually delivers events within a few minutes of the call, so events are
+	// looked up again until they are older than this.
+	DefaultEventLookback = 15 * time.Minute
+	// lookupRetries is how many times a throttled LookupEvents page is retried.
+	// LookupEvents allows 2 requests per second per account and region.
+	lookupRetries = 5
+	// lookupBackoff is the wait before the first retry of a throttled page, doubled
+	// for each retry after it
+	lookupBackoff = time.Second
+)
+
+// ec2EventResourceTypes maps substrings of EC2 event names to the resource types
+// they change. The first match wins, so more specific names come first.
+var ec2EventResourceTypes = []struct {
+	match        string
+	resourceType string
+}{
+	{"TransitGateway", "transit_gateway"},
+	{"VpcPeeringConnection", "peering_connection"},
+	{"VpcEndpointService", "endpoint_service"},
+	{"VpcEndpoint", "vpc_endpoint"},
+	{"DhcpOptions", "dhcp_options"},
+	{"FlowLogs", "flow_log"},
+	{"NetworkAcl", "network_acl"},
+	{"NetworkInterface", "network_interface"},
+	{"NatGateway", "nat_gateway"},
+	{"EgressOnlyInternetGateway", "egress_only_internet_gateway"},
+	{"InternetGateway", "internet_gateway"},
+	{"Route", "route_table"},
+	{"SecurityGroup", "security_group"},
+	{"Subnet", "subnet"},
+	{"Vpc", "vpc"},
+}
+
+// ec2IDResourceTypes maps EC2 resource ID prefixes to resource types for tag events
+var ec2IDResourceTypes = []struct {
+	prefix       string
+	resourceType string
+}{
+	{"vpce-svc-", "endpoint_service"},
+	{"vpce-", "vpc_endpoint"},
+	{"tgw-", "transit_gateway"},
+	{"pcx-", "peering_connection"},
+	{"dopt-", "dhcp_options"},
+	{"fl-", "flow_log"},
+	{"acl-", "network_acl"},
+	{"eni-", "network_interface"},
+	{"nat-", "nat_gateway"},
+	{"eigw-", "egress_only_internet_gateway"},
+	{"igw-", "internet_gateway"},
+	{"rtb-", "route_table"},
+	{"sgr-", "security_group"},
+	{"sg-", "security_group"},
+	{"subnet-", "subnet"},
+	{"vpc-", "vpc"},
+}
+
+// cloudTrailEvents is the subset of the CloudTrail client used by ChangeTracker
+type cloudTrailEvents interface {
+	LookupEvents(ctx context.Context, params *cloudtrail.LookupEventsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.LookupEventsOutput, error)
+}
+
+// ChangeTracker finds the resource types changed since its last poll from the
+// EC2 and IAM management events in CloudTrail event history
+type ChangeTracker struct {
+	regional cloudTrailEvents
+	global   cloudTrailEvents
+	lookback time.Duration
+	seen     map[string]time.Time
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+// NewChangeTracker creates a tracker that looks up EC2 events with regional and
+// IAM events with global, the CloudTrail client of us-east-1. A nil global
+// client ignores IAM changes.
+func NewChangeTracker(regional, global cloudTrailEvents) *ChangeTracker {
+	return &ChangeTracker{
+		regional: regional,
+		global:   global,
+		lookback: DefaultEventLookback,
+		seen:     make(map[string]time.Time),
+		now:      time.Now,
+		sleep:    sleepContext,
+	}
+}
+
+// sleepContext waits for d, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Changes returns the sorted resource types changed by events that were not seen
+// by an earlier poll
+func (t *ChangeTracker) Changes(ctx context.Context) ([]string, error) {
+	now := t.now()
+	startTime := now.Add(-t.lookback)
+
+	// Forget events that are no longer looked up, even when this poll fails
+	for id, eventTime := range t.seen {
+		if eventTime.Before(startTime) {
+			delete(t.seen, id)
+		}
+	}
+
+	changed := make(map[string]bool)
+	if err := t.lookup(ctx, t.regional, ec2EventSource, startTime, changed); err != nil {
+		return nil, err
+	}
+	if t.global != nil {
+		if err := t.lookup(ctx, t.global, iamEventSource, startTime, changed); err != nil {
+			return nil, err
+		}
+	}
+
+	resourceTypes := make([]string, 0, len(changed))
+	for resourceType := range changed {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+	return resourceTypes, nil
+}
+
+// lookup adds the resource types changed by unseen write events from an event
+// source. LookupEvents filters on one attribute only, so write events are looked
+// up, which leaves out the far more numerous Describe calls, and events of other
+// sources are skipped.
+func (t *ChangeTracker) lookup(ctx context.Context, client cloudTrailEvents, eventSource string, startTime time.Time, changed map[string]bool) error {
+	input := &cloudtrail.LookupEventsInput{
+		StartTime: aws.Time(startTime),
+		LookupAttributes: []ctTypes.LookupAttribute{
+			{
+				AttributeKey:   ctTypes.LookupAttributeKeyReadOnly,
+				AttributeValue: aws.String("false"),
+			},
+		},
+	}
+
+	for {
+		result, err := t.lookupPage(ctx, client, input)
+		if err != nil {
+			return fmt.Errorf("failed to look up %s events: %w", eventSource, err)
+		}
+
+		for _, event := range result.Events {
+			id := aws.ToString(event.EventId)
+			if _, ok := t.seen[id]; ok || aws.ToString(event.ReadOnly) == "true" {
+				continue
+			}
+			if source := aws.ToString(event.EventSource); source != "" && source != eventSource {
+				continue
+			}
+			t.seen[id] = aws.ToTime(event.EventTime)
+
+			for _, resourceType := range eventResourceTypes(eventSource, event) {
+				changed[resourceType] = true
+			}
+		}
+
+		if result.NextToken == nil {
+			return nil
+		}
+		input.NextToken = result.NextToken
+	}
+}
+
+// lookupPage looks up one page of events, retrying with a growing wait while
+// LookupEvents is throttled
+func (t *ChangeTracker) lookupPage(ctx context.Context, client cloudTrailEvents, input *cloudtrail.LookupEventsInput) (*cloudtrail.LookupEventsOutput, error) {
+	backoff := lookupBackoff
+	for retry := 0; ; retry++ {
+		result, err := client.LookupEvents(ctx, input)
+		if err == nil || retry == lookupRetries || !isThrottling(err) {
+			return result, err
+		}
+		if err := t.sleep(ctx, backoff); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// isThrottling reports whether an API call failed because it was rate limited
+func isThrottling(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ThrottlingException", "Throttling", "RequestLimitExceeded", "TooManyRequestsException":
+		return true
+	}
+	return false
+}
+
+// eventResourceTypes returns the resource types a write event may have changed
+func eventResourceTypes(eventSource string, event ctTypes.Event) []string {
+	name := aws.ToString(event.EventName)
+
+	if eventSource == iamEventSource {
+		if strings.Contains(name, "Role") || strings.Contains(name, "Policy") || strings.Contains(name, "InstanceProfile") {
+			return []string{"iam_role"}
+		}
+		return nil
+	}
+
+	// Tag changes name the tagged resources
+	if name == "CreateTags" || name == "DeleteTags" {
+		var resourceTypes []string
+		for _, resource := range event.Resources {
+			id := aws.ToString(resource.ResourceName)
+			for _, idType := range ec2IDResourceTypes {
+				if strings.HasPrefix(id, idType.prefix) {
+					resourceTypes = append(resourceTypes, idType.resourceType)
+					break
+				}
+			}
+		}
+		return resourceTypes
+	}
+
+	for _, eventType := range ec2EventResourceTypes {
+		if strings.Contains(name, eventType.match) {
+			return []string{eventType.resourceType}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctTypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/smithy-go"
+)
+
+type fakeCloudTrail struct {
+	pages [][]ctTypes.Event
+	calls int
+	err   error
+	// throttled is how many calls fail with a throttling error before pages are returned
+	throttled int
+	inputs    []*cloudtrail.LookupEventsInput
+}
+
+func (f *fakeCloudTrail) LookupEvents(ctx context.Context, params *cloudtrail.LookupEventsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.LookupEventsOutput, error) {
+	f.inputs = append(f.inputs, params)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.throttled > 0 {
+		f.throttled--
+		return nil, &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	}
+	page := f.calls % len(f.pages)
+	f.calls++
+
+	output := &cloudtrail.LookupEventsOutput{Events: f.pages[page]}
+	if page < len(f.pages)-1 {
+		output.NextToken = aws.String("next")
+	}
+	return output, nil
+}
+
+func testTrailEvent(id, name string, readOnly bool, resources ...string) ctTypes.Event {
+	event := ctTypes.Event{
+		EventId:   aws.String(id),
+		EventName: aws.String(name),
+		EventTime: aws.Time(time.Now()),
+		ReadOnly:  aws.String("false"),
+	}
+	if readOnly {
+		event.ReadOnly = aws.String("true")
+	}
+	for _, resource := range resources {
+		event.Resources = append(event.Resources, ctTypes.Resource{ResourceName: aws.String(resource)})
+	}
+	return event
+}
+
+func TestChangeTracker(t *testing.T) {
+	regional := &fakeCloudTrail{pages: [][]ctTypes.Event{
+		{
+			testTrailEvent("1", "AuthorizeSecurityGroupIngress", false),
+			testTrailEvent("2", "DescribeSubnets", true),
+			testTrailEvent("3", "CreateTransitGatewayRoute", false),
+		},
+		{
+			testTrailEvent("4", "CreateTags", false, "subnet-1", "i-123"),
+			testTrailEvent("5", "RunInstances", false),
+		},
+	}}
+	global := &fakeCloudTrail{pages: [][]ctTypes.Event{
+		{
+			testTrailEvent("6", "AttachRolePolicy", false),
+			testTrailEvent("7", "CreateUser", false),
+		},
+	}}
+
+	tracker := NewChangeTracker(regional, global)
+	changes, err := tracker.Changes(context.Background())
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	expected := []string{"iam_role", "security_group", "subnet", "transit_gateway"}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected changes %v, got %v", expected, changes)
+	}
+
+	// Events seen by an earlier poll are not reported again
+	changes, err = tracker.Changes(context.Background())
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no new changes, got %v", changes)
+	}
+
+	// IAM events are ignored without a global client
+	tracker = NewChangeTracker(&fakeCloudTrail{pages: global.pages}, nil)
+	changes, err = tracker.Changes(context.Background())
+	if err != nil || len(changes) != 0 {
+		t.Errorf("Expected no changes without a global client, got %v, %v", changes, err)
+	}
+
+	tracker = NewChangeTracker(&fakeCloudTrail{err: errors.New("throttled")}, nil)
+	if _, err := tracker.Changes(context.Background()); err == nil {
+		t.Error("Expected lookup error")
+	}
+}
+
+func TestChangeTrackerForgetsOldEvents(t *testing.T) {
+	regional := &fakeCloudTrail{pages: [][]ctTypes.Event{{testTrailEvent("1", "CreateSubnet", false)}}}
+	tracker := NewChangeTracker(regional, nil)
+
+	if _, err := tracker.Changes(context.Background()); err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(tracker.seen) != 1 {
+		t.Fatalf("Expected 1 seen event, got %d", len(tracker.seen))
+	}
+
+	tracker.now = func() time.Time { return time.Now().Add(2 * DefaultEventLookback) }
+	regional.pages = [][]ctTypes.Event{{}}
+	if _, err := tracker.Changes(context.Background()); err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if len(tracker.seen) != 0 {
+		t.Errorf("Expected events outside the lookback to be forgotten, got %d", len(tracker.seen))
+	}
+}
+
+func TestChangeTrackerThrottlingAndSources(t *testing.T) {
+	s3Event := testTrailEvent("2", "PutBucketPolicy", false)
+	s3Event.EventSource = aws.String("s3.amazonaws.com")
+	ec2Event := testTrailEvent("1", "CreateSubnet", false)
+	ec2Event.EventSource = aws.String(ec2EventSource)
+	regional := &fakeCloudTrail{pages: [][]ctTypes.Event{{ec2Event, s3Event}}, throttled: 2}
+
+	tracker := NewChangeTracker(regional, nil)
+	var waits []time.Duration
+	tracker.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	// Throttled pages are retried with a growing wait
+	changes, err := tracker.Changes(context.Background())
+	if err != nil {
+		t.Fatalf("Changes failed: %v", err)
+	}
+	if !reflect.DeepEqual(changes, []string{"subnet"}) {
+		t.Errorf("Expected only the EC2 event's changes, got %v", changes)
+	}
+	if !reflect.DeepEqual(waits, []time.Duration{lookupBackoff, 2 * lookupBackoff}) {
+		t.Errorf("Expected two growing waits, got %v", waits)
+	}
+	if len(tracker.seen) != 1 {
+		t.Errorf("Expected only the EC2 event to be remembered, got %d", len(tracker.seen))
+	}
+
+	// Only write events are looked up
+	attributes := regional.inputs[0].LookupAttributes
+	if len(attributes) != 1 || attributes[0].AttributeKey != ctTypes.LookupAttributeKeyReadOnly || aws.ToString(attributes[0].AttributeValue) != "false" {
+		t.Errorf("Expected a ReadOnly=false lookup, got %+v", attributes)
+	}
+
+	// A lookup throttled past its retries fails the poll
+	regional.throttled = lookupRetries + 1
+	if _, err := tracker.Changes(context.Background()); err == nil {
+		t.Error("Expected an error after the retries run out")
+	}
+}
@@ -2,18 +2,29 @@ package watch
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/signing"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/statefile"
 )
 
 // Comparator compares two network states and reports differences
 type Comparator struct {
-	verbose bool
+	verbose  bool
+	ignore   *IgnoreRules
+	s3       S3ObjectAPI
+	verifier signing.Signer
+	// resourceTypes limits the differences reported, nil reports every resource type
+	resourceTypes map[string]bool
+	// remediation is the format of revert suggestions, empty for none
+	remediation RemediationFormat
 }
 
 // NewComparator creates a new network state comparator
@@ -23,18 +34,57 @@ func NewComparator(verbose bool) *Comparator {
 	}
 }
 
-// LoadWorkingState loads a working state from a JSON file
+// SetIgnoreRules sets the rules used to suppress known or expected differences
+func (c *Comparator) SetIgnoreRules(rules *IgnoreRules) {
+	c.ignore = rules
+}
+
+// SetResourceTypes only reports differences of the given resource types, such as
+// "SecurityGroup". Nil reports every resource type.
+func (c *Comparator) SetResourceTypes(resourceTypes []string) {
+	c.resourceTypes = nil
+	if resourceTypes != nil {
+		c.resourceTypes = make(map[string]bool)
+		for _, resourceType := range resourceTypes {
+			c.resourceTypes[resourceType] = true
+		}
+	}
+}
+
+// SetRemediation suggests commands in the given format that would revert
+// security group rule, route and network ACL entry changes. An empty format
+// suggests none.
+func (c *Comparator) SetRemediation(format RemediationFormat) {
+	c.remediation = format
+}
+
+// LoadWorkingState loads a working state from a JSON file, migrating states saved
+// with an older schema version
 func (c *Comparator) LoadWorkingState(filename string) (*scanner.Network, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read working state file %s: %w", filename, err)
 	}
+	if data, err = statefile.Decompress(data); err != nil {
+		return nil, fmt.Errorf("failed to decompress working state file %s: %w", filename, err)
+	}
+	return decodeWorkingState(data, filename)
+}
 
+// decodeWorkingState parses a decompressed working state, migrating states saved
+// with an older schema version and naming the schema of states saved by a newer
+// version of pikaatools
+func decodeWorkingState(data []byte, location string) (*scanner.Network, error) {
 	var network scanner.Network
-	err = json.Unmarshal(data, &network)
+	err := json.Unmarshal(data, &network)
+	var versionErr *scanner.SchemaVersionError
+	if errors.As(err, &versionErr) {
+		return nil, fmt.Errorf("working state %s was %w", location, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse working state JSON from %s: %w", filename, err)
+		return nil, fmt.Errorf("failed to parse working state JSON from %s: %w", location, err)
 	}
+	network.Sort()
 
 	return &network, nil
 }
@@ -67,15 +117,90 @@ func (c *Comparator) Compare(baseline, current *scanner.Network) []Difference {
 	// Compare Internet Gateways
 	differences = append(differences, c.compareInternetGateways(baseline.InternetGateways, current.InternetGateways)...)
 
+	// Compare Egress-only Internet Gateways
+	differences = append(differences, c.compareEgressOnlyInternetGateways(baseline.EgressOnlyInternetGateways, current.EgressOnlyInternetGateways)...)
+
 	// Compare NAT Gateways
 	differences = append(differences, c.compareNATGateways(baseline.NATGateways, current.NATGateways)...)
 
 	// Compare IAM Roles
 	differences = append(differences, c.compareIAMRoles(baseline.IAMRoles, current.IAMRoles)...)
 
+	// Compare DHCP Option Sets
+	differences = append(differences, c.compareDhcpOptions(baseline.DhcpOptions, current.DhcpOptions)...)
+
+	// Compare VPC Endpoint Services
+	differences = append(differences, c.compareEndpointServices(baseline.EndpointServices, current.EndpointServices)...)
+
+	// Compare EKS Clusters and ECS Services
+	differences = append(differences, c.compareEKSClusters(baseline.EKSClusters, current.EKSClusters)...)
+	differences = append(differences, c.compareECSServices(baseline.ECSServices, current.ECSServices)...)
+
+	// Compare Database Subnet Groups and Databases
+	differences = append(differences, c.compareDatabaseSubnetGroups(baseline.DatabaseSubnetGroups, current.DatabaseSubnetGroups)...)
+	differences = append(differences, c.compareDatabases(baseline.Databases, current.Databases)...)
+
+	// Compare Global Accelerator and CloudFront ingress
+	differences = append(differences, c.compareEdgeIngresses(baseline.EdgeIngresses, current.EdgeIngresses)...)
+
+	// Compare App Mesh Virtual Gateways
+	differences = append(differences, c.compareMeshVirtualGateways(baseline.MeshVirtualGateways, current.MeshVirtualGateways)...)
+
+	// Resource types either scan failed to scan would show up as removed or added
+	failed := failedResourceTypes(baseline, current)
+	if c.resourceTypes != nil || len(failed) > 0 {
+		selected := differences[:0]
+		for _, diff := range differences {
+			if (c.resourceTypes == nil || c.resourceTypes[diff.ResourceType]) && !failed[diff.ResourceType] {
+				selected = append(selected, diff)
+			}
+		}
+		differences = selected
+	}
+
+	if c.remediation != "" {
+		suggestRemediations(differences, baseline, current, c.remediation)
+	}
+
 	return differences
 }
 
+// scanErrorResourceTypes maps the resource types recorded in Network.ScanErrors to
+// the resource types of the differences comparing them reports
+var scanErrorResourceTypes = map[string][]string{
+	"dhcp_options":                 {"DhcpOptions"},
+	"subnet":                       {"Subnet"},
+	"peering_connection":           {"PeeringConnection"},
+	"transit_gateway":              {"TransitGateway"},
+	"internet_gateway":             {"InternetGateway"},
+	"egress_only_internet_gateway": {"EgressOnlyInternetGateway"},
+	"nat_gateway":                  {"NATGateway"},
+	"route_table":                  {"RouteTable"},
+	"security_group":               {"SecurityGroup"},
+	"network_acl":                  {"NetworkACL"},
+	"endpoint_service":             {"EndpointService"},
+	"iam_role":                     {"IAMRole"},
+	"eks_cluster":                  {"EKSCluster"},
+	"ecs_service":                  {"ECSService"},
+	"database":                     {"DatabaseSubnetGroup", "Database"},
+	"edge_ingress":                 {"EdgeIngress"},
+	"mesh_virtual_gateway":         {"MeshVirtualGateway"},
+}
+
+// failedResourceTypes returns the resource types of differences that cannot be
+// trusted because a baseline or current scan failed to scan them
+func failedResourceTypes(baseline, current *scanner.Network) map[string]bool {
+	failed := make(map[string]bool)
+	for _, network := range []*scanner.Network{baseline, current} {
+		for _, scanError := range network.ScanErrors {
+			for _, resourceType := range scanErrorResourceTypes[scanError.ResourceType] {
+				failed[resourceType] = true
+			}
+		}
+	}
+	return failed
+}
+
 // PrintDifferences prints differences in colored output
 func (c *Comparator) PrintDifferences(differences []Difference) {
 	if len(differences) == 0 {
@@ -93,11 +218,11 @@ func (c *Comparator) PrintDifferences(differences []Difference) {
 	for _, diff := range differences {
 		switch diff.Type {
 		case Added:
-			fmt.Printf("%s %s: %s %s\n", red("+ ADDED"), cyan(diff.ResourceType), yellow(diff.ResourceID), diff.Description)
+			fmt.Printf("%s %s: %s %s\n", red("+ ADDED"), cyan(diff.ResourceType), yellow(diff.displayID()), diff.Description)
 		case Removed:
-			fmt.Printf("%s %s: %s %s\n", red("- REMOVED"), cyan(diff.ResourceType), yellow(diff.ResourceID), diff.Description)
+			fmt.Printf("%s %s: %s %s\n", red("- REMOVED"), cyan(diff.ResourceType), yellow(diff.displayID()), diff.Description)
 		case Modified:
-			fmt.Printf("%s %s: %s %s\n", red("~ MODIFIED"), cyan(diff.ResourceType), yellow(diff.ResourceID), diff.Description)
+			fmt.Printf("%s %s: %s %s\n", red("~ MODIFIED"), cyan(diff.ResourceType), yellow(diff.displayID()), diff.Description)
 		}
 
 		if c.verbose && len(diff.Details) > 0 {
@@ -105,17 +230,37 @@ func (c *Comparator) PrintDifferences(differences []Difference) {
 				fmt.Printf("    %s\n", detail)
 			}
 		}
+		if len(diff.Remediation) > 0 {
+			fmt.Printf("    %s\n", cyan("To revert:"))
+			for _, suggestion := range diff.Remediation {
+				for _, line := range strings.Split(suggestion, "\n") {
+					fmt.Printf("      %s\n", line)
+				}
+			}
+		}
 	}
 	fmt.Println()
 }
 
 // Difference represents a difference between two network states
 type Difference struct {
-	Type         DifferenceType
-	ResourceType string
-	ResourceID   string
-	Description  string
-	Details      []string
+	Type         DifferenceType `json:"type"`
+	ResourceType string         `json:"resource_type"`
+	ResourceID   string         `json:"resource_id"`
+	ResourceName string         `json:"resource_name,omitempty"`
+	Description  string         `json:"description"`
+	Details      []string       `json:"details,omitempty"`
+	// Remediation holds the commands that would revert the difference, when
+	// suggestions are enabled with SetRemediation
+	Remediation []string `json:"remediation,omitempty"`
+}
+
+// displayID returns the resource ID with its resolved name, if any
+func (d Difference) displayID() string {
+	if d.ResourceName == "" {
+		return d.ResourceID
+	}
+	return fmt.Sprintf("%s (%s)", d.ResourceName, d.ResourceID)
 }
 
 // DifferenceType represents the type of difference
@@ -127,6 +272,45 @@ const (
 	Modified
 )
 
+// String returns the lowercase name of the difference type
+func (t DifferenceType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON encodes the difference type by name rather than by ordinal
+func (t DifferenceType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON decodes a difference type from its name
+func (t *DifferenceType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	switch name {
+	case "added":
+		*t = Added
+	case "removed":
+		*t = Removed
+	case "modified":
+		*t = Modified
+	default:
+		return fmt.Errorf("unknown difference type: %s", name)
+	}
+	return nil
+}
+
 // Helper functions for comparing different resource types
 func (c *Comparator) compareVPCs(baseline, current []scanner.VPC) []Difference {
 	return c.compareSlices("VPC", baseline, current, func(v interface{}) string { 
@@ -176,6 +360,12 @@ func (c *Comparator) compareInternetGateways(baseline, current []scanner.Interne
 	})
 }
 
+func (c *Comparator) compareEgressOnlyInternetGateways(baseline, current []scanner.EgressOnlyInternetGateway) []Difference {
+	return c.compareSlices("EgressOnlyInternetGateway", baseline, current, func(eigw interface{}) string {
+		return eigw.(scanner.EgressOnlyInternetGateway).ID
+	})
+}
+
 func (c *Comparator) compareNATGateways(baseline, current []scanner.NATGateway) []Difference {
 	return c.compareSlices("NATGateway", baseline, current, func(nat interface{}) string { 
 		return nat.(scanner.NATGateway).ID 
@@ -188,6 +378,54 @@ func (c *Comparator) compareIAMRoles(baseline, current []scanner.IAMRole) []Diff
 	})
 }
 
+func (c *Comparator) compareDhcpOptions(baseline, current []scanner.DhcpOptions) []Difference {
+	return c.compareSlices("DhcpOptions", baseline, current, func(options interface{}) string {
+		return options.(scanner.DhcpOptions).ID
+	})
+}
+
+func (c *Comparator) compareEndpointServices(baseline, current []scanner.EndpointService) []Difference {
+	return c.compareSlices("EndpointService", baseline, current, func(service interface{}) string {
+		return service.(scanner.EndpointService).ID
+	})
+}
+
+func (c *Comparator) compareEKSClusters(baseline, current []scanner.EKSCluster) []Difference {
+	return c.compareSlices("EKSCluster", baseline, current, func(cluster interface{}) string {
+		return cluster.(scanner.EKSCluster).Name
+	})
+}
+
+func (c *Comparator) compareECSServices(baseline, current []scanner.ECSService) []Difference {
+	return c.compareSlices("ECSService", baseline, current, func(service interface{}) string {
+		return service.(scanner.ECSService).Arn
+	})
+}
+
+func (c *Comparator) compareDatabaseSubnetGroups(baseline, current []scanner.DatabaseSubnetGroup) []Difference {
+	return c.compareSlices("DatabaseSubnetGroup", baseline, current, func(group interface{}) string {
+		return group.(scanner.DatabaseSubnetGroup).Service + ":" + group.(scanner.DatabaseSubnetGroup).Name
+	})
+}
+
+func (c *Comparator) compareDatabases(baseline, current []scanner.Database) []Difference {
+	return c.compareSlices("Database", baseline, current, func(database interface{}) string {
+		return database.(scanner.Database).Key()
+	})
+}
+
+func (c *Comparator) compareEdgeIngresses(baseline, current []scanner.EdgeIngress) []Difference {
+	return c.compareSlices("EdgeIngress", baseline, current, func(ingress interface{}) string {
+		return ingress.(scanner.EdgeIngress).ID
+	})
+}
+
+func (c *Comparator) compareMeshVirtualGateways(baseline, current []scanner.MeshVirtualGateway) []Difference {
+	return c.compareSlices("MeshVirtualGateway", baseline, current, func(gateway interface{}) string {
+		return gateway.(scanner.MeshVirtualGateway).Arn
+	})
+}
+
 // Generic slice comparison function  
 func (c *Comparator) compareSlices(resourceType string, baseline, current interface{}, getID func(interface{}) string) []Difference {
 	var differences []Difference
@@ -216,41 +454,48 @@ func (c *Comparator) compareSlices(resourceType string, baseline, current interf
 		currentMap[id] = item
 	}
 
+	// Report differences in ID order so the output is the same on every run
+	baselineIDs := sortedKeys(baselineMap)
+	currentIDs := sortedKeys(currentMap)
+
 	// Find added items
-	for id := range currentMap {
+	for _, id := range currentIDs {
+		currentItem := currentMap[id]
 		if _, exists := baselineMap[id]; !exists {
-			differences = append(differences, Difference{
+			differences = c.appendDifference(differences, Difference{
 				Type:         Added,
 				ResourceType: resourceType,
 				ResourceID:   id,
 				Description:  fmt.Sprintf("New %s created", strings.ToLower(resourceType)),
-			})
+			}, currentItem)
 		}
 	}
 
 	// Find removed items
-	for id := range baselineMap {
+	for _, id := range baselineIDs {
+		baselineItem := baselineMap[id]
 		if _, exists := currentMap[id]; !exists {
-			differences = append(differences, Difference{
+			differences = c.appendDifference(differences, Difference{
 				Type:         Removed,
 				ResourceType: resourceType,
 				ResourceID:   id,
 				Description:  fmt.Sprintf("%s was deleted", strings.ToLower(resourceType)),
-			})
+			}, baselineItem)
 		}
 	}
 
 	// Find modified items
-	for id, currentItem := range currentMap {
+	for _, id := range currentIDs {
+		currentItem := currentMap[id]
 		if baselineItem, exists := baselineMap[id]; exists {
 			if details := c.findObjectDifferences(baselineItem, currentItem); len(details) > 0 {
-				differences = append(differences, Difference{
+				differences = c.appendDifference(differences, Difference{
 					Type:         Modified,
 					ResourceType: resourceType,
 					ResourceID:   id,
 					Description:  fmt.Sprintf("%s configuration changed", strings.ToLower(resourceType)),
 					Details:      details,
-				})
+				}, currentItem)
 			}
 		}
 	}
@@ -258,6 +503,24 @@ func (c *Comparator) compareSlices(resourceType string, baseline, current interf
 	return differences
 }
 
+// sortedKeys returns the keys of a map of resources by ID in order
+func sortedKeys(items map[string]interface{}) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// appendDifference appends a difference unless it is suppressed by the ignore rules
+func (c *Comparator) appendDifference(differences []Difference, diff Difference, resource interface{}) []Difference {
+	if !c.ignore.Apply(&diff, resource) {
+		return differences
+	}
+	return append(differences, diff)
+}
+
 // findObjectDifferences compares two objects and returns a list of field differences
 func (c *Comparator) findObjectDifferences(baseline, current interface{}) []string {
 	var details []string
@@ -314,6 +577,8 @@ func (c *Comparator) compareStructs(baseline, current reflect.Value, path string
 				details = append(details, c.compareSlicesReflect(baselineField, currentField, fieldPath)...)
 			case reflect.Map:
 				details = append(details, c.compareMaps(baselineField, currentField, fieldPath)...)
+			case reflect.Ptr:
+				details = append(details, c.comparePointers(baselineField, currentField, fieldPath)...)
 			default:
 				details = append(details, fmt.Sprintf("%s: %v → %v", fieldPath, baselineField.Interface(), currentField.Interface()))
 			}
@@ -323,15 +588,45 @@ func (c *Comparator) compareStructs(baseline, current reflect.Value, path string
 	return details
 }
 
+// comparePointers compares the values two pointer fields point to, where nil is shown as none
+func (c *Comparator) comparePointers(baseline, current reflect.Value, path string) []string {
+	if !baseline.IsNil() && !current.IsNil() && baseline.Elem().Kind() == reflect.Struct {
+		return c.compareStructs(baseline.Elem(), current.Elem(), path)
+	}
+
+	format := func(v reflect.Value) interface{} {
+		if v.IsNil() {
+			return "none"
+		}
+		return v.Elem().Interface()
+	}
+	return []string{fmt.Sprintf("%s: %v → %v", path, format(baseline), format(current))}
+}
+
 func (c *Comparator) compareSlicesReflect(baseline, current reflect.Value, path string) []string {
 	var details []string
 
+	elemType := baseline.Type().Elem()
+
+	// Rules saved without IDs are matched by what they allow, with their IDs left
+	// out of the comparison
+	if rules, ok := baseline.Interface().([]scanner.SecurityGroupRule); ok && !securityGroupRuleIDsKnown(rules, current.Interface().([]scanner.SecurityGroupRule)) {
+		baseline = reflect.ValueOf(withoutRuleIDs(rules))
+		current = reflect.ValueOf(withoutRuleIDs(current.Interface().([]scanner.SecurityGroupRule)))
+	}
+
+	// Diff elements individually when they have a stable identity
+	if keyFunc, ok := sliceElementKeys[elemType]; ok {
+		return c.compareKeyedSlices(baseline, current, path, keyFunc)
+	}
+	if elemType.Kind() == reflect.String {
+		return c.compareStringSlices(baseline, current, path)
+	}
+
 	if baseline.Len() != current.Len() {
 		details = append(details, fmt.Sprintf("%s: length changed from %d to %d", path, baseline.Len(), current.Len()))
 	}
 
-	// For simplicity, we'll just note that the slice changed if lengths differ
-	// More sophisticated comparison could be implemented for specific slice types
 	if baseline.Len() != current.Len() || !reflect.DeepEqual(baseline.Interface(), current.Interface()) {
 		details = append(details, fmt.Sprintf("%s: slice contents changed", path))
 	}
@@ -339,17 +634,262 @@ func (c *Comparator) compareSlicesReflect(baseline, current reflect.Value, path
 	return details
 }
 
+// sliceElementKeys maps slice element types to a function returning the element's identity,
+// so elements can be matched between baseline and current regardless of their order
+var sliceElementKeys = map[reflect.Type]func(reflect.Value) string{
+	reflect.TypeOf(scanner.SecurityGroupRule{}): func(v reflect.Value) string {
+		return securityGroupRuleIdentity(v.Interface().(scanner.SecurityGroupRule))
+	},
+	reflect.TypeOf(scanner.SecurityGroupReference{}): func(v reflect.Value) string {
+		return v.Interface().(scanner.SecurityGroupReference).GroupID
+	},
+	reflect.TypeOf(scanner.Route{}): func(v reflect.Value) string {
+		return v.Interface().(scanner.Route).Destination()
+	},
+	reflect.TypeOf(scanner.NetworkAclEntry{}): func(v reflect.Value) string {
+		return networkAclEntryKey(v.Interface().(scanner.NetworkAclEntry))
+	},
+	reflect.TypeOf(scanner.TransitGatewayAttachment{}): func(v reflect.Value) string {
+		return v.Interface().(scanner.TransitGatewayAttachment).ID
+	},
+	reflect.TypeOf(scanner.TransitGatewayRouteTable{}): func(v reflect.Value) string {
+		return v.Interface().(scanner.TransitGatewayRouteTable).ID
+	},
+	reflect.TypeOf(scanner.TransitGatewayRoute{}): func(v reflect.Value) string {
+		return v.Interface().(scanner.TransitGatewayRoute).Destination()
+	},
+	reflect.TypeOf(scanner.IAMPolicy{}): func(v reflect.Value) string {
+		return v.Interface().(scanner.IAMPolicy).Arn
+	},
+	reflect.TypeOf(scanner.IAMInstanceProfile{}): func(v reflect.Value) string {
+		return v.Interface().(scanner.IAMInstanceProfile).Arn
+	},
+	reflect.TypeOf(scanner.IAMInlinePolicy{}): func(v reflect.Value) string {
+		return v.Interface().(scanner.IAMInlinePolicy).PolicyName
+	},
+}
+
+// sliceElementSummaries maps slice element types to a function describing an added or
+// removed element when its identity alone does not say what it is
+var sliceElementSummaries = map[reflect.Type]func(reflect.Value) string{
+	reflect.TypeOf(scanner.SecurityGroupRule{}): func(v reflect.Value) string {
+		return securityGroupRuleKey(v.Interface().(scanner.SecurityGroupRule))
+	},
+	reflect.TypeOf(scanner.Route{}): func(v reflect.Value) string {
+		return v.Interface().(scanner.Route).Target()
+	},
+}
+
+// securityGroupRuleIdentity identifies a security group rule by its ID, so rules
+// scanned with their IDs keep their identity when edited in place, or otherwise
+// by what it allows
+func securityGroupRuleIdentity(rule scanner.SecurityGroupRule) string {
+	if rule.ID != "" {
+		return rule.ID
+	}
+	return securityGroupRuleKey(rule)
+}
+
+// securityGroupRuleIDsKnown reports whether the rules of both a baseline and
+// current scan were saved with their IDs. Baselines saved before rules were
+// scanned with their IDs have none, and their rules can only be matched by what
+// they allow.
+func securityGroupRuleIDsKnown(baseline, current []scanner.SecurityGroupRule) bool {
+	for _, rules := range [][]scanner.SecurityGroupRule{baseline, current} {
+		known := len(rules) == 0
+		for _, rule := range rules {
+			if rule.ID != "" {
+				known = true
+			}
+		}
+		if !known {
+			return false
+		}
+	}
+	return true
+}
+
+// securityGroupRuleIdentities returns the function identifying the rules of a
+// baseline and current scan: by ID when both sides have them, or otherwise by
+// what they allow
+func securityGroupRuleIdentities(baseline, current []scanner.SecurityGroupRule) func(scanner.SecurityGroupRule) string {
+	if securityGroupRuleIDsKnown(baseline, current) {
+		return securityGroupRuleIdentity
+	}
+	return securityGroupRuleKey
+}
+
+// withoutRuleIDs returns a copy of rules with their IDs cleared
+func withoutRuleIDs(rules []scanner.SecurityGroupRule) []scanner.SecurityGroupRule {
+	if rules == nil {
+		return nil
+	}
+	stripped := make([]scanner.SecurityGroupRule, len(rules))
+	for i, rule := range rules {
+		rule.ID = ""
+		stripped[i] = rule
+	}
+	return stripped
+}
+
+// networkAclEntryKey identifies a network ACL entry by its direction and rule number
+func networkAclEntryKey(entry scanner.NetworkAclEntry) string {
+	direction := "ingress"
+	if entry.Egress {
+		direction = "egress"
+	}
+	return fmt.Sprintf("%s #%d", direction, entry.RuleNumber)
+}
+
+// securityGroupRuleKey identifies a security group rule by what it allows
+func securityGroupRuleKey(rule scanner.SecurityGroupRule) string {
+	ports := fmt.Sprintf("%d-%d", rule.FromPort, rule.ToPort)
+	if rule.IpProtocol == "-1" {
+		ports = "all"
+	}
+
+	var sources []string
+	sources = append(sources, rule.CidrBlocks...)
+	sources = append(sources, rule.Ipv6CidrBlocks...)
+	sources = append(sources, rule.PrefixListIds...)
+	sources = append(sources, rule.ReferencedGroupIDs()...)
+	sort.Strings(sources)
+
+	return fmt.Sprintf("%s %s %s", rule.IpProtocol, ports, strings.Join(sources, ","))
+}
+
+// compareKeyedSlices matches slice elements by identity and reports added, removed and modified elements
+func (c *Comparator) compareKeyedSlices(baseline, current reflect.Value, path string, keyFunc func(reflect.Value) string) []string {
+	var details []string
+
+	baselineMap := make(map[string]reflect.Value)
+	var baselineKeys []string
+	for i := 0; i < baseline.Len(); i++ {
+		key := keyFunc(baseline.Index(i))
+		if _, exists := baselineMap[key]; !exists {
+			baselineKeys = append(baselineKeys, key)
+		}
+		baselineMap[key] = baseline.Index(i)
+	}
+
+	currentMap := make(map[string]reflect.Value)
+	var currentKeys []string
+	for i := 0; i < current.Len(); i++ {
+		key := keyFunc(current.Index(i))
+		if _, exists := currentMap[key]; !exists {
+			currentKeys = append(currentKeys, key)
+		}
+		currentMap[key] = current.Index(i)
+	}
+
+	// Elements sharing a key would hide each other in the maps
+	if len(baselineKeys) != baseline.Len() || len(currentKeys) != current.Len() {
+		return c.compareSliceMultisets(baseline, current, path, keyFunc)
+	}
+
+	for _, key := range baselineKeys {
+		if _, exists := currentMap[key]; !exists {
+			details = append(details, fmt.Sprintf("%s[%s]: removed%s", path, key, elementSummary(baselineMap[key], key)))
+		}
+	}
+
+	for _, key := range currentKeys {
+		currentItem := currentMap[key]
+		baselineItem, exists := baselineMap[key]
+		if !exists {
+			details = append(details, fmt.Sprintf("%s[%s]: added%s", path, key, elementSummary(currentItem, key)))
+		} else if !reflect.DeepEqual(baselineItem.Interface(), currentItem.Interface()) {
+			details = append(details, c.compareStructs(baselineItem, currentItem, fmt.Sprintf("%s[%s]", path, key))...)
+		}
+	}
+
+	return details
+}
+
+// compareSliceMultisets matches slice elements by their whole value, for slices
+// whose elements do not have unique keys, and reports the unmatched elements as
+// removed and added
+func (c *Comparator) compareSliceMultisets(baseline, current reflect.Value, path string, keyFunc func(reflect.Value) string) []string {
+	var details []string
+
+	matched := make([]bool, current.Len())
+	for i := 0; i < baseline.Len(); i++ {
+		found := false
+		for j := 0; j < current.Len(); j++ {
+			if !matched[j] && reflect.DeepEqual(baseline.Index(i).Interface(), current.Index(j).Interface()) {
+				matched[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			key := keyFunc(baseline.Index(i))
+			details = append(details, fmt.Sprintf("%s[%s]: removed%s", path, key, elementSummary(baseline.Index(i), key)))
+		}
+	}
+
+	for j := 0; j < current.Len(); j++ {
+		if !matched[j] {
+			key := keyFunc(current.Index(j))
+			details = append(details, fmt.Sprintf("%s[%s]: added%s", path, key, elementSummary(current.Index(j), key)))
+		}
+	}
+
+	return details
+}
+
+// elementSummary returns the summary of a slice element to follow its added or removed
+// line, or "" when the element has no summary beyond its key
+func elementSummary(v reflect.Value, key string) string {
+	summaryFunc, ok := sliceElementSummaries[v.Type()]
+	if !ok {
+		return ""
+	}
+	if summary := summaryFunc(v); summary != key {
+		return fmt.Sprintf(" (%s)", summary)
+	}
+	return ""
+}
+
+// compareStringSlices reports elements added to or removed from a slice of strings
+func (c *Comparator) compareStringSlices(baseline, current reflect.Value, path string) []string {
+	var details []string
+
+	baselineSet := make(map[string]bool)
+	for i := 0; i < baseline.Len(); i++ {
+		baselineSet[baseline.Index(i).String()] = true
+	}
+
+	currentSet := make(map[string]bool)
+	for i := 0; i < current.Len(); i++ {
+		currentSet[current.Index(i).String()] = true
+	}
+
+	for i := 0; i < baseline.Len(); i++ {
+		if value := baseline.Index(i).String(); !currentSet[value] {
+			details = append(details, fmt.Sprintf("%s: removed %s", path, value))
+		}
+	}
+
+	for i := 0; i < current.Len(); i++ {
+		if value := current.Index(i).String(); !baselineSet[value] {
+			details = append(details, fmt.Sprintf("%s: added %s", path, value))
+		}
+	}
+
+	return details
+}
+
 func (c *Comparator) compareMaps(baseline, current reflect.Value, path string) []string {
 	var details []string
 
 	// Check for added/removed keys
-	for _, key := range baseline.MapKeys() {
+	for _, key := range sortedMapKeys(baseline) {
 		if !current.MapIndex(key).IsValid() {
 			details = append(details, fmt.Sprintf("%s[%v]: key removed", path, key.Interface()))
 		}
 	}
 
-	for _, key := range current.MapKeys() {
+	for _, key := range sortedMapKeys(current) {
 		baselineValue := baseline.MapIndex(key)
 		currentValue := current.MapIndex(key)
 
@@ -363,9 +903,20 @@ func (c *Comparator) compareMaps(baseline, current reflect.Value, path string) [
 	return details
 }
 
+// sortedMapKeys returns the keys of a map in order, such as tag keys
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
 // shouldSkipField determines if a field should be skipped during comparison
 func (c *Comparator) shouldSkipField(fieldName string) bool {
-	skipFields := []string{"ScanTime", "CreateDate", "UpdateDate"}
+	// RunningCount follows deployments and scaling rather than configuration,
+	// TotalIPs follows the CIDR block
+	skipFields := []string{"ScanTime", "CreateDate", "UpdateDate", "RunningCount", "TotalIPs"}
 	for _, skip := range skipFields {
 		if fieldName == skip {
 			return true
@@ -2,7 +2,11 @@ package watch
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -72,6 +76,22 @@ func TestLoadWorkingState(t *testing.T) {
 	}
 }
 
+func TestLoadWorkingStateNewerSchema(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "working_state.json")
+	if err := os.WriteFile(filename, []byte(`{"schema_version": 999, "region": "us-east-1"}`), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	_, err := NewComparator(false).LoadWorkingState(filename)
+	var versionErr *scanner.SchemaVersionError
+	if !errors.As(err, &versionErr) || versionErr.Version != 999 {
+		t.Fatalf("Expected a schema version error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "upgrade pikaatools") {
+		t.Errorf("Expected the error to say to upgrade, got %q", err)
+	}
+}
+
 func TestLoadNonExistentFile(t *testing.T) {
 	comparator := NewComparator(false)
 	_, err := comparator.LoadWorkingState("non_existent_file.json")
@@ -282,4 +302,477 @@ func TestShouldSkipField(t *testing.T) {
 			t.Errorf("Expected field %s not to be skipped", field)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestCompareSecurityGroupRulesElementWise(t *testing.T) {
+	baseline := &scanner.Network{
+		SecurityGroups: []scanner.SecurityGroup{
+			{
+				ID: "sg-12345",
+				IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"10.0.0.0/8"}},
+					{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}, Description: "https"},
+				},
+			},
+		},
+	}
+
+	current := &scanner.Network{
+		SecurityGroups: []scanner.SecurityGroup{
+			{
+				ID: "sg-12345",
+				IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}, Description: "public https"},
+					{IpProtocol: "tcp", FromPort: 3389, ToPort: 3389, CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+			},
+		},
+	}
+
+	comparator := NewComparator(false)
+	differences := comparator.Compare(baseline, current)
+
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+
+	expected := []string{
+		"IngressRules[tcp 22-22 10.0.0.0/8]: removed",
+		"IngressRules[tcp 443-443 0.0.0.0/0].Description: https → public https",
+		"IngressRules[tcp 3389-3389 0.0.0.0/0]: added",
+	}
+
+	details := differences[0].Details
+	if len(details) != len(expected) {
+		t.Fatalf("Expected %d details, got %v", len(expected), details)
+	}
+
+	for i, detail := range expected {
+		if details[i] != detail {
+			t.Errorf("Expected detail %q, got %q", detail, details[i])
+		}
+	}
+}
+
+func TestCompareSecurityGroupRulesByID(t *testing.T) {
+	baseline := &scanner.Network{
+		SecurityGroups: []scanner.SecurityGroup{
+			{
+				ID: "sg-12345",
+				IngressRules: []scanner.SecurityGroupRule{
+					{ID: "sgr-1", IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"10.0.0.0/8"}},
+					{ID: "sgr-2", IpProtocol: "tcp", FromPort: 80, ToPort: 80, CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+			},
+		},
+	}
+
+	current := &scanner.Network{
+		SecurityGroups: []scanner.SecurityGroup{
+			{
+				ID: "sg-12345",
+				IngressRules: []scanner.SecurityGroupRule{
+					{ID: "sgr-1", IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"10.1.0.0/16"}},
+					{ID: "sgr-3", IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+			},
+		},
+	}
+
+	differences := NewComparator(false).Compare(baseline, current)
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+
+	// A rule edited in place keeps its ID, so only the changed field is reported
+	expected := []string{
+		"IngressRules[sgr-2]: removed (tcp 80-80 0.0.0.0/0)",
+		"IngressRules[sgr-1].CidrBlocks: removed 10.0.0.0/8",
+		"IngressRules[sgr-1].CidrBlocks: added 10.1.0.0/16",
+		"IngressRules[sgr-3]: added (tcp 443-443 0.0.0.0/0)",
+	}
+	if !reflect.DeepEqual(differences[0].Details, expected) {
+		t.Errorf("Expected details %v, got %v", expected, differences[0].Details)
+	}
+}
+
+func TestCompareSecurityGroupRulesWithoutIDs(t *testing.T) {
+	// Baselines saved before rules were scanned with their IDs have none
+	baseline := &scanner.Network{
+		SecurityGroups: []scanner.SecurityGroup{
+			{
+				ID: "sg-12345",
+				IngressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"10.0.0.0/8"}},
+					{IpProtocol: "tcp", FromPort: 80, ToPort: 80, CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+				EgressRules: []scanner.SecurityGroupRule{
+					{IpProtocol: "-1", CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+			},
+		},
+	}
+
+	current := &scanner.Network{
+		SecurityGroups: []scanner.SecurityGroup{
+			{
+				ID: "sg-12345",
+				IngressRules: []scanner.SecurityGroupRule{
+					{ID: "sgr-2", IpProtocol: "tcp", FromPort: 80, ToPort: 80, CidrBlocks: []string{"0.0.0.0/0"}},
+					{ID: "sgr-1", IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"10.0.0.0/8"}},
+				},
+				EgressRules: []scanner.SecurityGroupRule{
+					{ID: "sgr-3", IpProtocol: "-1", CidrBlocks: []string{"0.0.0.0/0"}},
+				},
+			},
+		},
+	}
+
+	comparator := NewComparator(false)
+	if differences := comparator.Compare(baseline, current); len(differences) != 0 {
+		t.Fatalf("Expected no differences, got %+v", differences)
+	}
+
+	// Changed rules are reported by what they allow
+	current.SecurityGroups[0].IngressRules[0].CidrBlocks = []string{"10.0.0.0/16"}
+	differences := comparator.Compare(baseline, current)
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+	expected := []string{
+		"IngressRules[tcp 80-80 0.0.0.0/0]: removed",
+		"IngressRules[tcp 80-80 10.0.0.0/16]: added",
+	}
+	if !reflect.DeepEqual(differences[0].Details, expected) {
+		t.Errorf("Expected details %v, got %v", expected, differences[0].Details)
+	}
+}
+
+func TestCompareSkipsResourceTypesThatFailedToScan(t *testing.T) {
+	baseline := &scanner.Network{
+		EKSClusters:         []scanner.EKSCluster{{Name: "prod", VpcID: "vpc-1"}},
+		MeshVirtualGateways: []scanner.MeshVirtualGateway{{Arn: "arn:aws:appmesh:us-east-1:123456789012:mesh/payments/virtualGateway/ingress"}},
+		NATGateways:         []scanner.NATGateway{{ID: "nat-1"}},
+	}
+	current := &scanner.Network{
+		ScanErrors: []scanner.ScanError{
+			{ResourceType: "eks_cluster", Error: "AccessDeniedException"},
+			{ResourceType: "mesh_virtual_gateway", Error: "AccessDeniedException"},
+		},
+	}
+
+	// Only the NAT gateway, which was scanned, is reported as removed
+	differences := NewComparator(false).Compare(baseline, current)
+	if len(differences) != 1 || differences[0].ResourceType != "NATGateway" || differences[0].Type != Removed {
+		t.Errorf("Expected only the NAT gateway to be removed, got %+v", differences)
+	}
+
+	// A baseline saved without them does not report them as added either
+	differences = NewComparator(false).Compare(current, baseline)
+	if len(differences) != 1 || differences[0].ResourceType != "NATGateway" || differences[0].Type != Added {
+		t.Errorf("Expected only the NAT gateway to be added, got %+v", differences)
+	}
+}
+
+func TestCompareRoutesByDestination(t *testing.T) {
+	baseline := &scanner.Network{
+		RouteTables: []scanner.RouteTable{
+			{
+				ID: "rtb-12345",
+				Routes: []scanner.Route{
+					{DestinationCidr: "10.0.0.0/16", GatewayID: "local"},
+					{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-12345"},
+				},
+				Associations: []string{"subnet-a", "subnet-b"},
+			},
+		},
+	}
+
+	current := &scanner.Network{
+		RouteTables: []scanner.RouteTable{
+			{
+				ID: "rtb-12345",
+				Routes: []scanner.Route{
+					{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-67890"},
+					{DestinationCidr: "10.0.0.0/16", GatewayID: "local"},
+				},
+				Associations: []string{"subnet-b", "subnet-c"},
+			},
+		},
+	}
+
+	comparator := NewComparator(false)
+	differences := comparator.Compare(baseline, current)
+
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+
+	expected := []string{
+		"Routes[0.0.0.0/0].GatewayID: igw-12345 → igw-67890",
+		"Associations: removed subnet-a",
+		"Associations: added subnet-c",
+	}
+
+	details := differences[0].Details
+	if len(details) != len(expected) {
+		t.Fatalf("Expected %d details, got %v", len(expected), details)
+	}
+
+	for i, detail := range expected {
+		if details[i] != detail {
+			t.Errorf("Expected detail %q, got %q", detail, details[i])
+		}
+	}
+}
+
+func TestCompareRoutesWithoutDestinationCidr(t *testing.T) {
+	routeTable := func(routes ...scanner.Route) *scanner.Network {
+		return &scanner.Network{RouteTables: []scanner.RouteTable{{ID: "rtb-12345", Routes: routes}}}
+	}
+	local := scanner.Route{DestinationCidr: "10.0.0.0/16", GatewayID: "local"}
+	s3 := scanner.Route{DestinationPrefixListID: "pl-63a5400a", GatewayID: "vpce-s3"}
+	dynamodb := scanner.Route{DestinationPrefixListID: "pl-02cd2c6b", GatewayID: "vpce-dynamodb"}
+
+	// Gateway endpoint routes are keyed by their destination prefix list
+	differences := NewComparator(false).Compare(routeTable(local, s3, dynamodb), routeTable(local, s3))
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+	expected := []string{"Routes[pl-02cd2c6b]: removed (vpce-dynamodb)"}
+	if details := differences[0].Details; len(details) != 1 || details[0] != expected[0] {
+		t.Errorf("Expected details %v, got %v", expected, details)
+	}
+
+	// Routes sharing a key, as in states saved without prefix list IDs, are
+	// matched by value
+	s3.DestinationPrefixListID, dynamodb.DestinationPrefixListID = "", ""
+	differences = NewComparator(false).Compare(routeTable(local, s3, dynamodb), routeTable(local, s3))
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+	expected = []string{"Routes[]: removed (vpce-dynamodb)"}
+	if details := differences[0].Details; len(details) != 1 || details[0] != expected[0] {
+		t.Errorf("Expected details %v, got %v", expected, details)
+	}
+}
+
+func TestCompareTransitGatewayRoutes(t *testing.T) {
+	tgw := func(routes ...scanner.TransitGatewayRoute) *scanner.Network {
+		return &scanner.Network{
+			TransitGateways: []scanner.TransitGateway{
+				{ID: "tgw-12345", RouteTables: []scanner.TransitGatewayRouteTable{{ID: "tgw-rtb-12345", Routes: routes}}},
+			},
+		}
+	}
+
+	baseline := tgw(
+		scanner.TransitGatewayRoute{DestinationCidr: "10.1.0.0/16", AttachmentID: "tgw-attach-a", State: "active"},
+		scanner.TransitGatewayRoute{DestinationCidr: "10.2.0.0/16", AttachmentID: "tgw-attach-b", State: "active"},
+	)
+	current := tgw(
+		scanner.TransitGatewayRoute{DestinationCidr: "10.2.0.0/16", State: "blackhole"},
+		scanner.TransitGatewayRoute{DestinationCidr: "10.1.0.0/16", AttachmentID: "tgw-attach-a", State: "active"},
+	)
+
+	differences := NewComparator(false).Compare(baseline, current)
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+
+	expected := []string{
+		"RouteTables[tgw-rtb-12345].Routes[10.2.0.0/16].AttachmentID: tgw-attach-b → ",
+		"RouteTables[tgw-rtb-12345].Routes[10.2.0.0/16].State: active → blackhole",
+	}
+	details := differences[0].Details
+	if len(details) != len(expected) {
+		t.Fatalf("Expected %d details, got %v", len(expected), details)
+	}
+	for i, detail := range expected {
+		if details[i] != detail {
+			t.Errorf("Expected detail %q, got %q", detail, details[i])
+		}
+	}
+}
+
+func TestCompareDhcpOptions(t *testing.T) {
+	baseline := &scanner.Network{
+		DhcpOptions: []scanner.DhcpOptions{
+			{ID: "dopt-12345", DomainName: "corp.example.com", DomainNameServers: []string{"10.0.0.2"}},
+		},
+	}
+	current := &scanner.Network{
+		DhcpOptions: []scanner.DhcpOptions{
+			{ID: "dopt-12345", DomainName: "corp.example.com", DomainNameServers: []string{"AmazonProvidedDNS"}},
+		},
+	}
+
+	differences := NewComparator(false).Compare(baseline, current)
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+	if differences[0].Type != Modified || differences[0].ResourceType != "DhcpOptions" || differences[0].ResourceID != "dopt-12345" {
+		t.Errorf("Unexpected difference: %+v", differences[0])
+	}
+}
+
+func TestCompareEndpointServicePrincipals(t *testing.T) {
+	baseline := &scanner.Network{
+		EndpointServices: []scanner.EndpointService{
+			{ID: "vpce-svc-12345", AllowedPrincipals: []string{"arn:aws:iam::111111111111:root"}},
+		},
+	}
+	current := &scanner.Network{
+		EndpointServices: []scanner.EndpointService{
+			{ID: "vpce-svc-12345", AllowedPrincipals: []string{"arn:aws:iam::111111111111:root", "*"}},
+		},
+	}
+
+	differences := NewComparator(false).Compare(baseline, current)
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+	if differences[0].Type != Modified || differences[0].ResourceType != "EndpointService" {
+		t.Errorf("Unexpected difference: %+v", differences[0])
+	}
+}
+
+func TestCompareECSServices(t *testing.T) {
+	arn := "arn:aws:ecs:us-east-1:123456789012:service/web/api"
+	baseline := &scanner.Network{
+		ECSServices: []scanner.ECSService{
+			{Arn: arn, Name: "api", RunningCount: 2, SecurityGroupIDs: []string{"sg-api"}},
+		},
+	}
+	current := &scanner.Network{
+		ECSServices: []scanner.ECSService{
+			{Arn: arn, Name: "api", RunningCount: 3, SecurityGroupIDs: []string{"sg-api", "sg-debug"}},
+		},
+	}
+
+	differences := NewComparator(false).Compare(baseline, current)
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+	for _, detail := range differences[0].Details {
+		if strings.Contains(detail, "RunningCount") {
+			t.Errorf("Expected the running task count to be ignored, got %q", detail)
+		}
+	}
+	if differences[0].ResourceType != "ECSService" || len(differences[0].Details) != 1 {
+		t.Errorf("Unexpected difference: %+v", differences[0])
+	}
+}
+
+func TestCompareDatabases(t *testing.T) {
+	baseline := &scanner.Network{
+		Databases: []scanner.Database{
+			{ID: "orders", Service: "rds", Engine: "postgres", SecurityGroupIDs: []string{"sg-db"}},
+			{ID: "orders", Service: "elasticache", Engine: "redis", SecurityGroupIDs: []string{"sg-cache"}},
+		},
+	}
+	current := &scanner.Network{
+		Databases: []scanner.Database{
+			{ID: "orders", Service: "rds", Engine: "postgres", SecurityGroupIDs: []string{"sg-db", "sg-open"}},
+			{ID: "orders", Service: "elasticache", Engine: "redis", SecurityGroupIDs: []string{"sg-cache"}},
+		},
+	}
+
+	differences := NewComparator(false).Compare(baseline, current)
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+	if differences[0].Type != Modified || differences[0].ResourceType != "Database" || differences[0].ResourceID != "rds:orders" {
+		t.Errorf("Unexpected difference: %+v", differences[0])
+	}
+}
+
+func TestCompareEdgeIngresses(t *testing.T) {
+	alb := scanner.EdgeTarget{ID: "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/abc", Type: "application"}
+	eip := scanner.EdgeTarget{ID: "eipalloc-1", Type: "eip"}
+	baseline := &scanner.Network{
+		EdgeIngresses: []scanner.EdgeIngress{
+			{ID: "E1", Service: "cloudfront", Targets: []scanner.EdgeTarget{alb}},
+		},
+	}
+	current := &scanner.Network{
+		EdgeIngresses: []scanner.EdgeIngress{
+			{ID: "E1", Service: "cloudfront", Targets: []scanner.EdgeTarget{alb, eip}},
+		},
+	}
+
+	differences := NewComparator(false).Compare(baseline, current)
+	if len(differences) != 1 {
+		t.Fatalf("Expected 1 difference, got %d", len(differences))
+	}
+	if differences[0].Type != Modified || differences[0].ResourceType != "EdgeIngress" || differences[0].ResourceID != "E1" {
+		t.Errorf("Unexpected difference: %+v", differences[0])
+	}
+}
+
+func TestCompareReorderedSliceIsNotModified(t *testing.T) {
+	baseline := &scanner.Network{
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", Subnets: []string{"subnet-a", "subnet-b"}},
+		},
+	}
+
+	current := &scanner.Network{
+		VPCs: []scanner.VPC{
+			{ID: "vpc-12345", Subnets: []string{"subnet-b", "subnet-a"}},
+		},
+	}
+
+	comparator := NewComparator(false)
+	differences := comparator.Compare(baseline, current)
+
+	if len(differences) != 0 {
+		t.Errorf("Expected reordering not to be reported, got %v", differences)
+	}
+}
+
+func TestCompareSubnetCapacity(t *testing.T) {
+	before, after := 120, 100
+	baseline := &scanner.Network{
+		Subnets: []scanner.Subnet{{ID: "subnet-1", CidrBlock: "10.0.1.0/24", AvailableIPs: &before}},
+	}
+	current := &scanner.Network{
+		Subnets: []scanner.Subnet{{ID: "subnet-1", CidrBlock: "10.0.1.0/24", TotalIPs: 251, AvailableIPs: &after}},
+	}
+
+	differences := NewComparator(false).Compare(baseline, current)
+	if len(differences) != 1 || len(differences[0].Details) != 1 || differences[0].Details[0] != "AvailableIPs: 120 → 100" {
+		t.Fatalf("Expected only the shrinking available address count, got %+v", differences)
+	}
+
+	baseline.Subnets[0].AvailableIPs = nil
+	differences = NewComparator(false).Compare(baseline, current)
+	if len(differences) != 1 || differences[0].Details[0] != "AvailableIPs: none → 100" {
+		t.Errorf("Expected a count missing from the baseline to be shown as none, got %+v", differences)
+	}
+}
+
+func TestCompareOrderIsStable(t *testing.T) {
+	baseline := &scanner.Network{}
+	current := &scanner.Network{}
+	for _, id := range []string{"vpc-c", "vpc-a", "vpc-e", "vpc-b", "vpc-d"} {
+		current.VPCs = append(current.VPCs, scanner.VPC{ID: id, Tags: map[string]string{"Name": id}})
+		baseline.VPCs = append(baseline.VPCs, scanner.VPC{ID: id, Tags: map[string]string{"Team": "a", "Env": "b", "Owner": "c"}})
+	}
+
+	comparator := NewComparator(false)
+	var first []string
+	for run := 0; run < 10; run++ {
+		var got []string
+		for _, diff := range comparator.Compare(baseline, current) {
+			got = append(got, diff.ResourceID+" "+strings.Join(diff.Details, ";"))
+		}
+		if run == 0 {
+			first = got
+			if !strings.HasPrefix(got[0], "vpc-a Tags[Env]: key removed;Tags[Owner]: key removed;Tags[Team]: key removed;Tags[Name]") {
+				t.Fatalf("Expected differences and details in order, got %v", got)
+			}
+		} else if strings.Join(got, "|") != strings.Join(first, "|") {
+			t.Fatalf("Expected the same order on every run, got %v and %v", first, got)
+		}
+	}
+}
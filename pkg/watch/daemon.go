@@ -0,0 +1,143 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/schedule"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// DaemonOptions configure a long-running daemon
+type DaemonOptions struct {
+	// Schedule decides when scans run
+	Schedule *schedule.Schedule
+	// Store receives a snapshot of every scan
+	Store *SnapshotStore
+	// Compare reports the differences between each scan and the previous snapshot
+	Compare bool
+	// RunOnStart scans once at startup before waiting for the schedule
+	RunOnStart bool
+}
+
+// daemonState is the previous snapshot a daemon compares against
+type daemonState struct {
+	previous    *scanner.Network
+	previousKey string
+	// loaded records that the store has been asked for its latest snapshot
+	loaded bool
+}
+
+// Daemon scans on a schedule until stopped, writing each scan to the snapshot
+// store and, with Compare, reporting drift from the previous snapshot
+func (w *Watcher) Daemon(ctx context.Context, options DaemonOptions) error {
+	if options.Schedule == nil || options.Store == nil {
+		return fmt.Errorf("daemon needs a schedule and a snapshot store")
+	}
+
+	w.scanner.SetVerbose(w.verbose)
+
+	state := &daemonState{}
+	if options.RunOnStart {
+		if err := w.daemonScan(ctx, options, state); err != nil && ctx.Err() == nil {
+			w.logger.Error("scan failed", "error", err)
+		}
+	}
+
+	for {
+		next := options.Schedule.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q never runs", options.Schedule)
+		}
+		w.logger.Info("next scan scheduled", "at", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			w.logger.Info("daemon stopped")
+			return nil
+
+		case <-timer.C:
+			if err := w.daemonScan(ctx, options, state); err != nil && ctx.Err() == nil {
+				w.logger.Error("scan failed", "error", err)
+				// Keep running even if one scan fails
+			}
+		}
+	}
+}
+
+// daemonScan scans once, writes the snapshot and compares it to the previous one
+func (w *Watcher) daemonScan(ctx context.Context, options DaemonOptions, state *daemonState) error {
+	scanStart := time.Now()
+	current, err := w.scanner.ScanNetwork(ctx, w.vpcID)
+	if err != nil {
+		if w.metrics != nil {
+			w.metrics.RecordFailure()
+		}
+		return fmt.Errorf("failed to scan network: %w", err)
+	}
+	scanDuration := time.Since(scanStart)
+
+	// The first comparison is against the snapshot an earlier run left behind
+	if options.Compare && !state.loaded {
+		previous, key, err := options.Store.Latest(ctx, current.AccountID, current.Region)
+		if err != nil {
+			w.logger.Warn("failed to load the previous snapshot", "error", err)
+		} else {
+			state.previous, state.previousKey, state.loaded = previous, key, true
+		}
+	}
+
+	key, err := options.Store.Put(ctx, current)
+	if err != nil {
+		return err
+	}
+	w.logger.Info("wrote snapshot", "uri", options.Store.URI(key), "duration", scanDuration.Round(time.Millisecond))
+
+	var differences []Difference
+	if options.Compare && state.previous != nil {
+		baseline := state.previous
+
+		// IAM roles in the previous snapshot are only compared when they are scanned
+		if !w.scanIAM && len(baseline.IAMRoles) > 0 {
+			withoutIAM := *baseline
+			withoutIAM.IAMRoles = nil
+			baseline = &withoutIAM
+		}
+
+		differences = w.comparator.Compare(baseline, current)
+		w.resolveNames(ctx, differences)
+		w.logger.Info("compared with the previous snapshot", "previous", options.Store.URI(state.previousKey), "differences", len(differences))
+
+		info := HTMLReportInfo{Region: w.region, VpcID: w.vpcID, Baseline: options.Store.URI(state.previousKey), Current: options.Store.URI(key)}
+		reportURL := w.writeReports(ctx, differences, info, scanStart)
+
+		if len(differences) > 0 {
+			if w.diffOutput == "" {
+				w.printDifferences(differences, scanDuration)
+			}
+			event := NewDriftEvent(w.region, w.vpcID, differences)
+			event.Baseline = options.Store.URI(state.previousKey)
+			event.ReportURL = reportURL
+			w.notify(ctx, event)
+		}
+
+		if w.diffOutput != "" {
+			report, err := FormatDifferences(differences, w.diffOutput)
+			if err != nil {
+				return err
+			}
+			fmt.Print(report)
+		}
+	}
+
+	if w.metrics != nil {
+		w.metrics.RecordScan(current, differences, scanDuration)
+	}
+
+	w.last = current
+	state.previous, state.previousKey = current, key
+	return nil
+}
@@ -0,0 +1,111 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// maxDesktopDifferences is how many differences a desktop notification lists
+const maxDesktopDifferences = 3
+
+// DesktopNotifier shows drift events as desktop notifications, with osascript on
+// macOS, notify-send on Linux and PowerShell on Windows
+type DesktopNotifier struct {
+	minSeverity Severity
+	goos        string
+	// start runs a command without waiting for it to exit
+	start func(name string, args ...string) error
+}
+
+// NewDesktopNotifier creates a notifier that shows a desktop notification when a
+// drift event has differences of minSeverity or above
+func NewDesktopNotifier(minSeverity Severity) *DesktopNotifier {
+	return &DesktopNotifier{
+		minSeverity: minSeverity,
+		goos:        runtime.GOOS,
+		start:       startCommand,
+	}
+}
+
+// startCommand starts a command and reaps it in the background, so a notification
+// that stays on screen does not hold up the watcher
+func startCommand(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// Notify shows the differences of the drift event at or above the minimum severity
+func (n *DesktopNotifier) Notify(ctx context.Context, event DriftEvent) error {
+	alert := newPageAlert(event, n.minSeverity)
+	if alert == nil {
+		return nil
+	}
+	selected, highest := alert.differences, alert.severity
+
+	title := fmt.Sprintf("pikaatools: %d network differences (%s severity)", len(selected), highest)
+	location := event.Region
+	if event.VpcID != "" {
+		location += " " + event.VpcID
+	}
+	lines := []string{"Network drift in " + location}
+	for i, diff := range selected {
+		if i == maxDesktopDifferences {
+			lines = append(lines, fmt.Sprintf("and %d more", len(selected)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s", diff.ResourceType, diff.displayID(), diff.Type))
+	}
+	body := strings.Join(lines, "\n")
+
+	name, args, err := n.command(title, body, highest)
+	if err != nil {
+		return err
+	}
+	if err := n.start(name, args...); err != nil {
+		return fmt.Errorf("failed to show desktop notification with %s: %w", name, err)
+	}
+	return nil
+}
+
+// command returns the command that shows a notification on the operating system
+func (n *DesktopNotifier) command(title, body string, severity Severity) (string, []string, error) {
+	switch n.goos {
+	case "darwin":
+		// Passed as arguments so the text needs no AppleScript quoting
+		return "osascript", []string{
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body,
+		}, nil
+	case "windows":
+		script := fmt.Sprintf(`Add-Type -AssemblyName System.Windows.Forms
+$icon = New-Object System.Windows.Forms.NotifyIcon
+$icon.Icon = [System.Drawing.SystemIcons]::Warning
+$icon.Visible = $true
+$icon.ShowBalloonTip(10000, %s, %s, 'Warning')
+Start-Sleep -Seconds 10
+$icon.Dispose()`, powerShellQuote(title), powerShellQuote(body))
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		urgency := "normal"
+		if severity == SeverityHigh {
+			urgency = "critical"
+		}
+		return "notify-send", []string{"--app-name=pikaatools", "--urgency=" + urgency, title, body}, nil
+	default:
+		return "", nil, fmt.Errorf("desktop notifications are not supported on %s", n.goos)
+	}
+}
+
+// powerShellQuote quotes s as a single-quoted PowerShell string
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,76 @@
+package watch
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type recordedCommand struct {
+	name string
+	args []string
+}
+
+func newTestDesktopNotifier(goos string, minSeverity Severity, commands *[]recordedCommand) *DesktopNotifier {
+	n := NewDesktopNotifier(minSeverity)
+	n.goos = goos
+	n.start = func(name string, args ...string) error {
+		*commands = append(*commands, recordedCommand{name: name, args: args})
+		return nil
+	}
+	return n
+}
+
+func TestDesktopNotifier(t *testing.T) {
+	event := NewDriftEvent("us-east-1", "vpc-1", []Difference{
+		{Type: Added, ResourceType: "Subnet", ResourceID: "subnet-1"},
+		{Type: Modified, ResourceType: "SecurityGroup", ResourceID: "sg-1", ResourceName: "web"},
+	})
+
+	var commands []recordedCommand
+	if err := newTestDesktopNotifier("linux", SeverityMedium, &commands).Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	want := []string{"--app-name=pikaatools", "--urgency=critical", "pikaatools: 1 network differences (high severity)",
+		"Network drift in us-east-1 vpc-1\nSecurityGroup web (sg-1) modified"}
+	if len(commands) != 1 || commands[0].name != "notify-send" || !reflect.DeepEqual(commands[0].args, want) {
+		t.Errorf("commands = %+v, want notify-send %q", commands, want)
+	}
+
+	// Nothing is shown below the threshold
+	commands = nil
+	low := NewDriftEvent("us-east-1", "", []Difference{{Type: Added, ResourceType: "Subnet", ResourceID: "subnet-1"}})
+	if err := newTestDesktopNotifier("linux", SeverityMedium, &commands).Notify(context.Background(), low); err != nil || len(commands) != 0 {
+		t.Errorf("Notify ran %+v with error %v, want nothing shown", commands, err)
+	}
+
+	// macOS passes the text as arguments and Windows quotes it
+	commands = nil
+	if err := newTestDesktopNotifier("darwin", SeverityLow, &commands).Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if args := commands[0].args; commands[0].name != "osascript" || !strings.HasSuffix(args[len(args)-1], "SecurityGroup web (sg-1) modified") {
+		t.Errorf("osascript command = %+v", commands[0])
+	}
+	if got := powerShellQuote("it's"); got != "'it''s'" {
+		t.Errorf("powerShellQuote = %s, want 'it''s'", got)
+	}
+
+	if err := newTestDesktopNotifier("plan9", SeverityLow, &commands).Notify(context.Background(), event); err == nil {
+		t.Error("Notify succeeded on an unsupported operating system")
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	severity, err := ParseSeverity("High")
+	if err != nil || severity != SeverityHigh {
+		t.Errorf("ParseSeverity(High) = %s, %v", severity, err)
+	}
+	if !SeverityHigh.AtLeast(SeverityMedium) || SeverityLow.AtLeast(SeverityMedium) {
+		t.Error("AtLeast does not order severities")
+	}
+	if _, err := ParseSeverity("critical"); err == nil {
+		t.Error("ParseSeverity accepted an unknown severity")
+	}
+}
@@ -0,0 +1,173 @@
+package watch
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Digest summarizes the drift events of a period, such as a day or a week of a
+// drift log, for teams that review drift asynchronously
+type Digest struct {
+	Since  time.Time
+	Until  time.Time
+	Events int
+	// Resources are the changed resources, most severe first
+	Resources []DigestResource
+}
+
+// DigestResource is a resource that differed from its baseline in one or more
+// scans of a digest's period
+type DigestResource struct {
+	Region   string
+	Severity Severity
+	// Latest is the resource's difference in the last scan that found one
+	Latest    Difference
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Scans is how many scans found the resource differing
+	Scans int
+}
+
+// NewDigest aggregates drift events detected between since and until, read
+// from a drift log, into one entry per resource
+func NewDigest(events []DriftEvent, since, until time.Time) *Digest {
+	digest := &Digest{Since: since, Until: until, Events: len(events)}
+
+	byResource := make(map[string]*DigestResource)
+	var keys []string
+	for _, event := range events {
+		for _, diff := range event.Differences {
+			key := event.Region + " " + diff.ResourceType + " " + diff.ResourceID
+			resource, ok := byResource[key]
+			if !ok {
+				resource = &DigestResource{Region: event.Region, FirstSeen: event.DetectedAt, Severity: SeverityLow}
+				byResource[key] = resource
+				keys = append(keys, key)
+			}
+			resource.Scans++
+			if !event.DetectedAt.Before(resource.LastSeen) {
+				resource.Latest = diff
+				resource.LastSeen = event.DetectedAt
+			}
+			if event.DetectedAt.Before(resource.FirstSeen) {
+				resource.FirstSeen = event.DetectedAt
+			}
+			if severity := DifferenceSeverity(diff); severity.AtLeast(resource.Severity) {
+				resource.Severity = severity
+			}
+		}
+	}
+
+	for _, key := range keys {
+		digest.Resources = append(digest.Resources, *byResource[key])
+	}
+	sort.SliceStable(digest.Resources, func(i, j int) bool {
+		a, b := digest.Resources[i], digest.Resources[j]
+		if a.Severity != b.Severity {
+			return severityRanks[a.Severity] > severityRanks[b.Severity]
+		}
+		if a.Latest.ResourceType != b.Latest.ResourceType {
+			return a.Latest.ResourceType < b.Latest.ResourceType
+		}
+		return a.Latest.ResourceID < b.Latest.ResourceID
+	})
+	return digest
+}
+
+// severityCounts returns how many resources of each severity the digest has
+func (d *Digest) severityCounts() map[Severity]int {
+	counts := make(map[Severity]int)
+	for _, resource := range d.Resources {
+		counts[resource.Severity]++
+	}
+	return counts
+}
+
+// period describes the digest's time window
+func (d *Digest) period() string {
+	const layout = "2006-01-02 15:04 MST"
+	if d.Since.IsZero() {
+		return "until " + d.Until.Local().Format(layout)
+	}
+	return d.Since.Local().Format(layout) + " to " + d.Until.Local().Format(layout)
+}
+
+// Subject returns the email subject of the digest
+func (d *Digest) Subject() string {
+	day := d.Until.Local().Format("2006-01-02")
+	if len(d.Resources) == 0 {
+		return fmt.Sprintf("pikaatools drift digest %s: no drift", day)
+	}
+	counts := d.severityCounts()
+	return fmt.Sprintf("pikaatools drift digest %s: %d resources changed (%d high severity)", day, len(d.Resources), counts[SeverityHigh])
+}
+
+// note describes when a resource was seen differing
+func (r DigestResource) note() string {
+	const layout = "2006-01-02 15:04"
+	if r.Scans == 1 {
+		return fmt.Sprintf("Region %s, seen once at %s", r.Region, r.LastSeen.Local().Format(layout))
+	}
+	return fmt.Sprintf("Region %s, seen in %d scans from %s to %s", r.Region, r.Scans,
+		r.FirstSeen.Local().Format(layout), r.LastSeen.Local().Format(layout))
+}
+
+// Text formats the digest as plain text
+func (d *Digest) Text() string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Network drift digest, %s\n\n", d.period()))
+	if len(d.Resources) == 0 {
+		result.WriteString("No drift recorded.\n")
+		return result.String()
+	}
+
+	counts := d.severityCounts()
+	result.WriteString(fmt.Sprintf("%d resources changed in %d scans with drift: %d high, %d medium and %d low severity.\n",
+		len(d.Resources), d.Events, counts[SeverityHigh], counts[SeverityMedium], counts[SeverityLow]))
+	for _, resource := range d.Resources {
+		diff := resource.Latest
+		result.WriteString(fmt.Sprintf("\n[%s] %s %s %s: %s\n", strings.ToUpper(string(resource.Severity)), strings.ToUpper(diff.Type.String()),
+			diff.ResourceType, diff.displayID(), diff.Description))
+		result.WriteString(fmt.Sprintf("  %s\n", resource.note()))
+		for _, detail := range diff.Details {
+			result.WriteString(fmt.Sprintf("    %s\n", detail))
+		}
+	}
+	return result.String()
+}
+
+// HTML formats the digest as an HTML page in the style of the HTML drift report
+func (d *Digest) HTML() string {
+	var result strings.Builder
+	title := "Network Drift Digest"
+
+	result.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	result.WriteString(fmt.Sprintf("<title>%s</title>\n", title))
+	result.WriteString(fmt.Sprintf("<style>\n%s\n</style>\n</head>\n<body>\n", htmlReportStyle))
+	result.WriteString(fmt.Sprintf("<h1>%s</h1>\n", title))
+	result.WriteString(fmt.Sprintf("<p class=\"meta\">%s</p>\n", html.EscapeString(d.period())))
+
+	if len(d.Resources) == 0 {
+		result.WriteString("<p class=\"ok\">✓ No drift recorded</p>\n</body>\n</html>\n")
+		return result.String()
+	}
+
+	counts := d.severityCounts()
+	result.WriteString("<table>\n")
+	result.WriteString(fmt.Sprintf("<tr><th>Resources changed</th><td>%d</td></tr>\n", len(d.Resources)))
+	result.WriteString(fmt.Sprintf("<tr><th>Scans with drift</th><td>%d</td></tr>\n", d.Events))
+	for _, severity := range []Severity{SeverityHigh, SeverityMedium, SeverityLow} {
+		result.WriteString(fmt.Sprintf("<tr><th><span class=\"badge %s\">%s</span></th><td>%d</td></tr>\n", severity, severity, counts[severity]))
+	}
+	result.WriteString("</table>\n")
+
+	for _, resource := range d.Resources {
+		writeHTMLDifference(&result, resource.Latest, resource.Severity, resource.note())
+	}
+
+	result.WriteString("</body>\n</html>\n")
+	return result.String()
+}
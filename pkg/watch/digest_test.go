@@ -0,0 +1,84 @@
+package watch
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func digestEvents() []DriftEvent {
+	first := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	return []DriftEvent{
+		{Region: "us-east-1", DetectedAt: first, Differences: []Difference{
+			{Type: Added, ResourceType: "Subnet", ResourceID: "subnet-1", Description: "Subnet added"},
+			{Type: Modified, ResourceType: "SecurityGroup", ResourceID: "sg-1", Description: "Security group modified", Details: []string{"GroupName: web → web-old"}},
+		}},
+		{Region: "us-east-1", DetectedAt: second, Differences: []Difference{
+			{Type: Modified, ResourceType: "SecurityGroup", ResourceID: "sg-1", Description: "Security group modified", Details: []string{"GroupName: web → web-new"}},
+		}},
+	}
+}
+
+func TestNewDigest(t *testing.T) {
+	until := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+	digest := NewDigest(digestEvents(), until.Add(-24*time.Hour), until)
+
+	if digest.Events != 2 {
+		t.Errorf("expected 2 events, got %d", digest.Events)
+	}
+	if len(digest.Resources) != 2 {
+		t.Fatalf("expected 2 resources, got %d", len(digest.Resources))
+	}
+
+	sg := digest.Resources[0]
+	if sg.Latest.ResourceID != "sg-1" || sg.Severity != SeverityHigh {
+		t.Fatalf("expected the high severity security group first, got %+v", sg)
+	}
+	if sg.Scans != 2 {
+		t.Errorf("expected the security group in 2 scans, got %d", sg.Scans)
+	}
+	if sg.Latest.Details[0] != "GroupName: web → web-new" {
+		t.Errorf("expected the latest difference, got %v", sg.Latest.Details)
+	}
+	if !sg.FirstSeen.Equal(digestEvents()[0].DetectedAt) || !sg.LastSeen.Equal(digestEvents()[1].DetectedAt) {
+		t.Errorf("unexpected first and last seen %v, %v", sg.FirstSeen, sg.LastSeen)
+	}
+
+	if subnet := digest.Resources[1]; subnet.Latest.ResourceID != "subnet-1" || subnet.Severity != SeverityLow || subnet.Scans != 1 {
+		t.Errorf("unexpected subnet entry %+v", subnet)
+	}
+}
+
+func TestDigestFormats(t *testing.T) {
+	until := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+	digest := NewDigest(digestEvents(), until.Add(-24*time.Hour), until)
+
+	if subject := digest.Subject(); !strings.Contains(subject, "2 resources changed (1 high severity)") {
+		t.Errorf("unexpected subject %q", subject)
+	}
+
+	text := digest.Text()
+	for _, want := range []string{"2 resources changed in 2 scans with drift", "[HIGH] MODIFIED SecurityGroup sg-1", "seen in 2 scans", "GroupName: web → web-new"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected text digest to contain %q:\n%s", want, text)
+		}
+	}
+
+	page := digest.HTML()
+	for _, want := range []string{"<h1>Network Drift Digest</h1>", "<div class=\"diff high\">", "web-new", "seen in 2 scans"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected HTML digest to contain %q", want)
+		}
+	}
+}
+
+func TestDigestEmpty(t *testing.T) {
+	digest := NewDigest(nil, time.Time{}, time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC))
+	if !strings.HasSuffix(digest.Subject(), "no drift") {
+		t.Errorf("unexpected subject %q", digest.Subject())
+	}
+	if !strings.Contains(digest.Text(), "No drift recorded.") {
+		t.Errorf("unexpected text %q", digest.Text())
+	}
+}
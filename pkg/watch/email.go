@@ -0,0 +1,144 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	sesTypes "github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+// EmailMessage is an email with plain text and HTML versions of its body
+type EmailMessage struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Bytes encodes the message as a multipart/alternative MIME message
+func (m EmailMessage) Bytes() ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		writer, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode email: %w", err)
+		}
+		encoder := quotedprintable.NewWriter(writer)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, fmt.Errorf("failed to encode email: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode email: %w", err)
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode email: %w", err)
+	}
+
+	var message bytes.Buffer
+	headers := [][2]string{
+		{"From", m.From},
+		{"To", strings.Join(m.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", m.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", parts.Boundary())},
+	}
+	for _, header := range headers {
+		message.WriteString(header[0] + ": " + header[1] + "\r\n")
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// EmailSender delivers email messages
+type EmailSender interface {
+	Send(ctx context.Context, message EmailMessage) error
+}
+
+// SMTPSender sends email through an SMTP server, upgrading the connection with
+// STARTTLS when the server offers it
+type SMTPSender struct {
+	addr     string
+	username string
+	password string
+}
+
+// NewSMTPSender creates a sender for the SMTP server at addr (host:port). Without
+// a username messages are sent unauthenticated.
+func NewSMTPSender(addr, username, password string) *SMTPSender {
+	return &SMTPSender{addr: addr, username: username, password: password}
+}
+
+// Send sends the message to its recipients
+func (s *SMTPSender) Send(ctx context.Context, message EmailMessage) error {
+	data, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.username != "" {
+		host, _, err := net.SplitHostPort(s.addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP server %q, expected host:port: %w", s.addr, err)
+		}
+		auth = smtp.PlainAuth("", s.username, s.password, host)
+	}
+	if err := smtp.SendMail(s.addr, auth, message.From, message.To, data); err != nil {
+		return fmt.Errorf("failed to send email through %s: %w", s.addr, err)
+	}
+	return nil
+}
+
+// sesAPI is the subset of the SES v2 API used by SESSender
+type sesAPI interface {
+	SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
+}
+
+// SESSender sends email through Amazon SES
+type SESSender struct {
+	api sesAPI
+}
+
+// NewSESSender creates a sender using the SES v2 API
+func NewSESSender(client *sesv2.Client) *SESSender {
+	return &SESSender{api: client}
+}
+
+// Send sends the message to its recipients as a raw MIME message
+func (s *SESSender) Send(ctx context.Context, message EmailMessage) error {
+	data, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.api.SendEmail(ctx, &sesv2.SendEmailInput{
+		FromEmailAddress: &message.From,
+		Destination:      &sesTypes.Destination{ToAddresses: message.To},
+		Content:          &sesTypes.EmailContent{Raw: &sesTypes.RawMessage{Data: data}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send email through SES: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package watch
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+)
+
+func TestEmailMessageBytes(t *testing.T) {
+	message := EmailMessage{
+		From:    "drift@example.com",
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "Drift digest",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	}
+	data, err := message.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	if to := parsed.Header.Get("To"); to != "a@example.com, b@example.com" {
+		t.Errorf("unexpected To %q", to)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %q: %v", parsed.Header.Get("Content-Type"), err)
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid part: %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		bodies = append(bodies, string(body))
+	}
+	if len(bodies) != 2 || bodies[0] != message.Text || bodies[1] != message.HTML {
+		t.Errorf("unexpected parts %q", bodies)
+	}
+}
+
+// fakeSES records the emails sent through it
+type fakeSES struct {
+	inputs []*sesv2.SendEmailInput
+}
+
+func (f *fakeSES) SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error) {
+	f.inputs = append(f.inputs, params)
+	return &sesv2.SendEmailOutput{}, nil
+}
+
+func TestSESSenderSend(t *testing.T) {
+	api := &fakeSES{}
+	sender := &SESSender{api: api}
+	message := EmailMessage{From: "drift@example.com", To: []string{"a@example.com"}, Subject: "Drift digest", Text: "body"}
+	if err := sender.Send(context.Background(), message); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if len(api.inputs) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(api.inputs))
+	}
+	input := api.inputs[0]
+	if *input.FromEmailAddress != "drift@example.com" || input.Destination.ToAddresses[0] != "a@example.com" {
+		t.Errorf("unexpected addresses %+v", input)
+	}
+	if !strings.Contains(string(input.Content.Raw.Data), "Subject: Drift digest") {
+		t.Errorf("expected a raw message with the subject, got %s", input.Content.Raw.Data)
+	}
+}
@@ -0,0 +1,139 @@
+package watch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxHistoryLine is the longest drift log line read back, large enough for
+// scans that find thousands of differences
+const maxHistoryLine = 64 * 1024 * 1024
+
+// HistoryLog is a notifier that appends each drift event to a JSONL drift log,
+// one event per line, for reviewing when each change appeared
+type HistoryLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewHistoryLog creates a notifier that appends drift events to the file at path
+func NewHistoryLog(path string) *HistoryLog {
+	return &HistoryLog{path: path}
+}
+
+// Notify appends the drift event to the log
+func (h *HistoryLog) Notify(ctx context.Context, event DriftEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal drift event: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open drift log %s: %w", h.path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write drift log %s: %w", h.path, err)
+	}
+	return nil
+}
+
+// HistoryFilter selects drift log entries. Zero fields match everything.
+type HistoryFilter struct {
+	Since        time.Time
+	Until        time.Time
+	ResourceType string
+	ResourceID   string
+}
+
+// matches returns the event with only the differences the filter selects, and
+// whether any are left
+func (f HistoryFilter) matches(event DriftEvent) (DriftEvent, bool) {
+	if !f.Since.IsZero() && event.DetectedAt.Before(f.Since) {
+		return event, false
+	}
+	if !f.Until.IsZero() && event.DetectedAt.After(f.Until) {
+		return event, false
+	}
+	if f.ResourceType == "" && f.ResourceID == "" {
+		return event, true
+	}
+
+	var differences []Difference
+	for _, diff := range event.Differences {
+		if f.ResourceType != "" && !strings.EqualFold(diff.ResourceType, f.ResourceType) {
+			continue
+		}
+		if f.ResourceID != "" && diff.ResourceID != f.ResourceID {
+			continue
+		}
+		differences = append(differences, diff)
+	}
+	event.Differences = differences
+	return event, len(differences) > 0
+}
+
+// ReadHistory reads the drift events of a drift log that match the filter, oldest first
+func ReadHistory(path string, filter HistoryFilter) ([]DriftEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open drift log %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var events []DriftEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxHistoryLine)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var event DriftEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return nil, fmt.Errorf("failed to parse drift log %s line %d: %w", path, lineNumber, err)
+		}
+		if event, ok := filter.matches(event); ok {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read drift log %s: %w", path, err)
+	}
+	return events, nil
+}
+
+// FormatHistory formats drift events for the terminal, one block per event
+func FormatHistory(events []DriftEvent) string {
+	var result strings.Builder
+	for _, event := range events {
+		scope := event.Region
+		if event.VpcID != "" {
+			scope += " " + event.VpcID
+		}
+		result.WriteString(fmt.Sprintf("[%s] %d differences (%s)\n", event.DetectedAt.Local().Format("2006-01-02 15:04:05"), len(event.Differences), scope))
+		if event.Baseline != "" {
+			result.WriteString(fmt.Sprintf("  baseline: %s\n", event.Baseline))
+		}
+		for _, diff := range event.Differences {
+			result.WriteString(fmt.Sprintf("  %-8s %s: %s %s\n", strings.ToUpper(diff.Type.String()), diff.ResourceType, diff.displayID(), diff.Description))
+			for _, detail := range diff.Details {
+				result.WriteString(fmt.Sprintf("      %s\n", detail))
+			}
+		}
+		result.WriteString("\n")
+	}
+	return result.String()
+}
@@ -0,0 +1,63 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drift.jsonl")
+	history := NewHistoryLog(path)
+
+	first := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	events := []DriftEvent{
+		{Region: "us-east-1", DetectedAt: first, Differences: []Difference{
+			{Type: Added, ResourceType: "VPC", ResourceID: "vpc-new", Description: "New vpc created"},
+		}},
+		{Region: "us-east-1", DetectedAt: first.Add(time.Hour), Differences: []Difference{
+			{Type: Modified, ResourceType: "SecurityGroup", ResourceID: "sg-1", Description: "security group configuration changed",
+				Details: []string{"IngressRules[sgr-1]: added (tcp 22-22 0.0.0.0/0)"}},
+			{Type: Removed, ResourceType: "Subnet", ResourceID: "subnet-1", Description: "Subnet removed"},
+		}},
+	}
+	for _, event := range events {
+		if err := history.Notify(context.Background(), event); err != nil {
+			t.Fatalf("Notify failed: %v", err)
+		}
+	}
+
+	all, err := ReadHistory(path, HistoryFilter{})
+	if err != nil {
+		t.Fatalf("ReadHistory failed: %v", err)
+	}
+	if len(all) != 2 || all[1].Differences[0].Type != Modified || !all[0].DetectedAt.Equal(first) {
+		t.Fatalf("Expected both events back in order, got %+v", all)
+	}
+
+	since, err := ReadHistory(path, HistoryFilter{Since: first.Add(time.Minute)})
+	if err != nil || len(since) != 1 || len(since[0].Differences) != 2 {
+		t.Errorf("Expected only the later event, got %+v, %v", since, err)
+	}
+
+	// Resource filters keep only the matching differences of each event
+	sg, err := ReadHistory(path, HistoryFilter{ResourceType: "securitygroup"})
+	if err != nil || len(sg) != 1 || len(sg[0].Differences) != 1 || sg[0].Differences[0].ResourceID != "sg-1" {
+		t.Errorf("Expected only the security group difference, got %+v, %v", sg, err)
+	}
+
+	text := FormatHistory(sg)
+	if !strings.Contains(text, "MODIFIED SecurityGroup: sg-1") || !strings.Contains(text, "IngressRules[sgr-1]: added") {
+		t.Errorf("Expected the difference and its details in the text, got:\n%s", text)
+	}
+
+	if err := os.WriteFile(path, []byte("{not json\n"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt log: %v", err)
+	}
+	if _, err := ReadHistory(path, HistoryFilter{}); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected a parse error naming the line, got %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package watch
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"strings"
+)
+
+// Severity ranks how urgently a difference should be reviewed
+type Severity string
+
+const (
+	SeverityHigh   Severity = "high"
+	SeverityMedium Severity = "medium"
+	SeverityLow    Severity = "low"
+)
+
+// highSeverityTypes are the resource types whose changes alter what can reach
+// what, or with which permissions
+var highSeverityTypes = map[string]bool{
+	"SecurityGroup":             true,
+	"NetworkACL":                true,
+	"RouteTable":                true,
+	"IAMRole":                   true,
+	"InternetGateway":           true,
+	"EgressOnlyInternetGateway": true,
+	"PeeringConnection":         true,
+	"TransitGateway":            true,
+	"EdgeIngress":               true,
+}
+
+// DifferenceSeverity returns the severity of a difference: high for any change to
+// security groups, network ACLs, route tables, IAM roles, gateways, peering and
+// edge entry points, medium for other removals and modifications, low for other
+// additions
+func DifferenceSeverity(diff Difference) Severity {
+	switch {
+	case highSeverityTypes[diff.ResourceType]:
+		return SeverityHigh
+	case diff.Type == Added:
+		return SeverityLow
+	default:
+		return SeverityMedium
+	}
+}
+
+// severityRanks orders severities from least to most urgent
+var severityRanks = map[Severity]int{SeverityLow: 1, SeverityMedium: 2, SeverityHigh: 3}
+
+// ParseSeverity parses a severity name: low, medium or high
+func ParseSeverity(name string) (Severity, error) {
+	severity := Severity(strings.ToLower(name))
+	if severityRanks[severity] == 0 {
+		return "", fmt.Errorf("unknown severity %q, expected low, medium or high", name)
+	}
+	return severity, nil
+}
+
+// AtLeast reports whether s is as urgent as minimum or more
+func (s Severity) AtLeast(minimum Severity) bool {
+	return severityRanks[s] >= severityRanks[minimum]
+}
+
+// FieldChange is one changed field of a difference, with its value before and
+// after when known. Changes without separate values are described by Note.
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+	Note   string
+}
+
+// FieldChanges splits the details of a difference into changed fields
+func (d Difference) FieldChanges() []FieldChange {
+	changes := make([]FieldChange, 0, len(d.Details))
+	for _, detail := range d.Details {
+		changes = append(changes, parseDetail(detail))
+	}
+	return changes
+}
+
+// parseDetail splits a detail written by the comparator, such as
+// "CidrBlock: 10.0.0.0/16 → 10.1.0.0/16" or "IngressRules[sgr-1]: added (tcp 443-443 0.0.0.0/0)"
+func parseDetail(detail string) FieldChange {
+	field, rest, ok := strings.Cut(detail, ": ")
+	if !ok {
+		return FieldChange{Note: detail}
+	}
+
+	if before, after, ok := strings.Cut(rest, " → "); ok {
+		return FieldChange{Field: field, Before: before, After: after}
+	}
+	if value, ok := strings.CutPrefix(rest, "key added with value "); ok {
+		return FieldChange{Field: field, After: value}
+	}
+	if rest == "key removed" {
+		return FieldChange{Field: field, Note: "removed"}
+	}
+	if value, ok := strings.CutPrefix(rest, "added"); ok {
+		return FieldChange{Field: field, After: detailValue(value, "added")}
+	}
+	if value, ok := strings.CutPrefix(rest, "removed"); ok {
+		return FieldChange{Field: field, Before: detailValue(value, "removed")}
+	}
+	return FieldChange{Field: field, Note: rest}
+}
+
+// detailValue returns the value of an added or removed detail, written as
+// " value" or " (summary)", or the fallback when there is none
+func detailValue(value, fallback string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		value = value[1 : len(value)-1]
+	}
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// HTMLReportInfo describes the comparison an HTML report covers
+type HTMLReportInfo struct {
+	Region   string
+	VpcID    string
+	Baseline string
+	// Current names the state compared with the baseline when it is not a live scan
+	Current string
+}
+
+// htmlReportStyle is the stylesheet embedded in the HTML drift report
+const htmlReportStyle = `body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin: 0 0 0.5em 0; }
+.meta { color: #666; }
+table { border-collapse: collapse; margin: 0.5em 0 1em 0; }
+td, th { border: 1px solid #ddd; padding: 4px 12px; text-align: left; vertical-align: top; }
+td.value { font-family: ui-monospace, Menlo, Consolas, monospace; white-space: pre-wrap; word-break: break-all; }
+td.before { background: #fff0f0; }
+td.after { background: #f0fff4; }
+.diff { border-left: 6px solid #ccc; padding: 0.5em 1em; margin: 1em 0; background: #fafafa; }
+.diff.high { border-color: #d73a49; }
+.diff.medium { border-color: #e36209; }
+.diff.low { border-color: #0366d6; }
+.badge { display: inline-block; border-radius: 3px; padding: 0 6px; margin-right: 6px; color: #fff; font-size: 0.8em; text-transform: uppercase; }
+.badge.high { background: #d73a49; }
+.badge.medium { background: #e36209; }
+.badge.low { background: #0366d6; }
+.badge.type { background: #6a737d; }
+.ok { color: #22863a; }`
+
+// FormatHTMLReport renders differences as a standalone HTML page with a summary
+// by type and severity and a table of before and after values per resource
+func FormatHTMLReport(differences []Difference, info HTMLReportInfo) string {
+	report := NewDiffReport(differences)
+	var result strings.Builder
+
+	title := "Network Drift Report"
+	if info.Region != "" {
+		title = fmt.Sprintf("Network Drift Report - Region: %s", info.Region)
+	}
+
+	result.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	result.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(title)))
+	result.WriteString(fmt.Sprintf("<style>\n%s\n</style>\n</head>\n<body>\n", htmlReportStyle))
+	result.WriteString(fmt.Sprintf("<h1>%s</h1>\n", html.EscapeString(title)))
+
+	meta := []string{fmt.Sprintf("Generated: %s", report.GeneratedAt.Local().Format("2006-01-02 15:04:05 MST"))}
+	if info.Baseline != "" {
+		meta = append(meta, fmt.Sprintf("Baseline: %s", info.Baseline))
+	}
+	if info.Current != "" {
+		meta = append(meta, fmt.Sprintf("Compared with: %s", info.Current))
+	}
+	if info.VpcID != "" {
+		meta = append(meta, fmt.Sprintf("VPC: %s", info.VpcID))
+	}
+	result.WriteString(fmt.Sprintf("<p class=\"meta\">%s</p>\n", html.EscapeString(strings.Join(meta, " · "))))
+
+	if len(report.Differences) == 0 {
+		result.WriteString("<p class=\"ok\">✓ No differences found - infrastructure state matches baseline</p>\n</body>\n</html>\n")
+		return result.String()
+	}
+
+	// Summary
+	severities := make(map[Severity]int)
+	for _, diff := range report.Differences {
+		severities[DifferenceSeverity(diff)]++
+	}
+	result.WriteString("<table>\n")
+	result.WriteString(fmt.Sprintf("<tr><th>Differences</th><td>%d</td></tr>\n", report.Summary.Total))
+	result.WriteString(fmt.Sprintf("<tr><th>Added</th><td>%d</td></tr>\n", report.Summary.Added))
+	result.WriteString(fmt.Sprintf("<tr><th>Removed</th><td>%d</td></tr>\n", report.Summary.Removed))
+	result.WriteString(fmt.Sprintf("<tr><th>Modified</th><td>%d</td></tr>\n", report.Summary.Modified))
+	for _, severity := range []Severity{SeverityHigh, SeverityMedium, SeverityLow} {
+		result.WriteString(fmt.Sprintf("<tr><th><span class=\"badge %s\">%s</span></th><td>%d</td></tr>\n", severity, severity, severities[severity]))
+	}
+	result.WriteString("</table>\n")
+
+	// Most severe first, keeping the report's order within a severity
+	for _, severity := range []Severity{SeverityHigh, SeverityMedium, SeverityLow} {
+		for _, diff := range report.Differences {
+			if DifferenceSeverity(diff) == severity {
+				writeHTMLDifference(&result, diff, severity, "")
+			}
+		}
+	}
+
+	result.WriteString("</body>\n</html>\n")
+	return result.String()
+}
+
+// writeHTMLDifference writes one difference with its field changes, and a note
+// under its description when not empty
+func writeHTMLDifference(result *strings.Builder, diff Difference, severity Severity, note string) {
+	result.WriteString(fmt.Sprintf("<div class=\"diff %s\">\n", severity))
+	result.WriteString(fmt.Sprintf("<h2><span class=\"badge %s\">%s</span><span class=\"badge type\">%s</span>%s %s</h2>\n",
+		severity, severity, diff.Type, html.EscapeString(diff.ResourceType), html.EscapeString(diff.displayID())))
+	result.WriteString(fmt.Sprintf("<p>%s</p>\n", html.EscapeString(diff.Description)))
+	if note != "" {
+		result.WriteString(fmt.Sprintf("<p class=\"meta\">%s</p>\n", html.EscapeString(note)))
+	}
+
+	changes := diff.FieldChanges()
+	if len(changes) > 0 {
+		result.WriteString("<table>\n<tr><th>Field</th><th>Before</th><th>After</th></tr>\n")
+		for _, change := range changes {
+			if change.Note != "" {
+				result.WriteString(fmt.Sprintf("<tr><td>%s</td><td class=\"value\" colspan=\"2\">%s</td></tr>\n",
+					html.EscapeString(change.Field), html.EscapeString(change.Note)))
+				continue
+			}
+			result.WriteString(fmt.Sprintf("<tr><td>%s</td><td class=\"value before\">%s</td><td class=\"value after\">%s</td></tr>\n",
+				html.EscapeString(change.Field), html.EscapeString(change.Before), html.EscapeString(change.After)))
+		}
+		result.WriteString("</table>\n")
+	}
+	result.WriteString("</div>\n")
+}
+
+// WriteHTMLReport writes an HTML drift report to a file
+func WriteHTMLReport(filename string, differences []Difference, info HTMLReportInfo) error {
+	if err := os.WriteFile(filename, []byte(FormatHTMLReport(differences, info)), 0644); err != nil {
+		return fmt.Errorf("failed to write HTML report %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDetail(t *testing.T) {
+	tests := map[string]FieldChange{
+		"CidrBlock: 10.0.0.0/16 → 10.1.0.0/16":               {Field: "CidrBlock", Before: "10.0.0.0/16", After: "10.1.0.0/16"},
+		"IngressRules[sgr-1]: added (tcp 443-443 0.0.0.0/0)": {Field: "IngressRules[sgr-1]", After: "tcp 443-443 0.0.0.0/0"},
+		"Routes[0.0.0.0/0]: removed (igw-1)":                 {Field: "Routes[0.0.0.0/0]", Before: "igw-1"},
+		"Tags[Owner]: key added with value platform":         {Field: "Tags[Owner]", After: "platform"},
+		"Tags[Owner]: key removed":                           {Field: "Tags[Owner]", Note: "removed"},
+		"SubnetIDs: added subnet-2":                          {Field: "SubnetIDs", After: "subnet-2"},
+		"SubnetIDs: length changed from 1 to 2":              {Field: "SubnetIDs", Note: "length changed from 1 to 2"},
+		"VPC no longer exists":                               {Note: "VPC no longer exists"},
+	}
+	for detail, want := range tests {
+		if got := parseDetail(detail); got != want {
+			t.Errorf("parseDetail(%q) = %+v, want %+v", detail, got, want)
+		}
+	}
+}
+
+func TestDifferenceSeverity(t *testing.T) {
+	tests := []struct {
+		diff Difference
+		want Severity
+	}{
+		{Difference{Type: Added, ResourceType: "SecurityGroup"}, SeverityHigh},
+		{Difference{Type: Modified, ResourceType: "RouteTable"}, SeverityHigh},
+		{Difference{Type: Added, ResourceType: "Subnet"}, SeverityLow},
+		{Difference{Type: Removed, ResourceType: "Subnet"}, SeverityMedium},
+		{Difference{Type: Modified, ResourceType: "VPC"}, SeverityMedium},
+	}
+	for _, tt := range tests {
+		if got := DifferenceSeverity(tt.diff); got != tt.want {
+			t.Errorf("DifferenceSeverity(%s %s) = %s, want %s", tt.diff.Type, tt.diff.ResourceType, got, tt.want)
+		}
+	}
+}
+
+func TestFormatHTMLReport(t *testing.T) {
+	differences := []Difference{
+		{Type: Added, ResourceType: "Subnet", ResourceID: "subnet-1", Description: "Subnet added"},
+		{
+			Type:         Modified,
+			ResourceType: "SecurityGroup",
+			ResourceID:   "sg-1",
+			Description:  "Security group modified",
+			Details:      []string{"Description: web → <script>alert(1)</script>"},
+		},
+	}
+
+	report := FormatHTMLReport(differences, HTMLReportInfo{Region: "us-east-1", Baseline: "prod.json"})
+	if strings.Contains(report, "<script>") {
+		t.Error("Expected field values to be escaped")
+	}
+	if !strings.Contains(report, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Error("Expected the after value in the field table")
+	}
+	if !strings.Contains(report, "Baseline: prod.json") {
+		t.Error("Expected the baseline in the report")
+	}
+
+	high := strings.Index(report, `<div class="diff high">`)
+	low := strings.Index(report, `<div class="diff low">`)
+	if high < 0 || low < 0 || high > low {
+		t.Errorf("Expected high severity differences before low severity ones")
+	}
+
+	empty := FormatHTMLReport(nil, HTMLReportInfo{})
+	if !strings.Contains(empty, "No differences found") {
+		t.Error("Expected a no differences message")
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drift.html")
+	if err := WriteHTMLReport(path, nil, HTMLReportInfo{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "<!DOCTYPE html>") {
+		t.Errorf("Expected an HTML document, got %q", data)
+	}
+}
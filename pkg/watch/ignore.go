@@ -0,0 +1,140 @@
+package watch
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultIgnoreFile is the suppression file loaded when present in the working directory
+const DefaultIgnoreFile = ".pikaaignore.yaml"
+
+// IgnoreRules suppresses known or expected differences
+type IgnoreRules struct {
+	Rules []IgnoreRule `yaml:"rules"`
+}
+
+// IgnoreRule selects differences to suppress. All selectors that are set must match.
+// Without a Field the whole difference is suppressed; with a Field only the details
+// under that field path are suppressed.
+type IgnoreRule struct {
+	ResourceType string            `yaml:"resource_type"`
+	ResourceID   string            `yaml:"resource_id"`
+	Tags         map[string]string `yaml:"tags"`
+	Field        string            `yaml:"field"`
+	Reason       string            `yaml:"reason"`
+}
+
+// LoadIgnoreRules loads suppression rules from a YAML file
+func LoadIgnoreRules(filename string) (*IgnoreRules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file %s: %w", filename, err)
+	}
+
+	var rules IgnoreRules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse ignore file %s: %w", filename, err)
+	}
+
+	if err := rules.Validate(filename); err != nil {
+		return nil, err
+	}
+
+	return &rules, nil
+}
+
+// Validate checks that every rule has a selector. The source names where the rules came from.
+func (r *IgnoreRules) Validate(source string) error {
+	for i, rule := range r.Rules {
+		if rule.ResourceType == "" && rule.ResourceID == "" && len(rule.Tags) == 0 && rule.Field == "" {
+			return fmt.Errorf("ignore rule %d in %s has no selectors", i+1, source)
+		}
+	}
+	return nil
+}
+
+// Apply removes suppressed parts of a difference. The resource is the baseline or
+// current object the difference refers to and is used for tag selectors.
+// It returns false when the whole difference is suppressed.
+func (r *IgnoreRules) Apply(diff *Difference, resource interface{}) bool {
+	if r == nil {
+		return true
+	}
+
+	tags := resourceTags(resource)
+
+	for _, rule := range r.Rules {
+		if !rule.matchesResource(diff, tags) {
+			continue
+		}
+
+		if rule.Field == "" {
+			return false
+		}
+
+		// Field rules only narrow the details of modifications
+		if diff.Type != Modified {
+			continue
+		}
+
+		var kept []string
+		for _, detail := range diff.Details {
+			if !matchesFieldPath(detail, rule.Field) {
+				kept = append(kept, detail)
+			}
+		}
+		diff.Details = kept
+
+		if len(diff.Details) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesResource reports whether the rule's resource selectors match a difference
+func (rule IgnoreRule) matchesResource(diff *Difference, tags map[string]string) bool {
+	if rule.ResourceType != "" && !strings.EqualFold(rule.ResourceType, diff.ResourceType) {
+		return false
+	}
+	if rule.ResourceID != "" && rule.ResourceID != diff.ResourceID {
+		return false
+	}
+	for key, value := range rule.Tags {
+		if actual, ok := tags[key]; !ok || (value != "*" && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesFieldPath reports whether a detail line describes the given field path or one of its children
+func matchesFieldPath(detail, field string) bool {
+	if !strings.HasPrefix(detail, field) {
+		return false
+	}
+
+	rest := detail[len(field):]
+	return rest == "" || strings.ContainsAny(rest[:1], ":.[")
+}
+
+// resourceTags extracts the Tags map of a scanned resource, if it has one
+func resourceTags(resource interface{}) map[string]string {
+	value := reflect.ValueOf(resource)
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	field := value.FieldByName("Tags")
+	if !field.IsValid() {
+		return nil
+	}
+
+	tags, _ := field.Interface().(map[string]string)
+	return tags
+}
@@ -0,0 +1,132 @@
+package watch
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func TestLoadIgnoreRules(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test_pikaaignore_*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	content := `rules:
+  - resource_type: NATGateway
+    field: PublicIP
+    reason: Elastic IPs are rotated
+  - resource_id: sg-sandbox
+`
+	if _, err := tmpFile.WriteString(content); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	rules, err := LoadIgnoreRules(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load ignore rules: %v", err)
+	}
+
+	if len(rules.Rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(rules.Rules))
+	}
+
+	if rules.Rules[0].Field != "PublicIP" {
+		t.Errorf("Expected field PublicIP, got %s", rules.Rules[0].Field)
+	}
+}
+
+func TestLoadIgnoreRulesWithoutSelectors(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test_pikaaignore_*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString("rules:\n  - reason: suppress everything\n"); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	if _, err := LoadIgnoreRules(tmpFile.Name()); err == nil {
+		t.Error("Expected error for rule without selectors")
+	}
+}
+
+func TestCompareWithIgnoreRules(t *testing.T) {
+	baseline := &scanner.Network{
+		NATGateways: []scanner.NATGateway{
+			{ID: "nat-12345", PublicIP: "1.2.3.4", State: "available"},
+			{ID: "nat-67890", PublicIP: "5.6.7.8", State: "available"},
+		},
+		SecurityGroups: []scanner.SecurityGroup{
+			{ID: "sg-12345", Description: "old", Tags: map[string]string{"Environment": "sandbox"}},
+		},
+	}
+
+	current := &scanner.Network{
+		NATGateways: []scanner.NATGateway{
+			{ID: "nat-12345", PublicIP: "4.3.2.1", State: "available"},
+			{ID: "nat-67890", PublicIP: "8.7.6.5", State: "pending"},
+		},
+		SecurityGroups: []scanner.SecurityGroup{
+			{ID: "sg-12345", Description: "new", Tags: map[string]string{"Environment": "sandbox"}},
+		},
+		Subnets: []scanner.Subnet{
+			{ID: "subnet-12345"},
+		},
+	}
+
+	comparator := NewComparator(false)
+	comparator.SetIgnoreRules(&IgnoreRules{
+		Rules: []IgnoreRule{
+			{ResourceType: "NATGateway", Field: "PublicIP"},
+			{Tags: map[string]string{"Environment": "sandbox"}},
+		},
+	})
+
+	differences := comparator.Compare(baseline, current)
+
+	// Only the subnet addition and the NAT state change should remain
+	if len(differences) != 2 {
+		t.Fatalf("Expected 2 differences, got %v", differences)
+	}
+
+	for _, diff := range differences {
+		switch diff.ResourceID {
+		case "subnet-12345":
+			if diff.Type != Added {
+				t.Errorf("Expected subnet to be added, got %s", diff.Type)
+			}
+		case "nat-67890":
+			if len(diff.Details) != 1 || diff.Details[0] != "State: available → pending" {
+				t.Errorf("Expected only the state change to remain, got %v", diff.Details)
+			}
+		default:
+			t.Errorf("Unexpected difference for %s", diff.ResourceID)
+		}
+	}
+}
+
+func TestMatchesFieldPath(t *testing.T) {
+	tests := []struct {
+		detail   string
+		field    string
+		expected bool
+	}{
+		{"PublicIP: 1.2.3.4 → 4.3.2.1", "PublicIP", true},
+		{"Tags[Owner]: a → b", "Tags", true},
+		{"Tags[Owner]: a → b", "Tags[Owner]", true},
+		{"Tags[Owner]: a → b", "Tags[Team]", false},
+		{"PublicIPv6: a → b", "PublicIP", false},
+	}
+
+	for _, tt := range tests {
+		if result := matchesFieldPath(tt.detail, tt.field); result != tt.expected {
+			t.Errorf("matchesFieldPath(%q, %q) = %v, expected %v", tt.detail, tt.field, result, tt.expected)
+		}
+	}
+}
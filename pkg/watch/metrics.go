@@ -0,0 +1,147 @@
+package watch
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// differenceKey identifies a drift gauge series
+type differenceKey struct {
+	diffType     string
+	resourceType string
+	severity     Severity
+}
+
+// Metrics collects watch results and serves them in the Prometheus text exposition format
+type Metrics struct {
+	mu             sync.Mutex
+	resources      map[string]int
+	differences    map[differenceKey]int
+	scanDuration   time.Duration
+	lastSuccess    time.Time
+	scansSucceeded int
+	scansFailed    int
+}
+
+// NewMetrics creates an empty metrics collector
+func NewMetrics() *Metrics {
+	return &Metrics{
+		resources:   make(map[string]int),
+		differences: make(map[differenceKey]int),
+	}
+}
+
+// RecordScan records a successful scan and the differences found against the baseline
+func (m *Metrics) RecordScan(network *scanner.Network, differences []Difference, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.resources = map[string]int{
+		"vpc":                len(network.VPCs),
+		"subnet":             len(network.Subnets),
+		"peering_connection": len(network.PeeringConnections),
+		"transit_gateway":    len(network.TransitGateways),
+		"internet_gateway":   len(network.InternetGateways),
+		"egress_only_internet_gateway": len(network.EgressOnlyInternetGateways),
+		"nat_gateway":        len(network.NATGateways),
+		"route_table":        len(network.RouteTables),
+		"security_group":     len(network.SecurityGroups),
+		"network_acl":        len(network.NetworkAcls),
+		"flow_log":           len(network.FlowLogs),
+		"iam_role":           len(network.IAMRoles),
+		"dhcp_options":       len(network.DhcpOptions),
+		"endpoint_service":   len(network.EndpointServices),
+		"eks_cluster":        len(network.EKSClusters),
+		"ecs_service":        len(network.ECSServices),
+		"database":           len(network.Databases),
+		"edge_ingress":       len(network.EdgeIngresses),
+	}
+
+	// Reset series from the previous scan so resolved drift drops back to zero
+	for key := range m.differences {
+		m.differences[key] = 0
+	}
+	for _, diff := range differences {
+		m.differences[differenceKey{diffType: diff.Type.String(), resourceType: diff.ResourceType, severity: DifferenceSeverity(diff)}]++
+	}
+
+	m.scanDuration = duration
+	m.lastSuccess = time.Now()
+	m.scansSucceeded++
+}
+
+// RecordFailure records a scan that could not be completed
+func (m *Metrics) RecordFailure() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scansFailed++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprint(w, m.Expose())
+}
+
+// Expose renders the metrics in the Prometheus text exposition format
+func (m *Metrics) Expose() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var result strings.Builder
+
+	result.WriteString("# HELP pikaatools_resources Number of resources of each type in the last scan.\n")
+	result.WriteString("# TYPE pikaatools_resources gauge\n")
+	resourceTypes := make([]string, 0, len(m.resources))
+	for resourceType := range m.resources {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+	for _, resourceType := range resourceTypes {
+		result.WriteString(fmt.Sprintf("pikaatools_resources{resource_type=%q} %d\n", resourceType, m.resources[resourceType]))
+	}
+
+	result.WriteString("# HELP pikaatools_drift_differences Differences from the baseline in the last scan by difference type, resource type and severity.\n")
+	result.WriteString("# TYPE pikaatools_drift_differences gauge\n")
+	keys := make([]differenceKey, 0, len(m.differences))
+	for key := range m.differences {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].diffType != keys[j].diffType {
+			return keys[i].diffType < keys[j].diffType
+		}
+		if keys[i].resourceType != keys[j].resourceType {
+			return keys[i].resourceType < keys[j].resourceType
+		}
+		return keys[i].severity < keys[j].severity
+	})
+	for _, key := range keys {
+		result.WriteString(fmt.Sprintf("pikaatools_drift_differences{type=%q,resource_type=%q,severity=%q} %d\n", key.diffType, key.resourceType, key.severity, m.differences[key]))
+	}
+
+	result.WriteString("# HELP pikaatools_scan_duration_seconds Duration of the last successful scan.\n")
+	result.WriteString("# TYPE pikaatools_scan_duration_seconds gauge\n")
+	result.WriteString(fmt.Sprintf("pikaatools_scan_duration_seconds %g\n", m.scanDuration.Seconds()))
+
+	result.WriteString("# HELP pikaatools_last_successful_scan_timestamp_seconds Unix time of the last successful scan.\n")
+	result.WriteString("# TYPE pikaatools_last_successful_scan_timestamp_seconds gauge\n")
+	lastSuccess := 0.0
+	if !m.lastSuccess.IsZero() {
+		lastSuccess = float64(m.lastSuccess.UnixNano()) / float64(time.Second)
+	}
+	result.WriteString(fmt.Sprintf("pikaatools_last_successful_scan_timestamp_seconds %.3f\n", lastSuccess))
+
+	result.WriteString("# HELP pikaatools_scans_total Scans performed by result.\n")
+	result.WriteString("# TYPE pikaatools_scans_total counter\n")
+	result.WriteString(fmt.Sprintf("pikaatools_scans_total{result=\"failure\"} %d\n", m.scansFailed))
+	result.WriteString(fmt.Sprintf("pikaatools_scans_total{result=\"success\"} %d\n", m.scansSucceeded))
+
+	return result.String()
+}
@@ -0,0 +1,48 @@
+package watch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func TestMetricsExpose(t *testing.T) {
+	metrics := NewMetrics()
+
+	network := &scanner.Network{
+		VPCs:    []scanner.VPC{{ID: "vpc-1"}},
+		Subnets: []scanner.Subnet{{ID: "subnet-1"}, {ID: "subnet-2"}},
+	}
+	differences := []Difference{
+		{Type: Added, ResourceType: "Subnet", ResourceID: "subnet-2"},
+		{Type: Modified, ResourceType: "VPC", ResourceID: "vpc-1"},
+		{Type: Removed, ResourceType: "SecurityGroup", ResourceID: "sg-1"},
+	}
+
+	metrics.RecordScan(network, differences, 1500*time.Millisecond)
+	metrics.RecordFailure()
+
+	output := metrics.Expose()
+	expected := []string{
+		`pikaatools_resources{resource_type="subnet"} 2`,
+		`pikaatools_drift_differences{type="added",resource_type="Subnet",severity="low"} 1`,
+		`pikaatools_drift_differences{type="modified",resource_type="VPC",severity="medium"} 1`,
+		`pikaatools_drift_differences{type="removed",resource_type="SecurityGroup",severity="high"} 1`,
+		`pikaatools_scan_duration_seconds 1.5`,
+		`pikaatools_scans_total{result="failure"} 1`,
+		`pikaatools_scans_total{result="success"} 1`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	// Drift that is resolved drops back to zero
+	metrics.RecordScan(network, nil, time.Second)
+	if !strings.Contains(metrics.Expose(), `pikaatools_drift_differences{type="added",resource_type="Subnet",severity="low"} 0`) {
+		t.Error("Expected resolved drift to be reported as 0")
+	}
+}
@@ -0,0 +1,201 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	ebTypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+const (
+	// driftEventSource is the source reported on EventBridge events
+	driftEventSource = "pikaatools"
+	// driftEventDetailType is the detail-type reported on EventBridge events
+	driftEventDetailType = "Network Drift Detected"
+	// maxSNSSubjectLength is the maximum subject length accepted by SNS
+	maxSNSSubjectLength = 100
+	// maxDriftEventPayload is the largest drift event published to SNS or
+	// EventBridge, both of which reject messages over 256 KB. The rest is left for
+	// the subject, source and other fields sent with it.
+	maxDriftEventPayload = 256*1024 - 2048
+)
+
+// Notifier publishes drift events to an external destination
+type Notifier interface {
+	Notify(ctx context.Context, event DriftEvent) error
+}
+
+// DriftEvent describes the differences detected by a single watch scan
+type DriftEvent struct {
+	Region      string       `json:"region"`
+	VpcID       string       `json:"vpc_id,omitempty"`
+	Baseline    string       `json:"baseline,omitempty"` // Baseline state file, set when watching a directory of baselines
+	DetectedAt  time.Time    `json:"detected_at"`
+	Differences []Difference `json:"differences"`
+	ReportURL   string       `json:"report_url,omitempty"` // Link to the HTML report, set when reports are uploaded to S3
+	// OmittedDifferences counts the differences left out to keep the event within
+	// the size SNS and EventBridge accept; the report lists them all
+	OmittedDifferences int `json:"omitted_differences,omitempty"`
+}
+
+// NewDriftEvent creates a drift event for the given differences
+func NewDriftEvent(region, vpcID string, differences []Difference) DriftEvent {
+	return DriftEvent{
+		Region:      region,
+		VpcID:       vpcID,
+		DetectedAt:  time.Now().UTC(),
+		Differences: differences,
+	}
+}
+
+// Summary returns a one-line description of the event
+func (e DriftEvent) Summary() string {
+	summary := fmt.Sprintf("pikaatools: %d network differences detected in %s", len(e.Differences), e.Region)
+	if e.VpcID != "" {
+		summary += fmt.Sprintf(" (%s)", e.VpcID)
+	}
+	return summary
+}
+
+// payload marshals the event, leaving out as many of the last differences as it
+// takes to fit within limit bytes
+func (e DriftEvent) payload(limit int) ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err != nil || len(data) <= limit {
+		return data, err
+	}
+
+	// Find the most differences that fit
+	total := len(e.Differences)
+	fits := func(kept int) ([]byte, bool, error) {
+		truncated := e
+		truncated.Differences = e.Differences[:kept]
+		truncated.OmittedDifferences = e.OmittedDifferences + total - kept
+		data, err := json.Marshal(truncated)
+		return data, len(data) <= limit, err
+	}
+	low, high := 0, total-1
+	for low < high {
+		mid := (low + high + 1) / 2
+		_, ok, err := fits(mid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	data, _, err = fits(low)
+	return data, err
+}
+
+// snsPublisher is the subset of the SNS client used by SNSNotifier
+type snsPublisher interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// SNSNotifier publishes drift events to an SNS topic
+type SNSNotifier struct {
+	client   snsPublisher
+	topicArn string
+}
+
+// NewSNSNotifier creates a notifier that publishes to the given SNS topic
+func NewSNSNotifier(client *sns.Client, topicArn string) *SNSNotifier {
+	return &SNSNotifier{
+		client:   client,
+		topicArn: topicArn,
+	}
+}
+
+// Notify publishes the drift event as a JSON message, truncated to the size SNS accepts
+func (n *SNSNotifier) Notify(ctx context.Context, event DriftEvent) error {
+	message, err := event.payload(maxDriftEventPayload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal drift event: %w", err)
+	}
+
+	subject := event.Summary()
+	if len(subject) > maxSNSSubjectLength {
+		subject = subject[:maxSNSSubjectLength]
+	}
+	messageStr := string(message)
+
+	_, err = n.client.Publish(ctx, &sns.PublishInput{
+		TopicArn: &n.topicArn,
+		Subject:  &subject,
+		Message:  &messageStr,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish drift event to SNS topic %s: %w", n.topicArn, err)
+	}
+
+	return nil
+}
+
+// eventBridgePublisher is the subset of the EventBridge client used by EventBridgeNotifier
+type eventBridgePublisher interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+// EventBridgeNotifier publishes drift events to an EventBridge event bus
+type EventBridgeNotifier struct {
+	client  eventBridgePublisher
+	busName string
+}
+
+// NewEventBridgeNotifier creates a notifier that publishes to the given event bus
+func NewEventBridgeNotifier(client *eventbridge.Client, busName string) *EventBridgeNotifier {
+	return &EventBridgeNotifier{
+		client:  client,
+		busName: busName,
+	}
+}
+
+// Notify puts the drift event on the event bus, truncated to the size EventBridge accepts
+func (n *EventBridgeNotifier) Notify(ctx context.Context, event DriftEvent) error {
+	detail, err := event.payload(maxDriftEventPayload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal drift event: %w", err)
+	}
+
+	source := driftEventSource
+	detailType := driftEventDetailType
+	detailStr := string(detail)
+
+	result, err := n.client.PutEvents(ctx, &eventbridge.PutEventsInput{
+		Entries: []ebTypes.PutEventsRequestEntry{
+			{
+				EventBusName: &n.busName,
+				Source:       &source,
+				DetailType:   &detailType,
+				Detail:       &detailStr,
+				Time:         &event.DetectedAt,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put drift event on event bus %s: %w", n.busName, err)
+	}
+
+	if result.FailedEntryCount > 0 {
+		for _, entry := range result.Entries {
+			if entry.ErrorCode != nil {
+				message := ""
+				if entry.ErrorMessage != nil {
+					message = *entry.ErrorMessage
+				}
+				return fmt.Errorf("event bus %s rejected drift event: %s %s", n.busName, *entry.ErrorCode, message)
+			}
+		}
+		return fmt.Errorf("event bus %s rejected drift event", n.busName)
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	ebTypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+type fakeSNSPublisher struct {
+	input *sns.PublishInput
+	err   error
+}
+
+func (f *fakeSNSPublisher) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.input = params
+	return &sns.PublishOutput{}, f.err
+}
+
+type fakeEventBridgePublisher struct {
+	input  *eventbridge.PutEventsInput
+	output *eventbridge.PutEventsOutput
+	err    error
+}
+
+func (f *fakeEventBridgePublisher) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	f.input = params
+	if f.output == nil {
+		f.output = &eventbridge.PutEventsOutput{}
+	}
+	return f.output, f.err
+}
+
+func testDriftEvent() DriftEvent {
+	return NewDriftEvent("us-east-1", "vpc-12345", []Difference{
+		{
+			Type:         Added,
+			ResourceType: "Subnet",
+			ResourceID:   "subnet-12345",
+			Description:  "New subnet created",
+		},
+	})
+}
+
+func TestSNSNotifier(t *testing.T) {
+	publisher := &fakeSNSPublisher{}
+	notifier := &SNSNotifier{client: publisher, topicArn: "arn:aws:sns:us-east-1:123456789012:drift"}
+
+	if err := notifier.Notify(context.Background(), testDriftEvent()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if *publisher.input.TopicArn != "arn:aws:sns:us-east-1:123456789012:drift" {
+		t.Errorf("Expected topic ARN to be passed through, got %s", *publisher.input.TopicArn)
+	}
+
+	if len(*publisher.input.Subject) > maxSNSSubjectLength {
+		t.Errorf("Expected subject to be at most %d characters, got %d", maxSNSSubjectLength, len(*publisher.input.Subject))
+	}
+
+	var event DriftEvent
+	if err := json.Unmarshal([]byte(*publisher.input.Message), &event); err != nil {
+		t.Fatalf("Expected message to be a JSON drift event: %v", err)
+	}
+
+	if len(event.Differences) != 1 || event.Differences[0].Type != Added {
+		t.Errorf("Expected one added difference, got %+v", event.Differences)
+	}
+}
+
+func TestSNSNotifierError(t *testing.T) {
+	publisher := &fakeSNSPublisher{err: errors.New("access denied")}
+	notifier := &SNSNotifier{client: publisher, topicArn: "arn:aws:sns:us-east-1:123456789012:drift"}
+
+	err := notifier.Notify(context.Background(), testDriftEvent())
+	if err == nil {
+		t.Fatal("Expected error when publish fails")
+	}
+
+	if !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("Expected wrapped publish error, got: %v", err)
+	}
+}
+
+func TestEventBridgeNotifier(t *testing.T) {
+	publisher := &fakeEventBridgePublisher{}
+	notifier := &EventBridgeNotifier{client: publisher, busName: "default"}
+
+	if err := notifier.Notify(context.Background(), testDriftEvent()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(publisher.input.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(publisher.input.Entries))
+	}
+
+	entry := publisher.input.Entries[0]
+	if *entry.Source != driftEventSource {
+		t.Errorf("Expected source %s, got %s", driftEventSource, *entry.Source)
+	}
+
+	if *entry.EventBusName != "default" {
+		t.Errorf("Expected bus name default, got %s", *entry.EventBusName)
+	}
+
+	if !strings.Contains(*entry.Detail, `"type":"added"`) {
+		t.Errorf("Expected detail to contain named difference type, got %s", *entry.Detail)
+	}
+}
+
+func TestNotifiersTruncateOversizedEvents(t *testing.T) {
+	var differences []Difference
+	for i := 0; i < 5000; i++ {
+		differences = append(differences, Difference{
+			Type:         Modified,
+			ResourceType: "SecurityGroup",
+			ResourceID:   fmt.Sprintf("sg-%05d", i),
+			Description:  "Security group rules changed",
+			Details:      []string{strings.Repeat("IngressRules[sgr-0123456789abcdef0].Description: old → new ", 2)},
+		})
+	}
+	event := NewDriftEvent("us-east-1", "vpc-12345", differences)
+	event.ReportURL = "https://reports.example.com/drift.html"
+
+	snsPublisher := &fakeSNSPublisher{}
+	if err := (&SNSNotifier{client: snsPublisher, topicArn: "arn:aws:sns:us-east-1:123456789012:drift"}).Notify(context.Background(), event); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ebPublisher := &fakeEventBridgePublisher{}
+	if err := (&EventBridgeNotifier{client: ebPublisher, busName: "default"}).Notify(context.Background(), event); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for name, payload := range map[string]string{"SNS": *snsPublisher.input.Message, "EventBridge": *ebPublisher.input.Entries[0].Detail} {
+		if len(payload) > maxDriftEventPayload {
+			t.Errorf("Expected the %s payload to fit in %d bytes, got %d", name, maxDriftEventPayload, len(payload))
+		}
+		var published DriftEvent
+		if err := json.Unmarshal([]byte(payload), &published); err != nil {
+			t.Fatalf("Expected the %s payload to be a JSON drift event: %v", name, err)
+		}
+		if len(published.Differences) == 0 || len(published.Differences)+published.OmittedDifferences != len(differences) {
+			t.Errorf("Expected the %s payload to count the omitted differences, got %d kept and %d omitted", name, len(published.Differences), published.OmittedDifferences)
+		}
+		if published.ReportURL != event.ReportURL {
+			t.Errorf("Expected the %s payload to link the report, got %q", name, published.ReportURL)
+		}
+	}
+
+	// The subject still counts every difference
+	if !strings.Contains(*snsPublisher.input.Subject, "5000 network differences") {
+		t.Errorf("Expected the subject to count every difference, got %q", *snsPublisher.input.Subject)
+	}
+}
+
+func TestEventBridgeNotifierFailedEntry(t *testing.T) {
+	code := "InternalFailure"
+	publisher := &fakeEventBridgePublisher{
+		output: &eventbridge.PutEventsOutput{
+			FailedEntryCount: 1,
+			Entries:          []ebTypes.PutEventsResultEntry{{ErrorCode: &code}},
+		},
+	}
+	notifier := &EventBridgeNotifier{client: publisher, busName: "default"}
+
+	err := notifier.Notify(context.Background(), testDriftEvent())
+	if err == nil {
+		t.Fatal("Expected error when the event bus rejects the entry")
+	}
+
+	if !strings.Contains(err.Error(), code) {
+		t.Errorf("Expected error to contain %s, got: %v", code, err)
+	}
+}
@@ -0,0 +1,294 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+	PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	// OpsgenieAPIURL is the Opsgenie API in the US region; EU accounts use
+	// https://api.eu.opsgenie.com
+	OpsgenieAPIURL = "https://api.opsgenie.com"
+	// maxPagerDutySummary is the longest summary PagerDuty accepts
+	maxPagerDutySummary = 1024
+	// maxOpsgenieMessage is the longest alert message Opsgenie accepts
+	maxOpsgenieMessage = 130
+	// maxOpsgenieDescription is the longest alert description Opsgenie accepts
+	maxOpsgenieDescription = 15000
+	// maxAlertDifferences is how many differences an alert summary names
+	maxAlertDifferences = 3
+)
+
+// pagerDutySeverities maps difference severities to PagerDuty event severities
+var pagerDutySeverities = map[Severity]string{
+	SeverityHigh:   "critical",
+	SeverityMedium: "error",
+	SeverityLow:    "warning",
+}
+
+// opsgeniePriorities maps difference severities to Opsgenie alert priorities
+var opsgeniePriorities = map[Severity]string{
+	SeverityHigh:   "P1",
+	SeverityMedium: "P2",
+	SeverityLow:    "P3",
+}
+
+// pageAlert is the part of a drift event that pages the on-call
+type pageAlert struct {
+	differences []Difference
+	severity    Severity
+	summary     string
+	// key identifies the drift, so scans that keep finding the same differences
+	// update one alert rather than opening a new one each time
+	key string
+}
+
+// newPageAlert selects the differences of an event at or above minSeverity. It
+// returns nil when there are none.
+func newPageAlert(event DriftEvent, minSeverity Severity) *pageAlert {
+	alert := &pageAlert{severity: SeverityLow}
+	for _, diff := range event.Differences {
+		severity := DifferenceSeverity(diff)
+		if !severity.AtLeast(minSeverity) {
+			continue
+		}
+		alert.differences = append(alert.differences, diff)
+		if severity.AtLeast(alert.severity) {
+			alert.severity = severity
+		}
+	}
+	if len(alert.differences) == 0 {
+		return nil
+	}
+
+	location := event.Region
+	if event.VpcID != "" {
+		location += " (" + event.VpcID + ")"
+	}
+	var names []string
+	for i, diff := range alert.differences {
+		if i == maxAlertDifferences {
+			names = append(names, fmt.Sprintf("and %d more", len(alert.differences)-i))
+			break
+		}
+		names = append(names, fmt.Sprintf("%s %s %s", diff.ResourceType, diff.displayID(), diff.Type))
+	}
+	alert.summary = fmt.Sprintf("pikaatools: %d %s severity network differences in %s: %s",
+		len(alert.differences), alert.severity, location, strings.Join(names, ", "))
+
+	keys := make([]string, len(alert.differences))
+	for i, diff := range alert.differences {
+		keys[i] = fmt.Sprintf("%s %s %s", diff.Type, diff.ResourceType, diff.ResourceID)
+	}
+	sort.Strings(keys)
+	hash := sha256.Sum256([]byte(strings.Join(append([]string{event.Region, event.VpcID, event.Baseline}, keys...), "\n")))
+	alert.key = "pikaatools-" + hex.EncodeToString(hash[:16])
+	return alert
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
+// postDriftEvent posts a drift event formatted for a destination as JSON and
+// checks the response status. URLs may hold secrets, so errors leave them out.
+func postDriftEvent(ctx context.Context, client *http.Client, destination, endpoint string, headers map[string]string, message interface{}) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s message: %w", destination, err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", destination, err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to post drift event to %s: %w", destination, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("drift event rejected by %s: %s %s", destination, response.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// PagerDutyNotifier triggers PagerDuty incidents through the Events API v2 for
+// drift events with differences at or above a severity
+type PagerDutyNotifier struct {
+	routingKey  string
+	minSeverity Severity
+	url         string
+	client      *http.Client
+}
+
+// NewPagerDutyNotifier creates a notifier that triggers events on the PagerDuty
+// service integration with the given routing key
+func NewPagerDutyNotifier(routingKey string, minSeverity Severity) *PagerDutyNotifier {
+	return &PagerDutyNotifier{
+		routingKey:  routingKey,
+		minSeverity: minSeverity,
+		url:         PagerDutyEventsURL,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// pagerDutyEvent is a PagerDuty Events API v2 event
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     pagerDutyPayload `json:"payload"`
+	Links       []pagerDutyLink  `json:"links,omitempty"`
+}
+
+// pagerDutyPayload describes what a PagerDuty event is about
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component,omitempty"`
+	Class         string                 `json:"class"`
+	CustomDetails map[string]interface{} `json:"custom_details"`
+}
+
+// pagerDutyLink is a link shown on a PagerDuty incident
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Notify triggers a PagerDuty event for the differences at or above the minimum
+// severity. Scans finding the same differences share a dedup key, so they are
+// grouped into the open incident.
+func (n *PagerDutyNotifier) Notify(ctx context.Context, event DriftEvent) error {
+	alert := newPageAlert(event, n.minSeverity)
+	if alert == nil {
+		return nil
+	}
+
+	pdEvent := pagerDutyEvent{
+		RoutingKey:  n.routingKey,
+		EventAction: "trigger",
+		DedupKey:    alert.key,
+		Payload: pagerDutyPayload{
+			Summary:       truncate(alert.summary, maxPagerDutySummary),
+			Source:        event.Region,
+			Severity:      pagerDutySeverities[alert.severity],
+			Timestamp:     event.DetectedAt.Format(time.RFC3339),
+			Component:     event.VpcID,
+			Class:         "network drift",
+			CustomDetails: map[string]interface{}{"differences": alert.differences},
+		},
+	}
+	if event.Baseline != "" {
+		pdEvent.Payload.CustomDetails["baseline"] = event.Baseline
+	}
+	if event.ReportURL != "" {
+		pdEvent.Links = []pagerDutyLink{{Href: event.ReportURL, Text: "Drift report"}}
+	}
+	return postDriftEvent(ctx, n.client, "PagerDuty", n.url, nil, pdEvent)
+}
+
+// OpsgenieNotifier creates Opsgenie alerts for drift events with differences at
+// or above a severity
+type OpsgenieNotifier struct {
+	apiKey      string
+	minSeverity Severity
+	apiURL      string
+	client      *http.Client
+}
+
+// NewOpsgenieNotifier creates a notifier that creates alerts with the given
+// Opsgenie API integration key
+func NewOpsgenieNotifier(apiKey string, minSeverity Severity) *OpsgenieNotifier {
+	return &OpsgenieNotifier{
+		apiKey:      apiKey,
+		minSeverity: minSeverity,
+		apiURL:      OpsgenieAPIURL,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetAPIURL sets the Opsgenie API to use, such as https://api.eu.opsgenie.com
+func (n *OpsgenieNotifier) SetAPIURL(apiURL string) {
+	n.apiURL = strings.TrimSuffix(apiURL, "/")
+}
+
+// opsgenieAlert is an Opsgenie alert creation request
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description"`
+	Priority    string            `json:"priority"`
+	Source      string            `json:"source"`
+	Tags        []string          `json:"tags"`
+	Details     map[string]string `json:"details"`
+}
+
+// Notify creates an Opsgenie alert for the differences at or above the minimum
+// severity. Scans finding the same differences share an alias, so Opsgenie
+// counts them on the open alert.
+func (n *OpsgenieNotifier) Notify(ctx context.Context, event DriftEvent) error {
+	alert := newPageAlert(event, n.minSeverity)
+	if alert == nil {
+		return nil
+	}
+
+	var description strings.Builder
+	for _, diff := range alert.differences {
+		fmt.Fprintf(&description, "%s %s %s (%s severity)\n", diff.ResourceType, diff.displayID(), diff.Type, DifferenceSeverity(diff))
+		for _, detail := range diff.Details {
+			fmt.Fprintf(&description, "  %s\n", detail)
+		}
+	}
+
+	details := map[string]string{"region": event.Region}
+	if event.VpcID != "" {
+		details["vpc_id"] = event.VpcID
+	}
+	if event.Baseline != "" {
+		details["baseline"] = event.Baseline
+	}
+	if event.ReportURL != "" {
+		details["report_url"] = event.ReportURL
+	}
+
+	return postDriftEvent(ctx, n.client, "Opsgenie", n.apiURL+"/v2/alerts", map[string]string{"Authorization": "GenieKey " + n.apiKey}, opsgenieAlert{
+		Message:     truncate(alert.summary, maxOpsgenieMessage),
+		Alias:       alert.key,
+		Description: truncate(description.String(), maxOpsgenieDescription),
+		Priority:    opsgeniePriorities[alert.severity],
+		Source:      "pikaatools",
+		Tags:        []string{"network-drift", event.Region},
+		Details:     details,
+	})
+}
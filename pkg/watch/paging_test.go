@@ -0,0 +1,104 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// pageServer records the requests of a fake alerting API
+type pageServer struct {
+	*httptest.Server
+	authorization string
+	bodies        []map[string]interface{}
+}
+
+func newPageServer(t *testing.T) *pageServer {
+	s := &pageServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("invalid payload %s: %v", data, err)
+		}
+		s.authorization = r.Header.Get("Authorization")
+		s.bodies = append(s.bodies, body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func pagingEvent() DriftEvent {
+	return NewDriftEvent("us-east-1", "vpc-prod", []Difference{
+		{Type: Added, ResourceType: "Subnet", ResourceID: "subnet-1"},
+		{Type: Modified, ResourceType: "RouteTable", ResourceID: "rtb-1", Details: []string{"Routes: added 0.0.0.0/0 via igw-1"}},
+	})
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	server := newPageServer(t)
+	notifier := NewPagerDutyNotifier("routing-key", SeverityHigh)
+	notifier.url = server.URL
+
+	event := pagingEvent()
+	event.ReportURL = "https://example.com/report.html"
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(server.bodies) != 1 {
+		t.Fatalf("sent %d events, want 1", len(server.bodies))
+	}
+	body := server.bodies[0]
+	payload := body["payload"].(map[string]interface{})
+	if body["routing_key"] != "routing-key" || body["event_action"] != "trigger" || payload["severity"] != "critical" {
+		t.Errorf("event = %v, want a critical trigger on the routing key", body)
+	}
+	if summary := payload["summary"].(string); !strings.Contains(summary, "1 high severity network differences in us-east-1 (vpc-prod): RouteTable rtb-1 modified") {
+		t.Errorf("summary = %q, want only the route table change", summary)
+	}
+	if links := body["links"].([]interface{}); links[0].(map[string]interface{})["href"] != event.ReportURL {
+		t.Errorf("links = %v, want the report", links)
+	}
+
+	// The same drift found again is grouped into the open incident
+	if err := notifier.Notify(context.Background(), pagingEvent()); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if server.bodies[1]["dedup_key"] != body["dedup_key"] {
+		t.Errorf("dedup keys %v and %v differ for the same drift", body["dedup_key"], server.bodies[1]["dedup_key"])
+	}
+
+	// Drift below the threshold does not page
+	low := NewDriftEvent("us-east-1", "", []Difference{{Type: Added, ResourceType: "Subnet", ResourceID: "subnet-1"}})
+	if err := notifier.Notify(context.Background(), low); err != nil || len(server.bodies) != 2 {
+		t.Errorf("Notify sent %d events with error %v, want nothing sent", len(server.bodies)-2, err)
+	}
+}
+
+func TestOpsgenieNotifier(t *testing.T) {
+	server := newPageServer(t)
+	notifier := NewOpsgenieNotifier("api-key", SeverityMedium)
+	notifier.SetAPIURL(server.URL + "/")
+
+	if err := notifier.Notify(context.Background(), pagingEvent()); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if server.authorization != "GenieKey api-key" {
+		t.Errorf("Authorization = %q, want the GenieKey", server.authorization)
+	}
+	body := server.bodies[0]
+	if body["priority"] != "P1" || !strings.HasPrefix(body["alias"].(string), "pikaatools-") {
+		t.Errorf("alert = %v, want a P1 alert with a pikaatools alias", body)
+	}
+	if message := body["message"].(string); len(message) > maxOpsgenieMessage {
+		t.Errorf("message is %d characters, longer than Opsgenie accepts", len(message))
+	}
+	if description := body["description"].(string); !strings.Contains(description, "Routes: added 0.0.0.0/0 via igw-1") {
+		t.Errorf("description = %q, want the route table details", description)
+	}
+}
@@ -0,0 +1,115 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// baselineSource is where the watched baselines are loaded from, and what they
+// looked like when they were last loaded
+type baselineSource struct {
+	location string // Working state file, s3:// or http(s):// URL, or directory
+	dir      bool
+	version  string    // Modification times and sizes of the local files last loaded
+	loaded   time.Time // When a remote baseline was last downloaded
+}
+
+// SetBaselineReload downloads remote baselines again every interval so updates
+// take effect without restarting the watcher. Zero keeps the first download.
+// Local baseline files are always reloaded when they change.
+func (w *Watcher) SetBaselineReload(interval time.Duration) {
+	w.reloadInterval = interval
+}
+
+// loadBaselines loads the baselines of a source and records their version
+func (w *Watcher) loadBaselines(ctx context.Context, source *baselineSource) ([]baselineState, error) {
+	version, err := source.localVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	var baselines []baselineState
+	if source.dir {
+		baselines, err = w.loadBaselineDir(ctx, source.location)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		w.logger.Debug("loading baseline state", "file", source.location)
+		network, err := w.comparator.LoadBaseline(ctx, source.location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load baseline state: %w", err)
+		}
+		w.logger.Debug("loaded baseline state", "file", source.location, "scan_time", network.ScanTime.Format(time.RFC3339))
+		baselines = []baselineState{{file: source.location, network: network}}
+	}
+
+	source.version = version
+	source.loaded = time.Now()
+	return baselines, nil
+}
+
+// reloadBaselines reloads the baselines of a source when its local files changed or
+// a remote baseline is due to be downloaded again. It returns the baselines to
+// compare against and whether they were reloaded. A baseline that fails to load,
+// such as one that is still being written, is reported and the previous baselines
+// are kept until the next scan.
+func (w *Watcher) reloadBaselines(ctx context.Context, source *baselineSource, baselines []baselineState) ([]baselineState, bool) {
+	if IsRemoteBaseline(source.location) {
+		if w.reloadInterval <= 0 || time.Since(source.loaded) < w.reloadInterval {
+			return baselines, false
+		}
+	} else {
+		version, err := source.localVersion()
+		if err != nil {
+			w.logger.Warn("failed to check baseline state for changes", "file", source.location, "error", err)
+			return baselines, false
+		}
+		if version == source.version {
+			return baselines, false
+		}
+	}
+
+	reloaded, err := w.loadBaselines(ctx, source)
+	if err != nil {
+		// Remote baselines are retried on the next interval rather than every scan
+		source.loaded = time.Now()
+		w.logger.Warn("failed to reload baseline state, comparing against the previous one", "file", source.location, "error", err)
+		return baselines, false
+	}
+	w.logger.Info("reloaded baseline state", "file", source.location)
+	return reloaded, true
+}
+
+// localVersion describes the modification times and sizes of a source's local
+// files, empty for a remote baseline
+func (s *baselineSource) localVersion() (string, error) {
+	if IsRemoteBaseline(s.location) {
+		return "", nil
+	}
+
+	files := []string{s.location}
+	if s.dir {
+		var err error
+		files, err = filepath.Glob(filepath.Join(s.location, "*.json"))
+		if err != nil {
+			return "", fmt.Errorf("failed to list baseline states in %s: %w", s.location, err)
+		}
+		sort.Strings(files)
+	}
+
+	versions := make([]string, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to check baseline state %s: %w", file, err)
+		}
+		versions = append(versions, fmt.Sprintf("%s:%d:%d", file, info.ModTime().UnixNano(), info.Size()))
+	}
+	return strings.Join(versions, ","), nil
+}
@@ -0,0 +1,609 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+// RemediationFormat is how suggestions to revert drift are written
+type RemediationFormat string
+
+const (
+	// RemediationCLI suggests AWS CLI commands
+	RemediationCLI RemediationFormat = "cli"
+	// RemediationTerraform suggests Terraform resource and import blocks
+	RemediationTerraform RemediationFormat = "terraform"
+)
+
+// ParseRemediationFormat parses a remediation format name: cli or terraform
+func ParseRemediationFormat(name string) (RemediationFormat, error) {
+	switch format := RemediationFormat(strings.ToLower(name)); format {
+	case RemediationCLI, RemediationTerraform:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown remediation format %q, expected cli or terraform", name)
+}
+
+// defaultNetworkAclRule is the rule number of the catch-all deny entry every
+// network ACL has, which cannot be changed
+const defaultNetworkAclRule = 32767
+
+// suggestRemediations sets the remediation of modified security groups, route
+// tables and network ACLs. Only the rules, routes and entries whose changes are
+// still in a difference's details are reverted, so suppressed changes are left alone.
+func suggestRemediations(differences []Difference, baseline, current *scanner.Network, format RemediationFormat) {
+	r := remediator{format: format, region: current.Region}
+	for i := range differences {
+		diff := &differences[i]
+		if diff.Type != Modified {
+			continue
+		}
+		switch diff.ResourceType {
+		case "SecurityGroup":
+			before, after := findByID(baseline.SecurityGroups, diff.ResourceID), findByID(current.SecurityGroups, diff.ResourceID)
+			if before != nil && after != nil {
+				diff.Remediation = r.securityGroup(before.(scanner.SecurityGroup), after.(scanner.SecurityGroup), diff.Details)
+			}
+		case "RouteTable":
+			before, after := findByID(baseline.RouteTables, diff.ResourceID), findByID(current.RouteTables, diff.ResourceID)
+			if before != nil && after != nil {
+				diff.Remediation = r.routeTable(before.(scanner.RouteTable), after.(scanner.RouteTable), diff.Details)
+			}
+		case "NetworkACL":
+			before, after := findByID(baseline.NetworkAcls, diff.ResourceID), findByID(current.NetworkAcls, diff.ResourceID)
+			if before != nil && after != nil {
+				diff.Remediation = r.networkAcl(before.(scanner.NetworkAcl), after.(scanner.NetworkAcl), diff.Details)
+			}
+		}
+	}
+}
+
+// findByID returns the element of a slice of resources with the given ID field,
+// or nil
+func findByID(resources interface{}, id string) interface{} {
+	items := reflect.ValueOf(resources)
+	for i := 0; i < items.Len(); i++ {
+		if items.Index(i).FieldByName("ID").String() == id {
+			return items.Index(i).Interface()
+		}
+	}
+	return nil
+}
+
+// detailChanged reports whether the details of a difference change the element
+// at path, such as "Routes[0.0.0.0/0]"
+func detailChanged(details []string, path string) bool {
+	for _, detail := range details {
+		if matchesFieldPath(detail, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// revertible is a rule, route or entry that a remediation deletes or recreates
+type revertible struct {
+	// create and remove are the AWS CLI arguments creating and deleting it
+	create [][]string
+	remove []string
+	// resources are the Terraform resources declaring it
+	resources []hclBlock
+	// importID is the Terraform import ID of its single resource, empty when
+	// it cannot be imported
+	importID string
+	// description names it in comments
+	description string
+}
+
+// remediator writes the commands reverting changed rules, routes and entries
+type remediator struct {
+	format RemediationFormat
+	region string
+}
+
+// revert returns the commands changing after back to before. Either is nil when
+// the element was added or removed. replace is the AWS CLI arguments changing
+// after to before in place, or nil to delete and recreate it.
+func (r remediator) revert(before, after *revertible, replace []string) []string {
+	if r.format == RemediationTerraform {
+		return r.revertTerraform(before, after)
+	}
+
+	var commands []string
+	switch {
+	case before != nil && after != nil && replace != nil:
+		commands = append(commands, r.cli(replace))
+	default:
+		if after != nil {
+			commands = append(commands, r.cli(after.remove))
+		}
+		if before != nil {
+			for _, args := range before.create {
+				commands = append(commands, r.cli(args))
+			}
+		}
+	}
+	return commands
+}
+
+// revertTerraform returns Terraform blocks changing after back to before: the
+// resources of a removed element, or an existing element imported into
+// resources with its baseline values
+func (r remediator) revertTerraform(before, after *revertible) []string {
+	if after == nil {
+		return blockStrings(before.resources)
+	}
+	if after.importID == "" {
+		return []string{fmt.Sprintf("# %s has no ID in the scan and cannot be imported, revert it with the AWS CLI", after.description)}
+	}
+
+	var suggestions []string
+	resources := after.resources
+	if before == nil {
+		suggestions = append(suggestions, fmt.Sprintf("# Import %s, then delete these blocks and apply again to remove it", after.description))
+	} else {
+		resources = before.resources
+	}
+	if len(resources) == 0 {
+		return nil
+	}
+	importBlock := hclBlock{kind: "import", attributes: [][2]string{
+		{"to", resources[0].labels[0] + "." + resources[0].labels[1]},
+		{"id", hclString(after.importID)},
+	}}
+	return append(append(suggestions, importBlock.String()), blockStrings(resources)...)
+}
+
+// cli formats an AWS CLI EC2 command
+func (r remediator) cli(args []string) string {
+	quoted := []string{"aws", "ec2"}
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	if r.region != "" {
+		quoted = append(quoted, "--region", r.region)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// securityGroup returns the commands reverting the changed rules of a security group
+func (r remediator) securityGroup(baseline, current scanner.SecurityGroup, details []string) []string {
+	var commands []string
+	for _, direction := range []struct {
+		field, name       string
+		baseline, current []scanner.SecurityGroupRule
+	}{
+		{"IngressRules", "ingress", baseline.IngressRules, current.IngressRules},
+		{"EgressRules", "egress", baseline.EgressRules, current.EgressRules},
+	} {
+		identity := securityGroupRuleIdentities(direction.baseline, direction.current)
+		baselineRules := make(map[string]scanner.SecurityGroupRule)
+		for _, rule := range direction.baseline {
+			baselineRules[identity(rule)] = rule
+		}
+		currentRules := make(map[string]bool)
+		for _, rule := range direction.current {
+			key := identity(rule)
+			currentRules[key] = true
+			if !detailChanged(details, fmt.Sprintf("%s[%s]", direction.field, key)) {
+				continue
+			}
+
+			after := r.securityGroupRule(current.ID, direction.name, rule)
+			before, existed := baselineRules[key]
+			if !existed {
+				commands = append(commands, r.revert(nil, after, nil)...)
+				continue
+			}
+			if onlyTagsChanged(before, rule) {
+				continue
+			}
+			var replace []string
+			if securityGroupRuleKey(before) == securityGroupRuleKey(rule) {
+				// Only the description changed
+				replace = []string{"update-security-group-rule-descriptions-" + direction.name,
+					"--group-id", current.ID, "--ip-permissions", ipPermissions(before)}
+			}
+			commands = append(commands, r.revert(r.securityGroupRule(current.ID, direction.name, before), after, replace)...)
+		}
+		for _, rule := range direction.baseline {
+			key := identity(rule)
+			if !currentRules[key] && detailChanged(details, fmt.Sprintf("%s[%s]", direction.field, key)) {
+				commands = append(commands, r.revert(r.securityGroupRule(current.ID, direction.name, rule), nil, nil)...)
+			}
+		}
+	}
+	return commands
+}
+
+// onlyTagsChanged reports whether two versions of a rule differ only in their
+// tags, or in the ID a baseline saved without IDs lacks
+func onlyTagsChanged(before, after scanner.SecurityGroupRule) bool {
+	before.Tags, after.Tags = nil, nil
+	before.ID, after.ID = "", ""
+	return reflect.DeepEqual(before, after)
+}
+
+// securityGroupRule describes how to create and delete a security group rule
+func (r remediator) securityGroupRule(groupID, direction string, rule scanner.SecurityGroupRule) *revertible {
+	element := &revertible{
+		create:      [][]string{{"authorize-security-group-" + direction, "--group-id", groupID, "--ip-permissions", ipPermissions(rule)}},
+		remove:      []string{"revoke-security-group-" + direction, "--group-id", groupID, "--ip-permissions", ipPermissions(rule)},
+		importID:    rule.ID,
+		description: fmt.Sprintf("%s rule %s", direction, securityGroupRuleKey(rule)),
+	}
+	if rule.ID != "" {
+		element.remove = []string{"revoke-security-group-" + direction, "--group-id", groupID, "--security-group-rule-ids", rule.ID}
+	}
+
+	// The Terraform rule resources take a single source each
+	var sources [][2]string
+	for _, cidr := range rule.CidrBlocks {
+		sources = append(sources, [2]string{"cidr_ipv4", cidr})
+	}
+	for _, cidr := range rule.Ipv6CidrBlocks {
+		sources = append(sources, [2]string{"cidr_ipv6", cidr})
+	}
+	for _, prefixList := range rule.PrefixListIds {
+		sources = append(sources, [2]string{"prefix_list_id", prefixList})
+	}
+	for _, group := range rule.ReferencedGroupIDs() {
+		sources = append(sources, [2]string{"referenced_security_group_id", group})
+	}
+	name := rule.ID
+	if name == "" {
+		name = groupID + " " + direction + " " + securityGroupRuleKey(rule)
+	}
+	for i, source := range sources {
+		resourceName := name
+		if len(sources) > 1 {
+			resourceName = fmt.Sprintf("%s %d", name, i+1)
+		}
+		attributes := [][2]string{
+			{"security_group_id", hclString(groupID)},
+			{"ip_protocol", hclString(rule.IpProtocol)},
+		}
+		if rule.IpProtocol != "-1" {
+			attributes = append(attributes,
+				[2]string{"from_port", strconv.Itoa(int(rule.FromPort))},
+				[2]string{"to_port", strconv.Itoa(int(rule.ToPort))})
+		}
+		attributes = append(attributes, [2]string{source[0], hclString(source[1])})
+		if rule.Description != "" {
+			attributes = append(attributes, [2]string{"description", hclString(rule.Description)})
+		}
+		element.resources = append(element.resources, hclBlock{
+			kind:       "resource",
+			labels:     []string{"aws_vpc_security_group_" + direction + "_rule", terraformName(resourceName)},
+			attributes: attributes,
+		})
+	}
+	if len(element.resources) != 1 {
+		element.importID = ""
+	}
+	return element
+}
+
+// ipPermission is a security group rule in the form the AWS CLI takes it
+type ipPermission struct {
+	IpProtocol       string              `json:"IpProtocol"`
+	FromPort         *int32              `json:"FromPort,omitempty"`
+	ToPort           *int32              `json:"ToPort,omitempty"`
+	IpRanges         []map[string]string `json:"IpRanges,omitempty"`
+	Ipv6Ranges       []map[string]string `json:"Ipv6Ranges,omitempty"`
+	PrefixListIds    []map[string]string `json:"PrefixListIds,omitempty"`
+	UserIdGroupPairs []map[string]string `json:"UserIdGroupPairs,omitempty"`
+}
+
+// ipPermissions encodes a security group rule as the JSON --ip-permissions value
+func ipPermissions(rule scanner.SecurityGroupRule) string {
+	permission := ipPermission{IpProtocol: rule.IpProtocol}
+	if rule.IpProtocol != "-1" {
+		permission.FromPort, permission.ToPort = &rule.FromPort, &rule.ToPort
+	}
+	withDescription := func(entry map[string]string) map[string]string {
+		if rule.Description != "" {
+			entry["Description"] = rule.Description
+		}
+		return entry
+	}
+	for _, cidr := range rule.CidrBlocks {
+		permission.IpRanges = append(permission.IpRanges, withDescription(map[string]string{"CidrIp": cidr}))
+	}
+	for _, cidr := range rule.Ipv6CidrBlocks {
+		permission.Ipv6Ranges = append(permission.Ipv6Ranges, withDescription(map[string]string{"CidrIpv6": cidr}))
+	}
+	for _, prefixList := range rule.PrefixListIds {
+		permission.PrefixListIds = append(permission.PrefixListIds, withDescription(map[string]string{"PrefixListId": prefixList}))
+	}
+	for _, group := range rule.ReferencedGroups {
+		pair := map[string]string{"GroupId": group.GroupID}
+		if group.OwnerID != "" {
+			pair["UserId"] = group.OwnerID
+		}
+		if group.PeeringID != "" {
+			pair["VpcPeeringConnectionId"] = group.PeeringID
+		}
+		permission.UserIdGroupPairs = append(permission.UserIdGroupPairs, withDescription(pair))
+	}
+
+	data, _ := json.Marshal([]ipPermission{permission})
+	return string(data)
+}
+
+// routeTargets are the route target fields with their AWS CLI flag and Terraform
+// attribute. Routes to instances also name the instance's network interface.
+var routeTargets = []struct {
+	flag, attribute string
+	value           func(scanner.Route) string
+}{
+	{"--gateway-id", "gateway_id", func(r scanner.Route) string { return r.GatewayID }},
+	{"--nat-gateway-id", "nat_gateway_id", func(r scanner.Route) string { return r.NatGatewayID }},
+	{"--egress-only-internet-gateway-id", "egress_only_gateway_id", func(r scanner.Route) string { return r.EgressOnlyGatewayID }},
+	{"--carrier-gateway-id", "carrier_gateway_id", func(r scanner.Route) string { return r.CarrierGatewayID }},
+	{"--local-gateway-id", "local_gateway_id", func(r scanner.Route) string { return r.LocalGatewayID }},
+	{"--transit-gateway-id", "transit_gateway_id", func(r scanner.Route) string { return r.TransitGatewayID }},
+	{"--vpc-peering-connection-id", "vpc_peering_connection_id", func(r scanner.Route) string { return r.VpcPeeringID }},
+	{"--network-interface-id", "network_interface_id", func(r scanner.Route) string { return r.NetworkInterfaceID }},
+}
+
+// routeTarget returns the flag, Terraform attribute and ID of a route's target
+func routeTarget(route scanner.Route) (flag, attribute, target string) {
+	for _, t := range routeTargets {
+		if value := t.value(route); value != "" {
+			return t.flag, t.attribute, value
+		}
+	}
+	return "", "", ""
+}
+
+// revertibleRoute reports whether a route can be created and deleted: the local
+// route and propagated routes are managed by AWS
+func revertibleRoute(route scanner.Route) bool {
+	return route.GatewayID != "local" && route.Origin != "CreateRouteTable" && route.Origin != "EnableVgwRoutePropagation"
+}
+
+// routeTable returns the commands reverting the changed routes of a route table
+func (r remediator) routeTable(baseline, current scanner.RouteTable, details []string) []string {
+	var commands []string
+	baselineRoutes := make(map[string]scanner.Route)
+	for _, route := range baseline.Routes {
+		baselineRoutes[route.Destination()] = route
+	}
+	currentRoutes := make(map[string]bool)
+	for _, route := range current.Routes {
+		currentRoutes[route.Destination()] = true
+		if !revertibleRoute(route) || !detailChanged(details, fmt.Sprintf("Routes[%s]", route.Destination())) {
+			continue
+		}
+
+		before, existed := baselineRoutes[route.Destination()]
+		if !existed {
+			commands = append(commands, r.revert(nil, r.route(current.ID, route), nil)...)
+			continue
+		}
+		flag, _, target := routeTarget(before)
+		if _, _, currentTarget := routeTarget(route); target == "" || target == currentTarget {
+			// Only the route's state changed
+			continue
+		}
+		replace := append(routeArgs("replace-route", current.ID, before), flag, target)
+		commands = append(commands, r.revert(r.route(current.ID, before), r.route(current.ID, route), replace)...)
+	}
+	for _, route := range baseline.Routes {
+		if !currentRoutes[route.Destination()] && revertibleRoute(route) && detailChanged(details, fmt.Sprintf("Routes[%s]", route.Destination())) {
+			commands = append(commands, r.revert(r.route(current.ID, route), nil, nil)...)
+		}
+	}
+	return commands
+}
+
+// routeArgs returns the AWS CLI arguments of a route command up to its target
+func routeArgs(command, routeTableID string, route scanner.Route) []string {
+	args := []string{command, "--route-table-id", routeTableID}
+	if route.DestinationCidr != "" {
+		return append(args, "--destination-cidr-block", route.DestinationCidr)
+	}
+	return append(args, "--destination-ipv6-cidr-block", route.DestinationIpv6Cidr)
+}
+
+// route describes how to create and delete a route
+func (r remediator) route(routeTableID string, route scanner.Route) *revertible {
+	flag, attribute, target := routeTarget(route)
+	element := &revertible{
+		remove:      routeArgs("delete-route", routeTableID, route),
+		importID:    routeTableID + "_" + route.Destination(),
+		description: "route " + route.Destination(),
+	}
+	if target == "" {
+		return element
+	}
+	element.create = [][]string{append(routeArgs("create-route", routeTableID, route), flag, target)}
+
+	attributes := [][2]string{{"route_table_id", hclString(routeTableID)}}
+	if route.DestinationCidr != "" {
+		attributes = append(attributes, [2]string{"destination_cidr_block", hclString(route.DestinationCidr)})
+	} else {
+		attributes = append(attributes, [2]string{"destination_ipv6_cidr_block", hclString(route.DestinationIpv6Cidr)})
+	}
+	attributes = append(attributes, [2]string{attribute, hclString(target)})
+	element.resources = []hclBlock{{
+		kind:       "resource",
+		labels:     []string{"aws_route", terraformName(routeTableID + " " + route.Destination())},
+		attributes: attributes,
+	}}
+	return element
+}
+
+// networkAcl returns the commands reverting the changed entries of a network ACL
+func (r remediator) networkAcl(baseline, current scanner.NetworkAcl, details []string) []string {
+	var commands []string
+	baselineEntries := make(map[string]scanner.NetworkAclEntry)
+	for _, entry := range baseline.Entries {
+		baselineEntries[networkAclEntryKey(entry)] = entry
+	}
+	currentEntries := make(map[string]bool)
+	for _, entry := range current.Entries {
+		key := networkAclEntryKey(entry)
+		currentEntries[key] = true
+		if entry.RuleNumber == defaultNetworkAclRule || !detailChanged(details, fmt.Sprintf("Entries[%s]", key)) {
+			continue
+		}
+
+		after := r.networkAclEntry(current.ID, entry)
+		if before, existed := baselineEntries[key]; existed {
+			replace := networkAclEntryArgs("replace-network-acl-entry", current.ID, before)
+			commands = append(commands, r.revert(r.networkAclEntry(current.ID, before), after, replace)...)
+		} else {
+			commands = append(commands, r.revert(nil, after, nil)...)
+		}
+	}
+	for _, entry := range baseline.Entries {
+		key := networkAclEntryKey(entry)
+		if !currentEntries[key] && entry.RuleNumber != defaultNetworkAclRule && detailChanged(details, fmt.Sprintf("Entries[%s]", key)) {
+			commands = append(commands, r.revert(r.networkAclEntry(current.ID, entry), nil, nil)...)
+		}
+	}
+	return commands
+}
+
+// networkAclEntryArgs returns the AWS CLI arguments creating or replacing an entry
+func networkAclEntryArgs(command, networkAclID string, entry scanner.NetworkAclEntry) []string {
+	args := []string{command, "--network-acl-id", networkAclID, "--rule-number", strconv.Itoa(int(entry.RuleNumber)), directionFlag(entry),
+		"--protocol", entry.Protocol, "--rule-action", entry.RuleAction}
+	if entry.CidrBlock != "" {
+		args = append(args, "--cidr-block", entry.CidrBlock)
+	} else {
+		args = append(args, "--ipv6-cidr-block", entry.Ipv6CidrBlock)
+	}
+	if entry.PortRange != nil {
+		args = append(args, "--port-range", fmt.Sprintf("From=%d,To=%d", entry.PortRange.From, entry.PortRange.To))
+	}
+	if entry.IcmpType != nil {
+		args = append(args, "--icmp-type-code", fmt.Sprintf("Type=%d,Code=%d", entry.IcmpType.Type, entry.IcmpType.Code))
+	}
+	return args
+}
+
+// directionFlag returns the AWS CLI flag selecting an entry's direction
+func directionFlag(entry scanner.NetworkAclEntry) string {
+	if entry.Egress {
+		return "--egress"
+	}
+	return "--ingress"
+}
+
+// networkAclEntry describes how to create and delete a network ACL entry
+func (r remediator) networkAclEntry(networkAclID string, entry scanner.NetworkAclEntry) *revertible {
+	attributes := [][2]string{
+		{"network_acl_id", hclString(networkAclID)},
+		{"rule_number", strconv.Itoa(int(entry.RuleNumber))},
+		{"egress", strconv.FormatBool(entry.Egress)},
+		{"protocol", hclString(entry.Protocol)},
+		{"rule_action", hclString(entry.RuleAction)},
+	}
+	if entry.CidrBlock != "" {
+		attributes = append(attributes, [2]string{"cidr_block", hclString(entry.CidrBlock)})
+	} else {
+		attributes = append(attributes, [2]string{"ipv6_cidr_block", hclString(entry.Ipv6CidrBlock)})
+	}
+	if entry.PortRange != nil {
+		attributes = append(attributes,
+			[2]string{"from_port", strconv.Itoa(int(entry.PortRange.From))},
+			[2]string{"to_port", strconv.Itoa(int(entry.PortRange.To))})
+	}
+	if entry.IcmpType != nil {
+		attributes = append(attributes,
+			[2]string{"icmp_type", strconv.Itoa(int(entry.IcmpType.Type))},
+			[2]string{"icmp_code", strconv.Itoa(int(entry.IcmpType.Code))})
+	}
+
+	return &revertible{
+		create:   [][]string{networkAclEntryArgs("create-network-acl-entry", networkAclID, entry)},
+		remove:   []string{"delete-network-acl-entry", "--network-acl-id", networkAclID, "--rule-number", strconv.Itoa(int(entry.RuleNumber)), directionFlag(entry)},
+		importID: fmt.Sprintf("%s:%d:%s:%t", networkAclID, entry.RuleNumber, entry.Protocol, entry.Egress),
+		resources: []hclBlock{{
+			kind:       "resource",
+			labels:     []string{"aws_network_acl_rule", terraformName(networkAclID + " " + networkAclEntryKey(entry))},
+			attributes: attributes,
+		}},
+		description: "entry " + networkAclEntryKey(entry),
+	}
+}
+
+// hclBlock is a Terraform block with attributes, written as terraform fmt would
+type hclBlock struct {
+	kind   string
+	labels []string
+	// attributes are names with their values already encoded
+	attributes [][2]string
+}
+
+// String formats the block
+func (b hclBlock) String() string {
+	var result strings.Builder
+	result.WriteString(b.kind)
+	for _, label := range b.labels {
+		result.WriteString(" " + strconv.Quote(label))
+	}
+	result.WriteString(" {\n")
+	width := 0
+	for _, attribute := range b.attributes {
+		width = max(width, len(attribute[0]))
+	}
+	for _, attribute := range b.attributes {
+		result.WriteString(fmt.Sprintf("  %-*s = %s\n", width, attribute[0], attribute[1]))
+	}
+	result.WriteString("}")
+	return result.String()
+}
+
+// blockStrings formats Terraform blocks
+func blockStrings(blocks []hclBlock) []string {
+	result := make([]string, len(blocks))
+	for i, block := range blocks {
+		result[i] = block.String()
+	}
+	return result
+}
+
+// hclString encodes a Terraform string, escaping template sequences
+func hclString(s string) string {
+	s = strings.NewReplacer("${", "$${", "%{", "%%{").Replace(s)
+	return strconv.Quote(s)
+}
+
+// terraformName turns an identifier such as "rtb-1 0.0.0.0/0" into a Terraform
+// resource name such as "revert_rtb_1_0_0_0_0_0"
+func terraformName(id string) string {
+	var name strings.Builder
+	name.WriteString("revert")
+	inWord := false
+	for _, c := range strings.ToLower(id) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			if !inWord {
+				name.WriteByte('_')
+				inWord = true
+			}
+			name.WriteRune(c)
+			continue
+		}
+		inWord = false
+	}
+	return name.String()
+}
+
+// shellQuote quotes a command argument for POSIX shells when it needs it
+func shellQuote(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(c rune) bool {
+		return !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.ContainsRune("-_./:=,@", c))
+	}) == -1 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,150 @@
+package watch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func remediationNetworks() (*scanner.Network, *scanner.Network) {
+	https := scanner.SecurityGroupRule{ID: "sgr-1", IpProtocol: "tcp", FromPort: 443, ToPort: 443, CidrBlocks: []string{"10.0.0.0/8"}}
+	baseline := &scanner.Network{
+		Region: "us-east-1",
+		SecurityGroups: []scanner.SecurityGroup{
+			{ID: "sg-1", IngressRules: []scanner.SecurityGroupRule{https}},
+		},
+		RouteTables: []scanner.RouteTable{{ID: "rtb-1", Routes: []scanner.Route{
+			{DestinationCidr: "10.0.0.0/16", GatewayID: "local", Origin: "CreateRouteTable"},
+			{DestinationCidr: "0.0.0.0/0", NatGatewayID: "nat-1", Origin: "CreateRoute"},
+			{DestinationCidr: "192.168.0.0/16", TransitGatewayID: "tgw-1", Origin: "CreateRoute"},
+		}}},
+		NetworkAcls: []scanner.NetworkAcl{{ID: "acl-1", Entries: []scanner.NetworkAclEntry{
+			{RuleNumber: 100, Protocol: "6", RuleAction: "allow", CidrBlock: "10.0.0.0/8", PortRange: &scanner.NetworkAclPortRange{From: 443, To: 443}},
+		}}},
+	}
+
+	open := scanner.SecurityGroupRule{ID: "sgr-2", IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"0.0.0.0/0"}, Description: "it's temporary"}
+	current := &scanner.Network{
+		Region: "us-east-1",
+		SecurityGroups: []scanner.SecurityGroup{
+			{ID: "sg-1", IngressRules: []scanner.SecurityGroupRule{baseline.SecurityGroups[0].IngressRules[0], open}},
+		},
+		RouteTables: []scanner.RouteTable{{ID: "rtb-1", Routes: []scanner.Route{
+			{DestinationCidr: "10.0.0.0/16", GatewayID: "local", Origin: "CreateRouteTable"},
+			{DestinationCidr: "0.0.0.0/0", GatewayID: "igw-1", Origin: "CreateRoute"},
+			{DestinationCidr: "172.16.0.0/12", VpcPeeringID: "pcx-1", Origin: "CreateRoute"},
+		}}},
+		NetworkAcls: []scanner.NetworkAcl{{ID: "acl-1", Entries: []scanner.NetworkAclEntry{
+			{RuleNumber: 100, Protocol: "6", RuleAction: "allow", CidrBlock: "0.0.0.0/0", PortRange: &scanner.NetworkAclPortRange{From: 443, To: 443}},
+		}}},
+	}
+	return baseline, current
+}
+
+// remediationOf returns the remediation of the difference of a resource type
+func remediationOf(t *testing.T, differences []Difference, resourceType string) []string {
+	t.Helper()
+	for _, diff := range differences {
+		if diff.ResourceType == resourceType {
+			return diff.Remediation
+		}
+	}
+	t.Fatalf("no %s difference in %+v", resourceType, differences)
+	return nil
+}
+
+func TestRemediationCLI(t *testing.T) {
+	comparator := NewComparator(false)
+	comparator.SetRemediation(RemediationCLI)
+	differences := comparator.Compare(remediationNetworks())
+
+	tests := []struct {
+		resourceType string
+		want         []string
+	}{
+		{"SecurityGroup", []string{
+			"aws ec2 revoke-security-group-ingress --group-id sg-1 --security-group-rule-ids sgr-2 --region us-east-1",
+		}},
+		{"RouteTable", []string{
+			"aws ec2 replace-route --route-table-id rtb-1 --destination-cidr-block 0.0.0.0/0 --nat-gateway-id nat-1 --region us-east-1",
+			"aws ec2 delete-route --route-table-id rtb-1 --destination-cidr-block 172.16.0.0/12 --region us-east-1",
+			"aws ec2 create-route --route-table-id rtb-1 --destination-cidr-block 192.168.0.0/16 --transit-gateway-id tgw-1 --region us-east-1",
+		}},
+		{"NetworkACL", []string{
+			"aws ec2 replace-network-acl-entry --network-acl-id acl-1 --rule-number 100 --ingress --protocol 6 --rule-action allow --cidr-block 10.0.0.0/8 --port-range From=443,To=443 --region us-east-1",
+		}},
+	}
+	for _, tt := range tests {
+		got := remediationOf(t, differences, tt.resourceType)
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s remediation:\n%s\nwant:\n%s", tt.resourceType, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestRemediationRestoresRuleWithoutID(t *testing.T) {
+	baseline, current := remediationNetworks()
+	rule := scanner.SecurityGroupRule{IpProtocol: "tcp", FromPort: 22, ToPort: 22, CidrBlocks: []string{"10.0.0.0/8"}, Description: "it's ssh"}
+	baseline.SecurityGroups[0].IngressRules = []scanner.SecurityGroupRule{rule}
+	current.SecurityGroups[0].IngressRules = nil
+
+	comparator := NewComparator(false)
+	comparator.SetRemediation(RemediationCLI)
+	got := remediationOf(t, comparator.Compare(baseline, current), "SecurityGroup")
+
+	want := `aws ec2 authorize-security-group-ingress --group-id sg-1 --ip-permissions '[{"IpProtocol":"tcp","FromPort":22,"ToPort":22,"IpRanges":[{"CidrIp":"10.0.0.0/8","Description":"it'\''s ssh"}]}]' --region us-east-1`
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("unexpected remediation %q", got)
+	}
+}
+
+func TestRemediationTerraform(t *testing.T) {
+	comparator := NewComparator(false)
+	comparator.SetRemediation(RemediationTerraform)
+	differences := comparator.Compare(remediationNetworks())
+
+	routes := strings.Join(remediationOf(t, differences, "RouteTable"), "\n")
+	for _, want := range []string{
+		"import {\n  to = aws_route.revert_rtb_1_0_0_0_0_0\n  id = \"rtb-1_0.0.0.0/0\"\n}",
+		"resource \"aws_route\" \"revert_rtb_1_0_0_0_0_0\" {\n  route_table_id         = \"rtb-1\"\n  destination_cidr_block = \"0.0.0.0/0\"\n  nat_gateway_id         = \"nat-1\"\n}",
+		"# Import route 172.16.0.0/12, then delete these blocks and apply again to remove it",
+		"transit_gateway_id     = \"tgw-1\"",
+	} {
+		if !strings.Contains(routes, want) {
+			t.Errorf("expected route remediation to contain %q:\n%s", want, routes)
+		}
+	}
+
+	rules := strings.Join(remediationOf(t, differences, "SecurityGroup"), "\n")
+	if !strings.Contains(rules, "id = \"sgr-2\"") || !strings.Contains(rules, "resource \"aws_vpc_security_group_ingress_rule\" \"revert_sgr_2\"") {
+		t.Errorf("expected the added rule to be imported:\n%s", rules)
+	}
+
+	entries := strings.Join(remediationOf(t, differences, "NetworkACL"), "\n")
+	if !strings.Contains(entries, "id = \"acl-1:100:6:false\"") || !strings.Contains(entries, "cidr_block     = \"10.0.0.0/8\"") {
+		t.Errorf("expected the entry to be imported with its baseline values:\n%s", entries)
+	}
+}
+
+func TestRemediationSkipsSuppressedChanges(t *testing.T) {
+	rules := &IgnoreRules{Rules: []IgnoreRule{{ResourceType: "RouteTable", Field: "Routes[172.16.0.0/12]"}}}
+	comparator := NewComparator(false)
+	comparator.SetIgnoreRules(rules)
+	comparator.SetRemediation(RemediationCLI)
+
+	for _, command := range remediationOf(t, comparator.Compare(remediationNetworks()), "RouteTable") {
+		if strings.Contains(command, "172.16.0.0/12") {
+			t.Errorf("suppressed route should not be reverted: %s", command)
+		}
+	}
+}
+
+func TestParseRemediationFormat(t *testing.T) {
+	if format, err := ParseRemediationFormat("Terraform"); err != nil || format != RemediationTerraform {
+		t.Errorf("expected terraform, got %q, %v", format, err)
+	}
+	if _, err := ParseRemediationFormat("pulumi"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
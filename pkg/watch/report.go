@@ -0,0 +1,280 @@
+package watch
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DiffReportSchemaVersion is the version of the JSON diff report schema.
+// Bump it whenever a field is renamed or removed.
+const DiffReportSchemaVersion = "1"
+
+// Supported structured diff output formats
+const (
+	DiffOutputJSON  = "json"
+	DiffOutputJUnit = "junit"
+	DiffOutputSARIF = "sarif"
+)
+
+// DiffReport is the machine-readable representation of a comparison
+type DiffReport struct {
+	SchemaVersion string       `json:"schema_version"`
+	GeneratedAt   time.Time    `json:"generated_at"`
+	Summary       DiffSummary  `json:"summary"`
+	Differences   []Difference `json:"differences"`
+}
+
+// DiffSummary counts differences by type
+type DiffSummary struct {
+	Total    int `json:"total"`
+	Added    int `json:"added"`
+	Removed  int `json:"removed"`
+	Modified int `json:"modified"`
+}
+
+// NewDiffReport creates a report with differences in a stable order
+func NewDiffReport(differences []Difference) DiffReport {
+	sorted := SortDifferences(differences)
+
+	report := DiffReport{
+		SchemaVersion: DiffReportSchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Differences:   sorted,
+	}
+
+	for _, diff := range sorted {
+		switch diff.Type {
+		case Added:
+			report.Summary.Added++
+		case Removed:
+			report.Summary.Removed++
+		case Modified:
+			report.Summary.Modified++
+		}
+	}
+	report.Summary.Total = len(sorted)
+
+	return report
+}
+
+// SortDifferences returns a copy of differences ordered by resource type, resource ID and difference type
+func SortDifferences(differences []Difference) []Difference {
+	sorted := make([]Difference, len(differences))
+	copy(sorted, differences)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ResourceType != sorted[j].ResourceType {
+			return sorted[i].ResourceType < sorted[j].ResourceType
+		}
+		if sorted[i].ResourceID != sorted[j].ResourceID {
+			return sorted[i].ResourceID < sorted[j].ResourceID
+		}
+		return sorted[i].Type < sorted[j].Type
+	})
+	return sorted
+}
+
+// FormatDifferences renders differences in a structured output format
+func FormatDifferences(differences []Difference, format string) (string, error) {
+	report := NewDiffReport(differences)
+
+	switch format {
+	case DiffOutputJSON:
+		return formatJSONReport(report)
+	case DiffOutputJUnit:
+		return formatJUnitReport(report)
+	case DiffOutputSARIF:
+		return formatSARIFReport(report)
+	default:
+		return "", fmt.Errorf("unsupported diff output format: %s", format)
+	}
+}
+
+func formatJSONReport(report DiffReport) (string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal diff report: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// JUnit XML structures
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+func formatJUnitReport(report DiffReport) (string, error) {
+	suite := junitTestSuite{
+		Name:      "pikaatools.drift",
+		Timestamp: report.GeneratedAt.Format(time.RFC3339),
+	}
+
+	if len(report.Differences) == 0 {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      "infrastructure state matches baseline",
+			ClassName: "pikaatools.drift",
+		})
+	}
+
+	for _, diff := range report.Differences {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      fmt.Sprintf("%s %s", diff.ResourceType, diff.ResourceID),
+			ClassName: "pikaatools.drift." + diff.ResourceType,
+			Failure: &junitFailure{
+				Message: diff.Description,
+				Type:    diff.Type.String(),
+				Body:    strings.Join(diff.Details, "\n"),
+			},
+		})
+	}
+
+	suite.Tests = len(suite.TestCases)
+	suite.Failures = len(report.Differences)
+
+	suites := junitTestSuites{
+		Name:     "pikaatools",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JUnit report: %w", err)
+	}
+	return xml.Header + string(data) + "\n", nil
+}
+
+// SARIF 2.1.0 structures
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	Kind               string `json:"kind"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+type sarifProperties struct {
+	Details []string `json:"details,omitempty"`
+}
+
+// sarifRuleID returns the SARIF rule identifier for a difference type
+func sarifRuleID(t DifferenceType) string {
+	return "drift/" + t.String()
+}
+
+func formatSARIFReport(report DiffReport) (string, error) {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "pikaatools",
+				InformationURI: "https://github.com/Yiu-Kelvin/pikaatools",
+				Rules: []sarifRule{
+					{ID: sarifRuleID(Added), ShortDescription: sarifMessage{Text: "Resource added since baseline"}},
+					{ID: sarifRuleID(Removed), ShortDescription: sarifMessage{Text: "Resource removed since baseline"}},
+					{ID: sarifRuleID(Modified), ShortDescription: sarifMessage{Text: "Resource configuration changed since baseline"}},
+				},
+			},
+		},
+		Results: []sarifResult{},
+	}
+
+	for _, diff := range report.Differences {
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  sarifRuleID(diff.Type),
+			Level:   "warning",
+			Message: sarifMessage{Text: fmt.Sprintf("%s %s: %s", diff.ResourceType, diff.ResourceID, diff.Description)},
+			Locations: []sarifLocation{
+				{
+					LogicalLocations: []sarifLogicalLocation{
+						{
+							Name:               diff.ResourceID,
+							Kind:               diff.ResourceType,
+							FullyQualifiedName: diff.ResourceType + "/" + diff.ResourceID,
+						},
+					},
+				},
+			},
+			Properties: sarifProperties{Details: diff.Details},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal SARIF report: %w", err)
+	}
+	return string(data) + "\n", nil
+}
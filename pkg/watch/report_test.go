@@ -0,0 +1,133 @@
+package watch
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func testDifferences() []Difference {
+	return []Difference{
+		{
+			Type:         Modified,
+			ResourceType: "SecurityGroup",
+			ResourceID:   "sg-12345",
+			Description:  "securitygroup configuration changed",
+			Details:      []string{"Description: old → new"},
+		},
+		{
+			Type:         Added,
+			ResourceType: "Subnet",
+			ResourceID:   "subnet-b",
+			Description:  "New subnet created",
+		},
+		{
+			Type:         Removed,
+			ResourceType: "Subnet",
+			ResourceID:   "subnet-a",
+			Description:  "subnet was deleted",
+		},
+	}
+}
+
+func TestNewDiffReport(t *testing.T) {
+	report := NewDiffReport(testDifferences())
+
+	if report.SchemaVersion != DiffReportSchemaVersion {
+		t.Errorf("Expected schema version %s, got %s", DiffReportSchemaVersion, report.SchemaVersion)
+	}
+
+	if report.Summary.Total != 3 || report.Summary.Added != 1 || report.Summary.Removed != 1 || report.Summary.Modified != 1 {
+		t.Errorf("Unexpected summary: %+v", report.Summary)
+	}
+
+	// Differences should be sorted by resource type then ID
+	expectedIDs := []string{"sg-12345", "subnet-a", "subnet-b"}
+	for i, id := range expectedIDs {
+		if report.Differences[i].ResourceID != id {
+			t.Errorf("Expected difference %d to be %s, got %s", i, id, report.Differences[i].ResourceID)
+		}
+	}
+}
+
+func TestFormatDifferencesJSON(t *testing.T) {
+	output, err := FormatDifferences(testDifferences(), DiffOutputJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var report DiffReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Expected valid JSON report: %v", err)
+	}
+
+	if report.Differences[1].Type != Removed {
+		t.Errorf("Expected difference type to round-trip, got %s", report.Differences[1].Type)
+	}
+
+	if !strings.Contains(output, `"type": "modified"`) {
+		t.Error("Expected difference types to be encoded by name")
+	}
+}
+
+func TestFormatDifferencesJUnit(t *testing.T) {
+	output, err := FormatDifferences(testDifferences(), DiffOutputJUnit)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal([]byte(output), &suites); err != nil {
+		t.Fatalf("Expected valid JUnit XML: %v", err)
+	}
+
+	if suites.Failures != 3 {
+		t.Errorf("Expected 3 failures, got %d", suites.Failures)
+	}
+
+	// No differences should still produce a passing test case
+	output, err = FormatDifferences(nil, DiffOutputJUnit)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := xml.Unmarshal([]byte(output), &suites); err != nil {
+		t.Fatalf("Expected valid JUnit XML: %v", err)
+	}
+
+	if suites.Tests != 1 || suites.Failures != 0 {
+		t.Errorf("Expected 1 passing test, got %d tests and %d failures", suites.Tests, suites.Failures)
+	}
+}
+
+func TestFormatDifferencesSARIF(t *testing.T) {
+	output, err := FormatDifferences(testDifferences(), DiffOutputSARIF)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal([]byte(output), &log); err != nil {
+		t.Fatalf("Expected valid SARIF JSON: %v", err)
+	}
+
+	if log.Version != "2.1.0" {
+		t.Errorf("Expected SARIF version 2.1.0, got %s", log.Version)
+	}
+
+	if len(log.Runs) != 1 || len(log.Runs[0].Results) != 3 {
+		t.Fatalf("Expected 1 run with 3 results")
+	}
+
+	if log.Runs[0].Results[0].RuleID != "drift/modified" {
+		t.Errorf("Expected rule drift/modified, got %s", log.Runs[0].Results[0].RuleID)
+	}
+}
+
+func TestFormatDifferencesUnsupported(t *testing.T) {
+	_, err := FormatDifferences(testDifferences(), "yaml")
+	if err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
@@ -0,0 +1,79 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// DefaultReportLinkExpiry is how long links to uploaded HTML reports stay valid,
+// the longest S3 accepts for presigned URLs
+const DefaultReportLinkExpiry = 7 * 24 * time.Hour
+
+// reportPutAPI is the subset of the S3 API used to upload HTML reports
+type reportPutAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// reportPresigner is the subset of the S3 presign client used to link to HTML reports
+type reportPresigner interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// ReportUploader uploads HTML drift reports to S3 under keys of the form
+// prefix/region/YYYYMMDDTHHMMSSZ.html and links to them with presigned URLs, so
+// notifications can point to the full diff without making the bucket public
+type ReportUploader struct {
+	api     reportPutAPI
+	presign reportPresigner
+	bucket  string
+	prefix  string
+	expiry  time.Duration
+}
+
+// NewReportUploader creates an uploader writing under an s3://bucket/prefix URI
+func NewReportUploader(client *s3.Client, uri string) (*ReportUploader, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "s3" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid S3 URI %q, expected s3://bucket/prefix", uri)
+	}
+	return &ReportUploader{
+		api:     client,
+		presign: s3.NewPresignClient(client),
+		bucket:  parsed.Host,
+		prefix:  strings.Trim(parsed.Path, "/"),
+		expiry:  DefaultReportLinkExpiry,
+	}, nil
+}
+
+// Upload writes an HTML report of a scan taken at the given time and returns a
+// presigned URL to view it
+func (u *ReportUploader) Upload(ctx context.Context, report, region string, at time.Time) (string, error) {
+	key := path.Join(u.prefix, region, at.UTC().Format("20060102T150405Z")+".html")
+	uri := fmt.Sprintf("s3://%s/%s", u.bucket, key)
+	contentType := "text/html; charset=utf-8"
+	_, err := u.api.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      &u.bucket,
+		Key:         &key,
+		Body:        strings.NewReader(report),
+		ContentType: &contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload HTML report %s: %w", uri, err)
+	}
+
+	request, err := u.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: &u.bucket,
+		Key:    &key,
+	}, s3.WithPresignExpires(u.expiry))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign HTML report %s: %w", uri, err)
+	}
+	return request.URL, nil
+}
@@ -0,0 +1,57 @@
+package watch
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type fakeReportS3 struct {
+	key     string
+	body    string
+	expires time.Duration
+}
+
+func (f *fakeReportS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, _ := io.ReadAll(params.Body)
+	f.key, f.body = *params.Key, string(data)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeReportS3) PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	options := s3.PresignOptions{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	f.expires = options.Expires
+	return &v4.PresignedHTTPRequest{URL: "https://" + *params.Bucket + ".s3.amazonaws.com/" + *params.Key + "?X-Amz-Signature=abc"}, nil
+}
+
+func TestReportUploader(t *testing.T) {
+	uploader, err := NewReportUploader(s3.New(s3.Options{Region: "us-east-1"}), "s3://reports/drift/")
+	if err != nil {
+		t.Fatalf("NewReportUploader failed: %v", err)
+	}
+	fake := &fakeReportS3{}
+	uploader.api, uploader.presign = fake, fake
+
+	at := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	link, err := uploader.Upload(context.Background(), "<html></html>", "eu-west-1", at)
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if fake.key != "drift/eu-west-1/20240115T103000Z.html" || fake.body != "<html></html>" {
+		t.Errorf("uploaded %q to %s", fake.body, fake.key)
+	}
+	if link != "https://reports.s3.amazonaws.com/drift/eu-west-1/20240115T103000Z.html?X-Amz-Signature=abc" || fake.expires != DefaultReportLinkExpiry {
+		t.Errorf("link = %s valid for %s", link, fake.expires)
+	}
+
+	if _, err := NewReportUploader(nil, "reports/drift"); err == nil {
+		t.Error("NewReportUploader accepted a URI without the s3 scheme")
+	}
+}
@@ -0,0 +1,86 @@
+package watch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// watchTypes maps the resource types accepted by ParseScope to the scanner
+// resource types that have to be scanned to compare them and the resource type
+// of their differences. Subnets take their route table, network ACL and type from
+// route tables and network ACLs, and VPCs list the IDs of their resources.
+var watchTypes = map[string]struct {
+	scan    []string
+	compare string
+}{
+	"vpcs":                       {[]string{"vpc", "subnet", "internet_gateway", "egress_only_internet_gateway", "nat_gateway", "security_group", "network_acl"}, "VPC"},
+	"subnets":                    {[]string{"subnet", "route_table", "network_acl"}, "Subnet"},
+	"securitygroups":             {[]string{"security_group"}, "SecurityGroup"},
+	"networkacls":                {[]string{"network_acl"}, "NetworkACL"},
+	"routetables":                {[]string{"route_table"}, "RouteTable"},
+	"peeringconnections":         {[]string{"peering_connection"}, "PeeringConnection"},
+	"transitgateways":            {[]string{"transit_gateway"}, "TransitGateway"},
+	"internetgateways":           {[]string{"internet_gateway"}, "InternetGateway"},
+	"egressonlyinternetgateways": {[]string{"egress_only_internet_gateway"}, "EgressOnlyInternetGateway"},
+	"natgateways":                {[]string{"nat_gateway"}, "NATGateway"},
+	"dhcpoptions":                {[]string{"dhcp_options"}, "DhcpOptions"},
+	"endpointservices":           {[]string{"endpoint_service"}, "EndpointService"},
+	"iamroles":                   {[]string{"iam_role"}, "IAMRole"},
+}
+
+// WatchTypes returns the resource types accepted by ParseScope in name order
+func WatchTypes() []string {
+	names := make([]string, 0, len(watchTypes))
+	for name := range watchTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Scope limits a watcher to some resource types
+type Scope struct {
+	// scanTypes are the scanner resource types to scan, sorted
+	scanTypes []string
+	// compareTypes are the resource types of the differences to report
+	compareTypes []string
+}
+
+// ParseScope parses resource types such as "securitygroups" and "routetables"
+func ParseScope(names []string) (*Scope, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no resource types to watch")
+	}
+	scan := make(map[string]bool)
+	scope := &Scope{}
+	for _, name := range names {
+		watchType, ok := watchTypes[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown resource type %q, expected one of %s", name, strings.Join(WatchTypes(), ", "))
+		}
+		for _, resourceType := range watchType.scan {
+			scan[resourceType] = true
+		}
+		scope.compareTypes = append(scope.compareTypes, watchType.compare)
+	}
+	for resourceType := range scan {
+		scope.scanTypes = append(scope.scanTypes, resourceType)
+	}
+	sort.Strings(scope.scanTypes)
+	return scope, nil
+}
+
+// filter returns the resourceTypes the scope scans
+func (s *Scope) filter(resourceTypes []string) []string {
+	var filtered []string
+	for _, resourceType := range resourceTypes {
+		for _, scanType := range s.scanTypes {
+			if resourceType == scanType {
+				filtered = append(filtered, resourceType)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,55 @@
+package watch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func TestParseScope(t *testing.T) {
+	scope, err := ParseScope([]string{"securitygroups", " Subnets"})
+	if err != nil {
+		t.Fatalf("ParseScope failed: %v", err)
+	}
+	if want := []string{"network_acl", "route_table", "security_group", "subnet"}; !reflect.DeepEqual(scope.scanTypes, want) {
+		t.Errorf("scan types = %v, want %v", scope.scanTypes, want)
+	}
+	if want := []string{"SecurityGroup", "Subnet"}; !reflect.DeepEqual(scope.compareTypes, want) {
+		t.Errorf("compare types = %v, want %v", scope.compareTypes, want)
+	}
+
+	// CloudTrail changes outside the scope are not rescanned
+	if got := scope.filter([]string{"internet_gateway", "route_table", "security_group"}); !reflect.DeepEqual(got, []string{"route_table", "security_group"}) {
+		t.Errorf("filter = %v, want route_table and security_group", got)
+	}
+
+	if _, err := ParseScope([]string{"instances"}); err == nil {
+		t.Error("ParseScope accepted an unknown resource type")
+	}
+	if _, err := ParseScope(nil); err == nil {
+		t.Error("ParseScope accepted no resource types")
+	}
+}
+
+func TestCompareResourceTypes(t *testing.T) {
+	baseline := &scanner.Network{
+		Subnets:        []scanner.Subnet{{ID: "subnet-1", CidrBlock: "10.0.1.0/24"}},
+		SecurityGroups: []scanner.SecurityGroup{{ID: "sg-1", Name: "web"}},
+	}
+	current := &scanner.Network{
+		SecurityGroups: []scanner.SecurityGroup{{ID: "sg-1", Name: "web", Description: "changed"}},
+	}
+
+	comparator := NewComparator(false)
+	comparator.SetResourceTypes([]string{"SecurityGroup"})
+	differences := comparator.Compare(baseline, current)
+	if len(differences) != 1 || differences[0].ResourceID != "sg-1" {
+		t.Errorf("differences = %+v, want only the security group change", differences)
+	}
+
+	comparator.SetResourceTypes(nil)
+	if differences := comparator.Compare(baseline, current); len(differences) != 2 {
+		t.Errorf("differences = %+v, want the subnet and security group changes", differences)
+	}
+}
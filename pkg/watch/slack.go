@@ -0,0 +1,201 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSlackDifferences is how many differences of a resource type a Slack
+	// message lists, keeping each attachment within Slack's text limits
+	maxSlackDifferences = 10
+	// maxSlackAttachments is how many resource types a Slack message lists
+	maxSlackAttachments = 20
+	// maxSlackDetail is the longest difference detail shown in a Slack message
+	maxSlackDetail = 200
+)
+
+// slackSeverityColors are the attachment colors of each severity, matching the
+// HTML drift report
+var slackSeverityColors = map[Severity]string{
+	SeverityHigh:   "#d73a49",
+	SeverityMedium: "#e36209",
+	SeverityLow:    "#0366d6",
+}
+
+// SlackNotifier posts drift events to a Slack incoming webhook as Block Kit
+// messages, with an attachment per resource type colored by its most severe
+// difference
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a notifier that posts to the given Slack incoming webhook URL
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// slackMessage is the payload of a Slack incoming webhook
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Blocks      []slackBlock      `json:"blocks"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+// slackAttachment is a colored attachment holding blocks
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// slackBlock is a Block Kit block
+type slackBlock struct {
+	Type     string         `json:"type"`
+	Text     *slackText     `json:"text,omitempty"`
+	Fields   []slackText    `json:"fields,omitempty"`
+	Elements []slackElement `json:"elements,omitempty"`
+}
+
+// slackText is a Block Kit text object
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackElement is a Block Kit button
+type slackElement struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+	URL  string     `json:"url,omitempty"`
+}
+
+// mrkdwn returns a Block Kit mrkdwn text object
+func mrkdwn(text string) *slackText {
+	return &slackText{Type: "mrkdwn", Text: text}
+}
+
+// slackEscape escapes the characters Slack treats as control characters
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// Notify posts the drift event to the webhook
+func (n *SlackNotifier) Notify(ctx context.Context, event DriftEvent) error {
+	return postDriftEvent(ctx, n.client, "Slack", n.webhookURL, nil, formatSlackMessage(event))
+}
+
+// slackGroup is the differences of one resource type in a Slack message
+type slackGroup struct {
+	resourceType string
+	severity     Severity
+	differences  []Difference
+}
+
+// formatSlackMessage formats a drift event as a Block Kit message: a summary,
+// then an attachment per resource type, most severe first, and a button linking
+// to the HTML report when it has been uploaded
+func formatSlackMessage(event DriftEvent) slackMessage {
+	location := event.Region
+	if event.VpcID != "" {
+		location += " / " + event.VpcID
+	}
+	fields := []slackText{
+		{Type: "mrkdwn", Text: "*Region:*\n" + slackEscape(location)},
+		{Type: "mrkdwn", Text: fmt.Sprintf("*Detected:*\n%s", event.DetectedAt.Format(time.RFC3339))},
+	}
+	if event.Baseline != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Baseline:*\n" + slackEscape(event.Baseline)})
+	}
+
+	message := slackMessage{
+		Text: event.Summary(),
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: fmt.Sprintf("%d network differences detected", len(event.Differences))}},
+			{Type: "section", Fields: fields},
+		},
+	}
+	if event.ReportURL != "" {
+		message.Blocks = append(message.Blocks, slackBlock{Type: "actions", Elements: []slackElement{
+			{Type: "button", Text: &slackText{Type: "plain_text", Text: "View full diff"}, URL: event.ReportURL},
+		}})
+	}
+
+	groups := groupBySeverity(event.Differences)
+	for i, group := range groups {
+		if i == maxSlackAttachments {
+			message.Attachments = append(message.Attachments, slackAttachment{
+				Color:  slackSeverityColors[SeverityLow],
+				Blocks: []slackBlock{{Type: "section", Text: mrkdwn(fmt.Sprintf("_and %d more resource types_", len(groups)-i))}},
+			})
+			break
+		}
+		message.Attachments = append(message.Attachments, slackAttachment{
+			Color:  slackSeverityColors[group.severity],
+			Blocks: []slackBlock{{Type: "section", Text: mrkdwn(formatSlackGroup(group))}},
+		})
+	}
+	return message
+}
+
+// groupBySeverity groups differences by resource type, most severe type first
+// and then by name
+func groupBySeverity(differences []Difference) []slackGroup {
+	byType := make(map[string]*slackGroup)
+	var groups []*slackGroup
+	for _, diff := range differences {
+		group, ok := byType[diff.ResourceType]
+		if !ok {
+			group = &slackGroup{resourceType: diff.ResourceType, severity: SeverityLow}
+			byType[diff.ResourceType] = group
+			groups = append(groups, group)
+		}
+		group.differences = append(group.differences, diff)
+		if severity := DifferenceSeverity(diff); severity.AtLeast(group.severity) {
+			group.severity = severity
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].severity != groups[j].severity {
+			return severityRanks[groups[i].severity] > severityRanks[groups[j].severity]
+		}
+		return groups[i].resourceType < groups[j].resourceType
+	})
+
+	result := make([]slackGroup, len(groups))
+	for i, group := range groups {
+		result[i] = *group
+	}
+	return result
+}
+
+// formatSlackGroup lists the differences of a resource type in mrkdwn
+func formatSlackGroup(group slackGroup) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "*%s* (%d, %s severity)", group.resourceType, len(group.differences), group.severity)
+	for i, diff := range group.differences {
+		if i == maxSlackDifferences {
+			fmt.Fprintf(&text, "\n_and %d more_", len(group.differences)-i)
+			break
+		}
+		fmt.Fprintf(&text, "\n• %s `%s`", diff.Type, slackEscape(diff.displayID()))
+		if len(diff.Details) > 0 {
+			detail := diff.Details[0]
+			if len(detail) > maxSlackDetail {
+				detail = detail[:maxSlackDetail] + "…"
+			}
+			fmt.Fprintf(&text, ": %s", slackEscape(detail))
+			if len(diff.Details) > 1 {
+				fmt.Fprintf(&text, " (+%d)", len(diff.Details)-1)
+			}
+		}
+	}
+	return text.String()
+}
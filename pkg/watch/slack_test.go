@@ -0,0 +1,71 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFormatSlackMessage(t *testing.T) {
+	event := NewDriftEvent("us-east-1", "vpc-1", []Difference{
+		{Type: Added, ResourceType: "Subnet", ResourceID: "subnet-1"},
+		{Type: Modified, ResourceType: "SecurityGroup", ResourceID: "sg-1", ResourceName: "web", Details: []string{"IngressRules: added tcp 22 from 0.0.0.0/0", "Tags: changed"}},
+		{Type: Removed, ResourceType: "Subnet", ResourceID: "subnet-2"},
+	})
+	event.ReportURL = "https://bucket.s3.amazonaws.com/report.html?X-Amz-Signature=abc"
+
+	message := formatSlackMessage(event)
+	if message.Text != event.Summary() {
+		t.Errorf("fallback text = %q, want the event summary", message.Text)
+	}
+	last := message.Blocks[len(message.Blocks)-1]
+	if last.Type != "actions" || last.Elements[0].URL != event.ReportURL {
+		t.Errorf("last block = %+v, want a button linking to the report", last)
+	}
+
+	if len(message.Attachments) != 2 {
+		t.Fatalf("attachments = %+v, want one per resource type", message.Attachments)
+	}
+	groups := message.Attachments[0].Blocks[0].Text.Text
+	if message.Attachments[0].Color != slackSeverityColors[SeverityHigh] || !strings.HasPrefix(groups, "*SecurityGroup* (1, high severity)") {
+		t.Errorf("first attachment = %+v, want the high severity security group change", message.Attachments[0])
+	}
+	if !strings.Contains(groups, "modified `web (sg-1)`: IngressRules: added tcp 22 from 0.0.0.0/0 (+1)") {
+		t.Errorf("security group attachment = %q, want the first detail and a count of the rest", groups)
+	}
+	subnets := message.Attachments[1]
+	if subnets.Color != slackSeverityColors[SeverityMedium] || !strings.HasPrefix(subnets.Blocks[0].Text.Text, "*Subnet* (2, medium severity)") {
+		t.Errorf("second attachment = %+v, want both subnet changes at medium severity", subnets)
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var received slackMessage
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid payload %s: %v", body, err)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte("invalid_blocks"))
+	}))
+	defer server.Close()
+
+	notifier := NewSlackNotifier(server.URL)
+	if err := notifier.Notify(context.Background(), testDriftEvent()); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(received.Blocks) == 0 || len(received.Attachments) == 0 {
+		t.Errorf("received %+v, want blocks and attachments", received)
+	}
+
+	status = http.StatusBadRequest
+	if err := notifier.Notify(context.Background(), testDriftEvent()); err == nil || !strings.Contains(err.Error(), "invalid_blocks") {
+		t.Errorf("Notify error = %v, want Slack's response", err)
+	}
+}
@@ -0,0 +1,210 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/signing"
+)
+
+// S3API is the subset of the S3 API used to store snapshots
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// SnapshotStore writes scans to an S3 bucket under date-partitioned keys of the
+// form prefix/account/region/YYYY/MM/DD/YYYYMMDDTHHMMSSZ.json, with the VPC ID
+// after the region for scans of one VPC
+type SnapshotStore struct {
+	api    S3API
+	bucket string
+	prefix string
+	vpcID  string
+	signer signing.Signer
+}
+
+// NewSnapshotStore creates a store writing under an s3://bucket/prefix URI
+func NewSnapshotStore(api S3API, uri string) (*SnapshotStore, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "s3" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid S3 URI %q, expected s3://bucket/prefix", uri)
+	}
+	return &SnapshotStore{
+		api:    api,
+		bucket: parsed.Host,
+		prefix: strings.Trim(parsed.Path, "/"),
+	}, nil
+}
+
+// SetVPCID keeps the snapshots of a scan limited to one VPC apart from full scans
+func (s *SnapshotStore) SetVPCID(vpcID string) {
+	s.vpcID = vpcID
+}
+
+// SetSigner signs every snapshot written, in an object with a .sig suffix, and
+// requires snapshots read back to carry a valid signature
+func (s *SnapshotStore) SetSigner(signer signing.Signer) {
+	s.signer = signer
+}
+
+// URI returns the s3:// URI of a key in the store's bucket
+func (s *SnapshotStore) URI(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.bucket, key)
+}
+
+// scopePrefix returns the key prefix of the snapshots of an account and region
+func (s *SnapshotStore) scopePrefix(accountID, region string) string {
+	if accountID == "" {
+		accountID = "unknown"
+	}
+	return path.Join(s.prefix, accountID, region, s.vpcID) + "/"
+}
+
+// SnapshotKey returns the key a scan taken at the given time is stored under
+func (s *SnapshotStore) SnapshotKey(accountID, region string, at time.Time) string {
+	at = at.UTC()
+	return s.scopePrefix(accountID, region) + at.Format("2006/01/02/20060102T150405Z") + ".json"
+}
+
+// Put writes a scan to the store and returns its key
+func (s *SnapshotStore) Put(ctx context.Context, network *scanner.Network) (string, error) {
+	data, err := json.MarshalIndent(network, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	at := network.ScanTime
+	if at.IsZero() {
+		at = time.Now()
+	}
+	key := s.SnapshotKey(network.AccountID, network.Region, at)
+	contentType := "application/json"
+	_, err = s.api.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      &s.bucket,
+		Key:         &key,
+		Body:        bytes.NewReader(data),
+		ContentType: &contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to write snapshot %s: %w", s.URI(key), err)
+	}
+
+	if s.signer != nil {
+		if err := s.putSignature(ctx, key, data); err != nil {
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// putSignature signs a snapshot and writes the signature next to it
+func (s *SnapshotStore) putSignature(ctx context.Context, key string, data []byte) error {
+	signature, err := s.signer.Sign(ctx, data)
+	if err != nil {
+		return fmt.Errorf("failed to sign snapshot %s: %w", s.URI(key), err)
+	}
+	encoded, err := signature.Marshal()
+	if err != nil {
+		return err
+	}
+
+	signatureKey := key + signing.SignatureSuffix
+	contentType := "application/json"
+	_, err = s.api.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      &s.bucket,
+		Key:         &signatureKey,
+		Body:        bytes.NewReader(encoded),
+		ContentType: &contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot signature %s: %w", s.URI(signatureKey), err)
+	}
+	return nil
+}
+
+// Latest reads the most recent snapshot of an account and region. It returns a
+// nil network and an empty key when there is none.
+func (s *SnapshotStore) Latest(ctx context.Context, accountID, region string) (*scanner.Network, string, error) {
+	prefix := s.scopePrefix(accountID, region)
+
+	// Keys sort by the time of their scan
+	var latest string
+	pages := s3.NewListObjectsV2Paginator(s.api, &s3.ListObjectsV2Input{
+		Bucket: &s.bucket,
+		Prefix: &prefix,
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list %s: %w", s.URI(prefix), err)
+		}
+		for _, object := range page.Contents {
+			if object.Key != nil && strings.HasSuffix(*object.Key, ".json") && *object.Key > latest {
+				latest = *object.Key
+			}
+		}
+	}
+	if latest == "" {
+		return nil, "", nil
+	}
+
+	network, err := s.Get(ctx, latest)
+	if err != nil {
+		return nil, "", err
+	}
+	return network, latest, nil
+}
+
+// Get reads the snapshot stored under a key, verifying its signature when a
+// signer is set
+func (s *SnapshotStore) Get(ctx context.Context, key string) (*scanner.Network, error) {
+	data, err := s.getObject(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", s.URI(key), err)
+	}
+
+	if s.signer != nil {
+		signatureData, err := s.getObject(ctx, key+signing.SignatureSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signature of snapshot %s: %w", s.URI(key), err)
+		}
+		signature, err := signing.Parse(signatureData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature of snapshot %s: %w", s.URI(key), err)
+		}
+		if err := s.signer.Verify(ctx, data, signature); err != nil {
+			return nil, fmt.Errorf("failed to verify snapshot %s: %w", s.URI(key), err)
+		}
+	}
+
+	var network scanner.Network
+	if err := json.Unmarshal(data, &network); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", s.URI(key), err)
+	}
+	network.Sort()
+	return &network, nil
+}
+
+// getObject reads the contents of an object in the store's bucket
+func (s *SnapshotStore) getObject(ctx context.Context, key string) ([]byte, error) {
+	result, err := s.api.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer result.Body.Close()
+	return io.ReadAll(result.Body)
+}
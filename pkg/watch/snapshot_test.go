@@ -0,0 +1,151 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/signing"
+)
+
+type fakeS3 struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[*params.Key] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, *params.Prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	output := &s3.ListObjectsV2Output{}
+	for _, key := range keys {
+		output.Contents = append(output.Contents, s3Types.Object{Key: &key})
+	}
+	return output, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	data, ok := f.objects[*params.Key]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func TestNewSnapshotStoreInvalidURI(t *testing.T) {
+	for _, uri := range []string{"bucket/prefix", "https://bucket/prefix", "s3:///prefix"} {
+		if _, err := NewSnapshotStore(&fakeS3{}, uri); err == nil {
+			t.Errorf("Expected an error for %q", uri)
+		}
+	}
+}
+
+func TestSnapshotStore(t *testing.T) {
+	api := &fakeS3{objects: make(map[string][]byte)}
+	store, err := NewSnapshotStore(api, "s3://drift-bucket/snapshots/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	network, key, err := store.Latest(ctx, "123456789012", "us-east-1")
+	if err != nil || network != nil || key != "" {
+		t.Fatalf("Expected no snapshot in an empty bucket, got %v %q %v", network, key, err)
+	}
+
+	first := &scanner.Network{
+		Region:    "us-east-1",
+		AccountID: "123456789012",
+		ScanTime:  time.Date(2024, 3, 9, 23, 59, 1, 0, time.UTC),
+		VPCs:      []scanner.VPC{{ID: "vpc-1"}},
+	}
+	second := &scanner.Network{
+		Region:    "us-east-1",
+		AccountID: "123456789012",
+		ScanTime:  time.Date(2024, 3, 10, 0, 5, 0, 0, time.UTC),
+		VPCs:      []scanner.VPC{{ID: "vpc-1"}, {ID: "vpc-2"}},
+	}
+	other := &scanner.Network{
+		Region:    "eu-west-1",
+		AccountID: "123456789012",
+		ScanTime:  time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	// Written out of order, the latest is still picked by its key
+	for _, n := range []*scanner.Network{second, first, other} {
+		if _, err := store.Put(ctx, n); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	wantKey := "snapshots/123456789012/us-east-1/2024/03/10/20240310T000500Z.json"
+	if _, ok := api.objects[wantKey]; !ok {
+		t.Fatalf("Expected a date-partitioned key %s, got %v", wantKey, api.objects)
+	}
+
+	network, key, err = store.Latest(ctx, "123456789012", "us-east-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if key != wantKey || len(network.VPCs) != 2 {
+		t.Errorf("Expected the latest us-east-1 snapshot, got %s with %d VPCs", key, len(network.VPCs))
+	}
+
+	store.SetVPCID("vpc-1")
+	if got := store.SnapshotKey("123456789012", "us-east-1", first.ScanTime); got != "snapshots/123456789012/us-east-1/vpc-1/2024/03/09/20240309T235901Z.json" {
+		t.Errorf("Expected the VPC ID in the key, got %s", got)
+	}
+	if network, _, _ := store.Latest(ctx, "123456789012", "us-east-1"); network != nil {
+		t.Errorf("Expected full scans not to be the latest snapshot of one VPC")
+	}
+}
+
+func TestSnapshotStoreSigned(t *testing.T) {
+	signer, err := signing.NewHMACSigner([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &fakeS3{objects: make(map[string][]byte)}
+	store, _ := NewSnapshotStore(api, "s3://drift-bucket")
+	store.SetSigner(signer)
+	ctx := context.Background()
+
+	key, err := store.Put(ctx, &scanner.Network{Region: "us-east-1", AccountID: "123456789012", ScanTime: time.Now()})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := api.objects[key+".sig"]; !ok {
+		t.Fatalf("Expected a signature object next to %s", key)
+	}
+
+	network, latest, err := store.Latest(ctx, "123456789012", "us-east-1")
+	if err != nil || latest != key || network == nil {
+		t.Fatalf("Expected the signed snapshot to be the latest, got %q %v", latest, err)
+	}
+
+	api.objects[key] = []byte(`{"region": "us-east-1", "account_id": "123456789012"}`)
+	if _, err := store.Get(ctx, key); !errors.Is(err, signing.ErrInvalidSignature) {
+		t.Errorf("Expected a tampered snapshot to be rejected, got %v", err)
+	}
+}
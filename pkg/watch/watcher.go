@@ -3,29 +3,52 @@ package watch
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/Yiu-Kelvin/pikaatools/pkg/aws"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/logging"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/names"
 	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/signing"
 )
 
 // Watcher handles periodic scanning and comparison
 type Watcher struct {
-	scanner     *scanner.NetworkScanner
-	comparator  *Comparator
-	interval    time.Duration
-	verbose     bool
-	region      string
-	vpcID       string
+	scanner       *scanner.NetworkScanner
+	comparator    *Comparator
+	interval      time.Duration
+	verbose       bool
+	region        string
+	vpcID         string
+	notifiers     []Notifier
+	diffOutput    string
+	reportFile    string
+	reports       *ReportUploader
+	nameProviders []names.Provider
+	metrics       *Metrics
+	scanIAM       bool
+	changes       *ChangeTracker
+	cache         *scanner.ScanCache
+	fullInterval  time.Duration
+	last          *scanner.Network
+	lastFullScan  time.Time
+	scope         *Scope
+	remediator    *AutoRemediator
+	logger        *slog.Logger
+	// reloadInterval is how often remote baselines are downloaded again, zero for never
+	reloadInterval time.Duration
 }
 
 // NewWatcher creates a new watcher instance
 func NewWatcher(awsClient *aws.Client, interval time.Duration, verbose bool, region, vpcID string) *Watcher {
-	return &Watcher{
+	logger, _ := logging.New(os.Stderr, logging.FormatText, logging.Level(verbose, false))
+	w := &Watcher{
 		scanner:     scanner.NewNetworkScanner(awsClient),
 		comparator:  NewComparator(verbose),
 		interval:    interval,
@@ -33,6 +56,137 @@ func NewWatcher(awsClient *aws.Client, interval time.Duration, verbose bool, reg
 		region:      region,
 		vpcID:       vpcID,
 	}
+	w.comparator.SetS3(awsClient.S3)
+	w.SetLogger(logger)
+	return w
+}
+
+// SetLogger sets the logger for scan details, warnings and errors
+func (w *Watcher) SetLogger(logger *slog.Logger) {
+	w.logger = logger
+	w.scanner.SetLogger(logger)
+}
+
+// SetProgress reports the progress of each scan to progress, nil for none
+func (w *Watcher) SetProgress(progress scanner.ProgressReporter) {
+	w.scanner.SetProgress(progress)
+}
+
+// AddNotifier registers a notifier that receives drift events
+func (w *Watcher) AddNotifier(notifier Notifier) {
+	w.notifiers = append(w.notifiers, notifier)
+}
+
+// SetVerifier requires baselines to carry a valid signature from the signer
+func (w *Watcher) SetVerifier(verifier signing.Signer) {
+	w.comparator.SetVerifier(verifier)
+}
+
+// SetIgnoreRules sets the rules used to suppress known or expected differences
+func (w *Watcher) SetIgnoreRules(rules *IgnoreRules) {
+	w.comparator.SetIgnoreRules(rules)
+}
+
+// SetAutoRemediator reverts the drift the remediator's policy allows after each scan
+func (w *Watcher) SetAutoRemediator(remediator *AutoRemediator) {
+	w.remediator = remediator
+}
+
+// SetRemediation suggests commands in the given format that would revert
+// security group rule, route and network ACL entry changes
+func (w *Watcher) SetRemediation(format RemediationFormat) {
+	w.comparator.SetRemediation(format)
+}
+
+// SetDiffOutput sets a structured output format (json, junit, sarif) for scan results.
+// An empty format prints colored text.
+func (w *Watcher) SetDiffOutput(format string) {
+	w.diffOutput = format
+}
+
+// SetReportFile writes an HTML drift report of every scan's differences to the file,
+// replacing the previous scan's report
+func (w *Watcher) SetReportFile(filename string) {
+	w.reportFile = filename
+}
+
+// SetReportUploader uploads an HTML drift report of every scan with differences
+// to S3 and links to it from drift events
+func (w *Watcher) SetReportUploader(uploader *ReportUploader) {
+	w.reports = uploader
+}
+
+// SetNameProviders sets the providers used to show friendly resource names in differences
+func (w *Watcher) SetNameProviders(providers []names.Provider) {
+	w.nameProviders = providers
+}
+
+// SetScanAppMesh enables or disables discovery of App Mesh virtual gateways
+func (w *Watcher) SetScanAppMesh(scanAppMesh bool) {
+	w.scanner.SetScanAppMesh(scanAppMesh)
+}
+
+// SetScanContainers enables or disables discovery of EKS clusters and ECS services
+func (w *Watcher) SetScanContainers(scanContainers bool) {
+	w.scanner.SetScanContainers(scanContainers)
+}
+
+// SetScanDatabases enables or disables discovery of RDS, ElastiCache and Redshift placement
+func (w *Watcher) SetScanDatabases(scanDatabases bool) {
+	w.scanner.SetScanDatabases(scanDatabases)
+}
+
+// SetCache reuses per-resource API results between scans from cache
+func (w *Watcher) SetCache(cache *scanner.ScanCache) {
+	w.cache = cache
+	w.scanner.SetCache(cache)
+}
+
+// SetScanEdge enables or disables discovery of Global Accelerator and CloudFront ingress
+func (w *Watcher) SetScanEdge(scanEdge bool) {
+	w.scanner.SetScanEdge(scanEdge)
+}
+
+// SetScanIAM enables or disables scanning of IAM roles, optionally including every role
+func (w *Watcher) SetScanIAM(scanIAM, allRoles bool) {
+	w.scanIAM = scanIAM
+	w.scanner.SetScanIAM(scanIAM)
+	w.scanner.SetAllIAMRoles(allRoles)
+}
+
+// SetIAMConcurrency sets how many IAM roles have their policies fetched at once
+func (w *Watcher) SetIAMConcurrency(concurrency int) {
+	w.scanner.SetIAMConcurrency(concurrency)
+}
+
+// SetScanTimeout limits each scan to timeout, so a hung API call cannot stall the
+// watcher. A scan that times out is reported as failed and not compared, since the
+// resources it did not finish would show up as removed. Zero never times out.
+func (w *Watcher) SetScanTimeout(timeout time.Duration) {
+	w.scanner.SetTimeout(timeout)
+}
+
+// SetChangeTracker rescans only the resource types changed according to tracker
+// on each interval, skipping intervals without changes. Everything is rescanned
+// at least every fullInterval to pick up changes the tracker missed.
+func (w *Watcher) SetChangeTracker(tracker *ChangeTracker, fullInterval time.Duration) {
+	w.changes = tracker
+	w.fullInterval = fullInterval
+}
+
+// SetScope scans and compares only the resource types of scope, nil for all of them
+func (w *Watcher) SetScope(scope *Scope) {
+	w.scope = scope
+	if scope == nil {
+		w.comparator.SetResourceTypes(nil)
+		return
+	}
+	w.comparator.SetResourceTypes(scope.compareTypes)
+}
+
+// SetMetrics sets the collector that records scan results for the metrics endpoint
+func (w *Watcher) SetMetrics(metrics *Metrics) {
+	w.metrics = metrics
 }
 
 // WatchOptions contains options for the watch command
@@ -45,27 +199,71 @@ type WatchOptions struct {
 	Verbose          bool
 }
 
-// Watch starts watching for changes against a baseline working state
+// baselineState is a loaded baseline working state
+type baselineState struct {
+	file    string
+	network *scanner.Network
+	// vpcIDs limits the comparison to the baseline's VPCs, nil compares everything
+	vpcIDs []string
+}
+
+// Watch starts watching for changes against a baseline working state, read from a
+// local file or an s3:// or http(s):// URL
 func (w *Watcher) Watch(ctx context.Context, workingStateFile string) error {
-	// Load the baseline working state
-	if w.verbose {
-		fmt.Printf("Loading baseline state from %s...\n", workingStateFile)
-	}
+	return w.watch(ctx, &baselineSource{location: workingStateFile})
+}
+
+// WatchDir starts watching for changes against every baseline working state in a
+// directory. Each baseline is compared only with the VPCs it contains, so teams
+// owning different VPCs can keep their own baselines.
+func (w *Watcher) WatchDir(ctx context.Context, dir string) error {
+	return w.watch(ctx, &baselineSource{location: dir, dir: true})
+}
 
-	baseline, err := w.comparator.LoadWorkingState(workingStateFile)
+// loadBaselineDir loads the *.json working states of a directory in name order.
+// Each VPC may be in only one baseline.
+func (w *Watcher) loadBaselineDir(ctx context.Context, dir string) ([]baselineState, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
 	if err != nil {
-		return fmt.Errorf("failed to load baseline state: %w", err)
+		return nil, fmt.Errorf("failed to list baseline states in %s: %w", dir, err)
 	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no baseline states (*.json) found in %s", dir)
+	}
+	sort.Strings(files)
 
-	if w.verbose {
-		fmt.Printf("Loaded baseline state from %s (scanned at %s)\n",
-			workingStateFile, baseline.ScanTime.Format(time.RFC3339))
-		fmt.Printf("Starting periodic scan every %v...\n", w.interval)
+	owners := make(map[string]string)
+	var baselines []baselineState
+	for _, file := range files {
+		network, err := w.comparator.LoadBaseline(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load baseline state: %w", err)
+		}
+
+		vpcIDs := make([]string, 0, len(network.VPCs))
+		for _, vpc := range network.VPCs {
+			if owner, ok := owners[vpc.ID]; ok {
+				return nil, fmt.Errorf("VPC %s is in both baseline states %s and %s", vpc.ID, owner, file)
+			}
+			owners[vpc.ID] = file
+			vpcIDs = append(vpcIDs, vpc.ID)
+		}
+
+		w.logger.Debug("loaded baseline state", "file", file, "vpcs", vpcIDs, "scan_time", network.ScanTime.Format(time.RFC3339))
+		baselines = append(baselines, baselineState{file: file, network: network, vpcIDs: vpcIDs})
+	}
+	return baselines, nil
+}
+
+// watch scans periodically until ctx is cancelled and compares each scan against
+// the baselines of a source, reloading them when they change
+func (w *Watcher) watch(ctx context.Context, source *baselineSource) error {
+	baselines, err := w.loadBaselines(ctx, source)
+	if err != nil {
+		return err
 	}
 
-	// Set up signal handling for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	w.logger.Debug("starting periodic scans", "interval", w.interval)
 
 	// Create a ticker for periodic scans
 	ticker := time.NewTicker(w.interval)
@@ -75,47 +273,277 @@ func (w *Watcher) Watch(ctx context.Context, workingStateFile string) error {
 	w.scanner.SetVerbose(w.verbose)
 
 	// Perform initial scan
-	color.Cyan("🔍 Starting initial scan...")
-	if err := w.performScan(ctx, baseline); err != nil {
+	if w.diffOutput == "" {
+		color.Cyan("🔍 Starting initial scan...")
+	}
+	if _, err := w.performScan(ctx, baselines, nil); err != nil {
+		if ctx.Err() != nil {
+			color.Yellow("\nWatch stopped")
+			return nil
+		}
 		return fmt.Errorf("initial scan failed: %w", err)
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			color.Yellow("Watch stopped by context cancellation")
-			return ctx.Err()
-
-		case <-sigChan:
-			color.Yellow("\nWatch stopped by signal")
+			color.Yellow("\nWatch stopped")
 			return nil
 
 		case <-ticker.C:
-			color.Cyan("🔍 Performing periodic scan...")
-			if err := w.performScan(ctx, baseline); err != nil {
-				color.Red("Scan failed: %v", err)
+			resourceTypes, rescan := w.changedResourceTypes(ctx)
+			var reloaded bool
+			baselines, reloaded = w.reloadBaselines(ctx, source, baselines)
+			if reloaded && !rescan && w.last != nil {
+				// Compare the unchanged network against the new baselines without rescanning it
+				if w.diffOutput == "" {
+					color.Cyan("🔍 Comparing against the reloaded baselines...")
+				}
+				if _, err := w.compareBaselines(ctx, baselines, w.last, time.Now(), 0); err != nil {
+					w.logger.Error("comparison failed", "error", err)
+				}
+				continue
+			}
+			if !rescan {
+				continue
+			}
+			if w.diffOutput == "" {
+				color.Cyan("🔍 Performing periodic scan...")
+			}
+			if _, err := w.performScan(ctx, baselines, resourceTypes); err != nil && ctx.Err() == nil {
+				w.logger.Error("scan failed", "error", err)
 				// Continue watching even if one scan fails
 			}
 		}
 	}
 }
 
-// performScan executes a scan and compares against baseline
-func (w *Watcher) performScan(ctx context.Context, baseline *scanner.Network) error {
+// Check performs a single scan against a baseline working state and returns the differences
+func (w *Watcher) Check(ctx context.Context, workingStateFile string) ([]Difference, error) {
+	baseline, err := w.comparator.LoadBaseline(ctx, workingStateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load baseline state: %w", err)
+	}
+
+	w.scanner.SetVerbose(w.verbose)
+
+	return w.performScan(ctx, []baselineState{{file: workingStateFile, network: baseline}}, nil)
+}
+
+// changedResourceTypes returns the resource types to rescan on this interval, nil
+// for all of them, and whether to scan at all
+func (w *Watcher) changedResourceTypes(ctx context.Context) ([]string, bool) {
+	if w.changes == nil || w.last == nil {
+		return nil, true
+	}
+	if w.fullInterval > 0 && time.Since(w.lastFullScan) >= w.fullInterval {
+		return nil, true
+	}
+
+	resourceTypes, err := w.changes.Changes(ctx)
+	if err != nil {
+		// Fall back to a full scan so changes are not missed
+		w.logger.Warn("failed to look up changes, scanning everything", "error", err)
+		return nil, true
+	}
+	if len(resourceTypes) == 0 {
+		w.logger.Debug("no changes recorded by CloudTrail since the last poll")
+		return nil, false
+	}
+	w.logger.Debug("CloudTrail recorded changes", "resource_types", resourceTypes)
+	if dropped := w.cache.Invalidate(resourceTypes); dropped > 0 {
+		w.logger.Debug("dropped cached results of changed resources", "results", dropped)
+	}
+	if w.scope != nil {
+		resourceTypes = w.scope.filter(resourceTypes)
+		if len(resourceTypes) == 0 {
+			w.logger.Debug("no changes to the watched resource types")
+			return nil, false
+		}
+	}
+	return resourceTypes, true
+}
+
+// performScan executes a scan and compares it against each baseline. Only
+// resourceTypes are rescanned when there is an earlier scan to reuse, nil
+// rescans everything.
+func (w *Watcher) performScan(ctx context.Context, baselines []baselineState, resourceTypes []string) ([]Difference, error) {
 	scanStart := time.Now()
 
 	// Perform the scan
-	current, err := w.scanner.ScanNetwork(ctx, w.vpcID)
+	var current *scanner.Network
+	var err error
+	switch {
+	case resourceTypes != nil && w.last != nil:
+		current, err = w.scanner.RescanNetwork(ctx, w.vpcID, w.last, resourceTypes)
+	case w.scope != nil:
+		// Only the watched resource types are scanned, the others are left empty
+		current, err = w.scanner.RescanNetwork(ctx, w.vpcID, &scanner.Network{}, w.scope.scanTypes)
+		if err == nil {
+			w.lastFullScan = scanStart
+		}
+	default:
+		current, err = w.scanner.ScanNetwork(ctx, w.vpcID)
+		if err == nil {
+			w.lastFullScan = scanStart
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("failed to scan network: %w", err)
+		if w.metrics != nil {
+			w.metrics.RecordFailure()
+		}
+		return nil, fmt.Errorf("failed to scan network: %w", err)
+	}
+	if len(current.Incomplete) > 0 {
+		if w.metrics != nil {
+			w.metrics.RecordFailure()
+		}
+		return nil, fmt.Errorf("scan timed out before scanning %s", strings.Join(current.Incomplete, ", "))
 	}
 
 	scanDuration := time.Since(scanStart)
+	w.last = current
+
+	return w.compareBaselines(ctx, baselines, current, scanStart, scanDuration)
+}
+
+// compareBaselines compares a scanned network against each baseline, reporting
+// and publishing the differences
+func (w *Watcher) compareBaselines(ctx context.Context, baselines []baselineState, current *scanner.Network, scanStart time.Time, scanDuration time.Duration) ([]Difference, error) {
+	var differences []Difference
+	var events []DriftEvent
+	for i, state := range baselines {
+		baseline := state.network
 
-	// Compare with baseline
-	differences := w.comparator.Compare(baseline, current)
+		// IAM roles in the baseline are only compared when they are scanned
+		if !w.scanIAM && len(baseline.IAMRoles) > 0 {
+			withoutIAM := *baseline
+			withoutIAM.IAMRoles = nil
+			baseline = &withoutIAM
+		}
+
+		// Compare with baseline, limited to its VPCs
+		compared := current
+		if state.vpcIDs != nil {
+			compared = current.ForVPCs(state.vpcIDs)
+		}
+		baselineDifferences := w.comparator.Compare(baseline, compared)
+		w.resolveNames(ctx, baselineDifferences)
+		differences = append(differences, baselineDifferences...)
+
+		// Structured output is printed once for every baseline below
+		if w.diffOutput == "" {
+			if len(baselines) > 1 {
+				w.printBaselineDifferences(state.file, baselineDifferences, scanDuration, i == 0)
+			} else {
+				w.printDifferences(baselineDifferences, scanDuration)
+			}
+		}
+
+		// Reverts are best effort and never fail the scan
+		if w.remediator != nil && len(baselineDifferences) > 0 {
+			if _, err := w.remediator.Remediate(ctx, baselineDifferences, baseline, compared); err != nil {
+				w.logger.Error("auto-remediation failed", "error", err)
+			}
+		}
+
+		// Drift events are published once the report they link to is written
+		if len(baselineDifferences) > 0 {
+			event := NewDriftEvent(w.region, w.vpcID, baselineDifferences)
+			if state.vpcIDs != nil {
+				event.VpcID = strings.Join(state.vpcIDs, ",")
+				event.Baseline = state.file
+			}
+			events = append(events, event)
+		}
+	}
+
+	if w.metrics != nil {
+		w.metrics.RecordScan(current, differences, scanDuration)
+	}
+
+	files := make([]string, len(baselines))
+	for i, state := range baselines {
+		files[i] = state.file
+	}
+	info := HTMLReportInfo{Region: w.region, VpcID: w.vpcID, Baseline: strings.Join(files, ", ")}
+	reportURL := w.writeReports(ctx, differences, info, scanStart)
+
+	// Publish drift events
+	for _, event := range events {
+		event.ReportURL = reportURL
+		w.notify(ctx, event)
+	}
 
-	// Print timestamp and scan info
+	// Print structured output without the human-readable decoration
+	if w.diffOutput != "" {
+		report, err := FormatDifferences(differences, w.diffOutput)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Print(report)
+	}
+
+	return differences, nil
+}
+
+// writeReports writes the HTML report of a scan to the report file and uploads it
+// to S3 when there are differences, returning the link to the uploaded report.
+// Reports are best effort and never fail the scan.
+func (w *Watcher) writeReports(ctx context.Context, differences []Difference, info HTMLReportInfo, scanTime time.Time) string {
+	if w.reportFile == "" && (w.reports == nil || len(differences) == 0) {
+		return ""
+	}
+	report := FormatHTMLReport(differences, info)
+	if w.reportFile != "" {
+		if err := os.WriteFile(w.reportFile, []byte(report), 0644); err != nil {
+			w.logger.Error("failed to write HTML report", "file", w.reportFile, "error", err)
+		}
+	}
+	if w.reports == nil || len(differences) == 0 {
+		return ""
+	}
+	reportURL, err := w.reports.Upload(ctx, report, w.region, scanTime)
+	if err != nil {
+		w.logger.Error("failed to upload HTML report", "error", err)
+		return ""
+	}
+	return reportURL
+}
+
+// resolveNames fills in the friendly names of the differing resources.
+// Name lookups are best effort and never fail the scan.
+func (w *Watcher) resolveNames(ctx context.Context, differences []Difference) {
+	if err := ResolveNames(ctx, w.nameProviders, differences); err != nil {
+		w.logger.Debug("failed to resolve resource names", "error", err)
+	}
+}
+
+// ResolveNames fills in the friendly names of the differing resources from the
+// name providers
+func ResolveNames(ctx context.Context, providers []names.Provider, differences []Difference) error {
+	if len(providers) == 0 || len(differences) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(differences))
+	for i, diff := range differences {
+		ids[i] = diff.ResourceID
+	}
+
+	resolved, err := names.Resolve(ctx, providers, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range differences {
+		differences[i].ResourceName = resolved[differences[i].ResourceID]
+	}
+	return nil
+}
+
+// printDifferences prints a timestamped, colored summary of the differences
+func (w *Watcher) printDifferences(differences []Difference, scanDuration time.Duration) {
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	if w.verbose {
 		fmt.Printf("\n[%s] Scan completed in %v (region: %s)\n", timestamp, scanDuration, w.region)
@@ -125,6 +553,26 @@ func (w *Watcher) performScan(ctx context.Context, baseline *scanner.Network) er
 
 	// Print differences
 	w.comparator.PrintDifferences(differences)
+}
 
-	return nil
+// printBaselineDifferences prints the differences found against one of several
+// baselines, led by the baseline's file. The scan details are printed with the first.
+func (w *Watcher) printBaselineDifferences(file string, differences []Difference, scanDuration time.Duration, first bool) {
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	if w.verbose && first {
+		fmt.Printf("\n[%s] Scan completed in %v (region: %s)\n", timestamp, scanDuration, w.region)
+	}
+	fmt.Printf("\n[%s] %s: ", timestamp, file)
+
+	w.comparator.PrintDifferences(differences)
+}
+
+// notify sends a drift event to all registered notifiers
+func (w *Watcher) notify(ctx context.Context, event DriftEvent) {
+	for _, notifier := range w.notifiers {
+		if err := notifier.Notify(ctx, event); err != nil {
+			// Continue notifying others even if one fails
+			w.logger.Error("notification failed", "error", err)
+		}
+	}
 }
\ No newline at end of file
@@ -0,0 +1,145 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yiu-Kelvin/pikaatools/pkg/names"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+)
+
+func writeBaseline(t *testing.T, dir, name string, vpcIDs ...string) {
+	t.Helper()
+	network := scanner.Network{Region: "us-east-1"}
+	for _, id := range vpcIDs {
+		network.VPCs = append(network.VPCs, scanner.VPC{ID: id})
+	}
+	data, err := json.Marshal(network)
+	if err != nil {
+		t.Fatalf("Failed to marshal baseline: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		t.Fatalf("Failed to write baseline: %v", err)
+	}
+}
+
+func TestLoadBaselineDir(t *testing.T) {
+	w := &Watcher{comparator: NewComparator(false), logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	dir := t.TempDir()
+	writeBaseline(t, dir, "team-b.json", "vpc-b1", "vpc-b2")
+	writeBaseline(t, dir, "team-a.json", "vpc-a")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("baselines"), 0644); err != nil {
+		t.Fatalf("Failed to write README: %v", err)
+	}
+
+	baselines, err := w.loadBaselineDir(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("loadBaselineDir failed: %v", err)
+	}
+	if len(baselines) != 2 || filepath.Base(baselines[0].file) != "team-a.json" || filepath.Base(baselines[1].file) != "team-b.json" {
+		t.Fatalf("Expected the two JSON baselines in name order, got %+v", baselines)
+	}
+	if !reflect.DeepEqual(baselines[1].vpcIDs, []string{"vpc-b1", "vpc-b2"}) {
+		t.Errorf("Expected team-b's VPCs, got %v", baselines[1].vpcIDs)
+	}
+
+	// A VPC may only have one baseline
+	writeBaseline(t, dir, "team-c.json", "vpc-a")
+	if _, err := w.loadBaselineDir(context.Background(), dir); err == nil || !strings.Contains(err.Error(), "VPC vpc-a is in both") {
+		t.Errorf("Expected an error for a VPC in two baselines, got %v", err)
+	}
+
+	if _, err := w.loadBaselineDir(context.Background(), t.TempDir()); err == nil {
+		t.Error("Expected an error for a directory without baselines")
+	}
+}
+
+type staticNames map[string]string
+
+func (p staticNames) Resolve(ctx context.Context, ids []string) (map[string]string, error) {
+	return p, nil
+}
+
+func TestResolveNames(t *testing.T) {
+	differences := []Difference{
+		{Type: Added, ResourceType: "Subnet", ResourceID: "subnet-1"},
+		{Type: Removed, ResourceType: "Subnet", ResourceID: "subnet-2"},
+	}
+
+	if err := ResolveNames(context.Background(), []names.Provider{staticNames{"subnet-1": "payments-a"}}, differences); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if differences[0].ResourceName != "payments-a" || differences[1].ResourceName != "" {
+		t.Errorf("Expected only subnet-1 to be named, got %+v", differences)
+	}
+}
+
+func TestReloadBaselines(t *testing.T) {
+	w := &Watcher{comparator: NewComparator(false), logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "working_state.json")
+	writeBaseline(t, dir, "working_state.json", "vpc-old")
+
+	source := &baselineSource{location: file}
+	baselines, err := w.loadBaselines(ctx, source)
+	if err != nil {
+		t.Fatalf("loadBaselines failed: %v", err)
+	}
+
+	if _, reloaded := w.reloadBaselines(ctx, source, baselines); reloaded {
+		t.Error("Expected an unchanged baseline not to be reloaded")
+	}
+
+	// A scheduled scan saves a new baseline
+	writeBaseline(t, dir, "working_state.json", "vpc-old", "vpc-new")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatalf("Failed to touch baseline: %v", err)
+	}
+	baselines, reloaded := w.reloadBaselines(ctx, source, baselines)
+	if !reloaded || len(baselines) != 1 || len(baselines[0].network.VPCs) != 2 {
+		t.Fatalf("Expected the updated baseline to be reloaded, got %v %+v", reloaded, baselines)
+	}
+
+	// The last scan is compared against the reloaded baseline without rescanning
+	w.last = &scanner.Network{Region: "us-east-1", VPCs: []scanner.VPC{{ID: "vpc-old"}}}
+	differences, err := w.compareBaselines(ctx, baselines, w.last, time.Now(), 0)
+	if err != nil {
+		t.Fatalf("compareBaselines failed: %v", err)
+	}
+	if len(differences) != 1 || differences[0].Type != Removed || differences[0].ResourceID != "vpc-new" {
+		t.Errorf("Expected vpc-new to be reported removed, got %+v", differences)
+	}
+
+	// A baseline that is still being written keeps the previous one
+	if err := os.WriteFile(file, []byte(`{"vpcs": [`), 0644); err != nil {
+		t.Fatalf("Failed to write baseline: %v", err)
+	}
+	baselines, reloaded = w.reloadBaselines(ctx, source, baselines)
+	if reloaded || len(baselines[0].network.VPCs) != 2 {
+		t.Errorf("Expected the previous baseline to be kept, got %v %+v", reloaded, baselines)
+	}
+
+	// A directory is reloaded when a baseline is added
+	dirSource := &baselineSource{location: dir, dir: true}
+	writeBaseline(t, dir, "working_state.json", "vpc-a")
+	baselines, err = w.loadBaselines(ctx, dirSource)
+	if err != nil {
+		t.Fatalf("loadBaselines failed: %v", err)
+	}
+	writeBaseline(t, dir, "team-b.json", "vpc-b")
+	if baselines, reloaded = w.reloadBaselines(ctx, dirSource, baselines); !reloaded || len(baselines) != 2 {
+		t.Errorf("Expected the directory to be reloaded with 2 baselines, got %v %+v", reloaded, baselines)
+	}
+}
@@ -0,0 +1,222 @@
+//go:build integration
+
+// Package integration runs watch scenarios against a LocalStack (or moto) endpoint.
+//
+// Start LocalStack and run:
+//
+//	make test-integration
+//
+// PIKAATOOLS_TEST_ENDPOINT overrides the endpoint (default http://localhost:4566).
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/aws"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/scanner"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+const (
+	defaultEndpoint = "http://localhost:4566"
+	testRegion      = "us-east-1"
+)
+
+// environment is an isolated VPC created for a single scenario
+type environment struct {
+	client   *aws.Client
+	vpcID    string
+	subnetID string
+	sgID     string
+}
+
+// expectedDifference describes a difference a scenario must produce.
+// An empty ResourceID or Detail matches any value.
+type expectedDifference struct {
+	Type         watch.DifferenceType
+	ResourceType string
+	ResourceID   string
+	Detail       string
+}
+
+// scenario mutates an environment after the baseline is taken and lists the differences the watcher must report
+type scenario struct {
+	name   string
+	mutate func(t *testing.T, ctx context.Context, env *environment) []expectedDifference
+}
+
+// newLocalStackClient creates a client pointed at the test endpoint
+func newLocalStackClient(t *testing.T, ctx context.Context) *aws.Client {
+	t.Helper()
+
+	endpoint := os.Getenv("PIKAATOOLS_TEST_ENDPOINT")
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	options := aws.DefaultOptions()
+	options.EndpointURL = endpoint
+	client, err := aws.NewClientWithOptions(ctx, testRegion, "", options)
+	if err != nil {
+		t.Fatalf("Failed to create AWS client: %v", err)
+	}
+	if _, err := client.EC2.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{}); err != nil {
+		t.Skipf("LocalStack not reachable at %s: %v", endpoint, err)
+	}
+
+	return client
+}
+
+// newEnvironment creates a VPC with one subnet and one security group
+func newEnvironment(t *testing.T, ctx context.Context, client *aws.Client) *environment {
+	t.Helper()
+
+	vpc, err := client.EC2.CreateVpc(ctx, &ec2.CreateVpcInput{CidrBlock: awssdk.String("10.42.0.0/16")})
+	if err != nil {
+		t.Fatalf("Failed to create VPC: %v", err)
+	}
+	env := &environment{client: client, vpcID: *vpc.Vpc.VpcId}
+	t.Cleanup(func() { env.cleanup(context.Background()) })
+
+	env.subnetID = env.createSubnet(t, ctx, "10.42.1.0/24")
+
+	sg, err := client.EC2.CreateSecurityGroup(ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:   awssdk.String("pikaatools-integration-" + env.vpcID),
+		Description: awssdk.String("pikaatools integration test"),
+		VpcId:       &env.vpcID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create security group: %v", err)
+	}
+	env.sgID = *sg.GroupId
+
+	return env
+}
+
+// createSubnet creates a subnet in the environment's VPC
+func (env *environment) createSubnet(t *testing.T, ctx context.Context, cidr string) string {
+	t.Helper()
+
+	subnet, err := env.client.EC2.CreateSubnet(ctx, &ec2.CreateSubnetInput{
+		VpcId:     &env.vpcID,
+		CidrBlock: awssdk.String(cidr),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create subnet %s: %v", cidr, err)
+	}
+	return *subnet.Subnet.SubnetId
+}
+
+// cleanup deletes everything created in the environment's VPC, ignoring errors
+func (env *environment) cleanup(ctx context.Context) {
+	ec2Client := env.client.EC2
+	vpcFilter := []types.Filter{{Name: awssdk.String("vpc-id"), Values: []string{env.vpcID}}}
+
+	if igws, err := ec2Client.DescribeInternetGateways(ctx, &ec2.DescribeInternetGatewaysInput{
+		Filters: []types.Filter{{Name: awssdk.String("attachment.vpc-id"), Values: []string{env.vpcID}}},
+	}); err == nil {
+		for _, igw := range igws.InternetGateways {
+			ec2Client.DetachInternetGateway(ctx, &ec2.DetachInternetGatewayInput{InternetGatewayId: igw.InternetGatewayId, VpcId: &env.vpcID})
+			ec2Client.DeleteInternetGateway(ctx, &ec2.DeleteInternetGatewayInput{InternetGatewayId: igw.InternetGatewayId})
+		}
+	}
+
+	if subnets, err := ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{Filters: vpcFilter}); err == nil {
+		for _, subnet := range subnets.Subnets {
+			ec2Client.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: subnet.SubnetId})
+		}
+	}
+
+	if sgs, err := ec2Client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{Filters: vpcFilter}); err == nil {
+		for _, sg := range sgs.SecurityGroups {
+			if sg.GroupName != nil && *sg.GroupName != "default" {
+				ec2Client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{GroupId: sg.GroupId})
+			}
+		}
+	}
+
+	ec2Client.DeleteVpc(ctx, &ec2.DeleteVpcInput{VpcId: &env.vpcID})
+}
+
+// saveBaseline scans the environment's VPC and writes the working state to a temporary file
+func saveBaseline(t *testing.T, ctx context.Context, env *environment) string {
+	t.Helper()
+
+	network, err := scanner.NewNetworkScanner(env.client).ScanNetwork(ctx, env.vpcID)
+	if err != nil {
+		t.Fatalf("Failed to scan baseline: %v", err)
+	}
+
+	data, err := json.MarshalIndent(network, "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal baseline: %v", err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "working_state.json")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatalf("Failed to write baseline: %v", err)
+	}
+	return filename
+}
+
+// runScenario takes a baseline, applies the mutation and asserts the watcher reports the expected differences
+func runScenario(t *testing.T, client *aws.Client, sc scenario) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	env := newEnvironment(t, ctx, client)
+	baselineFile := saveBaseline(t, ctx, env)
+
+	expected := sc.mutate(t, ctx, env)
+
+	watcher := watch.NewWatcher(client, 0, false, testRegion, env.vpcID)
+	watcher.SetDiffOutput(watch.DiffOutputJSON)
+
+	differences, err := watcher.Check(ctx, baselineFile)
+	if err != nil {
+		t.Fatalf("Watch check failed: %v", err)
+	}
+
+	if len(expected) == 0 && len(differences) != 0 {
+		t.Fatalf("Expected no differences, got %+v", differences)
+	}
+
+	for _, want := range expected {
+		if !containsDifference(differences, want) {
+			t.Errorf("Expected %s %s %s %q, got %+v", want.Type, want.ResourceType, want.ResourceID, want.Detail, differences)
+		}
+	}
+}
+
+// containsDifference reports whether any difference matches the expectation
+func containsDifference(differences []watch.Difference, want expectedDifference) bool {
+	for _, diff := range differences {
+		if diff.Type != want.Type || diff.ResourceType != want.ResourceType {
+			continue
+		}
+		if want.ResourceID != "" && diff.ResourceID != want.ResourceID {
+			continue
+		}
+		if want.Detail == "" {
+			return true
+		}
+		for _, detail := range diff.Details {
+			if strings.Contains(detail, want.Detail) {
+				return true
+			}
+		}
+	}
+	return false
+}
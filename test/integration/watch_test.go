@@ -0,0 +1,94 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/Yiu-Kelvin/pikaatools/pkg/watch"
+)
+
+func TestWatchDetectsMutations(t *testing.T) {
+	client := newLocalStackClient(t, context.Background())
+
+	scenarios := []scenario{
+		{
+			name: "No changes",
+			mutate: func(t *testing.T, ctx context.Context, env *environment) []expectedDifference {
+				return nil
+			},
+		},
+		{
+			name: "Subnet added",
+			mutate: func(t *testing.T, ctx context.Context, env *environment) []expectedDifference {
+				subnetID := env.createSubnet(t, ctx, "10.42.2.0/24")
+				return []expectedDifference{
+					{Type: watch.Added, ResourceType: "Subnet", ResourceID: subnetID},
+					{Type: watch.Modified, ResourceType: "VPC", ResourceID: env.vpcID, Detail: "Subnets: added " + subnetID},
+				}
+			},
+		},
+		{
+			name: "Subnet removed",
+			mutate: func(t *testing.T, ctx context.Context, env *environment) []expectedDifference {
+				if _, err := env.client.EC2.DeleteSubnet(ctx, &ec2.DeleteSubnetInput{SubnetId: &env.subnetID}); err != nil {
+					t.Fatalf("Failed to delete subnet: %v", err)
+				}
+				return []expectedDifference{
+					{Type: watch.Removed, ResourceType: "Subnet", ResourceID: env.subnetID},
+				}
+			},
+		},
+		{
+			name: "Security group ingress rule added",
+			mutate: func(t *testing.T, ctx context.Context, env *environment) []expectedDifference {
+				_, err := env.client.EC2.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+					GroupId: &env.sgID,
+					IpPermissions: []types.IpPermission{
+						{
+							IpProtocol: awssdk.String("tcp"),
+							FromPort:   awssdk.Int32(443),
+							ToPort:     awssdk.Int32(443),
+							IpRanges:   []types.IpRange{{CidrIp: awssdk.String("0.0.0.0/0")}},
+						},
+					},
+				})
+				if err != nil {
+					t.Fatalf("Failed to authorize ingress: %v", err)
+				}
+				return []expectedDifference{
+					{Type: watch.Modified, ResourceType: "SecurityGroup", ResourceID: env.sgID, Detail: "tcp 443-443 0.0.0.0/0"},
+				}
+			},
+		},
+		{
+			name: "Internet gateway attached",
+			mutate: func(t *testing.T, ctx context.Context, env *environment) []expectedDifference {
+				igw, err := env.client.EC2.CreateInternetGateway(ctx, &ec2.CreateInternetGatewayInput{})
+				if err != nil {
+					t.Fatalf("Failed to create internet gateway: %v", err)
+				}
+				_, err = env.client.EC2.AttachInternetGateway(ctx, &ec2.AttachInternetGatewayInput{
+					InternetGatewayId: igw.InternetGateway.InternetGatewayId,
+					VpcId:             &env.vpcID,
+				})
+				if err != nil {
+					t.Fatalf("Failed to attach internet gateway: %v", err)
+				}
+				return []expectedDifference{
+					{Type: watch.Added, ResourceType: "InternetGateway", ResourceID: *igw.InternetGateway.InternetGatewayId},
+				}
+			},
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			runScenario(t, client, sc)
+		})
+	}
+}